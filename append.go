@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// sniffSize is how much of the end of an existing file is examined to detect
+// its line terminator.
+const sniffSize = 4096
+
+// lineEndings maps --line-ending values to the terminators they write.
+var lineEndings = map[string][]byte{
+	"lf":   []byte("\n"),
+	"crlf": []byte("\r\n"),
+	"nul":  {0}, // NUL-delimited records, e.g. for xargs -0
+	"none": {},  // lines follow each other directly, e.g. a pure byte pattern
+}
+
+// parseLineEnding returns the terminator named by a --line-ending value.
+func parseLineEnding(s string) ([]byte, error) {
+	eol, ok := lineEndings[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return nil, fmt.Errorf("invalid --line-ending: %q (expected lf, crlf, nul or none)", s)
+	}
+	return eol, nil
+}
+
+// eolName returns the --line-ending name of eol.
+func eolName(eol []byte) string {
+	for name, e := range lineEndings {
+		if bytes.Equal(e, eol) {
+			return name
+		}
+	}
+	return fmt.Sprintf("%q", eol)
+}
+
+// sniffLineEnding examines the last sniffSize bytes of the size bytes in r and
+// reports the terminator of the last complete line (or NUL-delimited record)
+// in them (nil when there is none) and whether the content ends with a
+// terminator. Empty content counts as terminated.
+func sniffLineEnding(r io.ReaderAt, size int64) (eol []byte, terminated bool, err error) {
+	if size == 0 {
+		return nil, true, nil
+	}
+
+	n := int64(sniffSize)
+	if size < n {
+		n = size
+	}
+	buf := make([]byte, n)
+	if _, err := r.ReadAt(buf, size-n); err != nil && err != io.EOF {
+		return nil, false, err
+	}
+
+	last := buf[len(buf)-1]
+	terminated = last == '\n' || last == 0
+	switch i := bytes.LastIndexAny(buf, "\n\x00"); {
+	case i < 0:
+	case buf[i] == 0:
+		eol = lineEndings["nul"]
+	case i > 0 && buf[i-1] == '\r':
+		eol = lineEndings["crlf"]
+	default:
+		eol = lineEndings["lf"]
+	}
+	return eol, terminated, nil
+}
+
+// appendEnding decides the terminator for lines appended to path: explicit if
+// set, otherwise the one path already uses, otherwise LF. terminated reports
+// whether path is missing, empty or ends with a terminator; when it is false
+// a terminator must be written before the first new line so it is not glued
+// to the old last line.
+func appendEnding(path string, explicit []byte) (eol []byte, terminated bool, err error) {
+	detected, terminated, err := sniffFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	switch {
+	case explicit != nil:
+		eol = explicit
+	case detected != nil:
+		eol = detected
+	default:
+		eol = lineEndings["lf"]
+	}
+	return eol, terminated, nil
+}
+
+// sniffFile runs sniffLineEnding over the file at path. A missing file has
+// no terminator and counts as terminated.
+func sniffFile(path string) (eol []byte, terminated bool, err error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	if eol, terminated, err = sniffLineEnding(f, fi.Size()); err != nil {
+		return nil, false, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return eol, terminated, nil
+}
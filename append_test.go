@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSniffLineEnding(t *testing.T) {
+	long := bytes.Repeat([]byte("y"), 2*sniffSize)
+	tests := []struct {
+		name       string
+		content    []byte
+		eol        string
+		terminated bool
+	}{
+		{"empty", nil, "", true},
+		{"lf", []byte("a\nb\n"), "\n", true},
+		{"crlf", []byte("a\r\nb\r\n"), "\r\n", true},
+		{"lf unterminated", []byte("a\nb"), "\n", false},
+		{"crlf unterminated", []byte("a\r\nb"), "\r\n", false},
+		{"no terminator", []byte("abc"), "", false},
+		{"last line decides", []byte("a\r\nb\n"), "\n", true},
+		{"nul", []byte("a\x00b\x00"), "\x00", true},
+		{"nul unterminated", []byte("a\x00b"), "\x00", false},
+		{"beyond window", append([]byte("a\r\n"), long...), "", false},
+	}
+	for _, tt := range tests {
+		eol, terminated, err := sniffLineEnding(bytes.NewReader(tt.content), int64(len(tt.content)))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if string(eol) != tt.eol || terminated != tt.terminated {
+			t.Errorf("%s: got (%q, %v), want (%q, %v)", tt.name, eol, terminated, tt.eol, tt.terminated)
+		}
+	}
+}
+
+func TestRun_AppendJoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		extra    []string
+		want     string
+	}{
+		{"lf", "old\n", nil, "old\nxxxx\nxxxx\n"},
+		{"crlf", "old\r\n", nil, "old\r\nxxxx\r\nxxxx\r\n"},
+		{"lf no trailing terminator", "a\nold", nil, "a\nold\nxxxx\nxxxx\n"},
+		{"crlf no trailing terminator", "a\r\nold", nil, "a\r\nold\r\nxxxx\r\nxxxx\r\n"},
+		{"single unterminated line", "old", nil, "old\nxxxx\nxxxx\n"},
+		{"empty", "", nil, "xxxx\nxxxx\n"},
+		{"explicit matching", "old\r\n", []string{"--line-ending", "crlf"}, "old\r\nxxxx\r\nxxxx\r\n"},
+		{"explicit, no complete line", "old", []string{"--line-ending", "crlf"}, "old\r\nxxxx\r\nxxxx\r\n"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "out.txt")
+		if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+			t.Fatal(err)
+		}
+		args := append([]string{"2", path, "4", "char", "x", "--append"}, tt.extra...)
+		if code := run(args); code != 0 {
+			t.Fatalf("%s: run exited with %d", tt.name, code)
+		}
+		got, _ := os.ReadFile(path)
+		if string(got) != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRun_AppendCreatesMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "new.txt")
+	if code := run([]string{"1", path, "3", "char", "z", "--append", "--line-ending", "crlf"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "zzz\r\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRun_AppendRejectsConflicts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"2", path, "y", "--append"},
+		{"2", path, "--append", "--meta"},
+		{"2", path, "--append", "--split-lines", "1"},
+		{"2", path, "--append", "--line-ending", "crlf"},
+		{"2", path, "--append", "--line-ending", "none"},
+	} {
+		if code := run(args); code != 1 {
+			t.Errorf("%q: exit code %d, want 1", args, code)
+		}
+	}
+	if got, _ := os.ReadFile(path); string(got) != "old\n" {
+		t.Errorf("file changed: %q", got)
+	}
+}
+
+func TestRunDaemon_FollowsExistingLineEnding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("a\r\nold"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Unix(0, 0)
+	clk := &fakeClock{now: start, stopAt: start.Add(time.Second), cancel: cancel}
+
+	cfg := daemonConfig{path: path, width: 3, mode: "char", modeArg: "x", rate: 2}
+	if _, err := runDaemon(ctx, cfg, clk); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "a\r\nold\r\nxxx\r\nxxx\r\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRun_NULDelimitedRecords(t *testing.T) {
+	dir := t.TempDir()
+	for _, mode := range []string{"ascii", "digits"} {
+		path := filepath.Join(dir, mode+".txt")
+		if code := run([]string{"25", path, "y", "30", mode, "--line-ending", "nul", "--line-checksum", "--stamp"}); code != 0 {
+			t.Fatalf("mode=%s: exit code %d", mode, code)
+		}
+		data, _ := os.ReadFile(path)
+		if bytes.IndexByte(data, '\n') >= 0 {
+			t.Errorf("mode=%s: output has a line feed", mode)
+		}
+		records := bytes.Split(bytes.TrimSuffix(data, []byte{0}), []byte{0})
+		if len(records) != 26 || data[len(data)-1] != 0 {
+			t.Fatalf("mode=%s: %d records", mode, len(records))
+		}
+		for i, r := range records[1:] {
+			if len(r) != 30 {
+				t.Errorf("mode=%s: record %d is %d bytes", mode, i+1, len(r))
+			}
+		}
+
+		// The checks find the records by their terminator.
+		for _, args := range [][]string{{"verify-lines", path}, {"verify", "--auto", path}} {
+			if code := run(args); code != 0 {
+				t.Errorf("mode=%s: %s exited with %d", mode, args[0], code)
+			}
+		}
+		data[len(records[0])+1+5] ^= 1
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		output := captureStdout(t)
+		if code := run([]string{"verify-lines", path}); code != 1 {
+			t.Errorf("mode=%s: verify-lines accepted a corrupt record", mode)
+		}
+		if got := output(); !strings.Contains(got, "line 2: checksum mismatch") {
+			t.Errorf("mode=%s: output %q", mode, got)
+		}
+	}
+
+	// Appending follows the file's NUL terminators.
+	path := filepath.Join(dir, "append.txt")
+	if err := os.WriteFile(path, []byte("a\x00b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"2", path, "--append", "5", "digits"}); code != 0 {
+		t.Fatalf("append exit code %d", code)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "a\x00b\x0001234\x0056789\x00" {
+		t.Errorf("appended file %q", got)
+	}
+}
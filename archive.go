@@ -0,0 +1,115 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveKind returns "zip" or "tar" if filename names an archive that split
+// parts should be written into, or "" for plain files.
+func archiveKind(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".zip":
+		return "zip"
+	case ".tar":
+		return "tar"
+	}
+	return ""
+}
+
+// archiveWriter streams members into an archive one at a time. Each member
+// must be closed before the next is created.
+type archiveWriter interface {
+	// create starts a member. size is the exact number of bytes that will be written.
+	create(name string, size int64) (io.WriteCloser, error)
+	// Close finishes the archive without closing the underlying writer.
+	Close() error
+}
+
+// newArchiveWriter returns an archiveWriter of the given kind writing to w.
+func newArchiveWriter(kind string, w io.Writer) archiveWriter {
+	if kind == "tar" {
+		return &tarArchive{tw: tar.NewWriter(w)}
+	}
+	return &zipArchive{zw: zip.NewWriter(w)}
+}
+
+// validateMemberName refuses member names that would escape the extraction directory.
+func validateMemberName(name string) error {
+	clean := path.Clean(filepath.ToSlash(name))
+	if name == "" || path.IsAbs(clean) || filepath.IsAbs(name) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("invalid archive member name %q", name)
+	}
+	return nil
+}
+
+// zipArchive writes deflated zip members. zip.Writer streams every member
+// with a data descriptor, so nothing is buffered beyond the compressor.
+type zipArchive struct {
+	zw *zip.Writer
+}
+
+func (a *zipArchive) create(name string, size int64) (io.WriteCloser, error) {
+	w, err := a.zw.CreateHeader(&zip.FileHeader{
+		Name:     path.Clean(filepath.ToSlash(name)),
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nopWriteCloser{w}, nil
+}
+
+func (a *zipArchive) Close() error { return a.zw.Close() }
+
+// tarArchive writes tar members. The header needs the size up front, which
+// callers compute from the plan rather than by buffering the member.
+type tarArchive struct {
+	tw *tar.Writer
+}
+
+func (a *tarArchive) create(name string, size int64) (io.WriteCloser, error) {
+	err := a.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path.Clean(filepath.ToSlash(name)),
+		Mode:     0644,
+		Size:     size,
+		ModTime:  time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tarMember{a.tw}, nil
+}
+
+func (a *tarArchive) Close() error { return a.tw.Close() }
+
+// tarMember finishes its entry on Close, failing if the planned size was not met.
+type tarMember struct {
+	tw *tar.Writer
+}
+
+func (m tarMember) Write(p []byte) (int, error) {
+	n, err := m.tw.Write(p)
+	if errors.Is(err, tar.ErrWriteTooLong) {
+		err = fmt.Errorf("member larger than planned: %w", err)
+	}
+	return n, err
+}
+
+func (m tarMember) Close() error { return m.tw.Flush() }
+
+// nopWriteCloser adds a no-op Close to a writer.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
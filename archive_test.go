@@ -0,0 +1,138 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// splitFiles runs a plain split into dir and returns the part contents by base name.
+func splitFiles(t *testing.T, dir string, args ...string) map[string]string {
+	t.Helper()
+	if code := run(append([]string{"25", filepath.Join(dir, "fixtures.txt"), "y"}, args...)); code != 0 {
+		t.Fatalf("split run exited with %d", code)
+	}
+	want := map[string]string{}
+	for _, name := range []string{"fixtures-001.txt", "fixtures-002.txt", "fixtures-003.txt"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[name] = string(data)
+	}
+	return want
+}
+
+func TestRun_SplitIntoZip(t *testing.T) {
+	dir := t.TempDir()
+	args := []string{"12", "alpha", "--split-lines", "10", "--comment-every", "7"}
+	want := splitFiles(t, t.TempDir(), args...)
+
+	archive := filepath.Join(dir, "fixtures.zip")
+	manifestFile := filepath.Join(dir, "manifest.json")
+	if code := run(append([]string{"25", archive, "y"}, append(args, "--manifest", manifestFile)...)); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatalf("opening zip: %v", err)
+	}
+	defer zr.Close()
+	if len(zr.File) != len(want) {
+		t.Fatalf("zip has %d members, want %d", len(zr.File), len(want))
+	}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", f.Name, err)
+		}
+		got, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("reading %s: %v", f.Name, err)
+		}
+		if string(got) != want[f.Name] {
+			t.Errorf("member %s differs from the split file", f.Name)
+		}
+	}
+
+	data, err := os.ReadFile(manifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m manifest
+	json.Unmarshal(data, &m)
+	if len(m.Files) != 3 || m.Files[0].Archive != "fixtures.zip" || m.Files[0].Path != "fixtures-001.txt" {
+		t.Errorf("manifest files = %+v", m.Files)
+	}
+}
+
+func TestRun_SplitIntoTar(t *testing.T) {
+	dir := t.TempDir()
+	args := []string{"digits:4+ascii:11", "--split-lines", "10", "--comment-every", "3"}
+	want := splitFiles(t, t.TempDir(), args...)
+
+	archive := filepath.Join(dir, "fixtures.tar")
+	if code := run(append([]string{"25", archive, "y"}, args...)); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tr := tar.NewReader(f)
+	count := 0
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading tar: %v", err)
+		}
+		got, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want[hdr.Name] {
+			t.Errorf("member %s differs from the split file", hdr.Name)
+		}
+		count++
+	}
+	if count != len(want) {
+		t.Errorf("tar has %d members, want %d", count, len(want))
+	}
+}
+
+func TestRun_ArchiveOverwriteAppliesToWholeArchive(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "out.zip")
+	os.WriteFile(archive, []byte("keep"), 0644)
+
+	if code := run([]string{"5", archive, "n", "--split-lines", "2"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if got, _ := os.ReadFile(archive); string(got) != "keep" {
+		t.Error("declined run modified the archive")
+	}
+}
+
+func TestValidateMemberName(t *testing.T) {
+	for _, ok := range []string{"a.txt", "dir/a.txt", "./a.txt"} {
+		if err := validateMemberName(ok); err != nil {
+			t.Errorf("validateMemberName(%q) = %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "/etc/a", "../a", "dir/../../a"} {
+		if err := validateMemberName(bad); err == nil {
+			t.Errorf("validateMemberName(%q) = nil, want error", bad)
+		}
+	}
+}
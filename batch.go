@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// promptsDisabled makes runs fail instead of asking on stdin. Batch jobs set
+// it, since stdin may be the batch spec itself.
+var promptsDisabled bool
+
+// errNoPrompt reports that a run needed an answer it may not prompt for.
+var errNoPrompt = errors.New("cannot prompt in a batch job")
+
+// batchJob is one command line of a batch spec.
+type batchJob struct {
+	line int // one-based line number in the spec
+	args []string
+	err  error // set if the line could not be parsed into a valid job
+}
+
+// readBatch reads a batch spec: one generation command line per line, as it
+// would be typed after "generatelines". Blank lines and lines starting with #
+// are skipped.
+func readBatch(r io.Reader) ([]batchJob, error) {
+	var jobs []batchJob
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		job := batchJob{line: n}
+		job.args, job.err = splitJobLine(text)
+		if job.err == nil {
+			job.err = validateJob(job.args)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, sc.Err()
+}
+
+// splitJobLine splits a job line into arguments at unquoted whitespace.
+// Single and double quotes group text containing spaces; a backslash outside
+// single quotes escapes the next character.
+func splitJobLine(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// validateJob checks that args is a complete generation command line, so a
+// job never falls back to prompting for a missing field.
+func validateJob(args []string) error {
+	positional, _, err := separateOptions(args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 2 {
+		return errors.New("a job needs at least <lines> and <filename>")
+	}
+	_, err = newPreset(args)
+	return err
+}
+
+// runBatchCmd handles "batch <spec|->" and returns the exit code: 0 if every
+// job succeeded, otherwise 1.
+func runBatchCmd(args []string) int {
+	defer useUI(newUI(cliFlags{}))()
+	if len(args) != 1 {
+		cli.Error("batch requires a spec file, or - to read it from stdin")
+		cli.Hint(helpHint())
+		return 1
+	}
+
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+	jobs, err := readBatch(r)
+	if err != nil {
+		cli.Error("reading batch spec: %v", err)
+		return 1
+	}
+
+	promptsDisabled = true
+	defer func() { promptsDisabled = false }()
+
+	failed := 0
+	for _, job := range jobs {
+		if job.err != nil {
+			cli.Error("batch line %d: %v", job.line, job.err)
+			failed++
+			continue
+		}
+		cli.Info("batch line %d: %s %s", job.line, cmdName, strings.Join(job.args, " "))
+		if code := run(job.args); code != 0 {
+			cli.Error("batch line %d: job failed with exit code %d", job.line, code)
+			failed++
+		}
+	}
+
+	cli.Info("Batch: %d jobs, %d failed", len(jobs), failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// captureStderr redirects os.Stderr to a temp file for the rest of the test
+// and returns a function that reads what was written so far.
+func captureStderr(t *testing.T) func() string {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stderr
+	os.Stderr = f
+	t.Cleanup(func() {
+		os.Stderr = old
+		f.Close()
+	})
+	return func() string {
+		data, _ := os.ReadFile(f.Name())
+		return string(data)
+	}
+}
+
+func TestSplitJobLine(t *testing.T) {
+	got, err := splitJobLine(`10 "my file.txt"  y 20 words 'a,b c' --comment-text=\#\ %d`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10", "my file.txt", "y", "20", "words", "a,b c", "--comment-text=# %d"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, bad := range []string{`10 "out.txt`, `10 out.txt\`} {
+		if _, err := splitJobLine(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestRun_BatchFromStdin(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.txt")
+	exists := filepath.Join(dir, "exists.txt")
+	os.WriteFile(exists, []byte("old\n"), 0644)
+
+	spec := strings.Join([]string{
+		"# fixtures",
+		"",
+		"5 " + filepath.Join(dir, "bad.txt") + " y 10 nosuchmode",
+		"3 " + exists + " 10 digits",
+		"   # indented comment",
+		"7",
+		"3 '" + good + "' y 10 digits",
+	}, "\n")
+	specPath := filepath.Join(dir, "spec")
+	os.WriteFile(specPath, []byte(spec), 0644)
+	in, err := os.Open(specPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	oldStdin := os.Stdin
+	os.Stdin = in
+	defer func() { os.Stdin = oldStdin }()
+
+	captureStdout(t)
+	errOut := captureStderr(t)
+	if code := run([]string{"batch", "-"}); code != 1 {
+		t.Errorf("batch with failing jobs exited with %d, want 1", code)
+	}
+
+	if data, err := os.ReadFile(good); err != nil || string(data) != strings.Repeat("0123456789\n", 3) {
+		t.Errorf("good job: %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bad.txt")); err == nil {
+		t.Error("bad job created its file")
+	}
+	if data, _ := os.ReadFile(exists); string(data) != "old\n" {
+		t.Errorf("job without an overwrite answer changed the file: %q", data)
+	}
+
+	stderrText := errOut()
+	for _, want := range []string{
+		`batch line 3: unknown mode "nosuchmode"`,
+		"batch line 4: job failed",
+		"cannot prompt in a batch job",
+		"batch line 6: a job needs at least <lines> and <filename>",
+	} {
+		if !strings.Contains(stderrText, want) {
+			t.Errorf("stderr lacks %q:\n%s", want, stderrText)
+		}
+	}
+	if promptsDisabled {
+		t.Error("promptsDisabled left set after the batch")
+	}
+}
+
+func TestRun_BatchAllGood(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.txt")
+	os.WriteFile(spec, []byte("2 "+filepath.Join(dir, "a.txt")+" y 5 upper\n2 "+filepath.Join(dir, "b.txt")+" y 13 lorem\n"), 0644)
+	captureStdout(t)
+	if code := run([]string{"batch", spec}); code != 0 {
+		t.Fatalf("batch exited with %d", code)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "b.txt")); string(data) != "lorem ipsum d\nsit amet cons\n" {
+		t.Errorf("b.txt: %q", data)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// ANSI SGR codes used for the few highlighted messages.
+const (
+	ansiRed    = "31"
+	ansiGreen  = "32"
+	ansiYellow = "33"
+)
+
+// isTerminal reports whether w is a terminal that understands ANSI escapes.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return isTTY(f) && enableANSI(f)
+}
+
+// checkANSITarget rejects mode=blocks, whose lines are ANSI escape sequences,
+// for targets other than a terminal unless --force-ansi was given.
+func checkANSITarget(mode string, terminal, forceANSI bool) error {
+	if mode != "blocks" || terminal || forceANSI {
+		return nil
+	}
+	return errors.New("mode=blocks writes ANSI escape sequences meant for a terminal; use --force-ansi to write them anyway")
+}
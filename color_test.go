@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsTerminal_FileIsNot(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if isTerminal(f) || isTerminal(&bytes.Buffer{}) {
+		t.Error("isTerminal reported a regular file or buffer as a terminal")
+	}
+}
+
+func TestRun_NoColorOutputWhenRedirected(t *testing.T) {
+	output := captureStdout(t)
+	errOutput := captureStderr(t)
+
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	os.WriteFile(path, []byte("old\n"), 0644)
+	if code := run([]string{"3", path, "y"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+
+	got, gotErr := output(), errOutput()
+	if strings.Contains(got+gotErr, "\x1b[") {
+		t.Errorf("redirected output contains escape codes: %q %q", got, gotErr)
+	}
+	if !strings.Contains(gotErr, "already exists") || !strings.Contains(got, "Done!") {
+		t.Errorf("unexpected output: %q, stderr %q", got, gotErr)
+	}
+}
+
+func TestRun_BlocksNeedForceANSIForFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocks.txt")
+	if code := run([]string{"3", path, "y", "4", "blocks", "digits"}); code != 1 {
+		t.Fatalf("blocks to a file without --force-ansi exited with %d, want 1", code)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Fatal("blocks without --force-ansi created the file")
+	}
+
+	if code := run([]string{"3", path, "y", "4", "blocks", "digits", "--force-ansi"}); code != 0 {
+		t.Fatalf("blocks with --force-ansi exited with %d", code)
+	}
+	data, _ := os.ReadFile(path)
+	want := "\x1b[48;5;048m \x1b[48;5;049m \x1b[48;5;050m \x1b[48;5;051m \x1b[0m\n"
+	if lines := strings.SplitAfter(string(data), "\n"); len(lines) != 4 || lines[0] != want {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	captureStdout(t)
+	if code := run([]string{"sample", "4", "blocks"}); code != 1 {
+		t.Errorf("sample of blocks to a non-terminal exited with %d, want 1", code)
+	}
+}
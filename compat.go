@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// compatTarget is what the compatibility checks know about a run besides its
+// flags.
+type compatTarget struct {
+	filename string
+	mode     string
+	url, fd  bool
+	yes      bool // the overwrite answer is y
+	// existingEOL is the terminator the lines of an --append target already
+	// end with; nil when it is missing or has no complete line.
+	existingEOL []byte
+}
+
+// compatRule is one cell of the option compatibility matrix: when reports
+// whether the combination is present and msg why it is a problem. Errors stop
+// the run before any file is touched; warnings (merely odd combinations) are
+// printed and the run goes on.
+type compatRule struct {
+	when func(f cliFlags, t compatTarget) bool
+	warn bool
+	msg  func(f cliFlags, t compatTarget) string
+}
+
+// says returns a msg that is always s.
+func says(s string) func(cliFlags, compatTarget) string {
+	return func(cliFlags, compatTarget) string { return s }
+}
+
+// noEOL reports whether --line-ending none was given.
+func noEOL(f cliFlags) bool {
+	return f.eol != nil && len(f.eol) == 0
+}
+
+// isNUL reports whether eol is the NUL terminator of --line-ending nul.
+func isNUL(eol []byte) bool {
+	return bytes.Equal(eol, lineEndings["nul"])
+}
+
+// compatRules lists every combination of options the CLI refuses or warns
+// about, errors in the order they are reported.
+var compatRules = []compatRule{
+	{when: func(f cliFlags, _ compatTarget) bool { return f.splitPattern != "" && f.splitLines == 0 },
+		msg: says("--split-pattern requires --split-lines")},
+	{when: func(f cliFlags, _ compatTarget) bool { return f.alignFill != 0 && f.align == 0 },
+		msg: says("--align-fill requires --align")},
+	{when: func(f cliFlags, _ compatTarget) bool { return f.splitLines > 0 && f.align > 0 },
+		msg: says("--align is not supported with --split-lines")},
+	{when: func(f cliFlags, _ compatTarget) bool { return f.splitLines > 0 && f.meta },
+		msg: says("--meta is not supported with --split-lines")},
+	{when: func(f cliFlags, _ compatTarget) bool { return f.gzIndex && f.gzMembers == 0 },
+		msg: says("--gz-index requires --gz-member-lines")},
+	{when: func(f cliFlags, t compatTarget) bool {
+		return f.gzMembers > 0 && (f.splitLines > 0 || len(f.outs) > 0 || f.appendOut || t.url)
+	}, msg: says("--gz-member-lines is not supported with --split-lines, --out, --append or when uploading to a URL")},
+	{when: func(f cliFlags, _ compatTarget) bool {
+		return f.gzMembers > 0 && (f.exactBytes > 0 || f.maxBytes > 0 || f.align > 0)
+	}, msg: says("--gz-member-lines cannot be combined with --exact-bytes, --max-bytes or --align")},
+	{when: func(f cliFlags, _ compatTarget) bool { return f.gzMembers > 0 && (f.meta || f.verifyAfter) },
+		msg: says("--meta and --verify-after are not supported with --gz-member-lines")},
+	{when: func(f cliFlags, t compatTarget) bool { return t.url && (f.meta || f.splitLines > 0) },
+		msg: says("--meta and --split-lines are not supported when uploading to a URL")},
+	{when: func(f cliFlags, t compatTarget) bool {
+		return t.fd && (f.meta || f.splitLines > 0 || f.appendOut || len(f.outs) > 0 || f.gzIndex)
+	}, msg: says("--meta, --split-lines, --append, --out and --gz-index are not supported when writing to a file descriptor")},
+	{when: func(f cliFlags, _ compatTarget) bool { return slices.ContainsFunc(f.outs, isFDTarget) },
+		msg: says("--out does not take a file descriptor; give it as the output filename")},
+	{when: func(f cliFlags, t compatTarget) bool {
+		return f.golden != "" && (f.splitLines > 0 || f.gzMembers > 0 || len(f.outs) > 0 || t.url)
+	}, msg: says("--golden is not supported with --split-lines, --gz-member-lines, --out or when uploading to a URL")},
+	{when: func(f cliFlags, t compatTarget) bool { return f.golden != "" && f.golden == t.filename },
+		msg: says("--golden must name another file than the output")},
+	{when: func(f cliFlags, t compatTarget) bool {
+		return f.summaryJSON != "" && (f.splitLines > 0 || len(f.outs) > 0 || t.url)
+	}, msg: says("--summary-json is not supported with --split-lines, --out or when uploading to a URL")},
+	{when: func(f cliFlags, t compatTarget) bool { return f.summaryJSON != "" && f.summaryJSON == t.filename },
+		msg: says("--summary-json must name another file than the output")},
+	{when: func(f cliFlags, t compatTarget) bool {
+		return len(f.outs) > 0 && (f.splitLines > 0 || t.url || f.appendOut)
+	}, msg: says("--out is not supported with --split-lines, --append or when uploading to a URL")},
+	{when: func(f cliFlags, t compatTarget) bool {
+		return len(f.alsoLink) > 0 && (f.splitLines > 0 || len(f.outs) > 0 || t.url || t.fd)
+	}, msg: says("--also-link is not supported with --split-lines or --out, or when uploading to a URL or writing to a file descriptor")},
+	{when: func(f cliFlags, t compatTarget) bool {
+		return f.tempName != "" && (f.splitLines > 0 || len(f.outs) > 0 || f.appendOut || t.url || t.fd)
+	}, msg: says("--temp-name is not supported with --split-lines, --out or --append, or for a URL or file descriptor")},
+	{when: func(f cliFlags, t compatTarget) bool { return f.appendOut && (f.splitLines > 0 || t.url) },
+		msg: says("--append is not supported with --split-lines or when uploading to a URL")},
+	{when: func(f cliFlags, t compatTarget) bool { return f.bps > 0 && (f.splitLines > 0 || t.url) },
+		msg: says("--bps is not supported with --split-lines or when uploading to a URL")},
+	{when: func(f cliFlags, t compatTarget) bool { return f.wipeOld && (f.appendOut || t.url || t.fd) },
+		msg: says("--wipe-old is not supported with --append or for a URL or file descriptor: nothing is replaced")},
+	{when: func(f cliFlags, _ compatTarget) bool { return f.appendOut && (f.meta || f.manifest != "") },
+		msg: says("--meta and --manifest are not supported with --append")},
+	{when: func(f cliFlags, _ compatTarget) bool { return f.appendOut && f.align > 0 },
+		msg: says("--align is not supported with --append")},
+	{when: func(f cliFlags, _ compatTarget) bool { return f.appendOut && f.continuation != "" },
+		msg: says("--continuation is not supported with --append: the file's last line has no marker")},
+	{when: func(f cliFlags, t compatTarget) bool { return f.appendOut && t.yes },
+		msg: says("--append cannot be combined with an overwrite answer of y")},
+	{when: func(f cliFlags, t compatTarget) bool {
+		return f.appendOut && f.eol != nil && t.existingEOL != nil && !bytes.Equal(f.eol, t.existingEOL)
+	}, msg: func(f cliFlags, t compatTarget) string {
+		return fmt.Sprintf("--line-ending %s does not match the %s endings already in %s, and appending would mix them (leave it out to follow the file)",
+			eolName(f.eol), eolName(t.existingEOL), t.filename)
+	}},
+	{when: func(f cliFlags, _ compatTarget) bool { return f.stamp && (f.appendOut || f.exactBytes > 0) },
+		msg: says("--stamp is not supported with --append or --exact-bytes: the stamp must be the first line, outside the byte budget")},
+	{when: func(f cliFlags, t compatTarget) bool { return f.stamp && (t.mode == "binrec" || noEOL(f)) },
+		msg: says("--stamp needs line endings: it is not supported with mode=binrec or --line-ending none")},
+	{when: func(f cliFlags, t compatTarget) bool { return t.mode == "binrec" && f.eol != nil },
+		msg: says("--line-ending is not supported with mode=binrec (records have no line terminator)")},
+	{when: func(f cliFlags, _ compatTarget) bool { return f.maxBytes > 0 && (f.exactBytes > 0 || f.splitLines > 0) },
+		msg: says("--max-bytes cannot be combined with --exact-bytes or --split-lines")},
+	{when: func(f cliFlags, _ compatTarget) bool { return f.exactBytes > 0 && f.splitLines > 0 },
+		msg: says("--exact-bytes cannot be combined with --split-lines")},
+	{when: func(f cliFlags, _ compatTarget) bool { return noEOL(f) && f.align > 0 },
+		msg: says("--line-ending none cannot be combined with --align because padding requires line boundaries")},
+	{when: func(f cliFlags, _ compatTarget) bool { return noEOL(f) && f.commentEvery > 0 },
+		msg: says("--line-ending none cannot be combined with --comment-every because comment lines would run into the data")},
+	{when: func(f cliFlags, _ compatTarget) bool { return noEOL(f) && f.continuation != "" },
+		msg: says("--line-ending none cannot be combined with --continuation because there is no line break to continue over")},
+	{when: func(f cliFlags, _ compatTarget) bool { return noEOL(f) && f.linePattern.Enabled() },
+		msg: says("--line-ending none cannot be combined with --line-pattern because blank lines would be empty")},
+
+	{warn: true, when: func(f cliFlags, _ compatTarget) bool { return f.wipeOld && f.tempName != "" && len(f.alsoLink) == 0 },
+		msg: says("--wipe-old has no effect with --temp-name: the file is always new")},
+	{warn: true, when: func(f cliFlags, _ compatTarget) bool { return noEOL(f) && f.lineChecksum },
+		msg: says("--line-checksum with --line-ending none: the lines cannot be told apart, so verify cannot check them")},
+	{warn: true, when: func(f cliFlags, _ compatTarget) bool { return noEOL(f) && f.sortOrder != "" },
+		msg: says("--sort with --line-ending none: the sorted lines are written without breaks between them")},
+	{warn: true, when: func(f cliFlags, _ compatTarget) bool { return f.flushSet && f.flushEvery > 0 && isNUL(f.eol) },
+		msg: says("--flush-every with --line-ending nul: a follower such as tail -f sees the records run together, without line breaks")},
+	{warn: true, when: func(f cliFlags, _ compatTarget) bool { return f.meta && f.noMeta },
+		msg: says("--no-meta overrides --meta: no .meta sidecar is written")},
+	{warn: true, when: func(f cliFlags, _ compatTarget) bool { return f.keepGoing && len(f.outs) == 0 },
+		msg: says("--keep-going has no effect without --out")},
+	{warn: true, when: func(f cliFlags, _ compatTarget) bool { return f.quiet && f.verbose },
+		msg: says("--quiet overrides --verbose: only warnings and errors are printed")},
+}
+
+// checkCompat runs f and t through compatRules, returning the first error and
+// every warning.
+func checkCompat(f cliFlags, t compatTarget) (warnings []string, err error) {
+	for _, r := range compatRules {
+		if !r.when(f, t) {
+			continue
+		}
+		if !r.warn {
+			return nil, errors.New(r.msg(f, t))
+		}
+		warnings = append(warnings, r.msg(f, t))
+	}
+	return warnings, nil
+}
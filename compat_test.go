@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckCompat(t *testing.T) {
+	lf, crlf, none, nul := lineEndings["lf"], lineEndings["crlf"], lineEndings["none"], lineEndings["nul"]
+	file := compatTarget{filename: "out.txt", mode: "ascii"}
+	for _, tt := range []struct {
+		name  string
+		flags cliFlags
+		t     compatTarget
+		err   string // "" = accepted
+		warn  string // "" = no warning
+	}{
+		{"plain", cliFlags{}, file, "", ""},
+		{"crlf append to lf", cliFlags{appendOut: true, eol: crlf}, compatTarget{filename: "out.txt", existingEOL: lf},
+			"--line-ending crlf does not match the lf endings already in out.txt", ""},
+		{"lf append to crlf", cliFlags{appendOut: true, eol: lf}, compatTarget{filename: "out.txt", existingEOL: crlf},
+			"--line-ending lf does not match the crlf endings", ""},
+		{"crlf append to crlf", cliFlags{appendOut: true, eol: crlf}, compatTarget{existingEOL: crlf}, "", ""},
+		{"nul append to lf", cliFlags{appendOut: true, eol: nul}, compatTarget{filename: "out.txt", existingEOL: lf},
+			"--line-ending nul does not match the lf endings", ""},
+		{"lf append to nul", cliFlags{appendOut: true, eol: lf}, compatTarget{filename: "out.txt", existingEOL: nul},
+			"--line-ending lf does not match the nul endings", ""},
+		{"crlf append to new file", cliFlags{appendOut: true, eol: crlf}, file, "", ""},
+		{"no eol with align", cliFlags{eol: none, align: 4096}, file,
+			"--line-ending none cannot be combined with --align because padding requires line boundaries", ""},
+		{"no eol with comments", cliFlags{eol: none, commentEvery: 10}, file, "--comment-every because comment lines", ""},
+		{"no eol with continuation", cliFlags{eol: none, continuation: `\`}, file, "--continuation because", ""},
+		{"no eol with line pattern", cliFlags{eol: none, linePattern: "CB"}, file, "--line-pattern because blank lines", ""},
+		{"stamp with append", cliFlags{stamp: true, appendOut: true}, file, "--stamp is not supported with --append", ""},
+		{"stamp with binrec", cliFlags{stamp: true}, compatTarget{mode: "binrec"}, "--stamp needs line endings", ""},
+		{"temp name with split", cliFlags{tempName: "ci/out", splitLines: 10}, file, "--temp-name is not supported", ""},
+		{"bps with split", cliFlags{bps: 1024, splitLines: 10}, file, "--bps is not supported", ""},
+		{"bps on url", cliFlags{bps: 1024}, compatTarget{url: true}, "--bps is not supported", ""},
+		{"wipe with append", cliFlags{wipeOld: true, appendOut: true}, file, "--wipe-old is not supported", ""},
+		{"wipe on url", cliFlags{wipeOld: true}, compatTarget{url: true}, "--wipe-old is not supported", ""},
+		{"wipe with temp name", cliFlags{wipeOld: true, tempName: "ci/out"}, file, "", "--wipe-old has no effect"},
+		{"lf with align", cliFlags{eol: lf, align: 4096}, file, "", ""},
+		{"binrec with eol", cliFlags{eol: lf}, compatTarget{mode: "binrec"}, "not supported with mode=binrec", ""},
+		{"max and exact bytes", cliFlags{maxBytes: 10, exactBytes: 10}, file, "--max-bytes cannot be combined", ""},
+		{"append with y", cliFlags{appendOut: true}, compatTarget{yes: true}, "an overwrite answer of y", ""},
+		{"also link with out", cliFlags{alsoLink: []string{"a"}, outs: []string{"b.txt"}}, file, "--also-link is not supported", ""},
+		{"also link to fd", cliFlags{alsoLink: []string{"a"}}, compatTarget{fd: true}, "--also-link is not supported", ""},
+		{"golden on the output", cliFlags{golden: "out.txt"}, file, "--golden must name another file", ""},
+		{"fd out", cliFlags{outs: []string{"fd:3"}}, file, "--out does not take a file descriptor", ""},
+		{"gz members on url", cliFlags{gzMembers: 5}, compatTarget{url: true}, "--gz-member-lines is not supported", ""},
+		{"no eol with checksums", cliFlags{eol: none, lineChecksum: true}, file, "", "--line-checksum with --line-ending none"},
+		{"no eol sorted", cliFlags{eol: none, sortOrder: "asc"}, file, "", "--sort with --line-ending none"},
+		{"nul with flushing", cliFlags{eol: nul, flushEvery: 1, flushSet: true}, file, "", "--flush-every with --line-ending nul"},
+		{"nul with comments", cliFlags{eol: nul, commentEvery: 10}, file, "", ""},
+		{"meta and no-meta", cliFlags{meta: true, noMeta: true}, file, "", "--no-meta overrides --meta"},
+		{"keep going alone", cliFlags{keepGoing: true}, file, "", "--keep-going has no effect"},
+		{"keep going with out", cliFlags{keepGoing: true, outs: []string{"b.txt"}}, file, "", ""},
+		{"quiet and verbose", cliFlags{quiet: true, verbose: true}, file, "", "--quiet overrides --verbose"},
+	} {
+		warnings, err := checkCompat(tt.flags, tt.t)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: %v, want %q", tt.name, err, tt.err)
+		}
+		if got := strings.Join(warnings, "\n"); tt.warn == "" && got != "" || !strings.Contains(got, tt.warn) {
+			t.Errorf("%s: warnings %q, want %q", tt.name, got, tt.warn)
+		}
+	}
+}
+
+func TestRun_CompatCheckedBeforeWriting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"3", path, "y", "10", "--line-ending", "none", "--align", "64"}); code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "old\n" {
+		t.Errorf("file changed: %q", got)
+	}
+
+	errOutput := captureStderr(t)
+	if code := run([]string{"3", path, "y", "10", "--keep-going"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if got := errOutput(); !strings.Contains(got, "WARNING: --keep-going has no effect without --out") {
+		t.Errorf("stderr %q", got)
+	}
+}
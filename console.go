@@ -0,0 +1,42 @@
+package main
+
+// utf8CodePage is the Windows code page identifier for UTF-8.
+const utf8CodePage = 65001
+
+// consoleAPI is the part of the Windows console API needed to switch the
+// console to UTF-8. It sits behind an interface so the decision logic can be
+// tested on every platform.
+type consoleAPI interface {
+	// IsConsole reports whether the process is attached to a console window.
+	IsConsole() bool
+	InputCP() uint32
+	OutputCP() uint32
+	SetInputCP(cp uint32) error
+	SetOutputCP(cp uint32) error
+}
+
+// enableUTF8Console switches a legacy-code-page console to UTF-8 so prompts,
+// echoed filenames and error messages render correctly. File output is raw
+// UTF-8 regardless. The returned function restores the original code pages,
+// since they are shared with the parent shell.
+func enableUTF8Console(api consoleAPI) (restore func()) {
+	restore = func() {}
+	if !api.IsConsole() {
+		return restore
+	}
+
+	inCP, outCP := api.InputCP(), api.OutputCP()
+	var undo []func()
+	if outCP != utf8CodePage && api.SetOutputCP(utf8CodePage) == nil {
+		undo = append(undo, func() { api.SetOutputCP(outCP) })
+	}
+	if inCP != utf8CodePage && api.SetInputCP(utf8CodePage) == nil {
+		undo = append(undo, func() { api.SetInputCP(inCP) })
+	}
+
+	return func() {
+		for _, u := range undo {
+			u()
+		}
+	}
+}
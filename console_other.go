@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// setupConsole is a no-op outside Windows; terminals there are UTF-8 already.
+func setupConsole() (restore func()) {
+	return func() {}
+}
+
+// enableANSI reports that terminals outside Windows interpret ANSI escapes.
+func enableANSI(f *os.File) bool {
+	return true
+}
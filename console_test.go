@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+// fakeConsole records code page changes made through consoleAPI.
+type fakeConsole struct {
+	console     bool
+	in, out     uint32
+	setInCalls  int
+	setOutCalls int
+}
+
+func (f *fakeConsole) IsConsole() bool  { return f.console }
+func (f *fakeConsole) InputCP() uint32  { return f.in }
+func (f *fakeConsole) OutputCP() uint32 { return f.out }
+
+func (f *fakeConsole) SetInputCP(cp uint32) error {
+	f.setInCalls++
+	f.in = cp
+	return nil
+}
+
+func (f *fakeConsole) SetOutputCP(cp uint32) error {
+	f.setOutCalls++
+	f.out = cp
+	return nil
+}
+
+func TestEnableUTF8Console_LegacyCodePage(t *testing.T) {
+	c := &fakeConsole{console: true, in: 850, out: 437}
+	restore := enableUTF8Console(c)
+	if c.in != utf8CodePage || c.out != utf8CodePage {
+		t.Fatalf("expected UTF-8 code pages, got in=%d out=%d", c.in, c.out)
+	}
+
+	restore()
+	if c.in != 850 || c.out != 437 {
+		t.Fatalf("expected original code pages restored, got in=%d out=%d", c.in, c.out)
+	}
+}
+
+func TestEnableUTF8Console_AlreadyUTF8(t *testing.T) {
+	c := &fakeConsole{console: true, in: utf8CodePage, out: utf8CodePage}
+	enableUTF8Console(c)()
+	if c.setInCalls != 0 || c.setOutCalls != 0 {
+		t.Fatalf("expected no code page changes, got %d input / %d output", c.setInCalls, c.setOutCalls)
+	}
+}
+
+func TestEnableUTF8Console_NotAConsole(t *testing.T) {
+	// Redirected output (pipes, files) must be left alone.
+	c := &fakeConsole{console: false, in: 437, out: 437}
+	enableUTF8Console(c)()
+	if c.setInCalls != 0 || c.setOutCalls != 0 {
+		t.Fatalf("expected no code page changes without a console")
+	}
+}
@@ -0,0 +1,109 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32               = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleMode     = kernel32.NewProc("GetConsoleMode")
+	procGetConsoleCP       = kernel32.NewProc("GetConsoleCP")
+	procGetConsoleOutputCP = kernel32.NewProc("GetConsoleOutputCP")
+	procSetConsoleCP       = kernel32.NewProc("SetConsoleCP")
+	procSetConsoleOutputCP = kernel32.NewProc("SetConsoleOutputCP")
+	procSetConsoleMode     = kernel32.NewProc("SetConsoleMode")
+
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+// enableVirtualTerminalProcessing makes the console interpret ANSI escapes.
+const enableVirtualTerminalProcessing = 0x0004
+
+// winConsole implements consoleAPI with kernel32 calls.
+type winConsole struct{}
+
+func (winConsole) IsConsole() bool {
+	var mode uint32
+	for _, h := range []syscall.Handle{syscall.Stdout, syscall.Stderr} {
+		if r, _, _ := procGetConsoleMode.Call(uintptr(h), uintptr(unsafe.Pointer(&mode))); r != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (winConsole) InputCP() uint32 {
+	r, _, _ := procGetConsoleCP.Call()
+	return uint32(r)
+}
+
+func (winConsole) OutputCP() uint32 {
+	r, _, _ := procGetConsoleOutputCP.Call()
+	return uint32(r)
+}
+
+func (winConsole) SetInputCP(cp uint32) error {
+	if r, _, err := procSetConsoleCP.Call(uintptr(cp)); r == 0 {
+		return err
+	}
+	return nil
+}
+
+func (winConsole) SetOutputCP(cp uint32) error {
+	if r, _, err := procSetConsoleOutputCP.Call(uintptr(cp)); r == 0 {
+		return err
+	}
+	return nil
+}
+
+// setupConsole switches the console to UTF-8 for the lifetime of the process.
+func setupConsole() (restore func()) {
+	return enableUTF8Console(winConsole{})
+}
+
+// isTTY reports whether f is a console; NUL, files and pipes are not.
+func isTTY(f *os.File) bool {
+	var mode uint32
+	r, _, _ := procGetConsoleMode.Call(f.Fd(), uintptr(unsafe.Pointer(&mode)))
+	return r != 0
+}
+
+// enableANSI turns on escape sequence processing for the console behind f and
+// reports whether it is active. Consoles that refuse (pre-Windows 10) get no color.
+func enableANSI(f *os.File) bool {
+	h := f.Fd()
+	var mode uint32
+	if r, _, _ := procGetConsoleMode.Call(h, uintptr(unsafe.Pointer(&mode))); r == 0 {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	r, _, _ := procSetConsoleMode.Call(h, uintptr(mode|enableVirtualTerminalProcessing))
+	return r != 0
+}
+
+// consoleScreenBufferInfo mirrors CONSOLE_SCREEN_BUFFER_INFO.
+type consoleScreenBufferInfo struct {
+	sizeX, sizeY     int16
+	cursorX, cursorY int16
+	attributes       uint16
+	windowLeft       int16
+	windowTop        int16
+	windowRight      int16
+	windowBottom     int16
+	maxX, maxY       int16
+}
+
+func (stdoutTerminal) Columns() (int, bool) {
+	var info consoleScreenBufferInfo
+	r, _, _ := procGetConsoleScreenBufferInfo.Call(os.Stdout.Fd(), uintptr(unsafe.Pointer(&info)))
+	if r == 0 {
+		return 0, false
+	}
+	return int(info.windowRight-info.windowLeft) + 1, true
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+	"github.com/Bjornsrud/GenerateLines/genlines/humanize"
+)
+
+const (
+	defaultDaemonRate = 10.0
+	defaultDaemonKeep = 5
+)
+
+// daemonConfig configures a daemon run.
+type daemonConfig struct {
+	path       string
+	width      int
+	mode       string
+	modeArg    string
+	eol        []byte  // terminator; nil = the one the file already uses, else LF
+	rate       float64 // lines per second; 0 = paced by bps alone
+	bps        int64   // bytes per second; 0 = no limit
+	rotateSize int64   // rotate once the file reaches this many bytes (0 = never)
+	keep       int     // rotated files to keep (app.log.1 .. app.log.<keep>)
+	// flushEvery is how many lines go to the buffer before it is flushed to
+	// the file; 0 = only when it is full, before a rotation and at the end.
+	flushEvery int
+	wipeOld    bool // wipe rotated files before they are removed
+}
+
+// daemonStats summarizes a daemon run.
+type daemonStats struct {
+	lines     int64
+	bytes     int64
+	rotations int
+	flushes   int64
+	elapsed   time.Duration
+}
+
+// clock abstracts time for pacing so the daemon loop can be tested without real sleeps.
+type clock interface {
+	Now() time.Time
+	// Sleep waits for d or until ctx is done, whichever comes first.
+	Sleep(ctx context.Context, d time.Duration)
+}
+
+// realClock is the wall clock.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// parseDaemonArgs parses "daemon <filename> [width] [mode] [modeArg]" (without the
+// leading "daemon") together with the daemon options.
+func parseDaemonArgs(args []string, flags cliFlags) (daemonConfig, error) {
+	cfg := daemonConfig{
+		width:      defaultWidth,
+		mode:       "ascii",
+		rate:       flags.rate,
+		bps:        flags.bps,
+		rotateSize: flags.rotateSize,
+		keep:       flags.keep,
+		eol:        flags.eol,
+		flushEvery: flags.flushEvery,
+		wipeOld:    flags.wipeOld,
+	}
+	// The lines are paced, so each goes out as it is written unless
+	// --flush-every says otherwise: a tail -f of the file keeps up.
+	if !flags.flushSet {
+		cfg.flushEvery = 1
+	}
+	if cfg.rate == 0 && cfg.bps == 0 {
+		cfg.rate = defaultDaemonRate
+	}
+	if !flags.keepSet {
+		cfg.keep = defaultDaemonKeep
+	}
+
+	if len(args) == 0 {
+		return cfg, errors.New("daemon requires a filename")
+	}
+	cfg.path = args[0]
+	rest := args[1:]
+
+	if len(rest) >= 1 {
+		if n, err := parsePositiveInt(rest[0]); err == nil {
+			cfg.width = n
+			rest = rest[1:]
+		} else if isTermWidth(rest[0]) {
+			if cfg.width, err = resolveTermWidth(rest[0]); err != nil {
+				return cfg, err
+			}
+			rest = rest[1:]
+		}
+	}
+	if len(rest) >= 1 {
+		mode, implied, err := normalizeMode(rest[0])
+		if err != nil {
+			return cfg, err
+		}
+		cfg.mode, cfg.modeArg = mode, implied
+		rest = rest[1:]
+	}
+	if len(rest) >= 1 {
+		var err error
+		if cfg.modeArg, err = mergeModeArg(cfg.modeArg, rest[0]); err != nil {
+			return cfg, err
+		}
+	}
+	if flags.seedSet {
+		arg, err := genlines.SeedModeArg(cfg.mode, cfg.modeArg, flags.seed, genlines.SeedLabelContent)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.modeArg = arg
+	}
+
+	return cfg, checkANSITarget(cfg.mode, false, flags.forceANSI)
+}
+
+// runDaemonCmd runs the daemon subcommand until SIGINT/SIGTERM and returns the exit code.
+func runDaemonCmd(args []string, flags cliFlags) int {
+	cfg, err := parseDaemonArgs(args, flags)
+	if err != nil {
+		cli.Error("%v", err)
+		cli.Hint(helpHint())
+		return 1
+	}
+	if !guardTargets(flags, cfg.path) {
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rotate := "never"
+	if cfg.rotateSize > 0 {
+		rotate = fmt.Sprintf("at %d bytes, keeping %d", cfg.rotateSize, cfg.keep)
+	}
+	var pace []string
+	if cfg.rate > 0 {
+		pace = append(pace, humanize.Fixed(cfg.rate, -1)+" lines/s")
+	}
+	if cfg.bps > 0 {
+		pace = append(pace, humanize.Bytes(cfg.bps)+"/s")
+	}
+	cli.Info("Appending %s (width=%d, mode=%s) -> %s, rotating %s. Press Ctrl-C to stop.",
+		strings.Join(pace, ", at most "), cfg.width, cfg.mode, cfg.path, rotate)
+	if cfg.rate > 0 && cfg.bps > 0 {
+		eol := cfg.eol
+		if eol == nil {
+			eol = lineEndings["lf"]
+		}
+		cli.Info("%s", stricterLimit(cfg.rate, cfg.width+len(eol), cfg.bps))
+	}
+
+	st, err := runDaemon(ctx, cfg, realClock{})
+	cli.Info("Stopped after %s: %s lines, %s bytes, %d rotations.",
+		humanize.Duration(st.elapsed), humanize.Count(st.lines), humanize.Count(st.bytes), st.rotations)
+	cli.Verbose("Flushed %d times (every %d lines, before rotations and at the end)", st.flushes, cfg.flushEvery)
+	if cfg.bps > 0 {
+		cli.Info("Throughput: %s/s on average (--bps %s/s)",
+			humanize.Bytes(humanize.PerSecond(st.bytes, st.elapsed)), humanize.Bytes(cfg.bps))
+	}
+	if err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+	return 0
+}
+
+// runDaemon appends paced lines to cfg.path until ctx is cancelled, rotating the
+// file whenever it reaches cfg.rotateSize. Lines are paced at cfg.rate, and
+// the bytes, with cfg.bps, by a throttle under the buffer. The buffer is flushed every
+// cfg.flushEvery lines, and before a rotation so nothing is lost across the
+// rename.
+func runDaemon(ctx context.Context, cfg daemonConfig, clk clock) (daemonStats, error) {
+	var st daemonStats
+
+	gen, err := genlines.NewModeGenerator(cfg.mode, cfg.modeArg)
+	if err != nil {
+		return st, err
+	}
+
+	eol, terminated, err := appendEnding(cfg.path, cfg.eol)
+	if err != nil {
+		return st, err
+	}
+	f, size, err := openAppend(cfg.path)
+	if err != nil {
+		return st, err
+	}
+	var throttle *throttledWriter
+	if cfg.bps > 0 {
+		throttle = newThrottledWriter(ctx, f, cfg.bps, clk)
+	}
+	// sink returns what the buffer writes to f through.
+	sink := func(f *os.File) io.Writer {
+		if throttle == nil {
+			return f
+		}
+		throttle.w = f
+		return throttle
+	}
+	w := bufio.NewWriterSize(sink(f), 64*1024)
+	if !terminated {
+		n, _ := w.Write(eol)
+		size += int64(n)
+		st.bytes += int64(n)
+	}
+
+	var interval time.Duration
+	if cfg.rate > 0 {
+		interval = time.Duration(float64(time.Second) / cfg.rate)
+	}
+	start := clk.Now()
+
+	// flush pushes the buffered lines, if any, to the current file.
+	flush := func() error {
+		if w.Buffered() == 0 {
+			return nil
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		st.flushes++
+		return nil
+	}
+	// closeOut flushes and closes the current file.
+	closeOut := func() error {
+		if err := flush(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	for {
+		due := start.Add(time.Duration(st.lines) * interval)
+		if wait := due.Sub(clk.Now()); wait > 0 {
+			clk.Sleep(ctx, wait)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		// Line and terminator go to the buffer separately: joining them
+		// would copy every line just to add a byte or two.
+		n, err := w.WriteString(gen.NextLine(cfg.width))
+		if err == nil {
+			var m int
+			m, err = w.Write(eol)
+			n += m
+		}
+		size += int64(n)
+		st.bytes += int64(n)
+		if err != nil {
+			f.Close()
+			return st, fmt.Errorf("writing %s: %w", cfg.path, err)
+		}
+		st.lines++
+		if cfg.flushEvery > 0 && st.lines%int64(cfg.flushEvery) == 0 {
+			if err := flush(); err != nil {
+				f.Close()
+				return st, fmt.Errorf("writing %s: %w", cfg.path, err)
+			}
+		}
+
+		if cfg.rotateSize > 0 && size >= cfg.rotateSize {
+			if err := closeOut(); err != nil {
+				return st, fmt.Errorf("writing %s: %w", cfg.path, err)
+			}
+			if err := rotateFiles(cfg.path, cfg.keep, cfg.wipeOld); err != nil {
+				return st, err
+			}
+			st.rotations++
+			if f, size, err = openAppend(cfg.path); err != nil {
+				return st, err
+			}
+			w.Reset(sink(f))
+		}
+	}
+
+	st.elapsed = clk.Now().Sub(start)
+	if err := closeOut(); err != nil {
+		return st, fmt.Errorf("writing %s: %w", cfg.path, err)
+	}
+	return st, nil
+}
+
+// openAppend opens path for appending, creating it if needed, and returns its current size.
+func openAppend(path string) (*os.File, int64, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, 0, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, fi.Size(), nil
+}
+
+// rotateFiles shifts path.N to path.N+1 (dropping anything beyond keep) and moves path to path.1.
+// With keep == 0 the current file is simply removed. With wipeOld, the file
+// that is dropped is wiped first.
+func rotateFiles(path string, keep int, wipeOld bool) error {
+	if keep == 0 {
+		return removeWiped(path, wipeOld)
+	}
+	if err := removeWiped(fmt.Sprintf("%s.%d", path, keep), wipeOld); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for i := keep - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", path, i)
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", path, i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(path, path+".1")
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// fakeClock advances only when slept on and cancels the run once stopAt is reached.
+type fakeClock struct {
+	now    time.Time
+	stopAt time.Time
+	cancel context.CancelFunc
+	slept  time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) {
+	c.now = c.now.Add(d)
+	c.slept += d
+	if !c.now.Before(c.stopAt) {
+		c.cancel()
+	}
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestRunDaemon_RotatesWithoutLosingLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := &fakeClock{now: start, stopAt: start.Add(4500 * time.Millisecond), cancel: cancel}
+
+	// 10 lines/s of 10 bytes each, rotating every 100 bytes (10 lines).
+	cfg := daemonConfig{path: path, width: 9, mode: "digits", rate: 10, rotateSize: 100, keep: 2}
+	st, err := runDaemon(ctx, cfg, clk)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if st.lines != 45 || st.bytes != 450 || st.rotations != 4 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+	if clk.slept != 4500*time.Millisecond {
+		t.Fatalf("expected paced sleeps totalling 4.5s, got %s", clk.slept)
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected rotated files beyond keep=2 to be deleted, stat err=%v", err)
+	}
+
+	// The surviving files hold the last 25 lines in order: .2 (lines 21-30), .1 (31-40), current (41-45).
+	var got []string
+	for _, p := range []string{path + ".2", path + ".1", path} {
+		got = append(got, readLines(t, p)...)
+	}
+	if len(got) != 25 {
+		t.Fatalf("expected 25 surviving lines, got %d", len(got))
+	}
+	for i, line := range got {
+		n := 20 + i // zero-based line index in the whole run
+		want := ""
+		for c := 0; c < 9; c++ {
+			want += string(rune('0' + (n*9+c)%10))
+		}
+		if line != want {
+			t.Fatalf("surviving line %d: want %q, got %q", i, want, line)
+		}
+	}
+}
+
+func TestRunDaemon_AppendsToExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("existing\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Unix(0, 0)
+	clk := &fakeClock{now: start, stopAt: start.Add(time.Second), cancel: cancel}
+
+	cfg := daemonConfig{path: path, width: 5, mode: "char", modeArg: "x", rate: 4}
+	if _, err := runDaemon(ctx, cfg, clk); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	got := readLines(t, path)
+	want := []string{"existing", "xxxxx", "xxxxx", "xxxxx", "xxxxx"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected file contents: %q", got)
+	}
+}
+
+func TestParseDaemonArgs(t *testing.T) {
+	cfg, err := parseDaemonArgs([]string{"app.log", "120", "upper"}, cliFlags{rate: 50, rotateSize: 1 << 20})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if cfg.path != "app.log" || cfg.width != 120 || cfg.mode != "upper" || cfg.rate != 50 ||
+		cfg.rotateSize != 1<<20 || cfg.keep != defaultDaemonKeep {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	if _, err := parseDaemonArgs(nil, cliFlags{}); err == nil {
+		t.Fatalf("expected error without filename")
+	}
+	if _, err := parseDaemonArgs([]string{"app.log", "80", "pi"}, cliFlags{}); err != nil {
+		t.Fatalf("pi mode: %v", err)
+	}
+}
+
+func TestRotateFiles_KeepZeroRemoves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("x\n"), 0644)
+	if err := rotateFiles(path, 0, false); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if fileExists(path) || fileExists(path+".1") {
+		t.Fatalf("expected no files to remain with keep=0")
+	}
+}
+
+func TestRunDaemon_MatchesJoinedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Unix(0, 0)
+	clk := &fakeClock{now: start, stopAt: start.Add(3 * time.Second), cancel: cancel}
+
+	cfg := daemonConfig{path: path, width: 80, mode: "random", modeArg: "9", rate: 1000, eol: []byte("\r\n")}
+	st, err := runDaemon(ctx, cfg, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The old path joined each line and its terminator before writing.
+	gen, err := genlines.NewModeGenerator(cfg.mode, cfg.modeArg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want strings.Builder
+	for range st.lines {
+		want.WriteString(gen.NextLine(cfg.width) + string(cfg.eol))
+	}
+	got, _ := os.ReadFile(path)
+	if st.lines != 3000 || string(got) != want.String() {
+		t.Errorf("%d lines, %d bytes differ from joined lines (%d bytes)", st.lines, len(got), want.Len())
+	}
+}
+
+// watchClock is a fakeClock that records the size of a file, as tail -f
+// would see it, whenever the daemon sleeps.
+type watchClock struct {
+	fakeClock
+	path  string
+	sizes []int64
+}
+
+func (c *watchClock) Sleep(ctx context.Context, d time.Duration) {
+	var size int64
+	if fi, err := os.Stat(c.path); err == nil {
+		size = fi.Size()
+	}
+	c.sizes = append(c.sizes, size)
+	c.fakeClock.Sleep(ctx, d)
+}
+
+func TestRunDaemon_FlushEvery(t *testing.T) {
+	for _, tt := range []struct {
+		every   int
+		sizes   string
+		flushes int64
+	}{
+		{1, "6 12 18 24 30 36 42 48", 8},
+		{3, "0 0 18 18 18 36 36 36", 3},
+		{0, "0 0 0 0 0 0 0 0", 1},
+	} {
+		path := filepath.Join(t.TempDir(), "app.log")
+		ctx, cancel := context.WithCancel(context.Background())
+		start := time.Unix(0, 0)
+		clk := &watchClock{fakeClock: fakeClock{now: start, stopAt: start.Add(2 * time.Second), cancel: cancel}, path: path}
+
+		cfg := daemonConfig{path: path, width: 5, mode: "char", modeArg: "x", rate: 4, flushEvery: tt.every}
+		st, err := runDaemon(ctx, cfg, clk)
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Trim(fmt.Sprint(clk.sizes), "[]"); got != tt.sizes || st.flushes != tt.flushes {
+			t.Errorf("every %d: sizes %s, %d flushes; want %s, %d", tt.every, got, st.flushes, tt.sizes, tt.flushes)
+		}
+		if fi, _ := os.Stat(path); fi.Size() != 48 {
+			t.Errorf("every %d: %d bytes at the end", tt.every, fi.Size())
+		}
+	}
+
+	cfg, err := parseDaemonArgs([]string{"app.log"}, cliFlags{})
+	if err != nil || cfg.flushEvery != 1 {
+		t.Errorf("default flushEvery %d, %v", cfg.flushEvery, err)
+	}
+	cfg, err = parseDaemonArgs([]string{"app.log"}, cliFlags{flushEvery: 0, flushSet: true})
+	if err != nil || cfg.flushEvery != 0 {
+		t.Errorf("--flush-every 0: %d, %v", cfg.flushEvery, err)
+	}
+}
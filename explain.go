@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// source says where a run parameter got its value.
+type source int
+
+const (
+	fromDefault    source = iota
+	fromPositional        // a positional argument
+	fromPrompt            // an interactive prompt
+	fromFlag              // a --option
+	fromEnv               // an environment variable
+	fromPicked            // chosen by the program, e.g. a fresh seed
+	fromFile              // detected in the file appended to
+	fromMatch             // measured in a reference file (match:<path>)
+)
+
+// provenance is where one parameter came from: its source, with the
+// position, option or environment variable that set it.
+type provenance struct {
+	from source
+	arg  int    // fromPositional: position among the positional arguments, from 1
+	name string // the option (fromFlag, without dashes), variable (fromEnv) or reference file (fromMatch)
+}
+
+// prompted is the provenance of a value typed at a prompt.
+var prompted = provenance{from: fromPrompt}
+
+// positional returns the provenance of the positional argument at position n.
+func positional(n int) provenance {
+	return provenance{from: fromPositional, arg: n}
+}
+
+func (p provenance) String() string {
+	switch p.from {
+	case fromPositional:
+		return fmt.Sprintf("positional arg %d", p.arg)
+	case fromPrompt:
+		return "prompt"
+	case fromFlag:
+		return "flag --" + p.name
+	case fromEnv:
+		return "env " + p.name
+	case fromPicked:
+		return "picked"
+	case fromFile:
+		return "existing file"
+	case fromMatch:
+		return "match " + p.name
+	}
+	return "default"
+}
+
+// argSources records where getArgsOrPrompt took each positional parameter
+// from. The zero value is all defaults.
+type argSources struct {
+	lines, filename, overwrite, width, mode, modeArg provenance
+}
+
+// givenFlag is an option as given on the command line, for --explain.
+type givenFlag struct {
+	name  string // canonical name, without dashes
+	value string // "" for options without a value
+}
+
+// param is one row of --explain: a parameter, its value and its provenance.
+type param struct {
+	name  string
+	value string
+	src   provenance
+}
+
+// explainParams lists every parameter of a run with its value and provenance:
+// the positional ones first, then the settings that have a default or an
+// environment variable, then every other option given.
+func explainParams(p runParams, src argSources, flags cliFlags) []param {
+	overwrite := p.overwrite
+	if overwrite == "" {
+		overwrite = "ask"
+	}
+	eol := eolName(p.eol)
+	if len(p.eol) == 0 {
+		eol = "none"
+	}
+	eolSrc := provenance{}
+	switch {
+	case flags.eol != nil:
+		eolSrc = provenance{from: fromFlag, name: "line-ending"}
+	case flags.appendOut && len(p.eol) > 0:
+		eolSrc = provenance{from: fromFile}
+	}
+
+	params := []param{
+		{"lines", strconv.Itoa(p.lines), src.lines},
+		{"filename", p.filename, src.filename},
+		{"overwrite", overwrite, src.overwrite},
+		{"width", strconv.Itoa(p.width), src.width},
+		{"mode", p.mode, src.mode},
+		{"modeArg", strconv.Quote(p.modeArg), src.modeArg},
+		{"line-ending", eol, eolSrc},
+		{"max-lines", strconv.Itoa(p.maxLines), p.maxLinesSrc},
+		{"auto-ext", onOff(p.autoExt), p.autoExtSrc},
+	}
+	for _, g := range flags.given {
+		switch g.name {
+		case "line-ending", "max-lines", "auto-ext", "no-auto-ext", "explain":
+			continue
+		}
+		value := g.value
+		if value == "" {
+			value = "on"
+		}
+		params = append(params, param{g.name, value, provenance{from: fromFlag, name: g.name}})
+	}
+	return params
+}
+
+// runParams are the resolved values of a run that --explain shows.
+type runParams struct {
+	lines       int
+	filename    string
+	overwrite   string
+	width       int
+	mode        string
+	modeArg     string
+	eol         []byte // as written: "" for none
+	maxLines    int
+	maxLinesSrc provenance
+	autoExt     bool
+	autoExtSrc  provenance
+}
+
+// onOff formats a switch for --explain.
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// printExplain writes the --explain table of params to w.
+func printExplain(w io.Writer, params []param) {
+	fmt.Fprintln(w, "Parameters:")
+	for _, p := range params {
+		fmt.Fprintf(w, "  %s=%s (%s)\n", p.name, p.value, p.src)
+	}
+}
+
+// sourceSummary maps the parameters to their provenance, for the sidecar.
+func sourceSummary(params []param) map[string]string {
+	m := make(map[string]string, len(params))
+	for _, p := range params {
+		m[p.name] = p.src.String()
+	}
+	return m
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_ExplainShowsProvenance(t *testing.T) {
+	t.Setenv(maxLinesEnv, "5000")
+	path := filepath.Join(t.TempDir(), "out.txt")
+	output := captureStdout(t)
+	if code := run([]string{"10", path, "y", "120", "--explain", "--line-checksum", "--meta"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	text := output()
+	for _, want := range []string{
+		"Parameters:\n",
+		"  lines=10 (positional arg 1)\n",
+		"  filename=" + path + " (positional arg 2)\n",
+		"  overwrite=y (positional arg 3)\n",
+		"  width=120 (positional arg 4)\n",
+		"  mode=ascii (default)\n",
+		`  modeArg="" (default)` + "\n",
+		"  line-ending=lf (default)\n",
+		"  max-lines=5000 (env GENERATELINES_MAX_LINES)\n",
+		"  line-checksum=on (flag --line-checksum)\n",
+		"  meta=on (flag --meta)\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output lacks %q:\n%s", want, text)
+		}
+	}
+	if strings.Index(text, "Parameters:") > strings.Index(text, "Generating") {
+		t.Errorf("parameters not shown before generating:\n%s", text)
+	}
+
+	data, err := os.ReadFile(path + metaSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m runMeta
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Sources["width"] != "positional arg 4" || m.Sources["mode"] != "default" || m.Sources["max-lines"] != "env GENERATELINES_MAX_LINES" {
+		t.Errorf("sidecar sources %v", m.Sources)
+	}
+}
+
+func TestGetArgsOrPrompt_Sources(t *testing.T) {
+	_, _, _, _, _, _, src, err := getArgsOrPrompt([]string{"out.txt", "10", "digits", "-"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Swapped lines and filename keep the positions they were given at.
+	want := argSources{
+		lines:    positional(2),
+		filename: positional(1),
+		mode:     positional(3),
+		modeArg:  positional(4),
+	}
+	if src != want {
+		t.Errorf("sources %+v, want %+v", src, want)
+	}
+
+	t.Setenv(maxLinesEnv, "")
+	if _, p, err := resolveMaxLines(cliFlags{maxLines: 7, maxLinesSet: true}); err != nil || p.String() != "flag --max-lines" {
+		t.Errorf("max-lines provenance %q, %v", p, err)
+	}
+	if _, p, _ := resolveMaxLines(cliFlags{}); p.String() != "default" {
+		t.Errorf("max-lines provenance %q, want default", p)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// fdPrefix starts an output filename naming an inherited file descriptor,
+// e.g. fd:3, for harnesses that open the output themselves to avoid path
+// races.
+const fdPrefix = "fd:"
+
+// stdoutTarget is the output filename that writes the lines to stdout, for
+// piping into another command.
+const stdoutTarget = "-"
+
+// isFDTarget reports whether name names a file descriptor rather than a path;
+// stdoutTarget counts as one.
+func isFDTarget(name string) bool {
+	return name == stdoutTarget || strings.HasPrefix(strings.ToLower(name), fdPrefix)
+}
+
+// parseFDTarget returns the descriptor of an fd:N filename. The standard
+// streams are refused: stdout and stderr carry the run's messages.
+func parseFDTarget(name string) (int, error) {
+	fd, err := strconv.Atoi(name[len(fdPrefix):])
+	if err != nil || fd < 0 {
+		return 0, fmt.Errorf("invalid file descriptor target %q (expected fd:N, e.g. fd:3)", name)
+	}
+	if fd <= 2 {
+		return 0, fmt.Errorf("%s is a standard stream; pass the output on a descriptor of its own (3 or above)", name)
+	}
+	return fd, nil
+}
+
+// syncFD flushes what was written to an fd target to storage, if it is a
+// regular file; pipes and sockets have nothing to sync.
+func syncFD(f *os.File) error {
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		return nil
+	}
+	return f.Sync()
+}
@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || freebsd || netbsd || dragonfly)
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// openFD fails: inherited descriptors are only supported on Unix.
+func openFD(fd int) (*os.File, error) {
+	return nil, fmt.Errorf("fd:%d: file descriptor targets are not supported on %s", fd, runtime.GOOS)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFDTarget(t *testing.T) {
+	if fd, err := parseFDTarget("fd:3"); fd != 3 || err != nil {
+		t.Errorf("fd:3 = %d, %v", fd, err)
+	}
+	if fd, err := parseFDTarget("FD:12"); fd != 12 || err != nil {
+		t.Errorf("FD:12 = %d, %v", fd, err)
+	}
+	for _, name := range []string{"fd:", "fd:x", "fd:-3", "fd:3.txt", "fd:1", "fd:2"} {
+		if _, err := parseFDTarget(name); err == nil {
+			t.Errorf("%s accepted", name)
+		}
+	}
+	if isFDTarget("fdump.txt") || !isFDTarget("fd:4") {
+		t.Error("isFDTarget")
+	}
+}
+
+func TestRun_FDTargetRejected(t *testing.T) {
+	for _, args := range [][]string{
+		{"3", "fd:987", "y", "20", "ascii"},
+		{"3", "fd:one", "y", "20", "ascii"},
+		{"3", "fd:5", "y", "20", "ascii", "--meta"},
+		{"3", "fd:5", "y", "20", "ascii", "--split-lines", "1"},
+		{"3", "fd:5", "y", "20", "ascii", "--append"},
+	} {
+		output := captureStderr(t)
+		if code := run(args); code == 0 {
+			t.Errorf("%v accepted", args)
+		}
+		if text := output(); !strings.Contains(text, "fd:") && !strings.Contains(text, "file descriptor") {
+			t.Errorf("%v: stderr %q", args, text)
+		}
+	}
+}
+
+func TestRun_StdoutTarget(t *testing.T) {
+	t.Chdir(t.TempDir())
+	out, errOut := captureStdout(t), captureStderr(t)
+	if code := run([]string{"3", "-", "y", "10", "digits"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if got := out(); got != "0123456789\n0123456789\n0123456789\n" {
+		t.Errorf("stdout %q", got)
+	}
+	if got := errOut(); !strings.Contains(got, "Generating 3 lines") {
+		t.Errorf("stderr %q", got)
+	}
+	if fileExists("-") {
+		t.Error("wrote a file named -")
+	}
+}
+
+func TestRun_StdoutClosedEarly(t *testing.T) {
+	head := pipeStdoutToHead(t, 5)
+	errOut := captureStderr(t)
+
+	if code := run([]string{"1000000", "-", "y", "80"}); code != 0 {
+		t.Fatalf("exit code %d, want 0", code)
+	}
+	if lines := strings.Count(<-head, "\n") + 1; lines != 5 {
+		t.Errorf("head read %d lines", lines)
+	}
+	if msg := errOut(); !strings.Contains(msg, "Output closed after ") || strings.Contains(msg, "Error") {
+		t.Errorf("stderr %q", msg)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build linux || darwin || freebsd || netbsd || dragonfly
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// openFD returns a file writing to the inherited descriptor fd through a
+// duplicate of it, so closing the file leaves fd open for its owner.
+func openFD(fd int) (*os.File, error) {
+	fl, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_GETFL, 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("fd:%d is not an open file descriptor (%v)", fd, errno)
+	}
+	if int(fl)&syscall.O_ACCMODE == syscall.O_RDONLY {
+		return nil, fmt.Errorf("fd:%d is open for reading only", fd)
+	}
+	dup, err := syscall.Dup(fd)
+	if err != nil {
+		return nil, fmt.Errorf("fd:%d: %v", fd, err)
+	}
+	syscall.CloseOnExec(dup)
+	return os.NewFile(uintptr(dup), fmt.Sprintf("fd:%d", fd)), nil
+}
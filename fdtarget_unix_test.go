@@ -0,0 +1,71 @@
+//go:build linux || darwin || freebsd || netbsd || dragonfly
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRun_FDTargetWritesToPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	read := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		read <- string(data)
+	}()
+
+	output := captureStdout(t)
+	target := fmt.Sprintf("fd:%d", w.Fd())
+	code := run([]string{"4", target, "n", "10", "digits", "--line-ending", "crlf", "--verify-after"})
+	// The run closes only its duplicate: the pipe stays open until its
+	// owner closes it, and the reader sees EOF only then.
+	if _, err := w.Write([]byte("owner\n")); err != nil {
+		t.Errorf("the run closed the inherited descriptor: %v", err)
+	}
+	w.Close()
+	got := <-read
+	if code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if want := strings.Repeat("0123456789\r\n", 4) + "owner\n"; got != want {
+		t.Errorf("pipe read %q, want %q", got, want)
+	}
+	text := output()
+	for _, want := range []string{"Generating 4 lines (width=10, mode=digits) -> " + target, "--verify-after is skipped", "Done!"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("stdout lacks %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "exists") {
+		t.Errorf("the descriptor went through the exists check:\n%s", text)
+	}
+}
+
+func TestOpenFD_Errors(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	r.Close()
+	if _, err := openFD(int(r.Fd())); err == nil {
+		t.Error("a closed descriptor was accepted")
+	}
+
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := openFD(int(f.Fd())); err == nil || !strings.Contains(err.Error(), "reading only") {
+		t.Errorf("read-only descriptor: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// filenameClock supplies the time that filename tokens expand to. Tests
+// replace it.
+var filenameClock = time.Now
+
+// filenameTokens maps the letter after % in a filename to the time layout it
+// expands to.
+var filenameTokens = map[byte]string{
+	'Y': "2006",
+	'm': "01",
+	'd': "02",
+	'H': "15",
+	'M': "04",
+	'S': "05",
+}
+
+// ptrs returns pointers to the elements of s, to update them in place.
+func ptrs(s []string) []*string {
+	p := make([]*string, len(s))
+	for i := range s {
+		p[i] = &s[i]
+	}
+	return p
+}
+
+// expandFilename replaces the strftime-style tokens %Y, %m, %d, %H, %M and %S
+// in name with the parts of t, and %% with a single %. Any other % sequence
+// is an error, so a typo does not end up in a file name.
+func expandFilename(name string, t time.Time) (string, error) {
+	if !strings.Contains(name, "%") {
+		return name, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '%' {
+			b.WriteByte(name[i])
+			continue
+		}
+		if i+1 == len(name) {
+			return "", fmt.Errorf("filename %q ends in a lone %% (write %%%% for a literal %%, or use --no-expand)", name)
+		}
+		i++
+		if name[i] == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		layout, ok := filenameTokens[name[i]]
+		if !ok {
+			return "", fmt.Errorf("filename %q: unknown time token %%%c (expected %%Y, %%m, %%d, %%H, %%M, %%S or %%%% for a literal %%; or use --no-expand)", name, name[i])
+		}
+		b.WriteString(t.Format(layout))
+	}
+	return b.String(), nil
+}
+
+// autoExtEnv turns --auto-ext on (1, true, yes, on) or off (0, false, no,
+// off) for runs that give neither --auto-ext nor --no-auto-ext.
+const autoExtEnv = "GENERATELINES_AUTO_EXT"
+
+// autoExt is the extension --auto-ext gives a filename without one.
+const autoExt = ".txt"
+
+// extensionOnlyNames are base names that are an extension and nothing else,
+// so a name was almost certainly left out.
+var extensionOnlyNames = []string{".txt", ".gz", ".zip", ".tar", ".csv", ".log"}
+
+// resolveAutoExt reports whether --auto-ext is on for this run, and where
+// that was decided.
+func resolveAutoExt(flags cliFlags) (bool, provenance, error) {
+	if flags.autoExtSet {
+		name := "auto-ext"
+		if !flags.autoExt {
+			name = "no-auto-ext"
+		}
+		return flags.autoExt, provenance{from: fromFlag, name: name}, nil
+	}
+	if v, ok := os.LookupEnv(autoExtEnv); ok && strings.TrimSpace(v) != "" {
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "1", "true", "yes", "on":
+			return true, provenance{from: fromEnv, name: autoExtEnv}, nil
+		case "0", "false", "no", "off":
+			return false, provenance{from: fromEnv, name: autoExtEnv}, nil
+		}
+		return false, provenance{}, fmt.Errorf("invalid %s: %q (expected on or off)", autoExtEnv, v)
+	}
+	return false, provenance{}, nil
+}
+
+// checkNotOnlyExtension rejects an output name whose base is only an
+// extension, such as .txt.
+func checkNotOnlyExtension(name string) error {
+	base := filepath.Base(name)
+	for _, ext := range extensionOnlyNames {
+		if strings.EqualFold(base, ext) {
+			return fmt.Errorf("filename %q is only an extension; put a name before it, e.g. out%s", name, base)
+		}
+	}
+	return nil
+}
+
+// withAutoExt returns name with autoExt appended if its base name has no
+// extension. The leading dot of a dot-file does not start an extension, so
+// .hidden becomes .hidden.txt; a name ending in a path separator is left
+// for the directory check to reject.
+func withAutoExt(name string) string {
+	if name == "" || os.IsPathSeparator(name[len(name)-1]) {
+		return name
+	}
+	stem := strings.TrimPrefix(filepath.Base(name), ".")
+	if stem == "" || stem == "." || strings.Contains(stem, ".") {
+		return name
+	}
+	return name + autoExt
+}
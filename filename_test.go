@@ -0,0 +1,158 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// freezeFilenameClock makes filename tokens expand to t for the rest of the test.
+func freezeFilenameClock(t *testing.T, at time.Time) {
+	t.Helper()
+	old := filenameClock
+	filenameClock = func() time.Time { return at }
+	t.Cleanup(func() { filenameClock = old })
+}
+
+func TestExpandFilename(t *testing.T) {
+	at := time.Date(2026, 3, 7, 9, 5, 1, 0, time.UTC)
+	for in, want := range map[string]string{
+		"out.txt":                   "out.txt",
+		"out-%Y%m%d-%H%M%S.txt":     "out-20260307-090501.txt",
+		"fixtures/%Y/%m/day-%d.csv": "fixtures/2026/03/day-07.csv",
+		"100%%-%Y.txt":              "100%-2026.txt",
+		"%%Y":                       "%Y",
+	} {
+		if got, err := expandFilename(in, at); err != nil || got != want {
+			t.Errorf("expandFilename(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"out-%y.txt", "50%.txt", "out%"} {
+		if _, err := expandFilename(in, at); err == nil || !strings.Contains(err.Error(), "--no-expand") {
+			t.Errorf("expandFilename(%q): err = %v, want one pointing at --no-expand", in, err)
+		}
+	}
+}
+
+func TestRun_ExpandsFilename(t *testing.T) {
+	freezeFilenameClock(t, time.Date(2026, 10, 16, 14, 30, 0, 0, time.Local))
+	dir := t.TempDir()
+
+	out := captureStdout(t)
+	if code := run([]string{"3", filepath.Join(dir, "out-%Y%m%d-%H%M.txt"), "y", "5", "digits"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	resolved := filepath.Join(dir, "out-20261016-1430.txt")
+	if !fileExists(resolved) {
+		t.Fatalf("%s was not written", resolved)
+	}
+	if text := out(); !strings.Contains(text, "expands to "+resolved) || !strings.Contains(text, "-> "+resolved) {
+		t.Errorf("summary does not show the resolved name:\n%s", text)
+	}
+
+	// The resolved name is what the overwrite answer applies to.
+	os.WriteFile(resolved, []byte("keep\n"), 0644)
+	if code := run([]string{"3", filepath.Join(dir, "out-%Y%m%d-%H%M.txt"), "n", "5", "digits"}); code != 0 {
+		t.Fatalf("second run exited with %d", code)
+	}
+	if data, _ := os.ReadFile(resolved); string(data) != "keep\n" {
+		t.Errorf("declined run overwrote %s: %q", resolved, data)
+	}
+
+	// --no-expand keeps the name as typed; escaped percents survive split names.
+	if code := run([]string{"3", filepath.Join(dir, "raw-%Y.txt"), "y", "5", "digits", "--no-expand"}); code != 0 {
+		t.Fatalf("--no-expand run exited with %d", code)
+	}
+	if !fileExists(filepath.Join(dir, "raw-%Y.txt")) {
+		t.Error("--no-expand did not keep the literal name")
+	}
+	if code := run([]string{"4", filepath.Join(dir, "100%%-%Y.txt"), "y", "5", "digits", "--split-lines", "2"}); code != 0 {
+		t.Fatalf("split run exited with %d", code)
+	}
+	if !fileExists(filepath.Join(dir, "100%-2026-002.txt")) {
+		t.Error("split part 100%-2026-002.txt was not written")
+	}
+}
+
+func TestWithAutoExt(t *testing.T) {
+	for name, want := range map[string]string{
+		"out":                   "out.txt",
+		"dir/out":               "dir/out.txt",
+		"out.csv":               "out.csv",
+		"out.tar.gz":            "out.tar.gz",
+		"dir.d/out":             "dir.d/out.txt",
+		".hidden":               ".hidden.txt",
+		".hidden.log":           ".hidden.log",
+		"dir/":                  "dir/",
+		"data-2026-10-16":       "data-2026-10-16.txt",
+		"data-2026.10.16":       "data-2026.10.16",
+		filepath.Join("a", "b"): filepath.Join("a", "b") + ".txt",
+	} {
+		if got := withAutoExt(name); got != want {
+			t.Errorf("withAutoExt(%q) = %q, want %q", name, got, want)
+		}
+	}
+	for _, name := range []string{".txt", "dir/.TXT", ".gz"} {
+		if err := checkNotOnlyExtension(name); err == nil || !strings.Contains(err.Error(), "only an extension") {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"a.txt", ".hidden", ".txt.bak", "txt"} {
+		if err := checkNotOnlyExtension(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+}
+
+func TestRun_AutoExt(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(autoExtEnv, "")
+
+	// Off by default: the name is used as typed.
+	if code := run([]string{"2", filepath.Join(dir, "plain"), "y"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if !fileExists(filepath.Join(dir, "plain")) || fileExists(filepath.Join(dir, "plain.txt")) {
+		t.Error("a name without an extension changed without --auto-ext")
+	}
+
+	out := captureStdout(t)
+	if code := run([]string{"2", filepath.Join(dir, "data"), "y", "--auto-ext"}); code != 0 {
+		t.Fatalf("--auto-ext run exited with %d", code)
+	}
+	final := filepath.Join(dir, "data.txt")
+	if text := out(); !strings.Contains(text, "writing "+final+" (--auto-ext)") || !strings.Contains(text, "-> "+final) {
+		t.Errorf("summary does not show the final name:\n%s", text)
+	}
+	if !fileExists(final) || fileExists(filepath.Join(dir, "data")) {
+		t.Fatal("data.txt was not written in place of data")
+	}
+
+	// The overwrite answer applies to the final name, here set by the environment.
+	os.WriteFile(final, []byte("keep\n"), 0644)
+	t.Setenv(autoExtEnv, "on")
+	if code := run([]string{"2", filepath.Join(dir, "data"), "n"}); code != 0 {
+		t.Fatalf("declined run exited with %d", code)
+	}
+	if data, _ := os.ReadFile(final); string(data) != "keep\n" {
+		t.Errorf("declined run overwrote %s: %q", final, data)
+	}
+	if code := run([]string{"2", filepath.Join(dir, "env"), "y", "--no-auto-ext"}); code != 0 || !fileExists(filepath.Join(dir, "env")) {
+		t.Errorf("--no-auto-ext did not win over %s (exit %d)", autoExtEnv, code)
+	}
+
+	// Dot-files get the extension after their name; a bare extension is rejected.
+	if code := run([]string{"2", filepath.Join(dir, ".hidden"), "y"}); code != 0 || !fileExists(filepath.Join(dir, ".hidden.txt")) {
+		t.Errorf("dot-file not written as .hidden.txt (exit %d)", code)
+	}
+	t.Setenv(autoExtEnv, "")
+	if code := run([]string{"2", filepath.Join(dir, ".txt"), "y"}); code == 0 || fileExists(filepath.Join(dir, ".txt")) {
+		t.Error("a filename of only .txt was accepted")
+	}
+	t.Setenv(autoExtEnv, "maybe")
+	if code := run([]string{"2", filepath.Join(dir, "x"), "y"}); code == 0 {
+		t.Errorf("%s=maybe accepted", autoExtEnv)
+	}
+}
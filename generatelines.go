@@ -2,30 +2,45 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
-	"runtime"
+	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
+	"unicode"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
 )
 
 const (
-	defaultWidth = 80
+	defaultWidth = genlines.DefaultWidth
 	authorName   = "Christian K. Bjørnsrud"
 	repoURL      = "https://github.com/CKB78/GenerateLines"
 	version      = "1.0.1"
 )
 
 func main() {
-	args := os.Args[1:]
+	restore := setupConsole()
+	code := run(os.Args[1:])
+	restore()
+	os.Exit(code)
+}
 
+// run executes the command line in args and returns the process exit code.
+func run(args []string) (code int) {
 	// Version handling
 	if len(args) > 0 {
 		switch strings.ToLower(strings.TrimSpace(args[0])) {
 		case "version", "-v", "--version", "/v":
-			fmt.Printf("GenerateLines %s\n", version)
-			return
+			return runVersionCmd(args[1:])
 		}
 	}
 
@@ -33,182 +48,769 @@ func main() {
 	if len(args) > 0 {
 		switch strings.ToLower(strings.TrimSpace(args[0])) {
 		case "/?", "help", "-h", "--help":
-			printHelp()
-			return
+			return runHelpCmd(args[1:])
+		}
+	}
+
+	// Presets store raw command lines, so they see the options unparsed.
+	if len(args) > 0 && strings.EqualFold(args[0], "preset") {
+		return runPresetCmd(args[1:])
+	}
+	// So do batch specs, one command line per job.
+	if len(args) > 0 && strings.EqualFold(args[0], "batch") {
+		return runBatchCmd(args[1:])
+	}
+
+	cmdLine := args
+	args, flags, err := splitFlags(args)
+	// stdout carries the path of a --temp-name file, for $(...) to capture.
+	u := newUI(flags)
+	u.dataOut = flags.tempName != ""
+	defer useUI(u)()
+	configureLocale()
+	if err != nil {
+		cli.Error("%v", err)
+		cli.Hint(helpHint())
+		return 1
+	}
+
+	if flags.jsonSchema {
+		if len(args) > 0 {
+			cli.Error("--json-schema takes no other arguments")
+			return 1
+		}
+		if err := printSummarySchema(); err != nil {
+			cli.Error("%v", err)
+			return 1
 		}
+		return 0
+	}
+
+	if len(args) > 0 && strings.EqualFold(args[0], "daemon") {
+		return runDaemonCmd(args[1:], flags)
+	}
+	if len(args) > 0 && strings.EqualFold(args[0], "regen") {
+		return runRegenCmd(args[1:])
+	}
+	if len(args) > 0 && strings.EqualFold(args[0], "verify") {
+		return runVerifyCmd(args[1:], flags)
+	}
+	if len(args) > 0 && strings.EqualFold(args[0], "verify-lines") {
+		return runVerifyLinesCmd(args[1:])
+	}
+	if len(args) > 0 && strings.EqualFold(args[0], "sample") {
+		return runSampleCmd(args[1:], flags)
+	}
+	if len(args) > 0 && strings.EqualFold(args[0], "selftest") {
+		return runSelftestCmd(args[1:])
+	}
+	if len(args) > 0 && strings.EqualFold(args[0], "stress-files") {
+		return runStressFilesCmd(args[1:], flags)
+	}
+	if len(args) > 0 && strings.EqualFold(args[0], "streams") {
+		return runStreamsCmd(args[1:], flags)
+	}
+	if len(args) > 0 && strings.EqualFold(args[0], "history") {
+		return runHistoryCmd(args[1:])
 	}
 
+	// Every generation run is recorded, however it ends, when history is on.
+	hist := startHistory(cmdLine)
+	defer func() { hist.finish(code) }()
+
 	// Friendly hint when running interactively
 	if len(args) == 0 {
-		fmt.Println(helpHint())
-		fmt.Println()
+		cli.Info("%s", helpHint())
+	}
+
+	// --temp-name stands in for the filename argument; the name itself is
+	// picked when the file is created.
+	if flags.tempName != "" {
+		if len(args) == 0 {
+			cli.Error("--temp-name requires the number of lines")
+			cli.Hint(helpHint())
+			return 1
+		}
+		args = slices.Insert(args, 1, flags.tempName+tempNameHole)
+	}
+
+	lines, filename, overwriteFlag, width, mode, modeArg, src, err := getArgsOrPrompt(args, flags.strictArgs)
+	if err != nil {
+		cli.Error("%v", err)
+		cli.Hint(helpHint())
+		return 1
+	}
+	// With the lines on stdout, every message goes to stderr.
+	toStdout := filename == stdoutTarget
+	if toStdout {
+		cli.dataOut = true
+	}
+
+	// Time tokens resolve once, before the files are checked or named anywhere.
+	if !flags.noExpand {
+		now := filenameClock()
+		for _, name := range append([]*string{&filename}, ptrs(flags.outs)...) {
+			if isUploadURL(*name) || isFDTarget(*name) {
+				continue
+			}
+			expanded, err := expandFilename(*name, now)
+			if err != nil {
+				cli.Error("%v", err)
+				return 1
+			}
+			if expanded != *name {
+				cli.Info("Filename %s expands to %s", *name, expanded)
+				*name = expanded
+			}
+		}
+	}
+
+	// --auto-ext applies next, so the exists check and the prompt see the final name.
+	autoExt, autoExtSrc, err := resolveAutoExt(flags)
+	if err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+	for _, name := range append([]*string{&filename}, ptrs(flags.outs)...) {
+		if isUploadURL(*name) || isFDTarget(*name) {
+			continue
+		}
+		if err := checkNotOnlyExtension(*name); err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+		if autoExt {
+			if withExt := withAutoExt(*name); withExt != *name {
+				cli.Info("Filename %s has no extension; writing %s (--auto-ext)", *name, withExt)
+				*name = withExt
+			}
+		}
+	}
+
+	if mode == "char" {
+		if modeArg, err = decodeModeArg(modeArg, flags.allowControl); err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+	}
+
+	if err := checkANSITarget(mode, toStdout && isTerminal(os.Stdout), flags.forceANSI); err != nil {
+		cli.Error("%v", err)
+		return 1
 	}
 
-	lines, filename, overwriteFlag, width, mode, modeArg,
-		usedDefaultWidth, usedDefaultMode, err := getArgsOrPrompt(args)
+	// Nondeterministic runs get a seed picked here so it can be recorded.
+	nondeterministic := false
+	if flags.seedSet {
+		cli.Info("Seed: %d (every random feature without its own seed derives from it)", flags.seed)
+	} else if (mode == "random" || mode == "hashfill") && strings.TrimSpace(modeArg) == "" {
+		modeArg = strconv.FormatUint(newSeed(), 10)
+		src.modeArg = provenance{from: fromPicked}
+		nondeterministic = true
+		cli.Info("mode=%s: no seed given, using seed %s", mode, modeArg)
+	}
+	writeMetaFile := (nondeterministic || flags.meta) && !flags.noMeta
+
+	// Every combination of options is checked here, before any file is
+	// touched; the appended file is only read, for the endings it uses.
+	toURL := isUploadURL(filename)
+	toFD, fd := isFDTarget(filename), 0
+	binrec := mode == "binrec"
+	target := compatTarget{filename: filename, mode: mode, url: toURL, fd: toFD,
+		yes: overwriteFlag != "" && parseYesNo(overwriteFlag)}
+	if flags.appendOut && !binrec {
+		if target.existingEOL, _, err = sniffFile(filename); err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+	}
+	warnings, err := checkCompat(flags, target)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error:", err)
-		fmt.Fprintln(os.Stderr, helpHint())
-		os.Exit(1)
+		cli.Error("%v", err)
+		return 1
+	}
+	for _, w := range warnings {
+		cli.Warn("%s", w)
+	}
+	if flags.splitLines > 0 || flags.gzMembers > 0 || toURL || toFD || flags.appendOut {
+		writeMetaFile = false
+	}
+	// An inherited descriptor is written as it is: there is no path to
+	// check, name parts after, or put a sidecar next to.
+	if toFD && !toStdout {
+		if fd, err = parseFDTarget(filename); err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+	}
+	if toFD && flags.verifyAfter {
+		cli.Info("Note: --verify-after is skipped for file descriptor targets (the output cannot be read back)")
+		flags.verifyAfter = false
+	}
+
+	opts := genlines.Options{
+		Lines:   lines,
+		Width:   width,
+		Mode:    mode,
+		ModeArg: modeArg,
+
+		CommentEvery:   flags.commentEvery,
+		CommentText:    flags.commentText,
+		LineChecksum:   flags.lineChecksum,
+		EOL:            flags.eol,
+		EscapeNonASCII: flags.escapeASCII,
+		Rot:            flags.rot,
+		TrailingWS:     flags.trailingWS,
+		LinePattern:    flags.linePattern,
+		InjectUnicode:  flags.inject,
+		StartOffset:    flags.startOffset,
+		SafeStart:      flags.safeStart,
+		ByteRange:      flags.byteRange,
+		Sort:           flags.sortOrder,
+		SortMaxMemory:  flags.sortMaxMem,
+		Continuation:   flags.continuation,
+		FirstLine:      flags.firstLine,
+		LastLine:       flags.lastLine,
+		Unsafe:         flags.unsafe,
+		Ramp:           flags.ramp,
+		Align:          flags.align,
+		AlignFill:      flags.alignFill,
+		Retry:          writeRetry(flags),
+		FlushEvery:     flags.flushEvery,
+
+		Seed:    flags.seed,
+		HasSeed: flags.seedSet,
+	}
+
+	// Noise cannot be generated again: there are no settings worth recording,
+	// and nothing to verify the file against.
+	if !genlines.Reproducible(opts) {
+		if flags.meta {
+			cli.Warn("--meta ignored: the output of mode=%s cannot be reproduced", mode)
+		}
+		writeMetaFile = false
+		cli.Warn("mode=%s draws from crypto/rand: the output is different every run, so regen and --verify-after cannot check it", mode)
+		if flags.verifyAfter {
+			cli.Info("Note: --verify-after is skipped for output that cannot be generated again")
+			flags.verifyAfter = false
+		}
 	}
 
-	exists := fileExists(filename)
-	overwrite := false
+	// Appended lines follow the terminator the file already uses.
+	joint := false
+	if flags.appendOut && !binrec {
+		eol, terminated, err := appendEnding(filename, flags.eol)
+		if err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+		opts.EOL, joint = eol, !terminated
+	}
+
+	// The ceiling is on the final file, so appended lines get what is left.
+	var existing int64
+	if flags.maxBytes > 0 {
+		opts.MaxBytes = flags.maxBytes
+		if flags.appendOut {
+			if fi, err := os.Stat(filename); err == nil {
+				existing = fi.Size()
+			}
+			if joint {
+				existing += int64(len(opts.EOL))
+			}
+			if existing >= flags.maxBytes {
+				cli.Error("%s already takes %d bytes, at or over --max-bytes %d", filename, existing, flags.maxBytes)
+				return 1
+			}
+			opts.MaxBytes -= existing
+		}
+	}
+
+	// With an exact byte size, the budget decides the line count.
+	var tail int64
+	if flags.exactBytes > 0 {
+		opts.ExactBytes = flags.exactBytes
+		full, t, err := genlines.PlanExactBytes(opts)
+		if err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+		lines, tail = int(full), t
+		opts.Lines = lines
+	}
+
+	targets := sideTargets(flags, filename)
+	if !toURL && !toFD {
+		targets = append([]string{filename}, targets...)
+	}
+	if !guardTargets(flags, targets...) {
+		return 1
+	}
 
 	// Use one reader for any interactive prompts in main
 	in := bufio.NewReader(os.Stdin)
 
-	if exists {
-		if overwriteFlag != "" {
-			overwrite = parseYesNo(overwriteFlag)
-			if overwrite {
-				fmt.Printf("%s already exists. Overwriting...\n", filename)
+	// The stamp describes the run as its sidecar would, so it is made last.
+	if flags.stamp {
+		opts.HeaderLine = stampLine(newRunMeta(filename, opts, 0, ""))
+	}
+
+	pads, size, err := genlines.PlanAlign(opts)
+	if errors.Is(err, genlines.ErrSizeUnknown) {
+		size = -1
+	} else if err != nil {
+		cli.Error("%v", err)
+		if errors.Is(err, genlines.ErrSortMemory) {
+			cli.Hint(sortMemoryHint(opts))
+		}
+		return 1
+	}
+	if opts.SafeStart {
+		if err := reportSafeStart(opts); err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+	}
+	if flags.verbose && opts.ByteRange.Enabled() {
+		if err := reportPalette(opts); err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+	}
+	maxLines, maxLinesSrc, err := resolveMaxLines(flags)
+	if err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+	eol := opts.EOL
+	if eol == nil && !binrec {
+		eol = []byte("\n")
+	}
+	params := explainParams(runParams{
+		lines:       lines,
+		filename:    filename,
+		overwrite:   overwriteFlag,
+		width:       width,
+		mode:        mode,
+		modeArg:     modeArg,
+		eol:         eol,
+		maxLines:    maxLines,
+		maxLinesSrc: maxLinesSrc,
+		autoExt:     autoExt,
+		autoExtSrc:  autoExtSrc,
+	}, src, flags)
+	if flags.explain {
+		printExplain(cli.infoOut(), params)
+	}
+	hist.resolve(filename, opts)
+	if err := checkLineCap(in, lines, maxLines, size, flags.force); err != nil {
+		if errors.Is(err, errCapDeclined) {
+			cli.Info("Not generating. Exiting.")
+		} else {
+			cli.Error("%v", err)
+		}
+		return exitCapExceeded
+	}
+	if mode == "pi" && lines > 0 {
+		// The calibration burst is the start of the output, not a throwaway.
+		cal, err := genlines.CalibratePi(opts, piClock)
+		if err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+		if err := checkPiEstimate(in, cal, flags.force); err != nil {
+			if errors.Is(err, errPiDeclined) {
+				cli.Info("Not generating. Exiting.")
 			} else {
-				fmt.Printf("%s already exists. Not overwriting. Exiting.\n", filename)
-				return
+				cli.Error("%v", err)
+			}
+			return exitCapExceeded
+		}
+		opts.PiDigits = cal.Stream()
+	}
+
+	prompt := newOverwritePrompt(in, overwriteFlag)
+	if flags.splitLines > 0 {
+		return runSplit(prompt, hist, filename, opts, flags)
+	}
+	if toURL {
+		if flags.verifyAfter {
+			cli.Info("Note: --verify-after is skipped for URL targets (the upload cannot be read back)")
+		}
+		// The server decides about existing objects; there is nothing to prompt for.
+		return runUpload(hist, filename, opts, flags)
+	}
+	if len(flags.outs) > 0 {
+		return runMulti(prompt, hist, append([]string{filename}, flags.outs...), opts, flags, writeMetaFile)
+	}
+
+	// The descriptor is the caller's: it is written through a duplicate,
+	// and neither checked for content nor truncated.
+	var (
+		f      *os.File
+		exists bool
+	)
+	if toStdout {
+		// A reader that stops early, like "head", closes the pipe: that ends
+		// the run, not in failure. Ignoring SIGPIPE turns the signal Go would
+		// die of on stdout into an EPIPE write error.
+		signal.Ignore(syscall.SIGPIPE)
+		f = os.Stdout
+	} else if toFD {
+		if f, err = openFD(fd); err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+	} else if flags.tempName != "" {
+		// The name is new by construction: there is nothing to ask about.
+		if f, filename, err = createTempName(tempNamePrefix(filename)); err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+		fmt.Println(filename)
+		hist.setOutput(filename)
+	} else {
+		exists = fileExists(filename)
+		overwrite := false
+
+		if exists && !flags.appendOut {
+			asked, what := prompt.asks(), describeExisting(filename, size)
+			overwrite, err = prompt.allow(what, false)
+			if err == nil && asked && overwrite && !flags.noNameCheck {
+				overwrite, err = prompt.confirmName(filename, size)
 			}
-		} else {
-			overwrite, err = promptYesNoR(in, fmt.Sprintf("%s already exists. Overwrite? [y/n]: ", filename))
 			if err != nil {
-				fmt.Fprintln(os.Stderr, "Error:", err)
-				os.Exit(1)
+				cli.Error("%v", err)
+				return 1
 			}
-			if !overwrite {
-				fmt.Println("Not overwriting. Exiting.")
-				return
+			switch {
+			case asked && !overwrite:
+				cli.Info("%s", text("overwrite.declined"))
+				hist.skip()
+				return 0
+			case !overwrite:
+				cli.Warn(text("overwrite.exiting"), what)
+				hist.skip()
+				return 0
+			case !asked:
+				cli.Warn(text("overwrite.overwriting"), what)
 			}
 		}
+
+		openFlag := os.O_CREATE | os.O_WRONLY
+		if flags.appendOut {
+			openFlag |= os.O_APPEND
+		} else if overwrite {
+			openFlag |= os.O_TRUNC
+		} else if exists {
+			cli.Info("File exists and overwrite not allowed. Exiting.")
+			hist.skip()
+			return 0
+		}
+		if overwrite && flags.wipeOld {
+			if err := wipeFile(filename); err != nil {
+				cli.Error("wiping %s: %v", filename, err)
+				return 1
+			}
+		}
+
+		f, err = os.OpenFile(filename, openFlag, 0644)
+		if err != nil {
+			cli.Error("opening file: %v", err)
+			return 1
+		}
 	}
+	// stdout is the process's own: it stays open after the run.
+	closeOut := f.Close
+	if toStdout {
+		closeOut = func() error { return nil }
+	}
+	defer closeOut()
+	// Everything written to the file goes through fw, so --verbose sees
+	// every write that reaches the OS; with --bps, through the throttle.
+	var dst io.Writer = f
+	var throttle *throttledWriter
+	if flags.bps > 0 {
+		throttle = newThrottledWriter(context.Background(), f, flags.bps, throttleClock)
+		dst = throttle
+	}
+	fw := &writeCounter{w: dst}
 
-	openFlag := os.O_CREATE | os.O_WRONLY
-	if overwrite {
-		openFlag |= os.O_TRUNC
-	} else if exists {
-		fmt.Println("File exists and overwrite not allowed. Exiting.")
-		return
+	if joint {
+		if _, err := fw.Write(opts.EOL); err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+		cli.Info("%s did not end with a line terminator; added one before the new lines", filename)
+	}
+	// Appended runs are verified, and their golden offsets counted, from
+	// where the new lines start.
+	var start int64
+	if flags.verifyAfter || flags.golden != "" {
+		fi, err := f.Stat()
+		if err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+		start = fi.Size()
 	}
 
-	f, err := os.OpenFile(filename, openFlag, 0644)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error opening file:", err)
-		os.Exit(1)
+	var golden *goldenFile
+	if flags.golden != "" {
+		if golden, err = createGolden(flags.golden, start, opts.LineChecksum); err != nil {
+			cli.Error("creating golden file: %v", err)
+			return 1
+		}
+		defer golden.discard()
 	}
-	defer f.Close()
 
 	totalChars := lines * width
-	if mode == "pi" {
-		fmt.Printf("Mode=pi will generate %d digits (%d lines × %d cols)\n",
+	if mode == "pi" && lines > 0 && !opts.Ramp.Enabled() {
+		cli.Info("Mode=pi will generate %d digits (%d lines × %d cols)",
 			totalChars, lines, width)
 	}
 
 	// Build default usage note
 	defaultNote := ""
+	usedDefaultWidth, usedDefaultMode := src.width.from == fromDefault, src.mode.from == fromDefault
 	switch {
 	case usedDefaultWidth && usedDefaultMode:
-		defaultNote = " [using default width and mode]"
+		defaultNote = text("generate.defaults")
 	case usedDefaultWidth:
-		defaultNote = " [using default width]"
+		defaultNote = text("generate.defWidth")
 	case usedDefaultMode:
-		defaultNote = " [using default mode]"
+		defaultNote = text("generate.defMode")
 	}
 
-	fmt.Printf(
-		"Generating %d lines (width=%d, mode=%s)%s -> %s\n",
-		lines, width, mode, defaultNote, filename,
-	)
+	if flags.appendOut && exists {
+		cli.Info("Appending to %s with %s line endings", filename, eolName(opts.EOL))
+	}
 
-	w := bufio.NewWriterSize(f, 1024*64)
-	defer w.Flush()
+	switch {
+	case opts.ExactBytes > 0:
+		cli.Info("%s", text("generate.exact",
+			opts.ExactBytes, lines, tail, width, mode, filename))
+	case lines > 0 && opts.Ramp.Enabled():
+		cli.Info("%s", text("generate.ramp",
+			lines, opts.Ramp.Min, opts.Ramp.Max, opts.Ramp.Step, mode, filename))
+	case lines > 0 && binrec:
+		size, _ := genlines.BinrecSize(modeArg)
+		cli.Info("%s", text("generate.binrec", lines, size, mode, filename))
+	case lines > 0 && genlines.IsInterleaveSpec(mode):
+		cli.Info("%s", text("generate.interleave", lines, mode, filename))
+	case lines > 0:
+		cli.Info("%s", text("generate.lines", lines, width, mode, defaultNote, filename))
+	}
 
-	gen, err := newGenerator(mode, modeArg, totalChars)
+	// Only hash the output when something records the checksum. A
+	// compressed file is hashed as written, but profiled as content.
+	hashed := writeMetaFile || flags.manifest != "" || flags.summaryJSON != ""
+	var out io.Writer = fw
+	content := out
+	sum := sha256.New()
+	if flags.gzMembers > 0 {
+		content = io.Discard
+		if hashed {
+			out = io.MultiWriter(fw, sum)
+		}
+	}
+	var stats *genlines.ContentStats
+	if flags.stats {
+		stats = &genlines.ContentStats{}
+		content = io.MultiWriter(content, stats)
+	}
+	var physical *newlineCounter
+	if isRecordMode(mode) {
+		physical = &newlineCounter{}
+		content = io.MultiWriter(content, physical)
+	}
+	// Only the start of a plain file can be taken for another type.
+	var head *headRecorder
+	if flags.gzMembers == 0 && sniffable(mode) && !(flags.appendOut && exists) {
+		head = &headRecorder{}
+		content = io.MultiWriter(content, head)
+	}
+
+	// The summary, manifest and metadata all report from st.
+	var (
+		st      genlines.Stats
+		members []gzMember
+	)
+	if flags.gzMembers > 0 {
+		// The split run's Stats count raw bytes; keep only what it added.
+		var raw genlines.Stats
+		gzOpts := opts
+		gzOpts.Stats = &raw
+		members, err = writeGzipMembers(context.Background(), out, content, gzOpts, flags.gzMembers)
+		st = genlines.Stats{Mode: mode, Width: width, TrailingLines: raw.TrailingLines, TrailingBytes: raw.TrailingBytes, BlankLines: raw.BlankLines, Injected: raw.Injected}
+		for _, m := range members {
+			st.Lines += m.Lines
+			st.Bytes += m.Bytes
+		}
+		if hashed {
+			st.Checksums = map[string]string{"sha256": hex.EncodeToString(sum.Sum(nil))}
+		}
+	} else {
+		runOpts := opts
+		runOpts.Stats = &st
+		if hashed {
+			runOpts.Checksums = []string{"sha256"}
+		}
+		if golden != nil {
+			runOpts.OnLine = golden.add
+		}
+		_, _, err = genlines.GenerateTo(context.Background(), content, runOpts)
+	}
+	generated, written := st.Lines, st.Bytes
+	hist.written(generated, written)
+	if toStdout && (errors.Is(err, genlines.ErrOutputClosed) || genlines.IsOutputClosed(err)) {
+		cli.Info("%s", text("output.closed", generated))
+		return 0
+	}
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error:", err)
-		os.Exit(1)
+		cli.Error("%v", err)
+		return 1
+	}
+	if toFD {
+		if err := syncFD(f); err != nil {
+			cli.Error("syncing file: %v", err)
+			return 1
+		}
+	}
+	// Close before reporting, so a failed close (a deferred write error on
+	// a network mount, say) fails the run instead of passing unnoticed.
+	if err := closeOut(); err != nil {
+		cli.Error("closing file: %v", err)
+		return 1
+	}
+	if flags.gzIndex {
+		if err := writeGzIndex(filename, flags.gzMembers, members); err != nil {
+			cli.Error("writing member index: %v", err)
+			return 1
+		}
+		cli.Info("Wrote member index %s", filename+gzIndexSuffix)
+	}
+	if golden != nil {
+		if err := golden.commit(); err != nil {
+			cli.Error("writing golden file: %v", err)
+			return 1
+		}
+		cli.Info("Wrote golden file %s", flags.golden)
 	}
 
-	for i := 0; i < lines; i++ {
-		line := gen.NextLine(width)
-		if _, err := w.WriteString(line + "\n"); err != nil {
-			fmt.Fprintln(os.Stderr, "Error writing:", err)
-			os.Exit(1)
+	if flags.verifyAfter {
+		if beforeVerify != nil {
+			beforeVerify([]string{filename})
+		}
+		if code := reportVerify(filename, verifyFile(filename, start, opts, st.Checksums["sha256"])); code != 0 {
+			return code
 		}
 	}
 
-	fmt.Println("Done!")
-}
+	if flags.manifest != "" {
+		m := newManifest()
+		m.add(filename, generated, written, st.Checksums["sha256"])
+		if err := m.write(flags.manifest); err != nil {
+			cli.Error("writing manifest: %v", err)
+			return 1
+		}
+		cli.Info("Wrote manifest %s", flags.manifest)
+	}
 
-// helpHint returns the preferred help command hint for the current OS.
-func helpHint() string {
-	if runtime.GOOS == "windows" {
-		return `Tip: run "generatelines /?" for parameters and modes.`
+	if writeMetaFile {
+		meta := newRunMeta(filename, opts, written, st.Checksums["sha256"])
+		meta.Sources = sourceSummary(params)
+		if err := writeMeta(filename+metaSuffix, meta); err != nil {
+			cli.Error("writing metadata: %v", err)
+			return 1
+		}
+		cli.Info("Recorded settings in %s (reproduce with: %s regen %s)",
+			filename+metaSuffix, cmdName, filename+metaSuffix)
 	}
-	return `Tip: run "generatelines -h" for parameters and modes.`
-}
 
-// printHelp prints command usage, parameters, and available modes to stdout.
-func printHelp() {
-	fmt.Printf(`GenerateLines v%s
-Author: %s
-Repository: %s
-
-Generate a text file with N lines of repeatable content.
-
-Usage:
-  generatelines <lines> <filename> [y|n] [width] [mode] [modeArg]
-  generatelines /?
-  generatelines help
-  generatelines -h
-  generatelines --help
-  generatelines version
-  generatelines --version
-
-Parameters (positional):
-  lines        Number of lines to generate (required unless prompted)
-  filename     Output file name (required unless prompted)
-
-Optional parameters:
-  y | n        Auto-answer overwrite prompt if file already exists
-  width        Line width (columns). Default: 80
-  mode         Content generation mode. Default: ascii
-  modeArg      Additional argument for selected mode
-
-Modes:
-  ascii        Printable ASCII characters (32–126)
-  digits       Digits 0–9
-  upper        Uppercase letters A–Z
-  char         Repeat a single character (requires modeArg)
-               Example: generatelines 100 out.txt y 80 char #
-  pi           Digits of pi (default: digits)
-               modeArg: digits | ascii
-               digits -> pure pi digits (0–9)
-               ascii  -> pi digits mapped to printable ASCII (32–126)
-               Total digits generated = lines × width
-
-Notes:
-  - If parameters are omitted, the program will prompt interactively.
-  - Defaults are width=80 and mode=ascii.
-
-Examples:
-  generatelines 1000 lines.txt
-  generatelines 1000 lines.txt y
-  generatelines 1000 uppercase.txt y 120 upper
-  generatelines 1000 characters.txt y 80 char #
-  generatelines 1000 pi.txt n 80 pi
-`, version, authorName, repoURL)
+	if flags.summaryJSON != "" {
+		summary := newRunSummary(filename, opts, st, sourceSummary(params))
+		if writeMetaFile {
+			summary.Meta = filename + metaSuffix
+		}
+		summary.Manifest, summary.Golden = flags.manifest, flags.golden
+		if err := summary.write(flags.summaryJSON); err != nil {
+			cli.Error("writing summary: %v", err)
+			return 1
+		}
+		cli.Info("Wrote summary %s", flags.summaryJSON)
+	}
+
+	if stats != nil {
+		stats.Finish()
+		printStats(cli.infoOut(), stats)
+	}
+	printInjected(flags, st)
+	if head != nil {
+		warnSignature(flags, filename, head.head)
+	}
+	cli.Verbose("Output: %s", fw.summary())
+	if throttle != nil {
+		cli.Info("%s", throttle.summary())
+	}
+	if flags.flushEvery > 0 {
+		cli.Verbose("Flushed %d times (every %d lines and at the end)", st.Flushes, flags.flushEvery)
+	}
+	if len(flags.alsoLink) > 0 && !alsoLink(prompt, filename, flags.alsoLink, flags.noNameCheck, flags.wipeOld) {
+		cli.Error("not every --also-link destination was written; %s itself is complete", filename)
+		return 1
+	}
+
+	if opts.ExactBytes > 0 {
+		if generated == 1 {
+			cli.Summary("%s", text("done.exactOne", tail, written))
+		} else {
+			cli.Summary("%s", text("done.exact", generated, tail, written))
+		}
+		return 0
+	}
+	if lines == 0 && !flags.appendOut {
+		cli.Info("%s", text("generate.empty", filename))
+		return 0
+	}
+	if physical != nil {
+		cli.Summary("%s", text("done.records", generated, physical.n))
+		return 0
+	}
+	if binrec && opts.MaxBytes == 0 && flags.gzMembers == 0 {
+		size, _ := genlines.BinrecSize(modeArg)
+		cli.Summary("%s", text("done.binrec", generated, size, written))
+		return 0
+	}
+	if flags.gzMembers > 0 {
+		cli.Summary("%s", text("done.gzip", len(members), flags.gzMembers, written))
+		return 0
+	}
+	if opts.MaxBytes > 0 {
+		cli.Summary("%s %s", text("done"), maxBytesSummary(int(generated), lines, existing+written, flags.maxBytes))
+		return 0
+	}
+	if opts.Align > 0 {
+		cli.Summary("%s", text("done.align", pads, opts.Align, written))
+		return 0
+	}
+	cli.Summary("%s", text("done"))
+	return 0
 }
 
 // getArgsOrPrompt parses positional CLI arguments, or falls back to interactive prompts
-// when required arguments are missing. It also reports whether width/mode were chosen
-// implicitly (defaults) or explicitly provided by the user.
-func getArgsOrPrompt(args []string) (
+// when required arguments are missing. It also reports where each value came from: a
+// positional argument, a prompt or the default (see argSources). Arguments after modeArg
+// are ignored with a warning; with strict they are an error, and so is a token that
+// could be more than one argument (see ambiguousArgs).
+func getArgsOrPrompt(args []string, strict bool) (
 	lines int,
 	filename string,
 	overwriteFlag string,
 	width int,
 	mode string,
 	modeArg string,
-	usedDefaultWidth bool,
-	usedDefaultMode bool,
+	src argSources,
 	err error,
 ) {
 
@@ -216,35 +818,57 @@ func getArgsOrPrompt(args []string) (
 
 	width = defaultWidth
 	mode = "ascii"
-	usedDefaultWidth = true
-	usedDefaultMode = true
 
 	// Use one reader for the entire interactive sequence (important for tests and pipes).
 	in := bufio.NewReader(os.Stdin)
 
 	if len(args) == 0 {
-		linesStr, err = promptLineR(in, "Enter number of lines: ")
+		linesStr, err = promptLineR(in, text("prompt.lines"))
 		if err != nil {
 			return
 		}
-		fileStr, err = promptLineR(in, "Enter filename: ")
+		fileStr, err = promptLineR(in, text("prompt.filename"))
 		if err != nil {
 			return
 		}
+		src.lines, src.filename = prompted, prompted
 	} else if len(args) == 1 {
 		linesStr = args[0]
-		fileStr, err = promptLineR(in, "Enter filename: ")
+		fileStr, err = promptLineR(in, text("prompt.filename"))
 		if err != nil {
 			return
 		}
+		src.lines, src.filename = positional(1), prompted
 	} else {
 		linesStr = args[0]
 		fileStr = args[1]
+		src.lines, src.filename = positional(1), positional(2)
+		if strict {
+			if err = ambiguousArgs(args); err != nil {
+				return
+			}
+		}
+		if swapped(linesStr, fileStr) {
+			linesStr, fileStr = fileStr, linesStr
+			src.lines, src.filename = src.filename, src.lines
+			cli.Info("Note: assuming %s is the number of lines and %s the filename (the usual order is <lines> <filename>)",
+				strings.TrimSpace(linesStr), strings.TrimSpace(fileStr))
+		}
 	}
 
-	lines, err = parsePositiveInt(linesStr)
-	if err != nil {
-		err = fmt.Errorf(`invalid number of lines: %q (expected a positive integer)`, strings.TrimSpace(linesStr))
+	// A reference file is measured before anything else is looked at, so a
+	// missing one fails the run before any file is created.
+	matchPath, matchWidth, isMatch := parseMatchArg(linesStr)
+	var shape fileShape
+	if isMatch {
+		if shape, err = measureFile(matchPath); err != nil {
+			err = fmt.Errorf("cannot match the reference file: %w", err)
+			return
+		}
+		lines, src.lines = shape.lines, provenance{from: fromMatch, name: matchPath}
+		cli.Info("Matched %s: %d lines, widest %d columns", matchPath, shape.lines, shape.width)
+	} else if lines, err = parseLineCount(linesStr); err != nil {
+		err = fmt.Errorf(`invalid number of lines: %q (%v)`, strings.TrimSpace(linesStr), err)
 		return
 	}
 
@@ -258,48 +882,89 @@ func getArgsOrPrompt(args []string) (
 	if len(args) >= 3 {
 		rest = args[2:]
 	}
+	// next is the position of rest[0] among the arguments, counting from 1.
+	next := 3
 
 	if len(rest) >= 1 && looksLikeYesNo(rest[0]) {
 		overwriteFlag = rest[0]
-		rest = rest[1:]
+		src.overwrite = positional(next)
+		rest, next = rest[1:], next+1
 	}
 
 	if len(rest) >= 1 {
 		if n, werr := parsePositiveInt(rest[0]); werr == nil {
 			width = n
-			usedDefaultWidth = false
-			rest = rest[1:]
+			src.width = positional(next)
+			rest, next = rest[1:], next+1
+		} else if isTermWidth(rest[0]) {
+			width, err = resolveTermWidth(rest[0])
+			if err != nil {
+				return
+			}
+			src.width = positional(next)
+			rest, next = rest[1:], next+1
 		}
 	}
 
 	if len(rest) >= 1 {
-		mode = strings.ToLower(strings.TrimSpace(rest[0]))
-		usedDefaultMode = false
-		rest = rest[1:]
+		mode = strings.TrimSpace(rest[0])
+		src.mode = positional(next)
+		rest, next = rest[1:], next+1
 	}
 
 	if len(rest) >= 1 {
 		modeArg = rest[0]
+		src.modeArg = positional(next)
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		if strict {
+			err = fmt.Errorf("unexpected arguments after modeArg: %s", strings.Join(rest, " "))
+			return
+		}
+		cli.Warn("ignoring arguments after modeArg: %s (--strict-args makes this an error)", strings.Join(rest, " "))
 	}
 
-	switch mode {
-	case "", "ascii":
-		mode = "ascii"
-	case "digit", "digits":
-		mode = "digits"
-	case "upper", "uppercase":
-		mode = "upper"
-	case "char", "character":
-		mode = "char"
-	case "pi":
-		mode = "pi"
-	default:
-		err = fmt.Errorf("unknown mode: %s", mode)
+	var implied string
+	if mode, implied, err = normalizeMode(mode); err != nil {
 		return
 	}
+	if implied != "" {
+		if modeArg, err = mergeModeArg(implied, modeArg); err != nil {
+			return
+		}
+		src.modeArg = src.mode
+	}
+
+	if matchWidth {
+		switch {
+		case src.width.from != fromDefault:
+			err = fmt.Errorf("%s takes the width from %s; leave out the width argument", matchWidthPrefix, matchPath)
+			return
+		case shape.width == 0:
+			err = fmt.Errorf("%s has no non-empty line to take the width from; use %s with a width", matchPath, matchPrefix)
+			return
+		}
+		width, src.width = shape.width, src.lines
+	}
+
+	if genlines.IsInterleaveSpec(mode) {
+		if src.width.from != fromDefault {
+			err = errors.New("a width cannot be combined with an interleave spec; each stream sets its own width")
+			return
+		}
+		if modeArg != "" {
+			err = errors.New("an interleave spec takes no modeArg; use mode:width:arg per stream")
+			return
+		}
+	}
 
 	if mode == "char" {
-		modeArg = strings.TrimSpace(modeArg)
+		// Keep a whitespace character (e.g. a tab) so it can be rejected or
+		// allowed explicitly rather than vanishing.
+		if trimmed := strings.TrimSpace(modeArg); trimmed != "" {
+			modeArg = trimmed
+		}
 		if modeArg == "" {
 			err = errors.New("mode=char requires modeArg")
 			return
@@ -308,12 +973,154 @@ func getArgsOrPrompt(args []string) (
 
 	if width <= 0 {
 		width = defaultWidth
-		usedDefaultWidth = true
+		src.width = provenance{}
 	}
 
 	return
 }
 
+// decodeModeArg expands the escapes \\, \s (space), \t, \n, \r, \v, \f, \a,
+// \b, \e, \0 and \xHH in a character modeArg and rejects control characters
+// unless allowControl is set.
+func decodeModeArg(s string, allowControl bool) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case '\\':
+			b.WriteByte('\\')
+		case 's':
+			b.WriteByte(' ')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'v':
+			b.WriteByte('\v')
+		case 'f':
+			b.WriteByte('\f')
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'e':
+			b.WriteByte(0x1b)
+		case '0':
+			b.WriteByte(0)
+		case 'x':
+			if i+2 >= len(s) {
+				return "", fmt.Errorf("invalid escape in modeArg %q: \\x needs two hex digits", s)
+			}
+			v, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape in modeArg %q: \\x%s", s, s[i+1:i+3])
+			}
+			b.WriteByte(byte(v))
+			i += 2
+		default:
+			return "", fmt.Errorf("unknown escape \\%c in modeArg %q (use \\\\ for a backslash)", c, s)
+		}
+	}
+
+	out := b.String()
+	if !allowControl {
+		for _, r := range out {
+			if unicode.IsControl(r) {
+				return "", fmt.Errorf("modeArg contains the control character %U (%q); use --allow-control to write it anyway", r, r)
+			}
+		}
+	}
+	return out, nil
+}
+
+// swapped reports whether the first two arguments look like <filename> <lines>:
+// only the second is a lines argument. When both or neither are, the
+// documented order applies, so an all-numeric filename is never swapped.
+func swapped(first, second string) bool {
+	return !isLinesArg(first) && isLinesArg(second)
+}
+
+// isLinesArg reports whether s is a line count or a match:<path> argument.
+func isLinesArg(s string) bool {
+	if _, _, ok := parseMatchArg(s); ok {
+		return true
+	}
+	_, err := parseLineCount(s)
+	return err == nil
+}
+
+// ambiguousArgs reports positional arguments (args[0] and on) that could
+// bind to more than one parameter, listing the possibilities: <lines> and
+// <filename> in the wrong order, a number in the filename position (a
+// forgotten filename makes the width the file name), and a width that is also
+// the name of a registered mode.
+func ambiguousArgs(args []string) error {
+	first, second := args[0], args[1]
+	if swapped(first, second) {
+		return fmt.Errorf("ambiguous arguments %q %q: the order is <lines> <filename>, which makes %q the line count; write %s %s",
+			first, second, first, strings.TrimSpace(second), strings.TrimSpace(first))
+	}
+	if _, err := parseLineCount(second); err == nil {
+		return fmt.Errorf("ambiguous argument %q: it could be <filename> or [width] after a missing filename; name the file ./%s to write it",
+			second, strings.TrimSpace(second))
+	}
+	rest := args[2:]
+	if len(rest) > 0 && looksLikeYesNo(rest[0]) {
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		if _, err := parsePositiveInt(rest[0]); err == nil {
+			if _, _, err := genlines.LookupMode(rest[0]); err == nil {
+				return fmt.Errorf("ambiguous argument %q: it could be [width] or [mode] (a registered mode has that name)", rest[0])
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeMode maps a user-supplied mode name or alias to its canonical name.
+//
+// lorem takes its language after a colon too (lorem:de), which is split off
+// before the colon can be taken for an interleave spec and returned as
+// modeArg; see mergeModeArg.
+func normalizeMode(mode string) (name, modeArg string, err error) {
+	mode = strings.TrimSpace(mode)
+	if mode == "" {
+		return genlines.DefaultMode, "", nil
+	}
+	if base, lang, ok := strings.Cut(mode, ":"); ok && !strings.Contains(lang, "+") {
+		if name, _, err := genlines.LookupMode(base); err == nil && name == "lorem" {
+			return name, lang, nil
+		}
+	}
+	if genlines.IsInterleaveSpec(mode) {
+		if _, err := genlines.ParseInterleave(mode); err != nil {
+			return "", "", err
+		}
+		return mode, "", nil
+	}
+	name, _, err = genlines.LookupMode(mode)
+	return name, "", err
+}
+
+// mergeModeArg returns the modeArg of a run given both the one its mode
+// implied (lorem:de) and the modeArg argument, which must then agree.
+func mergeModeArg(implied, given string) (string, error) {
+	switch {
+	case implied == "":
+		return given, nil
+	case given == "" || given == implied:
+		return implied, nil
+	}
+	return "", fmt.Errorf("the mode names %q but modeArg is %q; give it once", implied, given)
+}
+
 // looksLikeYesNo reports whether s is a valid yes/no token (y/yes/n/no), case-insensitive.
 func looksLikeYesNo(s string) bool {
 	s = strings.TrimSpace(s)
@@ -333,221 +1140,246 @@ func parsePositiveInt(s string) (int, error) {
 	return n, nil
 }
 
-// promptLineR prints a prompt and reads a single line from r.
-func promptLineR(r *bufio.Reader, prompt string) (string, error) {
-	fmt.Print(prompt)
+// isTermWidth reports whether s is a terminal width token: "term",
+// optionally followed by a +N or -N offset (e.g. term-2).
+func isTermWidth(s string) bool {
+	s = strings.ToLower(strings.TrimSpace(s))
+	rest, ok := strings.CutPrefix(s, "term")
+	if !ok {
+		return false
+	}
+	if rest == "" {
+		return true
+	}
+	if rest[0] != '+' && rest[0] != '-' {
+		return false
+	}
+	_, err := strconv.Atoi(rest[1:])
+	return err == nil && !strings.ContainsAny(rest[1:], "+-")
+}
 
-	text, err := r.ReadString('\n')
-	if err != nil {
-		// Accept EOF if we got some input (e.g. piped input without trailing newline)
-		if len(text) == 0 {
-			return "", err
-		}
+// parseTermWidth resolves a terminal width token against probe. When stdout
+// is not a terminal the width falls back to defaultWidth (plus the offset) and
+// fallback is true so the caller can warn.
+func parseTermWidth(s string, probe terminalProbe) (width int, fallback bool, err error) {
+	token := strings.ToLower(strings.TrimSpace(s))
+	if !isTermWidth(token) {
+		return 0, false, fmt.Errorf("invalid terminal width %q (expected term, term+N or term-N)", s)
+	}
+	offset := 0
+	if rest := token[len("term"):]; rest != "" {
+		offset, _ = strconv.Atoi(rest)
 	}
 
-	return strings.TrimSpace(text), nil
+	cols, ok := probe.Columns()
+	if !ok {
+		cols, fallback = defaultWidth, true
+	}
+	width = cols + offset
+	if width <= 0 {
+		return 0, fallback, fmt.Errorf("width %s resolves to %d columns (must be > 0)", strings.TrimSpace(s), width)
+	}
+	return width, fallback, nil
 }
 
-// promptYesNoR prompts the user until a yes/no answer is provided (y/yes/n/no), case-insensitive.
-func promptYesNoR(r *bufio.Reader, prompt string) (bool, error) {
-	for {
-		s, err := promptLineR(r, prompt)
-		if err != nil {
-			return false, err
-		}
-		if strings.EqualFold(s, "y") || strings.EqualFold(s, "yes") {
-			return true, nil
-		}
-		if strings.EqualFold(s, "n") || strings.EqualFold(s, "no") {
-			return false, nil
-		}
-		fmt.Println("Please answer y or n.")
+// resolveTermWidth is parseTermWidth against the real terminal, warning on stderr
+// when it has to fall back.
+func resolveTermWidth(s string) (int, error) {
+	width, fallback, err := parseTermWidth(s, termProbe)
+	if err == nil && fallback {
+		cli.Warn("stdout is not a terminal; width %s uses %d columns", strings.TrimSpace(s), width)
 	}
+	return width, err
 }
 
-// parseYesNo reports whether s is a "yes" token (y/yes), case-insensitive.
-func parseYesNo(s string) bool {
-	return strings.EqualFold(strings.TrimSpace(s), "y") ||
-		strings.EqualFold(strings.TrimSpace(s), "yes")
+// lineCountMultipliers maps the accepted count suffixes to their (decimal) value.
+var lineCountMultipliers = map[byte]int64{
+	'k': 1_000,
+	'm': 1_000_000,
+	'g': 1_000_000_000,
 }
 
-// fileExists reports whether the given file path exists.
-func fileExists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
-}
+// parseLineCount parses a non-negative line count (0 means an empty file). Besides plain integers it accepts
+// digit separators (50_000_000, 50,000,000) and the decimal multipliers K, M and G
+// (50M, 1.5G). Suffixes always mean lines, never bytes, so byte units such as
+// 10MB are rejected rather than guessed at.
+func parseLineCount(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("expected a non-negative integer")
+	}
 
-// Generator produces fixed-width lines of content for output files.
-type Generator interface {
-	NextLine(width int) string
-}
+	lower := strings.ToLower(s)
+	if strings.HasSuffix(lower, "b") || strings.HasSuffix(lower, "ib") {
+		return 0, errors.New("byte units are not line counts; use K, M or G for thousands, millions or billions of lines")
+	}
 
-// newGenerator constructs a Generator for the given mode.
-// totalChars is used for sizing when mode requires precomputation (e.g. pi).
-func newGenerator(mode, modeArg string, totalChars int) (Generator, error) {
-	switch mode {
-	case "ascii":
-		return &cycleGen{palette: []byte(buildAsciiSequence())}, nil
+	mult := int64(1)
+	if m, ok := lineCountMultipliers[lower[len(lower)-1]]; ok {
+		mult = m
+		s = s[:len(s)-1]
+	}
 
-	case "digits":
-		return &cycleGen{palette: []byte("0123456789")}, nil
+	intPart, fracPart, hasFrac := strings.Cut(s, ".")
+	digits, err := stripDigitSeparators(intPart)
+	if err != nil {
+		return 0, err
+	}
 
-	case "upper":
-		return &cycleGen{palette: []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ")}, nil
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, errors.New("expected a non-negative integer")
+	}
+	if n > math.MaxInt64/mult {
+		return 0, errors.New("line count is too large")
+	}
+	n *= mult
 
-	case "char":
-		modeArg = strings.TrimSpace(modeArg)
-		if modeArg == "" {
-			return nil, errors.New("mode=char requires modeArg")
+	if hasFrac {
+		if mult == 1 {
+			return 0, errors.New("fractional line counts need a K, M or G suffix (e.g. 1.5M)")
 		}
-		r := []rune(modeArg)
-		if len(r) == 0 {
-			return nil, errors.New("mode=char requires modeArg")
+		f, ferr := strconv.ParseInt(fracPart, 10, 64)
+		if ferr != nil || fracPart == "" || strings.ContainsAny(fracPart, "+-") {
+			return 0, errors.New("malformed fraction")
 		}
-		return &singleCharGen{ch: string(r[0])}, nil
-
-	case "pi":
-		if totalChars <= 0 {
-			totalChars = 1
+		scale := int64(1)
+		for range fracPart {
+			if scale > mult {
+				break
+			}
+			scale *= 10
 		}
-
-		arg := strings.ToLower(strings.TrimSpace(modeArg))
-		var palette []byte
-		switch arg {
-		case "", "digits":
-			// Default: emit pure pi digits (0–9).
-			palette = []byte("0123456789")
-		case "ascii":
-			// Legacy: map pi digits onto printable ASCII (32–126).
-			palette = []byte(buildAsciiSequence())
-		default:
-			return nil, fmt.Errorf("mode=pi unknown modeArg: %s (expected digits or ascii)", modeArg)
+		if scale > mult || (f*mult)%scale != 0 {
+			return 0, errors.New("fraction does not resolve to a whole number of lines")
 		}
-
-		return &piGen{
-			palette: palette,
-			spigot:  newPiSpigot(totalChars),
-		}, nil
-
-	default:
-		return nil, errors.New("unknown mode")
+		n += f * mult / scale
 	}
-}
-
-// cycleGen emits characters by cycling through a fixed palette.
-type cycleGen struct {
-	palette []byte
-	pos     int
-}
 
-// NextLine returns the next line of output with the given width.
-func (g *cycleGen) NextLine(width int) string {
-	out := make([]byte, width)
-	for i := 0; i < width; i++ {
-		out[i] = g.palette[g.pos%len(g.palette)]
-		g.pos++
+	if n < 0 {
+		return 0, errors.New("must be >= 0")
 	}
-	return string(out)
-}
-
-// singleCharGen emits a line consisting of a single repeated character.
-type singleCharGen struct {
-	ch string
+	if n > math.MaxInt {
+		return 0, errors.New("line count is too large")
+	}
+	return int(n), nil
 }
 
-func (g *singleCharGen) NextLine(width int) string {
-	return strings.Repeat(g.ch, width)
+// stripDigitSeparators removes "_" between digits, or "," between groups of three
+// digits, rejecting mixed or misplaced separators.
+func stripDigitSeparators(s string) (string, error) {
+	hasUnderscore := strings.Contains(s, "_")
+	hasComma := strings.Contains(s, ",")
+	switch {
+	case hasUnderscore && hasComma:
+		return "", errors.New("use either _ or , as a digit separator, not both")
+	case hasUnderscore:
+		for _, part := range strings.Split(s, "_") {
+			if part == "" {
+				return "", errors.New("misplaced _ separator")
+			}
+		}
+		return strings.ReplaceAll(s, "_", ""), nil
+	case hasComma:
+		groups := strings.Split(s, ",")
+		if len(groups[0]) == 0 || len(groups[0]) > 3 {
+			return "", errors.New("misplaced , separator (expected groups of three digits)")
+		}
+		for _, g := range groups[1:] {
+			if len(g) != 3 {
+				return "", errors.New("misplaced , separator (expected groups of three digits)")
+			}
+		}
+		return strings.ReplaceAll(s, ",", ""), nil
+	}
+	return s, nil
 }
 
-// piGen emits digits of π mapped onto a printable ASCII palette.
-type piGen struct {
-	palette []byte
-	spigot  *piSpigot
+// byteSizeUnits maps the accepted size suffixes to their (binary) multiplier.
+var byteSizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1 << 30,
+	"gib": 1 << 30,
 }
 
-func (g *piGen) NextLine(width int) string {
-	out := make([]byte, width)
-	for i := 0; i < width; i++ {
-		out[i] = g.palette[g.spigot.NextDigit()%len(g.palette)]
+// parseByteSize parses a positive byte size such as 4096, 64K, 10MB or 1GiB.
+// All multipliers are binary (K = 1024).
+func parseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := len(s)
+	for i > 0 && (s[i-1] < '0' || s[i-1] > '9') {
+		i--
 	}
-	return string(out)
-}
-
-// buildAsciiSequence returns printable ASCII characters (32..126) as a string.
-func buildAsciiSequence() string {
-	var b strings.Builder
-	for i := 32; i <= 126; i++ {
-		b.WriteByte(byte(i))
+	mult, ok := byteSizeUnits[strings.ToLower(s[i:])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q (unknown unit %q)", s, s[i:])
 	}
-	return b.String()
-}
-
-// piSpigot implements a base-10 spigot algorithm for streaming digits of π.
-type piSpigot struct {
-	a        []int
-	queue    []int
-	nines    int
-	predigit int
-	started  bool
+	digits, err := stripDigitSeparators(s[:i])
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %v", s, err)
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q (expected a positive number of bytes)", s)
+	}
+	if n > math.MaxInt64/mult {
+		return 0, fmt.Errorf("invalid size %q (too large)", s)
+	}
+	return n * mult, nil
 }
 
-// newPiSpigot creates a spigot sized to generate at least the given number of digits.
-func newPiSpigot(digits int) *piSpigot {
-	size := digits*10/3 + 1
-	a := make([]int, size)
-	for i := range a {
-		a[i] = 2
+// promptLineR prints a prompt and reads a single line from r.
+// Batch jobs get errNoPrompt instead.
+func promptLineR(r *bufio.Reader, prompt string) (string, error) {
+	if promptsDisabled {
+		return "", fmt.Errorf("%w: %s", errNoPrompt, strings.TrimSuffix(strings.TrimSpace(prompt), ":"))
 	}
-	return &piSpigot{a: a, queue: make([]int, 0, 32)}
-}
+	cli.Prompt(prompt)
 
-// NextDigit returns the next digit of π (0..9).
-func (p *piSpigot) NextDigit() int {
-	if len(p.queue) > 0 {
-		d := p.queue[0]
-		p.queue = p.queue[1:]
-		return d
+	text, err := r.ReadString('\n')
+	if err != nil {
+		// Accept EOF if we got some input (e.g. piped input without trailing newline)
+		if len(text) == 0 {
+			return "", err
+		}
 	}
 
+	return strings.TrimSpace(text), nil
+}
+
+// promptYesNoR prompts the user until a yes/no answer is provided (y/yes/n/no), case-insensitive.
+func promptYesNoR(r *bufio.Reader, prompt string) (bool, error) {
 	for {
-		q := 0
-		for i := len(p.a) - 1; i >= 0; i-- {
-			x := 10*p.a[i] + q*(i+1)
-			den := 2*(i+1) - 1
-			p.a[i] = x % den
-			q = x / den
-		}
-		p.a[0] = q % 10
-		q /= 10
-
-		switch q {
-		case 9:
-			p.nines++
-		case 10:
-			p.queue = append(p.queue, p.predigit+1)
-			for i := 0; i < p.nines; i++ {
-				p.queue = append(p.queue, 0)
-			}
-			p.predigit = 0
-			p.nines = 0
-		default:
-			p.queue = append(p.queue, p.predigit)
-			for i := 0; i < p.nines; i++ {
-				p.queue = append(p.queue, 9)
-			}
-			p.predigit = q
-			p.nines = 0
+		s, err := promptLineR(r, prompt)
+		if err != nil {
+			return false, err
 		}
-
-		for len(p.queue) > 0 {
-			if !p.started && p.queue[0] == 0 {
-				p.queue = p.queue[1:]
-				continue
-			}
-			p.started = true
-			d := p.queue[0]
-			p.queue = p.queue[1:]
-			return d
+		if strings.EqualFold(s, "y") || strings.EqualFold(s, "yes") {
+			return true, nil
+		}
+		if strings.EqualFold(s, "n") || strings.EqualFold(s, "no") {
+			return false, nil
 		}
+		cli.Prompt(text("overwrite.retry") + "\n")
 	}
 }
+
+// parseYesNo reports whether s is a "yes" token (y/yes), case-insensitive.
+func parseYesNo(s string) bool {
+	return strings.EqualFold(strings.TrimSpace(s), "y") ||
+		strings.EqualFold(strings.TrimSpace(s), "yes")
+}
+
+// fileExists reports whether the given file path exists.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -1,275 +1,1019 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
 )
 
-func TestBuildAsciiSequence(t *testing.T) {
-	s := buildAsciiSequence()
-	if len(s) != (126 - 32 + 1) {
-		t.Fatalf("expected ascii palette length 95, got %d", len(s))
+func TestGetArgsOrPrompt_DefaultFlags_WhenOmitted(t *testing.T) {
+	// Only required args -> defaults should be used (width + mode)
+	lines, filename, ow, width, mode, modeArg, src, err := getArgsOrPrompt([]string{"10", "out.txt"}, false)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
 	}
-	if s[0] != byte(32) || s[len(s)-1] != byte(126) {
-		t.Fatalf("expected palette to start at 32 and end at 126, got %d..%d", s[0], s[len(s)-1])
+	defW, defM := src.width.from == fromDefault, src.mode.from == fromDefault
+	if lines != 10 || filename != "out.txt" || ow != "" || width != defaultWidth || mode != "ascii" || modeArg != "" {
+		t.Fatalf("unexpected parsed result: lines=%d file=%q ow=%q width=%d mode=%q arg=%q",
+			lines, filename, ow, width, mode, modeArg)
+	}
+	if !defW || !defM {
+		t.Fatalf("expected defW=true and defM=true, got defW=%v defM=%v", defW, defM)
 	}
 }
 
-func TestNewGenerator_UnknownMode(t *testing.T) {
-	_, err := newGenerator("bananas", "", 100)
-	if err == nil {
-		t.Fatalf("expected error for unknown mode, got nil")
+func TestGetArgsOrPrompt_NoDefaultFlags_WhenUserSpecifiesDefaults(t *testing.T) {
+	// User explicitly sets width=80 and mode=ascii -> should NOT be marked as default usage
+	lines, filename, ow, width, mode, modeArg, src, err := getArgsOrPrompt([]string{"10", "out.txt", "80", "ascii"}, false)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	defW, defM := src.width.from == fromDefault, src.mode.from == fromDefault
+	if lines != 10 || filename != "out.txt" || ow != "" || width != 80 || mode != "ascii" || modeArg != "" {
+		t.Fatalf("unexpected parsed result: lines=%d file=%q ow=%q width=%d mode=%q arg=%q",
+			lines, filename, ow, width, mode, modeArg)
+	}
+	if defW || defM {
+		t.Fatalf("expected defW=false and defM=false, got defW=%v defM=%v", defW, defM)
 	}
 }
 
-func TestNewGenerator_CharModeRequiresArg(t *testing.T) {
-	// Note: in CLI parsing, mode=char without arg is rejected earlier.
-	// This test focuses on generator behavior with empty arg.
-	_, err := newGenerator("char", "", 100)
+func TestGetArgsOrPrompt_OverwriteFlag_CaseInsensitive(t *testing.T) {
+	lines, filename, ow, width, mode, _, src, err := getArgsOrPrompt([]string{"10", "out.txt", "Y"}, false)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	defW, defM := src.width.from == fromDefault, src.mode.from == fromDefault
+	if lines != 10 || filename != "out.txt" || strings.ToLower(ow) != "y" {
+		t.Fatalf("unexpected overwrite flag parsing: lines=%d file=%q ow=%q", lines, filename, ow)
+	}
+	if width != defaultWidth || mode != "ascii" || !defW || !defM {
+		t.Fatalf("unexpected defaults: width=%d mode=%q defW=%v defM=%v", width, mode, defW, defM)
+	}
+}
+
+func TestRun_LoremLanguageAfterColon(t *testing.T) {
+	dir := t.TempDir()
+	colon, spaced := filepath.Join(dir, "colon.txt"), filepath.Join(dir, "spaced.txt")
+	captureStdout(t)
+	for _, lang := range []string{"en", "de", "no"} {
+		if code := run([]string{"20", colon, "y", "72", "lorem:" + lang}); code != 0 {
+			t.Fatalf("lorem:%s: exit code %d", lang, code)
+		}
+		if code := run([]string{"20", spaced, "y", "72", "lorem", lang}); code != 0 {
+			t.Fatalf("lorem %s: exit code %d", lang, code)
+		}
+		a, _ := os.ReadFile(colon)
+		b, _ := os.ReadFile(spaced)
+		if len(a) == 0 || !bytes.Equal(a, b) {
+			t.Errorf("lorem:%s and lorem %s differ", lang, lang)
+		}
+	}
+
+	_, _, _, _, _, _, _, err := getArgsOrPrompt([]string{"10", "out.txt", "72", "lorem:de", "no"}, false)
+	if err == nil || !strings.Contains(err.Error(), `the mode names "de" but modeArg is "no"`) {
+		t.Errorf("two languages: %v", err)
+	}
+	if _, _, _, _, _, _, _, err = getArgsOrPrompt([]string{"10", "out.txt", "72", "lorem:fr"}, false); err != nil {
+		t.Errorf("the language is checked by the mode, not the parser: %v", err)
+	}
+}
+
+func TestGetArgsOrPrompt_ModeChar_RequiresModeArg(t *testing.T) {
+	_, _, _, _, _, _, _, err := getArgsOrPrompt([]string{"10", "out.txt", "80", "char"}, false)
 	if err == nil {
-		t.Fatalf("expected error for char mode without arg, got nil")
+		t.Fatalf("expected error for char mode without modeArg")
 	}
 }
 
-func TestGenerator_ASCII(t *testing.T) {
-	g, err := newGenerator("ascii", "", 1000)
+func TestGetArgsOrPrompt_InteractivePrompts(t *testing.T) {
+	// Simulate interactive input: lines=7, filename=test.txt
+	oldStdin := os.Stdin
+	defer func() { os.Stdin = oldStdin }()
+
+	tmp, err := os.CreateTemp("", "stdin-*")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	_, _ = tmp.WriteString("7\n")
+	_, _ = tmp.WriteString("test.txt\n")
+	_, _ = tmp.Seek(0, 0)
+
+	os.Stdin = tmp
+
+	lines, filename, ow, width, mode, modeArg, src, err := getArgsOrPrompt([]string{}, false)
 	if err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
+	defW, defM := src.width.from == fromDefault, src.mode.from == fromDefault
+	if lines != 7 || filename != "test.txt" || ow != "" || width != defaultWidth || mode != "ascii" || modeArg != "" {
+		t.Fatalf("unexpected interactive result: lines=%d file=%q ow=%q width=%d mode=%q arg=%q",
+			lines, filename, ow, width, mode, modeArg)
+	}
+	if !defW || !defM {
+		t.Fatalf("expected defaults for width+mode in interactive flow")
+	}
+}
 
-	width := 80
-	palette := []byte(buildAsciiSequence())
-	if len(palette) == 0 {
-		t.Fatal("ascii palette is empty")
+func TestSplitFlags(t *testing.T) {
+	pos, flags, err := splitFlags([]string{"10", "--comment-every", "5", "out.txt", "--comment-text=# n=%d", "y"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if strings.Join(pos, " ") != "10 out.txt y" {
+		t.Fatalf("unexpected positional args: %q", pos)
+	}
+	if flags.commentEvery != 5 || flags.commentText != "# n=%d" {
+		t.Fatalf("unexpected flags: %+v", flags)
 	}
+}
 
-	line1 := g.NextLine(width)
-	if len(line1) != width {
-		t.Fatalf("expected length %d, got %d", width, len(line1))
+func TestSplitFlags_Errors(t *testing.T) {
+	cases := [][]string{
+		{"10", "out.txt", "--bogus"},
+		{"10", "out.txt", "--comment-every"},
+		{"10", "out.txt", "--comment-every", "0"},
+		{"10", "out.txt", "--comment-text", "# %s"},
+	}
+	for _, args := range cases {
+		if _, _, err := splitFlags(args); err == nil {
+			t.Fatalf("expected error for %q", args)
+		}
 	}
+}
 
-	// Ensure all chars are printable ASCII (32..126)
-	for i := 0; i < len(line1); i++ {
-		if line1[i] < 32 || line1[i] > 126 {
-			t.Fatalf("non-printable ascii at %d: %d", i, line1[i])
+func TestSplitFlags_DoubleDashEndsOptions(t *testing.T) {
+	pos, _, err := splitFlags([]string{"10", "--", "--weird-name.txt"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(pos) != 2 || pos[1] != "--weird-name.txt" {
+		t.Fatalf("unexpected positional args: %q", pos)
+	}
+}
+
+func TestParseLineCount(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"1000", 1000, true},
+		{" 42 ", 42, true},
+		{"50_000_000", 50_000_000, true},
+		{"50,000,000", 50_000_000, true},
+		{"10K", 10_000, true},
+		{"50M", 50_000_000, true},
+		{"50m", 50_000_000, true},
+		{"1.5G", 1_500_000_000, true},
+		{"2.25k", 2_250, true},
+		{"1_000K", 1_000_000, true},
+		{"1.5", 0, false},
+		{"10MB", 0, false},
+		{"10MiB", 0, false},
+		{"1.2345K", 0, false},
+		{"5,00", 0, false},
+		{"1__000", 0, false},
+		{"_100", 0, false},
+		{"1_000,000", 0, false},
+		{"0", 0, true},
+		{"0K", 0, true},
+		{"-5", 0, false},
+		{"abc", 0, false},
+		{"M", 0, false},
+		{"99999999999G", 0, false},
+	}
+
+	for _, c := range cases {
+		got, err := parseLineCount(c.in)
+		if c.ok && (err != nil || got != c.want) {
+			t.Fatalf("parseLineCount(%q) = %d, %v; want %d", c.in, got, err, c.want)
+		}
+		if !c.ok && err == nil {
+			t.Fatalf("parseLineCount(%q) = %d; want error", c.in, got)
 		}
 	}
+}
 
-	// Verify deterministic cycling:
-	// line1 should start at palette[0] and line2 should start at palette[width].
-	if line1[0] != palette[0] {
-		t.Fatalf("expected first char %q, got %q", palette[0], line1[0])
+func TestGetArgsOrPrompt_LinesWithSuffix(t *testing.T) {
+	lines, _, _, _, _, _, _, err := getArgsOrPrompt([]string{"1.5K", "out.txt"}, false)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if lines != 1500 {
+		t.Fatalf("expected 1500 lines, got %d", lines)
 	}
+}
 
-	line2 := g.NextLine(width)
-	if len(line2) != width {
-		t.Fatalf("expected length %d, got %d", width, len(line2))
+func TestParseByteSize(t *testing.T) {
+	cases := map[string]int64{
+		"4096":  4096,
+		"10K":   10 << 10,
+		"10MB":  10 << 20,
+		"1GiB":  1 << 30,
+		"1_024": 1024,
+		"64b":   64,
+	}
+	for in, want := range cases {
+		got, err := parseByteSize(in)
+		if err != nil || got != want {
+			t.Fatalf("parseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
 	}
+	for _, in := range []string{"", "0", "10X", "M", "1.5M", "-1K"} {
+		if _, err := parseByteSize(in); err == nil {
+			t.Fatalf("parseByteSize(%q): expected error", in)
+		}
+	}
+}
+
+func TestRun_ZeroLinesCreatesEmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.txt")
+	if code := run([]string{"0", path, "y", "80", "pi"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("expected file to be created: %v", err)
+	}
+	if fi.Size() != 0 {
+		t.Fatalf("expected empty file, got %d bytes", fi.Size())
+	}
+}
 
-	want2First := palette[width%len(palette)]
-	if line2[0] != want2First {
-		t.Fatalf("expected second line first char %q, got %q", want2First, line2[0])
+func TestRun_ZeroLinesOverwriteInteraction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "existing.txt")
+	if err := os.WriteFile(path, []byte("keep me\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
 	}
 
-	// Optional: also check that a known index matches expected palette advancement.
-	// line1[i] should equal palette[i]
-	checkIdx := 10
-	if line1[checkIdx] != palette[checkIdx%len(palette)] {
-		t.Fatalf("expected line1[%d]=%q, got %q", checkIdx, palette[checkIdx%len(palette)], line1[checkIdx])
+	if code := run([]string{"0", path, "n"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "keep me\n" {
+		t.Fatalf("expected file untouched with n, got %q", data)
+	}
+
+	if code := run([]string{"0", path, "y"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Fatalf("expected file truncated with y, got %q", data)
+	}
+}
+
+func TestRun_ZeroLinesStillValidatesMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "typo.txt")
+	if code := run([]string{"0", path, "y", "80", "digitz"}); code == 0 {
+		t.Fatalf("expected unknown mode to fail even with 0 lines")
+	}
+	if code := run([]string{"0", path, "y", "80", "pi", "bogus"}); code == 0 {
+		t.Fatalf("expected bad pi modeArg to fail even with 0 lines")
 	}
 }
 
-func TestGenerator_Digits(t *testing.T) {
-	g, err := newGenerator("digits", "", 1000)
+func TestGetArgsOrPrompt_InterleaveSpec(t *testing.T) {
+	_, _, _, _, mode, _, _, err := getArgsOrPrompt([]string{"10", "out.txt", "y", "digits:20+ascii:100"}, false)
 	if err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
+	if mode != "digits:20+ascii:100" {
+		t.Fatalf("unexpected mode %q", mode)
+	}
+
+	if _, _, _, _, _, _, _, err := getArgsOrPrompt([]string{"10", "out.txt", "y", "80", "digits:20+ascii:100"}, false); err == nil {
+		t.Fatalf("expected error when a width is combined with an interleave spec")
+	}
+}
 
-	line := g.NextLine(50)
-	if len(line) != 50 {
-		t.Fatalf("expected length 50, got %d", len(line))
+type fakeTerminal struct {
+	cols int
+	ok   bool
+}
+
+func (f fakeTerminal) Columns() (int, bool) { return f.cols, f.ok }
+
+func TestIsTermWidth(t *testing.T) {
+	for _, s := range []string{"term", "TERM", " term-2 ", "term+4", "term-0"} {
+		if !isTermWidth(s) {
+			t.Errorf("isTermWidth(%q) = false, want true", s)
+		}
+	}
+	for _, s := range []string{"terminal", "term-", "term+", "term--2", "term2", "80", "ascii", ""} {
+		if isTermWidth(s) {
+			t.Errorf("isTermWidth(%q) = true, want false", s)
+		}
 	}
-	for i := 0; i < len(line); i++ {
-		if line[i] < '0' || line[i] > '9' {
-			t.Fatalf("expected digit at %d, got %q", i, line[i])
+}
+
+func TestParseTermWidth(t *testing.T) {
+	tty := fakeTerminal{cols: 120, ok: true}
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"term", 120},
+		{"term-2", 118},
+		{"term+8", 128},
+	}
+	for _, tt := range tests {
+		got, fallback, err := parseTermWidth(tt.in, tty)
+		if err != nil || fallback || got != tt.want {
+			t.Errorf("parseTermWidth(%q) = %d, %v, %v; want %d", tt.in, got, fallback, err, tt.want)
 		}
 	}
+
+	if _, _, err := parseTermWidth("term-120", tty); err == nil {
+		t.Error("term-120 on a 120-column terminal should be rejected")
+	}
 }
 
-func TestGenerator_Upper(t *testing.T) {
-	g, err := newGenerator("upper", "", 1000)
+func TestParseTermWidth_NonTTYFallsBack(t *testing.T) {
+	got, fallback, err := parseTermWidth("term-2", fakeTerminal{})
+	if err != nil || !fallback || got != defaultWidth-2 {
+		t.Errorf("parseTermWidth = %d, %v, %v; want %d with fallback", got, fallback, err, defaultWidth-2)
+	}
+}
+
+func TestGetArgsOrPrompt_TermWidth(t *testing.T) {
+	old := termProbe
+	termProbe = fakeTerminal{cols: 100, ok: true}
+	defer func() { termProbe = old }()
+
+	_, _, _, width, mode, _, src, err := getArgsOrPrompt([]string{"10", "out.txt", "y", "term-1", "digits"}, false)
 	if err != nil {
-		t.Fatalf("unexpected err: %v", err)
+		t.Fatalf("unexpected error: %v", err)
+	}
+	usedDefaultWidth := src.width.from == fromDefault
+	if width != 99 || usedDefaultWidth || mode != "digits" {
+		t.Errorf("width=%d usedDefaultWidth=%v mode=%q; want 99 false digits", width, usedDefaultWidth, mode)
+	}
+}
+
+func TestRun_ExactBytes(t *testing.T) {
+	dir := t.TempDir()
+	for _, n := range []string{"1", "79", "81", "1000", "1,048,576", "1MiB"} {
+		path := filepath.Join(dir, "exact.txt")
+		if code := run([]string{"0", path, "y", "80", "--exact-bytes", n}); code != 0 {
+			t.Fatalf("--exact-bytes %s: run exited with %d", n, code)
+		}
+		want, _ := parseByteSize(n)
+		fi, err := os.Stat(path)
+		if err != nil || fi.Size() != want {
+			t.Errorf("--exact-bytes %s: size = %v (%v), want %d", n, fi.Size(), err, want)
+		}
 	}
 
-	line := g.NextLine(52)
-	if len(line) != 52 {
-		t.Fatalf("expected length 52, got %d", len(line))
+	path := filepath.Join(dir, "one.txt")
+	output := captureStdout(t)
+	if code := run([]string{"1", path, "y", "3", "char", "é", "--exact-bytes", "10"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
 	}
-	for i := 0; i < len(line); i++ {
-		if line[i] < 'A' || line[i] > 'Z' {
-			t.Fatalf("expected A-Z at %d, got %q", i, line[i])
+	if text := output(); !strings.Contains(text, "Wrote 1 complete line plus 3 trailing bytes (10 bytes).") {
+		t.Errorf("summary not singular:\n%s", text)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "ééé\né " {
+		t.Errorf("output %q, want the cut é padded with a space", data)
+	}
+}
+
+func TestGetArgsOrPrompt_InfersSwappedLinesAndFilename(t *testing.T) {
+	for _, args := range [][]string{
+		{"1000", "out.txt"},
+		{"out.txt", "1000"},
+		{"out.txt", "1K", "y", "40"},
+	} {
+		lines, filename, _, _, _, _, _, err := getArgsOrPrompt(args, false)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", args, err)
+		}
+		if lines != 1000 || filename != "out.txt" {
+			t.Errorf("%q: lines=%d filename=%q; want 1000 out.txt", args, lines, filename)
 		}
 	}
 }
 
-func TestGenerator_Char(t *testing.T) {
-	g, err := newGenerator("char", "#", 1000)
+func TestGetArgsOrPrompt_NumericFilenameKeepsOrder(t *testing.T) {
+	lines, filename, _, _, _, _, _, err := getArgsOrPrompt([]string{"2024", "500"}, false)
 	if err != nil {
-		t.Fatalf("unexpected err: %v", err)
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines != 2024 || filename != "500" {
+		t.Errorf("lines=%d filename=%q; want 2024 and 500 (documented order)", lines, filename)
 	}
+}
 
-	line := g.NextLine(33)
-	if line != strings.Repeat("#", 33) {
-		t.Fatalf("unexpected char line: %q", line)
+func TestGetArgsOrPrompt_NeitherIsACount(t *testing.T) {
+	_, _, _, _, _, _, _, err := getArgsOrPrompt([]string{"out.txt", "lots"}, false)
+	if err == nil || !strings.Contains(err.Error(), "invalid number of lines") {
+		t.Errorf("err = %v, want the strict invalid-lines error", err)
 	}
 }
 
-func TestPiSpigot_FirstDigits(t *testing.T) {
-	// Known first digits of pi: 3 1 4 1 5 9 2 6 5 3 5 8 9 7 9 3
-	want := []int{3, 1, 4, 1, 5, 9, 2, 6, 5, 3, 5, 8, 9, 7, 9, 3}
+func TestDecodeModeArg(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"#", "#"},
+		{`\\`, `\`},
+		{`\t`, "\t"},
+		{`\s`, " "},
+		{`\x1b`, "\x1b"},
+		{`\e`, "\x1b"},
+		{`\x41`, "A"},
+		{`a\`, `a\`},
+	}
+	for _, tt := range tests {
+		got, err := decodeModeArg(tt.in, true)
+		if err != nil || got != tt.want {
+			t.Errorf("decodeModeArg(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{`\q`, `\x4`, `\xZZ`} {
+		if _, err := decodeModeArg(bad, true); err == nil {
+			t.Errorf("decodeModeArg(%q) = nil error, want failure", bad)
+		}
+	}
+}
+
+func TestDecodeModeArg_RejectsControlCharacters(t *testing.T) {
+	for _, in := range []string{"\v", "\x1b", `\t`, `\x7f`, "\u0085"} {
+		_, err := decodeModeArg(in, false)
+		if err == nil || !strings.Contains(err.Error(), "--allow-control") {
+			t.Errorf("decodeModeArg(%q) error = %v, want a control-character error", in, err)
+		}
+	}
+	if got, err := decodeModeArg(`\x41`, false); err != nil || got != "A" {
+		t.Errorf("printable escape rejected: %q, %v", got, err)
+	}
+}
+
+func TestRun_CharControlRequiresAllowFlag(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tabs.txt")
+
+	for _, arg := range []string{"\t", `\t`, "\v"} {
+		if code := run([]string{"2", path, "y", "4", "char", arg}); code != 1 {
+			t.Errorf("char %q without --allow-control exited with %d, want 1", arg, code)
+		}
+	}
+	if fileExists(path) {
+		t.Fatal("rejected runs should not create the file")
+	}
+
+	if code := run([]string{"2", path, "y", "4", "char", `\t`, "--allow-control"}); code != 0 {
+		t.Fatalf("run with --allow-control exited with %d", code)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "\t\t\t\t\n\t\t\t\t\n" {
+		t.Errorf("file = %q, want tab lines", data)
+	}
+}
 
-	s := newPiSpigot(len(want))
-	for i, wd := range want {
-		got := s.NextDigit()
-		if got != wd {
-			t.Fatalf("pi digit %d: expected %d, got %d", i, wd, got)
+func TestRun_CharBlankAndEscapes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blank.txt")
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"char", " "}, "     \n     \n"},
+		{[]string{"char", `\s`}, "     \n     \n"},
+		{[]string{"char", `\t`, "--allow-control"}, "\t\t\t\t\t\n\t\t\t\t\t\n"},
+		{[]string{"template", "inline:{{.Line}} {{Fill 2}}"}, "1  !\n2 \"#\n"},
+	} {
+		if code := run(append([]string{"2", path, "y", "5"}, tt.args...)); code != 0 {
+			t.Fatalf("%q: run exited with %d", tt.args, code)
+		}
+		if data, _ := os.ReadFile(path); string(data) != tt.want {
+			t.Errorf("%q: file = %q, want %q", tt.args, data, tt.want)
 		}
 	}
 }
 
-func TestGenerator_PiMode_Digits_Default(t *testing.T) {
-	// In pi mode (default), output should be the raw pi digits as characters '0'..'9'.
-	g, err := newGenerator("pi", "", 80) // totalChars used only for spigot sizing
+func TestRun_CustomMode(t *testing.T) {
+	t.Cleanup(genlines.ResetModes)
+	err := genlines.RegisterMode("zigzag", genlines.ModeSpec{
+		Aliases:     []string{"zz"},
+		Description: "Rows of <",
+		Factory: func(string, int) (genlines.Generator, error) {
+			return genlines.NewGenerator("char", "<", 0)
+		},
+	})
 	if err != nil {
-		t.Fatalf("unexpected err: %v", err)
+		t.Fatal(err)
 	}
 
-	line := g.NextLine(10)
-	if len(line) != 10 {
-		t.Fatalf("expected length 10, got %d", len(line))
+	out := captureStdout(t)
+	printHelp()
+	if text := out(); !strings.Contains(text, "  zigzag       Rows of < (alias: zz)") {
+		t.Errorf("help does not list the custom mode:\n%s", text)
+	}
+	path := filepath.Join(t.TempDir(), "zz.txt")
+	if code := run([]string{"2", path, "y", "3", "ZZ"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
 	}
+	if data, _ := os.ReadFile(path); string(data) != "<<<\n<<<\n" {
+		t.Errorf("file = %q", data)
+	}
+}
+
+func TestRun_BadDatesArgCreatesNoFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dates.txt")
+	if code := run([]string{"10", path, "y", "30", "dates", "2006-01-02|often"}); code == 0 {
+		t.Fatalf("expected a bad step to fail")
+	}
+	if fileExists(path) {
+		t.Fatalf("file was created despite the invalid modeArg")
+	}
+}
 
-	// First digits of pi: 3,1,4,1,5,9,2,6,5,3
-	want := "3141592653"
-	if line != want {
-		t.Fatalf("unexpected pi-digits line.\nwant: %q\ngot:  %q", want, line)
+func TestRun_Template(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.txt")
+	if code := run([]string{"10", bad, "y", "30", "template", "inline:{{.Line"}); code == 0 {
+		t.Fatalf("expected a template parse error to fail")
 	}
+	if fileExists(bad) {
+		t.Fatalf("file was created despite the template parse error")
+	}
+
+	path := filepath.Join(dir, "tmpl.txt")
+	if code := run([]string{"2", path, "y", "30", "template", "inline:line {{.Line}}: {{Fill 5}}", "--max-lines", "1", "--force"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "line 1:  !\"#$\nline 2: %&'()\n" {
+		t.Errorf("unexpected content %q", got)
+	}
+}
 
-	// Also assert digits only, just to be safe.
-	for i := 0; i < len(line); i++ {
-		if line[i] < '0' || line[i] > '9' {
-			t.Fatalf("expected digit at %d, got %q", i, line[i])
+func TestRun_RecordModeReportsPhysicalLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.csv")
+	output := captureStdout(t)
+	if code := run([]string{"10", path, "y", "20", "csv", "cols=3;multiline=4"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if !strings.Contains(output(), "Wrote 10 records in 12 physical lines") {
+		t.Errorf("summary lacks the record and line counts:\n%s", output())
+	}
+}
+
+func TestRun_RampOverridesWidth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ramp.txt")
+	if code := run([]string{"6", path, "y", "80", "digits", "--ramp", "2:6:2:reset", "--meta"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	got, _ := os.ReadFile(path)
+	if want := "01\n2345\n678901\n23\n4567\n890123\n"; string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	m, err := readMeta(path + metaSuffix)
+	if err != nil || m.Ramp != "2:6:2:reset" {
+		t.Fatalf("meta ramp = %q (%v)", m.Ramp, err)
+	}
+	if code := run([]string{"1", path, "y", "--ramp", "5:4:1"}); code == 0 {
+		t.Fatalf("expected an invalid ramp to fail")
+	}
+}
+
+func TestRun_MaxBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capped.txt")
+	for _, tt := range []struct {
+		lines, maxBytes string
+		wantSize        int64
+		wantSummary     string
+	}{
+		{"10", "1000", 100, "Wrote all 10 lines before --max-bytes: 100 bytes (ceiling 1_000 bytes)."},
+		{"10", "55", 50, "Stopped by --max-bytes: wrote 5 of 10 lines, 50 bytes (ceiling 55 bytes)."},
+		{"10", "100", 100, "Wrote all 10 lines, exactly reaching --max-bytes: 100 bytes."},
+	} {
+		out := captureStdout(t)
+		if code := run([]string{tt.lines, path, "y", "9", "digits", "--max-bytes", tt.maxBytes}); code != 0 {
+			t.Fatalf("--max-bytes %s: run exited with %d", tt.maxBytes, code)
+		}
+		if fi, err := os.Stat(path); err != nil || fi.Size() != tt.wantSize {
+			t.Errorf("--max-bytes %s: size %d (%v), want %d", tt.maxBytes, fi.Size(), err, tt.wantSize)
+		}
+		if text := out(); !strings.Contains(text, tt.wantSummary) {
+			t.Errorf("--max-bytes %s: summary lacks %q:\n%s", tt.maxBytes, tt.wantSummary, text)
+		}
+	}
+
+	// Appending counts the bytes already there, and the terminator added to them.
+	os.WriteFile(path, []byte("old"), 0644)
+	if code := run([]string{"10", path, "9", "digits", "--append", "--max-bytes", "40"}); code != 0 {
+		t.Fatalf("append run exited with %d", code)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old\n012345678\n901234567\n890123456\n" {
+		t.Errorf("appended file = %q", data)
+	}
+	if code := run([]string{"10", path, "9", "digits", "--append", "--max-bytes", "24"}); code == 0 {
+		t.Error("expected appending to a file at the ceiling to fail")
+	}
+}
+
+func TestRun_Align(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "align.txt")
+	out := captureStdout(t)
+	if code := run([]string{"5", path, "y", "2", "digits", "--align", "8", "--align-fill", ".", "--meta"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	got, _ := os.ReadFile(path)
+	if want := "01\n23\n.\n45\n67\n.\n89\n"; string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if text := out(); !strings.Contains(text, "Inserted 2 padding lines to align lines to 8-byte boundaries (19 bytes)") {
+		t.Errorf("summary lacks the padding report:\n%s", text)
+	}
+
+	m, err := readMeta(path + metaSuffix)
+	if err != nil || m.Align != 8 || m.AlignFill != "." {
+		t.Fatalf("meta align = %d %q (%v)", m.Align, m.AlignFill, err)
+	}
+	for _, args := range [][]string{
+		{"5", path, "y", "2", "--align-fill", "."},
+		{"5", path, "y", "10", "--align", "8"},
+		{"5", path, "--append", "--align", "64"},
+	} {
+		if code := run(args); code == 0 {
+			t.Errorf("%q: expected an error", args)
 		}
 	}
 }
 
-func TestGenerator_PiMode_MappingToAsciiPalette(t *testing.T) {
-	// In pi mode with modeArg=ascii, digits 0..9 are mapped to printable ASCII palette by index.
-	// Palette index 0 corresponds to ASCII 32 (space), 1 -> '!', etc.
-	// So digit '3' maps to palette[3] = ASCII 35 '#'
-	g, err := newGenerator("pi", "ascii", 80) // totalChars used only for spigot sizing
+func TestWriteRetry(t *testing.T) {
+	r := writeRetry(cliFlags{})
+	if r.Attempts != defaultRetries || r.Backoff != defaultRetryBackoff || r.OnRetry == nil {
+		t.Fatalf("defaults: %+v", r)
+	}
+
+	_, flags, err := splitFlags([]string{"--retries", "0", "--retry-backoff", "1s"})
 	if err != nil {
-		t.Fatalf("unexpected err: %v", err)
+		t.Fatal(err)
+	}
+	if r := writeRetry(flags); r.Attempts != 0 || r.Backoff != time.Second {
+		t.Fatalf("from flags: %+v", r)
 	}
+	for _, bad := range [][]string{{"--retries", "-1"}, {"--retry-backoff", "soon"}} {
+		if _, _, err := splitFlags(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
 
-	line := g.NextLine(10)
-	if len(line) != 10 {
-		t.Fatalf("expected length 10, got %d", len(line))
+func TestRun_EscapeNonASCII(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "escaped.txt")
+	if code := run([]string{"2", path, "y", "3", "char", "é", "--escape-nonascii", "--meta"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
 	}
+	if data, _ := os.ReadFile(path); string(data) != strings.Repeat(`\u00e9`, 3)+"\n"+strings.Repeat(`\u00e9`, 3)+"\n" {
+		t.Errorf("file = %q", data)
+	}
+	m, err := readMeta(path + ".meta")
+	if err != nil || !m.EscapeNonASCII {
+		t.Errorf("sidecar does not record the escaping: %+v, %v", m, err)
+	}
+}
 
-	// First digits of pi: 3,1,4,1,5,9,2,6,5,3
-	// Expected chars: palette[d] where palette[0] = ' ' (32)
-	palette := []byte(buildAsciiSequence())
-	wantDigits := []int{3, 1, 4, 1, 5, 9, 2, 6, 5, 3}
-	want := make([]byte, 10)
-	for i, d := range wantDigits {
-		want[i] = palette[d%len(palette)]
+func TestRun_CSVDelimAndUnsafe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.csv")
+	if code := run([]string{"4", path, "y", "20", "csv", "cols=3;delim=;"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Count(string(data), ";") != 8 || strings.ContainsAny(string(data), "=+-@,") {
+		t.Errorf("safe semicolon file = %q", data)
 	}
 
-	if line != string(want) {
-		t.Fatalf("unexpected pi-mapped line.\nwant: %q\ngot:  %q", string(want), line)
+	if code := run([]string{"4", path, "y", "20", "csv", "cols=3;delim=;", "--unsafe"}); code != 0 {
+		t.Fatalf("unsafe run exited with %d", code)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), ";=") {
+		t.Errorf("unsafe file has no formula field: %q", data)
 	}
 }
 
-func TestGetArgsOrPrompt_DefaultFlags_WhenOmitted(t *testing.T) {
-	// Only required args -> defaults should be used (width + mode)
-	lines, filename, ow, width, mode, modeArg, defW, defM, err := getArgsOrPrompt([]string{"10", "out.txt"})
-	if err != nil {
-		t.Fatalf("unexpected err: %v", err)
+func TestRun_BinrecWritesRecordsWithoutTerminators(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.bin")
+	output := captureStdout(t)
+	if code := run([]string{"10", path, "y", "80", "binrec", "u16be:counter,bytes:6:zero", "--meta"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
 	}
-	if lines != 10 || filename != "out.txt" || ow != "" || width != defaultWidth || mode != "ascii" || modeArg != "" {
-		t.Fatalf("unexpected parsed result: lines=%d file=%q ow=%q width=%d mode=%q arg=%q",
-			lines, filename, ow, width, mode, modeArg)
+	data, _ := os.ReadFile(path)
+	if len(data) != 80 || data[0] != 0 || data[1] != 1 || data[9] != 2 {
+		t.Errorf("records = %x", data)
 	}
-	if !defW || !defM {
-		t.Fatalf("expected defW=true and defM=true, got defW=%v defM=%v", defW, defM)
+	if text := output(); !strings.Contains(text, "Generating 10 binary records of 8 bytes") ||
+		!strings.Contains(text, "Wrote 10 records of 8 bytes (80 bytes)") {
+		t.Errorf("summary lacks the record size and total:\n%s", text)
+	}
+	if m, err := readMeta(path + metaSuffix); err != nil || m.LineEnding != "" {
+		t.Errorf("meta line ending = %q (%v)", m.LineEnding, err)
+	}
+
+	if code := run([]string{"10", path, "y", "80", "binrec", "u8:counter", "--line-ending", "lf"}); code != 1 {
+		t.Errorf("--line-ending: exit %d, want 1", code)
 	}
 }
 
-func TestGetArgsOrPrompt_NoDefaultFlags_WhenUserSpecifiesDefaults(t *testing.T) {
-	// User explicitly sets width=80 and mode=ascii -> should NOT be marked as default usage
-	lines, filename, ow, width, mode, modeArg, defW, defM, err := getArgsOrPrompt([]string{"10", "out.txt", "80", "ascii"})
-	if err != nil {
-		t.Fatalf("unexpected err: %v", err)
+func TestGetArgsOrPrompt_ExtraArgumentsWarn(t *testing.T) {
+	errOut := captureStderr(t)
+	_, _, _, _, mode, modeArg, _, err := getArgsOrPrompt([]string{"10", "out.txt", "y", "80", "char", "#", "5", "extra"}, false)
+	if err != nil || mode != "char" || modeArg != "#" {
+		t.Fatalf("mode=%q modeArg=%q err=%v", mode, modeArg, err)
 	}
-	if lines != 10 || filename != "out.txt" || ow != "" || width != 80 || mode != "ascii" || modeArg != "" {
-		t.Fatalf("unexpected parsed result: lines=%d file=%q ow=%q width=%d mode=%q arg=%q",
-			lines, filename, ow, width, mode, modeArg)
+	if text := errOut(); !strings.Contains(text, "ignoring arguments after modeArg: 5 extra") {
+		t.Errorf("no warning for the dropped arguments:\n%s", text)
 	}
-	if defW || defM {
-		t.Fatalf("expected defW=false and defM=false, got defW=%v defM=%v", defW, defM)
+
+	_, _, _, _, _, _, _, err = getArgsOrPrompt([]string{"10", "out.txt", "y", "80", "char", "#", "5", "extra"}, true)
+	if err == nil || !strings.Contains(err.Error(), "unexpected arguments after modeArg: 5 extra") {
+		t.Errorf("strict: err = %v", err)
 	}
 }
 
-func TestGetArgsOrPrompt_OverwriteFlag_CaseInsensitive(t *testing.T) {
-	lines, filename, ow, width, mode, _, defW, defM, err := getArgsOrPrompt([]string{"10", "out.txt", "Y"})
-	if err != nil {
-		t.Fatalf("unexpected err: %v", err)
+func TestGetArgsOrPrompt_StrictRejectsAmbiguity(t *testing.T) {
+	genlines.RegisterMode("42", genlines.ModeSpec{Factory: func(string, int) (genlines.Generator, error) { return nil, nil }})
+	t.Cleanup(genlines.ResetModes)
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"out.txt", "1000"}, `write 1000 out.txt`},
+		{[]string{"10", "80", "ascii"}, `"80": it could be <filename> or [width]`},
+		{[]string{"10", "out.txt", "y", "42"}, `"42": it could be [width] or [mode]`},
+	} {
+		if _, _, _, _, _, _, _, err := getArgsOrPrompt(tt.args, true); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: err = %v, want it to mention %s", tt.args, err, tt.want)
+		}
+		// Lenient parsing keeps guessing.
+		if _, _, _, _, _, _, _, err := getArgsOrPrompt(tt.args, false); err != nil {
+			t.Errorf("%q lenient: %v", tt.args, err)
+		}
 	}
-	if lines != 10 || filename != "out.txt" || strings.ToLower(ow) != "y" {
-		t.Fatalf("unexpected overwrite flag parsing: lines=%d file=%q ow=%q", lines, filename, ow)
+
+	if _, _, _, width, mode, _, _, err := getArgsOrPrompt([]string{"10", "out.txt", "y", "80", "digits"}, true); err != nil || width != 80 || mode != "digits" {
+		t.Errorf("unambiguous strict: width=%d mode=%q err=%v", width, mode, err)
 	}
-	if width != defaultWidth || mode != "ascii" || !defW || !defM {
-		t.Fatalf("unexpected defaults: width=%d mode=%q defW=%v defM=%v", width, mode, defW, defM)
+}
+
+func TestRun_StrictArgs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	if code := run([]string{"10", path, "y", "8", "digits", "x", "y", "--strict-args"}); code != 1 {
+		t.Errorf("strict run with extra arguments exited with %d, want 1", code)
+	}
+	if fileExists(path) {
+		t.Error("strict run wrote a file")
 	}
 }
 
-func TestGetArgsOrPrompt_ModeChar_RequiresModeArg(t *testing.T) {
-	_, _, _, _, _, _, _, _, err := getArgsOrPrompt([]string{"10", "out.txt", "80", "char"})
-	if err == nil {
-		t.Fatalf("expected error for char mode without modeArg")
+func TestRun_RotMakesPlainCipherPair(t *testing.T) {
+	dir := t.TempDir()
+	plain, cipher := filepath.Join(dir, "plain.txt"), filepath.Join(dir, "cipher.txt")
+	if code := run([]string{"30", plain, "y", "60", "random", "--seed", "7"}); code != 0 {
+		t.Fatalf("plain run exited with %d", code)
+	}
+	if code := run([]string{"30", cipher, "y", "60", "random", "--seed", "7", "--rot", "3", "--meta"}); code != 0 {
+		t.Fatalf("rot run exited with %d", code)
+	}
+	p, _ := os.ReadFile(plain)
+	c, _ := os.ReadFile(cipher)
+	if len(p) != len(c) || string(p) == string(c) || genlines.Rotate(string(c), 23) != string(p) {
+		t.Error("cipher.txt is not plain.txt rotated by 3")
+	}
+	if m, err := readMeta(cipher + metaSuffix); err != nil || m.Rot != 3 {
+		t.Errorf("sidecar rot %d, %v", m.Rot, err)
+	}
+
+	for _, bad := range []string{"0", "26", "x"} {
+		if code := run([]string{"1", plain, "y", "--rot", bad}); code == 0 {
+			t.Errorf("--rot %s accepted", bad)
+		}
 	}
 }
 
-func TestGetArgsOrPrompt_InteractivePrompts(t *testing.T) {
-	// Simulate interactive input: lines=7, filename=test.txt
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
+func TestRun_TrailingWSSummaryAndRegen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ws.txt")
+	output := captureStdout(t)
+	code := run([]string{"200", path, "y", "20", "alpha", "--trailing-ws", "25%:5", "--line-ending", "crlf",
+		"--line-checksum", "--meta", "--verify-after"})
+	text := output()
+	if code != 0 {
+		t.Fatalf("run exited with %d:\n%s", code, text)
+	}
+	data, _ := os.ReadFile(path)
+	padded, extra := 0, 0
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n") {
+		if trimmed := strings.TrimRight(line, " \t"); trimmed != line {
+			padded++
+			extra += len(line) - len(trimmed)
+		}
+	}
+	if want := fmt.Sprintf("Added trailing whitespace to %d of 200 lines (%d bytes).", padded, extra); padded == 0 || !strings.Contains(text, want) {
+		t.Errorf("output lacks %q:\n%s", want, text)
+	}
+	if code := runVerifyLinesCmd([]string{path}); code != 0 {
+		t.Errorf("verify-lines exited with %d", code)
+	}
 
-	tmp, err := os.CreateTemp("", "stdin-*")
-	if err != nil {
-		t.Fatalf("CreateTemp: %v", err)
+	m, err := readMeta(path + metaSuffix)
+	if err != nil || m.TrailingWS != "0.25:5" {
+		t.Fatalf("sidecar trailingWS %q, %v", m.TrailingWS, err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if code := runRegenCmd([]string{path + metaSuffix}); code != 0 {
+		t.Fatalf("regen exited with %d", code)
+	}
+	if again, _ := os.ReadFile(path); string(again) != string(data) {
+		t.Error("regenerated file differs from the original")
 	}
-	defer os.Remove(tmp.Name())
-	defer tmp.Close()
 
-	_, _ = tmp.WriteString("7\n")
-	_, _ = tmp.WriteString("test.txt\n")
-	_, _ = tmp.Seek(0, 0)
+	for _, bad := range []string{"0", "150%", "0.5:x"} {
+		if code := run([]string{"1", path, "y", "--trailing-ws", bad}); code == 0 {
+			t.Errorf("--trailing-ws %s accepted", bad)
+		}
+	}
+}
 
-	os.Stdin = tmp
+func TestRun_InjectUnicodeSummaryAndRegen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hostile.txt")
+	output := captureStdout(t)
+	code := run([]string{"100", path, "y", "40", "alpha", "--inject-unicode", "zwsp,rlo:every=4:seed=7", "--meta", "--verify-after"})
+	text := output()
+	if code != 0 {
+		t.Fatalf("run exited with %d:\n%s", code, text)
+	}
+	data, _ := os.ReadFile(path)
+	zwsp, rlo := strings.Count(string(data), "\u200b"), strings.Count(string(data), "\u202e")
+	want := fmt.Sprintf("Injected 25 invisible code points: rlo (U+202E) %d, zwsp (U+200B) %d.", rlo, zwsp)
+	if zwsp+rlo != 25 || !strings.Contains(text, want) {
+		t.Errorf("output lacks %q:\n%s", want, text)
+	}
+	for i, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if injected := strings.ContainsAny(line, "\u200b\u202e"); injected != ((i+1)%4 == 0) {
+			t.Errorf("line %d: injected %v", i+1, injected)
+		}
+	}
 
-	lines, filename, ow, width, mode, modeArg, defW, defM, err := getArgsOrPrompt([]string{})
-	if err != nil {
-		t.Fatalf("unexpected err: %v", err)
+	m, err := readMeta(path + metaSuffix)
+	if err != nil || m.InjectUnicode != "zwsp,rlo:every=4:seed=7" {
+		t.Fatalf("sidecar injectUnicode %q, %v", m.InjectUnicode, err)
 	}
-	if lines != 7 || filename != "test.txt" || ow != "" || width != defaultWidth || mode != "ascii" || modeArg != "" {
-		t.Fatalf("unexpected interactive result: lines=%d file=%q ow=%q width=%d mode=%q arg=%q",
-			lines, filename, ow, width, mode, modeArg)
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
 	}
-	if !defW || !defM {
-		t.Fatalf("expected defaults for width+mode in interactive flow")
+	if code := runRegenCmd([]string{path + metaSuffix}); code != 0 {
+		t.Fatalf("regen exited with %d", code)
+	}
+	if again, _ := os.ReadFile(path); string(again) != string(data) {
+		t.Error("regenerated file differs from the original")
+	}
+
+	if code := run([]string{"1", path, "y", "--inject-unicode", "nbsp"}); code == 0 {
+		t.Error("--inject-unicode nbsp accepted")
+	}
+}
+
+func TestRun_StartOffsetContinuesARun(t *testing.T) {
+	dir := t.TempDir()
+	for _, mode := range []string{"ascii", "digits"} {
+		whole, first, second := filepath.Join(dir, mode+"-whole.txt"), filepath.Join(dir, mode+"-1.txt"), filepath.Join(dir, mode+"-2.txt")
+		for _, args := range [][]string{
+			{"200", whole, "y", "80", mode},
+			{"100", first, "y", "80", mode},
+			{"100", second, "y", "80", mode, "--start-offset", "8_000", "--meta"},
+		} {
+			if code := run(args); code != 0 {
+				t.Fatalf("%v exited with %d", args, code)
+			}
+		}
+		w, _ := os.ReadFile(whole)
+		a, _ := os.ReadFile(first)
+		b, _ := os.ReadFile(second)
+		if string(a)+string(b) != string(w) {
+			t.Errorf("%s: the halves do not concatenate to the 200-line run", mode)
+		}
+		if m, err := readMeta(second + metaSuffix); err != nil || m.StartOffset != 8000 {
+			t.Errorf("%s: sidecar startOffset %d, %v", mode, m.StartOffset, err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"10", filepath.Join(dir, "pi.txt"), "y", "80", "pi", "--start-offset", "100"},
+		{"10", filepath.Join(dir, "neg.txt"), "y", "--start-offset", "-1"},
+	} {
+		if code := run(args); code == 0 {
+			t.Errorf("%v accepted", args)
+		}
+	}
+}
+
+// FuzzGetArgsOrPrompt feeds getArgsOrPrompt two to six arguments (never a
+// prompt) and checks what it makes of whatever lands in each position.
+func FuzzGetArgsOrPrompt(f *testing.F) {
+	for _, args := range [][]string{
+		{"10", "out.txt"},
+		{"out.txt", "1K", "y", "40"},
+		{"2024", "500", "n", "0", "char", `\t`},
+		{"1_000", "out.txt", "YES", "term", "ascii:4+digits:4"},
+		{"-5", "", "maybe", "-80", "", "x"},
+		{"10", "out.txt", "80", "lorem", "3", "extra"},
+	} {
+		padded := append(args, make([]string, 6-len(args))...)
+		f.Add(padded[0], padded[1], padded[2], padded[3], padded[4], padded[5], uint8(len(args)-2))
+	}
+	captureStdout(f) // the swap note is not of interest here
+	f.Fuzz(func(t *testing.T, a0, a1, a2, a3, a4, a5 string, n uint8) {
+		args := []string{a0, a1, a2, a3, a4, a5}[:2+int(n)%5]
+		lines, filename, ow, width, mode, _, _, err := getArgsOrPrompt(args, false)
+		_, _, _, _, _, _, _, strictErr := getArgsOrPrompt(args, true)
+		if err != nil {
+			if strictErr == nil {
+				t.Errorf("%q: --strict-args accepted what is otherwise rejected (%v)", args, err)
+			}
+			return
+		}
+		if lines < 0 || filename == "" || filename != strings.TrimSpace(filename) || width <= 0 || mode == "" {
+			t.Errorf("%q: lines=%d filename=%q width=%d mode=%q", args, lines, filename, width, mode)
+		}
+		if ow != "" && !looksLikeYesNo(ow) {
+			t.Errorf("%q: overwrite flag %q", args, ow)
+		}
+	})
+}
+
+func TestRun_PaletteFileWithoutLineEndings(t *testing.T) {
+	dir := t.TempDir()
+	palette, out := filepath.Join(dir, "fw.img"), filepath.Join(dir, "pattern.bin")
+	os.WriteFile(palette, []byte{0x0a, 0x00, 0x80, 0xff}, 0644)
+
+	if code := run([]string{"4", out, "y", "6", "palette-file", palette + ":1:3", "--line-ending", "none", "--meta"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	data, _ := os.ReadFile(out)
+	if want := strings.Repeat("\x00\x80\xff", 8); string(data) != want {
+		t.Errorf("got % x, want % x", data, want)
+	}
+	m, err := readMeta(out + metaSuffix)
+	if err != nil || m.LineEnding != "none" || m.options().EOL == nil || len(m.options().EOL) != 0 {
+		t.Errorf("sidecar lineEnding %q, %v", m.LineEnding, err)
+	}
+
+	if code := run([]string{"1", out, "y", "6", "palette-file", palette + ":4"}); code == 0 {
+		t.Error("an offset past the end accepted")
+	}
+}
+
+func TestRun_LinePatternSummaryAndRegen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pattern.txt")
+	summaryPath := filepath.Join(t.TempDir(), "summary.json")
+	output := captureStdout(t)
+	code := run([]string{"10", path, "y", "20", "digits", "--line-pattern", "ccb", "--meta", "--verify-after",
+		"--summary-json", summaryPath})
+	text := output()
+	if code != 0 {
+		t.Fatalf("run exited with %d:\n%s", code, text)
+	}
+	data, _ := os.ReadFile(path)
+	line := "01234567890123456789\n"
+	if want := strings.Repeat(line+line+"\n", 3) + line; string(data) != want {
+		t.Errorf("file %q", data)
+	}
+	if want := "Line pattern CCB: 7 content lines, 3 blank lines."; !strings.Contains(text, want) {
+		t.Errorf("output lacks %q:\n%s", want, text)
+	}
+	if summary, _ := os.ReadFile(summaryPath); !strings.Contains(string(summary), `"blankLines": 3`) {
+		t.Errorf("summary %s", summary)
+	}
+
+	m, err := readMeta(path + metaSuffix)
+	if err != nil || m.LinePattern != "CCB" {
+		t.Fatalf("sidecar linePattern %q, %v", m.LinePattern, err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if code := runRegenCmd([]string{path + metaSuffix}); code != 0 {
+		t.Fatalf("regen exited with %d", code)
+	}
+	if again, _ := os.ReadFile(path); string(again) != string(data) {
+		t.Error("regenerated file differs from the original")
+	}
+
+	for _, bad := range []string{"CXB", "BB", ""} {
+		if code := run([]string{"1", path, "y", "--line-pattern", bad}); code == 0 {
+			t.Errorf("--line-pattern %q accepted", bad)
+		}
 	}
 }
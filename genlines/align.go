@@ -0,0 +1,163 @@
+package genlines
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// DefaultAlignFill is the padding character used when Options.AlignFill is not set.
+const DefaultAlignFill = ' '
+
+// validateAlign checks the alignment settings of o (with defaults applied).
+func (o Options) validateAlign() error {
+	if o.Align < 0 {
+		return fmt.Errorf("invalid alignment: %d", o.Align)
+	}
+	if o.Align == 0 {
+		return nil
+	}
+	if o.ExactBytes > 0 {
+		return errors.New("alignment cannot be combined with an exact byte size")
+	}
+	if canonicalMode(o.Mode) == "template" {
+		return errors.New("mode=template cannot be aligned: its line sizes are not known in advance")
+	}
+	if o.AlignFill == '\n' || o.AlignFill == '\r' {
+		return errors.New("the alignment fill character cannot be a line terminator")
+	}
+	longest, err := o.longestLine()
+	if err != nil {
+		return err
+	}
+	if longest > o.Align {
+		return fmt.Errorf("a %d-byte line does not fit in an alignment of %d bytes", longest, o.Align)
+	}
+	return nil
+}
+
+// longestLine returns the size of the longest output line of o (with
+// defaults applied), terminator and comment lines included.
+func (o Options) longestLine() (int64, error) {
+	eol := int64(len(o.EOL))
+	sizeOf, err := o.dataLineSizes()
+	if err != nil {
+		return 0, err
+	}
+	longest := sizeOf(1)
+	if IsInterleaveSpec(o.Mode) {
+		streams, err := ParseInterleave(o.Mode)
+		if err != nil {
+			return 0, err
+		}
+		for i := range streams {
+			longest = max(longest, sizeOf(int64(i)+1))
+		}
+	} else if o.Ramp.Enabled() {
+		// Line rising+1 is the first at the ramp's widest.
+		longest = sizeOf(o.Ramp.rising() + 1)
+	}
+	if o.TrailingWS.Enabled() {
+		longest += MaxTrailingWS // any line may get the most
+	}
+	if o.InjectUnicode.Enabled() {
+		longest += 6 // an escaped code point at most
+	}
+	if o.CommentEvery > 0 && o.Lines >= o.CommentEvery {
+		longest = max(longest, int64(len(formatComment(o.CommentText, int64(o.Lines))))+eol)
+	}
+	return longest, nil
+}
+
+// alignPad returns how many padding bytes go at stream offset off so that
+// the next size bytes do not straddle a multiple of align; 0 if they fit.
+// The padding is a whole line, so it is never shorter than the terminator.
+func alignPad(off, size, align int64, eol int) int64 {
+	into := off % align
+	if into == 0 || into+size <= align {
+		return 0
+	}
+	pad := align - into
+	if pad < int64(eol) {
+		pad += align
+	}
+	return pad
+}
+
+// alignLine returns a padding line of size bytes: fill characters and eol.
+func alignLine(size int64, fill byte, eol []byte) []byte {
+	return append(bytes.Repeat([]byte{fill}, int(size)-len(eol)), eol...)
+}
+
+// PlanAlign returns the number of padding lines an aligned run of opts
+// inserts and the total size of its output. Without alignment it reports no
+// padding and the size from PlanSize.
+func PlanAlign(opts Options) (padLines, size int64, err error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return 0, 0, err
+	}
+	if opts.Align == 0 {
+		size, err = PlanSize(opts)
+		return 0, size, err
+	}
+	_, padLines, size, err = opts.simulate()
+	return padLines, size, err
+}
+
+// simulate lays out the lines of a run of o (with defaults applied) one by
+// one as writeLines does, with alignment padding and the byte ceiling, and
+// returns the data lines, padding lines and bytes it writes.
+func (o Options) simulate() (lines, padLines, size int64, err error) {
+	eol := int64(len(o.EOL))
+	lineSize, err := o.dataLineSizes()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	// place adds an n-byte line and its padding, reporting false if the
+	// byte ceiling stops the run first.
+	place := func(n int64) (bool, error) {
+		pad := int64(0)
+		if o.Align > 0 {
+			pad = alignPad(size, n, o.Align, len(o.EOL))
+		}
+		if size > math.MaxInt64-n-pad {
+			return false, errTooLarge
+		}
+		if o.MaxBytes > 0 && size+pad+n > o.MaxBytes {
+			return false, nil
+		}
+		if pad > 0 {
+			padLines++
+		}
+		size += pad + n
+		return true, nil
+	}
+	trailing, inject := o.TrailingWS.resolve(o), o.InjectUnicode.resolve(o)
+	every := int64(o.CommentEvery)
+	if o.HeaderLine != "" {
+		if ok, err := place(int64(len(o.HeaderLine)) + eol); err != nil || !ok {
+			return 0, padLines, size, err
+		}
+	}
+	c := int64(0) // content lines so far, which the line sizes follow
+	for n := int64(1); n <= int64(o.Lines); n++ {
+		next := eol
+		if !o.LinePattern.blank(n) {
+			c++
+			next = lineSize(c) + inject.lineBytes(c, o.EscapeNonASCII) + int64(len(trailing.suffix(c)))
+		}
+		if ok, err := place(next); err != nil || !ok {
+			return lines, padLines, size, err
+		}
+		lines++
+		if every > 0 && n%every == 0 {
+			if ok, err := place(int64(len(formatComment(o.CommentText, n))) + eol); err != nil || !ok {
+				return lines, padLines, size, err
+			}
+		}
+	}
+	return lines, padLines, size, nil
+}
@@ -0,0 +1,98 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAlign_LinesStartOnConsistentOffsets(t *testing.T) {
+	for _, tt := range []struct {
+		width int
+		align int64
+		eol   string
+	}{
+		{10, 64, "\n"},
+		{7, 16, "\n"},
+		{15, 16, "\n"},
+		{14, 16, "\r\n"}, // lines fill their block exactly
+		{14, 33, "\r\n"}, // the remainder is too short for a padding line at times
+		{30, 4096, "\n"},
+	} {
+		opts := Options{Lines: 500, Width: tt.width, Mode: "digits", EOL: []byte(tt.eol), Align: tt.align}
+		var buf bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+			t.Fatalf("width %d align %d: %v", tt.width, tt.align, err)
+		}
+		size := int64(tt.width + len(tt.eol))
+		var off, real, pads int64
+		for _, line := range strings.SplitAfter(buf.String(), tt.eol) {
+			if line == "" {
+				continue
+			}
+			end := off + int64(len(line))
+			if strings.TrimLeft(strings.TrimSuffix(line, tt.eol), " ") == "" {
+				pads++
+				if end%tt.align != 0 {
+					t.Errorf("width %d align %d: padding line ends at %d, not on a boundary", tt.width, tt.align, end)
+				}
+			} else {
+				real++
+				if off%tt.align%size != 0 || off/tt.align != (end-1)/tt.align {
+					t.Errorf("width %d align %d: line %d at offset %d is not aligned", tt.width, tt.align, real, off)
+				}
+			}
+			off = end
+		}
+		if real != 500 {
+			t.Errorf("width %d align %d: %d data lines, want 500", tt.width, tt.align, real)
+		}
+		planPads, planSize, err := PlanAlign(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if planPads != pads || planSize != off {
+			t.Errorf("width %d align %d: planned %d pads / %d bytes, wrote %d / %d", tt.width, tt.align, planPads, planSize, pads, off)
+		}
+	}
+}
+
+func TestAlign_SizeMatchesPlan(t *testing.T) {
+	for _, opts := range []Options{
+		{Lines: 300, Mode: "digits:5+ascii:21+upper:9", Align: 64, AlignFill: '.'},
+		{Lines: 300, Ramp: Ramp{Min: 1, Max: 40, Step: 3, Reset: true}, Align: 41},
+		{Lines: 300, Width: 20, CommentEvery: 7, Align: 100},
+		{Lines: 50, Mode: "blocks", Width: 4, Align: 128},
+	} {
+		_, planned, err := PlanAlign(opts)
+		if err != nil {
+			t.Fatalf("%s: %v", opts.Mode, err)
+		}
+		if size, _ := PlanSize(opts); size != planned {
+			t.Errorf("%s: PlanSize %d, PlanAlign %d", opts.Mode, size, planned)
+		}
+		var buf bytes.Buffer
+		if _, written, err := GenerateTo(context.Background(), &buf, opts); err != nil || written != planned {
+			t.Errorf("%s: planned %d, wrote %d (%v)", opts.Mode, planned, written, err)
+		}
+	}
+}
+
+func TestAlign_Unsupported(t *testing.T) {
+	for _, opts := range []Options{
+		{Lines: 1, Width: 64, Align: 64},
+		{Lines: 1, Align: -1},
+		{ExactBytes: 100, Align: 128},
+		{Lines: 1, Mode: "template", ModeArg: "inline:x", Align: 128},
+		{Lines: 1, Width: 10, Align: 128, AlignFill: '\n'},
+		{Lines: 20, Width: 10, CommentEvery: 10, CommentText: strings.Repeat("#", 20), Align: 16},
+	} {
+		if _, err := PlanSize(opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+	if _, err := NewSeekable(Options{Lines: 1, Width: 10, Align: 64}); err == nil {
+		t.Error("expected NewSeekable to reject alignment")
+	}
+}
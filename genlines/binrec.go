@@ -0,0 +1,162 @@
+package genlines
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// errBinrecUnsupported is returned for settings that frame or rewrite lines
+// of text, which the fixed-size records of the binrec mode are not.
+var errBinrecUnsupported = errors.New("mode=binrec cannot be combined with a line ending, a width ramp, an exact byte size, comment lines, line checksums, alignment, escaping or interleave specs")
+
+// binrecInts maps the integer field types of the binrec mode to their size
+// in bytes and byte order.
+var binrecInts = map[string]struct {
+	size  int
+	order binary.ByteOrder
+}{
+	"u8":    {1, binary.BigEndian},
+	"u16le": {2, binary.LittleEndian},
+	"u16be": {2, binary.BigEndian},
+	"u32le": {4, binary.LittleEndian},
+	"u32be": {4, binary.BigEndian},
+	"u64le": {8, binary.LittleEndian},
+	"u64be": {8, binary.BigEndian},
+}
+
+// binrecField is one field of a binrec record layout.
+type binrecField struct {
+	size    int
+	order   binary.ByteOrder // nil for a bytes field
+	counter bool             // integer: the record number instead of value
+	value   uint64
+	cycle   bool // bytes: the ascii cycle instead of zero bytes
+	noise   bool // bytes: raw bytes from crypto/rand
+}
+
+// parseBinrecSpec parses the modeArg of the binrec mode: comma-separated
+// fields, each an integer <type>:counter or <type>:<value> (types u8, u16le,
+// u16be, u32le, u32be, u64le, u64be), or bytes:<n>:cycle, bytes:<n>:zero or
+// bytes:<n>:noise.
+func parseBinrecSpec(arg string) ([]binrecField, int, error) {
+	if strings.TrimSpace(arg) == "" {
+		return nil, 0, errors.New("mode=binrec requires a record layout, e.g. u32be:counter,bytes:20:cycle")
+	}
+	var fields []binrecField
+	size := 0
+	for i, p := range strings.Split(arg, ",") {
+		parts := strings.Split(strings.TrimSpace(p), ":")
+		var f binrecField
+		if parts[0] == "bytes" {
+			if len(parts) != 3 {
+				return nil, 0, fmt.Errorf("binrec field %d (%q): expected bytes:<n>:cycle, bytes:<n>:zero or bytes:<n>:noise", i+1, p)
+			}
+			n, err := strconv.Atoi(parts[1])
+			if err != nil || n <= 0 {
+				return nil, 0, fmt.Errorf("binrec field %d (%q): invalid byte count %q", i+1, p, parts[1])
+			}
+			switch parts[2] {
+			case "cycle":
+				f.cycle = true
+			case "noise":
+				f.noise = true
+			case "zero":
+			default:
+				return nil, 0, fmt.Errorf("binrec field %d (%q): unknown byte source %q (expected cycle, zero or noise)", i+1, p, parts[2])
+			}
+			f.size = n
+		} else {
+			t, ok := binrecInts[parts[0]]
+			if !ok {
+				return nil, 0, fmt.Errorf("binrec field %d (%q): unknown type %q (expected u8, u16le, u16be, u32le, u32be, u64le, u64be or bytes)", i+1, p, parts[0])
+			}
+			if len(parts) != 2 {
+				return nil, 0, fmt.Errorf("binrec field %d (%q): expected %s:counter or %s:<value>", i+1, p, parts[0], parts[0])
+			}
+			f.size, f.order = t.size, t.order
+			if parts[1] == "counter" {
+				f.counter = true
+			} else {
+				v, err := strconv.ParseUint(parts[1], 0, t.size*8)
+				if err != nil {
+					return nil, 0, fmt.Errorf("binrec field %d (%q): value %q does not fit %s", i+1, p, parts[1], parts[0])
+				}
+				f.value = v
+			}
+		}
+		fields = append(fields, f)
+		size += f.size
+	}
+	return fields, size, nil
+}
+
+// BinrecSize returns the size in bytes of one record of the binrec layout
+// arg.
+func BinrecSize(arg string) (int, error) {
+	_, size, err := parseBinrecSpec(arg)
+	return size, err
+}
+
+// newBinrecGen builds a generator of fixed-size binary records laid out as
+// arg describes.
+func newBinrecGen(arg string, _ int) (Generator, error) {
+	fields, size, err := parseBinrecSpec(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &binrecGen{fields: fields, size: size, ascii: cycleGen{palette: []byte(AsciiSequence())}}, nil
+}
+
+// binrecGen writes one binary record per line, ignoring the width. Counters
+// hold the one-based record number, wrapping at the size of their field;
+// cycle fields continue the ascii cycle from the record before; noise fields
+// hold random bytes.
+type binrecGen struct {
+	fields []binrecField
+	size   int
+	record uint64
+	ascii  cycleGen
+	noise  noiseSource
+}
+
+func (g *binrecGen) NextLine(int) string {
+	g.record++
+	out := make([]byte, 0, g.size)
+	var buf [8]byte
+	for _, f := range g.fields {
+		switch {
+		case f.order != nil:
+			v := f.value
+			if f.counter {
+				v = g.record
+			}
+			out = appendUint(out, buf[:], f.size, f.order, v)
+		case f.cycle:
+			out = append(out, g.ascii.NextLine(f.size)...)
+		case f.noise:
+			out = append(out, make([]byte, f.size)...)
+			g.noise.fill(out[len(out)-f.size:])
+		default:
+			out = append(out, make([]byte, f.size)...)
+		}
+	}
+	return string(out)
+}
+
+// appendUint appends the low size bytes of v to out in the given byte order.
+func appendUint(out, buf []byte, size int, order binary.ByteOrder, v uint64) []byte {
+	switch size {
+	case 1:
+		return append(out, byte(v))
+	case 2:
+		order.PutUint16(buf, uint16(v))
+	case 4:
+		order.PutUint32(buf, uint32(v))
+	default:
+		order.PutUint64(buf, v)
+	}
+	return append(out, buf[:size]...)
+}
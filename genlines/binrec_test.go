@@ -0,0 +1,90 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestBinrec_DecodesCountersAndLayout(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{Lines: 300, Width: 5, Mode: "binrec", ModeArg: "u32be:counter,u64le:counter,bytes:20:cycle,u8:counter,u16le:0xBEEF"}
+	lines, n, err := GenerateTo(context.Background(), &buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const size = 4 + 8 + 20 + 1 + 2
+	if lines != 300 || n != 300*size || buf.Len() != 300*size {
+		t.Fatalf("wrote %d records, %d bytes (buffer %d), want 300 records of %d bytes", lines, n, buf.Len(), size)
+	}
+	if planned, err := PlanSize(opts); err != nil || planned != n {
+		t.Errorf("PlanSize = %d, %v, want %d", planned, err, n)
+	}
+
+	ascii := []byte(AsciiSequence())
+	pos := 0
+	for i := 1; i <= 300; i++ {
+		rec := buf.Next(size)
+		if got := binary.BigEndian.Uint32(rec[0:4]); got != uint32(i) {
+			t.Fatalf("record %d: u32be counter = %d", i, got)
+		}
+		if got := binary.LittleEndian.Uint64(rec[4:12]); got != uint64(i) {
+			t.Fatalf("record %d: u64le counter = %d", i, got)
+		}
+		for j, b := range rec[12:32] {
+			if want := ascii[pos%len(ascii)]; b != want {
+				t.Fatalf("record %d: byte %d = %q, want %q", i, j, b, want)
+			}
+			pos++
+		}
+		if rec[32] != byte(i) { // wraps after 255
+			t.Fatalf("record %d: u8 counter = %d", i, rec[32])
+		}
+		if got := binary.LittleEndian.Uint16(rec[33:35]); got != 0xBEEF {
+			t.Fatalf("record %d: u16le literal = %#x", i, got)
+		}
+	}
+}
+
+func TestBinrec_SpecErrors(t *testing.T) {
+	for _, arg := range []string{
+		"",
+		"u24be:counter",
+		"u32be",
+		"u8:256",
+		"u16le:next",
+		"bytes:0:cycle",
+		"bytes:4",
+		"bytes:4:random",
+	} {
+		if _, err := NewGenerator("binrec", arg, 0); err == nil {
+			t.Errorf("%q: expected an error", arg)
+		}
+	}
+	if size, err := BinrecSize("u8:1,u16be:counter,bytes:5:zero"); err != nil || size != 8 {
+		t.Errorf("BinrecSize = %d, %v, want 8", size, err)
+	}
+}
+
+func TestBinrec_RejectsLineFraming(t *testing.T) {
+	base := Options{Lines: 3, Mode: "binrec", ModeArg: "u32be:counter"}
+	for name, mod := range map[string]func(*Options){
+		"eol":      func(o *Options) { o.EOL = []byte("\n") },
+		"comments": func(o *Options) { o.CommentEvery = 2 },
+		"checksum": func(o *Options) { o.LineChecksum = true },
+		"exact":    func(o *Options) { o.ExactBytes = 10 },
+		"align":    func(o *Options) { o.Align = 64 },
+		"ramp":     func(o *Options) { o.Ramp = Ramp{Min: 1, Max: 4, Step: 1} },
+	} {
+		o := base
+		mod(&o)
+		if _, _, err := GenerateTo(context.Background(), &bytes.Buffer{}, o); !errors.Is(err, errBinrecUnsupported) {
+			t.Errorf("%s: err = %v, want errBinrecUnsupported", name, err)
+		}
+	}
+	if _, err := ParseInterleave("ascii:10+binrec:10:u8:counter"); !errors.Is(err, errBinrecUnsupported) {
+		t.Errorf("interleave: err = %v, want errBinrecUnsupported", err)
+	}
+}
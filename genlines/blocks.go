@@ -0,0 +1,98 @@
+package genlines
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// blockCell is one cell of the blocks mode: a space on a 256-color
+	// background. The color index is always written with three digits, so
+	// every cell, and with it every line, has a fixed size in bytes.
+	blockCell = "\x1b[48;5;000m "
+
+	// blockReset ends every line of the blocks mode, so the color does not
+	// bleed into the terminator or whatever follows the output.
+	blockReset = "\x1b[0m"
+)
+
+// errBlocksUnsupported is returned for settings that assume one byte per
+// column, which the blocks mode does not write.
+var errBlocksUnsupported = errors.New("mode=blocks cannot be combined with a width ramp, an exact byte size, line checksums or interleave specs")
+
+// blockLineBytes returns the size in bytes of a blocks line of width cells,
+// without the terminator.
+func blockLineBytes(width int) int64 {
+	return int64(width)*int64(len(blockCell)) + int64(len(blockReset))
+}
+
+// splitBlocksArg splits the modeArg of the blocks mode, <mode>[:<modeArg>],
+// into the content mode it renders (default ascii) and that mode's modeArg.
+func splitBlocksArg(arg string) (mode, modeArg string) {
+	mode, modeArg, _ = strings.Cut(strings.TrimSpace(arg), ":")
+	if mode == "" {
+		mode = DefaultMode
+	}
+	return mode, modeArg
+}
+
+// newBlocksGen renders the content mode named by arg as a row of colored
+// cells per line, one cell per content byte.
+func newBlocksGen(arg string, totalChars int) (Generator, error) {
+	mode, modeArg := splitBlocksArg(arg)
+	if canonicalMode(mode) == "blocks" {
+		return nil, fmt.Errorf("mode=blocks cannot render %q", mode)
+	}
+	inner, err := newGenerator(mode, modeArg, totalChars)
+	if err != nil {
+		return nil, fmt.Errorf("mode=blocks: %w", err)
+	}
+	return &blockGen{inner: inner}, nil
+}
+
+// seedBlocksArg is the ModeSpec.SeedArg of the blocks mode: the seed goes to
+// the content mode it renders.
+func seedBlocksArg(seed uint64, arg string) string {
+	mode, modeArg := splitBlocksArg(arg)
+	_, spec, err := LookupMode(mode)
+	if err != nil || spec.SeedArg == nil {
+		return arg
+	}
+	return mode + ":" + spec.SeedArg(seed, modeArg)
+}
+
+// blockGen turns the first width bytes of each line of the wrapped generator
+// into cells whose background is the 256-color palette entry with that byte's
+// value. width counts visible cells, not bytes.
+type blockGen struct {
+	inner Generator
+}
+
+// Err reports a failure of the wrapped generator.
+func (g *blockGen) Err() error {
+	if f, ok := g.inner.(failingGenerator); ok {
+		return f.Err()
+	}
+	return nil
+}
+
+func (g *blockGen) NextLine(width int) string {
+	if width <= 0 {
+		return ""
+	}
+	content := g.inner.NextLine(width)
+	out := make([]byte, 0, blockLineBytes(width))
+	for i := 0; i < width; i++ {
+		b := byte(' ')
+		if i < len(content) {
+			b = content[i]
+		}
+		cell := len(out)
+		out = append(out, blockCell...)
+		idx := strconv.Itoa(int(b))
+		copy(out[cell+len(blockCell)-2-len(idx):], idx) // right-align in the 000 field
+	}
+	return string(append(out, blockReset...))
+}
@@ -0,0 +1,97 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var blockLineRE = regexp.MustCompile(`^(?:\x1b\[48;5;(\d{3})m )+\x1b\[0m$`)
+var blockCellRE = regexp.MustCompile(`\x1b\[48;5;(\d{3})m `)
+
+func TestBlocks_EscapeStructureAndCellCount(t *testing.T) {
+	g, err := NewGenerator("blocks", "digits", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, width := range []int{1, 7, 40} {
+		line := g.NextLine(width)
+		if !blockLineRE.MatchString(line) {
+			t.Fatalf("width %d: malformed line %q", width, line)
+		}
+		cells := blockCellRE.FindAllStringSubmatch(line, -1)
+		if len(cells) != width {
+			t.Errorf("width %d: %d cells", width, len(cells))
+		}
+		if int64(len(line)) != blockLineBytes(width) {
+			t.Errorf("width %d: %d bytes, want %d", width, len(line), blockLineBytes(width))
+		}
+		for _, c := range cells {
+			if c[1] < "048" || c[1] > "057" { // the bytes '0'..'9'
+				t.Errorf("width %d: color %s does not come from a digit", width, c[1])
+			}
+		}
+	}
+}
+
+func TestBlocks_ColorsFollowContent(t *testing.T) {
+	g, _ := NewGenerator("blocks", "char:A", 0)
+	want := strings.Repeat("\x1b[48;5;065m ", 3) + "\x1b[0m"
+	if got := g.NextLine(3); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	g, _ = NewGenerator("blocks", "", 0) // ascii
+	cells := blockCellRE.FindAllStringSubmatch(g.NextLine(3), -1)
+	for i, c := range cells {
+		if want := fmt.Sprintf("%03d", 32+i); c[1] != want {
+			t.Errorf("cell %d: color %s, want %s", i, c[1], want)
+		}
+	}
+}
+
+func TestBlocks_PlanSizeMatchesOutput(t *testing.T) {
+	opts := Options{Lines: 25, Width: 12, Mode: "blocks", ModeArg: "random:7", CommentEvery: 10}
+	want, err := PlanSize(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, n, err := GenerateTo(context.Background(), &buf, opts); err != nil || n != want || int64(buf.Len()) != want {
+		t.Errorf("wrote %d (%d reported, %v), planned %d", buf.Len(), n, err, want)
+	}
+}
+
+func TestBlocks_Unsupported(t *testing.T) {
+	for _, opts := range []Options{
+		{Lines: 1, Mode: "blocks", LineChecksum: true},
+		{Lines: 1, Mode: "blocks", ExactBytes: 100},
+		{Lines: 1, Mode: "blocks", Ramp: Ramp{Min: 1, Max: 5, Step: 1}},
+		{Lines: 1, Mode: "blocks:4+ascii:4"},
+	} {
+		if _, err := PlanSize(opts); !errors.Is(err, errBlocksUnsupported) {
+			t.Errorf("%+v: got %v", opts, err)
+		}
+	}
+	for _, arg := range []string{"blocks", "ip:cidr:bad", "nosuchmode"} {
+		if _, err := NewGenerator("blocks", arg, 0); err == nil {
+			t.Errorf("blocks %q: expected an error", arg)
+		}
+	}
+}
+
+func TestBlocks_SeedReachesContentMode(t *testing.T) {
+	arg, err := SeedModeArg("blocks", "random", 5, SeedLabelContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("random:%d", DeriveSeed(5, SeedLabelContent)); arg != want {
+		t.Errorf("got %q, want %q", arg, want)
+	}
+	if arg, _ := SeedModeArg("blocks", "random:9", 5, SeedLabelContent); arg != "random:9" {
+		t.Errorf("explicit seed overridden: %q", arg)
+	}
+}
@@ -0,0 +1,294 @@
+package genlines
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteRange limits the content to the bytes Lo through Hi, for transports
+// that mangle anything else. Modes that draw from a palette (ascii, digits,
+// upper, alpha, random and the csv fields) keep the part of it inside the
+// range; modes with a fixed alphabet must fit in it, or are rejected. The
+// zero value (Hi 0) is off.
+type ByteRange struct {
+	Lo, Hi byte
+}
+
+// Enabled reports whether r limits anything.
+func (r ByteRange) Enabled() bool {
+	return r.Hi > 0
+}
+
+// ParseByteRange parses "lo-hi", each bound decimal (32) or hex (0x20).
+func ParseByteRange(spec string) (ByteRange, error) {
+	lo, hi, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return ByteRange{}, fmt.Errorf("invalid byte range %q (expected lo-hi, e.g. 0x20-0x7e or 48-57)", spec)
+	}
+	var (
+		r      ByteRange
+		bounds = []*byte{&r.Lo, &r.Hi}
+	)
+	for i, s := range []string{lo, hi} {
+		s = strings.TrimSpace(s)
+		base := 10
+		if h, ok := strings.CutPrefix(strings.ToLower(s), "0x"); ok {
+			s, base = h, 16
+		}
+		n, err := strconv.ParseUint(s, base, 8)
+		if err != nil {
+			return ByteRange{}, fmt.Errorf("invalid byte range %q: %q is not a byte value (0-255 or 0x00-0xff)", spec, []string{lo, hi}[i])
+		}
+		*bounds[i] = byte(n)
+	}
+	if r.Lo > r.Hi {
+		return ByteRange{}, fmt.Errorf("invalid byte range %q: the low bound is above the high one", spec)
+	}
+	if r.Hi == 0 {
+		return ByteRange{}, fmt.Errorf("invalid byte range %q: only NUL bytes cannot hold any content", spec)
+	}
+	return r, nil
+}
+
+// String returns r in the form ParseByteRange accepts, in hex.
+func (r ByteRange) String() string {
+	return fmt.Sprintf("0x%02x-0x%02x", r.Lo, r.Hi)
+}
+
+// contains reports whether b is in r.
+func (r ByteRange) contains(b byte) bool {
+	return r.Lo <= b && b <= r.Hi
+}
+
+// outside returns the first byte of s outside r, if any.
+func (r ByteRange) outside(s string) (byte, bool) {
+	for i := 0; i < len(s); i++ {
+		if !r.contains(s[i]) {
+			return s[i], true
+		}
+	}
+	return 0, false
+}
+
+// filter returns the bytes of palette inside r, in order.
+func (r ByteRange) filter(palette []byte) []byte {
+	var kept []byte
+	for _, b := range palette {
+		if r.contains(b) {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
+
+// byteLimiter is implemented by generators that can keep their content
+// within a byte range: limitBytes narrows their palette to r, or returns an
+// error, which the caller prefixes with the mode, if they cannot fit in it.
+// Generators without it are taken to use all of printable ASCII.
+type byteLimiter interface {
+	limitBytes(r ByteRange) error
+}
+
+// paletted is implemented by generators that can list every byte their
+// content may hold.
+type paletted interface {
+	alphabet() []byte
+}
+
+// byteSet returns the distinct bytes of s, sorted.
+func byteSet(s string) []byte {
+	var seen [256]bool
+	for i := 0; i < len(s); i++ {
+		seen[s[i]] = true
+	}
+	var set []byte
+	for b, ok := range seen {
+		if ok {
+			set = append(set, byte(b))
+		}
+	}
+	return set
+}
+
+// errByteRangeUnsupported is returned for modes whose content is not
+// characters: blocks writes escape sequences and binrec binary fields.
+var errByteRangeUnsupported = errors.New("a byte range cannot be combined with mode=blocks or mode=binrec")
+
+// limitBytes restricts the content of gen, built for o, to o.ByteRange.
+func (o Options) limitBytes(gen Generator) error {
+	r := o.ByteRange
+	if !r.Enabled() {
+		return nil
+	}
+	l, ok := gen.(byteLimiter)
+	if !ok {
+		l = printableGen{}
+	}
+	if err := l.limitBytes(r); err != nil {
+		return fmt.Errorf("mode=%s: %w", o.Mode, err)
+	}
+	return nil
+}
+
+// printableGen stands in for generators without a byteLimiter.
+type printableGen struct{}
+
+func (printableGen) limitBytes(r ByteRange) error {
+	return fitAlphabet("characters (any printable ASCII)", AsciiSequence(), r)
+}
+
+// limitPalette narrows palette to r, or fails if nothing is left.
+func limitPalette(palette []byte, r ByteRange) ([]byte, error) {
+	kept := r.filter(palette)
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("no character of its palette %q is in bytes %s", palette, r)
+	}
+	return kept, nil
+}
+
+// fitAlphabet fails if a fixed alphabet, described by what, has a byte
+// outside r.
+func fitAlphabet(what, alphabet string, r ByteRange) error {
+	if b, ok := r.outside(alphabet); ok {
+		return fmt.Errorf("its %s include %q, outside bytes %s, and cannot be changed", what, b, r)
+	}
+	return nil
+}
+
+func (g *cycleGen) limitBytes(r ByteRange) (err error) {
+	g.palette, err = limitPalette(g.palette, r)
+	return err
+}
+
+func (g *cycleGen) alphabet() []byte { return byteSet(string(g.palette)) }
+
+func (g *randomGen) limitBytes(r ByteRange) (err error) {
+	g.palette, err = limitPalette(g.palette, r)
+	return err
+}
+
+func (g *randomGen) alphabet() []byte { return byteSet(string(g.palette)) }
+
+func (g *singleCharGen) limitBytes(r ByteRange) error {
+	return fitAlphabet("character", g.ch, r)
+}
+
+func (g *singleCharGen) alphabet() []byte { return byteSet(g.ch) }
+
+func (g *piGen) limitBytes(r ByteRange) error {
+	return fitAlphabet("digit characters", string(g.table[:]), r)
+}
+
+func (g *piGen) alphabet() []byte { return byteSet(string(g.table[:])) }
+
+func (g *wordGen) limitBytes(r ByteRange) error {
+	return fitAlphabet("words", strings.Join(g.words, " "), r)
+}
+
+func (g *wordGen) alphabet() []byte { return byteSet(strings.Join(g.words, " ")) }
+
+func (g *csvGen) limitBytes(r ByteRange) (err error) {
+	if err := fitAlphabet("delimiter and quote characters", g.structure(), r); err != nil {
+		return err
+	}
+	g.fill.palette, err = limitPalette(g.fill.palette, r)
+	return err
+}
+
+// structure returns the characters of csv records other than field content.
+func (g *csvGen) structure() string {
+	s := string(g.args.delim)
+	if g.args.multiline > 0 || g.unsafe {
+		s += `"`
+	}
+	if g.unsafe {
+		s += formulaChars
+	}
+	return s
+}
+
+func (g *csvGen) alphabet() []byte { return byteSet(string(g.fill.palette) + g.structure()) }
+
+func (g *interleaveGen) limitBytes(r ByteRange) error {
+	for i, sg := range g.gens {
+		o := Options{Mode: g.streams[i].Mode, ByteRange: r}
+		if err := o.limitBytes(sg); err != nil {
+			return fmt.Errorf("stream %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateByteRange checks that the parts of a line outside the mode's
+// content fit in o.ByteRange (with defaults applied). Line terminators are
+// not content and are not checked.
+func (o Options) validateByteRange() error {
+	r := o.ByteRange
+	if !r.Enabled() {
+		return nil
+	}
+	if mode := canonicalMode(o.Mode); mode == "blocks" || mode == "binrec" {
+		return errByteRangeUnsupported
+	}
+	for _, part := range []struct {
+		on       bool
+		what, in string
+	}{
+		{o.LineChecksum, "line checksums", "0123456789abcdef "},
+		{o.CommentEvery > 0, "comment lines", formatComment(o.CommentText, 1234567890)},
+		{o.Continuation != "", "the continuation marker", o.Continuation},
+		{o.TrailingWS.Enabled(), "trailing whitespace", " \t"},
+		{o.Align > 0, "alignment padding", string(o.AlignFill)},
+		{o.InjectUnicode.Enabled(), "injected code points", o.InjectUnicode.encoded()},
+		{o.EscapeNonASCII, "escapes", `\u0123456789abcdef`},
+	} {
+		if b, ok := r.outside(part.in); part.on && ok {
+			return fmt.Errorf("%s cannot be limited to bytes %s (%q is outside)", part.what, r, b)
+		}
+	}
+	// Letters in the range must rotate to letters in it.
+	if b, ok := r.outside(Rotate(string(r.all()), o.Rot)); o.Rot != 0 && ok {
+		return fmt.Errorf("--rot %d moves letters outside bytes %s (to %q)", o.Rot, r, b)
+	}
+	return nil
+}
+
+// fitSafeStart fails if the letters a safe start may write over the start
+// of line 1 are outside r.
+func (r ByteRange) fitSafeStart() error {
+	if b, ok := r.outside(safeStartLetters); r.Enabled() && ok {
+		return fmt.Errorf("a safe start replacing the start of line 1 with %q cannot be limited to bytes %s (%q is outside)", safeStartLetters, r, b)
+	}
+	return nil
+}
+
+// all returns every byte in r, in order.
+func (r ByteRange) all() []byte {
+	var set []byte
+	for b := int(r.Lo); b <= int(r.Hi); b++ {
+		set = append(set, byte(b))
+	}
+	return set
+}
+
+// EffectivePalette returns every byte the content of opts may hold, sorted,
+// with opts.ByteRange applied, or false if the mode does not list them
+// (template, dates, ip and other modes with structured content).
+func EffectivePalette(opts Options) ([]byte, bool, error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, false, err
+	}
+	opts.PiDigits = nil
+	gen, err := buildGenerator(opts)
+	if err != nil {
+		return nil, false, err
+	}
+	p, ok := gen.(paletted)
+	if !ok || p.alphabet() == nil {
+		return nil, false, nil
+	}
+	return p.alphabet(), true, nil
+}
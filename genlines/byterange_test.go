@@ -0,0 +1,135 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseByteRange(t *testing.T) {
+	for spec, want := range map[string]ByteRange{
+		"0x20-0x7e": {0x20, 0x7e},
+		"48-57":     {48, 57},
+		"0X30-57":   {0x30, 57},
+		" 0-255 ":   {0, 255},
+		"65-65":     {65, 65},
+	} {
+		got, err := ParseByteRange(spec)
+		if err != nil || got != want {
+			t.Errorf("%q: %v, %v; want %v", spec, got, err, want)
+		}
+	}
+	for _, spec := range []string{"", "48", "57-48", "0-256", "0x-0x10", "a-z", "0-0", "-5"} {
+		if _, err := ParseByteRange(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+	if got := (ByteRange{0x30, 0x39}).String(); got != "0x30-0x39" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestByteRange_AsciiToDigitsMatchesDigitsMode(t *testing.T) {
+	for _, extra := range []Options{{}, {StartOffset: 7}, {ExactBytes: 1000}} {
+		limited, digits := extra, extra
+		limited.Lines, limited.Width, limited.Mode, limited.ByteRange = 40, 33, "ascii", ByteRange{0x30, 0x39}
+		digits.Lines, digits.Width, digits.Mode = 40, 33, "digits"
+		var a, b bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &a, limited); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := GenerateTo(context.Background(), &b, digits); err != nil {
+			t.Fatal(err)
+		}
+		if a.String() != b.String() {
+			t.Errorf("%+v: ascii limited to 0x30-0x39 differs from digits", extra)
+		}
+	}
+	if p, ok, err := EffectivePalette(Options{Mode: "ascii", ByteRange: ByteRange{0x30, 0x39}}); string(p) != "0123456789" || !ok || err != nil {
+		t.Errorf("palette %q, %v, %v", p, ok, err)
+	}
+}
+
+func TestByteRange_KeepsContentInRange(t *testing.T) {
+	r := ByteRange{'A', 'f'}
+	for _, opts := range []Options{
+		{Lines: 50, Width: 40, Mode: "random", ModeArg: "3"},
+		{Lines: 50, Width: 40, Mode: "alpha"},
+		{Lines: 50, Width: 40, Mode: "upper:10+random:10:5"},
+		{Lines: 50, Width: 40, Mode: "csv", ModeArg: "delim=^"},
+		{Lines: 50, Width: 40, Mode: "char", ModeArg: "Z"},
+	} {
+		opts.ByteRange = r
+		var buf bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+			t.Errorf("mode=%s: %v", opts.Mode, err)
+			continue
+		}
+		if b, ok := r.outside(strings.ReplaceAll(buf.String(), "\n", "")); ok {
+			t.Errorf("mode=%s wrote %q", opts.Mode, b)
+		}
+	}
+}
+
+func TestByteRange_Rejected(t *testing.T) {
+	for _, tt := range []struct {
+		opts Options
+		want string
+	}{
+		{Options{Mode: "ascii", ByteRange: ByteRange{0x80, 0xff}}, "mode=ascii: no character of its palette"},
+		{Options{Mode: "digits", ByteRange: ByteRange{'A', 'Z'}}, "mode=digits: no character of its palette"},
+		{Options{Mode: "csv", ByteRange: ByteRange{'0', '9'}}, "mode=csv: its delimiter and quote characters include ','"},
+		{Options{Mode: "csv", ModeArg: "multiline=2;delim=;", ByteRange: ByteRange{';', 'z'}}, `include '"'`},
+		{Options{Mode: "char", ModeArg: "#", ByteRange: ByteRange{'0', '9'}}, "mode=char: its character include '#'"},
+		{Options{Mode: "pi", ByteRange: ByteRange{'A', 'Z'}}, "mode=pi: its digit characters include '0'"},
+		{Options{Mode: "lorem", ByteRange: ByteRange{'a', 'z'}}, "mode=lorem: its words include ' '"},
+		{Options{Mode: "jsonl", ByteRange: ByteRange{'0', 'z'}}, "mode=jsonl: its characters (any printable ASCII) include ' '"},
+		{Options{Mode: "ascii:4+digits:4", ByteRange: ByteRange{'A', 'Z'}}, "stream 2: mode=digits"},
+		{Options{Mode: "alpha", LineChecksum: true, ByteRange: ByteRange{'A', 'z'}}, "line checksums cannot be limited"},
+		{Options{Mode: "alpha", CommentEvery: 5, ByteRange: ByteRange{'A', 'z'}}, "comment lines cannot be limited"},
+		{Options{Mode: "alpha", Continuation: `\`, ByteRange: ByteRange{'a', 'z'}}, "continuation marker cannot be limited"},
+		{Options{Mode: "blocks", ByteRange: ByteRange{0x20, 0x7e}}, "mode=blocks"},
+	} {
+		tt.opts.Lines, tt.opts.Width = 3, 40
+		_, _, err := GenerateTo(context.Background(), &bytes.Buffer{}, tt.opts)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s %v: %v, want %q", tt.opts.Mode, tt.opts.ByteRange, err, tt.want)
+		}
+	}
+
+	// Fixed alphabets that fit are accepted as they are.
+	for _, opts := range []Options{
+		{Lines: 3, Width: 40, Mode: "jsonl", ByteRange: ByteRange{0x20, 0x7e}},
+		{Lines: 3, Width: 40, Mode: "lorem", ByteRange: ByteRange{' ', 'z'}},
+		{Lines: 3, Width: 40, Mode: "alpha", LineChecksum: true, ByteRange: ByteRange{' ', 'z'}},
+	} {
+		if _, _, err := GenerateTo(context.Background(), &bytes.Buffer{}, opts); err != nil {
+			t.Errorf("mode=%s: %v", opts.Mode, err)
+		}
+	}
+}
+
+func TestByteRange_RotAndSafeStart(t *testing.T) {
+	for _, tt := range []struct {
+		opts Options
+		want string // "" = accepted
+	}{
+		{Options{Mode: "ascii", Rot: 13, ByteRange: ByteRange{'0', '9'}}, ""},
+		{Options{Mode: "ascii", Rot: 13, ByteRange: ByteRange{'A', 'Z'}}, ""},
+		{Options{Mode: "ascii", Rot: 13, ByteRange: ByteRange{'A', 'M'}}, "--rot 13 moves letters outside"},
+		{Options{Mode: "ascii", SafeStart: true, ByteRange: ByteRange{'0', '9'}}, ""},
+		{Options{Mode: "random", ModeArg: "1", SafeStart: true, ByteRange: ByteRange{'0', '9'}}, "a safe start replacing the start of line 1"},
+	} {
+		tt.opts.Lines, tt.opts.Width = 3, 20
+		var buf bytes.Buffer
+		_, _, err := GenerateTo(context.Background(), &buf, tt.opts)
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("%+v: %v, want %q", tt.opts, err, tt.want)
+			continue
+		}
+		if b, ok := tt.opts.ByteRange.outside(strings.ReplaceAll(buf.String(), "\n", "")); err == nil && ok {
+			t.Errorf("%+v wrote %q", tt.opts, b)
+		}
+	}
+}
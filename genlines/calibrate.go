@@ -0,0 +1,104 @@
+package genlines
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// calibrationDigits is the most digits CalibratePi computes.
+	calibrationDigits = 500
+	// calibrationTime ends a calibration early on slow (large) runs.
+	calibrationTime = 200 * time.Millisecond
+)
+
+// PiCalibration is a short timed burst of the π spigot, sized for a whole
+// pi run, from which the run's duration is estimated. The spigot's cost per
+// digit depends only on its size, so the burst extrapolates linearly.
+type PiCalibration struct {
+	Digits  int           // digits the run needs
+	Sampled int           // digits computed by the burst
+	Elapsed time.Duration // time the burst took
+
+	head   []int // the sampled digits, replayed by Stream
+	stream DigitStream
+}
+
+// CalibratePi computes the first digits of the pi run described by opts,
+// timing them with now, until calibrationDigits are done, calibrationTime has
+// passed or the run's digits are exhausted. Pass the calibration's Stream as
+// Options.PiDigits so the sampled digits are used for the output.
+func CalibratePi(opts Options, now func() time.Time) (*PiCalibration, error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if IsInterleaveSpec(opts.Mode) {
+		return nil, errors.New("pi calibration needs mode=pi, not an interleave spec")
+	}
+	if name, _, _ := LookupMode(opts.Mode); name != "pi" {
+		return nil, errors.New("pi calibration needs mode=pi")
+	}
+	if opts.ExactBytes > 0 {
+		count, _, err := PlanExactBytes(opts)
+		if err != nil {
+			return nil, err
+		}
+		opts.Lines = int(count) + 1 // as GenerateTo sizes its generator
+	}
+
+	c := &PiCalibration{Digits: max(opts.Lines*opts.widest(), 1)}
+	c.stream = NewPiDigits(c.Digits)
+	start := now()
+	for c.Sampled < min(c.Digits, calibrationDigits) {
+		c.head = append(c.head, c.stream.NextDigit())
+		c.Sampled++
+		if c.Elapsed = now().Sub(start); c.Elapsed >= calibrationTime {
+			break
+		}
+	}
+	return c, nil
+}
+
+// Estimate returns the projected time to compute all of the run's digits.
+func (c *PiCalibration) Estimate() time.Duration {
+	if c.Sampled == 0 {
+		return 0
+	}
+	return time.Duration(float64(c.Elapsed) * float64(c.Digits) / float64(c.Sampled))
+}
+
+// Stream returns the run's digit stream: the sampled digits first, then the
+// rest from the same spigot. It can serve only one run.
+func (c *PiCalibration) Stream() DigitStream {
+	return &replayDigits{head: c.head, rest: c.stream}
+}
+
+// replayDigits returns buffered digits before reading on from rest.
+type replayDigits struct {
+	head []int
+	rest DigitStream
+}
+
+func (r *replayDigits) NextDigit() int {
+	if len(r.head) > 0 {
+		d := r.head[0]
+		r.head = r.head[1:]
+		return d
+	}
+	return r.rest.NextDigit()
+}
+
+func (r *replayDigits) Skip(n int) {
+	k := min(n, len(r.head))
+	r.head = r.head[k:]
+	if n > k {
+		r.rest.Skip(n - k)
+	}
+}
+
+func (r *replayDigits) NextDigits(dst []int) {
+	k := copy(dst, r.head)
+	r.head = r.head[k:]
+	nextDigits(r.rest, dst[k:])
+}
@@ -0,0 +1,87 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a clock that advances by step on every call.
+func fakeClock(step time.Duration) func() time.Time {
+	t := time.Unix(0, 0)
+	return func() time.Time {
+		t = t.Add(step)
+		return t
+	}
+}
+
+func TestCalibratePi_Extrapolates(t *testing.T) {
+	cal, err := CalibratePi(Options{Lines: 1000, Width: 100, Mode: "pi"}, fakeClock(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cal.Digits != 100_000 {
+		t.Errorf("Digits = %d, want 100000", cal.Digits)
+	}
+	// One tick per digit: the burst stops at calibrationTime.
+	if cal.Sampled != 200 || cal.Elapsed != 200*time.Millisecond {
+		t.Errorf("sampled %d digits in %s", cal.Sampled, cal.Elapsed)
+	}
+	if got := cal.Estimate(); got != 100*time.Second {
+		t.Errorf("Estimate = %s, want 100s", got)
+	}
+
+	cal, _ = CalibratePi(Options{Lines: 3, Width: 10, Mode: "pi"}, fakeClock(time.Nanosecond))
+	if cal.Sampled != 30 || cal.Estimate() != 30*time.Nanosecond {
+		t.Errorf("small run: sampled %d, estimate %s", cal.Sampled, cal.Estimate())
+	}
+
+	if _, err := CalibratePi(Options{Lines: 3, Mode: "digits"}, time.Now); err == nil {
+		t.Error("expected an error for a mode other than pi")
+	}
+}
+
+func TestCalibratePi_SampledDigitsAreUsed(t *testing.T) {
+	for _, opts := range []Options{
+		{Lines: 40, Width: 30, Mode: "pi"},
+		{Lines: 5, Width: 7, Mode: "pi", ModeArg: "ascii"},
+		{ExactBytes: 1000, Width: 64, Mode: "pi"},
+	} {
+		var want bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &want, opts); err != nil {
+			t.Fatal(err)
+		}
+
+		cal, err := CalibratePi(opts, fakeClock(time.Microsecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cal.Sampled == 0 {
+			t.Fatal("calibration sampled nothing")
+		}
+		opts.PiDigits = cal.Stream()
+		var got bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &got, opts); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("%+v: output with the calibrated stream differs", opts)
+		}
+	}
+}
+
+func TestReplayDigits_Skip(t *testing.T) {
+	r := &replayDigits{head: []int{1, 2, 3}, rest: NewCyclicDigits(4, 5, 6)}
+	r.Skip(2)
+	var got []int
+	for i := 0; i < 3; i++ {
+		got = append(got, r.NextDigit())
+	}
+	r.Skip(1)
+	got = append(got, r.NextDigit())
+	if want := []int{3, 4, 5, 4}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
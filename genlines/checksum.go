@@ -0,0 +1,70 @@
+package genlines
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+const (
+	// ChecksumWidth is the number of hex digits a line checksum occupies.
+	ChecksumWidth = 8
+
+	// MinChecksumLineWidth is the narrowest line that can carry a checksum:
+	// one content character, the separating space and the checksum.
+	MinChecksumLineWidth = 10
+)
+
+// AppendChecksum returns prefix followed by the CRC32 (IEEE) of prefix as
+// ChecksumWidth lowercase hex digits. Callers include the separating space in prefix.
+func AppendChecksum(prefix string) string {
+	return prefix + fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(prefix)))
+}
+
+// CheckLine reports whether line (without terminator) ends in a valid
+// checksum of the characters before it, separated by a space.
+func CheckLine(line string) bool {
+	if len(line) < MinChecksumLineWidth {
+		return false
+	}
+	prefix := line[:len(line)-ChecksumWidth]
+	return strings.HasSuffix(prefix, " ") && AppendChecksum(prefix) == line
+}
+
+// VerifyLines scans r line by line (LF or CRLF terminated) and calls bad with
+// the 1-based number of every line whose checksum does not match. A
+// continuation marker after the checksum (see Options.Continuation) is
+// ignored, and so is trailing whitespace (see Options.TrailingWS). It returns
+// the number of lines checked.
+func VerifyLines(r io.Reader, bad func(lineNo int64, line string)) (int64, error) {
+	return VerifyRecords(r, '\n', bad)
+}
+
+// VerifyRecords is VerifyLines for records that end with sep, e.g. 0 for
+// NUL-delimited output. A CR before an LF sep is not part of the record.
+func VerifyRecords(r io.Reader, sep byte, bad func(lineNo int64, line string)) (int64, error) {
+	br := bufio.NewReaderSize(r, bufferSize)
+	var n int64
+	for {
+		line, err := br.ReadString(sep)
+		if line != "" {
+			n++
+			line = strings.TrimSuffix(line, string(sep))
+			if sep == '\n' {
+				line = strings.TrimSuffix(line, "\r")
+			}
+			if !checkContinuedLine(line) && !CheckLine(strings.TrimRight(line, " \t")) {
+				bad(n, line)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckLine(t *testing.T) {
+	line := AppendChecksum("hello ")
+	if len(line) != 14 || !CheckLine(line) {
+		t.Fatalf("AppendChecksum(%q) = %q, which does not verify", "hello ", line)
+	}
+	for _, bad := range []string{
+		"jello " + line[6:],                  // content changed
+		"hello" + line[6:],                   // separator missing
+		line[:13] + "0",                      // checksum changed
+		"short",                              // too short
+		strings.ToUpper(line[:6]) + line[6:], // case matters
+	} {
+		if CheckLine(bad) {
+			t.Errorf("CheckLine(%q) = true, want false", bad)
+		}
+	}
+}
+
+func TestVerifyLines_FindsCorruptedLine(t *testing.T) {
+	var buf bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 20, Width: 30, Mode: "ascii", LineChecksum: true, EOL: []byte("\r\n")}); err != nil {
+		t.Fatalf("GenerateTo: %v", err)
+	}
+	data := buf.Bytes()
+	data[7*32+3] ^= 0x01 // flip a bit in line 8
+
+	var bad []int64
+	total, err := VerifyLines(bytes.NewReader(data), func(n int64, _ string) { bad = append(bad, n) })
+	if err != nil {
+		t.Fatalf("VerifyLines: %v", err)
+	}
+	if total != 20 || !reflect.DeepEqual(bad, []int64{8}) {
+		t.Errorf("VerifyLines = %d lines, bad %v; want 20 lines, bad [8]", total, bad)
+	}
+}
+
+func TestVerifyRecords_NUL(t *testing.T) {
+	var buf bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 10, Width: 20, Mode: "digits", LineChecksum: true, EOL: []byte{0}}); err != nil {
+		t.Fatalf("GenerateTo: %v", err)
+	}
+	data := buf.Bytes()
+	data[4*21+2] ^= 0x01 // line 5
+
+	var bad []int64
+	total, err := VerifyRecords(bytes.NewReader(data), 0, func(n int64, _ string) { bad = append(bad, n) })
+	if err != nil {
+		t.Fatalf("VerifyRecords: %v", err)
+	}
+	if total != 10 || !reflect.DeepEqual(bad, []int64{5}) {
+		t.Errorf("VerifyRecords = %d records, bad %v; want 10 records, bad [5]", total, bad)
+	}
+}
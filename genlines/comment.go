@@ -0,0 +1,45 @@
+package genlines
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ValidateCommentText checks that text only uses the %d verb (and %% for a literal percent sign).
+func ValidateCommentText(text string) error {
+	for i := 0; i < len(text); i++ {
+		if text[i] != '%' {
+			continue
+		}
+		if i+1 >= len(text) {
+			return fmt.Errorf("comment text %q ends with a lone %%", text)
+		}
+		switch text[i+1] {
+		case 'd', '%':
+			i++
+		default:
+			return fmt.Errorf("comment text %q uses %%%c (only %%d and %%%% are allowed)", text, text[i+1])
+		}
+	}
+	if strings.ContainsAny(text, "\r\n") {
+		return fmt.Errorf("comment text %q must not contain line breaks", text)
+	}
+	return nil
+}
+
+// formatComment expands %d in a validated comment template to n.
+func formatComment(text string, n int64) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '%' && i+1 < len(text) {
+			i++
+			if text[i] == 'd' {
+				b.WriteString(strconv.FormatInt(n, 10))
+				continue
+			}
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package genlines
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// DefaultContinuation is the conventional continuation marker, a
+	// trailing backslash.
+	DefaultContinuation = `\`
+
+	// MaxContinuationWidth is the longest continuation marker accepted.
+	MaxContinuationWidth = 8
+)
+
+// validateContinuation checks the continuation marker of o (with defaults
+// applied).
+func (o Options) validateContinuation() error {
+	if o.Continuation == "" {
+		return nil
+	}
+	if len(o.Continuation) > MaxContinuationWidth {
+		return fmt.Errorf("a continuation marker can be at most %d characters, got %d", MaxContinuationWidth, len(o.Continuation))
+	}
+	for _, r := range o.Continuation {
+		if r < ' ' || r > '~' {
+			return fmt.Errorf("continuation marker %q must be printable ASCII", o.Continuation)
+		}
+	}
+	switch {
+	case o.CommentEvery > 0 || o.Align > 0:
+		return errors.New("a continuation marker cannot be combined with comment lines or alignment, which would break up continued lines")
+	case o.MaxBytes > 0 || o.ExactBytes > 0:
+		return errors.New("a continuation marker cannot be combined with a byte ceiling or an exact byte size, which leave the last line unknown")
+	case IsInterleaveSpec(o.Mode) || canonicalMode(o.Mode) == "blocks" || canonicalMode(o.Mode) == "binrec":
+		return fmt.Errorf("a continuation marker is not supported with %s", o.Mode)
+	}
+	if w := o.narrowest(); w <= len(o.Continuation) {
+		return fmt.Errorf("continuation marker %q needs a width of at least %d, got %d", o.Continuation, len(o.Continuation)+1, w)
+	}
+	return nil
+}
+
+// checkContinuedLine is CheckLine for a line that may end in a continuation
+// marker after its checksum.
+func checkContinuedLine(line string) bool {
+	for k := 0; k <= MaxContinuationWidth && len(line)-k >= MinChecksumLineWidth; k++ {
+		if CheckLine(line[:len(line)-k]) {
+			return true
+		}
+	}
+	return false
+}
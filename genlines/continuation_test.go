@@ -0,0 +1,108 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestContinuation_MarksAllButLastLine(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		opts   Options
+		marker string
+	}{
+		{"default", Options{Lines: 5, Width: 12, Continuation: DefaultContinuation}, `\`},
+		{"crlf", Options{Lines: 4, Width: 12, Continuation: " &", EOL: []byte("\r\n")}, " &"},
+		{"ramp", Options{Lines: 6, Mode: "digits", Continuation: DefaultContinuation, Ramp: Ramp{Min: 3, Max: 6, Step: 1}}, `\`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			_, n, err := GenerateTo(context.Background(), &buf, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if planned, err := PlanSize(tt.opts); err != nil || planned != n {
+				t.Errorf("PlanSize = %d, %v, want %d", planned, err, n)
+			}
+			eol := "\n"
+			if tt.opts.EOL != nil {
+				eol = string(tt.opts.EOL)
+			}
+			lines := strings.Split(strings.TrimSuffix(buf.String(), eol), eol)
+			if len(lines) != tt.opts.Lines {
+				t.Fatalf("%d lines, want %d", len(lines), tt.opts.Lines)
+			}
+			for i, line := range lines {
+				want := tt.opts.Width
+				if tt.opts.Ramp.Enabled() {
+					want = tt.opts.Ramp.Width(int64(i + 1))
+				}
+				if len(line) != want {
+					t.Errorf("line %d: %q is %d wide, want %d", i+1, line, len(line), want)
+				}
+				last := i == len(lines)-1
+				if marked := strings.HasSuffix(line, tt.marker); marked == last {
+					t.Errorf("line %d: %q, marker present = %v", i+1, line, marked)
+				}
+			}
+		})
+	}
+}
+
+func TestContinuation_AfterChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{Lines: 3, Width: 20, Mode: "digits", LineChecksum: true, Continuation: DefaultContinuation, EOL: []byte("\r\n")}
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	// content, checksum, continuation, terminator
+	if first := lines[0]; len(first) != 20 || !CheckLine(strings.TrimSuffix(first, `\`)) || !strings.HasSuffix(first, `\`) {
+		t.Errorf("first line %q", first)
+	}
+	if last := lines[2]; len(last) != 20 || !CheckLine(last) {
+		t.Errorf("last line %q", last)
+	}
+	n, err := VerifyLines(&buf, func(n int64, line string) { t.Errorf("line %d failed verification: %q", n, line) })
+	if err != nil || n != 3 {
+		t.Errorf("VerifyLines = %d, %v", n, err)
+	}
+}
+
+func TestContinuation_MultiByteContent(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{Lines: 4, Width: 6, Mode: "char", ModeArg: "é", Continuation: DefaultContinuation}
+	_, n, err := GenerateTo(context.Background(), &buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if planned, err := PlanSize(opts); err != nil || planned != n {
+		t.Errorf("PlanSize = %d, %v, want %d", planned, err, n)
+	}
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if w := utf8.RuneCountInString(line); w != 6 {
+			t.Errorf("line %d: %q is %d columns", i+1, line, w)
+		}
+	}
+}
+
+func TestContinuation_Rejected(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"too narrow": {Lines: 2, Width: 1, Continuation: `\`},
+		"control":    {Lines: 2, Continuation: "\t"},
+		"too long":   {Lines: 2, Continuation: "123456789"},
+		"comments":   {Lines: 2, CommentEvery: 1, Continuation: `\`},
+		"align":      {Lines: 2, Align: 512, Continuation: `\`},
+		"max bytes":  {Lines: 2, MaxBytes: 100, Continuation: `\`},
+		"exact":      {Lines: 2, ExactBytes: 100, Continuation: `\`},
+		"interleave": {Lines: 2, Mode: "digits:5+upper:5", Continuation: `\`},
+		"checksum":   {Lines: 2, Width: 10, LineChecksum: true, Continuation: `\`},
+	} {
+		if _, _, err := GenerateTo(context.Background(), &bytes.Buffer{}, opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
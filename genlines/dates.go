@@ -0,0 +1,114 @@
+package genlines
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Defaults for the parts of a dates modeArg that are left empty.
+const (
+	DefaultDateLayout = time.RFC3339
+	DefaultDateStep   = time.Second
+)
+
+// DefaultDateStart is the first timestamp of a dates run without a start.
+var DefaultDateStart = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// dateSpec is a parsed dates modeArg.
+type dateSpec struct {
+	layout string
+	step   time.Duration
+	start  time.Time
+}
+
+// parseDateSpec parses a dates modeArg of the form layout|step|start. Every
+// part may be empty (or missing) to use its default. layout is a Go time
+// layout, step a time.ParseDuration string and start an RFC 3339 timestamp
+// or a timestamp in layout.
+func parseDateSpec(arg string) (dateSpec, error) {
+	spec := dateSpec{layout: DefaultDateLayout, step: DefaultDateStep, start: DefaultDateStart}
+	parts := strings.Split(arg, "|")
+	if len(parts) > 3 {
+		return spec, fmt.Errorf("mode=dates: modeArg %q has more than three |-separated parts (layout|step|start)", arg)
+	}
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+
+	if parts[0] != "" {
+		spec.layout = parts[0]
+	}
+	if !hasTimeElement(spec.layout) {
+		return spec, fmt.Errorf("mode=dates: layout %q contains no date or time elements (write them as in 2006-01-02T15:04:05Z07:00)", spec.layout)
+	}
+
+	if s := strings.TrimSpace(parts[1]); s != "" {
+		step, err := time.ParseDuration(s)
+		if err != nil {
+			return spec, fmt.Errorf("mode=dates: invalid step %q (expected a duration such as 1h, 15m or 500ms)", s)
+		}
+		if step == 0 {
+			return spec, errors.New("mode=dates: step must not be zero")
+		}
+		spec.step = step
+	}
+
+	if s := strings.TrimSpace(parts[2]); s != "" {
+		start, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			if start, err = time.Parse(spec.layout, s); err != nil {
+				return spec, fmt.Errorf("mode=dates: invalid start %q (expected RFC 3339 or the layout %q)", s, spec.layout)
+			}
+		}
+		spec.start = start
+	}
+
+	// A layout whose own output does not parse back is of no use to a parser test.
+	if _, err := time.Parse(spec.layout, spec.start.Format(spec.layout)); err != nil {
+		return spec, fmt.Errorf("mode=dates: layout %q cannot be parsed back: %v", spec.layout, err)
+	}
+	return spec, nil
+}
+
+// hasTimeElement reports whether layout formats at least one date or time
+// element, i.e. it is not just literal text.
+func hasTimeElement(layout string) bool {
+	a := time.Date(2001, 2, 3, 4, 5, 6, 7, time.UTC)
+	b := time.Date(2012, 11, 24, 16, 35, 47, 890123456, time.FixedZone("X", 3600))
+	return a.Format(layout) != b.Format(layout)
+}
+
+// dateGen emits one timestamp per line, advancing by a fixed step.
+type dateGen struct {
+	layout string
+	step   time.Duration
+	next   time.Time
+}
+
+func newDateGen(arg string, _ int) (Generator, error) {
+	spec, err := parseDateSpec(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &dateGen{layout: spec.layout, step: spec.step, next: spec.start}, nil
+}
+
+// NextLine returns the next timestamp, padded with spaces to width characters
+// or cut to width characters if it is longer; a layout may hold non-ASCII text.
+func (g *dateGen) NextLine(width int) string {
+	if width <= 0 {
+		return ""
+	}
+	s := g.next.Format(g.layout)
+	g.next = g.next.Add(g.step)
+	n := 0
+	for i := range s {
+		if n == width {
+			return s[:i]
+		}
+		n++
+	}
+	return s + strings.Repeat(" ", width-n)
+}
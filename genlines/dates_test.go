@@ -0,0 +1,91 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDates_ParseBackAndStep(t *testing.T) {
+	const layout = "2006-01-02T15:04:05Z"
+	var buf bytes.Buffer
+	opts := Options{Lines: 50, Width: 24, Mode: "dates", ModeArg: layout + "|1h|2020-01-01T00:00:00Z"}
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatalf("GenerateTo: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 50 {
+		t.Fatalf("got %d lines, want 50", len(lines))
+	}
+	want := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, line := range lines {
+		if len(line) != 24 {
+			t.Fatalf("line %d: width %d, want 24", i+1, len(line))
+		}
+		got, err := time.Parse(layout, strings.TrimRight(line, " "))
+		if err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if !got.Equal(want) {
+			t.Fatalf("line %d: got %s, want %s", i+1, got, want)
+		}
+		want = want.Add(time.Hour)
+	}
+}
+
+func TestDates_DefaultsAndStartInLayout(t *testing.T) {
+	g, err := NewGenerator("dates", "", 0)
+	if err != nil {
+		t.Fatalf("NewGenerator: %v", err)
+	}
+	if got := g.NextLine(20); got != "2000-01-01T00:00:00Z" {
+		t.Errorf("first default line = %q", got)
+	}
+	if got := g.NextLine(20); got != "2000-01-01T00:00:01Z" {
+		t.Errorf("second default line = %q", got)
+	}
+
+	g, err = NewGenerator("dates", "02/01/2006|-24h|05/03/2021", 0)
+	if err != nil {
+		t.Fatalf("NewGenerator: %v", err)
+	}
+	for _, want := range []string{"05/03/2021", "04/03/2021", "03/03/2021"} {
+		if got := g.NextLine(10); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	if got := g.NextLine(5); got != "02/03" {
+		t.Errorf("narrow line = %q, want it cut to the width", got)
+	}
+
+	// The width counts characters, not bytes, for a layout in any script.
+	g, err = NewGenerator("dates", "2. Jan 2006 · 15 Uhr|1h|2021-03-05T09:00:00Z", 0)
+	if err != nil {
+		t.Fatalf("NewGenerator: %v", err)
+	}
+	for _, tt := range []struct {
+		width int
+		want  string
+	}{{13, "5. Mar 2021 ·"}, {12, "5. Mar 2021 "}, {22, "5. Mar 2021 · 11 Uhr  "}} {
+		if got := g.NextLine(tt.width); got != tt.want {
+			t.Errorf("NextLine(%d) = %q, want %q", tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestDates_BadArgsFailValidation(t *testing.T) {
+	for _, arg := range []string{
+		"no elements here",
+		"2006-01-02|soon",
+		"2006-01-02|0s",
+		"2006-01-02|1h|yesterday",
+		"2006|1h|2020-01-01T00:00:00Z|extra",
+	} {
+		if _, err := PlanSize(Options{Lines: 1, Mode: "dates", ModeArg: arg}); err == nil {
+			t.Errorf("%q: expected an error", arg)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package genlines
+
+// DigitStream is an endless stream of decimal digits.
+type DigitStream interface {
+	// NextDigit returns the next digit (0..9).
+	NextDigit() int
+	// Skip discards the next n digits.
+	Skip(n int)
+}
+
+// batchDigits is implemented by streams that can fill many digits at once.
+type batchDigits interface {
+	NextDigits(dst []int)
+}
+
+// NewPiDigits returns the digits of π (3, 1, 4, 1, 5, ...), computed with a
+// spigot whose state is sized for digits digits, skipped ones included. The
+// stream is endless: reading past them recomputes a state twice the size up
+// to the current digit, so digits is only a hint that saves that work when
+// the count is known. Skip iterates the spigot, so it costs O(n·digits) like
+// reading the digits would.
+func NewPiDigits(digits int) DigitStream {
+	if digits <= 0 {
+		digits = 1
+	}
+	return newPiSpigot(digits)
+}
+
+// CyclicDigits repeats a fixed digit pattern. Skip is O(1).
+type CyclicDigits struct {
+	pattern []int
+	pos     int
+}
+
+// NewCyclicDigits returns a stream repeating pattern, which must be non-empty
+// and hold only digits 0..9.
+func NewCyclicDigits(pattern ...int) *CyclicDigits {
+	return &CyclicDigits{pattern: pattern}
+}
+
+// NextDigit returns the next digit of the pattern.
+func (c *CyclicDigits) NextDigit() int {
+	d := c.pattern[c.pos]
+	c.pos = (c.pos + 1) % len(c.pattern)
+	return d
+}
+
+// Skip discards the next n digits.
+func (c *CyclicDigits) Skip(n int) {
+	c.pos = (c.pos + n%len(c.pattern)) % len(c.pattern)
+}
+
+// nextDigits fills dst from s, in one call when s supports it.
+func nextDigits(s DigitStream, dst []int) {
+	if b, ok := s.(batchDigits); ok {
+		b.NextDigits(dst)
+		return
+	}
+	for i := range dst {
+		dst[i] = s.NextDigit()
+	}
+}
@@ -0,0 +1,103 @@
+package genlines
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPiDigits_SkipMatchesIterating(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 257, 1000} {
+		a := NewPiDigits(n + 50)
+		for i := 0; i < n; i++ {
+			a.NextDigit()
+		}
+		b := NewPiDigits(n + 50)
+		b.Skip(n)
+		for i := 0; i < 20; i++ {
+			if x, y := a.NextDigit(), b.NextDigit(); x != y {
+				t.Fatalf("skip %d, digit %d after: iterating gave %d, Skip gave %d", n, i, x, y)
+			}
+		}
+	}
+}
+
+func TestPiDigits_SkipAcrossPartialBuffer(t *testing.T) {
+	a := NewPiDigits(600)
+	want := make([]int, 500)
+	for i := range want {
+		want[i] = a.NextDigit()
+	}
+
+	b := NewPiDigits(600)
+	pos := 0
+	for _, skip := range []int{3, 1, 40, 7, 100, 2} {
+		b.Skip(skip)
+		pos += skip
+		if d := b.NextDigit(); d != want[pos] {
+			t.Fatalf("digit %d: got %d, want %d", pos, d, want[pos])
+		}
+		pos++
+	}
+}
+
+func TestPiDigits_GrowsPastItsSize(t *testing.T) {
+	sized := NewPiDigits(200)
+	want := make([]int, 200)
+	for i := range want {
+		want[i] = sized.NextDigit()
+	}
+
+	small := NewPiDigits(50)
+	for i, w := range want {
+		if d := small.NextDigit(); d != w {
+			t.Fatalf("digit %d: a spigot sized for 50 gave %d, want %d", i+1, d, w)
+		}
+	}
+
+	// Skip and NextDigits grow it too, in one step when they cross its size.
+	mixed, got := NewPiDigits(10), make([]int, 60)
+	mixed.Skip(30)
+	nextDigits(mixed, got)
+	if mixed.NextDigit() != want[90] || fmt.Sprint(got) != fmt.Sprint(want[30:90]) {
+		t.Errorf("Skip(30) and NextDigits(60) from a spigot sized for 10: %v", got)
+	}
+	if p := mixed.(*piSpigot); p.size != 180 || p.pos != 91 {
+		t.Errorf("size %d at digit %d, want 180 at 91", p.size, p.pos)
+	}
+
+	g, err := NewModeGenerator("pi", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line, digits strings.Builder
+	for range 20 {
+		line.WriteString(g.NextLine(10))
+	}
+	for _, d := range want {
+		digits.WriteByte(byte('0' + d))
+	}
+	if line.String() != digits.String() {
+		t.Errorf("mode=pi without a size:\n%s\nwant\n%s", line.String(), digits.String())
+	}
+}
+
+func TestCyclicDigits(t *testing.T) {
+	c := NewCyclicDigits(1, 2, 3)
+	c.Skip(100) // 100 % 3 == 1
+	for _, want := range []int{2, 3, 1, 2} {
+		if d := c.NextDigit(); d != want {
+			t.Fatalf("got %d, want %d", d, want)
+		}
+	}
+}
+
+func TestPiGen_PlugsInAnyDigitStream(t *testing.T) {
+	g := &piGen{
+		table: newPiTable([]byte("0123456789")),
+		open:  func(int) DigitStream { return NewCyclicDigits(2, 7, 1, 8) },
+	}
+	if line := g.NextLine(10); line != "2718271827" {
+		t.Fatalf("got %q", line)
+	}
+}
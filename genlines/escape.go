@@ -0,0 +1,145 @@
+package genlines
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// escapeNonASCII returns s with every non-ASCII rune written as a \uXXXX
+// escape, and runes outside the Basic Multilingual Plane as a UTF-16
+// surrogate pair (U+1F600 becomes \ud83d\ude00). Bytes that are not
+// valid UTF-8 become \ufffd. ASCII, backslashes included, is kept as is.
+func escapeNonASCII(s string) string {
+	i := 0
+	for i < len(s) && s[i] < utf8.RuneSelf {
+		i++
+	}
+	if i == len(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s) * 2)
+	b.WriteString(s[:i])
+	for _, r := range s[i:] {
+		switch {
+		case r < utf8.RuneSelf:
+			b.WriteByte(byte(r))
+		case r > 0xFFFF:
+			hi, lo := utf16.EncodeRune(r)
+			fmt.Fprintf(&b, `\u%04x\u%04x`, hi, lo)
+		default:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		}
+	}
+	return b.String()
+}
+
+// isASCII reports whether s is pure ASCII.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// escapedRuneLen returns the bytes r takes in the output: its UTF-8 length,
+// or the length of its escape if escape is set.
+func escapedRuneLen(r rune, escape bool) int64 {
+	switch {
+	case r < utf8.RuneSelf:
+		return 1
+	case !escape:
+		return int64(utf8.RuneLen(r))
+	case r > 0xFFFF:
+		return 12
+	default:
+		return 6
+	}
+}
+
+// columnSizer is implemented by generators whose columns are not always one
+// byte each. columnBytes returns the bytes one column takes in the output,
+// with non-ASCII escaped if escape is set, or -1 if it varies.
+type columnSizer interface {
+	columnBytes(escape bool) int64
+}
+
+// columnBytes returns the bytes one column of mode (not an interleave spec)
+// takes in the output of a run of o, or ErrSizeUnknown if it varies.
+func (o Options) columnBytes(mode, arg string) (int64, error) {
+	arg, err := o.seedArg(mode, arg, SeedLabelContent)
+	if err != nil {
+		return 0, err
+	}
+	gen, err := NewModeGenerator(mode, arg)
+	if err != nil {
+		return 0, err
+	}
+	cs, ok := gen.(columnSizer)
+	if !ok {
+		return 1, nil
+	}
+	if n := cs.columnBytes(o.EscapeNonASCII); n > 0 {
+		return n, nil
+	}
+	return 0, ErrSizeUnknown
+}
+
+// dataLineSizes returns a function giving the size in bytes of data line n
+// (one-based) of a run of o (with defaults applied), terminator included.
+func (o Options) dataLineSizes() (func(n int64) int64, error) {
+	eol := int64(len(o.EOL))
+	if canonicalMode(o.Mode) == "template" {
+		return nil, ErrSizeUnknown
+	}
+	if canonicalMode(o.Mode) == "blocks" {
+		return func(int64) int64 { return blockLineBytes(o.Width) + eol }, nil
+	}
+	if canonicalMode(o.Mode) == "binrec" {
+		size, err := BinrecSize(o.ModeArg)
+		if err != nil {
+			return nil, err
+		}
+		return func(int64) int64 { return int64(size) + eol }, nil
+	}
+	if IsInterleaveSpec(o.Mode) {
+		streams, err := ParseInterleave(o.Mode)
+		if err != nil {
+			return nil, err
+		}
+		sizes := make([]int64, len(streams))
+		for i, s := range streams {
+			f, err := o.columnBytes(s.Mode, s.ModeArg)
+			if err != nil {
+				return nil, err
+			}
+			sizes[i] = int64(s.Width)*f + eol
+		}
+		return func(n int64) int64 { return sizes[(n-1)%int64(len(sizes))] }, nil
+	}
+
+	f, err := o.columnBytes(o.Mode, o.ModeArg)
+	if err != nil {
+		return nil, err
+	}
+	// A checksum suffix is ASCII: only the content columns expand.
+	fixed := int64(0)
+	if o.LineChecksum {
+		fixed = ChecksumWidth + 1
+	}
+	size := func(n int64) int64 {
+		width, fixed := int64(o.Width), fixed
+		if o.Ramp.Enabled() {
+			width = int64(o.Ramp.Width(n))
+		}
+		if n < int64(o.Lines) {
+			fixed += int64(len(o.Continuation))
+		}
+		return (width-fixed)*f + fixed + eol
+	}
+	return size, nil
+}
@@ -0,0 +1,131 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEscapeNonASCII(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{`plain \ ascii`, `plain \ ascii`},
+		{"é", `\u00e9`},
+		{"a日b", `a\u65e5b`},
+		{"\U0001F600", `\ud83d\ude00`},
+		{"x\xffy", `x\ufffdy`},
+	}
+	for _, tt := range tests {
+		if got := escapeNonASCII(tt.in); got != tt.want {
+			t.Errorf("escapeNonASCII(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateTo_EscapeNonASCIIRoundTrips(t *testing.T) {
+	cases := []Options{
+		{Lines: 4, Width: 7, Mode: "char", ModeArg: "é"},
+		{Lines: 4, Width: 7, Mode: "char", ModeArg: "\U0001F600"},
+		{Lines: 4, Width: 20, Mode: "char", ModeArg: "日", LineChecksum: true},
+		{Lines: 5, Mode: "char:3:é+digits:4+char:2:\U0001F600"},
+		{Lines: 6, Mode: "char", ModeArg: "ß", Ramp: Ramp{Min: 1, Max: 4, Step: 1}, CommentEvery: 2},
+	}
+	for _, opts := range cases {
+		var plain, escaped bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &plain, opts); err != nil {
+			t.Fatalf("%s: %v", opts.Mode, err)
+		}
+		esc := opts
+		esc.EscapeNonASCII = true
+		_, n, err := GenerateTo(context.Background(), &escaped, esc)
+		if err != nil {
+			t.Fatalf("%s escaped: %v", opts.Mode, err)
+		}
+		for i, b := range escaped.Bytes() {
+			if b >= 0x80 {
+				t.Fatalf("%s: byte %d is not ASCII: %q", opts.Mode, i, escaped.String())
+			}
+		}
+		if planned, err := PlanSize(esc); err != nil || planned != n {
+			t.Errorf("%s: PlanSize = %d, %v; wrote %d", opts.Mode, planned, err, n)
+		}
+
+		want := strings.Split(plain.String(), "\n")
+		got := strings.Split(escaped.String(), "\n")
+		if len(got) != len(want) {
+			t.Fatalf("%s: %d lines escaped, %d plain", opts.Mode, len(got), len(want))
+		}
+		for i, line := range got {
+			var back string
+			if err := json.Unmarshal([]byte(`"`+line+`"`), &back); err != nil {
+				t.Fatalf("%s line %d: %v", opts.Mode, i+1, err)
+			}
+			if opts.LineChecksum && line != "" {
+				// The checksum covers the escaped text.
+				if line != AppendChecksum(line[:len(line)-ChecksumWidth]) {
+					t.Errorf("%s line %d: checksum does not match the escaped text: %q", opts.Mode, i+1, line)
+				}
+				back, want[i] = back[:len(back)-ChecksumWidth], want[i][:len(want[i])-ChecksumWidth]
+			}
+			if back != want[i] {
+				t.Errorf("%s line %d: unescaped %q, want %q", opts.Mode, i+1, back, want[i])
+			}
+		}
+	}
+}
+
+func TestGenerateTo_EscapeNonASCIISizeLimits(t *testing.T) {
+	base := Options{Width: 5, Mode: "char", ModeArg: "\U0001F600", EscapeNonASCII: true}
+	line := int64(5*12 + 1)
+
+	exact := base
+	exact.ExactBytes = 3*line + 10
+	var buf bytes.Buffer
+	lines, n, err := GenerateTo(context.Background(), &buf, exact)
+	if err != nil || lines != 3 || n != exact.ExactBytes {
+		t.Errorf("ExactBytes: lines=%d bytes=%d err=%v", lines, n, err)
+	}
+
+	capped := base
+	capped.Lines = 10
+	capped.MaxBytes = 4*line + 5
+	buf.Reset()
+	lines, n, err = GenerateTo(context.Background(), &buf, capped)
+	if err != nil || lines != 4 || n != 4*line {
+		t.Errorf("MaxBytes: lines=%d bytes=%d err=%v", lines, n, err)
+	}
+
+	aligned := base
+	aligned.Lines = 3
+	aligned.Align = 100
+	pads, size, err := PlanAlign(aligned)
+	buf.Reset()
+	_, n, gerr := GenerateTo(context.Background(), &buf, aligned)
+	if err != nil || gerr != nil || pads != 2 || size != n {
+		t.Errorf("Align: pads=%d planned=%d wrote=%d err=%v/%v", pads, size, n, err, gerr)
+	}
+	aligned.Align = 60
+	if _, _, err := PlanAlign(aligned); err == nil {
+		t.Error("a 61-byte escaped line should not fit an alignment of 60")
+	}
+}
+
+func TestPlanSize_EscapeNonASCIIWords(t *testing.T) {
+	opts := Options{Lines: 3, Width: 12, Mode: "words", ModeArg: "ça,über,日本", EscapeNonASCII: true}
+	if _, err := PlanSize(opts); !errors.Is(err, ErrSizeUnknown) {
+		t.Errorf("non-ASCII words: err = %v, want ErrSizeUnknown", err)
+	}
+	opts.ModeArg = "alpha,beta"
+	if size, err := PlanSize(opts); err != nil || size != 39 {
+		t.Errorf("ASCII words: PlanSize = %d, %v; want 39", size, err)
+	}
+}
+
+func TestNewSeekable_RejectsEscapeNonASCII(t *testing.T) {
+	if _, err := NewSeekable(Options{Lines: 1, Mode: "char", ModeArg: "é", EscapeNonASCII: true}); err == nil {
+		t.Error("NewSeekable should reject EscapeNonASCII")
+	}
+}
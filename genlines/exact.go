@@ -0,0 +1,76 @@
+package genlines
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// PlanExactBytes splits opts.ExactBytes into the number of complete lines
+// that fit and the size of the trailing partial line. Terminators count
+// toward the budget, so a CRLF EOL makes every line two bytes longer than
+// its width.
+func PlanExactBytes(opts Options) (lines, tail int64, err error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return 0, 0, err
+	}
+	sizeOf, err := opts.dataLineSizes()
+	if err != nil {
+		return 0, 0, err
+	}
+	sizes := []int64{sizeOf(1)}
+	if IsInterleaveSpec(opts.Mode) {
+		streams, err := ParseInterleave(opts.Mode)
+		if err != nil {
+			return 0, 0, err
+		}
+		sizes = sizes[:0]
+		for i := range streams {
+			sizes = append(sizes, sizeOf(int64(i)+1))
+		}
+	}
+
+	var cycle int64
+	for _, s := range sizes {
+		cycle += s
+	}
+	rem := opts.ExactBytes
+	lines = rem / cycle * int64(len(sizes))
+	rem %= cycle
+	for _, s := range sizes {
+		if rem < s {
+			break
+		}
+		rem -= s
+		lines++
+	}
+	return lines, rem, nil
+}
+
+// writeTail writes the first n bytes of line followed by eol to w. n is
+// always less than a full line, so the terminator is cut off (for a CRLF EOL
+// and n == len(line)+1, only its CR remains). If n ends inside a multibyte
+// character of a UTF-8 line, the bytes of that character are written as
+// spaces instead, so the output stays valid UTF-8 at exactly n bytes.
+func writeTail(w io.Writer, line string, eol []byte, n int64) (int64, error) {
+	full := append([]byte(line), eol...)
+	if n > int64(len(full)) {
+		return 0, fmt.Errorf("partial line of %d bytes exceeds line size %d", n, len(full))
+	}
+	tail := full[:n]
+	if utf8.ValidString(line) {
+		for i := len(tail) - 1; i >= 0 && i >= len(tail)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(tail[i]) {
+				if !utf8.FullRune(tail[i:]) {
+					for j := i; j < len(tail); j++ {
+						tail[j] = ' '
+					}
+				}
+				break
+			}
+		}
+	}
+	written, err := w.Write(tail)
+	return int64(written), err
+}
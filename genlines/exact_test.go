@@ -0,0 +1,83 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestPlanExactBytes(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		lines, tail int64
+	}{
+		{"even", Options{Width: 80, ExactBytes: 810}, 10, 0},
+		{"remainder", Options{Width: 80, ExactBytes: 1_048_576}, 12945, 31},
+		{"below width", Options{Width: 80, ExactBytes: 7}, 0, 7},
+		{"crlf", Options{Width: 80, EOL: []byte("\r\n"), ExactBytes: 1_048_576}, 12787, 42},
+		{"interleave", Options{Mode: "digits:3+ascii:5", ExactBytes: 4 + 6 + 4 + 2}, 3, 2},
+	}
+	for _, tt := range tests {
+		lines, tail, err := PlanExactBytes(tt.opts)
+		if err != nil || lines != tt.lines || tail != tt.tail {
+			t.Errorf("%s: PlanExactBytes = %d, %d, %v; want %d, %d", tt.name, lines, tail, err, tt.lines, tt.tail)
+		}
+	}
+}
+
+func TestGenerateTo_ExactBytes(t *testing.T) {
+	for _, n := range []int64{1, 7, 80, 81, 82, 1000, 4097} {
+		for _, eol := range []string{"\n", "\r\n"} {
+			var buf bytes.Buffer
+			opts := Options{Width: 80, Mode: "digits", EOL: []byte(eol), ExactBytes: n}
+			lines, written, err := GenerateTo(context.Background(), &buf, opts)
+			if err != nil {
+				t.Fatalf("n=%d: GenerateTo: %v", n, err)
+			}
+			if written != n || int64(buf.Len()) != n {
+				t.Errorf("n=%d eol=%q: wrote %d bytes (reported %d)", n, eol, buf.Len(), written)
+			}
+			if got := int64(strings.Count(buf.String(), eol)); got != lines {
+				t.Errorf("n=%d eol=%q: %d terminators, reported %d lines", n, eol, got, lines)
+			}
+
+			// The output is a prefix of the unbounded stream.
+			var full bytes.Buffer
+			GenerateTo(context.Background(), &full, Options{Lines: int(lines) + 1, Width: 80, Mode: "digits", EOL: []byte(eol)})
+			if !bytes.HasPrefix(full.Bytes(), buf.Bytes()) {
+				t.Errorf("n=%d eol=%q: output is not a prefix of the regular run", n, eol)
+			}
+		}
+	}
+}
+
+func TestGenerateTo_ExactBytesTailStaysUTF8(t *testing.T) {
+	// Lines of "ééé\n" are 7 bytes; the tails cut after 1 and 3 bytes of
+	// the third line fall inside an é.
+	for n, want := range map[int64]string{
+		14: "ééé\nééé\n",
+		15: "ééé\nééé\n ",
+		16: "ééé\nééé\né",
+		17: "ééé\nééé\né ",
+		20: "ééé\nééé\nééé",
+	} {
+		var buf bytes.Buffer
+		opts := Options{Width: 3, Mode: "char", ModeArg: "é", ExactBytes: n}
+		if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != want || !utf8.ValidString(got) {
+			t.Errorf("ExactBytes %d: %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestGenerateTo_ExactBytesRejectsComments(t *testing.T) {
+	_, _, err := GenerateTo(context.Background(), &bytes.Buffer{}, Options{ExactBytes: 100, CommentEvery: 2})
+	if err == nil {
+		t.Fatal("ExactBytes with CommentEvery should fail")
+	}
+}
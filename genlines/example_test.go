@@ -0,0 +1,57 @@
+package genlines_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// zebra draws stripes of '#' and '.' that are stripe columns wide.
+type zebra struct{ stripe int }
+
+func (z *zebra) NextLine(width int) string {
+	var b strings.Builder
+	for i := 0; i < width; i++ {
+		if i/z.stripe%2 == 0 {
+			b.WriteByte('#')
+		} else {
+			b.WriteByte('.')
+		}
+	}
+	return b.String()
+}
+
+func ExampleRegisterMode() {
+	defer genlines.ResetModes()
+
+	err := genlines.RegisterMode("zebra", genlines.ModeSpec{
+		Aliases:     []string{"stripes"},
+		Description: "Stripes of # and . (modeArg: stripe width, default 2)",
+		Factory: func(arg string, _ int) (genlines.Generator, error) {
+			if arg == "" {
+				return &zebra{stripe: 2}, nil
+			}
+			n, err := strconv.Atoi(arg)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("mode=zebra: invalid stripe width %q", arg)
+			}
+			return &zebra{stripe: n}, nil
+		},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	opts := genlines.Options{Lines: 2, Width: 12, Mode: "stripes", ModeArg: "3"}
+	if _, _, err := genlines.GenerateTo(context.Background(), os.Stdout, opts); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// ###...###...
+	// ###...###...
+}
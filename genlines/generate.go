@@ -0,0 +1,765 @@
+package genlines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// DefaultWidth is the line width used when Options.Width is not set.
+	DefaultWidth = 80
+
+	// MaxWidth is the widest line a run may have (2^31-1 columns). Lines
+	// wider than wideLineChunk are written in chunks when the generator is a
+	// LineAppender and nothing needs the whole line; otherwise each is held
+	// in memory, at its width times the bytes per column.
+	MaxWidth = 1<<31 - 1
+
+	// DefaultMode is the content mode used when Options.Mode is not set.
+	DefaultMode = "ascii"
+
+	// DefaultProgressEvery is the progress cadence used when Options.ProgressEvery is not set.
+	DefaultProgressEvery = 10000
+
+	// DefaultCommentText is the comment line template used when Options.CommentText is not set.
+	DefaultCommentText = "# checkpoint %d"
+
+	// bufferSize is the size of the write buffer GenerateTo puts in front of w.
+	bufferSize = 64 * 1024
+
+	// wideLineChunk is the most content columns of a wide line generated at
+	// a time.
+	wideLineChunk = 1 << 20
+)
+
+// Options configures a GenerateTo run.
+type Options struct {
+	Lines   int    // Number of lines to generate (>= 0)
+	Width   int    // Line width in columns. Default: DefaultWidth
+	Mode    string // Content mode or interleave spec (see ParseInterleave). Default: DefaultMode
+	ModeArg string // Additional argument for Mode
+
+	// Ramp, when enabled, overrides Width with growing line widths (see
+	// Ramp). Not supported with interleave specs or ExactBytes.
+	Ramp Ramp
+
+	// EOL is the terminator written after every line, e.g. "\r\n" or "\x00"
+	// for NUL-delimited records. Default: "\n".
+	EOL []byte
+
+	// LinePattern, when set, makes some data lines blank (see LinePattern).
+	// Blank lines count toward Lines; everything that applies to a line,
+	// from the checksum and the sentinels to trailing whitespace, applies
+	// to the content lines only, as if they were a run of their own, and
+	// OnLine is not called for blank lines. Not supported with ExactBytes,
+	// Sort, Continuation, mode=binrec or NewSeekable.
+	LinePattern LinePattern
+
+	// CommentEvery, when > 0, inserts a comment line after every CommentEvery
+	// data lines. Comment lines do not count toward Lines.
+	CommentEvery int
+	// CommentText is the comment line template; %d expands to the number of
+	// data lines written so far. Default: DefaultCommentText.
+	CommentText string
+
+	// LineChecksum, when set, ends every data line with a space and the CRC32
+	// (8 hex digits) of the characters before it, within Width. Width must be
+	// at least MinChecksumLineWidth. Not supported with interleave specs.
+	LineChecksum bool
+
+	// Continuation, when set, is a marker appended to every data line but
+	// the last, after the checksum and before the terminator, as legacy
+	// formats mark continued lines (e.g. DefaultContinuation). It counts
+	// toward Width, so every line stays exactly Width columns. Not supported
+	// with comment lines, Align, MaxBytes, ExactBytes, interleave specs or
+	// mode=blocks.
+	Continuation string
+
+	// ExactBytes, when > 0, makes the output exactly this many bytes: complete
+	// lines while they fit, then a prefix of the next line without its
+	// terminator. Lines is ignored. Not supported with comment lines.
+	ExactBytes int64
+
+	// Align, when > 0, keeps every line within one Align-byte block: a line
+	// that would cross a multiple of Align is preceded by a padding line of
+	// AlignFill characters ending exactly at the boundary. Every line must
+	// fit in Align bytes. Not supported with ExactBytes or mode=template.
+	Align int64
+	// AlignFill is the padding character. Default: DefaultAlignFill.
+	AlignFill byte
+
+	// MaxBytes, when > 0, caps the output size: the run stops before the
+	// first line (data, comment or padding) that would take it past MaxBytes
+	// bytes, so it ends at whichever of Lines and MaxBytes is reached first.
+	// Not supported with ExactBytes.
+	MaxBytes int64
+
+	// EscapeNonASCII, when set, writes every non-ASCII rune of the generated
+	// content as a \uXXXX escape (a surrogate pair of them above U+FFFF), so
+	// the output is pure ASCII. Width counts runes before escaping: each
+	// escaped rune takes 6 bytes, or 12 as a pair. Not supported with
+	// NewSeekable.
+	EscapeNonASCII bool
+
+	// Rot, when between 1 and 25, shifts every ASCII letter of the generated
+	// content Rot places through the alphabet (a Caesar cipher; DefaultRot
+	// is ROT13), so runs with and without it give plaintext/ciphertext
+	// pairs. Other bytes are kept, and comment lines and continuation
+	// markers are not shifted; a line checksum covers the shifted content.
+	// Not supported with mode=blocks, mode=binrec or NewSeekable.
+	Rot int
+
+	// TrailingWS, when enabled, ends a share of the data lines with 1 to
+	// MaxTrailingWS spaces and tabs after everything else on the line,
+	// checksum included, and before the terminator. The whitespace is not
+	// covered by the line checksum and does not count toward Width; Stats
+	// reports how many lines got it. Not supported with ExactBytes,
+	// Continuation, mode=binrec or NewSeekable.
+	TrailingWS TrailingWS
+
+	// InjectUnicode, when enabled, inserts an invisible code point (a
+	// zero-width character, BOM or bidi control) into the content of every
+	// InjectUnicode.Every-th data line, after Rot and before EscapeNonASCII
+	// and the line checksum, which covers it. Injected code points do not
+	// count toward Width; Stats reports how many of each were written. Not
+	// supported with ExactBytes, mode=blocks, mode=binrec or NewSeekable.
+	InjectUnicode InjectUnicode
+
+	// StartOffset, when > 0, starts the content as if StartOffset characters
+	// had already been generated, so a run can continue a conceptually larger
+	// one: a run of N lines of width W followed by one with StartOffset N×W
+	// gives the bytes of a single run of both. Only content characters count
+	// (not terminators, checksums or markers). The mode must be seekable
+	// (see SeekableGenerator); others return ErrNotSeekable.
+	StartOffset int64
+
+	// ByteRange, when enabled, limits the content to a range of byte values:
+	// palette modes keep the characters inside it, modes with a fixed
+	// alphabet must fit in it, and so must checksums, comments, markers and
+	// every other part of a line. Line terminators are not limited. Not
+	// supported with mode=blocks or mode=binrec.
+	ByteRange ByteRange
+
+	// SafeStart makes the output start neutrally, with two ASCII letters or
+	// digits that match no file type signature (see Signatures), so tools
+	// that sniff file types take it for text. A seekable mode starts its
+	// content up to a few thousand characters later instead (see
+	// SafeStartShift); otherwise the first characters of the first line are
+	// replaced by letters, keeping its size in bytes. Both happen after Rot
+	// and before InjectUnicode. Not supported with mode=blocks, mode=binrec
+	// or NewSeekable.
+	SafeStart bool
+
+	// FirstLine and LastLine, when set, replace the content of the first and
+	// the last data line, e.g. with sentinels a truncation check looks for:
+	// the text is padded with spaces or truncated to the content width, and
+	// ConfigPlaceholder in it expands to ConfigChecksum. The replaced content
+	// is still generated, so every other line is as in a run without them.
+	// Checksums, markers and trailing whitespace apply as to other lines;
+	// Rot does not. The text must be printable ASCII. Not supported with
+	// ExactBytes, Align, Sort, mode=blocks or mode=binrec, or FirstLine with
+	// SafeStart.
+	FirstLine string
+	LastLine  string
+
+	// HeaderLine, when set, is written as a line of its own before the data
+	// lines, e.g. a comment describing the run. It is not one of the Lines,
+	// is not numbered, and gets no checksum or other decoration; alignment
+	// padding and MaxBytes apply to it as to a comment line. The text must be
+	// printable ASCII. Not supported with ExactBytes, mode=binrec, an empty
+	// EOL or NewSeekable.
+	HeaderLine string
+
+	// Sort, when SortAsc or SortDesc, generates every data line before
+	// writing any and writes them in byte-wise order (not locale collation),
+	// e.g. for golden files of sorted input. Lines are compared as written,
+	// checksum and trailing whitespace included; comment and padding lines
+	// keep their places. The lines are held in memory: a run estimated (see
+	// SortMemory) to take more than SortMaxMemory fails with ErrSortMemory.
+	// Not supported with ExactBytes, MaxBytes, Align, Ramp, Continuation,
+	// SafeStart, mode=blocks, mode=binrec or NewSeekable.
+	Sort SortOrder
+	// SortMaxMemory caps the memory Sort may take. Default:
+	// DefaultSortMaxMemory.
+	SortMaxMemory int64
+
+	// Unsafe lets modes write content that consumers may treat as active:
+	// every other csv field then starts with a spreadsheet formula character
+	// (=, +, - or @), to test CSV injection defenses. Without it csv fields
+	// never do.
+	Unsafe bool
+
+	// Seed, when HasSeed is set, is the global seed: every feature with
+	// randomness that is not given a seed of its own derives one from it with
+	// DeriveSeed, so one value reproduces the whole run.
+	Seed    uint64
+	HasSeed bool
+
+	// PiDigits, when set, is the digit stream the pi mode reads instead of
+	// computing its own, e.g. PiCalibration.Stream. It serves a single run.
+	PiDigits DigitStream
+
+	// Retry configures retrying of transient write errors. Default: none.
+	Retry Retry
+
+	// FlushEvery, when > 0, pushes the buffered output to the writer after
+	// every FlushEvery data lines (and the comment line after them, if any),
+	// e.g. 1 for a file watched with tail -f. By default the output goes out
+	// in 64 KiB chunks and once more at the end. A failed flush fails the
+	// run like a failed write.
+	FlushEvery int
+
+	// Stats, when set, is filled in with the Stats of the run when it
+	// returns, also on failure. Checksums names the digests of the output
+	// to include (see ChecksumAlgorithms); they cost a pass over every byte,
+	// so none are computed by default.
+	Stats     *Stats
+	Checksums []string
+
+	// OnLine, when set, is called with the LineInfo of every complete data
+	// line once it is queued for the writer, e.g. to write an index of the
+	// output alongside it. A partial line of ExactBytes is not reported.
+	OnLine func(LineInfo)
+
+	// Progress, when set, is called with the number of lines generated so far
+	// every ProgressEvery lines and once more after the final line.
+	Progress      func(linesWritten int64)
+	ProgressEvery int64
+}
+
+// withDefaults returns a copy of o with unset fields replaced by their defaults.
+func (o Options) withDefaults() Options {
+	if o.Width <= 0 {
+		o.Width = DefaultWidth
+	}
+	if o.Mode == "" {
+		o.Mode = DefaultMode
+	}
+	if o.EOL == nil {
+		o.EOL = []byte("\n")
+		if canonicalMode(o.Mode) == "binrec" {
+			o.EOL = []byte{} // records follow each other directly
+		}
+	}
+	if o.CommentText == "" {
+		o.CommentText = DefaultCommentText
+	}
+	if o.AlignFill == 0 {
+		o.AlignFill = DefaultAlignFill
+	}
+	if o.ProgressEvery <= 0 {
+		o.ProgressEvery = DefaultProgressEvery
+	}
+	if o.SortMaxMemory <= 0 {
+		o.SortMaxMemory = DefaultSortMaxMemory
+	}
+	return o
+}
+
+// GenerateTo streams opts.Lines lines of generated content into w.
+// With opts.Lines == 0 the options are still validated but nothing is written.
+//
+// Output is buffered internally and flushed before returning. The context is
+// checked between lines; on cancellation the buffered lines are flushed and
+// ctx.Err() is returned. The returned counts always describe what actually
+// reached w, including on error, so callers can resume a partial run.
+func GenerateTo(ctx context.Context, w io.Writer, opts Options) (lines, bytes int64, err error) {
+	opts = opts.withDefaults()
+	rec := newStatsRecorder(opts)
+	defer func() { rec.finish(lines, bytes) }()
+	w = rec.wrap(w)
+
+	if err := opts.validate(); err != nil {
+		return 0, 0, err
+	}
+	every := opts.ProgressEvery
+
+	count, tail := int64(opts.Lines), int64(0)
+	if opts.ExactBytes > 0 {
+		if count, tail, err = PlanExactBytes(opts); err != nil {
+			return 0, 0, err
+		}
+		opts.Lines = int(count) + 1 // size the generator for the partial line too
+	}
+
+	gen, err := buildGenerator(opts)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var progress func(int64)
+	if opts.Progress != nil {
+		progress = func(n int64) {
+			if n%every == 0 {
+				opts.Progress(n)
+			}
+		}
+	}
+
+	lay := opts.layout()
+	lay.flushes = rec.flushCounter()
+	if opts.Sort != SortNone {
+		if err := lay.sortLines(gen, count, opts.Sort); err != nil {
+			return 0, 0, err
+		}
+	}
+	lines, bytes, err = writeLines(ctx, w, gen, lay, 0, count, progress)
+	if err == nil && tail > 0 {
+		n, terr := writeTail(w, lay.nextLine(gen, count+1), lay.eol, tail)
+		bytes += n
+		if terr != nil {
+			return lines, bytes, fmt.Errorf("writing partial line %d: %w", count+1, terr)
+		}
+	}
+	if err == nil && opts.Progress != nil && lines%every != 0 {
+		opts.Progress(lines)
+	}
+	return lines, bytes, err
+}
+
+// validate checks opts (with defaults applied) for settings that cannot be
+// generated, before any output is written.
+func (o Options) validate() error {
+	if o.Lines < 0 {
+		return fmt.Errorf("invalid number of lines: %d", o.Lines)
+	}
+	if w := o.widest(); w > MaxWidth {
+		return fmt.Errorf("a width of %d is over the maximum of %d", w, MaxWidth)
+	}
+	if err := o.validateChecksums(); err != nil {
+		return err
+	}
+	if o.CommentEvery > 0 {
+		if err := ValidateCommentText(o.CommentText); err != nil {
+			return err
+		}
+	}
+	if o.ExactBytes < 0 {
+		return fmt.Errorf("invalid exact byte count: %d", o.ExactBytes)
+	}
+	if o.MaxBytes < 0 {
+		return fmt.Errorf("invalid byte ceiling: %d", o.MaxBytes)
+	}
+	if o.StartOffset < 0 {
+		return fmt.Errorf("invalid start offset: %d", o.StartOffset)
+	}
+	if o.ExactBytes > 0 && o.MaxBytes > 0 {
+		return errors.New("an exact byte size cannot be combined with a byte ceiling")
+	}
+	if o.ExactBytes > 0 && o.CommentEvery > 0 {
+		return errors.New("an exact byte size cannot be combined with comment lines")
+	}
+	if o.Ramp.Enabled() {
+		if err := o.Ramp.validate(); err != nil {
+			return err
+		}
+		if IsInterleaveSpec(o.Mode) || o.ExactBytes > 0 {
+			return errRampUnsupported
+		}
+	}
+	if canonicalMode(o.Mode) == "blocks" && (o.Ramp.Enabled() || o.ExactBytes > 0 || o.LineChecksum) {
+		return errBlocksUnsupported
+	}
+	if canonicalMode(o.Mode) == "binrec" && (len(o.EOL) > 0 || o.Ramp.Enabled() || o.ExactBytes > 0 ||
+		o.CommentEvery > 0 || o.LineChecksum || o.Align > 0 || o.EscapeNonASCII) {
+		return errBinrecUnsupported
+	}
+	if canonicalMode(o.Mode) == "template" && o.ExactBytes > 0 {
+		return errors.New("mode=template cannot be combined with an exact byte size")
+	}
+	if err := o.validateAlign(); err != nil {
+		return err
+	}
+	if err := o.validateContinuation(); err != nil {
+		return err
+	}
+	if err := o.validateRot(); err != nil {
+		return err
+	}
+	if err := o.validateTrailingWS(); err != nil {
+		return err
+	}
+	if err := o.validateInjectUnicode(); err != nil {
+		return err
+	}
+	if err := o.validateSafeStart(); err != nil {
+		return err
+	}
+	if err := o.validateByteRange(); err != nil {
+		return err
+	}
+	if err := o.validateSort(); err != nil {
+		return err
+	}
+	if err := o.validateSentinels(); err != nil {
+		return err
+	}
+	if err := o.validateLinePattern(); err != nil {
+		return err
+	}
+	if err := o.validateHeader(); err != nil {
+		return err
+	}
+	if o.LineChecksum {
+		if IsInterleaveSpec(o.Mode) {
+			return errors.New("line checksums are not supported with interleave specs")
+		}
+		if w := o.narrowest() - len(o.Continuation); w < MinChecksumLineWidth {
+			return fmt.Errorf("line checksums need a width of at least %d, got %d", MinChecksumLineWidth+len(o.Continuation), o.narrowest())
+		}
+	}
+	// Generators defer their expensive setup to the first line, so building
+	// one here checks the mode and modeArg before any output exists.
+	gen, err := buildGenerator(o)
+	if err != nil {
+		return err
+	}
+	if wc, ok := gen.(widthChecker); ok {
+		return wc.checkWidth(o.narrowest() - len(o.Continuation))
+	}
+	return nil
+}
+
+// narrowest returns the smallest line width of a run (with defaults applied).
+func (o Options) narrowest() int {
+	if o.Ramp.Enabled() {
+		return o.Ramp.Min
+	}
+	return o.Width
+}
+
+// widest returns the largest line width of a run (with defaults applied).
+func (o Options) widest() int {
+	if o.Ramp.Enabled() {
+		return o.Ramp.Max
+	}
+	return o.Width
+}
+
+// buildGenerator constructs the generator for a run described by opts
+// (with defaults applied), including interleave specs.
+func buildGenerator(opts Options) (Generator, error) {
+	if IsInterleaveSpec(opts.Mode) {
+		if opts.ModeArg != "" {
+			return nil, fmt.Errorf("interleave spec %q takes no modeArg; use mode:width:arg per stream", opts.Mode)
+		}
+		if opts.StartOffset > 0 {
+			return nil, fmt.Errorf("a start offset needs a seekable mode, not an interleave spec: %w", ErrNotSeekable)
+		}
+		g, err := newInterleaveGen(opts.Mode, opts.Lines, opts.seedArg)
+		if err == nil && opts.Unsafe {
+			for _, sg := range g.gens {
+				allowUnsafe(sg)
+			}
+		}
+		if err == nil {
+			err = opts.limitBytes(g)
+		}
+		return g, err
+	}
+	arg, err := opts.seedArg(opts.Mode, opts.ModeArg, SeedLabelContent)
+	if err != nil {
+		return nil, err
+	}
+	gen, err := newGenerator(opts.Mode, arg, opts.Lines*opts.widest())
+	if pg, ok := gen.(*piGen); ok && opts.PiDigits != nil {
+		pg.open = func(int) DigitStream { return opts.PiDigits }
+	}
+	if tg, ok := gen.(*templateGen); ok {
+		tg.total = opts.Lines
+	}
+	if opts.Unsafe {
+		allowUnsafe(gen)
+	}
+	if err == nil {
+		err = opts.limitBytes(gen)
+	}
+	if err == nil && opts.StartOffset > 0 {
+		sg, ok := gen.(SeekableGenerator)
+		if !ok {
+			return nil, fmt.Errorf("a start offset needs a seekable mode: mode=%s: %w", opts.Mode, ErrNotSeekable)
+		}
+		sg.SeekChar(opts.StartOffset)
+	}
+	if err == nil && opts.SafeStart {
+		if _, shifted := opts.safeStartShift(gen); !shifted {
+			err = opts.ByteRange.fitSafeStart()
+		}
+	}
+	return gen, err
+}
+
+// allowUnsafe lets gen write content it avoids by default (see Options.Unsafe).
+func allowUnsafe(gen Generator) {
+	if u, ok := gen.(interface{ allowUnsafe() }); ok {
+		u.allowUnsafe()
+	}
+}
+
+// seedArg returns the modeArg of the feature labeled label, with its seed
+// derived from the global seed if the run has one.
+func (o Options) seedArg(mode, arg, label string) (string, error) {
+	if !o.HasSeed {
+		return arg, nil
+	}
+	return SeedModeArg(mode, arg, o.Seed, label)
+}
+
+// layout describes how generated content is framed into output lines.
+type layout struct {
+	width        int
+	eol          []byte
+	commentEvery int64
+	commentText  string
+	checksum     bool
+	ramp         Ramp
+	align        int64 // block size no line may straddle; 0 = none
+	alignFill    byte
+	maxBytes     int64 // ceiling on the bytes written; 0 = none
+	escape       bool  // write non-ASCII runes as \u escapes
+	rot          int   // letter rotation of the content; 0 = none
+	trailing     TrailingWS
+	inject       InjectUnicode
+	safeStart    bool     // start the first line neutrally
+	firstLine    string   // content of line 1 instead of the generated one
+	lastLine     string   // content of the last line instead of the generated one
+	header       string   // line written before line 1
+	sorted       []string // data lines to write instead of generating them (Sort)
+	continuation string
+	pattern      LinePattern
+	lines        int64 // content lines of the run; the last has no continuation
+	retry        Retry // how write errors are retried
+	flushEvery   int64
+	flushes      *int64 // counts the flushes, if set
+	onLine       func(LineInfo)
+}
+
+func (o Options) layout() layout {
+	return layout{
+		width:        o.Width,
+		eol:          o.EOL,
+		commentEvery: int64(o.CommentEvery),
+		commentText:  o.CommentText,
+		checksum:     o.LineChecksum,
+		ramp:         o.Ramp,
+		align:        o.Align,
+		alignFill:    o.AlignFill,
+		maxBytes:     o.MaxBytes,
+		escape:       o.EscapeNonASCII,
+		rot:          o.Rot,
+		trailing:     o.TrailingWS.resolve(o),
+		inject:       o.InjectUnicode.resolve(o),
+		safeStart:    o.SafeStart,
+		firstLine:    o.expandSentinel(o.FirstLine),
+		lastLine:     o.expandSentinel(o.LastLine),
+		header:       o.HeaderLine,
+		continuation: o.Continuation,
+		pattern:      o.LinePattern,
+		lines:        o.LinePattern.content(int64(o.Lines)),
+		retry:        o.Retry,
+		flushEvery:   int64(o.FlushEvery),
+		onLine:       o.OnLine,
+	}
+}
+
+// contentWidth returns the width of the generated content of data line n
+// (one-based): its width without the checksum and continuation marker.
+func (l layout) contentWidth(n int64) int {
+	width := l.lineWidth(n)
+	if l.checksum {
+		width -= ChecksumWidth + 1
+	}
+	if n < l.lines {
+		width -= len(l.continuation)
+	}
+	return width
+}
+
+// nextLine returns data line n (one-based) from gen, rotated, started
+// neutrally or replaced by its sentinel, with its injected code point and
+// escaped, with its checksum, continuation marker and trailing whitespace if
+// enabled, in that order.
+func (l layout) nextLine(gen Generator, n int64) string {
+	if l.sorted != nil {
+		return l.sorted[n-1]
+	}
+	line := Rotate(gen.NextLine(l.contentWidth(n)), l.rot)
+	if l.safeStart && n == 1 {
+		line = neutralStart(line, l.escape)
+	}
+	if s, ok := l.sentinel(n); ok {
+		line = fitSentinel(s, l.contentWidth(n))
+	}
+	line = l.inject.apply(line, n)
+	if l.escape {
+		line = escapeNonASCII(line)
+	}
+	if l.checksum {
+		line = AppendChecksum(line + " ")
+	}
+	return line + l.suffix(n)
+}
+
+// suffix returns what follows the content and checksum of data line n: its
+// continuation marker and trailing whitespace.
+func (l layout) suffix(n int64) string {
+	marker := ""
+	if n < l.lines {
+		marker = l.continuation
+	}
+	return marker + l.trailing.suffix(n)
+}
+
+// wideLine reports whether data line n is wider than wideLineChunk and can
+// be written in chunks from gen, with nothing that needs the whole line:
+// a checksum, rotation, escapes, an injected code point, a safe start or a
+// sentinel, sorting, alignment, a byte ceiling or OnLine.
+func (l layout) wideLine(gen Generator, n int64) (LineAppender, int, bool) {
+	width := l.contentWidth(n)
+	a, ok := gen.(LineAppender)
+	if !ok || width <= wideLineChunk || l.sorted != nil || l.checksum || l.rot != 0 || l.escape || l.inject.Enabled() ||
+		l.safeStart && n == 1 || l.align > 0 || l.maxBytes > 0 || l.onLine != nil {
+		return nil, 0, false
+	}
+	if _, ok := l.sentinel(n); ok {
+		return nil, 0, false
+	}
+	return a, width, true
+}
+
+// padding returns the padding line, if any, that keeps size bytes written at
+// stream offset off within one alignment block.
+func (l layout) padding(off, size int64) []byte {
+	if l.align == 0 {
+		return nil
+	}
+	pad := alignPad(off, size, l.align, len(l.eol))
+	if pad == 0 {
+		return nil
+	}
+	return alignLine(pad, l.alignFill, l.eol)
+}
+
+// fits reports whether size more bytes at stream offset off stay within the
+// byte ceiling, if there is one.
+func (l layout) fits(off, size int64) bool {
+	return l.maxBytes == 0 || off+size <= l.maxBytes
+}
+
+// writeLines writes count lines from gen into w through a write buffer,
+// numbering them from start+1 (for comments, progress and errors) and calling
+// progress (if set) after every data line. It returns the complete data lines
+// and bytes that reached w.
+func writeLines(ctx context.Context, w io.Writer, gen Generator, lay layout, start, count int64, progress func(int64)) (lines, bytes int64, err error) {
+	lw := newLineWriter(w, lay.retry)
+	lw.flushes = lay.flushes
+	failing, _ := gen.(failingGenerator)
+	// c counts the content lines so far: the layout numbers lines by it, so
+	// blank lines of a LinePattern leave the others as they would be.
+	c := lay.pattern.content(start)
+
+	if start == 0 && lay.header != "" {
+		header := append([]byte(lay.header), lay.eol...)
+		pad := lay.padding(lw.queued, int64(len(header)))
+		if !lay.fits(lw.queued, int64(len(pad)+len(header))) {
+			return 0, 0, nil
+		}
+		if err := lw.writeExtra(append(pad, header...)); err != nil {
+			lines, bytes = lw.written()
+			return lines, bytes, fmt.Errorf("writing the header line: %w", err)
+		}
+	}
+
+	done := ctx.Done()
+	for n := start; n < start+count; n++ {
+		select {
+		case <-done:
+			if ferr := lw.flush(); ferr != nil {
+				lines, bytes = lw.written()
+				return lines, bytes, errors.Join(ctx.Err(), fmt.Errorf("flushing output: %w", ferr))
+			}
+			lines, bytes = lw.written()
+			return lines, bytes, ctx.Err()
+		default:
+		}
+
+		blank := lay.pattern.blank(n + 1)
+		if !blank {
+			c++
+		}
+		if a, width, ok := lay.wideLine(gen, c); ok && !blank {
+			if err := lw.writeWideLine(a, width, lay.suffix(c), lay.eol); err != nil {
+				lines, bytes = lw.written()
+				return lines, bytes, fmt.Errorf("writing line %d: %w", n+1, err)
+			}
+		} else {
+			line := ""
+			if !blank {
+				line = lay.nextLine(gen, c)
+			}
+			if failing != nil && failing.Err() != nil {
+				if ferr := lw.flush(); ferr != nil {
+					lines, bytes = lw.written()
+					return lines, bytes, errors.Join(fmt.Errorf("line %d: %w", n+1, failing.Err()), fmt.Errorf("flushing output: %w", ferr))
+				}
+				lines, bytes = lw.written()
+				return lines, bytes, fmt.Errorf("line %d: %w", n+1, failing.Err())
+			}
+			// Padding and the line go in together or, past the ceiling, not at all.
+			pad := lay.padding(lw.queued, int64(len(line)+len(lay.eol)))
+			if !lay.fits(lw.queued, int64(len(pad)+len(line)+len(lay.eol))) {
+				break
+			}
+			if err := lw.writeExtra(pad); err != nil {
+				lines, bytes = lw.written()
+				return lines, bytes, fmt.Errorf("writing padding before line %d: %w", n+1, err)
+			}
+			off := lw.queued
+			if err := lw.writeLine(line, lay.eol); err != nil {
+				lines, bytes = lw.written()
+				return lines, bytes, fmt.Errorf("writing line %d: %w", n+1, err)
+			}
+			if lay.onLine != nil && !blank {
+				li := lay.info(c, off, line)
+				li.Number = n + 1
+				lay.onLine(li)
+			}
+		}
+
+		if lay.commentEvery > 0 && (n+1)%lay.commentEvery == 0 {
+			comment := append([]byte(formatComment(lay.commentText, n+1)), lay.eol...)
+			pad := lay.padding(lw.queued, int64(len(comment)))
+			if !lay.fits(lw.queued, int64(len(pad)+len(comment))) {
+				if progress != nil {
+					progress(n + 1)
+				}
+				break
+			}
+			if err := lw.writeExtra(append(pad, comment...)); err != nil {
+				lines, bytes = lw.written()
+				return lines, bytes, fmt.Errorf("writing comment after line %d: %w", n+1, err)
+			}
+		}
+
+		if lay.flushEvery > 0 && (n+1)%lay.flushEvery == 0 {
+			if err := lw.flush(); err != nil {
+				lines, bytes = lw.written()
+				return lines, bytes, fmt.Errorf("writing line %d: %w", n+1, err)
+			}
+		}
+
+		if progress != nil {
+			progress(n + 1)
+		}
+	}
+
+	if err := lw.flush(); err != nil {
+		lines, bytes = lw.written()
+		return lines, bytes, fmt.Errorf("flushing output: %w", err)
+	}
+
+	lines, bytes = lw.written()
+	return lines, bytes, nil
+}
@@ -0,0 +1,255 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateTo_WritesLines(t *testing.T) {
+	var buf bytes.Buffer
+	lines, n, err := GenerateTo(context.Background(), &buf, Options{Lines: 3, Width: 5, Mode: "digits"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := "01234\n56789\n01234\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected output.\nwant: %q\ngot:  %q", want, buf.String())
+	}
+	if lines != 3 || n != int64(len(want)) {
+		t.Fatalf("expected 3 lines / %d bytes, got %d / %d", len(want), lines, n)
+	}
+}
+
+func TestGenerateTo_CustomEOL(t *testing.T) {
+	var buf bytes.Buffer
+	_, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 2, Width: 3, Mode: "char", ModeArg: "x", EOL: []byte("\r\n")})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if buf.String() != "xxx\r\nxxx\r\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+}
+
+func TestGenerateTo_CancelMidStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var buf bytes.Buffer
+	opts := Options{
+		Lines:         1000,
+		Width:         10,
+		Mode:          "digits",
+		ProgressEvery: 100,
+		Progress: func(n int64) {
+			if n == 300 {
+				cancel()
+			}
+		},
+	}
+
+	lines, n, err := GenerateTo(ctx, &buf, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if lines != 300 || n != 300*11 {
+		t.Fatalf("expected partial count 300 lines / 3300 bytes, got %d / %d", lines, n)
+	}
+	if int64(buf.Len()) != n {
+		t.Fatalf("reported %d bytes but writer holds %d", n, buf.Len())
+	}
+}
+
+// failingWriter accepts limit bytes and then fails every write.
+type failingWriter struct {
+	limit int
+	buf   bytes.Buffer
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	room := f.limit - f.buf.Len()
+	if room <= 0 {
+		return 0, errDiskFull
+	}
+	if len(p) > room {
+		f.buf.Write(p[:room])
+		return room, errDiskFull
+	}
+	return f.buf.Write(p)
+}
+
+func TestGenerateTo_FailingWriter(t *testing.T) {
+	// Each line is 81 bytes; the writer gives up part-way through the buffer flush.
+	fw := &failingWriter{limit: 100000}
+	lines, n, err := GenerateTo(context.Background(), fw, Options{Lines: 10000})
+	if !errors.Is(err, errDiskFull) {
+		t.Fatalf("expected wrapped errDiskFull, got %v", err)
+	}
+	if n != 100000 {
+		t.Fatalf("expected 100000 bytes reported, got %d", n)
+	}
+	if lines != 100000/81 {
+		t.Fatalf("expected %d complete lines, got %d", 100000/81, lines)
+	}
+}
+
+func TestGenerateTo_ProgressCadence(t *testing.T) {
+	var calls []int64
+	opts := Options{
+		Lines:         25,
+		Width:         4,
+		ProgressEvery: 10,
+		Progress:      func(n int64) { calls = append(calls, n) },
+	}
+	if _, _, err := GenerateTo(context.Background(), &bytes.Buffer{}, opts); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := []int64{10, 20, 25}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("expected progress calls %v, got %v", want, calls)
+	}
+}
+
+func TestGenerateTo_UnknownMode(t *testing.T) {
+	var buf bytes.Buffer
+	_, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 1, Mode: "bananas"})
+	if err == nil || !strings.Contains(err.Error(), "unknown mode") {
+		t.Fatalf("expected unknown mode error, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected no output, got %q", buf.String())
+	}
+}
+
+func TestGenerateTo_CommentLines(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{Lines: 7, Width: 4, Mode: "digits", CommentEvery: 3, CommentText: "# at %d (100%%)"}
+	lines, n, err := GenerateTo(context.Background(), &buf, opts)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := "0123\n4567\n8901\n# at 3 (100%)\n2345\n6789\n0123\n# at 6 (100%)\n4567\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected output.\nwant: %q\ngot:  %q", want, buf.String())
+	}
+	if lines != 7 || n != int64(len(want)) {
+		t.Fatalf("expected 7 data lines / %d bytes, got %d / %d", len(want), lines, n)
+	}
+}
+
+func TestGenerateTo_CommentTextValidation(t *testing.T) {
+	for _, text := range []string{"# %s", "# %d %v", "100%", "a\nb"} {
+		var buf bytes.Buffer
+		_, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 5, CommentEvery: 1, CommentText: text})
+		if err == nil {
+			t.Fatalf("expected error for comment text %q", text)
+		}
+		if buf.Len() != 0 {
+			t.Fatalf("comment text %q: expected nothing written before validation, got %d bytes", text, buf.Len())
+		}
+	}
+}
+
+func TestGenerateTo_ZeroLines(t *testing.T) {
+	var buf bytes.Buffer
+	lines, n, err := GenerateTo(context.Background(), &buf, Options{Lines: 0, Mode: "pi"})
+	if err != nil || lines != 0 || n != 0 || buf.Len() != 0 {
+		t.Fatalf("expected empty output, got %d lines / %d bytes / %v", lines, n, err)
+	}
+	if _, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 0, Mode: "pi", ModeArg: "bogus"}); err == nil {
+		t.Fatalf("expected modeArg validation with 0 lines")
+	}
+}
+
+func TestGenerateTo_LineChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{Lines: 3, Width: 14, Mode: "digits", LineChecksum: true}
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatalf("GenerateTo: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+	for i, line := range lines {
+		if len(line) != 14 || !CheckLine(line) {
+			t.Errorf("line %d = %q, want 14 columns ending in a valid checksum", i, line)
+		}
+	}
+	// Content continues across lines as without checksums.
+	if !strings.HasPrefix(lines[0], "01234 ") || !strings.HasPrefix(lines[1], "56789 ") {
+		t.Errorf("unexpected content: %q", lines)
+	}
+	if size, _ := PlanSize(opts); size != int64(buf.Len()) {
+		t.Errorf("PlanSize = %d, wrote %d", size, buf.Len())
+	}
+}
+
+func TestGenerateTo_LineChecksumRejectsNarrowWidth(t *testing.T) {
+	var buf bytes.Buffer
+	_, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 1, Width: 9, LineChecksum: true})
+	if err == nil || buf.Len() != 0 {
+		t.Errorf("width 9 with checksums: err=%v, wrote %d bytes", err, buf.Len())
+	}
+}
+
+func TestGenerateTo_MaxBytes(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxBytes  int64
+		wantLines int64
+		wantBytes int64
+	}{
+		{"lines first", 1000, 10, 100},
+		{"bytes first", 55, 5, 50},
+		{"tie", 100, 10, 100},
+		{"below one line", 9, 0, 0},
+	}
+	for _, tt := range tests {
+		opts := Options{Lines: 10, Width: 9, Mode: "digits", MaxBytes: tt.maxBytes}
+		var buf bytes.Buffer
+		lines, n, err := GenerateTo(context.Background(), &buf, opts)
+		if err != nil || lines != tt.wantLines || n != tt.wantBytes || int64(buf.Len()) != n {
+			t.Errorf("%s: %d lines, %d bytes (%d buffered), %v; want %d lines, %d bytes",
+				tt.name, lines, n, buf.Len(), err, tt.wantLines, tt.wantBytes)
+		}
+		if planned, err := PlanSize(opts); err != nil || planned != tt.wantBytes {
+			t.Errorf("%s: PlanSize = %d, %v", tt.name, planned, err)
+		}
+	}
+}
+
+func TestGenerateTo_MaxBytesCountsEverything(t *testing.T) {
+	for _, opts := range []Options{
+		{Lines: 100, Width: 20, EOL: []byte("\r\n"), LineChecksum: true, MaxBytes: 500},
+		{Lines: 100, Width: 20, CommentEvery: 3, MaxBytes: 301},
+		{Lines: 100, Width: 20, Align: 64, MaxBytes: 1000},
+		{Lines: 100, Mode: "digits:5+ascii:30", MaxBytes: 777},
+	} {
+		var buf bytes.Buffer
+		_, n, err := GenerateTo(context.Background(), &buf, opts)
+		if err != nil || n > opts.MaxBytes || int64(buf.Len()) != n {
+			t.Fatalf("%+v: wrote %d bytes, %v", opts, n, err)
+		}
+		if !bytes.HasSuffix(buf.Bytes(), opts.withDefaults().EOL) {
+			t.Errorf("%+v: output does not end with a complete line", opts)
+		}
+		if planned, err := PlanSize(opts); err != nil || planned != n {
+			t.Errorf("%+v: PlanSize = %d, %v; wrote %d", opts, planned, err, n)
+		}
+	}
+
+	if _, _, err := GenerateTo(context.Background(), &bytes.Buffer{}, Options{ExactBytes: 10, MaxBytes: 10}); err == nil {
+		t.Error("expected ExactBytes with MaxBytes to be rejected")
+	}
+}
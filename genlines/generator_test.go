@@ -0,0 +1,357 @@
+package genlines
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestAsciiSequence(t *testing.T) {
+	s := AsciiSequence()
+	if len(s) != (126 - 32 + 1) {
+		t.Fatalf("expected ascii palette length 95, got %d", len(s))
+	}
+	if s[0] != byte(32) || s[len(s)-1] != byte(126) {
+		t.Fatalf("expected palette to start at 32 and end at 126, got %d..%d", s[0], s[len(s)-1])
+	}
+}
+
+func TestNewGenerator_UnknownMode(t *testing.T) {
+	_, err := NewGenerator("bananas", "", 100)
+	if err == nil {
+		t.Fatalf("expected error for unknown mode, got nil")
+	}
+}
+
+func TestNewGenerator_CharModeRequiresArg(t *testing.T) {
+	// Note: in CLI parsing, mode=char without arg is rejected earlier.
+	// This test focuses on generator behavior with empty arg.
+	_, err := NewGenerator("char", "", 100)
+	if err == nil {
+		t.Fatalf("expected error for char mode without arg, got nil")
+	}
+}
+
+func TestGenerator_ASCII(t *testing.T) {
+	g, err := NewGenerator("ascii", "", 1000)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	width := 80
+	palette := []byte(AsciiSequence())
+	if len(palette) == 0 {
+		t.Fatal("ascii palette is empty")
+	}
+
+	line1 := g.NextLine(width)
+	if len(line1) != width {
+		t.Fatalf("expected length %d, got %d", width, len(line1))
+	}
+
+	// Ensure all chars are printable ASCII (32..126)
+	for i := 0; i < len(line1); i++ {
+		if line1[i] < 32 || line1[i] > 126 {
+			t.Fatalf("non-printable ascii at %d: %d", i, line1[i])
+		}
+	}
+
+	// Verify deterministic cycling:
+	// line1 should start at palette[0] and line2 should start at palette[width].
+	if line1[0] != palette[0] {
+		t.Fatalf("expected first char %q, got %q", palette[0], line1[0])
+	}
+
+	line2 := g.NextLine(width)
+	if len(line2) != width {
+		t.Fatalf("expected length %d, got %d", width, len(line2))
+	}
+
+	want2First := palette[width%len(palette)]
+	if line2[0] != want2First {
+		t.Fatalf("expected second line first char %q, got %q", want2First, line2[0])
+	}
+
+	// Optional: also check that a known index matches expected palette advancement.
+	// line1[i] should equal palette[i]
+	checkIdx := 10
+	if line1[checkIdx] != palette[checkIdx%len(palette)] {
+		t.Fatalf("expected line1[%d]=%q, got %q", checkIdx, palette[checkIdx%len(palette)], line1[checkIdx])
+	}
+}
+
+func TestGenerator_Digits(t *testing.T) {
+	g, err := NewGenerator("digits", "", 1000)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	line := g.NextLine(50)
+	if len(line) != 50 {
+		t.Fatalf("expected length 50, got %d", len(line))
+	}
+	for i := 0; i < len(line); i++ {
+		if line[i] < '0' || line[i] > '9' {
+			t.Fatalf("expected digit at %d, got %q", i, line[i])
+		}
+	}
+}
+
+func TestGenerator_Upper(t *testing.T) {
+	g, err := NewGenerator("upper", "", 1000)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	line := g.NextLine(52)
+	if len(line) != 52 {
+		t.Fatalf("expected length 52, got %d", len(line))
+	}
+	for i := 0; i < len(line); i++ {
+		if line[i] < 'A' || line[i] > 'Z' {
+			t.Fatalf("expected A-Z at %d, got %q", i, line[i])
+		}
+	}
+}
+
+func TestGenerator_Char(t *testing.T) {
+	g, err := NewGenerator("char", "#", 1000)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	line := g.NextLine(33)
+	if line != strings.Repeat("#", 33) {
+		t.Fatalf("unexpected char line: %q", line)
+	}
+
+	// A blank is the character itself, not padding to trim.
+	for _, arg := range []string{" ", "\t"} {
+		g, err := NewGenerator("char", arg, 10)
+		if err != nil {
+			t.Fatalf("char %q: %v", arg, err)
+		}
+		if line := g.NextLine(5); line != strings.Repeat(arg, 5) {
+			t.Errorf("char %q: got %q", arg, line)
+		}
+	}
+}
+
+func TestPiSpigot_FirstDigits(t *testing.T) {
+	// Known first digits of pi: 3 1 4 1 5 9 2 6 5 3 5 8 9 7 9 3
+	want := []int{3, 1, 4, 1, 5, 9, 2, 6, 5, 3, 5, 8, 9, 7, 9, 3}
+
+	s := newPiSpigot(len(want))
+	for i, wd := range want {
+		got := s.NextDigit()
+		if got != wd {
+			t.Fatalf("pi digit %d: expected %d, got %d", i, wd, got)
+		}
+	}
+}
+
+func TestGenerator_PiMode_Digits_Default(t *testing.T) {
+	// In pi mode (default), output should be the raw pi digits as characters '0'..'9'.
+	g, err := NewGenerator("pi", "", 80) // totalChars used only for spigot sizing
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	line := g.NextLine(10)
+	if len(line) != 10 {
+		t.Fatalf("expected length 10, got %d", len(line))
+	}
+
+	// First digits of pi: 3,1,4,1,5,9,2,6,5,3
+	want := "3141592653"
+	if line != want {
+		t.Fatalf("unexpected pi-digits line.\nwant: %q\ngot:  %q", want, line)
+	}
+
+	// Also assert digits only, just to be safe.
+	for i := 0; i < len(line); i++ {
+		if line[i] < '0' || line[i] > '9' {
+			t.Fatalf("expected digit at %d, got %q", i, line[i])
+		}
+	}
+}
+
+func TestGenerator_PiMode_MappingToAsciiPalette(t *testing.T) {
+	// In pi mode with modeArg=ascii, digits 0..9 are mapped to printable ASCII palette by index.
+	// Palette index 0 corresponds to ASCII 32 (space), 1 -> '!', etc.
+	// So digit '3' maps to palette[3] = ASCII 35 '#'
+	g, err := NewGenerator("pi", "ascii", 80) // totalChars used only for spigot sizing
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	line := g.NextLine(10)
+	if len(line) != 10 {
+		t.Fatalf("expected length 10, got %d", len(line))
+	}
+
+	// First digits of pi: 3,1,4,1,5,9,2,6,5,3
+	// Expected chars: palette[d] where palette[0] = ' ' (32)
+	palette := []byte(AsciiSequence())
+	wantDigits := []int{3, 1, 4, 1, 5, 9, 2, 6, 5, 3}
+	want := make([]byte, 10)
+	for i, d := range wantDigits {
+		want[i] = palette[d%len(palette)]
+	}
+
+	if line != string(want) {
+		t.Fatalf("unexpected pi-mapped line.\nwant: %q\ngot:  %q", string(want), line)
+	}
+}
+
+func TestPiSpigot_NextDigitsMatchesNextDigit(t *testing.T) {
+	const n = 500
+
+	a := newPiSpigot(n)
+	want := make([]int, n)
+	for i := range want {
+		want[i] = a.NextDigit()
+	}
+
+	// Mix batch sizes so refills straddle spigot iterations.
+	b := newPiSpigot(n)
+	got := make([]int, 0, n)
+	for _, size := range []int{1, 7, 64, 3, 200, 225} {
+		buf := make([]int, size)
+		b.NextDigits(buf)
+		got = append(got, buf...)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("digit %d: NextDigits gave %d, NextDigit gave %d", i, got[i], want[i])
+		}
+	}
+}
+
+func BenchmarkGenerator_Pi(b *testing.B) {
+	const width = 80
+	for i := 0; i < b.N; i++ {
+		g, err := NewGenerator("pi", "", width*10)
+		if err != nil {
+			b.Fatalf("unexpected err: %v", err)
+		}
+		for l := 0; l < 10; l++ {
+			g.NextLine(width)
+		}
+	}
+}
+
+func TestGenerator_RandomSeeded(t *testing.T) {
+	a, err := NewGenerator("random", "42", 0)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	b, _ := NewGenerator("random", "42", 0)
+	c, _ := NewGenerator("random", "43", 0)
+
+	la, lb, lc := a.NextLine(200), b.NextLine(200), c.NextLine(200)
+	if la != lb {
+		t.Fatalf("same seed produced different lines")
+	}
+	if la == lc {
+		t.Fatalf("different seeds produced identical lines")
+	}
+	for i := 0; i < len(la); i++ {
+		if la[i] < 32 || la[i] > 126 {
+			t.Fatalf("non-printable byte %d at %d", la[i], i)
+		}
+	}
+}
+
+func TestGenerator_RandomRequiresSeed(t *testing.T) {
+	for _, arg := range []string{"", "abc", "-1"} {
+		if _, err := NewGenerator("random", arg, 0); err == nil {
+			t.Fatalf("expected error for seed %q", arg)
+		}
+	}
+}
+
+func TestGenerator_PiSpigotAllocatedLazily(t *testing.T) {
+	g, err := NewGenerator("pi", "", 5)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	pg := g.(*piGen)
+	if pg.spigot != nil {
+		t.Fatalf("expected no spigot before the first line")
+	}
+	if line := g.NextLine(5); line != "31415" {
+		t.Fatalf("unexpected first line %q", line)
+	}
+}
+
+// nextLineSeeds are a mode and modeArg for every built-in mode that can be
+// constructed without files.
+var nextLineSeeds = [][2]string{
+	{"ascii", ""}, {"digits", ""}, {"upper", ""}, {"alpha", ""}, {"char", "#"},
+	{"char", "é"}, {"random", "7"}, {"random", "7:3"}, {"hashfill", "seed"},
+	{"noise", ""}, {"pi", ""}, {"pi", "ascii"}, {"dates", ""}, {"ip", ""},
+	{"ip", "v6"}, {"ip", "10.0.0.0/30"}, {"words", "alpha,beta"}, {"lorem", ""},
+	{"blocks", ""}, {"csv", ""}, {"csv", "cols=3;multiline=2;delim=;"},
+	{"jsonl", ""}, {"jsonl", "multiline=2"}, {"jsonl", "schema=a:int,b:str:4"},
+	{"binrec", "u32be:counter,bytes:4:cycle"},
+}
+
+// FuzzNextLine checks the width contract of every generator: no width
+// panics, a width of 0 or less gives an empty line and uses up no content,
+// and a width the generator accepts gives exactly that many characters.
+func FuzzNextLine(f *testing.F) {
+	for _, seed := range nextLineSeeds {
+		for _, width := range []int{math.MinInt, -1, 0, 1, 2, 7, 80} {
+			f.Add(seed[0], seed[1], width)
+		}
+	}
+	f.Fuzz(func(t *testing.T, mode, arg string, width int) {
+		if canonicalMode(mode) == "template" {
+			return // its modeArg is a file to read
+		}
+		width %= 1 << 12
+		gen, err := NewModeGenerator(mode, arg)
+		if err != nil {
+			return
+		}
+		ignoresWidth := canonicalMode(mode) == "binrec"
+		if jg, ok := gen.(*jsonlGen); ok && jg.args.schema != nil {
+			ignoresWidth = true
+		}
+		line := gen.NextLine(width)
+		if ignoresWidth {
+			return
+		}
+		if width <= 0 {
+			if line != "" {
+				t.Fatalf("mode=%s %q: NextLine(%d) = %q, want empty", mode, arg, width, line)
+			}
+			if !Reproducible(Options{Mode: mode, ModeArg: arg}) {
+				return
+			}
+			fresh, _ := NewModeGenerator(mode, arg)
+			if got, want := gen.NextLine(9), fresh.NextLine(9); got != want {
+				t.Fatalf("mode=%s %q: NextLine(%d) used up content: %q, want %q", mode, arg, width, got, want)
+			}
+			return
+		}
+		if wc, ok := gen.(widthChecker); ok && wc.checkWidth(width) != nil {
+			return
+		}
+		if canonicalMode(mode) == "palette-file" && len(line) != width {
+			t.Fatalf("mode=palette-file %q: NextLine(%d) is %d bytes", arg, width, len(line))
+		}
+		if canonicalMode(mode) == "blocks" {
+			if int64(len(line)) != blockLineBytes(width) {
+				t.Fatalf("mode=blocks: NextLine(%d) is %d bytes, want %d", width, len(line), blockLineBytes(width))
+			}
+			return
+		}
+		if n := utf8.RuneCountInString(line); n != width {
+			t.Fatalf("mode=%s %q: NextLine(%d) = %q, %d characters", mode, arg, width, line, n)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package genlines
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// LineFor returns line index (zero-based) of the hashfill mode for seed. The
+// content is a pure function of (seed, index, width), so any line can be
+// computed without generating the ones before it.
+//
+// Characters come from SHA-256(seed || index || block) for block = 0, 1, ...,
+// with index as a big-endian uint64 and block as a big-endian uint32; each
+// hash byte b maps to the printable ASCII character 32 + b%95.
+func LineFor(seed string, index, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	msg := make([]byte, len(seed)+8+4)
+	copy(msg, seed)
+	binary.BigEndian.PutUint64(msg[len(seed):], uint64(index))
+
+	out := make([]byte, 0, width+sha256.Size)
+	for block := uint32(0); len(out) < width; block++ {
+		binary.BigEndian.PutUint32(msg[len(seed)+8:], block)
+		sum := sha256.Sum256(msg)
+		for _, b := range sum {
+			out = append(out, 32+b%95)
+		}
+	}
+	return string(out[:width])
+}
+
+// hashGen emits LineFor(seed, n, width) for n = 0, 1, ...
+type hashGen struct {
+	seed  string
+	index int
+}
+
+func newHashGen(arg string, _ int) (Generator, error) {
+	if strings.TrimSpace(arg) == "" {
+		return nil, errors.New("mode=hashfill: a seed is required")
+	}
+	return &hashGen{seed: arg}, nil
+}
+
+func (g *hashGen) NextLine(width int) string {
+	if width <= 0 {
+		return ""
+	}
+	line := LineFor(g.seed, g.index, width)
+	g.index++
+	return line
+}
@@ -0,0 +1,44 @@
+package genlines
+
+import (
+	"testing"
+)
+
+func TestLineFor(t *testing.T) {
+	line := LineFor("seed", 7, 100)
+	if len(line) != 100 {
+		t.Fatalf("len = %d, want 100", len(line))
+	}
+	for _, c := range []byte(line) {
+		if c < 32 || c > 126 {
+			t.Fatalf("non-printable byte %d in %q", c, line)
+		}
+	}
+	if LineFor("seed", 7, 100) != line {
+		t.Error("LineFor is not deterministic")
+	}
+	if LineFor("seed", 7, 10) != line[:10] {
+		t.Error("a narrower line should be a prefix of a wider one")
+	}
+	if LineFor("seed", 8, 100) == line || LineFor("seee", 7, 100) == line {
+		t.Error("different indexes or seeds should give different lines")
+	}
+	if LineFor("seed", 0, 0) != "" {
+		t.Error("width 0 should give an empty line")
+	}
+}
+
+func TestHashGen_MatchesLineFor(t *testing.T) {
+	gen, err := NewGenerator("hash", "abc", 0)
+	if err != nil {
+		t.Fatalf("NewGenerator: %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		if got, want := gen.NextLine(40), LineFor("abc", i, 40); got != want {
+			t.Fatalf("line %d = %q, want %q", i, got, want)
+		}
+	}
+	if _, err := NewGenerator("hashfill", " ", 0); err == nil {
+		t.Error("hashfill without a seed should fail")
+	}
+}
@@ -0,0 +1,20 @@
+package humanize_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Bjornsrud/GenerateLines/genlines/humanize"
+)
+
+func Example() {
+	fmt.Println(humanize.Bytes(100000))
+	fmt.Println(humanize.Count(1048576))
+	fmt.Println(humanize.Duration(65*time.Second + 250*time.Millisecond))
+	fmt.Println(humanize.Fixed(3.32192809, 3))
+	// Output:
+	// 97.7 KiB
+	// 1_048_576
+	// 1m05.250s
+	// 3.322
+}
@@ -0,0 +1,98 @@
+// Package humanize formats numbers for people: sizes, counts, rates and
+// durations. Every function returns the same string on every platform and in
+// every locale (a '.' decimal point, '_' between groups of thousands, English
+// unit names), so output built from it can be compared byte for byte, e.g. in
+// the golden tests of a wrapper script.
+package humanize
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bytes formats n bytes in binary units with one decimal, e.g. "512 B",
+// "2.0 KiB" or "7.5 GiB". A value that rounds up to 1024 of a unit is given
+// in the next one: 1048575 bytes is "1.0 MiB", not "1024.0 KiB".
+func Bytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 5; m /= unit {
+		div *= unit
+		exp++
+	}
+	v := float64(n) / float64(div)
+	if Fixed(v, 1) == "1024.0" && exp < 5 {
+		v /= unit
+		exp++
+	}
+	return Fixed(v, 1) + " " + string("KMGTPE"[exp]) + "iB"
+}
+
+// Count formats n with an underscore between groups of three digits, e.g.
+// "1_048_576", the way Go literals write it.
+func Count(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	var b strings.Builder
+	b.WriteString(sign)
+	for i := range len(s) {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte('_')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// Fixed formats v with exactly decimals digits after the decimal point, or,
+// for a decimals of -1, with as few as represent v exactly. It never uses an
+// exponent.
+func Fixed(v float64, decimals int) string {
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+// Duration formats d to the millisecond, with seconds always shown with three
+// decimals and minutes and hours only when needed: "0.350s", "12.345s",
+// "2m05.000s", "1h02m03.500s".
+func Duration(d time.Duration) string {
+	d = d.Round(time.Millisecond)
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	h, m := int64(d/time.Hour), int64(d%time.Hour/time.Minute)
+	s, ms := int64(d%time.Minute/time.Second), int64(d%time.Second/time.Millisecond)
+	frac := "." + pad(ms, 3) + "s"
+	switch {
+	case h > 0:
+		return sign + strconv.FormatInt(h, 10) + "h" + pad(m, 2) + "m" + pad(s, 2) + frac
+	case m > 0:
+		return sign + strconv.FormatInt(m, 10) + "m" + pad(s, 2) + frac
+	}
+	return sign + strconv.FormatInt(s, 10) + frac
+}
+
+// PerSecond returns how many of n there are per second over d, rounded; a d
+// of zero or less counts as one nanosecond.
+func PerSecond(n int64, d time.Duration) int64 {
+	if d <= 0 {
+		d = time.Nanosecond
+	}
+	return int64(float64(n)/d.Seconds() + 0.5)
+}
+
+// pad formats n with at least width digits.
+func pad(n int64, width int) string {
+	s := strconv.FormatInt(n, 10)
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+	return s
+}
@@ -0,0 +1,73 @@
+package humanize
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// formatAll formats the representative values the tests lock.
+func formatAll() []string {
+	return []string{
+		Bytes(0), Bytes(512), Bytes(1023), Bytes(1024), Bytes(1536), Bytes(2048),
+		Bytes(100000), Bytes(1 << 20), Bytes(8_100_000_000), Bytes(math.MaxInt64), Bytes(-5),
+		Bytes(1048575), Bytes(1048525), Bytes(1048524), Bytes(1<<30 - 1),
+		Count(0), Count(7), Count(999), Count(1000), Count(65536), Count(1_048_576),
+		Count(-1234567), Count(math.MinInt64),
+		Fixed(0, 1), Fixed(48.828125, 1), Fixed(3.32192809, 3), Fixed(0.05, 1), Fixed(1234567.891, 2),
+		Duration(0), Duration(350 * time.Millisecond), Duration(1234567 * time.Microsecond),
+		Duration(65 * time.Second), Duration(time.Hour + 2*time.Minute + 3500*time.Millisecond),
+		Duration(-1500 * time.Millisecond), Duration(400 * time.Microsecond),
+	}
+}
+
+func TestFormats(t *testing.T) {
+	want := []string{
+		"0 B", "512 B", "1023 B", "1.0 KiB", "1.5 KiB", "2.0 KiB",
+		"97.7 KiB", "1.0 MiB", "7.5 GiB", "8.0 EiB", "-5 B",
+		"1.0 MiB", "1.0 MiB", "1023.9 KiB", "1.0 GiB",
+		"0", "7", "999", "1_000", "65_536", "1_048_576",
+		"-1_234_567", "-9_223_372_036_854_775_808",
+		"0.0", "48.8", "3.322", "0.1", "1234567.89",
+		"0.000s", "0.350s", "1.235s",
+		"1m05.000s", "1h02m03.500s",
+		"-1.500s", "0.000s",
+	}
+	got := formatAll()
+	if len(got) != len(want) {
+		t.Fatalf("%d values, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("value %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// TestFormats_SameInEveryLocale locks that the locale environment does not
+// reach the output.
+func TestFormats_SameInEveryLocale(t *testing.T) {
+	base := formatAll()
+	for _, loc := range []string{"C", "de_DE.UTF-8", "nb_NO.UTF-8", "fr_FR.UTF-8", "hi_IN.UTF-8"} {
+		t.Setenv("LC_ALL", loc)
+		t.Setenv("LC_NUMERIC", loc)
+		t.Setenv("LANG", loc)
+		for i, s := range formatAll() {
+			if s != base[i] {
+				t.Errorf("%s: value %d = %q, want %q", loc, i, s, base[i])
+			}
+		}
+	}
+}
+
+func TestPerSecond(t *testing.T) {
+	if got := PerSecond(300, 2*time.Second); got != 150 {
+		t.Errorf("PerSecond(300, 2s) = %d", got)
+	}
+	if got := PerSecond(1, 3*time.Second); got != 0 {
+		t.Errorf("PerSecond(1, 3s) = %d", got)
+	}
+	if got := PerSecond(5, 0); got != 5_000_000_000 {
+		t.Errorf("PerSecond(5, 0) = %d", got)
+	}
+}
@@ -0,0 +1,228 @@
+package genlines
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SeedLabelInjectUnicode labels the seed InjectUnicode derives from the
+// global seed when it has none of its own.
+const SeedLabelInjectUnicode = "inject-unicode"
+
+// invisibleRunes maps the names InjectUnicode accepts to their code points:
+// zero-width characters, the byte order mark and the bidi controls.
+var invisibleRunes = map[string]rune{
+	"zwsp": '\u200b', // zero width space
+	"zwnj": '\u200c', // zero width non-joiner
+	"zwj":  '\u200d', // zero width joiner
+	"wj":   '\u2060', // word joiner
+	"bom":  '\ufeff', // byte order mark (zero width no-break space)
+	"lrm":  '\u200e', // left-to-right mark
+	"rlm":  '\u200f', // right-to-left mark
+	"lre":  '\u202a', // left-to-right embedding
+	"rle":  '\u202b', // right-to-left embedding
+	"pdf":  '\u202c', // pop directional formatting
+	"lro":  '\u202d', // left-to-right override
+	"rlo":  '\u202e', // right-to-left override
+	"lri":  '\u2066', // left-to-right isolate
+	"rli":  '\u2067', // right-to-left isolate
+	"fsi":  '\u2068', // first strong isolate
+	"pdi":  '\u2069', // pop directional isolate
+}
+
+// InvisibleRuneNames returns the names InjectUnicode accepts, sorted.
+func InvisibleRuneNames() []string {
+	names := make([]string, 0, len(invisibleRunes))
+	for name := range invisibleRunes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InvisibleRune returns the code point of name, one of InvisibleRuneNames.
+func InvisibleRune(name string) (rune, bool) {
+	r, ok := invisibleRunes[name]
+	return r, ok
+}
+
+// InjectUnicode inserts one invisible code point into the content of every
+// Every-th data line, as a hostile fixture for Unicode sanitizers. The code
+// point is one of Names and sits at the start of the content (AtStart) or at
+// a column within it; both choices depend only on the seed and the line
+// number. Injected code points do not count toward the width: the line
+// keeps its visible characters and grows by their bytes.
+type InjectUnicode struct {
+	Names   []string // from InvisibleRuneNames; none = off
+	Every   int      // inject into lines Every, 2×Every, ...; 0 means 1
+	AtStart bool     // at the start of the content rather than a seeded column
+	Seed    uint64
+	HasSeed bool // Seed is set; otherwise it derives from Options.Seed, or is 0
+}
+
+// Enabled reports whether u injects anything.
+func (u InjectUnicode) Enabled() bool {
+	return len(u.Names) > 0
+}
+
+// ParseInjectUnicode parses "name[,name...][:every=N][:seed=S][:at=start|any]",
+// e.g. "zwsp:every=50:seed=7" or "bom:at=start".
+func ParseInjectUnicode(spec string) (InjectUnicode, error) {
+	fields := strings.Split(strings.TrimSpace(spec), ":")
+	var u InjectUnicode
+	for _, name := range strings.Split(fields[0], ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := invisibleRunes[name]; !ok {
+			return InjectUnicode{}, fmt.Errorf("unknown code point name %q (expected one of %v)", name, InvisibleRuneNames())
+		}
+		u.Names = append(u.Names, name)
+	}
+	for _, f := range fields[1:] {
+		key, value, _ := strings.Cut(f, "=")
+		var err error
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "every":
+			u.Every, err = strconv.Atoi(strings.TrimSpace(value))
+			if err == nil && u.Every < 1 {
+				err = errors.New("must be at least 1")
+			}
+		case "seed":
+			u.Seed, err = ParseSeed(value)
+			u.HasSeed = true
+		case "at":
+			switch strings.ToLower(strings.TrimSpace(value)) {
+			case "start":
+				u.AtStart = true
+			case "any":
+				u.AtStart = false
+			default:
+				err = errors.New("expected start or any")
+			}
+		default:
+			return InjectUnicode{}, fmt.Errorf("unknown setting %q (expected every, seed or at)", f)
+		}
+		if err != nil {
+			return InjectUnicode{}, fmt.Errorf("invalid %s %q: %v", key, value, err)
+		}
+	}
+	return u, nil
+}
+
+// String returns u in the form ParseInjectUnicode accepts.
+func (u InjectUnicode) String() string {
+	s := strings.Join(u.Names, ",")
+	if u.Every > 1 {
+		s += ":every=" + strconv.Itoa(u.Every)
+	}
+	if u.HasSeed {
+		s += ":seed=" + strconv.FormatUint(u.Seed, 10)
+	}
+	if u.AtStart {
+		s += ":at=start"
+	}
+	return s
+}
+
+// errInjectUnsupported is returned for settings that cannot take an
+// injected code point: blocks lines are escape sequences it would break,
+// binrec records are binary, and an exact byte size plans the partial line
+// from uniform line sizes.
+var errInjectUnsupported = errors.New("unicode injection cannot be combined with an exact byte size, mode=blocks or mode=binrec")
+
+// validateInjectUnicode checks o.InjectUnicode (with defaults applied).
+func (o Options) validateInjectUnicode() error {
+	u := o.InjectUnicode
+	for _, name := range u.Names {
+		if _, ok := invisibleRunes[name]; !ok {
+			return fmt.Errorf("unknown code point name %q (expected one of %v)", name, InvisibleRuneNames())
+		}
+	}
+	if u.Every < 0 {
+		return fmt.Errorf("invalid injection interval: %d", u.Every)
+	}
+	if u.Enabled() && (o.ExactBytes > 0 || canonicalMode(o.Mode) == "blocks" || canonicalMode(o.Mode) == "binrec") {
+		return errInjectUnsupported
+	}
+	return nil
+}
+
+// resolve returns u with its interval defaulted and the seed it draws from
+// in a run of o.
+func (u InjectUnicode) resolve(o Options) InjectUnicode {
+	if u.Every == 0 {
+		u.Every = 1
+	}
+	if !u.HasSeed && o.HasSeed {
+		u.Seed, u.HasSeed = DeriveSeed(o.Seed, SeedLabelInjectUnicode), true
+	}
+	return u
+}
+
+// pick returns the name injected into data line n (one-based) and a hash
+// for its column, or "" if the line gets none.
+func (u InjectUnicode) pick(n int64) (string, uint64) {
+	if !u.Enabled() || n%int64(u.Every) != 0 {
+		return "", 0
+	}
+	h := splitmix64(u.Seed ^ splitmix64(uint64(n)))
+	return u.Names[h%uint64(len(u.Names))], splitmix64(h)
+}
+
+// apply returns content, the content of data line n, with its injected code
+// point, if any.
+func (u InjectUnicode) apply(content string, n int64) string {
+	name, h := u.pick(n)
+	if name == "" {
+		return content
+	}
+	at := 0
+	if !u.AtStart {
+		// A column from 0 to the rune count, at a rune boundary.
+		for col := h % uint64(utf8.RuneCountInString(content)+1); col > 0; col-- {
+			_, size := utf8.DecodeRuneInString(content[at:])
+			at += size
+		}
+	}
+	return content[:at] + string(invisibleRunes[name]) + content[at:]
+}
+
+// lineBytes returns the bytes the injection adds to data line n, written
+// as a \u escape if escape is set.
+func (u InjectUnicode) lineBytes(n int64, escape bool) int64 {
+	name, _ := u.pick(n)
+	switch {
+	case name == "":
+		return 0
+	case escape:
+		return 6 // every name is in the BMP
+	}
+	return int64(utf8.RuneLen(invisibleRunes[name]))
+}
+
+// count returns how many code points of each name data lines 1 to lines get.
+func (u InjectUnicode) count(lines int64) map[string]int64 {
+	if !u.Enabled() {
+		return nil
+	}
+	counts := make(map[string]int64)
+	for n := int64(u.Every); n <= lines; n += int64(u.Every) {
+		name, _ := u.pick(n)
+		counts[name]++
+	}
+	return counts
+}
+
+// encoded returns the UTF-8 encoding of every code point of t.Names.
+func (t InjectUnicode) encoded() string {
+	var b strings.Builder
+	for _, name := range t.Names {
+		if r, ok := InvisibleRune(name); ok {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParseInjectUnicode(t *testing.T) {
+	for spec, want := range map[string]InjectUnicode{
+		"zwsp":                       {Names: []string{"zwsp"}},
+		"zwsp:every=50:seed=7":       {Names: []string{"zwsp"}, Every: 50, Seed: 7, HasSeed: true},
+		"BOM:at=start":               {Names: []string{"bom"}, AtStart: true},
+		"rlo,lri,pdi:every=3:at=any": {Names: []string{"rlo", "lri", "pdi"}, Every: 3},
+	} {
+		got, err := ParseInjectUnicode(spec)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: %+v, %v; want %+v", spec, got, err, want)
+		}
+		if again, _ := ParseInjectUnicode(got.String()); !reflect.DeepEqual(again, got) {
+			t.Errorf("%q: String() %q does not round-trip", spec, got.String())
+		}
+	}
+	for _, spec := range []string{"", "nbsp", "zwsp,", "zwsp:every=0", "zwsp:every=x", "zwsp:seed=", "zwsp:at=end", "zwsp:count=2"} {
+		if _, err := ParseInjectUnicode(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestInjectUnicode_ExpectedLines(t *testing.T) {
+	var st Stats
+	opts := Options{Lines: 200, Width: 30, Mode: "alpha", Stats: &st,
+		InjectUnicode: InjectUnicode{Names: []string{"zwsp", "rlo"}, Every: 7, Seed: 3, HasSeed: true}}
+	var buf bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int64{}
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		var found []string
+		for _, name := range opts.InjectUnicode.Names {
+			if c := strings.Count(line, string(invisibleRunes[name])); c > 0 {
+				found = append(found, name)
+				counts[name] += int64(c)
+			}
+		}
+		if want := (i+1)%7 == 0; want != (len(found) == 1) || len(found) > 1 {
+			t.Errorf("line %d: injected %v", i+1, found)
+		}
+		// Injected code points do not count toward the width.
+		if visible := utf8.RuneCountInString(line) - len(found); visible != opts.Width {
+			t.Errorf("line %d: %d visible characters, want %d", i+1, visible, opts.Width)
+		}
+	}
+	if counts["zwsp"] == 0 || counts["rlo"] == 0 || !reflect.DeepEqual(st.Injected, counts) {
+		t.Errorf("stats %v, output has %v", st.Injected, counts)
+	}
+	if size, err := PlanSize(opts); err != nil || size != int64(buf.Len()) {
+		t.Errorf("PlanSize = %d, %v; wrote %d bytes", size, err, buf.Len())
+	}
+}
+
+func TestInjectUnicode_LeadingBOMAndChecksums(t *testing.T) {
+	opts := Options{Lines: 20, Width: 20, Mode: "digits", LineChecksum: true, EOL: []byte("\r\n"),
+		InjectUnicode: InjectUnicode{Names: []string{"bom"}, AtStart: true}}
+	var buf bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if !strings.HasPrefix(line, "\ufeff") || strings.Count(line, "\ufeff") != 1 || !CheckLine(line) {
+			t.Errorf("line %d: %q does not start with a BOM covered by its checksum", i+1, line)
+		}
+	}
+
+	// Escaped, the BOM is six ASCII bytes, and planning knows it.
+	opts.EscapeNonASCII = true
+	buf.Reset()
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), `\ufeff`) {
+		t.Errorf("escaped output starts %q", buf.String()[:10])
+	}
+	if size, err := PlanSize(opts); err != nil || size != int64(buf.Len()) {
+		t.Errorf("PlanSize = %d, %v; wrote %d bytes", size, err, buf.Len())
+	}
+}
+
+func TestInjectUnicode_Deterministic(t *testing.T) {
+	run := func(opts Options) string {
+		var buf bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	base := Options{Lines: 60, Width: 40, Mode: "random", ModeArg: "1", InjectUnicode: InjectUnicode{Names: InvisibleRuneNames()}}
+	if run(base) != run(base) {
+		t.Error("two runs differ")
+	}
+	global := base
+	global.Seed, global.HasSeed = 5, true
+	if run(global) == run(base) {
+		t.Error("the global seed does not move the injected code points")
+	}
+	var all bytes.Buffer
+	create := func(int) (io.WriteCloser, error) { return nopCloser{&all}, nil }
+	if _, err := GenerateSplit(context.Background(), global, 25, create); err != nil {
+		t.Fatal(err)
+	}
+	if all.String() != run(global) {
+		t.Error("split output differs from the single run")
+	}
+}
+
+func TestInjectUnicode_Rejected(t *testing.T) {
+	zwsp := InjectUnicode{Names: []string{"zwsp"}}
+	for name, opts := range map[string]Options{
+		"name":   {Lines: 1, InjectUnicode: InjectUnicode{Names: []string{"nbsp"}}},
+		"every":  {Lines: 1, InjectUnicode: InjectUnicode{Names: []string{"zwsp"}, Every: -1}},
+		"exact":  {ExactBytes: 100, InjectUnicode: zwsp},
+		"blocks": {Lines: 1, Mode: "blocks", InjectUnicode: zwsp},
+		"binrec": {Lines: 1, Mode: "binrec", ModeArg: "u8:counter", InjectUnicode: zwsp},
+	} {
+		if _, _, err := GenerateTo(context.Background(), io.Discard, opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := NewSeekable(Options{Lines: 1, InjectUnicode: zwsp}); err == nil {
+		t.Error("NewSeekable accepted unicode injection")
+	}
+}
@@ -0,0 +1,106 @@
+package genlines
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Stream is one component of an interleave spec: a mode with its own width.
+type Stream struct {
+	Mode    string
+	ModeArg string
+	Width   int
+}
+
+// IsInterleaveSpec reports whether mode is an interleave spec such as
+// "digits:20+ascii:100" rather than a plain mode name.
+func IsInterleaveSpec(mode string) bool {
+	return strings.ContainsAny(mode, ":+")
+}
+
+// ParseInterleave parses an interleave spec of the form
+// mode:width[:modeArg]+mode:width[:modeArg]+... into its streams.
+// Lines are taken from the streams in turn, each at its own width.
+func ParseInterleave(spec string) ([]Stream, error) {
+	parts := strings.Split(spec, "+")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("interleave spec %q needs at least two streams joined by +", spec)
+	}
+
+	streams := make([]Stream, 0, len(parts))
+	for i, p := range parts {
+		fields := strings.SplitN(p, ":", 3)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("interleave stream %d (%q): expected mode:width", i+1, p)
+		}
+		name, spec, err := LookupMode(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("interleave stream %d: %w", i+1, err)
+		}
+		if name == "blocks" {
+			return nil, fmt.Errorf("interleave stream %d: %w", i+1, errBlocksUnsupported)
+		}
+		if name == "binrec" {
+			return nil, fmt.Errorf("interleave stream %d: %w", i+1, errBinrecUnsupported)
+		}
+		if name == "template" {
+			return nil, fmt.Errorf("interleave stream %d: mode=template cannot be interleaved", i+1)
+		}
+		width, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		if err != nil || width <= 0 {
+			return nil, fmt.Errorf("interleave stream %d (%q): invalid width %q", i+1, p, fields[1])
+		}
+		s := Stream{Mode: name, Width: width}
+		if len(fields) == 3 {
+			s.ModeArg = fields[2]
+		}
+		// A blank modeArg is kept: it is the character of a char stream.
+		if spec.RequiresArg && s.ModeArg == "" {
+			return nil, fmt.Errorf("interleave stream %d: mode=%s requires modeArg (%s:%d:<arg>)", i+1, name, name, width)
+		}
+		streams = append(streams, s)
+	}
+	return streams, nil
+}
+
+// interleaveGen takes lines from its streams in turn, each at the stream's own width.
+type interleaveGen struct {
+	streams []Stream
+	gens    []Generator
+	next    int
+}
+
+// newInterleaveGen builds the stream generators for a run of totalLines
+// lines. seedArg resolves each stream's modeArg against the global seed.
+func newInterleaveGen(spec string, totalLines int, seedArg func(mode, arg, label string) (string, error)) (*interleaveGen, error) {
+	streams, err := ParseInterleave(spec)
+	if err != nil {
+		return nil, err
+	}
+	g := &interleaveGen{streams: streams, gens: make([]Generator, len(streams))}
+	for i, s := range streams {
+		// Stream i serves lines i, i+k, i+2k, ...
+		lines := (totalLines - i + len(streams) - 1) / len(streams)
+		arg, err := seedArg(s.Mode, s.ModeArg, fmt.Sprintf("%s/%d", SeedLabelContent, i+1))
+		if err != nil {
+			return nil, fmt.Errorf("interleave stream %d: %w", i+1, err)
+		}
+		if g.gens[i], err = newGenerator(s.Mode, arg, lines*s.Width); err != nil {
+			return nil, fmt.Errorf("interleave stream %d: %w", i+1, err)
+		}
+		if wc, ok := g.gens[i].(widthChecker); ok {
+			if err := wc.checkWidth(s.Width); err != nil {
+				return nil, fmt.Errorf("interleave stream %d: %w", i+1, err)
+			}
+		}
+	}
+	return g, nil
+}
+
+// NextLine ignores width; every stream writes at its configured width.
+func (g *interleaveGen) NextLine(int) string {
+	s := g.next
+	g.next = (g.next + 1) % len(g.gens)
+	return g.gens[s].NextLine(g.streams[s].Width)
+}
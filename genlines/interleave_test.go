@@ -0,0 +1,99 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGenerateTo_InterleaveAlternatesWidthsAndContent(t *testing.T) {
+	var buf bytes.Buffer
+	_, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 5, Mode: "digits:4+upper:10+char:2:#"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := []string{"0123", "ABCDEFGHIJ", "##", "4567", "KLMNOPQRST"}
+	got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected interleaved lines.\nwant: %q\ngot:  %q", want, got)
+	}
+}
+
+func TestGenerateTo_InterleavePiStreamSizedForItsShare(t *testing.T) {
+	// The pi stream is wider than the default width; its spigot must be sized
+	// for its own share of the output or later digits come out wrong.
+	var buf bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 4, Mode: "char:1:x+pi:300"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+
+	s := newPiSpigot(600)
+	want := make([]byte, 600)
+	for i := range want {
+		want[i] = byte('0' + s.NextDigit())
+	}
+	if lines[1]+lines[3] != string(want) {
+		t.Fatalf("pi stream digits differ from a dedicated spigot")
+	}
+}
+
+func TestGenerateTo_InterleaveBlankCharStream(t *testing.T) {
+	var buf bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 2, Mode: "char:3: +digits:2"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if buf.String() != "   \n01\n" {
+		t.Fatalf("got %q", buf.String())
+	}
+}
+
+func TestParseInterleave_Errors(t *testing.T) {
+	for _, spec := range []string{
+		"digits:20",         // single stream
+		"digits+ascii:10",   // missing width
+		"digits:0+ascii:10", // zero width
+		"digts:5+ascii:10",  // unknown mode
+		"char:5+ascii:10",   // char needs an arg
+	} {
+		if _, err := ParseInterleave(spec); err == nil {
+			t.Fatalf("ParseInterleave(%q): expected error", spec)
+		}
+	}
+}
+
+func TestPlanSize_MatchesOutput(t *testing.T) {
+	cases := []Options{
+		{Lines: 1000, Width: 80},
+		{Lines: 7, Mode: "digits:3+ascii:11+upper:1"},
+		{Lines: 1234, Width: 5, CommentEvery: 7, CommentText: "# %d of many (%d) 100%%"},
+		{Lines: 0, Width: 10},
+		{Lines: 25, Width: 3, EOL: []byte("\r\n"), CommentEvery: 5},
+	}
+	for i, opts := range cases {
+		var buf bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+			t.Fatalf("case %d: GenerateTo: %v", i, err)
+		}
+		planned, err := PlanSize(opts)
+		if err != nil {
+			t.Fatalf("case %d: PlanSize: %v", i, err)
+		}
+		if planned != int64(buf.Len()) {
+			t.Fatalf("case %d: planned %d bytes, generated %d", i, planned, buf.Len())
+		}
+	}
+}
+
+func TestPlanSize_Overflow(t *testing.T) {
+	if strconv.IntSize < 64 {
+		t.Skip("lines times width fits in an int64 on 32-bit platforms")
+	}
+	if _, err := PlanSize(Options{Lines: math.MaxInt >> 23, Width: 1 << 30}); err == nil {
+		t.Fatalf("expected overflow error")
+	}
+}
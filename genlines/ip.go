@@ -0,0 +1,107 @@
+package genlines
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"strings"
+)
+
+// newIPGen emits one IP address per line. arg is v4[:seed], v6[:seed] (seeded
+// pseudo-random addresses; the seed defaults to 0) or cidr:<block> (every
+// address of the block in order, wrapping around). The default is v4.
+func newIPGen(arg string, _ int) (Generator, error) {
+	kind, rest, _ := strings.Cut(strings.TrimSpace(arg), ":")
+	switch strings.ToLower(kind) {
+	case "", "v4", "v6":
+		var seed uint64
+		if strings.TrimSpace(rest) != "" {
+			s, err := ParseSeed(rest)
+			if err != nil {
+				return nil, fmt.Errorf("mode=ip: %w", err)
+			}
+			seed = s
+		}
+		return &randomIPGen{src: rand.NewPCG(seed, seed), v6: strings.EqualFold(kind, "v6")}, nil
+	case "cidr":
+		p, err := netip.ParsePrefix(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("mode=ip: invalid CIDR block: %w", err)
+		}
+		p = p.Masked()
+		return &cidrGen{prefix: p, next: p.Addr()}, nil
+	default:
+		return nil, fmt.Errorf("mode=ip unknown modeArg: %s (expected v4, v6, v4:<seed>, v6:<seed> or cidr:<block>)", arg)
+	}
+}
+
+// The longest text forms of an IPv4 and an IPv6 address.
+const (
+	maxIPv4Len = len("255.255.255.255")
+	maxIPv6Len = len("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff")
+)
+
+// checkIPWidth rejects widths that cannot hold every address of the family.
+func checkIPWidth(v6 bool, width int) error {
+	family, need := "IPv4", maxIPv4Len
+	if v6 {
+		family, need = "IPv6", maxIPv6Len
+	}
+	if width < need {
+		return fmt.Errorf("mode=ip: %s addresses need a width of at least %d, got %d", family, need, width)
+	}
+	return nil
+}
+
+// padAddr returns a space-padded to width. An address longer than width is
+// returned whole: a cut one would not be an address.
+func padAddr(a netip.Addr, width int) string {
+	s := a.String()
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+// randomIPGen emits seeded pseudo-random IPv4 or IPv6 addresses.
+type randomIPGen struct {
+	src *rand.PCG
+	v6  bool
+}
+
+func (g *randomIPGen) checkWidth(width int) error { return checkIPWidth(g.v6, width) }
+
+func (g *randomIPGen) NextLine(width int) string {
+	if width <= 0 {
+		return ""
+	}
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], g.src.Uint64())
+	if !g.v6 {
+		return padAddr(netip.AddrFrom4([4]byte(b[:4])), width)
+	}
+	binary.BigEndian.PutUint64(b[8:], g.src.Uint64())
+	return padAddr(netip.AddrFrom16(b), width)
+}
+
+// cidrGen enumerates the addresses of a prefix in order, starting again at
+// the network address after the last one.
+type cidrGen struct {
+	prefix netip.Prefix
+	next   netip.Addr
+}
+
+func (g *cidrGen) checkWidth(width int) error { return checkIPWidth(g.prefix.Addr().Is6(), width) }
+
+func (g *cidrGen) NextLine(width int) string {
+	if width <= 0 {
+		return ""
+	}
+	a := g.next
+	g.next = a.Next()
+	if !g.next.IsValid() || !g.prefix.Contains(g.next) {
+		g.next = g.prefix.Addr()
+	}
+	return padAddr(a, width)
+}
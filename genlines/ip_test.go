@@ -0,0 +1,116 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestIP_RandomAddressesParse(t *testing.T) {
+	for _, arg := range []string{"", "v4", "v4:42", "v6", "V6:7"} {
+		g, err := NewGenerator("ip", arg, 0)
+		if err != nil {
+			t.Fatalf("%q: %v", arg, err)
+		}
+		seen := map[string]bool{}
+		for i := 0; i < 200; i++ {
+			line := g.NextLine(40)
+			if len(line) != 40 {
+				t.Fatalf("%q: width %d, want 40", arg, len(line))
+			}
+			a, err := netip.ParseAddr(strings.TrimRight(line, " "))
+			if err != nil {
+				t.Fatalf("%q line %d: %v", arg, i+1, err)
+			}
+			if v6 := strings.HasPrefix(strings.ToLower(arg), "v6"); a.Is4() == v6 {
+				t.Fatalf("%q line %d: %s has the wrong family", arg, i+1, a)
+			}
+			seen[line] = true
+		}
+		if len(seen) < 190 {
+			t.Errorf("%q: only %d distinct addresses in 200 lines", arg, len(seen))
+		}
+	}
+}
+
+func TestIP_SeedIsDeterministic(t *testing.T) {
+	a, _ := NewGenerator("ip", "v6:99", 0)
+	b, _ := NewGenerator("ip", "v6:99", 0)
+	c, _ := NewGenerator("ip", "v6:100", 0)
+	first := a.NextLine(39)
+	if first != b.NextLine(39) {
+		t.Fatalf("same seed gave different addresses")
+	}
+	if first == c.NextLine(39) {
+		t.Fatalf("different seeds gave the same address")
+	}
+}
+
+func TestIP_CIDREnumeratesAndWraps(t *testing.T) {
+	tests := []struct {
+		block string
+		want  []string
+	}{
+		{"192.168.1.5/30", []string{"192.168.1.4", "192.168.1.5", "192.168.1.6", "192.168.1.7", "192.168.1.4", "192.168.1.5"}},
+		{"10.0.0.0/32", []string{"10.0.0.0", "10.0.0.0"}},
+		{"255.255.255.254/31", []string{"255.255.255.254", "255.255.255.255", "255.255.255.254"}},
+		{"2001:db8::/127", []string{"2001:db8::", "2001:db8::1", "2001:db8::"}},
+	}
+	for _, tt := range tests {
+		g, err := NewGenerator("ip", "cidr:"+tt.block, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.block, err)
+		}
+		for i, want := range tt.want {
+			if got := strings.TrimRight(g.NextLine(20), " "); got != want {
+				t.Errorf("%s line %d: got %q, want %q", tt.block, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestIP_NarrowWidthRejected(t *testing.T) {
+	for _, tt := range []struct {
+		arg   string
+		width int
+	}{{"v4", 14}, {"v6", 38}, {"cidr:10.0.0.0/24", 10}, {"cidr:2001:db8::/64", 20}} {
+		_, _, err := GenerateTo(context.Background(), io.Discard, Options{Lines: 2, Width: tt.width, Mode: "ip", ModeArg: tt.arg})
+		if err == nil || !strings.Contains(err.Error(), "need a width of at least") {
+			t.Errorf("%s at width %d: got %v, want a width error", tt.arg, tt.width, err)
+		}
+	}
+	for _, tt := range []struct {
+		arg   string
+		width int
+	}{{"v4", 15}, {"v6", 39}} {
+		var buf bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 50, Width: tt.width, Mode: "ip", ModeArg: tt.arg}); err != nil {
+			t.Fatalf("%s at width %d: %v", tt.arg, tt.width, err)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			if _, err := netip.ParseAddr(strings.TrimRight(line, " ")); err != nil {
+				t.Fatalf("%s at width %d: %v", tt.arg, tt.width, err)
+			}
+		}
+	}
+
+	g, _ := NewGenerator("ip", "v6:7", 0)
+	if line := g.NextLine(5); len(line) <= 5 {
+		t.Errorf("NextLine(5) = %q, want the whole address", line)
+	}
+}
+
+func TestIP_BadArgs(t *testing.T) {
+	_, err := NewGenerator("ip", "cidr:10.0.0.0/33", 0)
+	if err == nil || !strings.Contains(err.Error(), "10.0.0.0/33") || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("bad CIDR: got %v, want the netip parse error", err)
+	}
+	for _, arg := range []string{"v5", "v4:seed", "cidr:"} {
+		if _, err := NewGenerator("ip", arg, 0); err == nil {
+			t.Errorf("%q: expected an error", arg)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package genlines
+
+import "strings"
+
+// LineInfo describes one data line as it was queued for the writer, for
+// Options.OnLine.
+type LineInfo struct {
+	Number int64 // one-based data line number
+	// Offset is the byte offset of the line's first byte in the output of
+	// the run (in its part, for GenerateSplit), after every terminator,
+	// comment line and padding line before it.
+	Offset int64
+	Width  int // the line's width setting: Width, or the ramp width
+	Bytes  int // bytes of the line, trailing whitespace included, without its terminator
+	// Checksum is the CRC32 the line ends with under LineChecksum, or "".
+	Checksum string
+}
+
+// lineWidth returns the width setting of data line n (one-based).
+func (l layout) lineWidth(n int64) int {
+	if l.ramp.Enabled() {
+		return l.ramp.Width(n)
+	}
+	return l.width
+}
+
+// info describes data line n (one-based), built by nextLine and queued at
+// stream offset off.
+func (l layout) info(n, off int64, line string) LineInfo {
+	li := LineInfo{Number: n, Offset: off, Width: l.lineWidth(n), Bytes: len(line)}
+	if l.checksum && l.sorted != nil {
+		// A sorted line ends in the whitespace of the line it was.
+		end := len(strings.TrimRight(line, " \t"))
+		li.Checksum = line[end-ChecksumWidth : end]
+	} else if l.checksum {
+		end := len(line) - len(l.trailing.suffix(n))
+		if n < l.lines {
+			end -= len(l.continuation)
+		}
+		li.Checksum = line[end-ChecksumWidth : end]
+	}
+	return li
+}
@@ -0,0 +1,53 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestOnLine_OffsetsMatchOutput(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"comments crlf": {Lines: 12, Width: 20, Mode: "digits", CommentEvery: 3, EOL: []byte("\r\n"), LineChecksum: true},
+		"escaped":       {Lines: 6, Width: 5, Mode: "char", ModeArg: "é", EscapeNonASCII: true},
+		"ramp":          {Lines: 9, Mode: "alpha", Ramp: Ramp{Min: 2, Max: 6, Step: 2}},
+		"aligned":       {Lines: 20, Width: 30, Align: 64},
+		"continued":     {Lines: 4, Width: 25, LineChecksum: true, Continuation: " &"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var infos []LineInfo
+			opts.OnLine = func(li LineInfo) { infos = append(infos, li) }
+			var buf bytes.Buffer
+			if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+				t.Fatal(err)
+			}
+			if len(infos) != opts.Lines {
+				t.Fatalf("%d lines reported, want %d", len(infos), opts.Lines)
+			}
+			eol := opts.EOL
+			if eol == nil {
+				eol = []byte("\n")
+			}
+			out := buf.Bytes()
+			for i, li := range infos {
+				if li.Number != int64(i+1) {
+					t.Errorf("line %d reported as %d", i+1, li.Number)
+				}
+				end := li.Offset + int64(li.Bytes)
+				if end+int64(len(eol)) > int64(len(out)) || !bytes.Equal(out[end:end+int64(len(eol))], eol) {
+					t.Fatalf("line %d: no terminator at offset %d", li.Number, end)
+				}
+				if li.Offset > 0 && !bytes.Equal(out[li.Offset-int64(len(eol)):li.Offset], eol) {
+					t.Errorf("line %d: offset %d does not follow a terminator", li.Number, li.Offset)
+				}
+				line := string(out[li.Offset:end])
+				if opts.LineChecksum && (li.Checksum == "" || !bytes.Contains([]byte(line), []byte(" "+li.Checksum))) {
+					t.Errorf("line %d: checksum %q not in %q", li.Number, li.Checksum, line)
+				}
+				if want := opts.Width; !opts.Ramp.Enabled() && li.Width != want {
+					t.Errorf("line %d: width %d, want %d", li.Number, li.Width, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package genlines
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ModeSpec describes a content mode.
+type ModeSpec struct {
+	Aliases     []string // Alternative names accepted for the mode
+	Description string   // One-line description for help output
+	RequiresArg bool     // Whether the mode needs a modeArg
+
+	// Help is the longer text "help <mode>" prints, one or more lines
+	// without the aliases; Description is used when it is empty.
+	Help string
+	// Examples are command lines using the mode, as typed after the
+	// program name.
+	Examples []string
+
+	// SeedArg, for modes with randomness, returns the modeArg to use when a
+	// run has a global seed (see Options.Seed): arg itself if it already
+	// holds a seed, otherwise arg with the derived seed filled in.
+	SeedArg func(seed uint64, arg string) string
+
+	// Factory builds a Generator for the mode. totalChars is the expected
+	// output size, a hint for modes that size precomputed state, or 0 when
+	// the output has no set end; the generator must keep going past it.
+	Factory func(arg string, totalChars int) (Generator, error)
+}
+
+// registeredMode pairs a canonical mode name with its spec.
+type registeredMode struct {
+	name string
+	spec ModeSpec
+}
+
+var (
+	// registryMu guards registry; embedders may register modes while runs
+	// look them up.
+	registryMu sync.RWMutex
+	// registry holds the known modes in display order: the built-in ones,
+	// then those added with RegisterMode.
+	registry []registeredMode
+	// builtinModes is the number of built-in modes at the start of registry.
+	builtinModes int
+)
+
+// cyclePalette returns a factory cycling through palette.
+func cyclePalette(palette string) func(string, int) (Generator, error) {
+	return func(string, int) (Generator, error) {
+		return &cycleGen{palette: []byte(palette)}, nil
+	}
+}
+
+func init() {
+	register("ascii", ModeSpec{
+		Description: "Printable ASCII characters (32–126)",
+		Examples:    []string{"1000 lines.txt y 80 ascii"},
+		Factory:     cyclePalette(AsciiSequence()),
+	})
+	register("digits", ModeSpec{
+		Aliases:     []string{"digit", "num", "numbers"},
+		Description: "Digits 0–9",
+		Examples:    []string{"1000 digits.txt y 60 digits"},
+		Factory:     cyclePalette("0123456789"),
+	})
+	register("upper", ModeSpec{
+		Aliases:     []string{"uppercase"},
+		Description: "Uppercase letters A–Z",
+		Examples:    []string{"1000 uppercase.txt y 120 upper"},
+		Factory:     cyclePalette("ABCDEFGHIJKLMNOPQRSTUVWXYZ"),
+	})
+	register("alpha", ModeSpec{
+		Aliases:     []string{"letters"},
+		Description: "Letters A–Z followed by a–z",
+		Examples:    []string{"1000 letters.txt y 52 alpha"},
+		Factory:     cyclePalette("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"),
+	})
+	register("char", ModeSpec{
+		Aliases:     []string{"character"},
+		Description: "Repeat a single character (requires modeArg)",
+		Help: `Repeat a single character (requires modeArg)
+A quoted blank (" ") is kept, not trimmed
+Escapes: \s (space) \t \n \r \v \f \a \b \e \0 \xHH \\
+(control characters need --allow-control)`,
+		Examples:    []string{`100 out.txt y 80 char "#"`},
+		RequiresArg: true,
+		Factory:     newCharGen,
+	})
+	register("random", ModeSpec{
+		Aliases:     []string{"rand"},
+		Description: "Seeded pseudo-random printable ASCII (modeArg: seed)",
+		Help: `Seeded pseudo-random printable ASCII (32–126)
+modeArg: numeric seed. Without one a seed is picked and
+recorded in <filename>.meta so "regen" can reproduce the file`,
+		Examples: []string{"1000 random.txt y 80 random 42"},
+		SeedArg:  seedIfEmpty,
+		Factory:  newRandomModeGen,
+	})
+	register("hashfill", ModeSpec{
+		Aliases:     []string{"hash"},
+		Description: "Printable ASCII where each line depends only on (seed, line number) (modeArg: seed)",
+		Help: `Printable ASCII where line K depends only on (seed, K), so any
+line can be recomputed alone. modeArg: seed (any text);
+without one a seed is picked and recorded`,
+		Examples: []string{"1000 hashed.txt y 64 hashfill release-1"},
+		SeedArg:  seedIfEmpty,
+		Factory:  newHashGen,
+	})
+	register("noise", ModeSpec{
+		Aliases:     []string{"entropy"},
+		Description: "Incompressible printable ASCII from crypto/rand, never the same twice",
+		Help: `Incompressible printable ASCII from crypto/rand, different
+every run: no .meta sidecar is written and --verify-after is
+skipped`,
+		Examples: []string{"1000 noise.txt y 80 noise"},
+		Factory:  newNoiseGen,
+	})
+	register("dates", ModeSpec{
+		Aliases:     []string{"date", "calendar"},
+		Description: "Timestamps advancing by a fixed step (modeArg: layout|step|start)",
+		Help: `One timestamp per line, space-padded to the width (cut if
+longer). modeArg: layout|step|start, e.g.
+"2006-01-02T15:04:05Z|1h|2020-01-01T00:00:00Z"; empty parts
+default to RFC 3339, 1s and 2000-01-01T00:00:00Z`,
+		Examples: []string{`1000 dates.txt y 30 dates "2006-01-02 15:04|15m|2024-01-01T00:00:00Z"`},
+		Factory:  newDateGen,
+	})
+	register("ip", ModeSpec{
+		Aliases:     []string{"address"},
+		Description: "One IP address per line (modeArg: v4[:seed] | v6[:seed] | cidr:<block>)",
+		Help: `One IP address per line, space-padded to the width (at least
+15 for v4, 39 for v6)
+modeArg: v4[:seed] | v6[:seed] (pseudo-random, seed
+default 0) | cidr:<block> (every address in order, wrapping)`,
+		Examples: []string{"1000 hosts.txt y 15 ip cidr:10.0.0.0/24", "sample 39 ip v6:7"},
+		SeedArg:  seedIPArg,
+		Factory:  newIPGen,
+	})
+	register("words", ModeSpec{
+		Aliases:     []string{"word", "dictionary"},
+		Description: "Words separated by single spaces (modeArg: word,word,... | @file)",
+		Help: `Words separated by single spaces, repeating to fill each line;
+the last word is cut at the line end. modeArg: word,word,...
+or @file with one word per line. A word longer than the width
+is an error`,
+		Examples:    []string{"1000 words.txt y 60 words alpha,beta,gamma"},
+		RequiresArg: true,
+		Factory:     newWordsGen,
+	})
+	register("lorem", ModeSpec{
+		Aliases:     []string{"ipsum"},
+		Description: "Lorem ipsum words separated by single spaces (modeArg: la, en, de or no)",
+		Help: `Filler words, filled like words. modeArg: the language,
+la (classic Latin, the default), en, de or no; on the command
+line it can also follow the mode after a colon, as in lorem:de`,
+		Examples: []string{"1000 lorem.txt y 72 lorem", "1000 fyll.txt y 72 lorem no"},
+		Factory:  newLoremGen,
+	})
+	register("blocks", ModeSpec{
+		Aliases:     []string{"block", "colors"},
+		Description: "ANSI 256-color background cells rendering another mode (modeArg: mode[:modeArg])",
+		Help: `A colored texture for terminal demos: each byte of another
+mode becomes a cell with that 256-color background.
+modeArg: mode[:modeArg], default ascii. Width counts cells.
+Files need --force-ansi`,
+		Examples: []string{"sample term blocks random:7"},
+		SeedArg:  seedBlocksArg,
+		Factory:  newBlocksGen,
+	})
+	register("csv", ModeSpec{
+		Description: "Comma-separated records of letters and digits (modeArg: cols=N;multiline=K)",
+		Help: `Records of comma-separated letters and digits, each exactly
+width characters. modeArg: cols=N;multiline=K;delim=C
+(default 4 columns; every Kth record has a quoted field with
+an embedded newline; delim is a character or comma,
+semicolon, tab, pipe, space). No field starts with = + - @
+unless --unsafe is given`,
+		Examples: []string{`1000 data.csv y 60 csv "cols=6;multiline=10"`},
+		Factory:  newCSVGen,
+	})
+	register("jsonl", ModeSpec{
+		Aliases:     []string{"ndjson"},
+		Description: `One JSON object per record, {"id":N,"text":"..."} (modeArg: multiline=K)`,
+		Help: `One {"id":N,"text":"..."} object per record, width >= 37.
+modeArg: multiline=K breaks every Kth object across two
+lines; a schema such as id:int,name:str:12,flag:bool,score:float
+sets the fields (width is then ignored)`,
+		Examples: []string{"1000 records.jsonl y 80 jsonl", "1000 people.jsonl y 80 jsonl id:int,name:str:12,flag:bool"},
+		Factory:  newJSONLGen,
+	})
+	register("template", ModeSpec{
+		Aliases:     []string{"tmpl"},
+		Description: "Lines rendered from a Go text/template (modeArg: file | inline:<text>)",
+		Help: `Each line rendered from a Go text/template
+modeArg: a template file, or inline:<text>; prefix seed:<n>:
+to seed Rand. Data: .Line, .Width, .Total; functions:
+{{Fill n}} (n chars of the ASCII cycle), {{Rand n}} (n seeded
+random chars). Output is used verbatim`,
+		Examples:    []string{`1000 lines.txt y 40 template "inline:line {{.Line}}: {{Fill 20}}"`},
+		RequiresArg: true,
+		SeedArg:     seedTemplateArg,
+		Factory:     newTemplateGen,
+	})
+	register("binrec", ModeSpec{
+		Aliases:     []string{"binary"},
+		Description: "Fixed-size binary records without line endings (modeArg: u32be:counter,bytes:20:cycle|zero|noise,...)",
+		Help: `Fixed-size binary records, no line endings
+modeArg: comma-separated fields, each <type>:counter or
+<type>:<value> with type u8, u16le, u16be, u32le, u32be,
+u64le, u64be, or bytes:<n>:cycle | zero | noise. Width is
+ignored`,
+		Examples:    []string{"1000 records.bin y 80 binrec u32be:counter,u64le:counter,bytes:20:cycle"},
+		RequiresArg: true,
+		Factory:     newBinrecGen,
+	})
+	register("palette-file", ModeSpec{
+		Aliases:     []string{"bytefile"},
+		Description: "Cycle the bytes of a file, any value included (modeArg: path[:offset[:length]])",
+		Help: `Cycle the raw bytes of a file, any value included, so the
+output need not be text. modeArg: path[:offset[:length]].
+Width counts bytes; --line-ending none gives a pure byte
+pattern`,
+		Examples:    []string{"1000 pattern.bin y 256 palette-file fw.img:0:256 --line-ending none"},
+		RequiresArg: true,
+		Factory:     newPaletteFileGen,
+	})
+	register("pi", ModeSpec{
+		Description: "Digits of pi (modeArg: digits | ascii)",
+		Help: `Digits of pi (default: digits)
+modeArg: digits | ascii
+digits -> pure pi digits (0–9)
+ascii  -> pi digits mapped to the first ten printable
+          ASCII characters (space through ')')
+Total digits generated = lines × width. Runs estimated
+to take over 30s ask first (or need --force)`,
+		Examples: []string{"50 pi.txt n 80 pi", "sample 60 pi ascii 3"},
+		Factory:  newPiGen,
+	})
+	builtinModes = len(registry)
+}
+
+// register adds a mode to the registry.
+func register(name string, spec ModeSpec) {
+	registry = append(registry, registeredMode{name: name, spec: spec})
+}
+
+// RegisterMode adds a custom content mode, so programs embedding the package
+// can generate their own content through GenerateTo, NewGenerator and
+// interleave specs. Names and aliases are matched case-insensitively and must
+// not clash with an existing mode or alias. It is safe for concurrent use.
+func RegisterMode(name string, spec ModeSpec) error {
+	if spec.Factory == nil {
+		return fmt.Errorf("mode %q: ModeSpec.Factory is required", name)
+	}
+	names := make([]string, 0, 1+len(spec.Aliases))
+	for _, n := range append([]string{name}, spec.Aliases...) {
+		key := strings.ToLower(strings.TrimSpace(n))
+		if key == "" || strings.ContainsAny(key, ":+ \t") {
+			return fmt.Errorf("invalid mode name %q: names need at least one character and no ':', '+' or blanks", n)
+		}
+		names = append(names, key)
+	}
+	spec.Aliases = names[1:]
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for i, key := range names {
+		if i > 0 && key == names[0] {
+			return fmt.Errorf("mode %q: alias %q repeats the name", names[0], key)
+		}
+		if m, ok := findMode(key); ok {
+			return fmt.Errorf("mode %q: %q is already registered as mode %q", names[0], key, m.name)
+		}
+	}
+	register(names[0], spec)
+	return nil
+}
+
+// ResetModes removes every mode added with RegisterMode, leaving the built-in
+// ones. It is meant for tests.
+func ResetModes() {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	clear(registry[builtinModes:])
+	registry = registry[:builtinModes]
+}
+
+// CustomModes returns the canonical names of the modes added with
+// RegisterMode, in registration order.
+func CustomModes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry)-builtinModes)
+	for _, m := range registry[builtinModes:] {
+		names = append(names, m.name)
+	}
+	return names
+}
+
+// findMode returns the registered mode whose name or alias is key. The caller
+// holds registryMu.
+func findMode(key string) (registeredMode, bool) {
+	for _, m := range registry {
+		if m.name == key {
+			return m, true
+		}
+		for _, a := range m.spec.Aliases {
+			if a == key {
+				return m, true
+			}
+		}
+	}
+	return registeredMode{}, false
+}
+
+// canonicalMode returns the canonical name of mode, or "" if it is not a
+// registered mode (such as an interleave spec).
+func canonicalMode(mode string) string {
+	name, _, err := LookupMode(mode)
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// ModeNames returns the canonical names of all registered modes in display order.
+func ModeNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, len(registry))
+	for i, m := range registry {
+		names[i] = m.name
+	}
+	return names
+}
+
+// LookupMode resolves a mode name or alias, case-insensitively, to its
+// canonical name and spec. Unknown names that are close to a known one get a
+// "did you mean" suggestion in the error.
+func LookupMode(name string) (string, ModeSpec, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if m, ok := findMode(key); ok {
+		return m.name, m.spec, nil
+	}
+
+	if s := suggestMode(key); s != "" {
+		return "", ModeSpec{}, fmt.Errorf("unknown mode %q — did you mean %q?", name, s)
+	}
+	return "", ModeSpec{}, fmt.Errorf("unknown mode %q", name)
+}
+
+// maxSuggestDistance is the largest edit distance that still earns a suggestion.
+const maxSuggestDistance = 2
+
+// suggestMode returns the canonical name of the mode whose name or alias is
+// nearest to key, or "" if nothing is close enough to be a plausible typo.
+// The caller holds registryMu.
+func suggestMode(key string) string {
+	best, bestDist := "", maxSuggestDistance+1
+	for _, m := range registry {
+		for _, candidate := range append([]string{m.name}, m.spec.Aliases...) {
+			if d := editDistance(key, candidate); d < bestDist {
+				best, bestDist = m.name, d
+			}
+		}
+	}
+	// Very short inputs are within two edits of almost anything.
+	if bestDist >= len([]rune(key)) {
+		return ""
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
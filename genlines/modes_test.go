@@ -0,0 +1,154 @@
+package genlines
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLookupMode_Aliases(t *testing.T) {
+	cases := map[string]string{
+		"digits":    "digits",
+		"num":       "digits",
+		"Numbers":   "digits",
+		"UPPER":     "upper",
+		"alpha":     "alpha",
+		"letters":   "alpha",
+		" char ":    "char",
+		"rand":      "random",
+		"uppercase": "upper",
+	}
+	for in, want := range cases {
+		name, _, err := LookupMode(in)
+		if err != nil || name != want {
+			t.Fatalf("LookupMode(%q) = %q, %v; want %q", in, name, err, want)
+		}
+	}
+}
+
+func TestLookupMode_Suggestions(t *testing.T) {
+	cases := map[string]string{
+		"upperr": "upper",
+		"digts":  "digits",
+		"ascci":  "ascii",
+		"rnadom": "random",
+		"numbrs": "digits",
+		"alhpa":  "alpha",
+	}
+	for in, want := range cases {
+		_, _, err := LookupMode(in)
+		if err == nil {
+			t.Fatalf("LookupMode(%q): expected error", in)
+		}
+		if !strings.Contains(err.Error(), `did you mean "`+want+`"?`) {
+			t.Fatalf("LookupMode(%q): expected suggestion %q, got %v", in, want, err)
+		}
+	}
+}
+
+func TestLookupMode_NoSuggestionForHopelessInput(t *testing.T) {
+	for _, in := range []string{"bananas", "x", "zz", "spreadsheet"} {
+		_, _, err := LookupMode(in)
+		if err == nil {
+			t.Fatalf("LookupMode(%q): expected error", in)
+		}
+		if strings.Contains(err.Error(), "did you mean") {
+			t.Fatalf("LookupMode(%q): unexpected suggestion: %v", in, err)
+		}
+	}
+}
+
+func TestRegisterMode(t *testing.T) {
+	t.Cleanup(ResetModes)
+	factory := cyclePalette("xy")
+	if err := RegisterMode("Custom", ModeSpec{Aliases: []string{"cust"}, Factory: factory}); err != nil {
+		t.Fatal(err)
+	}
+	if name, _, err := LookupMode("CUST"); err != nil || name != "custom" {
+		t.Fatalf("LookupMode(CUST) = %q, %v", name, err)
+	}
+	if got := CustomModes(); strings.Join(got, ",") != "custom" {
+		t.Errorf("CustomModes() = %q", got)
+	}
+
+	for _, tt := range []struct {
+		name string
+		spec ModeSpec
+	}{
+		{"custom", ModeSpec{Factory: factory}},                           // same name
+		{"other", ModeSpec{Aliases: []string{"cust"}, Factory: factory}}, // taken alias
+		{"num", ModeSpec{Factory: factory}},                              // built-in alias
+		{"twice", ModeSpec{Aliases: []string{"Twice"}, Factory: factory}},
+		{"a:b", ModeSpec{Factory: factory}},
+		{"", ModeSpec{Factory: factory}},
+		{"nofactory", ModeSpec{}},
+	} {
+		if err := RegisterMode(tt.name, tt.spec); err == nil {
+			t.Errorf("RegisterMode(%q, %+v): expected an error", tt.name, tt.spec.Aliases)
+		}
+	}
+
+	ResetModes()
+	if _, _, err := LookupMode("custom"); err == nil {
+		t.Error("custom mode survived ResetModes")
+	}
+	if len(CustomModes()) != 0 || len(ModeNames()) != builtinModes {
+		t.Errorf("modes after reset: %q", ModeNames())
+	}
+}
+
+func TestRegisterMode_Concurrent(t *testing.T) {
+	t.Cleanup(ResetModes)
+	done := make(chan error)
+	for i := 0; i < 8; i++ {
+		go func() {
+			err := RegisterMode(fmt.Sprintf("mode%d", i), ModeSpec{Factory: cyclePalette("z")})
+			if err == nil {
+				_, _, err = LookupMode(fmt.Sprintf("mode%d", i))
+			}
+			done <- err
+		}()
+		go func() {
+			_, _, err := LookupMode("digits")
+			done <- err
+		}()
+	}
+	for i := 0; i < 16; i++ {
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}
+	if n := len(CustomModes()); n != 8 {
+		t.Errorf("%d custom modes, want 8", n)
+	}
+}
+
+func TestGenerator_Alpha(t *testing.T) {
+	g, err := NewGenerator("alpha", "", 0)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	line := g.NextLine(60)
+	want := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyzABCDEFGH"
+	if line != want {
+		t.Fatalf("unexpected alpha line.\nwant: %q\ngot:  %q", want, line)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"upper", "upper", 0},
+		{"upperr", "upper", 1},
+		{"kitten", "sitting", 3},
+		{"", "pi", 2},
+	}
+	for _, c := range cases {
+		if got := editDistance(c.a, c.b); got != c.want {
+			t.Fatalf("editDistance(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
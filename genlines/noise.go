@@ -0,0 +1,116 @@
+package genlines
+
+import "crypto/rand"
+
+// noiseBlockSize is how many bytes a noiseSource reads from crypto/rand at a
+// time, so generating noise does not pay for a read per byte.
+const noiseBlockSize = 64 * 1024
+
+// noiseSource hands out bytes from crypto/rand, read a block at a time.
+type noiseSource struct {
+	buf []byte
+	pos int
+}
+
+// refill reads the next block once the current one is used up.
+func (s *noiseSource) refill() {
+	if s.pos < len(s.buf) {
+		return
+	}
+	if s.buf == nil {
+		s.buf = make([]byte, noiseBlockSize)
+	}
+	rand.Read(s.buf) // never fails; crypto/rand crashes the program instead
+	s.pos = 0
+}
+
+// next returns the next random byte.
+func (s *noiseSource) next() byte {
+	s.refill()
+	b := s.buf[s.pos]
+	s.pos++
+	return b
+}
+
+// printable returns a random printable ASCII character (32–126), uniformly:
+// bytes from 190 up, where the 95 characters would not fit evenly, are
+// skipped.
+func (s *noiseSource) printable() byte {
+	for {
+		if b := s.next(); b < 190 {
+			return ' ' + b%95
+		}
+	}
+}
+
+// fill fills p with raw random bytes.
+func (s *noiseSource) fill(p []byte) {
+	for len(p) > 0 {
+		s.refill()
+		n := copy(p, s.buf[s.pos:])
+		s.pos += n
+		p = p[n:]
+	}
+}
+
+// noiseGen writes incompressible printable ASCII from crypto/rand. Its
+// output cannot be produced again, which Reproducible reports.
+type noiseGen struct {
+	src noiseSource
+}
+
+func newNoiseGen(string, int) (Generator, error) {
+	return &noiseGen{}, nil
+}
+
+func (g *noiseGen) NextLine(width int) string {
+	if width <= 0 {
+		return ""
+	}
+	out := make([]byte, width)
+	for i := range out {
+		out[i] = g.src.printable()
+	}
+	return string(out)
+}
+
+// Reproducible reports whether every run of o writes the same bytes, which
+// verifying a file against its settings or regenerating it relies on. Only
+// content drawn from crypto/rand is not: mode=noise (also rendered by blocks
+// or as an interleave stream) and binrec noise fields.
+func Reproducible(o Options) bool {
+	if !IsInterleaveSpec(o.Mode) {
+		return reproducibleMode(o.Mode, o.ModeArg)
+	}
+	streams, err := ParseInterleave(o.Mode)
+	if err != nil {
+		return true // the run fails before writing anything
+	}
+	for _, s := range streams {
+		if !reproducibleMode(s.Mode, s.ModeArg) {
+			return false
+		}
+	}
+	return true
+}
+
+// reproducibleMode reports whether mode with arg always gives the same content.
+func reproducibleMode(mode, arg string) bool {
+	switch canonicalMode(mode) {
+	case "noise":
+		return false
+	case "blocks":
+		return reproducibleMode(splitBlocksArg(arg))
+	case "binrec":
+		fields, _, err := parseBinrecSpec(arg)
+		if err != nil {
+			return true
+		}
+		for _, f := range fields {
+			if f.noise {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package genlines
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"math"
+	"testing"
+)
+
+func generateNoise(t *testing.T, opts Options) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestNoise_PrintableAndDifferent(t *testing.T) {
+	opts := Options{Lines: 2000, Width: 100, Mode: "noise"}
+	a, b := generateNoise(t, opts), generateNoise(t, opts)
+	if len(a) != 2000*101 || len(b) != len(a) {
+		t.Fatalf("sizes %d and %d, want %d", len(a), len(b), 2000*101)
+	}
+	if bytes.Equal(a, b) {
+		t.Error("two runs wrote the same bytes")
+	}
+	for i, line := range bytes.Split(bytes.TrimSuffix(a, []byte("\n")), []byte("\n")) {
+		for _, c := range line {
+			if c < ' ' || c > '~' {
+				t.Fatalf("line %d has byte %#x outside printable ASCII", i+1, c)
+			}
+		}
+	}
+}
+
+func TestNoise_Entropy(t *testing.T) {
+	data := bytes.ReplaceAll(generateNoise(t, Options{Lines: 1000, Width: 200, Mode: "noise"}), []byte("\n"), nil)
+
+	var counts [256]int
+	for _, c := range data {
+		counts[c]++
+	}
+	entropy := 0.0
+	for _, n := range counts {
+		if n > 0 {
+			p := float64(n) / float64(len(data))
+			entropy -= p * math.Log2(p)
+		}
+	}
+	// log2(95) ≈ 6.57 bits per character for uniform printable ASCII.
+	if entropy < 6.5 {
+		t.Errorf("entropy %.3f bits per character, want about 6.57", entropy)
+	}
+
+	var packed bytes.Buffer
+	zw, _ := flate.NewWriter(&packed, flate.BestCompression)
+	zw.Write(data)
+	zw.Close()
+	if ratio := float64(packed.Len()) / float64(len(data)); ratio < 0.8 {
+		t.Errorf("noise compressed to %.2f of its size", ratio)
+	}
+}
+
+func TestBinrec_NoiseField(t *testing.T) {
+	opts := Options{Lines: 500, Mode: "binrec", ModeArg: "u16be:counter,bytes:30:noise"}
+	a, b := generateNoise(t, opts), generateNoise(t, opts)
+	if len(a) != 500*32 || bytes.Equal(a, b) {
+		t.Fatalf("%d bytes, equal runs = %v", len(a), bytes.Equal(a, b))
+	}
+	if a[0] != 0 || a[1] != 1 || a[32] != 0 || a[33] != 2 {
+		t.Errorf("counters disturbed by noise: % x", a[:34])
+	}
+}
+
+func TestReproducible(t *testing.T) {
+	for _, tt := range []struct {
+		opts Options
+		want bool
+	}{
+		{Options{}, true},
+		{Options{Mode: "random"}, true},
+		{Options{Mode: "noise"}, false},
+		{Options{Mode: "entropy"}, false},
+		{Options{Mode: "digits:5+noise:10"}, false},
+		{Options{Mode: "digits:5+upper:10"}, true},
+		{Options{Mode: "blocks", ModeArg: "noise"}, false},
+		{Options{Mode: "blocks", ModeArg: "random:7"}, true},
+		{Options{Mode: "binrec", ModeArg: "u8:counter,bytes:4:noise"}, false},
+		{Options{Mode: "binrec", ModeArg: "u8:counter,bytes:4:zero"}, true},
+	} {
+		if got := Reproducible(tt.opts); got != tt.want {
+			t.Errorf("Reproducible(%s %s) = %v, want %v", tt.opts.Mode, tt.opts.ModeArg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package genlines
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxPaletteFile is the most bytes mode=palette-file takes from its file.
+const maxPaletteFile = 1 << 20
+
+// paletteFileGen cycles through bytes read from a file, any value included,
+// so its lines are not necessarily text. The width counts bytes.
+type paletteFileGen struct {
+	cycleGen
+}
+
+// newPaletteFileGen reads the palette named by arg: a path, optionally
+// followed by :offset or :offset:length (decimal or 0x hex) to take a range
+// of the file instead of all of it.
+func newPaletteFileGen(arg string, _ int) (Generator, error) {
+	path, offset, length, err := parsePaletteFileArg(arg)
+	if err != nil {
+		return nil, fmt.Errorf("mode=palette-file: %w", err)
+	}
+	palette, err := readPaletteFile(path, offset, length)
+	if err != nil {
+		return nil, fmt.Errorf("mode=palette-file: %w", err)
+	}
+	return &paletteFileGen{cycleGen{palette: palette}}, nil
+}
+
+// parsePaletteFileArg splits a palette-file modeArg into its path and range;
+// length is -1 when the range runs to the end of the file. Only numeric
+// fields are taken off the end, so a path may hold colons (C:\fw.bin).
+func parsePaletteFileArg(arg string) (path string, offset, length int64, err error) {
+	path = strings.TrimSpace(arg)
+	var nums []int64
+	for len(nums) < 2 {
+		i := strings.LastIndexByte(path, ':')
+		if i < 0 {
+			break
+		}
+		n, err := strconv.ParseInt(path[i+1:], 0, 64)
+		if err != nil || n < 0 {
+			break
+		}
+		nums = append([]int64{n}, nums...)
+		path = path[:i]
+	}
+	if path == "" {
+		return "", 0, 0, errors.New("modeArg must name a file: path[:offset[:length]]")
+	}
+	offset, length = 0, -1
+	switch len(nums) {
+	case 2:
+		length = nums[1]
+		if length == 0 {
+			return "", 0, 0, fmt.Errorf("the range of %q is empty (length 0)", arg)
+		}
+		fallthrough
+	case 1:
+		offset = nums[0]
+	}
+	return path, offset, length, nil
+}
+
+// readPaletteFile returns length bytes of the file at path from offset, or
+// the rest of the file if length is -1.
+func readPaletteFile(path string, offset, length int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	size := fi.Size()
+	if offset >= size {
+		return nil, fmt.Errorf("offset %d leaves nothing of %s (%d bytes)", offset, path, size)
+	}
+	if length < 0 {
+		length = size - offset
+	}
+	if length > size-offset {
+		return nil, fmt.Errorf("%d bytes from offset %d run past the end of %s (%d bytes)", length, offset, path, size)
+	}
+	if length > maxPaletteFile {
+		return nil, fmt.Errorf("a palette of %d bytes is over the limit of %d; give a range (path:offset:length)", length, maxPaletteFile)
+	}
+	palette := make([]byte, length)
+	if _, err := f.ReadAt(palette, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return palette, nil
+}
+
+// columnBytes reports one byte per column, unless escaping makes bytes
+// from 0x80 up into escapes whose length depends on their neighbours.
+func (g *paletteFileGen) columnBytes(escape bool) int64 {
+	if escape && !isASCII(string(g.palette)) {
+		return -1
+	}
+	return 1
+}
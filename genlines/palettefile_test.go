@@ -0,0 +1,99 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePalette writes every byte value, 0x00 to 0xff, to a file in a
+// temporary directory and returns its path.
+func writePalette(t *testing.T) string {
+	t.Helper()
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	path := filepath.Join(t.TempDir(), "fw.img")
+	if err := os.WriteFile(path, all, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPaletteFile_CyclesExactBytes(t *testing.T) {
+	path := writePalette(t)
+	for _, tt := range []struct {
+		arg  string
+		want []byte
+	}{
+		{path + ":0xfd:3", []byte{0xfd, 0xfe, 0xff}},
+		{path + ":0x7e:4", []byte{0x7e, 0x7f, 0x80, 0x81}},
+		{path + ":250", []byte{250, 251, 252, 253, 254, 255}},
+	} {
+		var buf bytes.Buffer
+		opts := Options{Lines: 5, Width: 7, Mode: "palette-file", ModeArg: tt.arg, EOL: []byte{}}
+		if _, n, err := GenerateTo(context.Background(), &buf, opts); err != nil || n != 35 {
+			t.Fatalf("%s: %d bytes, %v", tt.arg, n, err)
+		}
+		want := bytes.Repeat(tt.want, 35/len(tt.want)+1)[:35]
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("%s:\n% x\nwant\n% x", tt.arg, buf.Bytes(), want)
+		}
+	}
+
+	// The whole file, continued by a start offset, with line endings.
+	var buf bytes.Buffer
+	opts := Options{Lines: 2, Width: 200, Mode: "bytefile", ModeArg: path, StartOffset: 100}
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.Bytes(); got[0] != 100 || got[155] != 255 || got[156] != 0 || got[200] != '\n' || got[201] != 44 {
+		t.Errorf("unexpected bytes % x", got[:8])
+	}
+}
+
+func TestPaletteFile_Errors(t *testing.T) {
+	path := writePalette(t)
+	for arg, want := range map[string]string{
+		"":                   "modeArg must name a file",
+		":0:4":               "modeArg must name a file",
+		path + ".missing":    "no such file",
+		filepath.Dir(path):   "is not a regular file",
+		path + ":16:0":       "is empty (length 0)",
+		path + ":256":        "offset 256 leaves nothing",
+		path + ":0x100:1":    "offset 256 leaves nothing",
+		path + ":200:57":     "57 bytes from offset 200 run past the end",
+		"C:" + path + ":x:3": "no such file", // only numeric fields are a range
+	} {
+		_, err := NewModeGenerator("palette-file", arg)
+		if err == nil || !strings.Contains(err.Error(), want) || !strings.HasPrefix(err.Error(), "mode=palette-file: ") {
+			t.Errorf("%q: %v, want %q", arg, err, want)
+		}
+	}
+
+	big := filepath.Join(t.TempDir(), "big.img")
+	os.WriteFile(big, make([]byte, maxPaletteFile+1), 0644)
+	if _, err := NewModeGenerator("palette-file", big); err == nil || !strings.Contains(err.Error(), "over the limit") {
+		t.Errorf("oversized palette: %v", err)
+	}
+	if _, err := NewModeGenerator("palette-file", big+":1"); err != nil {
+		t.Errorf("a range within the limit: %v", err)
+	}
+}
+
+func TestPaletteFile_SizeWithEscapes(t *testing.T) {
+	path := writePalette(t)
+	if n, err := PlanSize(Options{Lines: 3, Width: 10, Mode: "palette-file", ModeArg: path + ":0x80:4"}); n != 33 || err != nil {
+		t.Errorf("PlanSize = %d, %v", n, err)
+	}
+	if _, err := PlanSize(Options{Lines: 3, Width: 10, Mode: "palette-file", ModeArg: path + ":0x80:4", EscapeNonASCII: true}); err != ErrSizeUnknown {
+		t.Errorf("escaped non-ASCII bytes: %v, want ErrSizeUnknown", err)
+	}
+	if n, err := PlanSize(Options{Lines: 3, Width: 10, Mode: "palette-file", ModeArg: path + ":0x41:4", EscapeNonASCII: true}); n != 33 || err != nil {
+		t.Errorf("escaped ASCII palette: %d, %v", n, err)
+	}
+}
@@ -0,0 +1,79 @@
+package genlines
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// LinePattern lays out the data lines of a run as content and blank lines:
+// a string of C (content) and B (blank) letters, repeated over the whole
+// run, so "CCB" gives two content lines, then a blank one, and so on. A
+// blank line is only its terminator. The generator moves on only for content
+// lines, so they are the lines of a run without the pattern. The zero value
+// is off.
+type LinePattern string
+
+// Enabled reports whether p puts any blank line in a run.
+func (p LinePattern) Enabled() bool {
+	return p != ""
+}
+
+// ParseLinePattern parses a pattern of C and B letters (either case), which
+// must have at least one C.
+func ParseLinePattern(spec string) (LinePattern, error) {
+	p := LinePattern(strings.ToUpper(strings.TrimSpace(spec)))
+	if p == "" {
+		return "", errors.New("empty line pattern: give C (content) and B (blank) letters, e.g. CCB")
+	}
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+// validate checks that p is empty or a pattern ParseLinePattern accepts.
+func (p LinePattern) validate() error {
+	if p == "" {
+		return nil
+	}
+	for i := 0; i < len(p); i++ {
+		if c := p[i]; c != 'C' && c != 'B' {
+			return fmt.Errorf("invalid line pattern %q: %q is not C (content) or B (blank)", string(p), c)
+		}
+	}
+	if !strings.Contains(string(p), "C") {
+		return fmt.Errorf("invalid line pattern %q: it needs at least one C, or no line has content", string(p))
+	}
+	return nil
+}
+
+// errLinePatternUnsupported is returned for settings that plan or move lines
+// as if every line had content, or have no lines.
+var errLinePatternUnsupported = errors.New("a line pattern cannot be combined with an exact byte size, sorting, a continuation marker or mode=binrec")
+
+// validateLinePattern checks o.LinePattern (with defaults applied).
+func (o Options) validateLinePattern() error {
+	if err := o.LinePattern.validate(); err != nil {
+		return err
+	}
+	if o.LinePattern.Enabled() && (o.ExactBytes > 0 || o.Sort != SortNone || o.Continuation != "" || canonicalMode(o.Mode) == "binrec") {
+		return errLinePatternUnsupported
+	}
+	return nil
+}
+
+// blank reports whether data line n (one-based) is a blank line.
+func (p LinePattern) blank(n int64) bool {
+	return p != "" && p[(n-1)%int64(len(p))] == 'B'
+}
+
+// content returns how many of data lines 1 to lines have content.
+func (p LinePattern) content(lines int64) int64 {
+	if p == "" {
+		return lines
+	}
+	k := int64(len(p))
+	per := int64(strings.Count(string(p), "C"))
+	return lines/k*per + int64(strings.Count(string(p[:lines%k]), "C"))
+}
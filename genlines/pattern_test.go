@@ -0,0 +1,119 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLinePattern_BlankLines(t *testing.T) {
+	for _, tt := range []struct {
+		pattern string
+		lines   int
+		content int
+	}{
+		{"CCB", 10, 7},
+		{"cccCBB", 20, 14},
+		{"BC", 5, 2},
+	} {
+		p, err := ParseLinePattern(tt.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := Options{Lines: tt.lines, Width: 20, Mode: "random", ModeArg: "3", LinePattern: p}
+		var stats Stats
+		opts.Stats = &stats
+		lines := generateLines(t, opts)
+		if len(lines) != tt.lines {
+			t.Fatalf("%s: %d lines, want %d", tt.pattern, len(lines), tt.lines)
+		}
+
+		// The content lines are a run of their own.
+		var content []string
+		for i, line := range lines {
+			if blank := p[i%len(p)] == 'B'; blank != (line == "") {
+				t.Errorf("%s: line %d %q", tt.pattern, i+1, line)
+			}
+			if line != "" {
+				content = append(content, line)
+			}
+		}
+		plain := generateLines(t, Options{Lines: tt.content, Width: 20, Mode: "random", ModeArg: "3"})
+		if !slices.Equal(content, plain) {
+			t.Errorf("%s: content lines differ from a run of %d lines", tt.pattern, tt.content)
+		}
+		if stats.Lines != int64(tt.lines) || stats.BlankLines != int64(tt.lines-tt.content) {
+			t.Errorf("%s: stats %d lines, %d blank", tt.pattern, stats.Lines, stats.BlankLines)
+		}
+
+		size, err := PlanSize(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := int64(len(strings.Join(lines, "\n")) + 1); size != want {
+			t.Errorf("%s: planned %d bytes, wrote %d", tt.pattern, size, want)
+		}
+	}
+}
+
+func TestLinePattern_Composes(t *testing.T) {
+	opts := Options{Lines: 9, Width: 20, Mode: "digits", LinePattern: "CCB", CommentEvery: 3, CommentText: "# at %d",
+		LineChecksum: true, FirstLine: "FIRST", LastLine: "LAST"}
+	lines := generateLines(t, opts)
+	// Comments follow every third data line, blank ones included, and the
+	// sentinels take the first and last content lines. "*" is any content.
+	want := []string{"FIRST", "*", "", "# at 3", "*", "*", "", "# at 6", "*", "LAST", "", "# at 9"}
+	if len(lines) != len(want) {
+		t.Fatalf("%d lines: %q", len(lines), lines)
+	}
+	for i, line := range lines {
+		switch want[i] {
+		case "", "# at 3", "# at 6", "# at 9":
+			if line != want[i] {
+				t.Errorf("line %d %q, want %q", i+1, line, want[i])
+			}
+		default:
+			if !strings.HasPrefix(line, strings.TrimSuffix(want[i], "*")) || len(line) != 20 || !CheckLine(line) {
+				t.Errorf("line %d %q", i+1, line)
+			}
+		}
+	}
+
+	// Split parts continue the pattern where the last one stopped.
+	var joined bytes.Buffer
+	if _, err := GenerateSplit(context.Background(), opts, 4, func(int) (io.WriteCloser, error) {
+		return nopCloser{&joined}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if joined.String() != strings.Join(lines, "\n")+"\n" {
+		t.Error("split parts differ from the run")
+	}
+}
+
+func TestLinePattern_Rejected(t *testing.T) {
+	for _, spec := range []string{"CXB", "BBB", "C B", " "} {
+		if _, err := ParseLinePattern(spec); err == nil {
+			t.Errorf("%q accepted", spec)
+		}
+	}
+	for _, opts := range []Options{
+		{LinePattern: "BB"},
+		{LinePattern: "cb"},
+		{LinePattern: "CB", ExactBytes: 100},
+		{LinePattern: "CB", Sort: SortDesc},
+		{LinePattern: "CB", Continuation: `\`},
+		{LinePattern: "CB", Mode: "binrec"},
+	} {
+		opts.Lines, opts.Width = 5, 20
+		if _, _, err := GenerateTo(context.Background(), io.Discard, opts); err == nil {
+			t.Errorf("%+v accepted", opts)
+		}
+	}
+	if _, err := NewSeekable(Options{Lines: 5, Mode: "ascii", LinePattern: "CB"}); err == nil {
+		t.Error("NewSeekable accepted a line pattern")
+	}
+}
@@ -0,0 +1,129 @@
+package genlines
+
+// piSpigot implements a base-10 spigot algorithm for streaming digits of π.
+// Its state holds enough precision for size digits; reading past them grows
+// it (see grow), so the stream never runs out of accurate digits.
+type piSpigot struct {
+	size     int // digits the state is accurate for
+	pos      int // digits read or skipped so far
+	a        []int
+	buf      []int // digits released by the last spigot iteration
+	head     int   // next unread index in buf
+	nines    int
+	predigit int
+	started  bool
+}
+
+// newPiSpigot creates a spigot sized to generate at least the given number of digits.
+func newPiSpigot(digits int) *piSpigot {
+	a := make([]int, digits*10/3+1)
+	for i := range a {
+		a[i] = 2
+	}
+	return &piSpigot{size: digits, a: a, buf: make([]int, 0, 32)}
+}
+
+// grow makes sure the state is accurate for the next n digits. The state of
+// a spigot depends on its size from the first digit on, so a larger one is
+// computed from the start up to the current position: doubling the size each
+// time keeps that within a constant factor of sizing for every digit up front.
+func (p *piSpigot) grow(n int) {
+	need := p.pos + n
+	if need <= p.size {
+		return
+	}
+	bigger := newPiSpigot(max(2*p.size, need))
+	bigger.Skip(p.pos)
+	*p = *bigger
+}
+
+// NextDigit returns the next digit of π (0..9).
+func (p *piSpigot) NextDigit() int {
+	p.grow(1)
+	p.pos++
+	for p.head >= len(p.buf) {
+		p.step()
+	}
+	d := p.buf[p.head]
+	p.head++
+	return d
+}
+
+// Skip discards the next n digits. The spigot cannot jump ahead, so this
+// runs it through every skipped digit: O(n·state).
+func (p *piSpigot) Skip(n int) {
+	p.grow(n)
+	p.pos += max(n, 0)
+	for n > 0 {
+		if p.head >= len(p.buf) {
+			p.step()
+			continue
+		}
+		c := min(n, len(p.buf)-p.head)
+		p.head += c
+		n -= c
+	}
+}
+
+// NextDigits fills dst with the next len(dst) digits of π.
+func (p *piSpigot) NextDigits(dst []int) {
+	p.grow(len(dst))
+	p.pos += len(dst)
+	n := 0
+	for n < len(dst) {
+		if p.head >= len(p.buf) {
+			p.step()
+			continue
+		}
+		c := copy(dst[n:], p.buf[p.head:])
+		p.head += c
+		n += c
+	}
+}
+
+// step runs one spigot iteration and replaces buf with the digits it releases
+// (possibly none, while a run of nines is still pending).
+func (p *piSpigot) step() {
+	p.buf = p.buf[:0]
+	p.head = 0
+
+	q := 0
+	for i := len(p.a) - 1; i >= 0; i-- {
+		x := 10*p.a[i] + q*(i+1)
+		den := 2*(i+1) - 1
+		p.a[i] = x % den
+		q = x / den
+	}
+	p.a[0] = q % 10
+	q /= 10
+
+	switch q {
+	case 9:
+		p.nines++
+	case 10:
+		p.emit(p.predigit + 1)
+		for i := 0; i < p.nines; i++ {
+			p.emit(0)
+		}
+		p.predigit = 0
+		p.nines = 0
+	default:
+		p.emit(p.predigit)
+		for i := 0; i < p.nines; i++ {
+			p.emit(9)
+		}
+		p.predigit = q
+		p.nines = 0
+	}
+}
+
+// emit appends d to buf, dropping the leading zero predigit.
+func (p *piSpigot) emit(d int) {
+	if !p.started {
+		if d == 0 {
+			return
+		}
+		p.started = true
+	}
+	p.buf = append(p.buf, d)
+}
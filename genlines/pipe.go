@@ -0,0 +1,20 @@
+package genlines
+
+import "errors"
+
+// ErrOutputClosed marks a write that failed because the reader of the output
+// went away, as when a pipe into "head" is closed early. The run stops with
+// an error that wraps both it and the system error; whether that is a failure
+// is the caller's call, since only a stream like stdout may end that way.
+var ErrOutputClosed = errors.New("output closed by reader")
+
+// IsOutputClosed reports whether err is a broken-pipe write error: EPIPE, or
+// ERROR_BROKEN_PIPE or ERROR_NO_DATA on Windows.
+func IsOutputClosed(err error) bool {
+	for _, target := range brokenPipeErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
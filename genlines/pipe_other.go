@@ -0,0 +1,8 @@
+//go:build !windows
+
+package genlines
+
+import "syscall"
+
+// brokenPipeErrors are the write errors IsOutputClosed accepts.
+var brokenPipeErrors = []error{syscall.EPIPE}
@@ -0,0 +1,48 @@
+package genlines
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestGenerateTo_OutputClosed(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	go func() {
+		sc := bufio.NewScanner(r)
+		for i := 0; i < 5 && sc.Scan(); i++ {
+		}
+		r.Close()
+	}()
+
+	lines, _, err := GenerateTo(context.Background(), w, Options{Lines: 1000000, Width: 40})
+	if !errors.Is(err, ErrOutputClosed) || !IsOutputClosed(err) {
+		t.Fatalf("expected ErrOutputClosed, got %v", err)
+	}
+	if lines < 5 || lines >= 1000000 {
+		t.Errorf("%d lines written before the reader left", lines)
+	}
+}
+
+func TestIsOutputClosed(t *testing.T) {
+	if !IsOutputClosed(&os.PathError{Op: "write", Path: "|1", Err: syscall.EPIPE}) {
+		t.Error("EPIPE not classified as a closed output")
+	}
+	for _, err := range []error{errDiskFull, syscall.ENOSPC, syscall.EINTR, nil} {
+		if IsOutputClosed(err) {
+			t.Errorf("%v classified as a closed output", err)
+		}
+	}
+
+	fw := &failingWriter{limit: 100}
+	if _, _, err := GenerateTo(context.Background(), fw, Options{Lines: 100}); errors.Is(err, ErrOutputClosed) {
+		t.Errorf("disk full reported as a closed output: %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+package genlines
+
+import "syscall"
+
+// brokenPipeErrors are the write errors IsOutputClosed accepts. A pipe whose
+// reader has gone fails with ERROR_NO_DATA; syscall does not name it.
+var brokenPipeErrors = []error{syscall.EPIPE, syscall.ERROR_BROKEN_PIPE, syscall.Errno(232)}
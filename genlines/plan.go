@@ -0,0 +1,142 @@
+package genlines
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// errTooLarge is returned when a planned size does not fit in an int64.
+var errTooLarge = errors.New("planned output size is too large")
+
+// PlanSize returns the exact number of bytes a GenerateTo run with opts will
+// write, including terminators, comment lines, alignment padding, injected
+// code points and trailing whitespace, and stopping where MaxBytes ends the
+// run, without generating anything. Options GenerateTo would reject are reported as errors.
+func PlanSize(opts Options) (int64, error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return 0, err
+	}
+	if opts.ExactBytes > 0 {
+		return opts.ExactBytes, nil
+	}
+	if opts.Align > 0 || opts.TrailingWS.Enabled() || opts.InjectUnicode.Enabled() || opts.LinePattern.Enabled() {
+		_, _, size, err := opts.simulate()
+		return size, err
+	}
+	eol := int64(len(opts.EOL))
+	lines := int64(opts.Lines)
+	sizeOf, err := opts.dataLineSizes()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	if IsInterleaveSpec(opts.Mode) {
+		streams, err := ParseInterleave(opts.Mode)
+		if err != nil {
+			return 0, err
+		}
+		k := int64(len(streams))
+		for i := range streams {
+			count := (lines - int64(i) + k - 1) / k
+			if err := addProduct(&total, count, sizeOf(int64(i)+1)); err != nil {
+				return 0, err
+			}
+		}
+	} else if opts.Ramp.Enabled() {
+		widths, err := opts.Ramp.total(lines)
+		if err != nil {
+			return 0, err
+		}
+		f, err := opts.columnBytes(opts.Mode, opts.ModeArg)
+		if err != nil {
+			return 0, err
+		}
+		// Content columns take f bytes each; checksum and continuation
+		// columns one.
+		var fixed int64
+		if opts.LineChecksum {
+			fixed = lines * (ChecksumWidth + 1)
+		}
+		if lines > 0 {
+			fixed += (lines - 1) * int64(len(opts.Continuation))
+		}
+		if err := addProduct(&total, widths-fixed, f); err != nil {
+			return 0, err
+		}
+		if err := addProduct(&total, lines, eol); err != nil {
+			return 0, err
+		}
+		if err := addProduct(&total, 1, fixed); err != nil {
+			return 0, err
+		}
+	} else if lines > 0 {
+		// Only the last line differs, lacking a continuation marker.
+		if err := addProduct(&total, lines-1, sizeOf(1)); err != nil {
+			return 0, err
+		}
+		if err := addProduct(&total, 1, sizeOf(lines)); err != nil {
+			return 0, err
+		}
+	}
+
+	if opts.HeaderLine != "" {
+		if err := addProduct(&total, 1, int64(len(opts.HeaderLine))+eol); err != nil {
+			return 0, err
+		}
+	}
+	if opts.CommentEvery > 0 {
+		n, err := commentBytes(opts.CommentText, int64(opts.CommentEvery), lines, eol)
+		if err != nil {
+			return 0, err
+		}
+		if total > math.MaxInt64-n {
+			return 0, errTooLarge
+		}
+		total += n
+	}
+	if opts.MaxBytes > 0 && total > opts.MaxBytes {
+		_, _, total, err := opts.simulate()
+		return total, err
+	}
+	return total, nil
+}
+
+// addProduct adds a × b to *total, failing on overflow.
+func addProduct(total *int64, a, b int64) error {
+	if a == 0 || b == 0 {
+		return nil
+	}
+	if a > (math.MaxInt64-*total)/b {
+		return errTooLarge
+	}
+	*total += a * b
+	return nil
+}
+
+// commentBytes returns the bytes taken by the comment lines written after
+// every every-th of lines data lines, computed per digit-length band of the
+// line counter rather than by formatting each comment.
+func commentBytes(text string, every, lines, eol int64) (int64, error) {
+	verbs := int64(strings.Count(strings.ReplaceAll(text, "%%", ""), "%d"))
+	fixed := int64(len(formatComment(text, 0))) - verbs + eol // length without counter digits
+
+	var total int64
+	for digits, lo := int64(1), int64(1); lo <= lines; digits, lo = digits+1, lo*10 {
+		hi := lines
+		if lo <= math.MaxInt64/10 && lo*10-1 < hi {
+			hi = lo*10 - 1
+		}
+		// multiples of every within [lo, hi]
+		count := hi/every - (lo-1)/every
+		if err := addProduct(&total, count, fixed+verbs*digits); err != nil {
+			return 0, err
+		}
+		if lo > math.MaxInt64/10 {
+			break
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,101 @@
+package genlines
+
+import "math"
+
+// ContentStats profiles output as it is written: a byte histogram, the
+// number of lines and their width range. Line terminators (LF, and the CR of
+// a CRLF) delimit lines and are not counted as content. Use it as an extra
+// writer next to the real output so no second pass is needed.
+type ContentStats struct {
+	Histogram [256]int64 // occurrences of each content byte
+	Bytes     int64      // content bytes, excluding terminators
+	Lines     int64      // terminated lines, plus a trailing partial line if any
+
+	MinLineWidth int64 // narrowest line in bytes (0 if no lines)
+	MaxLineWidth int64 // widest line in bytes
+
+	cur       int64 // bytes in the current line so far
+	inLine    bool  // the current line has started
+	pendingCR bool  // a CR waiting to see whether an LF follows
+}
+
+// Write records p. It never fails.
+func (s *ContentStats) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if s.pendingCR {
+			s.pendingCR = false
+			if b == '\n' {
+				s.endLine()
+				continue
+			}
+			s.count('\r')
+		}
+		switch b {
+		case '\n':
+			s.endLine()
+		case '\r':
+			s.inLine = true
+			s.pendingCR = true
+		default:
+			s.count(b)
+		}
+	}
+	return len(p), nil
+}
+
+func (s *ContentStats) count(b byte) {
+	s.Histogram[b]++
+	s.Bytes++
+	s.cur++
+	s.inLine = true
+}
+
+func (s *ContentStats) endLine() {
+	if s.Lines == 0 || s.cur < s.MinLineWidth {
+		s.MinLineWidth = s.cur
+	}
+	s.MaxLineWidth = max(s.MaxLineWidth, s.cur)
+	s.Lines++
+	s.cur, s.inLine = 0, false
+}
+
+// Finish accounts for a trailing line without terminator (and a trailing
+// lone CR). Call it once after the last Write.
+func (s *ContentStats) Finish() {
+	if s.pendingCR {
+		s.pendingCR = false
+		s.count('\r')
+	}
+	if s.inLine {
+		s.endLine()
+	}
+}
+
+// Distinct returns the number of different content bytes seen.
+func (s *ContentStats) Distinct() int {
+	n := 0
+	for _, c := range s.Histogram {
+		if c > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Entropy returns the Shannon entropy of the content in bits per byte
+// (0 for a single repeated byte, 8 for uniformly random bytes), a rough
+// estimate of how well the content compresses.
+func (s *ContentStats) Entropy() float64 {
+	if s.Bytes == 0 {
+		return 0
+	}
+	total := float64(s.Bytes)
+	var h float64
+	for _, c := range s.Histogram {
+		if c > 0 {
+			p := float64(c) / total
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
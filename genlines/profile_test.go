@@ -0,0 +1,51 @@
+package genlines
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func profile(t *testing.T, opts Options) *ContentStats {
+	t.Helper()
+	s := &ContentStats{}
+	if _, _, err := GenerateTo(context.Background(), s, opts); err != nil {
+		t.Fatalf("GenerateTo: %v", err)
+	}
+	s.Finish()
+	return s
+}
+
+func TestContentStats_Digits(t *testing.T) {
+	s := profile(t, Options{Lines: 100, Width: 37, Mode: "digits"})
+	if s.Distinct() != 10 {
+		t.Errorf("Distinct = %d, want 10", s.Distinct())
+	}
+	if s.Lines != 100 || s.Bytes != 3700 || s.MinLineWidth != 37 || s.MaxLineWidth != 37 {
+		t.Errorf("stats = %d lines, %d bytes, width %d..%d", s.Lines, s.Bytes, s.MinLineWidth, s.MaxLineWidth)
+	}
+	if math.Abs(s.Entropy()-math.Log2(10)) > 0.01 {
+		t.Errorf("Entropy = %f, want about %f", s.Entropy(), math.Log2(10))
+	}
+}
+
+func TestContentStats_CharHasNoEntropy(t *testing.T) {
+	s := profile(t, Options{Lines: 50, Width: 80, Mode: "char", ModeArg: "#", EOL: []byte("\r\n")})
+	if s.Entropy() != 0 || s.Distinct() != 1 || s.Histogram['#'] != 4000 {
+		t.Errorf("Entropy = %f, Distinct = %d, '#' = %d", s.Entropy(), s.Distinct(), s.Histogram['#'])
+	}
+}
+
+func TestContentStats_LineWidthsAndPartialLine(t *testing.T) {
+	s := &ContentStats{}
+	s.Write([]byte("ab\r\nabcd\n"))
+	s.Write([]byte("x\ry\r"))
+	s.Finish()
+
+	if s.Lines != 3 || s.MinLineWidth != 2 || s.MaxLineWidth != 4 {
+		t.Errorf("lines=%d width %d..%d; want 3 lines, width 2..4", s.Lines, s.MinLineWidth, s.MaxLineWidth)
+	}
+	if s.Histogram['\r'] != 2 || s.Bytes != 10 {
+		t.Errorf("lone CRs = %d, bytes = %d; want 2 and 10", s.Histogram['\r'], s.Bytes)
+	}
+}
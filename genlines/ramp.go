@@ -0,0 +1,139 @@
+package genlines
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Ramp makes line widths grow instead of staying fixed: line K (one-based) is
+// Min + (K-1)*Step columns wide, capped at Max. Without Reset the remaining
+// lines stay at Max; with Reset the ramp starts over at Min after the first
+// line at Max. The zero Ramp is disabled.
+type Ramp struct {
+	Min, Max, Step int
+	Reset          bool
+}
+
+// ParseRamp parses a ramp spec of the form min:max:step[:reset], optionally
+// prefixed with "ramp:", e.g. "ramp:10:500:5".
+func ParseRamp(s string) (Ramp, error) {
+	s = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "ramp:")
+	fields := strings.Split(s, ":")
+	if len(fields) < 3 || len(fields) > 4 {
+		return Ramp{}, fmt.Errorf("invalid ramp %q: expected min:max:step[:reset]", s)
+	}
+
+	var nums [3]int
+	for i, f := range fields[:3] {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return Ramp{}, fmt.Errorf("invalid ramp %q: %q is not a number", s, f)
+		}
+		nums[i] = n
+	}
+	r := Ramp{Min: nums[0], Max: nums[1], Step: nums[2]}
+	if len(fields) == 4 {
+		if strings.TrimSpace(fields[3]) != "reset" {
+			return Ramp{}, fmt.Errorf("invalid ramp %q: the fourth field can only be reset", s)
+		}
+		r.Reset = true
+	}
+	if err := r.validate(); err != nil {
+		return Ramp{}, err
+	}
+	return r, nil
+}
+
+// String returns r as a spec accepted by ParseRamp.
+func (r Ramp) String() string {
+	s := fmt.Sprintf("%d:%d:%d", r.Min, r.Max, r.Step)
+	if r.Reset {
+		s += ":reset"
+	}
+	return s
+}
+
+// Enabled reports whether r is set.
+func (r Ramp) Enabled() bool {
+	return r != Ramp{}
+}
+
+// validate checks an enabled ramp.
+func (r Ramp) validate() error {
+	switch {
+	case r.Min < 1:
+		return fmt.Errorf("invalid ramp %s: min must be at least 1", r)
+	case r.Max < r.Min:
+		return fmt.Errorf("invalid ramp %s: max must not be below min", r)
+	case r.Step < 1:
+		return fmt.Errorf("invalid ramp %s: step must be at least 1", r)
+	}
+	return nil
+}
+
+// rising returns the number of lines before the first one at Max.
+func (r Ramp) rising() int64 {
+	return (int64(r.Max-r.Min) + int64(r.Step) - 1) / int64(r.Step)
+}
+
+// Width returns the width of line k (one-based).
+func (r Ramp) Width(k int64) int {
+	rising := r.rising()
+	i := k - 1
+	if r.Reset {
+		i %= rising + 1
+	}
+	if i >= rising {
+		return r.Max
+	}
+	return r.Min + int(i)*r.Step
+}
+
+// total returns the summed widths of lines 1..n.
+func (r Ramp) total(n int64) (int64, error) {
+	rising := r.rising()
+	if r.Reset {
+		var cycle int64
+		if err := r.risingSum(&cycle, rising); err != nil {
+			return 0, err
+		}
+		if err := addProduct(&cycle, 1, int64(r.Max)); err != nil {
+			return 0, err
+		}
+		var total int64
+		if err := addProduct(&total, n/(rising+1), cycle); err != nil {
+			return 0, err
+		}
+		return total, r.risingSum(&total, n%(rising+1))
+	}
+
+	var total int64
+	if err := r.risingSum(&total, min(n, rising)); err != nil {
+		return 0, err
+	}
+	return total, addProduct(&total, max(n-rising, 0), int64(r.Max))
+}
+
+// risingSum adds the widths of the first n lines of the ramp, all below Max,
+// to *total: n*Min + Step*n*(n-1)/2.
+func (r Ramp) risingSum(total *int64, n int64) error {
+	if err := addProduct(total, n, int64(r.Min)); err != nil {
+		return err
+	}
+	a, b := n, n-1
+	if a%2 == 0 {
+		a /= 2
+	} else {
+		b /= 2
+	}
+	var tri int64
+	if err := addProduct(&tri, a, b); err != nil {
+		return err
+	}
+	return addProduct(total, tri, int64(r.Step))
+}
+
+// errRampUnsupported is returned for features that need a fixed line width.
+var errRampUnsupported = errors.New("a width ramp cannot be combined with interleave specs or an exact byte size")
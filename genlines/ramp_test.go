@@ -0,0 +1,98 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseRamp(t *testing.T) {
+	r, err := ParseRamp("ramp:10:500:5")
+	if err != nil || r != (Ramp{Min: 10, Max: 500, Step: 5}) {
+		t.Fatalf("got %+v, %v", r, err)
+	}
+	r, err = ParseRamp("1:4:1:reset")
+	if err != nil || r != (Ramp{Min: 1, Max: 4, Step: 1, Reset: true}) {
+		t.Fatalf("got %+v, %v", r, err)
+	}
+	if r.String() != "1:4:1:reset" {
+		t.Errorf("String() = %q", r.String())
+	}
+	for _, s := range []string{"", "10:500", "0:5:1", "10:5:1", "1:5:0", "1:5:x", "1:5:1:again", "1:2:3:reset:5"} {
+		if _, err := ParseRamp(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestRamp_WidthSequence(t *testing.T) {
+	tests := []struct {
+		ramp Ramp
+		want []int
+	}{
+		{Ramp{Min: 10, Max: 20, Step: 5}, []int{10, 15, 20, 20, 20}},
+		{Ramp{Min: 1, Max: 10, Step: 4}, []int{1, 5, 9, 10, 10}},
+		{Ramp{Min: 1, Max: 10, Step: 4, Reset: true}, []int{1, 5, 9, 10, 1, 5, 9, 10, 1}},
+		{Ramp{Min: 3, Max: 3, Step: 1, Reset: true}, []int{3, 3, 3}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		opts := Options{Lines: len(tt.want), Mode: "digits", Ramp: tt.ramp}
+		if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+			t.Fatalf("%s: %v", tt.ramp, err)
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		for i, line := range lines {
+			if len(line) != tt.want[i] {
+				t.Errorf("%s line %d: width %d, want %d", tt.ramp, i+1, len(line), tt.want[i])
+			}
+		}
+		// Content flows on across the varying widths.
+		joined := strings.Join(lines, "")
+		for i := range joined {
+			if joined[i] != byte('0'+i%10) {
+				t.Fatalf("%s: content breaks at offset %d: %q", tt.ramp, i, joined)
+			}
+		}
+	}
+}
+
+func TestRamp_SizeMatchesPlan(t *testing.T) {
+	for _, opts := range []Options{
+		{Lines: 1000, Ramp: Ramp{Min: 10, Max: 500, Step: 5}},
+		{Lines: 1000, Ramp: Ramp{Min: 10, Max: 500, Step: 7, Reset: true}},
+		{Lines: 97, Ramp: Ramp{Min: 1, Max: 1000, Step: 3}, EOL: []byte("\r\n")},
+		{Lines: 250, Ramp: Ramp{Min: 12, Max: 40, Step: 2, Reset: true}, CommentEvery: 9, LineChecksum: true},
+	} {
+		planned, err := PlanSize(opts)
+		if err != nil {
+			t.Fatalf("PlanSize(%s): %v", opts.Ramp, err)
+		}
+		var buf bytes.Buffer
+		_, written, err := GenerateTo(context.Background(), &buf, opts)
+		if err != nil {
+			t.Fatalf("GenerateTo(%s): %v", opts.Ramp, err)
+		}
+		if written != planned || int64(buf.Len()) != planned {
+			t.Errorf("%s: planned %d, wrote %d (%d buffered)", opts.Ramp, planned, written, buf.Len())
+		}
+	}
+}
+
+func TestRamp_Unsupported(t *testing.T) {
+	ramp := Ramp{Min: 10, Max: 20, Step: 1}
+	for _, opts := range []Options{
+		{Lines: 1, Mode: "digits:5+ascii:5", Ramp: ramp},
+		{ExactBytes: 100, Ramp: ramp},
+		{Lines: 1, LineChecksum: true, Ramp: Ramp{Min: 5, Max: 20, Step: 1}},
+		{Lines: 1, Ramp: Ramp{Min: 5, Max: 4, Step: 1}},
+	} {
+		if _, err := PlanSize(opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+	if _, err := NewSeekable(Options{Lines: 1, Ramp: ramp}); err == nil {
+		t.Errorf("expected NewSeekable to reject a ramp")
+	}
+}
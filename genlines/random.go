@@ -0,0 +1,51 @@
+package genlines
+
+import (
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ParseSeed parses a decimal uint64 seed as used by the random mode.
+func ParseSeed(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("a numeric seed is required")
+	}
+	seed, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, errors.New("seed must be a non-negative integer: " + s)
+	}
+	return seed, nil
+}
+
+// randomGen emits palette characters chosen by a seeded PCG generator.
+// Characters are picked with Uint64()%len(palette) rather than IntN so the
+// output depends only on the PCG algorithm and stays stable across Go releases.
+type randomGen struct {
+	src     *rand.PCG
+	palette []byte
+}
+
+func newRandomGen(seed uint64, palette []byte) *randomGen {
+	return &randomGen{src: rand.NewPCG(seed, seed), palette: palette}
+}
+
+func (g *randomGen) NextLine(width int) string {
+	if width <= 0 {
+		return ""
+	}
+	return string(g.AppendLine(make([]byte, 0, width), width))
+}
+
+// AppendLine appends the next width characters to dst.
+func (g *randomGen) AppendLine(dst []byte, width int) []byte {
+	n := uint64(len(g.palette))
+	dst = slices.Grow(dst, max(width, 0))
+	for range max(width, 0) {
+		dst = append(dst, g.palette[g.src.Uint64()%n])
+	}
+	return dst
+}
@@ -0,0 +1,64 @@
+package genlines
+
+import (
+	"fmt"
+	"io"
+)
+
+// lineReader is an io.Reader producing generated lines on demand.
+type lineReader struct {
+	gen       Generator
+	width     int
+	remaining int
+	eol       []byte
+	line      []byte // current line, terminator included
+	off       int    // read offset into line
+	n         int    // lines produced so far
+}
+
+// NewReader returns an io.Reader that lazily yields lines lines of gen output,
+// each width columns wide and followed by eol (default "\n").
+//
+// Reads smaller than a line are served from the remainder of the current
+// line; io.EOF is returned once the final terminator has been read. A line
+// that fails to render (see mode=template) ends the stream with its error.
+func NewReader(gen Generator, width int, lines int, eol []byte) io.Reader {
+	if eol == nil {
+		eol = []byte("\n")
+	}
+	if width <= 0 {
+		width = DefaultWidth
+	}
+	if lines < 0 {
+		lines = 0
+	}
+	return &lineReader{gen: gen, width: width, remaining: lines, eol: eol}
+}
+
+func (r *lineReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if r.off == len(r.line) {
+			if r.remaining == 0 {
+				break
+			}
+			line := r.gen.NextLine(r.width)
+			r.n++
+			if f, ok := r.gen.(failingGenerator); ok && f.Err() != nil {
+				r.remaining = 0
+				return n, fmt.Errorf("line %d: %w", r.n, f.Err())
+			}
+			r.line = append(append(r.line[:0], line...), r.eol...)
+			r.off = 0
+			r.remaining--
+		}
+		c := copy(p[n:], r.line[r.off:])
+		r.off += c
+		n += c
+	}
+
+	if n == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,64 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+func TestNewReader_MatchesGenerateTo(t *testing.T) {
+	const lines, width = 200, 80
+
+	var want bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &want, Options{Lines: lines, Width: width, Mode: "ascii"}); err != nil {
+		t.Fatalf("GenerateTo: %v", err)
+	}
+
+	for _, size := range []int{1, 7, 1 << 20} {
+		gen, err := NewGenerator("ascii", "", lines*width)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		r := NewReader(gen, width, lines, nil)
+
+		var got bytes.Buffer
+		buf := make([]byte, size)
+		for {
+			n, err := r.Read(buf)
+			got.Write(buf[:n])
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("buffer %d: unexpected err: %v", size, err)
+			}
+		}
+
+		if !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Fatalf("buffer %d: reader output differs from GenerateTo (%d vs %d bytes)", size, got.Len(), want.Len())
+		}
+	}
+}
+
+func TestNewReader_EOFAfterFinalTerminator(t *testing.T) {
+	gen, _ := NewGenerator("digits", "", 6)
+	r := NewReader(gen, 3, 2, []byte("\r\n"))
+
+	buf := make([]byte, 10)
+	n, err := r.Read(buf)
+	if err != nil || string(buf[:n]) != "012\r\n345\r\n" {
+		t.Fatalf("unexpected first read: %q, %v", buf[:n], err)
+	}
+	if n, err := r.Read(buf); n != 0 || err != io.EOF {
+		t.Fatalf("expected 0, io.EOF after final terminator, got %d, %v", n, err)
+	}
+}
+
+func TestNewReader_ZeroLines(t *testing.T) {
+	gen, _ := NewGenerator("digits", "", 0)
+	got, err := io.ReadAll(NewReader(gen, 10, 0, nil))
+	if err != nil || len(got) != 0 {
+		t.Fatalf("expected empty stream, got %q, %v", got, err)
+	}
+}
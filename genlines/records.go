@@ -0,0 +1,370 @@
+package genlines
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultCSVColumns is the number of fields per csv record.
+	DefaultCSVColumns = 4
+
+	// jsonlOverhead is the size of a jsonl record without its text and id.
+	jsonlOverhead = len(`{"id":,"text":""}`)
+	// minJSONLWidth fits a record with the largest id and an empty text.
+	minJSONLWidth = jsonlOverhead + 20
+
+	// DefaultSchemaStrLen is the length of a jsonl schema str field given
+	// without one.
+	DefaultSchemaStrLen = 8
+)
+
+// formulaChars start the fields that spreadsheets evaluate as formulas (CSV
+// injection). Safe csv fields never start with one.
+const formulaChars = "=+-@"
+
+// csvDelimNames are the names a csv delim option may use instead of the
+// character itself.
+var csvDelimNames = map[string]byte{"comma": ',', "semicolon": ';', "tab": '\t', `\t`: '\t', "pipe": '|', "space": ' '}
+
+// fieldChars is the alphabet of generated field content: letters and digits
+// only, so fields never need quoting or escaping on their own.
+const fieldChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// recordArgs holds the options of the record modes (csv, jsonl), given in
+// their modeArg as key=value pairs separated by semicolons.
+type recordArgs struct {
+	cols      int
+	multiline int  // every multiline-th record holds an embedded newline; 0 = never
+	delim     byte // csv field separator
+	schema    []schemaField
+}
+
+// schemaField is one member of a jsonl schema, name:type[:length].
+type schemaField struct {
+	name string
+	key  string // name as a JSON string
+	kind string // int, str, bool or float
+	size int    // length of a str
+}
+
+// parseSchema parses a jsonl schema: comma-separated name:type fields, with
+// an optional length for str (name:str:12).
+func parseSchema(spec string) ([]schemaField, error) {
+	var fields []schemaField
+	seen := map[string]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		name, rest, _ := strings.Cut(strings.TrimSpace(part), ":")
+		kind, size, hasSize := strings.Cut(rest, ":")
+		f := schemaField{name: strings.TrimSpace(name), kind: strings.ToLower(strings.TrimSpace(kind))}
+		if f.name == "" {
+			return nil, fmt.Errorf("schema field %q has no name", part)
+		}
+		if seen[f.name] {
+			return nil, fmt.Errorf("schema field %q appears twice", f.name)
+		}
+		seen[f.name] = true
+		switch f.kind {
+		case "int", "bool", "float":
+			if hasSize {
+				return nil, fmt.Errorf("schema field %q: only str takes a length", f.name)
+			}
+		case "str":
+			f.size = DefaultSchemaStrLen
+			if hasSize {
+				n, err := strconv.Atoi(strings.TrimSpace(size))
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("schema field %q: invalid length %q", f.name, size)
+				}
+				f.size = n
+			}
+		case "":
+			return nil, fmt.Errorf("schema field %q has no type (expected int, str, bool or float)", f.name)
+		default:
+			return nil, fmt.Errorf("schema field %q: unknown type %q (expected int, str, bool or float)", f.name, kind)
+		}
+		key, _ := json.Marshal(f.name)
+		f.key = string(key)
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// parseRecordArgs parses the modeArg of mode. keys lists the options mode
+// accepts. A delim option may name the semicolon itself: "delim=;" ends the
+// option, and a separator right after it is optional.
+func parseRecordArgs(mode, arg string, keys ...string) (recordArgs, error) {
+	ra := recordArgs{cols: DefaultCSVColumns, delim: ','}
+	for rest := arg; rest != ""; {
+		var kv string
+		kv, rest, _ = strings.Cut(rest, ";")
+		if strings.TrimSpace(kv) == "" {
+			continue
+		}
+		key, value, hasValue := strings.Cut(kv, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !hasValue && strings.Contains(kv, ":") {
+			key, value = "schema", kv // a bare schema
+		}
+		known := false
+		for _, k := range keys {
+			known = known || k == key
+		}
+		if !known {
+			return ra, fmt.Errorf("mode=%s: unknown option %q (expected %s)", mode, key, strings.Join(keys, ", "))
+		}
+		if key == "schema" {
+			schema, err := parseSchema(value)
+			if err != nil {
+				return ra, fmt.Errorf("mode=%s: %w", mode, err)
+			}
+			ra.schema = schema
+			continue
+		}
+		if key == "delim" {
+			if value == "" {
+				value, rest = ";", strings.TrimPrefix(rest, ";")
+			}
+			d, err := parseCSVDelim(value)
+			if err != nil {
+				return ra, fmt.Errorf("mode=%s: %w", mode, err)
+			}
+			ra.delim = d
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n <= 0 {
+			return ra, fmt.Errorf("mode=%s: invalid %s: %q (expected a positive integer)", mode, key, value)
+		}
+		switch key {
+		case "cols":
+			ra.cols = n
+		case "multiline":
+			ra.multiline = n
+		}
+	}
+	if ra.multiline > 0 && len(ra.schema) == 1 {
+		return ra, fmt.Errorf("mode=%s: multiline needs a schema of at least two fields", mode)
+	}
+	return ra, nil
+}
+
+// parseCSVDelim parses the value of a csv delim option: one printable ASCII
+// character that cannot occur in field content, or a name from csvDelimNames.
+func parseCSVDelim(value string) (byte, error) {
+	if len(value) != 1 {
+		if d, ok := csvDelimNames[strings.ToLower(strings.TrimSpace(value))]; ok {
+			return d, nil
+		}
+		return 0, fmt.Errorf("invalid delim: %q (expected one character, or comma, semicolon, tab, pipe or space)", value)
+	}
+	d := value[0]
+	switch {
+	case d == '"':
+		return 0, errors.New("invalid delim: the double quote is the quote character")
+	case strings.IndexByte(fieldChars, d) >= 0:
+		return 0, fmt.Errorf("invalid delim: %q is a field character", value)
+	case d != '\t' && (d < ' ' || d > '~'):
+		return 0, fmt.Errorf("invalid delim: %q (expected a printable ASCII character or tab)", value)
+	}
+	return d, nil
+}
+
+// recordGen is the state shared by the record modes: the record counter and
+// the cycle that field content is drawn from.
+type recordGen struct {
+	args   recordArgs
+	record int64
+	fill   cycleGen
+}
+
+// next advances to the next record and reports whether it is one of the
+// records that span two physical lines.
+func (g *recordGen) next() bool {
+	g.record++
+	return g.args.multiline > 0 && g.record%int64(g.args.multiline) == 0
+}
+
+// multilineField returns a field of width characters, quoted with q, whose
+// content is split by an embedded newline. width must be at least 4.
+func (g *recordGen) multilineField(width int, q byte) string {
+	inner := width - 3 // quotes and newline
+	head := inner / 2
+	return string(q) + g.fill.NextLine(head) + "\n" + g.fill.NextLine(inner-head) + string(q)
+}
+
+// newCSVGen writes records of cols delimited fields (modeArg:
+// cols=N;multiline=K;delim=C). Every record is exactly width characters, the
+// embedded newline of a multiline record included.
+func newCSVGen(arg string, _ int) (Generator, error) {
+	ra, err := parseRecordArgs("csv", arg, "cols", "multiline", "delim")
+	if err != nil {
+		return nil, err
+	}
+	return &csvGen{recordGen: recordGen{args: ra, fill: cycleGen{palette: []byte(fieldChars)}}}, nil
+}
+
+type csvGen struct {
+	recordGen
+	unsafe bool  // start every other field with a formula character
+	field  int64 // fields written, for unsafe
+}
+
+func (g *csvGen) allowUnsafe() { g.unsafe = true }
+
+// checkWidth requires one character per field (four for the first field of
+// multiline records) plus the separators.
+func (g *csvGen) checkWidth(width int) error {
+	need := 2*g.args.cols - 1
+	if g.args.multiline > 0 {
+		need += 3
+	}
+	if width < need {
+		return fmt.Errorf("mode=csv: %d columns need a width of at least %d, got %d", g.args.cols, need, width)
+	}
+	return nil
+}
+
+func (g *csvGen) NextLine(width int) string {
+	if width <= 0 {
+		return ""
+	}
+	multi := g.next()
+	cols := g.args.cols
+	avail := max(width-(cols-1), 0) // below checkWidth, fields are empty
+	var b strings.Builder
+	b.Grow(width)
+	for i := 0; i < cols; i++ {
+		w := avail / cols
+		if i == 0 {
+			w += avail % cols // the first field takes the remainder
+		}
+		if i > 0 {
+			b.WriteByte(g.args.delim)
+		}
+		if multi && i == 0 {
+			b.WriteString(g.multilineField(w, '"'))
+		} else {
+			b.WriteString(g.nextField(w))
+		}
+	}
+	return b.String()
+}
+
+// nextField returns a field of width characters. Unsafe, every other field
+// starts with a formula character, cycling through formulaChars; one that is
+// the delimiter makes the field quoted, which needs 3 characters.
+func (g *csvGen) nextField(width int) string {
+	g.field++
+	if !g.unsafe || g.field%2 == 1 {
+		return g.fill.NextLine(width)
+	}
+	f := formulaChars[(g.field/2-1)%int64(len(formulaChars))]
+	switch {
+	case f != g.args.delim:
+		return string(f) + g.fill.NextLine(width-1)
+	case width >= 3:
+		return `"` + string(f) + g.fill.NextLine(width-3) + `"`
+	default:
+		return g.fill.NextLine(width)
+	}
+}
+
+// newJSONLGen writes one JSON object per record, {"id":N,"text":"..."} with
+// the text sized so the record is exactly width characters (modeArg:
+// multiline=K). A multiline record breaks the object after the id member, so
+// it is still valid JSON but spans two physical lines. With a schema
+// (modeArg: name:type,...) the objects have its fields instead, and the width
+// is not used.
+func newJSONLGen(arg string, _ int) (Generator, error) {
+	ra, err := parseRecordArgs("jsonl", arg, "multiline", "schema")
+	if err != nil {
+		return nil, err
+	}
+	return &jsonlGen{
+		recordGen: recordGen{args: ra, fill: cycleGen{palette: []byte(fieldChars)}},
+		ascii:     cycleGen{palette: []byte(AsciiSequence())},
+	}, nil
+}
+
+type jsonlGen struct {
+	recordGen
+	ascii cycleGen // schema str content
+}
+
+// columnBytes is -1 with a schema: record sizes follow the fields, not the width.
+func (g *jsonlGen) columnBytes(bool) int64 {
+	if g.args.schema != nil {
+		return -1
+	}
+	return 1
+}
+
+func (g *jsonlGen) checkWidth(width int) error {
+	if g.args.schema != nil {
+		return nil
+	}
+	if width < minJSONLWidth {
+		return fmt.Errorf("mode=jsonl needs a width of at least %d, got %d", minJSONLWidth, width)
+	}
+	return nil
+}
+
+func (g *jsonlGen) NextLine(width int) string {
+	if g.args.schema != nil {
+		return g.schemaRecord(g.next())
+	}
+	if width <= 0 {
+		return ""
+	}
+	multi := g.next()
+	id := strconv.FormatInt(g.record, 10)
+	sep := ","
+	if multi {
+		sep = ",\n"
+	}
+	text := width - jsonlOverhead - len(id) - len(sep) + 1
+	return `{"id":` + id + sep + `"text":"` + g.fill.NextLine(max(text, 0)) + `"}`
+}
+
+// schemaRecord returns the current record as an object with the schema's
+// fields: ints are the record number, strs the next characters of the ASCII
+// cycle, bools alternate starting with true, and floats are the record
+// number / 1000 with three decimals. A multiline record breaks after the
+// first member.
+func (g *jsonlGen) schemaRecord(multi bool) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, f := range g.args.schema {
+		if i > 0 {
+			b.WriteByte(',')
+			if multi && i == 1 {
+				b.WriteByte('\n')
+			}
+		}
+		b.WriteString(f.key)
+		b.WriteByte(':')
+		switch f.kind {
+		case "int":
+			b.WriteString(strconv.FormatInt(g.record, 10))
+		case "str":
+			b.WriteByte('"')
+			for _, c := range []byte(g.ascii.NextLine(f.size)) {
+				if c == '"' || c == '\\' {
+					b.WriteByte('\\')
+				}
+				b.WriteByte(c)
+			}
+			b.WriteByte('"')
+		case "bool":
+			b.WriteString(strconv.FormatBool(g.record%2 == 1))
+		case "float":
+			b.WriteString(strconv.FormatFloat(float64(g.record)/1000, 'f', 3, 64))
+		}
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -0,0 +1,263 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCSV_MultilineRecords(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{Lines: 100, Width: 30, Mode: "csv", ModeArg: "cols=5;multiline=7"}
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	if physical := strings.Count(buf.String(), "\n"); physical != 100+100/7 {
+		t.Errorf("%d physical lines, want %d", physical, 100+100/7)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 100 {
+		t.Fatalf("%d records, want 100", len(records))
+	}
+	for i, rec := range records {
+		if len(rec) != 5 {
+			t.Fatalf("record %d has %d fields", i+1, len(rec))
+		}
+		multi := strings.Contains(rec[0], "\n")
+		if want := (i+1)%7 == 0; multi != want {
+			t.Errorf("record %d: embedded newline %v, want %v", i+1, multi, want)
+		}
+	}
+}
+
+func TestCSV_WidthAndOptions(t *testing.T) {
+	g, err := NewGenerator("csv", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := g.NextLine(13); len(line) != 13 || strings.Count(line, ",") != 3 {
+		t.Errorf("unexpected record %q", line)
+	}
+	if _, err := PlanSize(Options{Lines: 1, Width: 8, Mode: "csv", ModeArg: "cols=5"}); err == nil {
+		t.Error("expected an error for a width below the column count")
+	}
+	for _, arg := range []string{"rows=3", "cols=0", "multiline=x"} {
+		if _, err := NewGenerator("csv", arg, 0); err == nil {
+			t.Errorf("%q: expected an error", arg)
+		}
+	}
+}
+
+func TestJSONL_MultilineRecords(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{Lines: 50, Width: 60, Mode: "jsonl", ModeArg: "multiline=10"}
+	size, _ := PlanSize(opts)
+	if _, n, err := GenerateTo(context.Background(), &buf, opts); err != nil || n != size {
+		t.Fatalf("wrote %d bytes (planned %d): %v", n, size, err)
+	}
+	if physical := strings.Count(buf.String(), "\n"); physical != 55 {
+		t.Errorf("%d physical lines, want 55", physical)
+	}
+
+	dec := json.NewDecoder(&buf)
+	for id := 1; ; id++ {
+		var rec struct {
+			ID   int    `json:"id"`
+			Text string `json:"text"`
+		}
+		if err := dec.Decode(&rec); err == io.EOF {
+			if id != 51 {
+				t.Errorf("decoded %d records, want 50", id-1)
+			}
+			break
+		} else if err != nil {
+			t.Fatalf("record %d: %v", id, err)
+		}
+		if rec.ID != id {
+			t.Errorf("record %d has id %d", id, rec.ID)
+		}
+	}
+	if _, err := PlanSize(Options{Lines: 1, Width: 20, Mode: "jsonl"}); err == nil {
+		t.Error("expected an error for a narrow jsonl width")
+	}
+}
+
+func TestCSV_DelimiterOption(t *testing.T) {
+	for arg, want := range map[string]byte{
+		"":                ',',
+		"cols=5;delim=;":  ';',
+		"delim=;;cols=5":  ';',
+		"delim=;cols=5":   ';',
+		"delim=tab":       '\t',
+		"delim=\t;cols=2": '\t',
+		"delim=|":         '|',
+		"delim=space":     ' ',
+	} {
+		ra, err := parseRecordArgs("csv", arg, "cols", "multiline", "delim")
+		if err != nil || ra.delim != want {
+			t.Errorf("%q: delim %q, %v; want %q", arg, ra.delim, err, want)
+		}
+	}
+	for _, arg := range []string{`delim="`, "delim=x", "delim=7", "delim=ab", "delim=\x01"} {
+		if _, err := NewGenerator("csv", arg, 0); err == nil {
+			t.Errorf("%q: expected an error", arg)
+		}
+	}
+	if _, err := NewGenerator("jsonl", "delim=;", 0); err == nil {
+		t.Error("jsonl should not accept a delim")
+	}
+}
+
+// readTSV generates a large tab-separated csv sample and parses it.
+func readTSV(t *testing.T, opts Options) [][]string {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	r := csv.NewReader(&buf)
+	r.Comma = '\t'
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != opts.Lines {
+		t.Fatalf("%d records, want %d", len(records), opts.Lines)
+	}
+	return records
+}
+
+func TestCSV_NoFormulaFieldsUnlessUnsafe(t *testing.T) {
+	opts := Options{Lines: 5000, Width: 47, Mode: "csv", ModeArg: "cols=7;multiline=13;delim=tab"}
+	for i, rec := range readTSV(t, opts) {
+		if len(rec) != 7 {
+			t.Fatalf("record %d has %d fields", i+1, len(rec))
+		}
+		for _, field := range rec {
+			if field != "" && strings.ContainsRune(formulaChars, rune(field[0])) {
+				t.Fatalf("record %d has a formula field %q", i+1, field)
+			}
+		}
+	}
+
+	opts.Unsafe = true
+	starts := map[byte]int{}
+	for _, rec := range readTSV(t, opts) {
+		for _, field := range rec {
+			if strings.ContainsRune(formulaChars, rune(field[0])) {
+				starts[field[0]]++
+			}
+		}
+	}
+	for _, c := range []byte(formulaChars) {
+		if starts[c] < 1000 {
+			t.Errorf("unsafe: %d fields start with %q, want every formula character often", starts[c], c)
+		}
+	}
+}
+
+func TestCSV_UnsafeQuotesFieldsHoldingTheDelimiter(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{Lines: 40, Width: 23, Mode: "csv", ModeArg: "cols=4;delim=-", Unsafe: true}
+	size, _ := PlanSize(opts)
+	if _, n, err := GenerateTo(context.Background(), &buf, opts); err != nil || n != size {
+		t.Fatalf("wrote %d bytes (planned %d): %v", n, size, err)
+	}
+	r := csv.NewReader(&buf)
+	r.Comma = '-'
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dashes := 0
+	for i, rec := range records {
+		if len(rec) != 4 {
+			t.Fatalf("record %d has %d fields: %q", i+1, len(rec), rec)
+		}
+		for _, field := range rec {
+			if strings.HasPrefix(field, "-") {
+				dashes++
+			}
+		}
+	}
+	if dashes == 0 {
+		t.Error("no field starts with the delimiter -")
+	}
+}
+
+func TestJSONL_Schema(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{Lines: 30, Width: 10, Mode: "jsonl", ModeArg: "id:int,name:str:12,flag:bool,score:float,tag:str"}
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PlanSize(opts); !errors.Is(err, ErrSizeUnknown) {
+		t.Errorf("PlanSize err = %v, want ErrSizeUnknown", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 30 {
+		t.Fatalf("%d lines, want 30", len(lines))
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, `{"id":`) || !strings.Contains(line, `,"name":"`) {
+			t.Fatalf("line %d: keys out of order: %s", i+1, line)
+		}
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("line %d: %v: %s", i+1, err, line)
+		}
+		if len(rec) != 5 {
+			t.Errorf("line %d has %d keys", i+1, len(rec))
+		}
+		if id, ok := rec["id"].(float64); !ok || id != float64(i+1) {
+			t.Errorf("line %d: id = %v", i+1, rec["id"])
+		}
+		if name, ok := rec["name"].(string); !ok || len(name) != 12 {
+			t.Errorf("line %d: name = %#v", i+1, rec["name"])
+		}
+		if tag, ok := rec["tag"].(string); !ok || len(tag) != DefaultSchemaStrLen {
+			t.Errorf("line %d: tag = %#v", i+1, rec["tag"])
+		}
+		if flag, ok := rec["flag"].(bool); !ok || flag != (i%2 == 0) {
+			t.Errorf("line %d: flag = %#v", i+1, rec["flag"])
+		}
+		if score, ok := rec["score"].(float64); !ok || score != float64(i+1)/1000 {
+			t.Errorf("line %d: score = %#v", i+1, rec["score"])
+		}
+	}
+}
+
+func TestJSONL_SchemaOptions(t *testing.T) {
+	g, err := NewGenerator("jsonl", "schema=a:int,b:str:3;multiline=2", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.NextLine(0)
+	if line := g.NextLine(0); line != "{\"a\":2,\n\"b\":\"#$%\"}" {
+		t.Errorf("multiline record = %q", line)
+	}
+
+	for arg, want := range map[string]string{
+		"id:int,when:date":   `"when": unknown type "date"`,
+		"id:int,id:str":      `"id" appears twice`,
+		"id:int:4":           "only str takes a length",
+		"id":                 "unknown option",
+		"name:str:x":         "invalid length",
+		":int":               "has no name",
+		"id:int;multiline=3": "at least two fields",
+	} {
+		if _, err := NewGenerator("jsonl", arg, 0); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: err = %v, want it to mention %s", arg, err, want)
+		}
+	}
+}
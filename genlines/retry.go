@@ -0,0 +1,70 @@
+package genlines
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// Retry configures retrying of transient write errors. The zero Retry
+// disables retrying, so every write error ends the run.
+type Retry struct {
+	// Attempts is how many times a failed write is retried before the run
+	// fails with all the errors seen.
+	Attempts int
+	// Backoff is the wait before the first retry; it doubles for each further one.
+	Backoff time.Duration
+	// Transient, when set, classifies additional errors as transient, besides
+	// those IsTransient accepts.
+	Transient func(error) bool
+	// OnRetry, when set, is called before each retry with the number of the
+	// first data line not yet written, the retry number (from 1) and the error.
+	OnRetry func(line int64, attempt int, err error)
+}
+
+// IsTransient reports whether err is a write error worth retrying: an
+// interrupted system call (EINTR) or a resource that is temporarily
+// unavailable (EAGAIN).
+func IsTransient(err error) bool {
+	return errors.Is(err, syscall.EINTR) || errors.Is(err, syscall.EAGAIN)
+}
+
+// transient reports whether r retries err.
+func (r Retry) transient(err error) bool {
+	return IsTransient(err) || (r.Transient != nil && r.Transient(err))
+}
+
+// sleep waits between retries. Tests replace it.
+var sleep = time.Sleep
+
+// write writes p to w, retrying transient errors as configured. onRetry is
+// called before each retry. Failing for good, it returns the bytes written
+// and every error seen, joined.
+func (r Retry) write(w func([]byte) (int, error), p []byte, onRetry func(attempt int, err error)) (int, error) {
+	var (
+		total int
+		errs  []error
+	)
+	for attempt := 0; ; attempt++ {
+		n, err := w(p[total:])
+		total += n
+		if err == nil {
+			return total, nil
+		}
+		errs = append(errs, err)
+		if !r.transient(err) {
+			return total, err
+		}
+		if attempt >= r.Attempts {
+			if r.Attempts == 0 {
+				return total, err
+			}
+			return total, fmt.Errorf("giving up after %d retries: %w", r.Attempts, errors.Join(errs...))
+		}
+		if onRetry != nil {
+			onRetry(attempt+1, err)
+		}
+		sleep(r.Backoff << attempt)
+	}
+}
@@ -0,0 +1,95 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyWriter fails its first fails writes with err, then writes to buf.
+type flakyWriter struct {
+	buf   bytes.Buffer
+	fails int
+	err   error
+	calls int
+}
+
+func (f *flakyWriter) Write(p []byte) (int, error) {
+	f.calls++
+	if f.fails != 0 {
+		if f.fails > 0 {
+			f.fails--
+		}
+		return 0, f.err
+	}
+	return f.buf.Write(p)
+}
+
+// noSleep replaces the retry backoff for the duration of the test and
+// records the waits.
+func noSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var waits []time.Duration
+	old := sleep
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	t.Cleanup(func() { sleep = old })
+	return &waits
+}
+
+func TestRetry_TransientErrorsRecover(t *testing.T) {
+	waits := noSleep(t)
+	w := &flakyWriter{fails: 2, err: syscall.EAGAIN}
+	var retried []int64
+	opts := Options{Lines: 100, Width: 10, Retry: Retry{
+		Attempts: 3,
+		Backoff:  10 * time.Millisecond,
+		OnRetry:  func(line int64, attempt int, err error) { retried = append(retried, line) },
+	}}
+
+	lines, n, err := GenerateTo(context.Background(), w, opts)
+	if err != nil {
+		t.Fatalf("GenerateTo: %v", err)
+	}
+	if lines != 100 || n != 1100 || w.buf.Len() != 1100 {
+		t.Fatalf("lines=%d bytes=%d buffered=%d, want 100, 1100, 1100", lines, n, w.buf.Len())
+	}
+	if len(retried) != 2 || retried[0] != 1 || retried[1] != 1 {
+		t.Errorf("OnRetry lines = %v, want [1 1]", retried)
+	}
+	if want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond}; len(*waits) != 2 || (*waits)[0] != want[0] || (*waits)[1] != want[1] {
+		t.Errorf("backoff = %v, want %v", *waits, want)
+	}
+}
+
+func TestRetry_GivesUpAfterLimit(t *testing.T) {
+	noSleep(t)
+	w := &flakyWriter{fails: -1, err: syscall.EINTR}
+	lines, n, err := GenerateTo(context.Background(), w, Options{Lines: 100, Width: 10, Retry: Retry{Attempts: 2}})
+	if err == nil || !errors.Is(err, syscall.EINTR) || !strings.Contains(err.Error(), "giving up after 2 retries") {
+		t.Fatalf("got %v, want the accumulated EINTR errors", err)
+	}
+	if lines != 0 || n != 0 || w.calls != 3 {
+		t.Errorf("lines=%d bytes=%d calls=%d, want 0, 0, 3", lines, n, w.calls)
+	}
+}
+
+func TestRetry_PermanentErrorsFailImmediately(t *testing.T) {
+	noSleep(t)
+	boom := errors.New("disk on fire")
+	w := &flakyWriter{fails: -1, err: boom}
+	_, _, err := GenerateTo(context.Background(), w, Options{Lines: 10, Retry: Retry{Attempts: 5}})
+	if !errors.Is(err, boom) || w.calls != 1 {
+		t.Fatalf("err=%v calls=%d, want the error after one call", err, w.calls)
+	}
+
+	// A custom predicate makes it transient.
+	w = &flakyWriter{fails: 1, err: boom}
+	retry := Retry{Attempts: 1, Transient: func(err error) bool { return errors.Is(err, boom) }}
+	if lines, _, err := GenerateTo(context.Background(), w, Options{Lines: 10, Retry: retry}); err != nil || lines != 10 {
+		t.Fatalf("with predicate: lines=%d err=%v", lines, err)
+	}
+}
@@ -0,0 +1,47 @@
+package genlines
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultRot is the rotation of ROT13, which is its own inverse.
+const DefaultRot = 13
+
+// errRotUnsupported is returned for modes whose lines are not text a
+// rotation could shift without breaking them.
+var errRotUnsupported = errors.New("a letter rotation cannot be combined with mode=blocks or mode=binrec")
+
+// validateRot checks o.Rot (with defaults applied).
+func (o Options) validateRot() error {
+	if o.Rot == 0 {
+		return nil
+	}
+	if o.Rot < 0 || o.Rot > 25 {
+		return fmt.Errorf("invalid rotation: %d (expected 1 to 25)", o.Rot)
+	}
+	switch canonicalMode(o.Mode) {
+	case "blocks", "binrec":
+		return errRotUnsupported
+	}
+	return nil
+}
+
+// Rotate returns s with every ASCII letter shifted n places through the
+// alphabet (n from 0 to 25), keeping its case; all other bytes stay as they
+// are. Rotate(Rotate(s, n), 26-n) is s.
+func Rotate(s string, n int) string {
+	if n == 0 {
+		return s
+	}
+	out := []byte(s)
+	for i, c := range out {
+		switch {
+		case c >= 'a' && c <= 'z':
+			out[i] = 'a' + (c-'a'+byte(n))%26
+		case c >= 'A' && c <= 'Z':
+			out[i] = 'A' + (c-'A'+byte(n))%26
+		}
+	}
+	return string(out)
+}
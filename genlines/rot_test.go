@@ -0,0 +1,78 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRotate_RoundTrip(t *testing.T) {
+	in := AsciiSequence() + "Hello, World! 0123456789 zZ"
+	for n := 0; n <= 25; n++ {
+		out := Rotate(in, n)
+		if back := Rotate(out, (26-n)%26); back != in {
+			t.Errorf("rot %d then %d: %q, want %q", n, (26-n)%26, back, in)
+		}
+		for i := range in {
+			c := in[i]
+			isLetter := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+			if !isLetter && out[i] != c {
+				t.Errorf("rot %d changed %q at %d to %q", n, c, i, out[i])
+			}
+		}
+	}
+	if got := Rotate("Hello, World! 42", 13); got != "Uryyb, Jbeyq! 42" {
+		t.Errorf("ROT13 = %q", got)
+	}
+	if got := Rotate("xyz XYZ", 3); got != "abc ABC" {
+		t.Errorf("rot 3 = %q", got)
+	}
+}
+
+func TestRot_PairsWithPlainRun(t *testing.T) {
+	plain := Options{Lines: 20, Width: 50, Mode: "random", ModeArg: "7"}
+	cipher := plain
+	cipher.Rot = DefaultRot
+	var a, b bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &a, plain); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := GenerateTo(context.Background(), &b, cipher); err != nil {
+		t.Fatal(err)
+	}
+	if a.String() == b.String() || Rotate(b.String(), 26-DefaultRot) != a.String() {
+		t.Errorf("ciphertext is not the plaintext rotated by %d", DefaultRot)
+	}
+}
+
+func TestRot_CommentsAndChecksumsUnshifted(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{Lines: 4, Width: 30, Mode: "alpha", Rot: 5, LineChecksum: true, CommentEvery: 2, CommentText: "# checkpoint %d"}
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if lines[2] != "# checkpoint 2" {
+		t.Errorf("comment line %q", lines[2])
+	}
+	if !strings.HasPrefix(lines[0], "FGHIJ") || !CheckLine(lines[0]) {
+		t.Errorf("first line %q", lines[0])
+	}
+}
+
+func TestRot_Rejected(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"negative": {Lines: 1, Rot: -1},
+		"26":       {Lines: 1, Rot: 26},
+		"blocks":   {Lines: 1, Mode: "blocks", Rot: 13},
+		"binrec":   {Lines: 1, Mode: "binrec", ModeArg: "u8:counter", Rot: 13},
+	} {
+		if _, _, err := GenerateTo(context.Background(), &bytes.Buffer{}, opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := NewSeekable(Options{Lines: 1, Rot: 13}); err == nil {
+		t.Error("NewSeekable accepted a rotation")
+	}
+}
@@ -0,0 +1,136 @@
+package genlines
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// Signature is a file type signature: tools that sniff file types take
+// content starting with Prefix for Name.
+type Signature struct {
+	Prefix string
+	Name   string
+}
+
+// Signatures lists the file type signatures SniffSignature recognizes. Each
+// prefix is at most two bytes, so the first two bytes of an output decide.
+var Signatures = []Signature{
+	{"PK", "ZIP archive"},
+	{"%P", "PDF document"},
+	{"{", "JSON object"},
+	{"[", "JSON array"},
+	{"<!", "HTML or XML document"},
+	{"<?", "XML document"},
+	{"#!", "script (shebang)"},
+	{"\x1f\x8b", "gzip stream"},
+}
+
+// SniffSignature returns the signature of Signatures that head starts with,
+// if any.
+func SniffSignature(head []byte) (Signature, bool) {
+	for _, sig := range Signatures {
+		if strings.HasPrefix(string(head), sig.Prefix) {
+			return sig, true
+		}
+	}
+	return Signature{}, false
+}
+
+// safeStartSearch is how many content positions past the start offset
+// SafeStart tries for a neutral start before it replaces characters instead.
+const safeStartSearch = 4096
+
+// safeStartLetters are the letters that replace the start of a first line
+// no shift can make neutral.
+const safeStartLetters = "Aa"
+
+// safeHead reports whether a line starting with s starts neutrally: its
+// first two bytes (or its only one) are ASCII letters or digits and match no
+// signature.
+func safeHead(s string) bool {
+	for i := 0; i < min(2, len(s)); i++ {
+		c := s[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	_, hit := SniffSignature([]byte(s))
+	return !hit
+}
+
+// neutralStart returns s, or, if it does not start neutrally, s with its
+// first characters replaced by safeStartLetters. The replacement covers
+// whole runes and has their size in bytes (escaped, if escape is set), so
+// the line keeps the size planned for it.
+func neutralStart(s string, escape bool) string {
+	if safeHead(s) {
+		return s
+	}
+	var n int64
+	i := 0
+	for i < len(s) && n < 2 {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		n += escapedRuneLen(r, escape)
+		i += size
+	}
+	return strings.Repeat(safeStartLetters, int(n+1)/2)[:n] + s[i:]
+}
+
+// safeStartShift returns how many characters past o.StartOffset gen must
+// start for the first line to start neutrally, with rotation applied, and
+// leaves gen there. It returns false, with gen at o.StartOffset, if gen is
+// not seekable or no position within safeStartSearch does.
+func (o Options) safeStartShift(gen Generator) (int64, bool) {
+	sg, ok := gen.(SeekableGenerator)
+	if !ok {
+		return 0, false
+	}
+	n := min(2, o.layout().contentWidth(1))
+	for shift := int64(0); shift < safeStartSearch; shift++ {
+		sg.SeekChar(o.StartOffset + shift)
+		if safeHead(Rotate(sg.NextLine(n), o.Rot)) {
+			sg.SeekChar(o.StartOffset + shift)
+			return shift, true
+		}
+	}
+	sg.SeekChar(o.StartOffset)
+	return 0, false
+}
+
+// SafeStartShift reports what Options.SafeStart does to the output of opts:
+// shifted is false if the mode cannot be shifted to a neutral start, so the
+// first characters of the first line are replaced by letters instead (if
+// they are not neutral already); otherwise shift is how many characters
+// the content starts past opts.StartOffset. It returns 0, true when
+// SafeStart is off.
+func SafeStartShift(opts Options) (shift int64, shifted bool, err error) {
+	opts = opts.withDefaults()
+	if !opts.SafeStart {
+		return 0, true, nil
+	}
+	if err := opts.validate(); err != nil {
+		return 0, false, err
+	}
+	// Probe a generator of its own, leaving a calibrated pi stream unread.
+	opts.SafeStart, opts.PiDigits = false, nil
+	gen, err := buildGenerator(opts)
+	if err != nil {
+		return 0, false, err
+	}
+	shift, shifted = opts.safeStartShift(gen)
+	return shift, shifted, nil
+}
+
+// errSafeStartUnsupported is returned for modes whose lines are not text a
+// tool could sniff: blocks lines start with an escape sequence letters
+// would break, and binrec records are binary.
+var errSafeStartUnsupported = errors.New("a safe start cannot be combined with mode=blocks or mode=binrec")
+
+// validateSafeStart checks o.SafeStart (with defaults applied).
+func (o Options) validateSafeStart() error {
+	if mode := canonicalMode(o.Mode); o.SafeStart && (mode == "blocks" || mode == "binrec") {
+		return errSafeStartUnsupported
+	}
+	return nil
+}
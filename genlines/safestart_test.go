@@ -0,0 +1,150 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSniffSignature(t *testing.T) {
+	for head, want := range map[string]string{
+		"PK\x03\x04":     "ZIP archive",
+		"%PDF-1.7":       "PDF document",
+		`{"a":1}`:        "JSON object",
+		"[1,2]":          "JSON array",
+		"<!DOCTYPE html": "HTML or XML document",
+		"<?xml":          "XML document",
+		"#!/bin/sh":      "script (shebang)",
+		"\x1f\x8b\x08":   "gzip stream",
+	} {
+		if sig, ok := SniffSignature([]byte(head)); !ok || sig.Name != want {
+			t.Errorf("%q: %+v, %v; want %s", head, sig, ok, want)
+		}
+	}
+	for _, head := range []string{"", "P", "%", "<a", " {", "AB", "01", "Pk"} {
+		if sig, ok := SniffSignature([]byte(head)); ok {
+			t.Errorf("%q: matched %+v", head, sig)
+		}
+	}
+}
+
+func TestNeutralStart(t *testing.T) {
+	for _, tt := range []struct {
+		in     string
+		escape bool
+		want   string
+	}{
+		{"AbCd", false, "AbCd"},
+		{"0123", false, "0123"},
+		{"{{{{", false, "Aa{{"},
+		{"PKPK", false, "AaPK"},
+		{" !\"#", false, "Aa\"#"},
+		{"<", false, "A"},
+		{"éé", false, "Aaé"},
+		{"éé", true, "AaAaAaé"},
+		{"\U0001F600x", false, "AaAax"},
+	} {
+		if got := neutralStart(tt.in, tt.escape); got != tt.want {
+			t.Errorf("neutralStart(%q, %v) = %q, want %q", tt.in, tt.escape, got, tt.want)
+		}
+	}
+}
+
+func TestSafeStart_ShiftsSeekableContent(t *testing.T) {
+	for _, opts := range []Options{
+		{Lines: 50, Width: 20, Mode: "ascii"},
+		{Lines: 50, Width: 20, Mode: "ascii", StartOffset: 1000, Rot: 13},
+		{Lines: 50, Width: 20, Mode: "alpha"},
+		{Lines: 50, Width: 20, Mode: "ascii", LineChecksum: true},
+	} {
+		opts.SafeStart = true
+		shift, shifted, err := SafeStartShift(opts)
+		if err != nil || !shifted {
+			t.Fatalf("%+v: shift %d, %v, %v", opts, shift, shifted, err)
+		}
+		var got bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &got, opts); err != nil {
+			t.Fatal(err)
+		}
+		if !safeHead(got.String()) {
+			t.Errorf("%+v: output starts with %q", opts, got.String()[:2])
+		}
+
+		// The shifted output is the plain one started that much later.
+		plain := opts
+		plain.SafeStart, plain.StartOffset = false, opts.StartOffset+shift
+		var want bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &want, plain); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("%+v: shift %d does not match the output started at offset %d", opts, shift, plain.StartOffset)
+		}
+	}
+
+	if shift, _, _ := SafeStartShift(Options{Lines: 1, Width: 20, Mode: "ascii", SafeStart: true}); shift != 16 {
+		t.Errorf("ascii shifted by %d, want 16 (to \"01\")", shift)
+	}
+	if shift, shifted, _ := SafeStartShift(Options{Lines: 1, Width: 20, Mode: "alpha", SafeStart: true}); shift != 0 || !shifted {
+		t.Errorf("alpha shifted by %d, %v; it already starts with letters", shift, shifted)
+	}
+}
+
+func TestSafeStart_ReplacesUnshiftableStart(t *testing.T) {
+	for _, opts := range []Options{
+		{Lines: 5, Width: 10, Mode: "char", ModeArg: "{"},
+		{Lines: 5, Width: 10, Mode: "char", ModeArg: "é"},
+		{Lines: 5, Width: 10, Mode: "char", ModeArg: "é", EscapeNonASCII: true, LineChecksum: true},
+		{Lines: 5, Width: 10, Mode: "ascii:4+digits:6"},
+	} {
+		opts.SafeStart = true
+		if _, shifted, err := SafeStartShift(opts); err != nil || shifted {
+			t.Errorf("%+v: shifted %v, %v", opts, shifted, err)
+		}
+		size, err := PlanSize(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		_, n, err := GenerateTo(context.Background(), &buf, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		if !strings.HasPrefix(out, "Aa") || n != size {
+			t.Errorf("%+v: %d bytes (planned %d) starting %q", opts, n, size, out[:min(6, len(out))])
+		}
+		if opts.LineChecksum {
+			bad := 0
+			if _, err := VerifyLines(strings.NewReader(out), func(int64, string) { bad++ }); err != nil || bad > 0 {
+				t.Errorf("%+v: %d bad lines, %v", opts, bad, err)
+			}
+		}
+		// Only the first line changes.
+		plain := opts
+		plain.SafeStart = false
+		var want bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &want, plain); err != nil {
+			t.Fatal(err)
+		}
+		if _, rest, _ := strings.Cut(out, "\n"); !strings.HasSuffix(want.String(), "\n"+rest) {
+			t.Errorf("%+v: lines after the first differ", opts)
+		}
+	}
+}
+
+func TestSafeStart_Unsupported(t *testing.T) {
+	for _, opts := range []Options{
+		{Lines: 1, Width: 4, Mode: "blocks", SafeStart: true},
+		{Lines: 1, Mode: "binrec", ModeArg: "u8", SafeStart: true},
+	} {
+		if _, _, err := GenerateTo(context.Background(), &bytes.Buffer{}, opts); !errors.Is(err, errSafeStartUnsupported) {
+			t.Errorf("mode=%s: %v", opts.Mode, err)
+		}
+	}
+	if _, err := NewSeekable(Options{Lines: 1, Width: 4, Mode: "ascii", SafeStart: true}); err == nil {
+		t.Error("NewSeekable accepted a safe start")
+	}
+}
@@ -0,0 +1,63 @@
+package genlines
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+// SeedLabelContent labels the seed a run's content mode derives from the
+// global seed. Interleave stream N (from 1) uses SeedLabelContent + "/N".
+const SeedLabelContent = "content"
+
+// DeriveSeed returns the seed the feature labeled label draws from in a run
+// with the global seed seed: the first eight bytes, big-endian, of
+// SHA-256(seed as big-endian uint64 || label). Different labels give
+// independent streams, so features can be added without shifting the output
+// of the others.
+func DeriveSeed(seed uint64, label string) uint64 {
+	msg := make([]byte, 8, 8+len(label))
+	binary.BigEndian.PutUint64(msg, seed)
+	sum := sha256.Sum256(append(msg, label...))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// SeedModeArg returns the modeArg mode runs with under the global seed seed.
+// A modeArg that already carries a seed is an override and is returned
+// unchanged, as is the modeArg of a mode without randomness.
+func SeedModeArg(mode, arg string, seed uint64, label string) (string, error) {
+	_, spec, err := LookupMode(mode)
+	if err != nil {
+		return "", err
+	}
+	if spec.SeedArg == nil {
+		return arg, nil
+	}
+	return spec.SeedArg(DeriveSeed(seed, label), arg), nil
+}
+
+// seedIfEmpty is a ModeSpec.SeedArg for modes whose whole modeArg is the seed.
+func seedIfEmpty(seed uint64, arg string) string {
+	if strings.TrimSpace(arg) != "" {
+		return arg
+	}
+	return strconv.FormatUint(seed, 10)
+}
+
+// seedIPArg is the ModeSpec.SeedArg of the ip mode: v4 and v6 without a seed
+// get one; CIDR enumeration has no randomness.
+func seedIPArg(seed uint64, arg string) string {
+	kind, rest, _ := strings.Cut(strings.TrimSpace(arg), ":")
+	switch strings.ToLower(kind) {
+	case "", "v4", "v6":
+		if strings.TrimSpace(rest) != "" {
+			return arg
+		}
+		if kind == "" {
+			kind = "v4"
+		}
+		return kind + ":" + strconv.FormatUint(seed, 10)
+	}
+	return arg
+}
@@ -0,0 +1,76 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"testing"
+)
+
+func generate(t *testing.T, opts Options) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatalf("GenerateTo(%+v): %v", opts, err)
+	}
+	return buf.String()
+}
+
+func TestDeriveSeed(t *testing.T) {
+	if DeriveSeed(42, "content") != DeriveSeed(42, "content") {
+		t.Fatal("DeriveSeed is not deterministic")
+	}
+	seen := map[uint64]string{}
+	for _, seed := range []uint64{0, 1, 42} {
+		for _, label := range []string{"content", "content/1", "content/2", "widths"} {
+			d := DeriveSeed(seed, label)
+			if prev, dup := seen[d]; dup {
+				t.Fatalf("%d/%s collides with %s", seed, label, prev)
+			}
+			seen[d] = strconv.FormatUint(seed, 10) + "/" + label
+		}
+	}
+}
+
+func TestSeed_ReproducesWholeRun(t *testing.T) {
+	for _, mode := range []string{"random", "hashfill", "ip", "random:20+ascii:5+random:20"} {
+		opts := Options{Lines: 30, Width: 40, Mode: mode, Seed: 7, HasSeed: true}
+		a, b := generate(t, opts), generate(t, opts)
+		if a != b {
+			t.Errorf("%s: the same seed gave different output", mode)
+		}
+		opts.Seed = 8
+		if generate(t, opts) == a {
+			t.Errorf("%s: different seeds gave the same output", mode)
+		}
+	}
+}
+
+func TestSeed_DerivedStreamsAreIndependent(t *testing.T) {
+	out := generate(t, Options{Lines: 2, Mode: "random:30+random:30", Seed: 1, HasSeed: true})
+	if out[:30] == out[31:61] {
+		t.Fatalf("both interleave streams produced %q", out[:30])
+	}
+
+	// The content stream is exactly the random mode with the derived seed.
+	derived := strconv.FormatUint(DeriveSeed(1, SeedLabelContent), 10)
+	if generate(t, Options{Lines: 5, Mode: "random", Seed: 1, HasSeed: true}) != generate(t, Options{Lines: 5, Mode: "random", ModeArg: derived}) {
+		t.Fatal("content does not draw from DeriveSeed(seed, \"content\")")
+	}
+}
+
+func TestSeed_ModeArgOverrides(t *testing.T) {
+	for _, tt := range []struct{ mode, arg string }{{"random", "99"}, {"hashfill", "s1"}, {"ip", "v6:5"}, {"ip", "cidr:10.0.0.0/30"}} {
+		with := generate(t, Options{Lines: 5, Mode: tt.mode, ModeArg: tt.arg, Seed: 3, HasSeed: true})
+		without := generate(t, Options{Lines: 5, Mode: tt.mode, ModeArg: tt.arg})
+		if with != without {
+			t.Errorf("%s %s: the global seed replaced the modeArg's own seed", tt.mode, tt.arg)
+		}
+	}
+	if arg, _ := SeedModeArg("ip", "v6", 3, SeedLabelContent); arg != "v6:"+strconv.FormatUint(DeriveSeed(3, SeedLabelContent), 10) {
+		t.Errorf("ip v6 seeded arg = %q", arg)
+	}
+	if arg, _ := SeedModeArg("digits", "", 3, SeedLabelContent); arg != "" {
+		t.Errorf("digits seeded arg = %q, want it unchanged", arg)
+	}
+}
@@ -0,0 +1,127 @@
+package genlines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNotSeekable is returned for modes whose output can only be produced sequentially (e.g. pi).
+var ErrNotSeekable = errors.New("mode is not seekable")
+
+// SeekableGenerator is a Generator whose output is a pure function of the
+// absolute character offset, so it can start anywhere without replaying the prefix.
+type SeekableGenerator interface {
+	Generator
+
+	// SeekChar positions the generator as if offset characters had already been emitted.
+	SeekChar(offset int64)
+}
+
+// SeekChar positions the cycle at offset. A negative offset counts back from
+// the start of the cycle.
+func (g *cycleGen) SeekChar(offset int64) {
+	n := int64(len(g.palette))
+	pos := offset % n
+	if pos < 0 {
+		pos += n
+	}
+	g.pos = int(pos)
+}
+
+// SeekChar is a no-op; every line is identical.
+func (g *singleCharGen) SeekChar(offset int64) {}
+
+// Seekable addresses the lines of a virtual output by index without
+// generating the lines before them. It is not safe for concurrent use.
+type Seekable struct {
+	gen    SeekableGenerator
+	lines  int
+	width  int
+	eol    []byte
+	offset int64 // Options.StartOffset
+}
+
+// NewSeekable prepares random access over the output described by opts.
+// It returns ErrNotSeekable if the mode can only be generated sequentially.
+func NewSeekable(opts Options) (*Seekable, error) {
+	opts = opts.withDefaults()
+	if opts.CommentEvery > 0 {
+		return nil, errors.New("comment lines are not supported with random access")
+	}
+	if opts.LineChecksum {
+		return nil, errors.New("line checksums are not supported with random access")
+	}
+	if opts.Ramp.Enabled() {
+		return nil, errors.New("a width ramp is not supported with random access")
+	}
+	if opts.Align > 0 || opts.MaxBytes > 0 {
+		return nil, errors.New("alignment and byte ceilings are not supported with random access")
+	}
+	if opts.EscapeNonASCII {
+		return nil, errors.New("escaping non-ASCII is not supported with random access")
+	}
+	if opts.Continuation != "" {
+		return nil, errors.New("continuation markers are not supported with random access")
+	}
+	if opts.TrailingWS.Enabled() {
+		return nil, errors.New("trailing whitespace is not supported with random access")
+	}
+	if opts.InjectUnicode.Enabled() {
+		return nil, errors.New("unicode injection is not supported with random access")
+	}
+	if opts.SafeStart {
+		return nil, errors.New("a safe start is not supported with random access")
+	}
+	if opts.LinePattern.Enabled() {
+		return nil, errors.New("line patterns are not supported with random access")
+	}
+	if opts.HeaderLine != "" {
+		return nil, errors.New("a header line is not supported with random access")
+	}
+	if opts.Rot != 0 {
+		return nil, errors.New("a letter rotation is not supported with random access")
+	}
+	if opts.Sort != SortNone {
+		return nil, errors.New("sorting is not supported with random access")
+	}
+	if opts.FirstLine != "" || opts.LastLine != "" {
+		return nil, errors.New("first and last lines are not supported with random access")
+	}
+
+	gen, err := newGenerator(opts.Mode, opts.ModeArg, opts.Lines*opts.Width)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.limitBytes(gen); err != nil {
+		return nil, err
+	}
+	sg, ok := gen.(SeekableGenerator)
+	if !ok {
+		return nil, fmt.Errorf("mode=%s: %w", opts.Mode, ErrNotSeekable)
+	}
+	if opts.StartOffset < 0 {
+		return nil, fmt.Errorf("invalid start offset: %d", opts.StartOffset)
+	}
+	return &Seekable{gen: sg, lines: opts.Lines, width: opts.Width, eol: opts.EOL, offset: opts.StartOffset}, nil
+}
+
+// LineAt returns the content of line n (zero-based), without its terminator.
+func (s *Seekable) LineAt(n int) (string, error) {
+	if n < 0 || n >= s.lines {
+		return "", fmt.Errorf("line %d out of bounds (lines=%d)", n, s.lines)
+	}
+	s.gen.SeekChar(s.offset + int64(n)*int64(s.width))
+	return s.gen.NextLine(s.width), nil
+}
+
+// GenerateRange writes count lines starting at firstLine (zero-based),
+// terminators included, exactly as they appear in a full sequential run.
+func (s *Seekable) GenerateRange(w io.Writer, firstLine, count int) (lines, bytes int64, err error) {
+	if firstLine < 0 || count < 0 || firstLine > s.lines || count > s.lines-firstLine {
+		return 0, 0, fmt.Errorf("line range %d+%d out of bounds (lines=%d)", firstLine, count, s.lines)
+	}
+	s.gen.SeekChar(s.offset + int64(firstLine)*int64(s.width))
+	return writeLines(context.Background(), w, s.gen, layout{width: s.width, eol: s.eol}, 0, int64(count), nil)
+}
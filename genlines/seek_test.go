@@ -0,0 +1,140 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSeekable_GenerateRangeMatchesSequential(t *testing.T) {
+	cases := []struct {
+		mode, arg string
+		width     int
+	}{
+		{"ascii", "", 80},
+		{"ascii", "", 13},
+		{"digits", "", 7},
+		{"upper", "", 100},
+		{"char", "#", 5},
+	}
+
+	for _, c := range cases {
+		opts := Options{Lines: 300, Width: c.width, Mode: c.mode, ModeArg: c.arg}
+
+		var full bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &full, opts); err != nil {
+			t.Fatalf("%s: GenerateTo: %v", c.mode, err)
+		}
+		all := strings.SplitAfter(full.String(), "\n")
+
+		s, err := NewSeekable(opts)
+		if err != nil {
+			t.Fatalf("%s: NewSeekable: %v", c.mode, err)
+		}
+
+		for _, r := range [][2]int{{0, 1}, {1, 5}, {97, 3}, {250, 50}, {299, 1}} {
+			var got bytes.Buffer
+			if _, _, err := s.GenerateRange(&got, r[0], r[1]); err != nil {
+				t.Fatalf("%s: GenerateRange(%d, %d): %v", c.mode, r[0], r[1], err)
+			}
+			want := strings.Join(all[r[0]:r[0]+r[1]], "")
+			if got.String() != want {
+				t.Fatalf("%s width %d: range %v differs from sequential run", c.mode, c.width, r)
+			}
+		}
+
+		got, err := s.LineAt(42)
+		if err != nil {
+			t.Fatalf("%s: LineAt(42): %v", c.mode, err)
+		}
+		if want := strings.TrimSuffix(all[42], "\n"); got != want {
+			t.Fatalf("%s: LineAt(42)=%q, want %q", c.mode, got, want)
+		}
+	}
+}
+
+func TestSeekable_PiNotSeekable(t *testing.T) {
+	_, err := NewSeekable(Options{Lines: 10, Mode: "pi"})
+	if !errors.Is(err, ErrNotSeekable) {
+		t.Fatalf("expected ErrNotSeekable, got %v", err)
+	}
+}
+
+func TestSeekable_RangeOutOfBounds(t *testing.T) {
+	s, err := NewSeekable(Options{Lines: 10})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if _, _, err := s.GenerateRange(&bytes.Buffer{}, 8, 3); err == nil {
+		t.Fatalf("expected out-of-bounds error")
+	}
+	if _, _, err := s.GenerateRange(&bytes.Buffer{}, 5, math.MaxInt); err == nil {
+		t.Fatalf("expected out-of-bounds error for a count that overflows")
+	}
+	for _, n := range []int{-1, 10} {
+		if _, err := s.LineAt(n); err == nil {
+			t.Fatalf("LineAt(%d): expected out-of-bounds error", n)
+		}
+	}
+}
+
+func TestCycleGen_SeekCharNegative(t *testing.T) {
+	g, err := newGenerator("digits", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg := g.(SeekableGenerator)
+	sg.SeekChar(-3)
+	if got := sg.NextLine(5); got != "78901" {
+		t.Fatalf("SeekChar(-3) then NextLine(5) = %q, want %q", got, "78901")
+	}
+}
+
+func TestStartOffset_ConcatenationMatchesSingleRun(t *testing.T) {
+	for _, mode := range []string{"ascii", "digits"} {
+		var whole, first, second bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &whole, Options{Lines: 200, Width: 80, Mode: mode}); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := GenerateTo(context.Background(), &first, Options{Lines: 100, Width: 80, Mode: mode}); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := GenerateTo(context.Background(), &second, Options{Lines: 100, Width: 80, Mode: mode, StartOffset: 100 * 80}); err != nil {
+			t.Fatal(err)
+		}
+		if first.String()+second.String() != whole.String() {
+			t.Errorf("%s: the two halves do not concatenate to the 200-line run", mode)
+		}
+
+		// Random access continues from the offset too.
+		s, err := NewSeekable(Options{Lines: 100, Width: 80, Mode: mode, StartOffset: 100 * 80})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.LineAt(5)
+		if err != nil {
+			t.Fatalf("%s: LineAt(5): %v", mode, err)
+		}
+		if want := strings.Split(whole.String(), "\n")[105]; got != want {
+			t.Errorf("%s: LineAt(5) = %q, want line 106 of the whole run %q", mode, got, want)
+		}
+	}
+}
+
+func TestStartOffset_NeedsSeekableMode(t *testing.T) {
+	for _, opts := range []Options{
+		{Lines: 1, Mode: "pi", StartOffset: 10},
+		{Lines: 1, Mode: "random", ModeArg: "7", StartOffset: 10},
+		{Lines: 1, Mode: "digits:5+upper:8", StartOffset: 10},
+	} {
+		if _, _, err := GenerateTo(context.Background(), &bytes.Buffer{}, opts); !errors.Is(err, ErrNotSeekable) {
+			t.Errorf("%s: expected ErrNotSeekable, got %v", opts.Mode, err)
+		}
+	}
+	if _, _, err := GenerateTo(context.Background(), &bytes.Buffer{}, Options{Lines: 1, StartOffset: -1}); err == nil {
+		t.Error("a negative start offset was accepted")
+	}
+}
@@ -0,0 +1,126 @@
+package genlines
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// ConfigPlaceholder in Options.FirstLine or Options.LastLine is replaced by
+// ConfigChecksum of the run, e.g. "BEGIN-{config}".
+const ConfigPlaceholder = "{config}"
+
+// ConfigChecksum returns the CRC32 (IEEE) of the settings of opts that decide
+// its content, as ChecksumWidth lowercase hex digits: two runs with the same
+// checksum were configured alike. FirstLine, LastLine and HeaderLine are not
+// included, and neither are settings that only affect how the output is delivered
+// (Retry, Stats, callbacks).
+func ConfigChecksum(opts Options) string {
+	o := opts.withDefaults()
+	var b strings.Builder
+	fmt.Fprintf(&b, "lines=%d\nwidth=%d\nmode=%s\nmodeArg=%s\neol=%x\n", o.Lines, o.Width, canonicalMode(o.Mode), o.ModeArg, o.EOL)
+	if o.CommentEvery > 0 {
+		fmt.Fprintf(&b, "comments=%d %q\n", o.CommentEvery, o.CommentText)
+	}
+	fmt.Fprintf(&b, "checksum=%t\nexact=%d\nmax=%d\nalign=%d %q\nescape=%t\ncontinuation=%q\nrot=%d\n",
+		o.LineChecksum, o.ExactBytes, o.MaxBytes, o.Align, o.AlignFill, o.EscapeNonASCII, o.Continuation, o.Rot)
+	if o.Ramp.Enabled() {
+		fmt.Fprintf(&b, "ramp=%s\n", o.Ramp)
+	}
+	if o.TrailingWS.Enabled() {
+		fmt.Fprintf(&b, "trailing=%s\n", o.TrailingWS)
+	}
+	if o.LinePattern.Enabled() {
+		fmt.Fprintf(&b, "pattern=%s\n", o.LinePattern)
+	}
+	if o.InjectUnicode.Enabled() {
+		fmt.Fprintf(&b, "inject=%s\n", o.InjectUnicode)
+	}
+	if o.ByteRange.Enabled() {
+		fmt.Fprintf(&b, "bytes=%s\n", o.ByteRange)
+	}
+	fmt.Fprintf(&b, "start=%d\nsafe=%t\nsort=%s\nunsafe=%t\n", o.StartOffset, o.SafeStart, o.Sort, o.Unsafe)
+	if o.HasSeed {
+		fmt.Fprintf(&b, "seed=%d\n", o.Seed)
+	}
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(b.String())))
+}
+
+// errSentinelUnsupported is returned for settings that plan line sizes in
+// advance, move lines or are not lines of text.
+var errSentinelUnsupported = errors.New("a first or last line cannot be combined with an exact byte size, alignment, sorting, mode=blocks or mode=binrec")
+
+// validateSentinels checks o.FirstLine and o.LastLine (with defaults
+// applied).
+func (o Options) validateSentinels() error {
+	if o.FirstLine == "" && o.LastLine == "" {
+		return nil
+	}
+	for _, s := range []struct{ what, text string }{{"first line", o.FirstLine}, {"last line", o.LastLine}} {
+		for i := 0; i < len(s.text); i++ {
+			if c := s.text[i]; c < 0x20 || c > 0x7e {
+				return fmt.Errorf("the %s %q must be printable ASCII (%q is not)", s.what, s.text, c)
+			}
+		}
+	}
+	if mode := canonicalMode(o.Mode); o.ExactBytes > 0 || o.Align > 0 || o.Sort != SortNone || mode == "blocks" || mode == "binrec" {
+		return errSentinelUnsupported
+	}
+	if o.FirstLine != "" && o.SafeStart {
+		return errors.New("a first line cannot be combined with a safe start: both decide how line 1 starts")
+	}
+	if r := o.ByteRange; r.Enabled() {
+		if b, ok := r.outside(o.FirstLine + o.LastLine); ok {
+			return fmt.Errorf("the first and last lines cannot be limited to bytes %s (%q is outside)", r, b)
+		}
+	}
+	return nil
+}
+
+// validateHeader checks o.HeaderLine (with defaults applied).
+func (o Options) validateHeader() error {
+	if o.HeaderLine == "" {
+		return nil
+	}
+	for i := 0; i < len(o.HeaderLine); i++ {
+		if c := o.HeaderLine[i]; c < 0x20 || c > 0x7e {
+			return fmt.Errorf("the header line %q must be printable ASCII (%q is not)", o.HeaderLine, c)
+		}
+	}
+	if o.ExactBytes > 0 || len(o.EOL) == 0 || canonicalMode(o.Mode) == "binrec" {
+		return errors.New("a header line cannot be combined with an exact byte size, mode=binrec or an empty line terminator")
+	}
+	return nil
+}
+
+// expandSentinel returns text with ConfigPlaceholder replaced for o.
+func (o Options) expandSentinel(text string) string {
+	if !strings.Contains(text, ConfigPlaceholder) {
+		return text
+	}
+	return strings.ReplaceAll(text, ConfigPlaceholder, ConfigChecksum(o))
+}
+
+// fitSentinel pads text with spaces, or truncates it, to width columns.
+func fitSentinel(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if len(text) >= width {
+		return text[:width]
+	}
+	return text + strings.Repeat(" ", width-len(text))
+}
+
+// sentinel returns the text replacing the content of data line n, if any.
+// A run of one line gets the last line.
+func (l layout) sentinel(n int64) (string, bool) {
+	switch {
+	case n == l.lines && l.lastLine != "":
+		return l.lastLine, true
+	case n == 1 && l.firstLine != "":
+		return l.firstLine, true
+	}
+	return "", false
+}
@@ -0,0 +1,190 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// generateLines returns the lines of a run of opts, without terminators.
+func generateLines(t *testing.T, opts Options) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+func TestSentinels_ReplaceFirstAndLastLine(t *testing.T) {
+	for _, opts := range []Options{
+		{Lines: 50, Width: 20, Mode: "random", ModeArg: "3"},
+		{Lines: 50, Width: 20, Mode: "ascii", Rot: 13},
+		{Lines: 50, Width: 20, Mode: "lorem", ModeArg: "de"},
+		{Lines: 50, Width: 30, Mode: "digits", LineChecksum: true, Continuation: `\`},
+	} {
+		plain := generateLines(t, opts)
+		opts.FirstLine, opts.LastLine = "BEGIN", "END-OF-FILE-MARKER-THAT-IS-TOO-LONG"
+		lines := generateLines(t, opts)
+
+		// The last line has no continuation marker.
+		first, last := strings.TrimSuffix(lines[0], opts.Continuation), lines[len(lines)-1]
+		if opts.LineChecksum {
+			if !CheckLine(first) || !CheckLine(last) {
+				t.Errorf("mode=%s: sentinel lines fail their checksum: %q, %q", opts.Mode, first, last)
+			}
+			first, last = first[:len(first)-ChecksumWidth-1], last[:len(last)-ChecksumWidth-1]
+		}
+		if want := "BEGIN" + strings.Repeat(" ", len(first)-5); first != want {
+			t.Errorf("mode=%s: line 1 %q", opts.Mode, lines[0])
+		}
+		if want := opts.LastLine[:len(last)]; last != want || len(lines[len(lines)-1]) != opts.Width {
+			t.Errorf("mode=%s: line %d %q, want %q", opts.Mode, len(lines), lines[len(lines)-1], want)
+		}
+		if !slices.Equal(lines[1:len(lines)-1], plain[1:len(plain)-1]) {
+			t.Errorf("mode=%s: lines 2..N-1 differ from an undecorated run", opts.Mode)
+		}
+	}
+}
+
+func TestSentinels_ConfigPlaceholder(t *testing.T) {
+	opts := Options{Lines: 10, Width: 30, Mode: "random", ModeArg: "5", FirstLine: "BEGIN-{config}", LastLine: "END-{config}"}
+	sum := ConfigChecksum(opts)
+	lines := generateLines(t, opts)
+	if lines[0] != "BEGIN-"+sum+strings.Repeat(" ", 30-14) || strings.TrimRight(lines[9], " ") != "END-"+sum {
+		t.Errorf("sentinels %q, %q with checksum %s", lines[0], lines[9], sum)
+	}
+
+	// The checksum follows the content settings, not the sentinels.
+	same := opts
+	same.FirstLine, same.Retry = "", Retry{Attempts: 3}
+	if ConfigChecksum(same) != sum {
+		t.Error("checksum depends on the sentinels or on delivery settings")
+	}
+	for _, change := range []func(*Options){
+		func(o *Options) { o.Lines++ },
+		func(o *Options) { o.ModeArg = "6" },
+		func(o *Options) { o.EOL = []byte("\r\n") },
+		func(o *Options) { o.Seed, o.HasSeed = 1, true },
+		func(o *Options) { o.LineChecksum = true },
+	} {
+		other := opts
+		change(&other)
+		if ConfigChecksum(other) == sum {
+			t.Errorf("%+v has the same checksum", other)
+		}
+	}
+}
+
+func TestSentinels_SplitAndOneLine(t *testing.T) {
+	opts := Options{Lines: 9, Width: 10, Mode: "alpha", FirstLine: "FIRST", LastLine: "LAST"}
+	whole := generateLines(t, opts)
+	var joined bytes.Buffer
+	if _, err := GenerateSplit(context.Background(), opts, 4, func(int) (io.WriteCloser, error) {
+		return nopCloser{&joined}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if joined.String() != strings.Join(whole, "\n")+"\n" {
+		t.Error("split parts differ from the run")
+	}
+
+	opts.Lines = 1
+	if lines := generateLines(t, opts); lines[0] != "LAST      " {
+		t.Errorf("one line: %q", lines[0])
+	}
+}
+
+func TestSentinels_Rejected(t *testing.T) {
+	for _, opts := range []Options{
+		{FirstLine: "tab\there"},
+		{LastLine: "ænd"},
+		{FirstLine: "x", ExactBytes: 100},
+		{LastLine: "x", Align: 4096},
+		{LastLine: "x", Sort: SortAsc},
+		{FirstLine: "x", Mode: "blocks"},
+		{FirstLine: "x", SafeStart: true},
+		{LastLine: "END", ByteRange: ByteRange{'a', 'z'}},
+	} {
+		opts.Lines, opts.Width = 5, 20
+		if _, _, err := GenerateTo(context.Background(), io.Discard, opts); err == nil {
+			t.Errorf("%+v accepted", opts)
+		}
+	}
+	if _, err := NewSeekable(Options{Lines: 5, Mode: "ascii", LastLine: "x"}); err == nil {
+		t.Error("NewSeekable accepted a last line")
+	}
+	// A safe start and a last line do not meet.
+	opts := Options{Lines: 5, Width: 20, Mode: "ascii", SafeStart: true, LastLine: "END"}
+	if _, _, err := GenerateTo(context.Background(), io.Discard, opts); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHeaderLine(t *testing.T) {
+	for _, opts := range []Options{
+		{Lines: 20, Width: 20, Mode: "random", ModeArg: "3", LineChecksum: true},
+		{Lines: 20, Width: 20, Mode: "digits", CommentEvery: 5, Align: 64, EOL: []byte("\r\n")},
+		{Lines: 20, Width: 20, Mode: "ascii", MaxBytes: 100},
+	} {
+		plain := generateLines(t, opts)
+		opts.HeaderLine = "# header"
+		lines := generateLines(t, opts)
+		if strings.TrimSuffix(lines[0], "\r") != "# header" {
+			t.Errorf("%+v: line 1 %q", opts, lines[0])
+		}
+		if opts.Align == 0 && opts.MaxBytes == 0 && !slices.Equal(lines[1:], plain) {
+			t.Errorf("%+v: data lines differ from a run without the header", opts)
+		}
+
+		var stats Stats
+		opts.Stats = &stats
+		var buf bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+			t.Fatal(err)
+		}
+		size, err := PlanSize(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != int64(buf.Len()) {
+			t.Errorf("%+v: planned %d bytes, wrote %d", opts, size, buf.Len())
+		}
+		if opts.MaxBytes == 0 && stats.Lines != 20 {
+			t.Errorf("%+v: %d lines counted", opts, stats.Lines)
+		}
+	}
+
+	// Only the first split part has it.
+	opts := Options{Lines: 9, Width: 10, Mode: "alpha", HeaderLine: "# h"}
+	whole := generateLines(t, opts)
+	var joined bytes.Buffer
+	if _, err := GenerateSplit(context.Background(), opts, 4, func(int) (io.WriteCloser, error) {
+		return nopCloser{&joined}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if joined.String() != strings.Join(whole, "\n")+"\n" {
+		t.Error("split parts differ from the run")
+	}
+}
+
+func TestHeaderLine_Rejected(t *testing.T) {
+	for _, opts := range []Options{
+		{HeaderLine: "tab\there"},
+		{HeaderLine: "x", ExactBytes: 100},
+		{HeaderLine: "x", Mode: "binrec"},
+		{HeaderLine: "x", EOL: []byte{}},
+	} {
+		opts.Lines, opts.Width = 5, 20
+		if _, _, err := GenerateTo(context.Background(), io.Discard, opts); err == nil {
+			t.Errorf("%+v accepted", opts)
+		}
+	}
+	if _, err := NewSeekable(Options{Lines: 5, Mode: "ascii", HeaderLine: "x"}); err == nil {
+		t.Error("NewSeekable accepted a header line")
+	}
+}
@@ -0,0 +1,119 @@
+package genlines
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// SortOrder is the order Options.Sort writes the data lines in.
+type SortOrder string
+
+const (
+	SortNone SortOrder = ""     // as generated
+	SortAsc  SortOrder = "asc"  // byte-wise ascending
+	SortDesc SortOrder = "desc" // byte-wise descending
+)
+
+// DefaultSortMaxMemory is the memory cap used when Options.SortMaxMemory is
+// not set.
+const DefaultSortMaxMemory = 256 << 20
+
+// sortLineOverhead is the memory a held line takes besides its bytes.
+const sortLineOverhead = 16
+
+// ErrSortMemory is returned when the lines of a sorted run would not fit in
+// Options.SortMaxMemory.
+var ErrSortMemory = errors.New("too many lines to sort in memory")
+
+// errSortUnsupported is returned for settings that depend on the order the
+// lines are generated in: sizes planned line by line, widths and markers
+// tied to a line number, and modes that are not lines of text.
+var errSortUnsupported = errors.New("sorting cannot be combined with an exact byte size, a byte ceiling, alignment, a width ramp, continuation markers, a safe start, mode=blocks or mode=binrec")
+
+// ParseSortOrder parses "asc" or "desc".
+func ParseSortOrder(s string) (SortOrder, error) {
+	switch o := SortOrder(strings.ToLower(strings.TrimSpace(s))); o {
+	case SortAsc, SortDesc:
+		return o, nil
+	}
+	return SortNone, fmt.Errorf("invalid sort order %q (expected asc or desc)", s)
+}
+
+// SortMemory returns an estimate of the memory a run of opts with Sort set
+// holds its lines in: every data line at its widest, plus the bookkeeping
+// of each.
+func SortMemory(opts Options) int64 {
+	opts = opts.withDefaults()
+	f := int64(0)
+	modes, args := []string{opts.Mode}, []string{opts.ModeArg}
+	if IsInterleaveSpec(opts.Mode) {
+		streams, _ := ParseInterleave(opts.Mode)
+		modes, args = nil, nil
+		for _, s := range streams {
+			modes, args = append(modes, s.Mode), append(args, s.ModeArg)
+		}
+	}
+	for i, mode := range modes {
+		n, err := opts.columnBytes(mode, args[i])
+		if err != nil {
+			n = utf8.UTFMax
+			if opts.EscapeNonASCII {
+				n = 12 // a surrogate pair of escapes
+			}
+		}
+		f = max(f, n)
+	}
+	per := int64(opts.widest())*f + sortLineOverhead
+	if opts.TrailingWS.Enabled() {
+		per += MaxTrailingWS
+	}
+	if opts.InjectUnicode.Enabled() {
+		per += 12
+	}
+	if lines := int64(opts.Lines); lines > 0 && per > math.MaxInt64/lines {
+		return math.MaxInt64
+	}
+	return int64(opts.Lines) * per
+}
+
+// validateSort checks o.Sort (with defaults applied).
+func (o Options) validateSort() error {
+	switch o.Sort {
+	case SortNone:
+		return nil
+	case SortAsc, SortDesc:
+	default:
+		return fmt.Errorf("invalid sort order %q (expected asc or desc)", o.Sort)
+	}
+	if mode := canonicalMode(o.Mode); o.ExactBytes > 0 || o.MaxBytes > 0 || o.Align > 0 || o.Ramp.Enabled() || o.Continuation != "" ||
+		o.SafeStart || mode == "blocks" || mode == "binrec" {
+		return errSortUnsupported
+	}
+	if need := SortMemory(o); need > o.SortMaxMemory {
+		return fmt.Errorf("%w: sorting %d lines takes about %d bytes, over the cap of %d", ErrSortMemory, o.Lines, need, o.SortMaxMemory)
+	}
+	return nil
+}
+
+// sortLines generates count data lines from gen through l and keeps them,
+// sorted in order, for nextLine to hand out instead.
+func (l *layout) sortLines(gen Generator, count int64, order SortOrder) error {
+	lines := make([]string, count)
+	failing, _ := gen.(failingGenerator)
+	for n := range count {
+		lines[n] = l.nextLine(gen, n+1)
+		if failing != nil && failing.Err() != nil {
+			return fmt.Errorf("line %d: %w", n+1, failing.Err())
+		}
+	}
+	slices.Sort(lines)
+	if order == SortDesc {
+		slices.Reverse(lines)
+	}
+	l.sorted = lines
+	return nil
+}
@@ -0,0 +1,139 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSort_MatchesSortStrings(t *testing.T) {
+	for _, opts := range []Options{
+		{Lines: 200, Width: 30, Mode: "random", ModeArg: "7"},
+		{Lines: 120, Width: 40, Mode: "lorem", ModeArg: "no", LineChecksum: true},
+		{Lines: 90, Width: 24, Mode: "ascii:24+random:24:5"},
+		{Lines: 150, Width: 30, Mode: "random", ModeArg: "2", TrailingWS: TrailingWS{Fraction: 0.5, HasSeed: true}},
+	} {
+		var plain bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &plain, opts); err != nil {
+			t.Fatal(err)
+		}
+		want := strings.Split(strings.TrimSuffix(plain.String(), "\n"), "\n")
+		sort.Strings(want)
+
+		for _, order := range []SortOrder{SortAsc, SortDesc} {
+			sorted := opts
+			sorted.Sort = order
+			var buf bytes.Buffer
+			lines, n, err := GenerateTo(context.Background(), &buf, sorted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lines != int64(opts.Lines) || n != int64(plain.Len()) {
+				t.Errorf("mode=%s %s: %d lines, %d bytes", opts.Mode, order, lines, n)
+			}
+			got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if order == SortDesc {
+				slices.Reverse(got)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("mode=%s %s: lines not in byte-wise order", opts.Mode, order)
+			}
+		}
+	}
+}
+
+func TestSort_CommentsSplitAndLineInfo(t *testing.T) {
+	opts := Options{Lines: 40, Width: 20, Mode: "random", ModeArg: "1", Sort: SortAsc, CommentEvery: 10, LineChecksum: true,
+		TrailingWS: TrailingWS{Fraction: 0.3, HasSeed: true}}
+	var whole bytes.Buffer
+	var infos []LineInfo
+	opts.OnLine = func(li LineInfo) { infos = append(infos, li) }
+	if _, _, err := GenerateTo(context.Background(), &whole, opts); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(whole.String(), "\n")
+	if lines[10] != "# checkpoint 10" || lines[43] != "# checkpoint 40" {
+		t.Errorf("comment lines moved: %q, %q", lines[10], lines[43])
+	}
+	for _, li := range infos {
+		line := string(whole.Bytes()[li.Offset : li.Offset+int64(li.Bytes)])
+		if !strings.HasSuffix(strings.TrimRight(line, " \t"), " "+li.Checksum) {
+			t.Errorf("line %d %q: checksum %q", li.Number, line, li.Checksum)
+		}
+	}
+	var data strings.Builder
+	for _, line := range lines[:len(lines)-1] {
+		if !strings.HasPrefix(line, "#") {
+			data.WriteString(line + "\n")
+		}
+	}
+	if _, err := VerifyLines(strings.NewReader(data.String()), func(n int64, line string) { t.Errorf("line %d fails its checksum: %q", n, line) }); err != nil {
+		t.Fatal(err)
+	}
+
+	var joined bytes.Buffer
+	opts.OnLine = nil
+	_, err := GenerateSplit(context.Background(), opts, 7, func(int) (io.WriteCloser, error) {
+		return nopCloser{&joined}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if joined.String() != strings.Join(lines, "\n") {
+		t.Error("split parts of a sorted run differ from the run")
+	}
+}
+
+func TestSort_MemoryCap(t *testing.T) {
+	opts := Options{Lines: 1000, Width: 100, Mode: "ascii", Sort: SortAsc, SortMaxMemory: 64 << 10}
+	_, _, err := GenerateTo(context.Background(), io.Discard, opts)
+	if !errors.Is(err, ErrSortMemory) || !strings.Contains(err.Error(), "sorting 1000 lines takes about 116000 bytes, over the cap of 65536") {
+		t.Fatalf("err = %v", err)
+	}
+	if need := SortMemory(opts); need != 1000*(100+sortLineOverhead) {
+		t.Errorf("SortMemory = %d", need)
+	}
+	opts.SortMaxMemory = 1 << 20
+	if _, _, err := GenerateTo(context.Background(), io.Discard, opts); err != nil {
+		t.Errorf("within the cap: %v", err)
+	}
+	// Without Sort nothing is held, whatever the cap.
+	opts.Sort, opts.SortMaxMemory = SortNone, 1
+	if _, _, err := GenerateTo(context.Background(), io.Discard, opts); err != nil {
+		t.Errorf("unsorted: %v", err)
+	}
+}
+
+func TestSort_Unsupported(t *testing.T) {
+	for _, opts := range []Options{
+		{Mode: "ascii", ExactBytes: 100},
+		{Mode: "ascii", MaxBytes: 100},
+		{Mode: "ascii", Align: 4096},
+		{Mode: "ascii", Ramp: Ramp{Min: 10, Max: 20, Step: 1}},
+		{Mode: "ascii", Continuation: DefaultContinuation},
+		{Mode: "ascii", SafeStart: true},
+		{Mode: "blocks"},
+		{Mode: "binrec", EOL: []byte{}},
+	} {
+		opts.Lines, opts.Width, opts.Sort = 5, 40, SortAsc
+		if _, _, err := GenerateTo(context.Background(), io.Discard, opts); err == nil {
+			t.Errorf("%+v accepted", opts)
+		}
+	}
+	if _, err := NewSeekable(Options{Lines: 5, Mode: "ascii", Sort: SortDesc}); err == nil {
+		t.Error("NewSeekable accepted Sort")
+	}
+	for _, s := range []string{"", "up", "ascending"} {
+		if _, err := ParseSortOrder(s); err == nil {
+			t.Errorf("ParseSortOrder(%q) accepted", s)
+		}
+	}
+	if o, err := ParseSortOrder(" DESC "); o != SortDesc || err != nil {
+		t.Errorf("ParseSortOrder = %q, %v", o, err)
+	}
+}
@@ -0,0 +1,99 @@
+package genlines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Part describes one output written by GenerateSplit.
+type Part struct {
+	Index int   // 1-based part number
+	Lines int64 // data lines in the part
+	Bytes int64 // bytes written to the part
+}
+
+// GenerateSplit streams opts.Lines lines across consecutive parts of at most
+// linesPerPart lines each. create opens the writer for a part (1-based index).
+// Every part is flushed and closed before the next one is created, so only one
+// output is open at a time. Content and comment numbering continue across parts,
+// so concatenating the parts gives the same bytes as a single GenerateTo run.
+//
+// The returned parts cover everything written, including a failed last part.
+func GenerateSplit(ctx context.Context, opts Options, linesPerPart int, create func(index int) (io.WriteCloser, error)) ([]Part, error) {
+	if linesPerPart <= 0 {
+		return nil, fmt.Errorf("invalid lines per part: %d", linesPerPart)
+	}
+	opts = opts.withDefaults()
+	var parts []Part
+	rec := newStatsRecorder(opts)
+	defer func() {
+		var lines, bytes int64
+		for _, p := range parts {
+			lines += p.Lines
+			bytes += p.Bytes
+		}
+		rec.finish(lines, bytes)
+	}()
+
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts.ExactBytes > 0 {
+		return nil, errors.New("an exact byte size is not supported with split output")
+	}
+	if opts.Align > 0 {
+		return nil, errors.New("alignment is not supported with split output")
+	}
+	if opts.MaxBytes > 0 {
+		return nil, errors.New("a byte ceiling is not supported with split output")
+	}
+
+	gen, err := buildGenerator(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var progress func(int64)
+	if opts.Progress != nil {
+		progress = func(n int64) {
+			if n%opts.ProgressEvery == 0 {
+				opts.Progress(n)
+			}
+		}
+	}
+
+	lay := opts.layout()
+	lay.flushes = rec.flushCounter()
+	if opts.Sort != SortNone {
+		if err := lay.sortLines(gen, int64(opts.Lines), opts.Sort); err != nil {
+			return nil, err
+		}
+	}
+
+	total, per := int64(opts.Lines), int64(linesPerPart)
+	for start, index := int64(0), 1; start < total; start, index = start+per, index+1 {
+		count := min(per, total-start)
+
+		w, err := create(index)
+		if err != nil {
+			return parts, fmt.Errorf("part %d: %w", index, err)
+		}
+		lines, bytes, werr := writeLines(ctx, rec.wrap(w), gen, lay, start, count, progress)
+		cerr := w.Close()
+		parts = append(parts, Part{Index: index, Lines: lines, Bytes: bytes})
+
+		if werr != nil || cerr != nil {
+			if cerr != nil {
+				cerr = fmt.Errorf("closing: %w", cerr)
+			}
+			return parts, fmt.Errorf("part %d: %w", index, errors.Join(werr, cerr))
+		}
+	}
+
+	if opts.Progress != nil && total > 0 && total%opts.ProgressEvery != 0 {
+		opts.Progress(total)
+	}
+	return parts, nil
+}
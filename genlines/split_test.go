@@ -0,0 +1,94 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestGenerateSplit_PartsConcatenateToSingleRun(t *testing.T) {
+	opts := Options{Lines: 23, Width: 7, Mode: "alpha", CommentEvery: 4}
+
+	var parts []*bytes.Buffer
+	got, err := GenerateSplit(context.Background(), opts, 10, func(index int) (io.WriteCloser, error) {
+		if index != len(parts)+1 {
+			t.Fatalf("create called with index %d, want %d", index, len(parts)+1)
+		}
+		b := new(bytes.Buffer)
+		parts = append(parts, b)
+		return nopCloser{b}, nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateSplit: %v", err)
+	}
+
+	wantLines := []int64{10, 10, 3}
+	if len(got) != len(wantLines) {
+		t.Fatalf("got %d parts, want %d", len(got), len(wantLines))
+	}
+	var joined bytes.Buffer
+	for i, p := range got {
+		if p.Index != i+1 || p.Lines != wantLines[i] || p.Bytes != int64(parts[i].Len()) {
+			t.Errorf("part %d = %+v (buffer has %d bytes)", i, p, parts[i].Len())
+		}
+		joined.Write(parts[i].Bytes())
+	}
+
+	var single bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &single, opts); err != nil {
+		t.Fatalf("GenerateTo: %v", err)
+	}
+	if joined.String() != single.String() {
+		t.Errorf("parts joined:\n%s\nsingle run:\n%s", joined.String(), single.String())
+	}
+}
+
+func TestGenerateSplit_CreateErrorNamesPart(t *testing.T) {
+	boom := errors.New("boom")
+	parts, err := GenerateSplit(context.Background(), Options{Lines: 5, Width: 3}, 2, func(index int) (io.WriteCloser, error) {
+		if index == 2 {
+			return nil, boom
+		}
+		return nopCloser{new(bytes.Buffer)}, nil
+	})
+	if !errors.Is(err, boom) || err.Error() != "part 2: boom" {
+		t.Fatalf("err = %v", err)
+	}
+	if len(parts) != 1 || parts[0].Lines != 2 {
+		t.Errorf("parts = %+v", parts)
+	}
+}
+
+func TestGenerateSplit_OneFileOpenAtATime(t *testing.T) {
+	if _, err := os.ReadDir("/proc/self/fd"); err != nil {
+		t.Skip("no /proc/self/fd")
+	}
+	openFDs := func() int {
+		entries, _ := os.ReadDir("/proc/self/fd")
+		return len(entries)
+	}
+	dir := t.TempDir()
+	base := -1
+	_, err := GenerateSplit(context.Background(), Options{Lines: 2000, Width: 3}, 1, func(index int) (io.WriteCloser, error) {
+		// Every earlier part is closed by now: the count is the same at
+		// every create.
+		if n := openFDs(); base < 0 {
+			base = n
+		} else if n != base {
+			t.Fatalf("part %d: %d descriptors open, %d at part 1", index, n, base)
+		}
+		return os.Create(filepath.Join(dir, fmt.Sprintf("p%d", index)))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
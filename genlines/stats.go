@@ -0,0 +1,173 @@
+package genlines
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sort"
+	"time"
+)
+
+// checksumAlgorithms maps the names Options.Checksums accepts to their hash.
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha1":   sha1.New,
+	"md5":    md5.New,
+	"crc32":  func() hash.Hash { return crc32.NewIEEE() },
+}
+
+// ChecksumAlgorithms returns the names Options.Checksums accepts, sorted.
+func ChecksumAlgorithms() []string {
+	names := make([]string, 0, len(checksumAlgorithms))
+	for name := range checksumAlgorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stats summarizes a run for callers that report on it, as the CLI does. It
+// is filled in through Options.Stats when GenerateTo or GenerateSplit
+// returns, also after a failure, and then describes the output that reached
+// the writer.
+type Stats struct {
+	Lines    int64         `json:"lines"` // data lines written, blank ones included
+	Bytes    int64         `json:"bytes"` // bytes written, all lines and padding included
+	Duration time.Duration `json:"duration"`
+	Mode     string        `json:"mode"` // canonical mode name or interleave spec
+	Width    int           `json:"width"`
+	Seed     uint64        `json:"seed,omitempty"` // global seed, if HasSeed
+	HasSeed  bool          `json:"hasSeed,omitempty"`
+
+	// TrailingLines is how many of the data lines written end in trailing
+	// whitespace (see Options.TrailingWS), and TrailingBytes how many spaces
+	// and tabs that adds up to.
+	TrailingLines int64 `json:"trailingLines,omitempty"`
+	TrailingBytes int64 `json:"trailingBytes,omitempty"`
+
+	// BlankLines is how many of the data lines written are blank lines of
+	// Options.LinePattern.
+	BlankLines int64 `json:"blankLines,omitempty"`
+
+	// Flushes is how many times the buffered output was pushed to the
+	// writer before it was full: every Options.FlushEvery lines and at the
+	// end.
+	Flushes int64 `json:"flushes,omitempty"`
+
+	// Injected counts the code points of Options.InjectUnicode written, by
+	// name (see InvisibleRuneNames).
+	Injected map[string]int64 `json:"injected,omitempty"`
+
+	// Checksums holds the hex digest of the bytes written for every
+	// algorithm named in Options.Checksums (concatenated over all parts
+	// of a GenerateSplit run).
+	Checksums map[string]string `json:"checksums,omitempty"`
+}
+
+// validateChecksums checks the algorithm names of o.Checksums.
+func (o Options) validateChecksums() error {
+	for _, name := range o.Checksums {
+		if _, ok := checksumAlgorithms[name]; !ok {
+			return fmt.Errorf("unknown checksum algorithm %q (expected one of %v)", name, ChecksumAlgorithms())
+		}
+	}
+	return nil
+}
+
+// statsRecorder collects the Stats of one run of o (with defaults applied).
+type statsRecorder struct {
+	dst      *Stats
+	start    time.Time
+	stats    Stats
+	hashes   map[string]hash.Hash
+	trailing TrailingWS
+	inject   InjectUnicode
+	pattern  LinePattern
+}
+
+// newStatsRecorder starts the clock for a run of o, or returns nil if o has
+// no Stats to fill in.
+func newStatsRecorder(o Options) *statsRecorder {
+	if o.Stats == nil {
+		return nil
+	}
+	mode := o.Mode
+	if name := canonicalMode(mode); name != "" {
+		mode = name
+	}
+	r := &statsRecorder{
+		dst:      o.Stats,
+		start:    time.Now(),
+		stats:    Stats{Mode: mode, Width: o.Width},
+		hashes:   make(map[string]hash.Hash),
+		trailing: o.TrailingWS.resolve(o),
+		inject:   o.InjectUnicode.resolve(o),
+		pattern:  o.LinePattern,
+	}
+	if o.HasSeed {
+		r.stats.Seed, r.stats.HasSeed = o.Seed, true
+	}
+	for _, name := range o.Checksums {
+		if newHash, ok := checksumAlgorithms[name]; ok {
+			r.hashes[name] = newHash()
+		}
+	}
+	return r
+}
+
+// wrap returns w, hashing what reaches it if r computes checksums.
+func (r *statsRecorder) wrap(w io.Writer) io.Writer {
+	if r == nil || len(r.hashes) == 0 {
+		return w
+	}
+	return &hashingWriter{w: w, hashes: r.hashes}
+}
+
+// flushCounter returns where the flushes of the run are counted, or nil if r
+// is nil.
+func (r *statsRecorder) flushCounter() *int64 {
+	if r == nil {
+		return nil
+	}
+	return &r.stats.Flushes
+}
+
+// finish stores the Stats of a run that wrote lines and bytes.
+func (r *statsRecorder) finish(lines, bytes int64) {
+	if r == nil {
+		return
+	}
+	st := r.stats
+	st.Lines, st.Bytes = lines, bytes
+	st.Duration = time.Since(r.start)
+	content := r.pattern.content(lines)
+	st.BlankLines = lines - content
+	st.TrailingLines, st.TrailingBytes = r.trailing.count(content)
+	st.Injected = r.inject.count(content)
+	if len(r.hashes) > 0 {
+		st.Checksums = make(map[string]string, len(r.hashes))
+		for name, h := range r.hashes {
+			st.Checksums[name] = hex.EncodeToString(h.Sum(nil))
+		}
+	}
+	*r.dst = st
+}
+
+// hashingWriter passes writes to w and hashes the bytes w accepted.
+type hashingWriter struct {
+	w      io.Writer
+	hashes map[string]hash.Hash
+}
+
+func (h *hashingWriter) Write(p []byte) (int, error) {
+	n, err := h.w.Write(p)
+	for _, sum := range h.hashes {
+		sum.Write(p[:n])
+	}
+	return n, err
+}
@@ -0,0 +1,100 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"testing"
+)
+
+// wantChecksums returns the sha256 and crc32 digests of data as Stats reports them.
+func wantChecksums(data []byte) map[string]string {
+	sum := sha256.Sum256(data)
+	return map[string]string{
+		"sha256": hex.EncodeToString(sum[:]),
+		"crc32":  fmt.Sprintf("%08x", crc32.ChecksumIEEE(data)),
+	}
+}
+
+func TestStats_MatchesOutput(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"default":    {Lines: 50},
+		"crlf":       {Lines: 20, Width: 30, Mode: "upper", EOL: []byte("\r\n"), LineChecksum: true},
+		"comments":   {Lines: 25, Width: 10, Mode: "digits", CommentEvery: 7},
+		"exact":      {Width: 16, Mode: "alpha", ExactBytes: 1000},
+		"seeded":     {Lines: 10, Width: 12, Mode: "rand", Seed: 42, HasSeed: true},
+		"interleave": {Lines: 9, Mode: "digits:5+upper:8"},
+		"empty":      {Lines: 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var st Stats
+			opts.Stats, opts.Checksums = &st, []string{"sha256", "crc32"}
+			var buf bytes.Buffer
+			lines, n, err := GenerateTo(context.Background(), &buf, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if st.Lines != lines || st.Bytes != n || st.Bytes != int64(buf.Len()) {
+				t.Errorf("stats %d lines, %d bytes; wrote %d lines, %d bytes (buffer %d)", st.Lines, st.Bytes, lines, n, buf.Len())
+			}
+			want := wantChecksums(buf.Bytes())
+			if st.Checksums["sha256"] != want["sha256"] || st.Checksums["crc32"] != want["crc32"] {
+				t.Errorf("checksums %v, want %v", st.Checksums, want)
+			}
+			if st.HasSeed != opts.HasSeed || st.Seed != opts.Seed || st.Duration <= 0 {
+				t.Errorf("stats %+v", st)
+			}
+		})
+	}
+}
+
+func TestStats_ModeAndWidthDefaults(t *testing.T) {
+	var st Stats
+	if _, _, err := GenerateTo(context.Background(), io.Discard, Options{Lines: 1, Mode: "numbers", Stats: &st}); err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode != "digits" || st.Width != DefaultWidth || st.Checksums != nil {
+		t.Errorf("stats %+v, want mode digits, width %d and no checksums", st, DefaultWidth)
+	}
+}
+
+func TestStats_PartialFailure(t *testing.T) {
+	var st Stats
+	fw := &failingWriter{limit: 100000}
+	lines, n, err := GenerateTo(context.Background(), fw, Options{Lines: 10000, Stats: &st, Checksums: []string{"sha256", "crc32"}})
+	if !errors.Is(err, errDiskFull) {
+		t.Fatalf("expected errDiskFull, got %v", err)
+	}
+	if st.Lines != lines || st.Bytes != n || st.Bytes != int64(fw.buf.Len()) {
+		t.Errorf("stats %d lines, %d bytes; wrote %d lines, %d bytes (writer %d)", st.Lines, st.Bytes, lines, n, fw.buf.Len())
+	}
+	if want := wantChecksums(fw.buf.Bytes()); st.Checksums["sha256"] != want["sha256"] {
+		t.Errorf("sha256 %s covers other bytes than the %d accepted", st.Checksums["sha256"], fw.buf.Len())
+	}
+}
+
+func TestStats_SplitAndRejected(t *testing.T) {
+	var st Stats
+	var all bytes.Buffer
+	create := func(int) (io.WriteCloser, error) { return nopCloser{&all}, nil }
+	opts := Options{Lines: 25, Width: 8, Stats: &st, Checksums: []string{"sha256"}}
+	if _, err := GenerateSplit(context.Background(), opts, 10, create); err != nil {
+		t.Fatal(err)
+	}
+	if st.Lines != 25 || st.Bytes != int64(all.Len()) || st.Checksums["sha256"] != wantChecksums(all.Bytes())["sha256"] {
+		t.Errorf("split stats %+v for %d bytes", st, all.Len())
+	}
+
+	st = Stats{Lines: -1}
+	if _, _, err := GenerateTo(context.Background(), io.Discard, Options{Lines: 1, Stats: &st, Checksums: []string{"sha3"}}); err == nil {
+		t.Fatal("expected an unknown checksum algorithm to fail")
+	}
+	if st.Lines != 0 || st.Bytes != 0 {
+		t.Errorf("rejected run left stats %+v", st)
+	}
+}
@@ -0,0 +1,40 @@
+package genlines
+
+import "sync"
+
+// syncGen serializes the calls to a Generator shared between goroutines.
+type syncGen struct {
+	mu  sync.Mutex
+	gen Generator
+}
+
+// SyncGenerator wraps g so it can be shared between goroutines: each
+// NextLine call holds a lock for the whole line, so every line is one whole
+// line of g and no content is lost or repeated, though which goroutine gets
+// which line depends on scheduling. Generators are not safe for concurrent
+// use otherwise. The wrapper does not implement LineAppender, since the
+// pieces of a line would not stay together, so wide lines are held whole.
+func SyncGenerator(g Generator) Generator {
+	if _, ok := g.(*syncGen); ok {
+		return g
+	}
+	return &syncGen{gen: g}
+}
+
+// NextLine returns the next line of the wrapped generator.
+func (s *syncGen) NextLine(width int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gen.NextLine(width)
+}
+
+// Err reports why the last line of the wrapped generator failed, if it can
+// fail (see failingGenerator).
+func (s *syncGen) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.gen.(failingGenerator); ok {
+		return f.Err()
+	}
+	return nil
+}
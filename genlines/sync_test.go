@@ -0,0 +1,60 @@
+package genlines
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Run with -race: the wrapped generator is hammered from several goroutines.
+func TestSyncGenerator_SharedAcrossGoroutines(t *testing.T) {
+	const workers, perWorker, width = 8, 500, 37
+	for _, mode := range []string{"ascii", "digits", "alpha"} {
+		gen, err := NewModeGenerator(mode, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		shared := SyncGenerator(gen)
+		if SyncGenerator(shared) != shared {
+			t.Errorf("mode=%s: wrapping twice added a layer", mode)
+		}
+
+		got := make([][]string, workers)
+		var wg sync.WaitGroup
+		for w := range workers {
+			wg.Go(func() {
+				for range perWorker {
+					got[w] = append(got[w], shared.NextLine(width))
+				}
+			})
+		}
+		wg.Wait()
+
+		// The palettes cycle, so the lines are in another order but together
+		// hold the characters of a sequential run, each line whole.
+		seq, _ := NewModeGenerator(mode, "")
+		var want, all []string
+		for range workers * perWorker {
+			want = append(want, seq.NextLine(width))
+		}
+		for _, lines := range got {
+			all = append(all, lines...)
+		}
+		if chars(all) != chars(want) {
+			t.Errorf("mode=%s: the shared run emitted other characters than a sequential one", mode)
+		}
+		slices.Sort(all)
+		slices.Sort(want)
+		if !slices.Equal(all, want) {
+			t.Errorf("mode=%s: the shared run's lines are not those of a sequential run", mode)
+		}
+	}
+}
+
+// chars returns the characters of lines, sorted.
+func chars(lines []string) string {
+	b := []byte(strings.Join(lines, ""))
+	slices.Sort(b)
+	return string(b)
+}
@@ -0,0 +1,108 @@
+package genlines
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// ErrSizeUnknown is returned by PlanSize for modes whose line lengths depend
+// on their content, such as template.
+var ErrSizeUnknown = errors.New("the output size depends on the generated content and cannot be planned")
+
+// TemplateLine is the data a template mode template is executed with, once
+// per data line.
+type TemplateLine struct {
+	Line  int // one-based data line number
+	Width int // the line's width setting
+	Total int // data lines in the run
+
+	// Fill returns the next n characters of the printable ASCII cycle, which
+	// continues across calls and lines. Also available as {{Fill n}}.
+	Fill func(n int) string
+	// Rand returns n seeded pseudo-random printable ASCII characters. Also
+	// available as {{Rand n}}.
+	Rand func(n int) string
+}
+
+// newTemplateGen parses the template given by arg: [seed:<n>:]<path> or
+// [seed:<n>:]inline:<text>. The seed (default 0) drives Rand.
+func newTemplateGen(arg string, _ int) (Generator, error) {
+	var seed uint64
+	if rest, ok := strings.CutPrefix(arg, "seed:"); ok {
+		n, text, ok := strings.Cut(rest, ":")
+		s, err := strconv.ParseUint(n, 10, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("mode=template: invalid seed prefix in %q (expected seed:<n>:<template>)", arg)
+		}
+		seed, arg = s, text
+	}
+
+	name, text := "inline", ""
+	if t, ok := strings.CutPrefix(arg, "inline:"); ok {
+		text = t
+	} else {
+		if strings.TrimSpace(arg) == "" {
+			return nil, errors.New("mode=template requires modeArg: a template file or inline:<text>")
+		}
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("mode=template: %w", err)
+		}
+		name, text = arg, string(data)
+	}
+
+	g := &templateGen{
+		fill: &cycleGen{palette: []byte(AsciiSequence())},
+		rand: newRandomGen(seed, []byte(AsciiSequence())),
+	}
+	funcs := template.FuncMap{"Fill": g.fillN, "Rand": g.randN}
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("mode=template: %w", err)
+	}
+	g.tmpl = tmpl
+	return g, nil
+}
+
+// seedTemplateArg is the ModeSpec.SeedArg of the template mode.
+func seedTemplateArg(seed uint64, arg string) string {
+	if strings.HasPrefix(arg, "seed:") {
+		return arg
+	}
+	return "seed:" + strconv.FormatUint(seed, 10) + ":" + arg
+}
+
+// templateGen renders a text/template per line. The output is used as is:
+// only Fill ties it to the width. A failed execution yields an empty line
+// and is reported by Err.
+type templateGen struct {
+	tmpl  *template.Template
+	fill  *cycleGen
+	rand  *randomGen
+	total int
+	line  int
+	buf   bytes.Buffer
+	err   error
+}
+
+func (g *templateGen) fillN(n int) string { return g.fill.NextLine(max(n, 0)) }
+func (g *templateGen) randN(n int) string { return g.rand.NextLine(max(n, 0)) }
+
+func (g *templateGen) NextLine(width int) string {
+	g.line++
+	g.buf.Reset()
+	data := TemplateLine{Line: g.line, Width: width, Total: g.total, Fill: g.fillN, Rand: g.randN}
+	if err := g.tmpl.Execute(&g.buf, data); err != nil {
+		g.err = err
+		return ""
+	}
+	return g.buf.String()
+}
+
+// Err returns the error of the last failed execution.
+func (g *templateGen) Err() error { return g.err }
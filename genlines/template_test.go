@@ -0,0 +1,100 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplate_LineAndFill(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{Lines: 3, Width: 40, Mode: "template", ModeArg: "inline:line {{.Line}}: {{Fill 20}}"}
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	ascii := AsciiSequence()
+	want := "line 1: " + ascii[0:20] + "\n" +
+		"line 2: " + ascii[20:40] + "\n" +
+		"line 3: " + ascii[40:60] + "\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestTemplate_ContextAndFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "line.tmpl")
+	os.WriteFile(path, []byte(`{{.Line}}/{{.Total}} w={{.Width}} {{call .Fill 3}}`), 0o644)
+	var buf bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 2, Width: 9, Mode: "tmpl", ModeArg: path}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "1/2 w=9  !\"\n2/2 w=9 #$%\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestTemplate_RandIsSeeded(t *testing.T) {
+	gen := func(arg string) string {
+		var buf bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 3, Mode: "template", ModeArg: arg}); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	a, b, c := gen("inline:{{Rand 16}}"), gen("inline:{{Rand 16}}"), gen("seed:9:inline:{{Rand 16}}")
+	if a != b {
+		t.Error("the same template gave different Rand output")
+	}
+	if a == c {
+		t.Error("seed:9: did not change the Rand output")
+	}
+	if len(a) != 3*17 {
+		t.Errorf("unexpected output %q", a)
+	}
+}
+
+func TestTemplate_ParseErrorBeforeOutput(t *testing.T) {
+	var buf bytes.Buffer
+	_, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 3, Mode: "template", ModeArg: "inline:ok\n{{.Line"})
+	if err == nil || !strings.Contains(err.Error(), "inline:2") {
+		t.Fatalf("expected a parse error naming line 2, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %d bytes before failing", buf.Len())
+	}
+}
+
+func TestTemplate_ExecErrorNamesLine(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{Lines: 5, Mode: "template", ModeArg: "inline:{{if eq .Line 3}}{{.Nope}}{{end}}x"}
+	lines, _, err := GenerateTo(context.Background(), &buf, opts)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3: ") {
+		t.Fatalf("expected an error for line 3, got %v", err)
+	}
+	if lines != 2 || buf.String() != "x\nx\n" {
+		t.Errorf("wrote %d lines %q before failing", lines, buf.String())
+	}
+
+	gen, _ := NewGenerator("template", opts.ModeArg, 0)
+	_, err = io.ReadAll(NewReader(gen, 10, 5, nil))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3: ") {
+		t.Errorf("reader: expected an error for line 3, got %v", err)
+	}
+}
+
+func TestTemplate_SizeUnknown(t *testing.T) {
+	if _, err := PlanSize(Options{Lines: 3, Mode: "template", ModeArg: "inline:x"}); !errors.Is(err, ErrSizeUnknown) {
+		t.Errorf("PlanSize: got %v", err)
+	}
+	if _, err := PlanSize(Options{Mode: "template", ModeArg: "inline:x", ExactBytes: 10}); err == nil {
+		t.Error("expected an error for an exact byte size")
+	}
+	if _, err := ParseInterleave("template:5:inline:x+ascii:5"); err == nil {
+		t.Error("expected an error for an interleaved template")
+	}
+}
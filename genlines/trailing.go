@@ -0,0 +1,131 @@
+package genlines
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SeedLabelTrailingWS labels the seed trailing whitespace derives from the
+// global seed when TrailingWS has none of its own.
+const SeedLabelTrailingWS = "trailing-ws"
+
+// MaxTrailingWS is the most whitespace characters TrailingWS adds to a line.
+const MaxTrailingWS = 3
+
+// TrailingWS adds 1 to MaxTrailingWS spaces or tabs to the end of a share of
+// the data lines, beyond their width, for testing how consumers handle
+// trailing whitespace. Which lines get it, and what, depends only on the seed
+// and the line number, so a run is reproducible and split parts match it.
+type TrailingWS struct {
+	Fraction float64 // share of data lines that get whitespace, in (0, 1]; 0 = off
+	Seed     uint64
+	HasSeed  bool // Seed is set; otherwise it derives from Options.Seed, or is 0
+}
+
+// Enabled reports whether t adds whitespace to any line.
+func (t TrailingWS) Enabled() bool {
+	return t.Fraction > 0
+}
+
+// ParseTrailingWS parses "P[:seed]": P is the share of lines, as a fraction
+// (0.1) or a percentage (10%), and seed a decimal uint64.
+func ParseTrailingWS(spec string) (TrailingWS, error) {
+	p, seed, hasSeed := strings.Cut(strings.TrimSpace(spec), ":")
+	var (
+		t   TrailingWS
+		err error
+	)
+	if pct, ok := strings.CutSuffix(p, "%"); ok {
+		t.Fraction, err = strconv.ParseFloat(pct, 64)
+		t.Fraction /= 100
+	} else {
+		t.Fraction, err = strconv.ParseFloat(p, 64)
+	}
+	if err != nil || !(t.Fraction > 0 && t.Fraction <= 1) {
+		return TrailingWS{}, fmt.Errorf("invalid trailing whitespace share %q (expected a fraction in (0, 1] or a percentage such as 10%%)", p)
+	}
+	if hasSeed {
+		if t.Seed, err = ParseSeed(seed); err != nil {
+			return TrailingWS{}, fmt.Errorf("invalid trailing whitespace seed: %w", err)
+		}
+		t.HasSeed = true
+	}
+	return t, nil
+}
+
+// String returns t in the form ParseTrailingWS accepts.
+func (t TrailingWS) String() string {
+	s := strconv.FormatFloat(t.Fraction, 'g', -1, 64)
+	if t.HasSeed {
+		s += ":" + strconv.FormatUint(t.Seed, 10)
+	}
+	return s
+}
+
+// errTrailingWSUnsupported is returned for settings that need to know where
+// a line ends: an exact byte size plans the partial line from uniform line
+// sizes, binrec records have no terminator to pad before, and a continuation
+// marker must end its line.
+var errTrailingWSUnsupported = errors.New("trailing whitespace cannot be combined with an exact byte size, a continuation marker or mode=binrec")
+
+// validateTrailingWS checks o.TrailingWS (with defaults applied).
+func (o Options) validateTrailingWS() error {
+	t := o.TrailingWS
+	if t.Fraction < 0 || t.Fraction > 1 {
+		return fmt.Errorf("invalid trailing whitespace share %g (expected 0 to 1)", t.Fraction)
+	}
+	if t.Enabled() && (o.ExactBytes > 0 || o.Continuation != "" || canonicalMode(o.Mode) == "binrec") {
+		return errTrailingWSUnsupported
+	}
+	return nil
+}
+
+// resolve returns t with the seed it draws from in a run of o.
+func (t TrailingWS) resolve(o Options) TrailingWS {
+	if !t.HasSeed && o.HasSeed {
+		t.Seed, t.HasSeed = DeriveSeed(o.Seed, SeedLabelTrailingWS), true
+	}
+	return t
+}
+
+// suffix returns the whitespace data line n (one-based) ends with, or "".
+func (t TrailingWS) suffix(n int64) string {
+	if !t.Enabled() {
+		return ""
+	}
+	h := splitmix64(t.Seed ^ splitmix64(uint64(n)))
+	if float64(h>>11)/(1<<53) >= t.Fraction {
+		return ""
+	}
+	h = splitmix64(h)
+	ws := make([]byte, 1+h%MaxTrailingWS)
+	for i := range ws {
+		ws[i] = " \t"[h>>(8+i)&1]
+	}
+	return string(ws)
+}
+
+// count returns how many of data lines 1 to lines get whitespace, and how
+// many characters of it in all.
+func (t TrailingWS) count(lines int64) (padded, extra int64) {
+	if !t.Enabled() {
+		return 0, 0
+	}
+	for n := int64(1); n <= lines; n++ {
+		if ws := t.suffix(n); ws != "" {
+			padded++
+			extra += int64(len(ws))
+		}
+	}
+	return padded, extra
+}
+
+// splitmix64 is the SplitMix64 finalizer, a fast bijective mix of x.
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
@@ -0,0 +1,151 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseTrailingWS(t *testing.T) {
+	for spec, want := range map[string]TrailingWS{
+		"0.25":   {Fraction: 0.25},
+		"10%":    {Fraction: 0.1},
+		"1":      {Fraction: 1},
+		"0.5:42": {Fraction: 0.5, Seed: 42, HasSeed: true},
+	} {
+		got, err := ParseTrailingWS(spec)
+		if err != nil || got != want {
+			t.Errorf("%q: %+v, %v; want %+v", spec, got, err, want)
+		}
+	}
+	for _, spec := range []string{"", "0", "1.5", "-0.1", "0%", "200%", "x", "0.5:", "100%:0x"} {
+		if _, err := ParseTrailingWS(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+	if got, _ := ParseTrailingWS("0.2:7"); got.String() != "0.2:7" {
+		t.Errorf("String() = %q", got.String())
+	}
+}
+
+// dataLines splits output into its lines, without terminators.
+func dataLines(out, eol string) []string {
+	return strings.Split(strings.TrimSuffix(out, eol), eol)
+}
+
+func TestTrailingWS_PadsShareOfLines(t *testing.T) {
+	var st Stats
+	opts := Options{Lines: 2000, Width: 20, Mode: "alpha", TrailingWS: TrailingWS{Fraction: 0.3, Seed: 9, HasSeed: true}, Stats: &st}
+	var buf bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	var padded, extra int64
+	for i, line := range dataLines(buf.String(), "\n") {
+		body := strings.TrimRight(line, " \t")
+		if len(body) != opts.Width || strings.ContainsAny(body, " \t") {
+			t.Fatalf("line %d: content %q is not %d letters", i+1, body, opts.Width)
+		}
+		if n := len(line) - len(body); n > 0 {
+			if n > MaxTrailingWS {
+				t.Errorf("line %d: %d trailing characters", i+1, n)
+			}
+			padded++
+			extra += int64(n)
+		}
+	}
+	if padded != st.TrailingLines || extra != st.TrailingBytes {
+		t.Errorf("stats report %d lines, %d bytes; output has %d lines, %d bytes", st.TrailingLines, st.TrailingBytes, padded, extra)
+	}
+	if padded < 500 || padded > 700 {
+		t.Errorf("%d of %d lines padded, want about 30%%", padded, opts.Lines)
+	}
+	if size, err := PlanSize(opts); err != nil || size != int64(buf.Len()) {
+		t.Errorf("PlanSize = %d, %v; wrote %d bytes", size, err, buf.Len())
+	}
+}
+
+func TestTrailingWS_Deterministic(t *testing.T) {
+	run := func(opts Options) string {
+		var buf bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	base := Options{Lines: 200, Width: 10, Mode: "digits", TrailingWS: TrailingWS{Fraction: 0.5}}
+	if run(base) != run(base) {
+		t.Error("two runs differ")
+	}
+	seeded := base
+	seeded.TrailingWS.Seed, seeded.TrailingWS.HasSeed = 1, true
+	if run(seeded) == run(base) {
+		t.Error("a seed does not change which lines are padded")
+	}
+	global := base
+	global.Seed, global.HasSeed = 1, true
+	if run(global) == run(base) {
+		t.Error("the global seed does not change which lines are padded")
+	}
+
+	// Split parts concatenate to the single run.
+	var all bytes.Buffer
+	create := func(int) (io.WriteCloser, error) { return nopCloser{&all}, nil }
+	if _, err := GenerateSplit(context.Background(), seeded, 30, create); err != nil {
+		t.Fatal(err)
+	}
+	if all.String() != run(seeded) {
+		t.Error("split output differs from the single run")
+	}
+}
+
+func TestTrailingWS_CRLFAndChecksums(t *testing.T) {
+	opts := Options{Lines: 300, Width: 24, Mode: "upper", EOL: []byte("\r\n"), LineChecksum: true, TrailingWS: TrailingWS{Fraction: 1}}
+	var buf bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(buf.String(), "\r\n") != opts.Lines || strings.Count(buf.String(), "\n") != opts.Lines {
+		t.Fatal("whitespace broke the CRLF terminators")
+	}
+	for i, line := range dataLines(buf.String(), "\r\n") {
+		body := strings.TrimRight(line, " \t")
+		if body == line || !CheckLine(body) {
+			t.Fatalf("line %d: %q is not a checksummed line with trailing whitespace", i+1, line)
+		}
+	}
+	bad := 0
+	if n, err := VerifyLines(&buf, func(int64, string) { bad++ }); err != nil || n != int64(opts.Lines) || bad != 0 {
+		t.Errorf("VerifyLines checked %d lines, %d bad, err %v", n, bad, err)
+	}
+}
+
+func TestTrailingWS_Aligned(t *testing.T) {
+	opts := Options{Lines: 100, Width: 30, Align: 64, TrailingWS: TrailingWS{Fraction: 0.5}}
+	var buf bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	if size, err := PlanSize(opts); err != nil || size != int64(buf.Len()) {
+		t.Errorf("PlanSize = %d, %v; wrote %d bytes", size, err, buf.Len())
+	}
+}
+
+func TestTrailingWS_Rejected(t *testing.T) {
+	ws := TrailingWS{Fraction: 0.5}
+	for name, opts := range map[string]Options{
+		"share":        {Lines: 1, TrailingWS: TrailingWS{Fraction: 2}},
+		"exact":        {ExactBytes: 100, TrailingWS: ws},
+		"continuation": {Lines: 2, Continuation: "\\", TrailingWS: ws},
+		"binrec":       {Lines: 1, Mode: "binrec", ModeArg: "u8:counter", TrailingWS: ws},
+	} {
+		if _, _, err := GenerateTo(context.Background(), io.Discard, opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := NewSeekable(Options{Lines: 1, TrailingWS: ws}); err == nil {
+		t.Error("NewSeekable accepted trailing whitespace")
+	}
+}
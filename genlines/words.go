@@ -0,0 +1,157 @@
+package genlines
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// loremWords is the built-in word list of the lorem mode: the classic
+// Latin, used without a language.
+var loremWords = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do
+eiusmod tempor incididunt ut labore et dolore magna aliqua ut enim ad minim veniam quis
+nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat duis aute irure
+dolor in reprehenderit in voluptate velit esse cillum dolore eu fugiat nulla pariatur
+excepteur sint occaecat cupidatat non proident sunt in culpa qui officia deserunt mollit anim
+id est laborum`)
+
+// newWordsGen fills lines with the words of a dictionary given as arg: the
+// words themselves, separated by commas or whitespace, or @path to read them
+// from a file.
+func newWordsGen(arg string, _ int) (Generator, error) {
+	arg = strings.TrimSpace(arg)
+	if path, ok := strings.CutPrefix(arg, "@"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("mode=words: %w", err)
+		}
+		arg = string(data)
+	}
+	words := strings.FieldsFunc(arg, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	if len(words) == 0 {
+		return nil, errors.New("mode=words: the dictionary is empty")
+	}
+	return newWordGen("words", words), nil
+}
+
+// loremFiles holds the filler text of the other lorem languages, one file
+// per language code, in UTF-8.
+//
+//go:embed lorem/*.txt
+var loremFiles embed.FS
+
+// LoremLanguages returns the language codes the lorem mode takes as its
+// modeArg, sorted. "la" is the classic Latin, also used without one.
+func LoremLanguages() []string {
+	langs := []string{"la"}
+	entries, _ := loremFiles.ReadDir("lorem")
+	for _, e := range entries {
+		langs = append(langs, strings.TrimSuffix(e.Name(), ".txt"))
+	}
+	slices.Sort(langs)
+	return langs
+}
+
+// loremLanguage returns the words of lorem language lang.
+func loremLanguage(lang string) ([]string, error) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" || lang == "la" {
+		return loremWords, nil
+	}
+	data, err := loremFiles.ReadFile("lorem/" + lang + ".txt")
+	if err != nil || strings.ContainsAny(lang, "/.") {
+		return nil, fmt.Errorf("mode=lorem: unknown language %q (available: %s)", lang, strings.Join(LoremLanguages(), ", "))
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// newLoremGen fills lines with the lorem words of the language given as arg.
+func newLoremGen(arg string, _ int) (Generator, error) {
+	words, err := loremLanguage(arg)
+	if err != nil {
+		return nil, err
+	}
+	return newWordGen("lorem", words), nil
+}
+
+// wordGen fills lines with words in dictionary order, one space between
+// words. A line ends with as much of its last word as fits, so lines are
+// always exactly full and never hold two spaces in a row; the next line
+// starts with the following word. Width counts characters, so words in
+// UTF-8 (æøå) take one column per character.
+type wordGen struct {
+	mode    string
+	words   []string
+	cols    []int // characters of each word
+	longest int   // index of the longest word
+	next    int
+}
+
+func newWordGen(mode string, words []string) *wordGen {
+	g := &wordGen{mode: mode, words: words, cols: make([]int, len(words))}
+	for i, w := range words {
+		g.cols[i] = utf8.RuneCountInString(w)
+		if g.cols[i] > g.cols[g.longest] {
+			g.longest = i
+		}
+	}
+	return g
+}
+
+// checkWidth rejects widths that cannot hold every word of the dictionary.
+func (g *wordGen) checkWidth(width int) error {
+	if n := g.cols[g.longest]; n > width {
+		return fmt.Errorf("mode=%s: dictionary word %q is %d characters long, longer than the line width %d", g.mode, g.words[g.longest], n, width)
+	}
+	return nil
+}
+
+// columnBytes is 1 for ASCII words. Non-ASCII words make a line's size
+// depend on the words it holds.
+func (g *wordGen) columnBytes(bool) int64 {
+	for _, w := range g.words {
+		if !isASCII(w) {
+			return -1
+		}
+	}
+	return 1
+}
+
+func (g *wordGen) NextLine(width int) string {
+	if width <= 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.Grow(width)
+	for col := 0; col < width; {
+		if col > 0 {
+			b.WriteByte(' ')
+			col++
+		}
+		w, n := g.words[g.next], g.cols[g.next]
+		g.next = (g.next + 1) % len(g.words)
+		if n > width-col {
+			n = width - col
+			w = w[:runePrefix(w, n)]
+		}
+		b.WriteString(w)
+		col += n
+	}
+	return b.String()
+}
+
+// runePrefix returns the length in bytes of the first n characters of s.
+func runePrefix(s string, n int) int {
+	i := 0
+	for ; n > 0 && i < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return i
+}
@@ -0,0 +1,183 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestWords_OneWordDictionaryRepeats(t *testing.T) {
+	g, err := NewGenerator("words", "ab", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		width int
+		want  string
+	}{
+		{10, "ab ab ab a"},
+		{9, "ab ab ab "},
+		{2, "ab"},
+		{1000, strings.Repeat("ab ", 334)[:1000]},
+	} {
+		got := g.NextLine(tc.width)
+		if got != tc.want {
+			t.Errorf("width %d: got %q, want %q", tc.width, got, tc.want)
+		}
+		if strings.Contains(got, "  ") {
+			t.Errorf("width %d: %q has a run of spaces", tc.width, got)
+		}
+	}
+}
+
+func TestWords_WidthEqualToWordLength(t *testing.T) {
+	var buf bytes.Buffer
+	_, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 4, Width: 5, Mode: "words", ModeArg: "alpha,gamma"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "alpha\ngamma\nalpha\ngamma\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWords_LongWordIsRejectedUpfront(t *testing.T) {
+	var buf bytes.Buffer
+	_, _, err := GenerateTo(context.Background(), &buf, Options{Lines: 4, Width: 8, Mode: "words", ModeArg: "a, extraordinary ,b"})
+	if err == nil {
+		t.Fatal("expected an error for a word longer than the width")
+	}
+	for _, want := range []string{`"extraordinary"`, "13 characters", "width 8"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %d bytes before failing", buf.Len())
+	}
+
+	_, err = PlanSize(Options{Lines: 4, Mode: "words:3:abcd+ascii:10"})
+	if err == nil || !strings.Contains(err.Error(), "interleave stream 1") {
+		t.Errorf("interleave stream: got %v", err)
+	}
+}
+
+func TestWords_DictionaryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dict.txt")
+	if err := os.WriteFile(path, []byte("one\r\ntwo\n\nthree\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGenerator("dictionary", "@"+path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := g.NextLine(20), "one two three one tw"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := NewGenerator("words", " , ", 0); err == nil {
+		t.Error("expected an error for an empty dictionary")
+	}
+}
+
+func TestLorem_FillsWithoutDoubleSpaces(t *testing.T) {
+	g, err := NewGenerator("lorem", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		line := g.NextLine(37)
+		if len(line) != 37 || strings.Contains(line, "  ") || line[0] == ' ' {
+			t.Fatalf("line %d: %q", i+1, line)
+		}
+	}
+}
+
+func TestLorem_Languages(t *testing.T) {
+	if got := strings.Join(LoremLanguages(), ","); got != "de,en,la,no" {
+		t.Fatalf("languages %s", got)
+	}
+	for _, lang := range LoremLanguages() {
+		words, err := loremLanguage(lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dict := map[string]bool{}
+		for _, w := range words {
+			if !utf8.ValidString(w) {
+				t.Errorf("%s: %q is not UTF-8", lang, w)
+			}
+			dict[w] = true
+		}
+		if lang != "la" && len(words) < 200 {
+			t.Errorf("%s: only %d words", lang, len(words))
+		}
+
+		opts := Options{Lines: 200, Width: 53, Mode: "lorem", ModeArg: lang}
+		lines := generateLines(t, opts)
+		if len(lines) != 200 {
+			t.Fatalf("%s: %d lines", lang, len(lines))
+		}
+		// Every word is whole, except the last of a line that is cut.
+		for i, line := range lines {
+			if n := utf8.RuneCountInString(line); n != 53 || strings.Contains(line, "  ") || line[0] == ' ' {
+				t.Fatalf("%s: line %d %q has %d characters", lang, i+1, line, n)
+			}
+			fields := strings.Fields(line)
+			for j, w := range fields {
+				if j == len(fields)-1 && !strings.HasSuffix(line, " ") {
+					w = wordWithPrefix(words, w)
+				}
+				if !dict[w] {
+					t.Errorf("%s: line %d has %q, not in the word list", lang, i+1, w)
+				}
+			}
+		}
+	}
+
+	if got := generateLines(t, Options{Lines: 1, Width: 13, Mode: "lorem", ModeArg: "LA"}); got[0] != "lorem ipsum d" {
+		t.Errorf("latin %q", got[0])
+	}
+	if got := generateLines(t, Options{Lines: 1, Width: 13, Mode: "lorem", ModeArg: "no"}); got[0] != "den korte rap" {
+		t.Errorf("norwegian %q", got[0])
+	}
+	_, err := NewGenerator("lorem", "fr", 0)
+	if err == nil || !strings.Contains(err.Error(), `unknown language "fr" (available: de, en, la, no)`) {
+		t.Errorf("unknown language: %v", err)
+	}
+}
+
+// wordWithPrefix returns the first of words that starts with prefix, or
+// prefix if none does.
+func wordWithPrefix(words []string, prefix string) string {
+	for _, w := range words {
+		if strings.HasPrefix(w, prefix) {
+			return w
+		}
+	}
+	return prefix
+}
+
+func TestWords_UTF8Width(t *testing.T) {
+	g, err := NewGenerator("words", "blåbær,øl", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"blåbær øl b", "øl blåbær ø", "blåbær øl b"} {
+		if got := g.NextLine(11); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	if _, _, err := GenerateTo(context.Background(), &bytes.Buffer{}, Options{Lines: 1, Width: 5, Mode: "words", ModeArg: "blåbær"}); err == nil ||
+		!strings.Contains(err.Error(), "6 characters") {
+		t.Errorf("long word: %v", err)
+	}
+	if _, err := PlanSize(Options{Lines: 3, Width: 12, Mode: "words", ModeArg: "blåbær"}); !errors.Is(err, ErrSizeUnknown) {
+		t.Errorf("PlanSize: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package genlines
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// lineWriter buffers output for w and tracks how many data lines have been
+// fully delivered to w, so partial runs can report exact counts.
+type lineWriter struct {
+	cw     *countingWriter
+	bw     *bufio.Writer
+	queued int64   // bytes handed to bw so far
+	ends   []int64 // end offsets of data lines not yet known to be delivered
+	lines  int64   // data lines known to be delivered
+	chunk  []byte  // reused by writeWideLine
+	// flushes, if set, counts the flushes that pushed buffered bytes out.
+	flushes *int64
+}
+
+func newLineWriter(w io.Writer, retry Retry) *lineWriter {
+	cw := &countingWriter{w: w, retry: retry}
+	lw := &lineWriter{cw: cw, bw: bufio.NewWriterSize(cw, bufferSize)}
+	if retry.OnRetry != nil {
+		cw.onRetry = func(attempt int, err error) {
+			lines, _ := lw.written()
+			retry.OnRetry(lines+1, attempt, err)
+		}
+	}
+	return lw
+}
+
+// pruneAt bounds the number of pending line offsets kept between checks.
+const pruneAt = 4096
+
+// writeLine writes one data line followed by eol.
+func (lw *lineWriter) writeLine(line string, eol []byte) error {
+	n, err := lw.bw.WriteString(line)
+	lw.queued += int64(n)
+	if err != nil {
+		return err
+	}
+	return lw.endLine(eol)
+}
+
+// writeWideLine writes one data line of width content characters from a,
+// generated wideLineChunk at a time, followed by suffix and eol.
+func (lw *lineWriter) writeWideLine(a LineAppender, width int, suffix string, eol []byte) (err error) {
+	if lw.chunk == nil {
+		lw.chunk = make([]byte, 0, wideLineChunk)
+	}
+	if lw.chunk, err = writeChunks(a, width, lw.chunk, lw.writeExtra); err != nil {
+		return err
+	}
+	n, err := lw.bw.WriteString(suffix)
+	lw.queued += int64(n)
+	if err != nil {
+		return err
+	}
+	return lw.endLine(eol)
+}
+
+// writeChunks passes the next width characters of a to write, at most
+// wideLineChunk of them at a time, in buf. It returns buf, grown if needed,
+// for the next call.
+func writeChunks(a LineAppender, width int, buf []byte, write func([]byte) error) ([]byte, error) {
+	for width > 0 {
+		n := min(width, wideLineChunk)
+		buf = a.AppendLine(buf[:0], n)
+		if err := write(buf); err != nil {
+			return buf, err
+		}
+		width -= n
+	}
+	return buf, nil
+}
+
+// endLine writes eol and records the end of a data line.
+func (lw *lineWriter) endLine(eol []byte) error {
+	if err := lw.writeExtra(eol); err != nil {
+		return err
+	}
+	lw.ends = append(lw.ends, lw.queued)
+	if len(lw.ends) >= pruneAt {
+		lw.written()
+	}
+	return nil
+}
+
+// writeExtra writes bytes that are not part of a counted data line (e.g. comments).
+func (lw *lineWriter) writeExtra(p []byte) error {
+	n, err := lw.bw.Write(p)
+	lw.queued += int64(n)
+	return err
+}
+
+// flush pushes any buffered bytes to the underlying writer.
+func (lw *lineWriter) flush() error {
+	if lw.bw.Buffered() == 0 {
+		return nil
+	}
+	if err := lw.bw.Flush(); err != nil {
+		return err
+	}
+	if lw.flushes != nil {
+		*lw.flushes++
+	}
+	return nil
+}
+
+// written reports the data lines and bytes that have reached the underlying writer.
+func (lw *lineWriter) written() (lines, bytes int64) {
+	i := 0
+	for i < len(lw.ends) && lw.ends[i] <= lw.cw.n {
+		i++
+	}
+	lw.lines += int64(i)
+	lw.ends = append(lw.ends[:0], lw.ends[i:]...)
+	return lw.lines, lw.cw.n
+}
+
+// countingWriter counts the bytes successfully written to w, retrying
+// transient write errors as configured. A broken pipe comes back marked with
+// ErrOutputClosed.
+type countingWriter struct {
+	w       io.Writer
+	n       int64
+	retry   Retry
+	onRetry func(attempt int, err error)
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.retry.write(func(p []byte) (int, error) {
+		n, err := c.w.Write(p)
+		c.n += int64(n)
+		return n, err
+	}, p, c.onRetry)
+	if err != nil && IsOutputClosed(err) {
+		err = fmt.Errorf("%w: %w", ErrOutputClosed, err)
+	}
+	return n, err
+}
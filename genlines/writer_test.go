@@ -0,0 +1,165 @@
+package genlines
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// wideWidth is the width of the wide lines below: 32 MiB.
+const wideWidth = 32 << 20
+
+// hashCounter counts and hashes what is written to it.
+type hashCounter struct {
+	h hash.Hash
+	n int64
+}
+
+func (c *hashCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return c.h.Write(p)
+}
+
+func TestWriteLines_WideLineInChunks(t *testing.T) {
+	for _, mode := range []string{"random", "ascii", "char"} {
+		opts := Options{Lines: 1, Width: wideWidth, Mode: mode, ModeArg: map[string]string{"random": "4", "char": "é"}[mode]}
+		gen, err := newGenerator(opts.Mode, opts.ModeArg, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := sha256.Sum256([]byte(gen.NextLine(wideWidth) + "\n"))
+
+		// TestWriteChunks_Allocs checks that the line is not held in memory.
+		w := &hashCounter{h: sha256.New()}
+		if _, _, err := GenerateTo(context.Background(), w, opts); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(w.h.Sum(nil), want[:]) {
+			t.Errorf("mode=%s: %d bytes differ from NextLine", mode, w.n)
+		}
+	}
+}
+
+func TestWriteChunks_Allocs(t *testing.T) {
+	gen := newRandomGen(1, []byte(AsciiSequence()))
+	buf := make([]byte, 0, wideLineChunk)
+	var n int64
+	write := func(p []byte) error { n += int64(len(p)); return nil }
+	allocs := testing.AllocsPerRun(2, func() {
+		buf, _ = writeChunks(gen, wideWidth, buf, write)
+	})
+	if allocs > 0 || n != 3*wideWidth {
+		t.Errorf("%v allocations, %d bytes", allocs, n)
+	}
+}
+
+func TestWriteLines_WideLinesMatchWholeLines(t *testing.T) {
+	// Chunked lines with what follows the content; then lines with a
+	// checksum, which needs the whole line.
+	width := wideLineChunk*2 + 5
+	for _, opts := range []Options{
+		{Lines: 3, Width: width, Mode: "ascii", Continuation: `\`, EOL: []byte("\r\n")},
+		{Lines: 3, Width: width, Mode: "upper", TrailingWS: TrailingWS{Fraction: 1, HasSeed: true}, EOL: []byte("\r\n")},
+	} {
+		var got bytes.Buffer
+		if _, _, err := GenerateTo(context.Background(), &got, opts); err != nil {
+			t.Fatal(err)
+		}
+		gen, _ := newGenerator(opts.Mode, "", 0)
+		lay := opts.withDefaults().layout()
+		var want strings.Builder
+		for n := int64(1); n <= 3; n++ {
+			want.WriteString(gen.NextLine(lay.contentWidth(n)) + lay.suffix(n) + "\r\n")
+		}
+		if got.String() != want.String() {
+			t.Errorf("mode=%s: chunked lines differ from whole ones", opts.Mode)
+		}
+	}
+
+	opts := Options{Lines: 2, Width: width, Mode: "ascii", LineChecksum: true}
+	var sum bytes.Buffer
+	if _, _, err := GenerateTo(context.Background(), &sum, opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(sum.String(), "\n"), "\n") {
+		if !CheckLine(line) {
+			t.Error("a wide line with a checksum fails it")
+		}
+	}
+
+	// A width over MaxWidth does not fit in an int on 32-bit platforms.
+	if math.MaxInt > MaxWidth {
+		over := MaxWidth
+		over++
+		if _, _, err := GenerateTo(context.Background(), io.Discard, Options{Lines: 1, Width: over}); err == nil {
+			t.Error("a width over MaxWidth was accepted")
+		}
+	}
+}
+
+// writeLog records the size of every write that reaches it.
+type writeLog struct {
+	sizes []int
+}
+
+func (l *writeLog) Write(p []byte) (int, error) {
+	l.sizes = append(l.sizes, len(p))
+	return len(p), nil
+}
+
+func (l *writeLog) Close() error { return nil }
+
+func TestWriteLines_FlushEvery(t *testing.T) {
+	for _, tt := range []struct {
+		opts    Options
+		sizes   []int
+		flushes int64
+	}{
+		{Options{Lines: 5, Width: 9, FlushEvery: 1}, []int{10, 10, 10, 10, 10}, 5},
+		{Options{Lines: 7, Width: 9, FlushEvery: 3}, []int{30, 30, 10}, 3},
+		// The comment after a line goes out with it.
+		{Options{Lines: 4, Width: 9, FlushEvery: 2, CommentEvery: 2, CommentText: "#%d"}, []int{23, 23}, 2},
+		{Options{Lines: 5, Width: 9}, []int{50}, 1},
+	} {
+		var log writeLog
+		var stats Stats
+		tt.opts.Stats = &stats
+		if _, _, err := GenerateTo(context.Background(), &log, tt.opts); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(log.sizes, tt.sizes) || stats.Flushes != tt.flushes {
+			t.Errorf("every %d: writes %v, %d flushes; want %v, %d", tt.opts.FlushEvery, log.sizes, stats.Flushes, tt.sizes, tt.flushes)
+		}
+	}
+
+	// Split parts keep counting lines from the start of the run.
+	var log writeLog
+	var stats Stats
+	opts := Options{Lines: 6, Width: 9, FlushEvery: 2, Stats: &stats}
+	if _, err := GenerateSplit(context.Background(), opts, 3, func(int) (io.WriteCloser, error) {
+		return &log, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{20, 10, 10, 20}; !slices.Equal(log.sizes, want) || stats.Flushes != 4 {
+		t.Errorf("split: writes %v, %d flushes", log.sizes, stats.Flushes)
+	}
+}
+
+func TestWriteLines_FlushError(t *testing.T) {
+	fw := &failingWriter{limit: 25}
+	lines, n, err := GenerateTo(context.Background(), fw, Options{Lines: 5, Width: 9, FlushEvery: 1})
+	if !errors.Is(err, errDiskFull) || !strings.Contains(err.Error(), "writing line 3") {
+		t.Fatalf("got %v", err)
+	}
+	if lines != 2 || n != 25 {
+		t.Errorf("reported %d lines, %d bytes", lines, n)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// goldenFile writes the --golden companion of a data file: a TSV with a
+// header row and one row per data line, holding its number, byte offset in
+// the data file, width setting, size in bytes and, with line checksums, the
+// checksum. Rows go to a temporary file that replaces path only once the
+// run succeeded, so a golden file always describes a complete run.
+type goldenFile struct {
+	path     string
+	tmp      *os.File
+	w        *bufio.Writer
+	base     int64 // offset of the first generated byte in the data file
+	checksum bool
+	err      error // first write error
+}
+
+// createGolden starts the golden file for a run whose output starts at byte
+// base of the data file (after the existing content when appending).
+func createGolden(path string, base int64, checksum bool) (*goldenFile, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	g := &goldenFile{path: path, tmp: tmp, w: bufio.NewWriter(tmp), base: base, checksum: checksum}
+	header := "line\toffset\twidth\tbytes"
+	if checksum {
+		header += "\tchecksum"
+	}
+	_, g.err = fmt.Fprintln(g.w, header)
+	return g, nil
+}
+
+// add writes the row of one data line; it is the run's Options.OnLine.
+func (g *goldenFile) add(li genlines.LineInfo) {
+	if g.err != nil {
+		return
+	}
+	if g.checksum {
+		_, g.err = fmt.Fprintf(g.w, "%d\t%d\t%d\t%d\t%s\n", li.Number, g.base+li.Offset, li.Width, li.Bytes, li.Checksum)
+	} else {
+		_, g.err = fmt.Fprintf(g.w, "%d\t%d\t%d\t%d\n", li.Number, g.base+li.Offset, li.Width, li.Bytes)
+	}
+}
+
+// commit puts the golden file in place.
+func (g *goldenFile) commit() error {
+	defer os.Remove(g.tmp.Name())
+	if g.err == nil {
+		g.err = g.w.Flush()
+	}
+	if cerr := g.tmp.Close(); g.err == nil {
+		g.err = cerr
+	}
+	if g.err != nil {
+		return g.err
+	}
+	if err := os.Chmod(g.tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(g.tmp.Name(), g.path)
+}
+
+// discard drops the golden file of a failed run.
+func (g *goldenFile) discard() {
+	g.tmp.Close()
+	os.Remove(g.tmp.Name())
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// readGolden returns the rows of a golden file after checking its header.
+func readGolden(t *testing.T, path, header string) [][]string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if rows[0] != header {
+		t.Fatalf("header %q, want %q", rows[0], header)
+	}
+	var out [][]string
+	for _, r := range rows[1:] {
+		out = append(out, strings.Split(r, "\t"))
+	}
+	return out
+}
+
+func TestRun_GoldenOffsetsSeekIntoData(t *testing.T) {
+	dir := t.TempDir()
+	data, golden := filepath.Join(dir, "data.txt"), filepath.Join(dir, "data.tsv")
+	if code := run([]string{"20", data, "y", "30", "alpha", "--golden", golden,
+		"--line-checksum", "--comment-every", "4", "--line-ending", "crlf", "--rot13"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	rows := readGolden(t, golden, "line\toffset\twidth\tbytes\tchecksum")
+	if len(rows) != 20 {
+		t.Fatalf("%d rows, want 20", len(rows))
+	}
+	f, err := os.Open(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, i := range []int{0, 3, 4, 11, 19} {
+		row := rows[i]
+		off, _ := strconv.ParseInt(row[1], 10, 64)
+		size, _ := strconv.Atoi(row[3])
+		buf := make([]byte, size+2)
+		if _, err := f.ReadAt(buf, off); err != nil && err != io.EOF {
+			t.Fatal(err)
+		}
+		line := string(buf[:size])
+		if row[0] != strconv.Itoa(i+1) || row[2] != "30" || string(buf[size:]) != "\r\n" {
+			t.Errorf("row %v: data at offset %d is %q", row, off, buf)
+		}
+		if !genlines.CheckLine(line) || !strings.HasSuffix(line, " "+row[4]) {
+			t.Errorf("row %v: line %q does not carry checksum %s", row, line, row[4])
+		}
+	}
+}
+
+func TestRun_GoldenAppendCountsExistingBytes(t *testing.T) {
+	dir := t.TempDir()
+	data, golden := filepath.Join(dir, "data.txt"), filepath.Join(dir, "data.tsv")
+	if err := os.WriteFile(data, []byte("existing header"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"3", data, "10", "digits", "--append", "--golden", golden}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	rows := readGolden(t, golden, "line\toffset\twidth\tbytes")
+	content, _ := os.ReadFile(data)
+	for _, row := range rows {
+		off, _ := strconv.Atoi(row[1])
+		if got := string(content[off : off+10]); got != "0123456789" {
+			t.Errorf("row %v: %q at offset %d", row, got, off)
+		}
+	}
+	if first := rows[0][1]; first != strconv.Itoa(len("existing header\n")) {
+		t.Errorf("first offset %s, want after the existing content and its added terminator", first)
+	}
+
+	for _, args := range [][]string{
+		{"3", data, "y", "--golden", data},
+		{"3", filepath.Join(dir, "p.txt"), "y", "--split-lines", "2", "--golden", golden},
+	} {
+		if code := run(args); code == 0 {
+			t.Errorf("%q: expected a failure", args)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checkTargetSafety returns an error if writing to path would clobber the
+// running executable, a Go source file inside a module, or a file this
+// process already has open.
+func checkTargetSafety(path string) error {
+	if exe, err := os.Executable(); err == nil {
+		if err := checkNotExecutable(path, exe); err != nil {
+			return err
+		}
+	}
+	if err := checkNotModuleSource(path); err != nil {
+		return err
+	}
+	return checkNotOpen(path, "/proc/self/fd")
+}
+
+// guardTargets runs checkTargetSafety over paths, the files a run writes
+// (empty ones are skipped). It reports the first refusal and returns false,
+// unless --force-target is given, which turns every refusal into a warning.
+func guardTargets(flags cliFlags, paths ...string) bool {
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		err := checkTargetSafety(p)
+		if err == nil {
+			continue
+		}
+		if !flags.forceTarget {
+			cli.Error("%v", err)
+			cli.Hint("Use --force-target to write there anyway.")
+			return false
+		}
+		cli.Warn("--force-target given, ignoring safety check: %v", err)
+	}
+	return true
+}
+
+// sideTargets lists the files a run writes besides its output filename: the
+// --golden, --manifest and --summary-json files and the --also-link copies.
+func sideTargets(flags cliFlags, filename string) []string {
+	paths := []string{flags.golden, flags.manifest, flags.summaryJSON}
+	for _, dir := range flags.alsoLink {
+		paths = append(paths, filepath.Join(dir, filepath.Base(filename)))
+	}
+	return paths
+}
+
+// checkNotExecutable refuses path if it is the same file as exe.
+func checkNotExecutable(path, exe string) error {
+	if sameFile(path, exe) {
+		return fmt.Errorf("refusing to overwrite the running executable %s", exe)
+	}
+	return nil
+}
+
+// checkNotModuleSource refuses .go files located inside a Go module.
+func checkNotModuleSource(path string) error {
+	if !strings.EqualFold(filepath.Ext(path), ".go") {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		if fileExists(filepath.Join(dir, "go.mod")) {
+			return fmt.Errorf("refusing to write Go source file %s inside module %s", path, dir)
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return nil
+		}
+	}
+}
+
+// checkNotOpen refuses path if it is already open in this process, as listed
+// by the fd directory (e.g. /proc/self/fd). Platforms without it are skipped.
+func checkNotOpen(path, fdDir string) error {
+	entries, err := os.ReadDir(fdDir)
+	if err != nil {
+		return nil
+	}
+	for _, e := range entries {
+		if sameFile(path, filepath.Join(fdDir, e.Name())) {
+			return fmt.Errorf("refusing to write %s: the file is currently open by this process", path)
+		}
+	}
+	return nil
+}
+
+// sameFile reports whether a and b both exist and refer to the same file.
+func sameFile(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil || !ai.Mode().IsRegular() {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
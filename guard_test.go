@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckNotExecutable_CopiedBinary(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skipf("os.Executable unavailable: %v", err)
+	}
+
+	dir := t.TempDir()
+	copied := filepath.Join(dir, "generatelines")
+	src, err := os.Open(exe)
+	if err != nil {
+		t.Fatalf("open executable: %v", err)
+	}
+	defer src.Close()
+	dst, err := os.Create(copied)
+	if err != nil {
+		t.Fatalf("create copy: %v", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		t.Fatalf("copy executable: %v", err)
+	}
+	dst.Close()
+
+	if err := checkNotExecutable(copied, copied); err == nil {
+		t.Fatalf("expected refusal when target is the executable")
+	}
+	if err := checkNotExecutable(filepath.Join(dir, ".", "generatelines"), copied); err == nil {
+		t.Fatalf("expected refusal for an equivalent path to the executable")
+	}
+	if err := checkNotExecutable(copied, exe); err != nil {
+		t.Fatalf("a copy is a different file and should be allowed, got %v", err)
+	}
+	if err := checkNotExecutable(filepath.Join(dir, "other.txt"), copied); err != nil {
+		t.Fatalf("unexpected refusal for unrelated path: %v", err)
+	}
+}
+
+func TestCheckNotModuleSource(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example\n"), 0644); err != nil {
+		t.Fatalf("write go.mod: %v", err)
+	}
+	sub := filepath.Join(dir, "pkg")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if err := checkNotModuleSource(filepath.Join(sub, "main.go")); err == nil {
+		t.Fatalf("expected refusal for .go file inside module")
+	}
+	if err := checkNotModuleSource(filepath.Join(sub, "main.txt")); err != nil {
+		t.Fatalf("unexpected refusal for non-Go file: %v", err)
+	}
+}
+
+func TestCheckNotOpen(t *testing.T) {
+	if _, err := os.Stat("/proc/self/fd"); err != nil {
+		t.Skip("/proc/self/fd not available")
+	}
+	f, err := os.CreateTemp(t.TempDir(), "open-*")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	defer f.Close()
+
+	if err := checkNotOpen(f.Name(), "/proc/self/fd"); err == nil {
+		t.Fatalf("expected refusal for a file open in this process")
+	}
+	if err := checkNotOpen(filepath.Join(t.TempDir(), "closed.txt"), "/proc/self/fd"); err != nil {
+		t.Fatalf("unexpected refusal: %v", err)
+	}
+}
+
+func TestRun_GuardCoversSideFiles(t *testing.T) {
+	module := t.TempDir()
+	if err := os.WriteFile(filepath.Join(module, "go.mod"), []byte("module example\n"), 0644); err != nil {
+		t.Fatalf("write go.mod: %v", err)
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.txt")
+	source := filepath.Join(module, "gen.go")
+	captureStderr(t)
+	captureStdout(t)
+	for _, extra := range [][]string{
+		{"--golden", source},
+		{"--manifest", source},
+		{"--summary-json", source},
+		{"--golden", source, "--force"},
+	} {
+		args := append([]string{"3", out, "y", "10"}, extra...)
+		if code := run(args); code != 1 {
+			t.Errorf("%v exited with %d, want 1", extra, code)
+		}
+		if fileExists(source) || fileExists(out) {
+			t.Fatalf("%v wrote despite the refusal", extra)
+		}
+	}
+
+	linked := filepath.Join(dir, "link.go")
+	if code := run([]string{"3", linked, "y", "10", "--also-link", module}); code != 1 {
+		t.Errorf("--also-link into a module exited with %d, want 1", code)
+	}
+	if fileExists(linked) || fileExists(filepath.Join(module, "link.go")) {
+		t.Fatal("--also-link wrote despite the refusal")
+	}
+
+	if code := run([]string{"3", out, "y", "10", "--golden", source, "--force-target"}); code != 0 {
+		t.Fatalf("--force-target exited with %d", code)
+	}
+	if !fileExists(source) {
+		t.Error("--force-target did not write the golden file")
+	}
+}
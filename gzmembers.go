@@ -0,0 +1,84 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// gzIndexSuffix is appended to the output file name for the member index
+// written with --gz-index.
+const gzIndexSuffix = ".gzidx"
+
+// gzMember describes one gzip member of a --gz-member-lines file.
+type gzMember struct {
+	FirstLine int64 `json:"firstLine"` // one-based data line the member starts with
+	Lines     int64 `json:"lines"`
+	Offset    int64 `json:"offset"`   // byte offset of the member in the file
+	Bytes     int64 `json:"bytes"`    // compressed size
+	RawBytes  int64 `json:"rawBytes"` // uncompressed size
+}
+
+// gzIndex is the member index sidecar: where each member starts, so a reader
+// can decompress from any member without reading the ones before it.
+type gzIndex struct {
+	Tool        string     `json:"tool"`
+	Version     string     `json:"version"`
+	File        string     `json:"file"` // base name, relative to the sidecar
+	MemberLines int        `json:"memberLines"`
+	Members     []gzMember `json:"members"`
+}
+
+// writeGzipMembers generates opts into w as a multi-member gzip stream, a new
+// member starting every perMember data lines (comment lines go with the data
+// line before them). The uncompressed content is also written to content.
+// Decompressing the members in order gives the bytes of a plain run; a run
+// of 0 lines writes one empty member, so the file is still valid gzip.
+func writeGzipMembers(ctx context.Context, w, content io.Writer, opts genlines.Options, perMember int) ([]gzMember, error) {
+	cw := &writeCounter{w: w}
+	var offsets []int64
+	create := func(int) (io.WriteCloser, error) {
+		offsets = append(offsets, cw.bytes)
+		gz := gzip.NewWriter(cw)
+		return struct {
+			io.Writer
+			io.Closer
+		}{io.MultiWriter(gz, content), gz}, nil
+	}
+	parts, err := genlines.GenerateSplit(ctx, opts, perMember, create)
+
+	members := make([]gzMember, len(parts))
+	first := int64(1)
+	for i, p := range parts {
+		end := cw.bytes
+		if i+1 < len(offsets) {
+			end = offsets[i+1]
+		}
+		members[i] = gzMember{FirstLine: first, Lines: p.Lines, Offset: offsets[i], Bytes: end - offsets[i], RawBytes: p.Bytes}
+		first += p.Lines
+	}
+	if err != nil {
+		return members, err
+	}
+	if len(members) == 0 {
+		if err := gzip.NewWriter(cw).Close(); err != nil {
+			return nil, err
+		}
+		members = append(members, gzMember{FirstLine: 1, Bytes: cw.bytes})
+	}
+	return members, nil
+}
+
+// writeGzIndex stores the member index of filename next to it.
+func writeGzIndex(filename string, perMember int, members []gzMember) error {
+	idx := gzIndex{Tool: "generatelines", Version: version, File: filepath.Base(filename), MemberLines: perMember, Members: members}
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename+gzIndexSuffix, append(data, '\n'))
+}
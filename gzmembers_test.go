@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gzipMembers splits data into its gzip members by decoding one at a time.
+func gzipMembers(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	br := bufio.NewReader(bytes.NewReader(data))
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var members [][]byte
+	for {
+		zr.Multistream(false)
+		content, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		members = append(members, content)
+		if err := zr.Reset(br); err == io.EOF {
+			return members
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRun_GzipMembers(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain.txt")
+	packed := filepath.Join(dir, "packed.txt.gz")
+	if code := run([]string{"25", plain, "y", "12", "ascii", "--comment-every", "4"}); code != 0 {
+		t.Fatalf("plain run exited with %d", code)
+	}
+	if code := run([]string{"25", packed, "y", "12", "ascii", "--comment-every", "4", "--gz-member-lines", "10", "--gz-index"}); code != 0 {
+		t.Fatalf("gzip run exited with %d", code)
+	}
+	want, _ := os.ReadFile(plain)
+	data, _ := os.ReadFile(packed)
+
+	// The file decompresses as a whole to the plain run.
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := io.ReadAll(zr); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("decompressed output differs from the plain run (%v)", err)
+	}
+
+	members := gzipMembers(t, data)
+	if len(members) != 3 {
+		t.Fatalf("%d gzip members, want 3", len(members))
+	}
+	if n := strings.Count(string(members[2]), "\n"); n != 5+1 {
+		t.Errorf("last member has %d lines, want 5 data lines and a comment", n)
+	}
+
+	// The index points at every member, each decompressing on its own.
+	raw, err := os.ReadFile(packed + gzIndexSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idx gzIndex
+	if err := json.Unmarshal(raw, &idx); err != nil {
+		t.Fatal(err)
+	}
+	if idx.File != "packed.txt.gz" || idx.MemberLines != 10 || len(idx.Members) != 3 {
+		t.Fatalf("index = %+v", idx)
+	}
+	for i, m := range idx.Members {
+		if m.FirstLine != int64(i*10+1) || m.RawBytes != int64(len(members[i])) {
+			t.Errorf("member %d: %+v", i+1, m)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(data[m.Offset : m.Offset+m.Bytes]))
+		if err != nil {
+			t.Fatalf("member %d at offset %d: %v", i+1, m.Offset, err)
+		}
+		if got, _ := io.ReadAll(zr); !bytes.Equal(got, members[i]) {
+			t.Errorf("member %d read from its offset differs", i+1)
+		}
+	}
+}
+
+func TestRun_GzipMembersEmptyAndRejected(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "empty.gz")
+	if code := run([]string{"0", path, "y", "--gz-member-lines", "10"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	data, _ := os.ReadFile(path)
+	if members := gzipMembers(t, data); len(members) != 1 || len(members[0]) != 0 {
+		t.Errorf("0 lines: members %q, want one empty member", members)
+	}
+
+	for _, args := range [][]string{
+		{"10", path, "y", "--gz-index"},
+		{"10", path, "y", "--gz-member-lines", "5", "--split-lines", "5"},
+		{"10", path, "y", "--gz-member-lines", "5", "--exact-bytes", "100"},
+		{"10", path, "y", "--gz-member-lines", "5", "--verify-after"},
+	} {
+		if code := run(args); code != 1 {
+			t.Errorf("%v: exit %d, want 1", args, code)
+		}
+	}
+}
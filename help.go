@@ -0,0 +1,475 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// defaultCommandName is the program name help output uses when the one it
+// was invoked as is unusable.
+const defaultCommandName = "generatelines"
+
+// cmdName is the name the program was invoked as (see commandName), so the
+// usage lines and examples in help output can be pasted as they are.
+var cmdName = invokedAs()
+
+// invokedAs returns the command name for os.Args[0].
+func invokedAs() string {
+	if len(os.Args) == 0 {
+		return defaultCommandName
+	}
+	return commandName(os.Args[0])
+}
+
+// commandName returns the name to type for a program started as arg0: its
+// base name without a .exe extension. Names that are empty, would need
+// quoting, or belong to a go test binary give defaultCommandName.
+func commandName(arg0 string) string {
+	name := filepath.Base(arg0)
+	if ext := filepath.Ext(name); strings.EqualFold(ext, ".exe") {
+		name = strings.TrimSuffix(name, ext)
+	}
+	if name == "" || name == "." || name == ".." || strings.HasPrefix(name, "-") || strings.HasSuffix(name, ".test") {
+		return defaultCommandName
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_.+", r) {
+			return defaultCommandName
+		}
+	}
+	return name
+}
+
+// expandHelp fills in the command name in help text: {cmd} becomes cmdName
+// and {pad} as many spaces, to line up continuation lines.
+func expandHelp(text string) string {
+	return strings.NewReplacer("{cmd}", cmdName, "{pad}", strings.Repeat(" ", len(cmdName))).Replace(text)
+}
+
+// helpHint returns the preferred help command hint for the current OS.
+func helpHint() string {
+	if runtime.GOOS == "windows" {
+		return expandHelp(`Tip: run "{cmd} /?" for parameters and modes.`)
+	}
+	return expandHelp(`Tip: run "{cmd} -h" for parameters and modes.`)
+}
+
+// runHelpCmd handles "help [modes | <mode>]" and returns the exit code.
+// Without a topic it prints the whole help.
+func runHelpCmd(args []string) int {
+	if len(args) == 0 {
+		printHelp()
+		return 0
+	}
+	if len(args) > 1 {
+		cli.Error("help takes one topic: modes or a mode name")
+		return 1
+	}
+	if strings.EqualFold(strings.TrimSpace(args[0]), "modes") {
+		printModes()
+		return 0
+	}
+	name, spec, err := genlines.LookupMode(args[0])
+	if err != nil {
+		cli.Error("%v", err)
+		cli.Hint(expandHelp(`Tip: run "{cmd} help modes" for the list of modes.`))
+		return 1
+	}
+	printModeHelp(name, spec)
+	return 0
+}
+
+// printHelp prints command usage, parameters, and available modes to stdout.
+func printHelp() {
+	fmt.Print(expandHelp(fmt.Sprintf(helpIntro, version, authorName, repoURL)))
+	printModes()
+	fmt.Print(expandHelp(helpOutro))
+}
+
+// helpIntro is the help before the modes: usage, parameters and options. It
+// is a format for the version, author and repository.
+const helpIntro = `GenerateLines v%s
+Author: %s
+Repository: %s
+
+Generate a text file with N lines of repeatable content.
+
+Usage:
+  {cmd} <lines> <filename> [y|n] [width] [mode] [modeArg] [options]
+  {cmd} /?
+  {cmd} help [modes | <mode>]
+  {cmd} -h
+  {cmd} --help
+  {cmd} version
+  {cmd} --version
+  {cmd} version --full | --json
+  {cmd} regen <file.meta> [output]
+  {cmd} verify [--auto] <file>
+  {cmd} verify-lines <file>
+  {cmd} selftest
+  {cmd} sample <width> <mode> [modeArg|-] [count]
+  {cmd} preset save <name> <lines> <filename> [...] [options]
+  {cmd} preset run <name> [field=value ...] [options]
+  {cmd} preset list
+  {cmd} preset delete <name>
+  {cmd} batch <spec|->
+  {cmd} history [N]
+  {cmd} daemon <filename> [width] [mode] [modeArg] [--rate N]
+  {pad} [--rotate-size SIZE] [--keep N] [--bps SIZE]
+  {cmd} stress-files <count> <dir> [size-per-file] [mode] [modeArg]
+  {pad} [--concurrency N]
+  {cmd} streams <stdout-lines> <stderr-lines> [width] [mode] [modeArg]
+  {pad} [--ratio A:B] [--tags] [--delay D]
+
+Parameters (positional):
+  lines        Number of lines to generate (required unless prompted);
+               0 creates (or truncates to) an empty file
+               Accepts 1_000_000, 1,000,000 and K/M/G multipliers (1M, 1.5G)
+               match:<path> takes the line count of a reference file;
+               match+width:<path> also takes its widest line as the width
+  filename     Output file name (required unless prompted), or an http(s)://
+               URL to PUT the content to (extra headers from
+               GENERATELINES_HEADER_<NAME> environment variables), fd:N
+               to write to inherited file descriptor N (Unix), or - for
+               stdout (messages then go to stderr)
+
+Optional parameters:
+  y | n        Auto-answer overwrite prompt if file already exists. When
+               prompted about one of several files, A / N answer y / n
+               for all remaining ones. The prompt shows the file's size and
+               age and the new size; a file over 100 times larger than the
+               new output must also be confirmed by typing its name
+  width        Line width (columns). Default: 80
+               "term" uses the terminal width; term-2 / term+4 add an offset
+  mode         Content generation mode. Default: ascii
+  modeArg      Additional argument for selected mode
+
+Options:
+  --comment-every N    Insert a comment line after every N data lines
+  --comment-text TEXT  Comment line template; %%d is the data line count so far
+                       Default: "# checkpoint %%d"
+  --meta               Always write a <filename>.meta sidecar recording the run
+  --no-meta            Never write the sidecar (even for unseeded random runs)
+  --max-lines N        Confirm runs above N lines (0 = no cap). Default:
+                       100000000, or $GENERATELINES_MAX_LINES
+  --split-lines N      Write part files of at most N lines each (out-001.txt, ...)
+  --gz-member-lines N  Write the file gzip-compressed, starting a new gzip member
+                       every N lines so readers can skip members; it still
+                       gunzips as a whole
+  --gz-index           With --gz-member-lines, also write <filename>.gzidx,
+                       a JSON list of the member offsets
+  --split-pattern PAT  Part file names, with one %%d or %%0Nd (e.g. part-%%04d.txt)
+                       With a .zip or .tar filename the parts become members
+                       of that archive
+  --manifest PATH      After a successful run, write a JSON list of the output
+                       files with lines, bytes and SHA-256
+  --golden PATH        Also write a TSV describing the output, one row per data
+                       line: line number, byte offset, width, bytes and (with
+                       --line-checksum) the checksum
+  --summary-json PATH  After a successful run, write a JSON summary of it
+                       (file, lines, bytes, duration, mode, width, seed,
+                       checksums, where each setting came from)
+  --json-schema        Print the JSON Schema of the --summary-json file and exit
+  --out PATH           Also write the same stream to PATH (repeatable). The
+                       overwrite answer or prompt applies per file; a
+                       summary lists each file with its size and SHA-256
+  --keep-going         With --out, finish the other files when one fails
+                       (default: stop all of them). Exits 1 either way
+  --also-link DIRS     When the output is complete, hard-link it into each of
+                       the comma-separated DIRS under the same name (a copy
+                       where a link cannot be made, e.g. across devices).
+                       Existing files there are prompted for like the output
+  --wipe-old           Overwrite a file that is replaced (the output, --out
+                       targets, split parts, --also-link destinations, files
+                       daemon rotation deletes) with zeros and sync it first.
+                       Best effort: SSDs and copy-on-write filesystems may
+                       keep the old blocks
+  --exact-bytes SIZE   Write exactly SIZE bytes (e.g. 1048576, 1MiB): whole lines,
+                       then a partial last line without terminator. The lines
+                       argument is ignored
+  --max-bytes SIZE     Stop before the first line that would make the file
+                       larger than SIZE (e.g. 4GiB): the run ends at lines or
+                       SIZE, whichever comes first, and says which. Counts
+                       terminators, comments, checksums and padding, and the
+                       existing content with --append
+  --align SIZE         Keep every line within one SIZE-byte block (e.g. 4KiB):
+                       a line that would cross a multiple of SIZE is preceded
+                       by a padding line ending on the boundary. The summary
+                       reports the padding lines and the final size
+  --align-fill C       Padding character for --align. Default: space
+  --append             Add the lines to the end of an existing file, using its
+                       line endings (and ending its last line first if needed)
+  --temp-name PREFIX   Write a new file PREFIX-XXXXXXXX.txt (random hex) instead
+                       of filename, which is left out, and print its path on
+                       stdout, alone (messages go to stderr): no clashes
+                       between parallel runs
+  --line-ending E      Line terminator: lf, crlf, nul (0x00, for xargs -0) or
+                       none. Default: lf, or the file's own with --append and
+                       daemon
+  --ramp MIN:MAX:STEP  Grow line widths instead of using width: line K is
+                       MIN+(K-1)*STEP columns, capped at MAX (add :reset to
+                       start over at MIN after MAX), e.g. --ramp 10:500:5
+  --verify-after       Read the output back after closing it and compare it with
+                       a fresh run; reports the first differing byte offset and
+                       exits with 4 on a mismatch. Skipped for URLs and archives
+  --retries N          Retry transient write errors (EINTR, EAGAIN) up to N
+                       times, logging each retry. Default: 3
+  --retry-backoff D    Wait before the first retry, doubled after each
+                       (e.g. 500ms). Default: 200ms
+  --flush-every N      Push the buffered output to the file after every N
+                       lines, e.g. for tail -f; 0 = in 64 KiB chunks and at
+                       the end. Default: 0, or 1 for daemon
+  --bps SIZE           Write at most SIZE bytes per second (e.g. 500K), evenly
+                       paced whatever the line width, and report the average
+                       reached. With daemon's --rate, the stricter one wins
+  --seed N             Global seed: random modes (and future random features)
+                       without a seed of their own derive one from N, so N
+                       alone reproduces the run
+  --line-checksum      End every line with a space and the CRC32 (8 hex digits)
+                       of the characters before it; check with verify-lines.
+                       Needs width >= 10
+  --continuation       End every line but the last with a backslash, within
+                       the width (after the checksum, before the line
+                       ending); --continuation-marker M uses M instead
+  --first-line TEXT    Replace the content of line 1 with TEXT, padded or cut
+                       to the width; {config} becomes a checksum of the
+                       settings (e.g. BEGIN-{config}). Other lines are unchanged
+  --last-line TEXT     The same for the last line, e.g. a sentinel that shows
+                       the file was not truncated
+  --stamp              Write a first line describing the run (# generatelines
+                       v... lines=... config=...), not counted in lines; check
+                       the file later with verify --auto
+  --escape-nonascii    Write non-ASCII characters as \uXXXX escapes (surrogate
+                       pairs above U+FFFF) for a pure-ASCII file. Width counts
+                       characters before escaping: each takes 6 bytes (12)
+  --start-offset N     Start the content as if N characters had already been
+                       generated (ascii, digits, upper, alpha and char only),
+                       e.g. 8000 continues a run of 100 lines x 80
+  --safe-start         Start the output with two letters or digits, so tools
+                       that sniff file types do not take it for JSON, PDF,
+                       ZIP, HTML or a script: seekable modes start the content
+                       later (the shift is printed and recorded), others
+                       replace the first characters of line 1
+  --byte-range LO-HI   Keep the content within bytes LO to HI (decimal or 0x
+                       hex, e.g. 0x30-0x39): palettes keep the characters in
+                       the range; fixed alphabets (csv delimiters, words, pi)
+                       must fit or are rejected. --verbose prints the palette
+  --sort asc|desc      Generate every line first and write them in byte-wise
+                       order (not locale collation), e.g. for golden files of
+                       sorted input; the lines are held in memory
+  --sort-max-memory S  Refuse a --sort run estimated to need more memory than
+                       S (default 256MiB)
+  --no-sniff-warning   Do not warn when the output starts with a file type
+                       signature (PK, %%P, {, [, <!, <?, #!, gzip)
+  --rot N              Shift every letter of the content N places (1-25), e.g.
+                       for plaintext/ciphertext pairs; other bytes are kept.
+                       --rot13 is --rot 13
+  --line-pattern P     Make the lines content (C) or blank (B) following the
+                       pattern P, repeated, e.g. CCB; only content lines move
+                       the generator on. Blank lines count toward the lines
+  --trailing-ws P[:seed]
+                       End a share P (0.1 or 10%%) of the lines with 1-3 spaces
+                       or tabs, after the checksum (which does not cover them)
+                       and before the line ending; same seed, same lines
+  --inject-unicode SPEC
+                       Insert an invisible code point into every Nth line's
+                       content: NAME[,NAME...][:every=N][:seed=S][:at=start],
+                       e.g. zwsp:every=50:seed=7 or bom:at=start. Names: zwsp
+                       zwnj zwj wj bom lrm rlm lre rle pdf lro rlo lri rli fsi
+                       pdi. They do not count toward the width
+  --unsafe             Let csv fields start with spreadsheet formula characters
+                       (= + - @), to test CSV injection defenses
+  --allow-control      Allow control characters in a char modeArg (written
+                       with escapes such as \t or \x1b)
+  --stats              Print a content profile at the end: byte histogram,
+                       distinct bytes, line width range and entropy
+  --verbose            Print how many writes reached the output file(s) and their
+                       average size, for tuning buffer sizes, and the number
+                       of flushes with --flush-every
+  --no-color           Disable colored messages (also: NO_COLOR environment variable)
+  --quiet              Print only warnings and errors (both always on stderr)
+  --json-messages      Print every message as a JSON object on a line of its
+                       own: {"level":"info","message":"..."}
+  --force-ansi         Allow mode=blocks to write its escape sequences to a
+                       file (or to a non-terminal stdout with sample)
+  --no-expand          Use the filename as typed instead of expanding the time
+                       tokens %%Y %%m %%d %%H %%M %%S (and %%%% for a literal %%)
+  --auto-ext           Append .txt to a filename without an extension (.hidden
+                       gets .hidden.txt), before the exists check. Off unless
+                       given or $GENERATELINES_AUTO_EXT=on; --no-auto-ext wins
+  --strict-args        Reject arguments after modeArg (otherwise ignored with a
+                       warning) and positional arguments that could be read
+                       more than one way, such as a number as the filename
+  --explain            Before generating, list every parameter with its value
+                       and where it came from (positional arg N, prompt,
+                       default, flag or environment variable)
+  --no-name-confirm    Overwrite a much larger file on y alone, without typing
+                       its name
+  --force              Skip the --max-lines and slow pi confirmations
+  --force-target       Write even if the output, or a --golden, --manifest,
+                       --summary-json or --also-link file, is the running
+                       program, a Go source file in a module, or a file
+                       already open
+
+Presets:
+  Saved in presets.json under the user config directory
+  ($GENERATELINES_CONFIG_DIR overrides). "preset run" takes field=value
+  overrides for lines, filename, overwrite, width, mode and modeArg, plus
+  extra options.
+
+History:
+  With $GENERATELINES_HISTORY set to a file (or to default for
+  ~/.local/state/generatelines/history.jsonl), every run appends one JSON
+  line: start time, command line, resolved settings, output, size, duration
+  and outcome. "history" lists the last N runs (default 10).
+
+Batch:
+  "batch" runs one job per line of a spec file (- reads it from stdin, e.g.
+  a here-doc). A job is a command line as typed after {cmd} and
+  needs at least <lines> and <filename>; quote arguments with spaces. Blank
+  lines and lines starting with # are skipped. Jobs never prompt: give y or
+  n for files that may exist. Exits 1 if any job failed.
+
+Daemon mode:
+  Appends paced lines to <filename> until interrupted (Ctrl-C / SIGTERM),
+  rotating it logrotate-style: <filename> -> <filename>.1 -> <filename>.2 ...
+  --rate N             Lines per second. Default: 10, or none with --bps
+  --rotate-size SIZE   Rotate when the file reaches SIZE bytes (e.g. 10M).
+                       Default: never
+  --keep N             Rotated files to keep. Default: 5
+
+Stress files:
+  "stress-files" creates <count> files of size-per-file bytes (default 1K;
+  64K, 1M ...) in <dir>, creating it if needed, for filesystem benchmarks
+  that need many small files. Files are named by their zero-padded index
+  (001.txt .. 300.txt); existing ones are only replaced with --force. Reports
+  the files/s and bytes/s reached. Ctrl-C stops after the files in progress,
+  removing any cut short, and reports how many were completed.
+  --concurrency N      Files created at a time. Default: 1
+
+Streams:
+  "streams" writes <stdout-lines> lines to stdout and <stderr-lines> to
+  stderr, interleaved in a fixed pattern, for testing tools that must keep a
+  child's streams apart. Nothing else is printed unless it fails.
+  --ratio A:B          A stdout lines, then B stderr lines, in turn; when one
+                       stream is done the other gets the rest. Default: 1:1
+  --tags               Start every line with OUT or ERR and its number in its
+                       stream (OUT 00001 ...), within the width
+  --delay D            Wait D (e.g. 10ms) after every line
+
+`
+
+// helpOutro is the help after the modes.
+const helpOutro = `
+Sample:
+  "sample" prints count (default 5) lines to stdout exactly as the start of a
+  run with the same width, mode and modeArg, and writes no file. Use - for no
+  modeArg before a count. --line-checksum, --ramp, --comment-every,
+  --escape-nonascii, --rot, --trailing-ws, --line-pattern, --inject-unicode,
+  --start-offset, --safe-start and --byte-range apply. If the reader closes
+  stdout early (| head), sample stops quietly with exit code 0.
+  Example: {cmd} sample 60 pi - 3
+
+Selftest:
+  "selftest" generates a small sample of every mode in memory and through a
+  temporary file, checks counts, widths and each mode's content rules, and
+  prints a PASS/FAIL table. Exits with 1 if any mode fails.
+
+Notes:
+  - If parameters are omitted, the program will prompt interactively.
+  - Defaults are width=80 and mode=ascii.
+
+Examples:
+  {cmd} 1000 lines.txt
+  {cmd} 1000 lines.txt y
+  {cmd} 1000 uppercase.txt y 120 upper
+  {cmd} 1000 characters.txt y 80 char "#"
+  {cmd} 50 pi.txt n 80 pi
+  {cmd} daemon app.log --rate 100 --rotate-size 10M --keep 5
+  {cmd} stress-files 10000 bench/ 4K --concurrency 8
+  {cmd} streams 300 100 40 --ratio 3:1 --tags --delay 5ms
+  {cmd} help modes
+  {cmd} help csv
+`
+
+// modeTextWidth is the width of the mode list after the column of mode
+// names; aliases that do not fit on the first line get a line of their own.
+const modeTextWidth = 64
+
+// printModes prints the Modes section of the help: every registered mode,
+// built-in ones first, then how to interleave them.
+func printModes() {
+	fmt.Print(expandHelp("Modes:\n  (\"{cmd} help <mode>\" shows one mode with examples)\n"))
+	for _, name := range genlines.ModeNames() {
+		_, spec, _ := genlines.LookupMode(name)
+		lines := modeText(spec)
+		if note := aliasNote(spec.Aliases); note != "" {
+			if utf8.RuneCountInString(lines[0])+1+len(note) <= modeTextWidth {
+				lines[0] += " " + note
+			} else {
+				lines = append(lines, note)
+			}
+		}
+		fmt.Printf("  %-12s %s\n", name, lines[0])
+		for _, l := range lines[1:] {
+			fmt.Printf("  %-12s %s\n", "", l)
+		}
+	}
+	fmt.Print(`  a:W+b:W      Interleave streams with their own widths, e.g. digits:20+ascii:100
+               (lines alternate between the streams; add :arg for a modeArg,
+               e.g. char:20:#). No separate width argument is allowed.
+`)
+}
+
+// printModeHelp prints the help of one mode, as "help <mode>" does.
+func printModeHelp(name string, spec genlines.ModeSpec) {
+	title := name
+	if note := aliasNote(spec.Aliases); note != "" {
+		title += " " + note
+	}
+	fmt.Printf("Mode %s:\n", title)
+	for _, l := range modeText(spec) {
+		fmt.Println("  " + l)
+	}
+	if len(spec.Examples) > 0 {
+		if len(spec.Examples) == 1 {
+			fmt.Println("\nExample:")
+		} else {
+			fmt.Println("\nExamples:")
+		}
+		for _, ex := range spec.Examples {
+			fmt.Printf("  %s %s\n", cmdName, ex)
+		}
+	}
+}
+
+// modeText returns the help text of spec as lines: its Help, or else its
+// Description with a note when it requires a modeArg.
+func modeText(spec genlines.ModeSpec) []string {
+	if spec.Help != "" {
+		return strings.Split(spec.Help, "\n")
+	}
+	text := spec.Description
+	if spec.RequiresArg && !strings.Contains(text, "modeArg") {
+		text += " (requires modeArg)"
+	}
+	return []string{text}
+}
+
+// aliasNote returns "(alias: a)" or "(aliases: a, b)", or "" without aliases.
+func aliasNote(aliases []string) string {
+	switch len(aliases) {
+	case 0:
+		return ""
+	case 1:
+		return "(alias: " + aliases[0] + ")"
+	}
+	return "(aliases: " + strings.Join(aliases, ", ") + ")"
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+func TestCommandName(t *testing.T) {
+	for arg0, want := range map[string]string{
+		"/usr/local/bin/genlines":     "genlines",
+		"./generatelines":             "generatelines",
+		"GenerateLines.exe":           "GenerateLines",
+		"GENLINES.EXE":                "GENLINES",
+		"gen-lines_2.x":               "gen-lines_2.x",
+		"":                            defaultCommandName,
+		"/":                           defaultCommandName,
+		".exe":                        defaultCommandName,
+		"/tmp/gen lines":              defaultCommandName,
+		`say"hi"`:                     defaultCommandName,
+		"-bash":                       defaultCommandName,
+		"/tmp/go-build1/x/pkg.test":   defaultCommandName,
+		"/tmp/x/generatelines%d.exe":  defaultCommandName,
+		"/tmp/x/gen\tlines":           defaultCommandName,
+		"/opt/bin/generatelines-v1.0": "generatelines-v1.0",
+	} {
+		if got := commandName(arg0); got != want {
+			t.Errorf("commandName(%q) = %q, want %q", arg0, got, want)
+		}
+	}
+}
+
+// useCmdName sets cmdName for the rest of the test.
+func useCmdName(t *testing.T, name string) {
+	old := cmdName
+	cmdName = name
+	t.Cleanup(func() { cmdName = old })
+}
+
+func TestRunHelpCmd_UsesCommandName(t *testing.T) {
+	useCmdName(t, "gl")
+	out := captureStdout(t)
+	if code := run([]string{"--help"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	text := out()
+	for _, want := range []string{
+		"  gl <lines> <filename> [y|n]",
+		"  gl daemon <filename> [width] [mode] [modeArg] [--rate N]\n     [--rotate-size SIZE]",
+		"  gl 1000 characters.txt y 80 char \"#\"",
+		"typed after gl and",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("help lacks %q", want)
+		}
+	}
+	if strings.Contains(text, "generatelines ") || strings.Contains(text, "{cmd}") || strings.Contains(text, "{pad}") {
+		t.Error("help still names generatelines or has a placeholder left")
+	}
+	if hint := helpHint(); !strings.Contains(hint, `"gl -h"`) && !strings.Contains(hint, `"gl /?"`) {
+		t.Errorf("hint %q", hint)
+	}
+}
+
+func TestRunHelpCmd_Topics(t *testing.T) {
+	useCmdName(t, "genlines")
+	out := captureStdout(t)
+	if code := run([]string{"help", "modes"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	modes := out()
+	if !strings.HasPrefix(modes, "Modes:\n") || strings.Contains(modes, "Usage:") || strings.Contains(modes, "Examples:") {
+		t.Errorf("help modes printed more than the modes:\n%s", modes)
+	}
+	for _, name := range genlines.ModeNames() {
+		if !strings.Contains(modes, "\n  "+name+" ") {
+			t.Errorf("help modes lacks %s", name)
+		}
+	}
+
+	// A mode is found by its alias, and its text and examples come from the
+	// registry.
+	out = captureStdout(t)
+	if code := run([]string{"help", "RAND"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	_, spec, _ := genlines.LookupMode("random")
+	want := "Mode random (alias: rand):\n  " + strings.ReplaceAll(spec.Help, "\n", "\n  ") +
+		"\n\nExample:\n  genlines " + spec.Examples[0] + "\n"
+	if got := out(); got != want {
+		t.Errorf("help rand:\n%s\nwant:\n%s", got, want)
+	}
+
+	errOutput := captureStderr(t)
+	if code := run([]string{"help", "randm"}); code != 1 {
+		t.Errorf("unknown mode: exit code %d", code)
+	}
+	if got := errOutput(); !strings.Contains(got, `did you mean "random"`) || !strings.Contains(got, "genlines help modes") {
+		t.Errorf("stderr %q", got)
+	}
+	if code := run([]string{"help", "modes", "ascii"}); code != 1 {
+		t.Errorf("two topics: exit code %d", code)
+	}
+}
+
+func TestRunHelpCmd_CustomMode(t *testing.T) {
+	t.Cleanup(genlines.ResetModes)
+	if err := genlines.RegisterMode("stairs", genlines.ModeSpec{
+		Description: "Steps of #",
+		RequiresArg: true,
+		Factory: func(string, int) (genlines.Generator, error) {
+			return genlines.NewGenerator("char", "#", 0)
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	out := captureStdout(t)
+	if code := run([]string{"help", "stairs"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if got := out(); got != "Mode stairs:\n  Steps of # (requires modeArg)\n" {
+		t.Errorf("help stairs: %q", got)
+	}
+}
+
+func TestModeExamples_Run(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile("fw.img", bytes.Repeat([]byte("\x00\x01firmware\xff"), 30), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range genlines.ModeNames() {
+		_, spec, _ := genlines.LookupMode(name)
+		if len(spec.Examples) == 0 {
+			t.Errorf("%s has no example", name)
+		}
+		for _, ex := range spec.Examples {
+			args, err := splitJobLine(ex)
+			if err != nil {
+				t.Fatalf("%s: %v", ex, err)
+			}
+			if name == "blocks" {
+				args = append(args, "--force-ansi") // stdout is not a terminal here
+			}
+			if !strings.Contains(" "+ex+" ", " "+name+" ") {
+				t.Errorf("%s: example %q does not use the mode", name, ex)
+			}
+			out := captureStdout(t)
+			if code := run(args); code != 0 {
+				t.Errorf("%s: %q exited with %d", name, ex, code)
+			}
+			out()
+		}
+	}
+}
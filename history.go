@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+	"github.com/Bjornsrud/GenerateLines/genlines/humanize"
+)
+
+// historyEnv turns on the run history: a path to the history file, or
+// "default" for defaultHistoryPath. Unset or empty, nothing is recorded.
+const historyEnv = "GENERATELINES_HISTORY"
+
+// defaultHistoryEntries is how many entries "history" lists without a count.
+const defaultHistoryEntries = 10
+
+// historyEntry is one line of the history file: a generation run and how it
+// ended.
+type historyEntry struct {
+	Time       time.Time      `json:"time"` // when the run started, in UTC
+	Version    string         `json:"version"`
+	Args       []string       `json:"args"`               // the command line as given
+	Output     string         `json:"output,omitempty"`   // absolute path, URL or fd:N
+	Options    map[string]any `json:"options,omitempty"`  // resolved settings, by sidecar name
+	Lines      int64          `json:"lines,omitempty"`    // data lines the run wrote
+	Bytes      int64          `json:"bytes,omitempty"`    // bytes the run wrote
+	DurationMs float64        `json:"durationMs"`         // whole run, prompts included
+	Outcome    string         `json:"outcome"`            // ok, skipped, failed, verify-failed or over-cap
+	ExitCode   int            `json:"exitCode,omitempty"` // the run's exit code
+}
+
+// runHistory records one run in the history file, if there is one. A nil
+// *runHistory (history off) records nothing.
+type runHistory struct {
+	path    string
+	entry   historyEntry
+	start   time.Time
+	skipped bool // ended without writing, e.g. an overwrite was declined
+}
+
+// historyPath returns the history file configured in the environment, or ""
+// when history is off.
+func historyPath() (string, error) {
+	v := strings.TrimSpace(os.Getenv(historyEnv))
+	if v != "default" {
+		return v, nil
+	}
+	return defaultHistoryPath()
+}
+
+// defaultHistoryPath returns $XDG_STATE_HOME/generatelines/history.jsonl,
+// with ~/.local/state for an unset XDG_STATE_HOME.
+func defaultHistoryPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("no home directory for the history file (set %s to a path): %w", historyEnv, err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "generatelines", "history.jsonl"), nil
+}
+
+// startHistory begins the history entry of a generation run of args, or
+// returns nil when history is off. A history file that cannot be located is
+// warned about and leaves history off: it never stops the run.
+func startHistory(args []string) *runHistory {
+	path, err := historyPath()
+	if err != nil {
+		cli.Warn("run not recorded in the history: %v", err)
+		return nil
+	}
+	if path == "" {
+		return nil
+	}
+	now := time.Now()
+	return &runHistory{path: path, start: now, entry: historyEntry{
+		Time:    now.UTC().Truncate(time.Millisecond),
+		Version: version,
+		Args:    append([]string(nil), args...),
+	}}
+}
+
+// resolve records the output and the settings of the run, once known.
+func (h *runHistory) resolve(filename string, opts genlines.Options) {
+	if h == nil {
+		return
+	}
+	h.setOutput(filename)
+	h.entry.Options = map[string]any{}
+	for _, s := range newRunMeta(filename, opts, 0, "").settings() {
+		h.entry.Options[s.name] = s.value
+	}
+}
+
+// setOutput records where the run writes, as an absolute path for files.
+func (h *runHistory) setOutput(filename string) {
+	if h == nil {
+		return
+	}
+	h.entry.Output = filename
+	if isUploadURL(filename) || isFDTarget(filename) {
+		return
+	}
+	if abs, err := filepath.Abs(filename); err == nil {
+		h.entry.Output = abs
+	}
+}
+
+// written records what the run wrote: the lines and bytes of the Stats of
+// its generate call, which also count for a run that failed part way.
+func (h *runHistory) written(lines, bytes int64) {
+	if h == nil {
+		return
+	}
+	h.entry.Lines, h.entry.Bytes = lines, bytes
+}
+
+// skip marks a run that ends successfully without writing anything, such
+// as one whose overwrite was declined.
+func (h *runHistory) skip() {
+	if h == nil {
+		return
+	}
+	h.skipped = true
+}
+
+// finish appends the entry of a run that exits with code to the history
+// file. The entry goes in as one write to a file opened with O_APPEND, so
+// the lines of runs finishing at the same time do not interleave. Failures
+// are warned about, never returned.
+func (h *runHistory) finish(code int) {
+	if h == nil {
+		return
+	}
+	e := h.entry
+	e.DurationMs = float64(time.Since(h.start).Microseconds()) / 1000
+	e.ExitCode = code
+	switch {
+	case code == 0 && h.skipped:
+		e.Outcome = "skipped"
+	case code == 0:
+		e.Outcome = "ok"
+	case code == exitVerifyFailed:
+		e.Outcome = "verify-failed"
+	case code == exitCapExceeded:
+		e.Outcome = "over-cap"
+	default:
+		e.Outcome = "failed"
+	}
+
+	if err := appendHistory(h.path, e); err != nil {
+		cli.Warn("run not recorded in the history file %s: %v", h.path, err)
+	}
+}
+
+// appendHistory adds e to the history file at path as one JSON line,
+// creating the file and its directory if needed.
+func appendHistory(path string, e historyEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readHistory returns the last n entries of the history file at path, oldest
+// first. Lines that are not entries (e.g. one cut short by a full disk) are
+// skipped.
+func readHistory(path string, n int) ([]historyEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []historyEntry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var e historyEntry
+		if json.Unmarshal(sc.Bytes(), &e) != nil {
+			continue
+		}
+		entries = append(entries, e)
+		if len(entries) > n {
+			entries = entries[1:]
+		}
+	}
+	return entries, sc.Err()
+}
+
+// runHistoryCmd handles "history [N]": it lists the last N runs (default
+// defaultHistoryEntries) and returns the exit code.
+func runHistoryCmd(args []string) int {
+	n := defaultHistoryEntries
+	switch {
+	case len(args) > 1:
+		cli.Error("history takes at most one argument, the number of entries")
+		cli.Hint(helpHint())
+		return 1
+	case len(args) == 1:
+		v, err := parsePositiveInt(args[0])
+		if err != nil {
+			cli.Error("invalid number of entries: %q (expected a positive integer)", args[0])
+			return 1
+		}
+		n = v
+	}
+
+	path, err := historyPath()
+	if err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+	if path == "" {
+		cli.Error("history is off; set %s to a file path, or to default for %s", historyEnv, "~/.local/state/generatelines/history.jsonl")
+		return 1
+	}
+	entries, err := readHistory(path, n)
+	if errors.Is(err, os.ErrNotExist) {
+		cli.Info("No runs recorded yet in %s", path)
+		return 0
+	}
+	if err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+
+	for _, e := range entries {
+		// A run that failed before resolving its settings never got to write.
+		lines := "-"
+		if e.Options != nil {
+			lines = humanize.Count(e.Lines)
+		}
+		// A run that failed before naming its output shows what was typed.
+		what := e.Output
+		if what == "" {
+			what = strings.Join(e.Args, " ")
+		}
+		took := humanize.Duration(time.Duration(e.DurationMs * float64(time.Millisecond)))
+		fmt.Printf("%s  %-13s %10s lines %10s %9s  %s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Outcome,
+			lines, humanize.Bytes(e.Bytes), took, what)
+	}
+	return 0
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// historyLines returns the entries in the history file at path.
+func historyLines(t *testing.T, path string) []historyEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []historyEntry
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e historyEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestRun_HistoryRecordsRuns(t *testing.T) {
+	dir := t.TempDir()
+	hist := filepath.Join(dir, "state", "history.jsonl")
+	t.Setenv(historyEnv, hist)
+
+	out := filepath.Join(dir, "out.txt")
+	if code := run([]string{"10", out, "y", "20", "digits", "--line-checksum"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if code := run([]string{"10", out, "y", "20", "nosuchmode"}); code == 0 {
+		t.Fatal("unknown mode accepted")
+	}
+	if code := run([]string{"5", out, "y", "20", "random", "--seed", "3"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if code := run([]string{"version"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+
+	entries := historyLines(t, hist)
+	if len(entries) != 3 {
+		t.Fatalf("%d entries, want 3 (subcommands are not runs)", len(entries))
+	}
+	first := entries[0]
+	if first.Outcome != "ok" || first.Output != out || first.Lines != 10 || first.Bytes != 10*21 || first.Version != version ||
+		strings.Join(first.Args, " ") != "10 "+out+" y 20 digits --line-checksum" {
+		t.Errorf("entry %+v", first)
+	}
+	if first.Options["lines"] != 10.0 || first.Options["mode"] != "digits" || first.Options["lineChecksum"] != true {
+		t.Errorf("options %v", first.Options)
+	}
+	if entries[1].Outcome != "failed" || entries[1].ExitCode != 1 || entries[1].Options != nil {
+		t.Errorf("failed run %+v", entries[1])
+	}
+	if entries[2].Options["seed"] != 3.0 || entries[2].Time.Before(first.Time) {
+		t.Errorf("seeded run %+v", entries[2])
+	}
+
+	output := captureStdout(t)
+	if code := run([]string{"history", "2"}); code != 0 {
+		t.Fatalf("history exit code %d", code)
+	}
+	lines := strings.Split(strings.TrimSpace(output()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "failed") || !strings.Contains(lines[1], "ok") ||
+		!strings.Contains(lines[1], " 5 lines") || !strings.HasSuffix(lines[1], out) {
+		t.Errorf("history listing %q", lines)
+	}
+}
+
+func TestRun_HistoryRecordsWhatWasWritten(t *testing.T) {
+	dir := t.TempDir()
+	hist := filepath.Join(dir, "history.jsonl")
+	t.Setenv(historyEnv, hist)
+	out := filepath.Join(dir, "out.txt")
+	captureStdout(t)
+	captureStderr(t)
+
+	for _, args := range [][]string{
+		{"3", out, "y", "10"},
+		{"3", out, "n", "10"},
+		{"100", out, "y", "10", "--max-bytes", "50"},
+		{"4", "-", "y", "10"},
+	} {
+		if code := run(args); code != 0 {
+			t.Fatalf("%v exited with %d", args, code)
+		}
+	}
+
+	entries := historyLines(t, hist)
+	if len(entries) != 4 {
+		t.Fatalf("%d entries, want 4", len(entries))
+	}
+	for i, want := range []struct {
+		outcome      string
+		lines, bytes int64
+	}{
+		{"ok", 3, 33},
+		{"skipped", 0, 0},
+		{"ok", 4, 44},
+		{"ok", 4, 44},
+	} {
+		if e := entries[i]; e.Outcome != want.outcome || e.Lines != want.lines || e.Bytes != want.bytes {
+			t.Errorf("entry %d: %s, %d lines, %d bytes; want %s, %d lines, %d bytes",
+				i, e.Outcome, e.Lines, e.Bytes, want.outcome, want.lines, want.bytes)
+		}
+	}
+}
+
+func TestRun_HistoryOffOrUnwritable(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	t.Setenv(historyEnv, "")
+	out := filepath.Join(dir, "out.txt")
+	if code := run([]string{"3", out, "y"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "generatelines")); len(entries) > 0 {
+		t.Error("history written while off")
+	}
+	if code := run([]string{"history"}); code != 1 {
+		t.Errorf("history while off exited with %d", code)
+	}
+
+	// default picks the state directory.
+	t.Setenv(historyEnv, "default")
+	if code := run([]string{"3", out, "y"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if entries := historyLines(t, filepath.Join(dir, "generatelines", "history.jsonl")); len(entries) != 1 {
+		t.Errorf("%d entries", len(entries))
+	}
+
+	// A history file that cannot be written costs a warning, not the run.
+	t.Setenv(historyEnv, filepath.Join(out, "history.jsonl"))
+	errOutput := captureStderr(t)
+	if code := run([]string{"3", out, "y"}); code != 0 {
+		t.Errorf("exit code %d", code)
+	}
+	if got := errOutput(); !strings.Contains(got, "WARNING: run not recorded in the history file") {
+		t.Errorf("stderr %q", got)
+	}
+}
+
+func TestAppendHistory_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	const n = 50
+	// Long entries make torn writes likely if the appends were not atomic.
+	arg := strings.Repeat("x", 8*1024)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			if err := appendHistory(path, historyEntry{Args: []string{arg}, ExitCode: i, Outcome: "ok"}); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	seen := map[int]bool{}
+	for _, e := range historyLines(t, path) {
+		if len(e.Args) != 1 || e.Args[0] != arg {
+			t.Fatalf("torn entry %d", e.ExitCode)
+		}
+		seen[e.ExitCode] = true
+	}
+	if len(seen) != n {
+		t.Errorf("%d distinct entries, want %d", len(seen), n)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+	"github.com/Bjornsrud/GenerateLines/genlines/humanize"
+)
+
+const (
+	// defaultMaxLines is the soft cap above which a run needs confirmation.
+	defaultMaxLines = 100_000_000
+
+	// maxLinesEnv overrides defaultMaxLines; 0 disables the cap.
+	maxLinesEnv = "GENERATELINES_MAX_LINES"
+
+	// exitCapExceeded is the exit code when a run over the line cap, or a pi
+	// run estimated to take longer than piWarnAfter, is not confirmed.
+	exitCapExceeded = 3
+
+	// piWarnAfter is the estimated duration above which a pi run needs
+	// confirmation.
+	piWarnAfter = 30 * time.Second
+)
+
+// piClock times the pi calibration burst. Tests replace it.
+var piClock = time.Now
+
+// stdinIsTerminal reports whether stdin is interactive: a terminal, not just
+// any character device such as /dev/null. Tests replace it.
+var stdinIsTerminal = func() bool { return isTTY(os.Stdin) }
+
+// resolveMaxLines returns the line cap from the --max-lines flag, the
+// environment, or the default, in that order of precedence.
+func resolveMaxLines(flags cliFlags) (int, provenance, error) {
+	if flags.maxLinesSet {
+		return flags.maxLines, provenance{from: fromFlag, name: "max-lines"}, nil
+	}
+	if v, ok := os.LookupEnv(maxLinesEnv); ok && strings.TrimSpace(v) != "" {
+		n, err := parseLineCount(v)
+		if err != nil {
+			return 0, provenance{}, fmt.Errorf("invalid %s: %q (%v)", maxLinesEnv, v, err)
+		}
+		return n, provenance{from: fromEnv, name: maxLinesEnv}, nil
+	}
+	return defaultMaxLines, provenance{}, nil
+}
+
+// errCapDeclined reports that a run over the line cap was not confirmed.
+var errCapDeclined = errors.New("run exceeds the line cap and was not confirmed")
+
+// checkLineCap confirms runs of more than maxLines lines (0 = no cap). Interactive
+// sessions are asked, showing the projected size (size < 0 if unknown); non-interactive ones fail unless force is set.
+func checkLineCap(in *bufio.Reader, lines, maxLines int, size int64, force bool) error {
+	if maxLines == 0 || lines <= maxLines {
+		return nil
+	}
+
+	projected := "unknown"
+	if size >= 0 {
+		projected = humanize.Bytes(size)
+	}
+	msg := fmt.Sprintf("%d lines exceeds the cap of %d lines (projected size %s)", lines, maxLines, projected)
+	if force {
+		cli.Warn("--force given: %s", msg)
+		return nil
+	}
+	if !stdinIsTerminal() || promptsDisabled {
+		return fmt.Errorf("%s; use --force or raise --max-lines / %s", msg, maxLinesEnv)
+	}
+
+	ok, err := promptYesNoR(in, fmt.Sprintf("%s. Continue? [y/n]: ", msg))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errCapDeclined
+	}
+	return nil
+}
+
+// errPiDeclined reports that a slow pi run was not confirmed.
+var errPiDeclined = errors.New("pi run estimated to be slow and was not confirmed")
+
+// checkPiEstimate confirms pi runs whose calibration projects more than
+// piWarnAfter. Like checkLineCap, interactive sessions are asked and
+// non-interactive ones fail unless force is set.
+func checkPiEstimate(in *bufio.Reader, cal *genlines.PiCalibration, force bool) error {
+	est := cal.Estimate()
+	if est <= piWarnAfter {
+		return nil
+	}
+
+	msg := fmt.Sprintf("mode=pi: computing %d digits is estimated to take %s (measured %d digits in %s)",
+		cal.Digits, humanize.Duration(est.Round(time.Second)), cal.Sampled, humanize.Duration(cal.Elapsed))
+	if force {
+		cli.Warn("--force given: %s", msg)
+		return nil
+	}
+	if !stdinIsTerminal() || promptsDisabled {
+		return fmt.Errorf("%s; use --force to run it anyway", msg)
+	}
+
+	ok, err := promptYesNoR(in, fmt.Sprintf("%s. Continue? [y/n]: ", msg))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errPiDeclined
+	}
+	return nil
+}
+
+// sortMemoryHint suggests what to do about a --sort run over its memory cap.
+func sortMemoryHint(opts genlines.Options) string {
+	return fmt.Sprintf("Tip: raise --sort-max-memory to at least %s, or write the lines unsorted and sort them on disk with LC_ALL=C sort (the same byte-wise order)",
+		humanize.Bytes(genlines.SortMemory(opts)))
+}
+
+// maxBytesSummary describes which limit ended a run with a --max-bytes
+// ceiling: generated of want lines were written, making the file size bytes.
+func maxBytesSummary(generated, want int, size, ceiling int64) string {
+	switch {
+	case generated < want:
+		return text("maxBytes.cut", generated, want, size, ceiling)
+	case size == ceiling:
+		return text("maxBytes.exact", generated, size)
+	default:
+		return text("maxBytes.within", generated, size, ceiling)
+	}
+}
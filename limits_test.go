@@ -0,0 +1,189 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withStdin replaces os.Stdin with a file holding input and marks it as
+// interactive (or not) for the duration of the test.
+func withStdin(t *testing.T, input string, interactive bool) {
+	t.Helper()
+
+	tmp, err := os.CreateTemp(t.TempDir(), "stdin-*")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	tmp.WriteString(input)
+	tmp.Seek(0, 0)
+
+	oldStdin, oldTerm := os.Stdin, stdinIsTerminal
+	os.Stdin = tmp
+	stdinIsTerminal = func() bool { return interactive }
+	t.Cleanup(func() {
+		os.Stdin, stdinIsTerminal = oldStdin, oldTerm
+		tmp.Close()
+	})
+}
+
+func TestRun_LineCap_UnderCapDoesNotPrompt(t *testing.T) {
+	withStdin(t, "", true) // any prompt would hit EOF and fail
+	path := filepath.Join(t.TempDir(), "small.txt")
+	if code := run([]string{"10", path, "y", "--max-lines", "10"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if !fileExists(path) {
+		t.Fatalf("expected output file")
+	}
+}
+
+func TestRun_LineCap_OverCapDeclined(t *testing.T) {
+	withStdin(t, "n\n", true)
+	path := filepath.Join(t.TempDir(), "big.txt")
+	if code := run([]string{"11", path, "y", "--max-lines", "10"}); code != exitCapExceeded {
+		t.Fatalf("expected exit %d, got %d", exitCapExceeded, code)
+	}
+	if fileExists(path) {
+		t.Fatalf("declined run must not create the file")
+	}
+}
+
+func TestRun_LineCap_OverCapConfirmed(t *testing.T) {
+	withStdin(t, "y\n", true)
+	path := filepath.Join(t.TempDir(), "big.txt")
+	if code := run([]string{"11", path, "y", "--max-lines", "10"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+}
+
+func TestRun_LineCap_NonInteractiveFailsUnlessForced(t *testing.T) {
+	withStdin(t, "y\n", false)
+	dir := t.TempDir()
+
+	t.Setenv(maxLinesEnv, "5")
+	if code := run([]string{"6", filepath.Join(dir, "a.txt"), "y"}); code != exitCapExceeded {
+		t.Fatalf("expected exit %d without --force, got %d", exitCapExceeded, code)
+	}
+	if code := run([]string{"6", filepath.Join(dir, "b.txt"), "y", "--force"}); code != 0 {
+		t.Fatalf("expected --force to bypass the cap, got %d", code)
+	}
+	if code := run([]string{"6", filepath.Join(dir, "c.txt"), "y", "--max-lines", "0"}); code != 0 {
+		t.Fatalf("expected --max-lines 0 to disable the cap, got %d", code)
+	}
+}
+
+// withPiClock makes every reading of the pi calibration clock advance by step.
+func withPiClock(t *testing.T, step time.Duration) {
+	t.Helper()
+	old := piClock
+	now := time.Unix(0, 0)
+	piClock = func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+	t.Cleanup(func() { piClock = old })
+}
+
+func TestRun_PiEstimate_FastRunDoesNotPrompt(t *testing.T) {
+	withStdin(t, "", true) // any prompt would hit EOF and fail
+	withPiClock(t, time.Microsecond)
+	path := filepath.Join(t.TempDir(), "pi.txt")
+	if code := run([]string{"4", path, "y", "10", "pi"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "3141592653\n5897932384\n6264338327\n9502884197\n" {
+		t.Errorf("unexpected pi content %q", data)
+	}
+}
+
+func TestRun_PiEstimate_SlowRunNeedsConfirmation(t *testing.T) {
+	withPiClock(t, time.Second) // one second per digit: 40 digits take 40s
+	dir := t.TempDir()
+
+	withStdin(t, "", false)
+	path := filepath.Join(dir, "refused.txt")
+	if code := run([]string{"4", path, "y", "10", "pi"}); code != exitCapExceeded {
+		t.Fatalf("non-interactive slow pi run exited with %d, want %d", code, exitCapExceeded)
+	}
+	if fileExists(path) {
+		t.Fatal("refused run created the file")
+	}
+
+	withStdin(t, "n\n", true)
+	if code := run([]string{"4", path, "y", "10", "pi"}); code != exitCapExceeded || fileExists(path) {
+		t.Fatalf("declined run exited with %d", code)
+	}
+
+	withStdin(t, "y\n", true)
+	if code := run([]string{"4", path, "y", "10", "pi"}); code != 0 {
+		t.Fatalf("confirmed run exited with %d", code)
+	}
+
+	withStdin(t, "", false)
+	forced := filepath.Join(dir, "forced.txt")
+	if code := run([]string{"4", forced, "y", "10", "pi", "--force"}); code != 0 {
+		t.Fatalf("forced run exited with %d", code)
+	}
+	if data, _ := os.ReadFile(forced); !strings.HasPrefix(string(data), "3141592653\n") {
+		t.Errorf("unexpected pi content %q", data)
+	}
+}
+
+func TestRun_SortAndMemoryCap(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sorted.txt")
+	if code := run([]string{"50", path, "y", "12", "random", "3", "--sort", "desc", "--meta", "--verify-after"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 50 || !sort.IsSorted(sort.Reverse(sort.StringSlice(lines))) {
+		t.Errorf("not sorted descending:\n%s", data)
+	}
+	if m, err := readMeta(path + metaSuffix); err != nil || m.Sort != "desc" {
+		t.Fatalf("sidecar sort %q, %v", m.Sort, err)
+	}
+	if code := run([]string{"regen", path + metaSuffix, filepath.Join(dir, "again.txt")}); code != 0 {
+		t.Errorf("regen exited with %d", code)
+	}
+
+	output := captureStderr(t)
+	if code := run([]string{"100000", filepath.Join(dir, "big.txt"), "y", "80", "ascii", "--sort", "asc", "--sort-max-memory", "1MiB"}); code == 0 {
+		t.Fatal("a sort over the memory cap was accepted")
+	}
+	if text := output(); !strings.Contains(text, "over the cap of 1048576") || !strings.Contains(text, "raise --sort-max-memory to at least 9.2 MiB") {
+		t.Errorf("stderr %q", text)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "big.txt")); !os.IsNotExist(err) {
+		t.Errorf("refused run left a file: %v", err)
+	}
+}
+
+func TestRun_LineCap_DevNullStdinRefuses(t *testing.T) {
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	old := os.Stdin
+	os.Stdin = null
+	t.Cleanup(func() { os.Stdin = old })
+
+	// /dev/null is a character device but not a terminal: no prompt, just
+	// the refusal.
+	errOut := captureStderr(t)
+	path := filepath.Join(t.TempDir(), "big.txt")
+	if code := run([]string{"20", path, "y", "--max-lines", "10"}); code != exitCapExceeded {
+		t.Fatalf("exit code %d, want %d", code, exitCapExceeded)
+	}
+	if got := errOut(); !strings.Contains(got, "use --force") || strings.Contains(got, "EOF") {
+		t.Errorf("stderr %q", got)
+	}
+	if fileExists(path) {
+		t.Error("file written past the cap")
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// linkFile makes a hard link. Tests replace it to act like a filesystem that
+// cannot link across devices.
+var linkFile = os.Link
+
+// alsoLink places the finished output src into each of dirs under its base
+// name (--also-link), asking before it replaces an existing file as for the
+// output itself. Destinations are handled one by one: a failed one is
+// reported and the others are still written, and src is never touched. It
+// reports whether every destination was written or deliberately skipped.
+// With wipeOld, a replaced file is wiped before the new one takes its name.
+func alsoLink(prompt *overwritePrompt, src string, dirs []string, noNameCheck, wipeOld bool) bool {
+	var size int64 = -1
+	if fi, err := os.Stat(src); err == nil {
+		size = fi.Size()
+	}
+	ok := true
+	for i, dir := range dirs {
+		dst := filepath.Join(dir, filepath.Base(src))
+		if sameFile(src, dst) {
+			cli.Info("%s is already %s", dst, src)
+			continue
+		}
+		if fileExists(dst) {
+			asked, what := prompt.asks(), describeExisting(dst, size)
+			overwrite, err := prompt.allow(what, i < len(dirs)-1)
+			if err == nil && asked && overwrite && !noNameCheck {
+				overwrite, err = prompt.confirmName(dst, size)
+			}
+			if err != nil {
+				cli.Error("%s: %v", dst, err)
+				ok = false
+				continue
+			}
+			if !overwrite {
+				cli.Warn(text("overwrite.skipped"), what)
+				continue
+			}
+		}
+		linkErr, err := placeFile(src, dst, wipeOld)
+		switch {
+		case err != nil:
+			cli.Error("cannot place %s in %s: %v", src, dir, err)
+			ok = false
+		case linkErr != nil:
+			cli.Info("Copied %s to %s (no hard link: %v)", src, dst, linkErr)
+		default:
+			cli.Info("Linked %s to %s", src, dst)
+		}
+	}
+	return ok
+}
+
+// placeFile puts the content of src at dst, replacing any file there: as a
+// hard link if one can be made, otherwise as a copy, in which case linkErr
+// says why the link failed. Either way dst appears whole, renamed into place
+// from a temporary name in its directory; with wipeOld, the file it replaces
+// is wiped just before.
+func placeFile(src, dst string, wipeOld bool) (linkErr, err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	name := tmp.Name()
+	defer os.Remove(name)
+	// The link needs the name free; the copy recreates it.
+	tmp.Close()
+	os.Remove(name)
+
+	if linkErr = linkFile(src, name); linkErr != nil {
+		var le *os.LinkError
+		if errors.As(linkErr, &le) {
+			linkErr = le.Err
+		}
+		if err := copyFile(src, name); err != nil {
+			return linkErr, err
+		}
+	}
+	if wipeOld {
+		if err := wipeFile(dst); err != nil {
+			return linkErr, err
+		}
+	}
+	return linkErr, os.Rename(name, dst)
+}
+
+// copyFile copies src to dst, which must not exist yet.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
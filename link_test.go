@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// linkDirs creates a primary output and two destination directories in one
+// temporary directory.
+func linkDirs(t *testing.T) (src, dir1, dir2 string) {
+	t.Helper()
+	root := t.TempDir()
+	dir1, dir2 = filepath.Join(root, "one"), filepath.Join(root, "two")
+	for _, d := range []string{dir1, dir2} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(root, "out.txt"), dir1, dir2
+}
+
+// isSameFile reports whether a and b are links to one file.
+func isSameFile(t *testing.T, a, b string) bool {
+	t.Helper()
+	ai, err := os.Stat(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return os.SameFile(ai, bi)
+}
+
+func TestRun_AlsoLinkHardLinks(t *testing.T) {
+	src, dir1, dir2 := linkDirs(t)
+	out := captureStdout(t)
+	if code := run([]string{"5", src, "y", "10", "digits", "--also-link", dir1 + "," + dir2}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	for _, d := range []string{dir1, dir2} {
+		if !isSameFile(t, src, filepath.Join(d, "out.txt")) {
+			t.Errorf("%s/out.txt is not a link to the output", d)
+		}
+	}
+	if got := out(); strings.Count(got, "Linked ") != 2 {
+		t.Errorf("stdout %q", got)
+	}
+
+	// Linking again finds the links already there.
+	out = captureStdout(t)
+	if code := run([]string{"5", src, "y", "10", "digits", "--also-link", dir1}); code != 0 {
+		t.Fatalf("second run: exit code %d", code)
+	}
+	if got := out(); !strings.Contains(got, "is already "+src) {
+		t.Errorf("second run: %q", got)
+	}
+}
+
+func TestAlsoLink_CopiesAcrossDevices(t *testing.T) {
+	src, dir1, _ := linkDirs(t)
+	if err := os.WriteFile(src, []byte("content\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := linkFile
+	linkFile = func(oldname, newname string) error {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EXDEV}
+	}
+	t.Cleanup(func() { linkFile = old })
+
+	out := captureStdout(t)
+	if !alsoLink(newOverwritePrompt(nil, "y"), src, []string{dir1}, false, false) {
+		t.Fatal("alsoLink failed")
+	}
+	dst := filepath.Join(dir1, "out.txt")
+	if isSameFile(t, src, dst) {
+		t.Error("the copy is a link")
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "content\n" {
+		t.Errorf("copy holds %q", data)
+	}
+	if got := out(); !strings.Contains(got, "Copied "+src+" to "+dst+" (no hard link: "+syscall.EXDEV.Error()+")") {
+		t.Errorf("stdout %q", got)
+	}
+	if entries, _ := os.ReadDir(dir1); len(entries) != 1 {
+		t.Errorf("%d files left in %s", len(entries), dir1)
+	}
+}
+
+func TestAlsoLink_FailedDestinationKeepsTheRest(t *testing.T) {
+	src, dir1, dir2 := linkDirs(t)
+	missing := filepath.Join(filepath.Dir(src), "missing")
+	captureStdout(t)
+	errOutput := captureStderr(t)
+	if code := run([]string{"5", src, "y", "10", "--also-link", dir1 + "," + missing + "," + dir2}); code != 1 {
+		t.Fatalf("exit code %d, want 1", code)
+	}
+	if data, err := os.ReadFile(src); err != nil || len(data) != 5*11 {
+		t.Fatalf("output lost: %d bytes, %v", len(data), err)
+	}
+	for _, d := range []string{dir1, dir2} {
+		if !isSameFile(t, src, filepath.Join(d, "out.txt")) {
+			t.Errorf("%s was not linked", d)
+		}
+	}
+	if got := errOutput(); !strings.Contains(got, "in "+missing) || !strings.Contains(got, src+" itself is complete") {
+		t.Errorf("stderr %q", got)
+	}
+}
+
+func TestAlsoLink_AsksPerDestination(t *testing.T) {
+	src, dir1, dir2 := linkDirs(t)
+	if err := os.WriteFile(src, []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{dir1, dir2} {
+		if err := os.WriteFile(filepath.Join(d, "out.txt"), []byte("old\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	captureStdout(t)
+	prompt := newOverwritePrompt(bufio.NewReader(strings.NewReader("n\ny\n")), "")
+	if !alsoLink(prompt, src, []string{dir1, dir2}, true, false) {
+		t.Fatal("alsoLink failed")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir1, "out.txt")); string(data) != "old\n" {
+		t.Errorf("declined destination holds %q", data)
+	}
+	if !isSameFile(t, src, filepath.Join(dir2, "out.txt")) {
+		t.Error("accepted destination was not replaced by a link")
+	}
+}
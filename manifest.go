@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// manifest lists every file produced by a run. It is shared by single-file
+// and split runs and is only written once the whole run has succeeded.
+type manifest struct {
+	Tool    string          `json:"tool"`
+	Version string          `json:"version"`
+	Created time.Time       `json:"created"`
+	Files   []manifestEntry `json:"files"`
+}
+
+// manifestEntry describes one output file. Path is relative to the manifest's directory.
+// Members of an archive name the archive in Archive and their member name in Path.
+type manifestEntry struct {
+	Archive string `json:"archive,omitempty"`
+	Path    string `json:"path"`
+	Lines   int64  `json:"lines"`
+	Bytes   int64  `json:"bytes"`
+	SHA256  string `json:"sha256"`
+}
+
+// newManifest returns an empty manifest stamped with the current time.
+func newManifest() *manifest {
+	return &manifest{
+		Tool:    "generatelines",
+		Version: version,
+		Created: time.Now().UTC().Truncate(time.Second),
+		Files:   []manifestEntry{},
+	}
+}
+
+// add records an output file.
+func (m *manifest) add(path string, lines, bytes int64, sum string) {
+	m.Files = append(m.Files, manifestEntry{Path: path, Lines: lines, Bytes: bytes, SHA256: sum})
+}
+
+// addMember records a member of an archive file.
+func (m *manifest) addMember(archive, member string, lines, bytes int64, sum string) {
+	m.Files = append(m.Files, manifestEntry{Archive: archive, Path: member, Lines: lines, Bytes: bytes, SHA256: sum})
+}
+
+// write stores m as indented JSON at path, rewriting file paths relative to
+// the manifest's directory. The file is written to a temporary name and
+// renamed into place so readers never see a partial manifest.
+func (m *manifest) write(path string) error {
+	out := *m
+	out.Files = make([]manifestEntry, len(m.Files))
+	for i, e := range m.Files {
+		if e.Archive != "" {
+			e.Archive = manifestPath(path, e.Archive)
+		} else {
+			e.Path = manifestPath(path, e.Path)
+		}
+		out.Files[i] = e
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers see either the old content or the complete new one.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// manifestPath returns file relative to the directory of manifestFile, using
+// forward slashes. It falls back to the absolute path when no relative one exists.
+// Upload URLs are kept as they are.
+func manifestPath(manifestFile, file string) string {
+	if isUploadURL(file) {
+		return file
+	}
+	base, err := filepath.Abs(filepath.Dir(manifestFile))
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	rel, err := filepath.Rel(base, abs)
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// hashingWriter is an output that hashes everything written to it.
+type hashingWriter struct {
+	wc  io.WriteCloser
+	sum hash.Hash
+	w   io.Writer
+}
+
+func newHashingWriter(wc io.WriteCloser) *hashingWriter {
+	sum := sha256.New()
+	return &hashingWriter{wc: wc, sum: sum, w: io.MultiWriter(wc, sum)}
+}
+
+func (h *hashingWriter) Write(p []byte) (int, error) { return h.w.Write(p) }
+func (h *hashingWriter) Close() error                { return h.wc.Close() }
+
+// hexSum returns the SHA-256 of everything written so far.
+func (h *hashingWriter) hexSum() string { return hex.EncodeToString(h.sum.Sum(nil)) }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Lines arguments that take the shape of a reference file: match:<path>
+// counts its lines, match+width:<path> also takes its widest line as the
+// width.
+const (
+	matchPrefix      = "match:"
+	matchWidthPrefix = "match+width:"
+)
+
+// parseMatchArg splits a match:<path> or match+width:<path> lines argument.
+// ok is false for anything else, such as a plain count.
+func parseMatchArg(s string) (path string, withWidth, ok bool) {
+	s = strings.TrimSpace(s)
+	for _, p := range []struct {
+		prefix    string
+		withWidth bool
+	}{{matchPrefix, false}, {matchWidthPrefix, true}} {
+		if len(s) >= len(p.prefix) && strings.EqualFold(s[:len(p.prefix)], p.prefix) {
+			return s[len(p.prefix):], p.withWidth, true
+		}
+	}
+	return "", false, false
+}
+
+// fileShape is what match:<path> learns about a reference file.
+type fileShape struct {
+	lines int
+	width int // characters in the widest line, without its terminator
+}
+
+// measureFile streams the file at path and returns its line count and widest
+// line. A last line without terminator counts, and a CRLF counts as the
+// terminator, so the CR is not part of the width. Widths count UTF-8
+// characters; any other byte counts as one.
+func measureFile(path string) (fileShape, error) {
+	if path == "" {
+		return fileShape{}, errors.New("no path given")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fileShape{}, err
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err == nil && fi.IsDir() {
+		return fileShape{}, fmt.Errorf("%s is a directory", path)
+	}
+
+	var (
+		shape      fileShape
+		width      int
+		cr, inLine bool
+		buf        = make([]byte, 64*1024)
+	)
+	for {
+		n, err := f.Read(buf)
+		for _, b := range buf[:n] {
+			if b == '\n' {
+				if cr {
+					width--
+				}
+				shape.lines++
+				shape.width = max(shape.width, width)
+				width, cr, inLine = 0, false, false
+				continue
+			}
+			if b&0xc0 != 0x80 {
+				width++
+			}
+			cr, inLine = b == '\r', true
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fileShape{}, fmt.Errorf("reading %s: %w", path, err)
+		}
+	}
+	if inLine {
+		shape.lines++
+		shape.width = max(shape.width, width)
+	}
+	return shape, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMeasureFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		content      string
+		lines, width int
+	}{
+		{"", 0, 0},
+		{"\n\n", 2, 0},
+		{"abc", 1, 3},
+		{"abc\n", 1, 3},
+		{"ab\r\nlonger\r\nx", 3, 6},
+		{"wide line\n\nnarrow\n", 3, 9},
+		{"ærø\n", 1, 3},
+		{"cr\rinside\n", 1, 9},
+		{strings.Repeat("x", 70_000) + "\ny\n", 2, 70_000},
+	} {
+		path := filepath.Join(dir, "ref.txt")
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := measureFile(path)
+		if err != nil || got.lines != tt.lines || got.width != tt.width {
+			t.Errorf("%.20q: %+v, %v, want %d lines, width %d", tt.content, got, err, tt.lines, tt.width)
+		}
+	}
+	for _, path := range []string{"", dir, filepath.Join(dir, "missing")} {
+		if _, err := measureFile(path); err == nil {
+			t.Errorf("%q measured", path)
+		}
+	}
+}
+
+func TestParseMatchArg(t *testing.T) {
+	for arg, want := range map[string]struct {
+		path      string
+		withWidth bool
+		ok        bool
+	}{
+		"match:prod.log":         {"prod.log", false, true},
+		" MATCH+Width:a b.log ":  {"a b.log", true, true},
+		"match:":                 {"", false, true},
+		"1000":                   {"", false, false},
+		"matches.txt":            {"", false, false},
+		"match-width:x":          {"", false, false},
+		"match+width:dir/x:y.gz": {"dir/x:y.gz", true, true},
+	} {
+		path, withWidth, ok := parseMatchArg(arg)
+		if path != want.path || withWidth != want.withWidth || ok != want.ok {
+			t.Errorf("parseMatchArg(%q) = %q, %t, %t", arg, path, withWidth, ok)
+		}
+	}
+}
+
+func TestRun_MatchLines(t *testing.T) {
+	dir := t.TempDir()
+	ref := filepath.Join(dir, "prod.log")
+	if err := os.WriteFile(ref, []byte("2024 start\r\n\n2024 the longest line here\r\nend"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.txt")
+	stdout := captureStdout(t)
+	if code := run([]string{"match:" + ref, out, "y", "10", "--explain"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if data, _ := os.ReadFile(out); strings.Count(string(data), "\n") != 4 || len(data) != 4*11 {
+		t.Errorf("match: output %q", data)
+	}
+	if got := stdout(); !strings.Contains(got, "Matched "+ref+": 4 lines, widest 26 columns") || !strings.Contains(got, "lines=4 (match "+ref+")") {
+		t.Errorf("stdout %q", got)
+	}
+
+	if code := run([]string{"match+width:" + ref, out, "y", "digits"}); code != 0 {
+		t.Fatalf("match+width: exit code %d", code)
+	}
+	data, _ := os.ReadFile(out)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 4 || len(lines[0]) != 26 {
+		t.Errorf("match+width: %d lines of %d", len(lines), len(lines[0]))
+	}
+
+	// The width is either matched or given.
+	if code := run([]string{"match+width:" + ref, out, "y", "30"}); code != 1 {
+		t.Errorf("match+width with a width: exit code %d", code)
+	}
+}
+
+func TestRun_MatchMissingReferenceCreatesNothing(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.txt")
+	for _, arg := range []string{"match:" + filepath.Join(dir, "missing.log"), "match:" + dir, "match:"} {
+		if code := run([]string{arg, out, "y"}); code != 1 {
+			t.Errorf("%s: exit code %d", arg, code)
+		}
+		if fileExists(out) {
+			t.Fatalf("%s: %s was created", arg, out)
+		}
+	}
+}
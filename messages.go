@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Bjornsrud/GenerateLines/genlines/humanize"
+)
+
+// catalogs holds the user-facing messages of the interactive prompts, the
+// overwrite question and the summaries, per language and keyed by message.
+// English is complete; another language may leave messages out, which then
+// fall back to English. Error messages stay English. Answers stay y/n/A/N in
+// every language, so scripts and the overwrite argument work unchanged.
+var catalogs = map[string]map[string]string{
+	"en": {
+		"prompt.lines":    "Enter number of lines: ",
+		"prompt.filename": "Enter filename: ",
+
+		"overwrite.exists":      "%s already exists.",
+		"overwrite.existsInfo":  "%s already exists (%s, modified %s).",
+		"overwrite.existsNew":   "%s already exists (%s, modified %s); the new output is %s.",
+		"overwrite.typeName":    "%s is over %d times the size of the new output. Type its name to overwrite it: ",
+		"overwrite.nameWrong":   "The name does not match.",
+		"overwrite.partsExist":  "%s part files already exist (first: %s).",
+		"overwrite.question":    " Overwrite? ",
+		"overwrite.retry":       "Please answer y or n.",
+		"overwrite.retryAll":    "Please answer y, n, A (all remaining) or N (none of the remaining).",
+		"overwrite.overwriting": "%s Overwriting...",
+		"overwrite.exiting":     "%s Not overwriting. Exiting.",
+		"overwrite.declined":    "Not overwriting. Exiting.",
+		"overwrite.skipped":     "%s Not overwriting it.",
+
+		"generate.lines":      "Generating %s lines (width=%d, mode=%s)%s -> %s\n",
+		"generate.defaults":   " [using default width and mode]",
+		"generate.defWidth":   " [using default width]",
+		"generate.defMode":    " [using default mode]",
+		"generate.exact":      "Generating exactly %s bytes: %s lines + %s trailing bytes (width=%d, mode=%s) -> %s\n",
+		"generate.ramp":       "Generating %s lines (widths ramping %d..%d by %d, mode=%s) -> %s\n",
+		"generate.binrec":     "Generating %s binary records of %s bytes (mode=%s) -> %s\n",
+		"generate.interleave": "Generating %s lines (interleaved %s) -> %s\n",
+		"generate.files":      "Generating %s lines (width=%d, mode=%s) -> %s files\n",
+		"generate.members":    "Generating %s lines into %s %s members of up to %s lines -> %s (%s)\n",
+		"generate.parts":      "Generating %s lines into %s files of up to %s lines -> %s\n",
+		"generate.empty":      "Generated 0 lines (empty file) -> %s\n",
+		"generate.noParts":    "Generated 0 lines (no part files created)\n",
+
+		"done":            "Done!",
+		"done.exact":      "Done! Wrote %s complete lines plus %s trailing bytes (%s bytes).",
+		"done.exactOne":   "Done! Wrote 1 complete line plus %s trailing bytes (%s bytes).",
+		"done.records":    "Done! Wrote %s records in %s physical lines.",
+		"done.binrec":     "Done! Wrote %s records of %s bytes (%s bytes).",
+		"done.gzip":       "Done! Wrote %s gzip members of up to %s lines (%s bytes compressed).",
+		"done.align":      "Done! Inserted %s padding lines to align lines to %s-byte boundaries (%s bytes).",
+		"done.members":    "Done! Wrote %s members to %s",
+		"done.parts":      "Done! Wrote %s .. %s",
+		"done.upload":     "Done! Uploaded %s lines (%s bytes).",
+		"done.verified":   "Done! All %s lines verified.",
+		"done.regen":      "Done! Checksum matches the recorded run.",
+		"maxBytes.cut":    "Stopped by --max-bytes: wrote %s of %s lines, %s bytes (ceiling %s bytes).",
+		"maxBytes.exact":  "Wrote all %s lines, exactly reaching --max-bytes: %s bytes.",
+		"maxBytes.within": "Wrote all %s lines before --max-bytes: %s bytes (ceiling %s bytes).",
+		"output.closed":   "Output closed after %s lines.",
+
+		"pattern.summary":  "Line pattern %s: %s content lines, %s blank lines.",
+		"trailing.summary": "Added trailing whitespace to %s of %s lines (%s bytes).",
+		"inject.summary":   "Injected %s invisible code points: %s.",
+	},
+	"nb": {
+		"prompt.lines":    "Skriv inn antall linjer: ",
+		"prompt.filename": "Skriv inn filnavn: ",
+
+		"overwrite.exists":      "%s finnes allerede.",
+		"overwrite.existsInfo":  "%s finnes allerede (%s, endret %s).",
+		"overwrite.existsNew":   "%s finnes allerede (%s, endret %s); den nye utdataen er %s.",
+		"overwrite.typeName":    "%s er over %d ganger så stor som den nye utdataen. Skriv navnet for å overskrive den: ",
+		"overwrite.nameWrong":   "Navnet stemmer ikke.",
+		"overwrite.partsExist":  "%s delfiler finnes allerede (første: %s).",
+		"overwrite.question":    " Overskrive? ",
+		"overwrite.retry":       "Svar y (ja) eller n (nei).",
+		"overwrite.retryAll":    "Svar y (ja), n (nei), A (alle resterende) eller N (ingen av de resterende).",
+		"overwrite.overwriting": "%s Overskriver...",
+		"overwrite.exiting":     "%s Overskriver ikke. Avslutter.",
+		"overwrite.declined":    "Overskriver ikke. Avslutter.",
+		"overwrite.skipped":     "%s Overskriver den ikke.",
+
+		"generate.lines":      "Genererer %s linjer (bredde=%d, modus=%s)%s -> %s\n",
+		"generate.defaults":   " [bruker standard bredde og modus]",
+		"generate.defWidth":   " [bruker standard bredde]",
+		"generate.defMode":    " [bruker standard modus]",
+		"generate.exact":      "Genererer nøyaktig %s byte: %s linjer + %s byte til slutt (bredde=%d, modus=%s) -> %s\n",
+		"generate.ramp":       "Genererer %s linjer (bredder fra %d til %d i steg på %d, modus=%s) -> %s\n",
+		"generate.binrec":     "Genererer %s binære poster på %s byte (modus=%s) -> %s\n",
+		"generate.interleave": "Genererer %s linjer (flettet %s) -> %s\n",
+		"generate.files":      "Genererer %s linjer (bredde=%d, modus=%s) -> %s filer\n",
+		"generate.members":    "Genererer %s linjer i %s %s-medlemmer på opptil %s linjer -> %s (%s)\n",
+		"generate.parts":      "Genererer %s linjer i %s filer på opptil %s linjer -> %s\n",
+		"generate.empty":      "Genererte 0 linjer (tom fil) -> %s\n",
+		"generate.noParts":    "Genererte 0 linjer (ingen delfiler opprettet)\n",
+
+		"done":            "Ferdig!",
+		"done.exact":      "Ferdig! Skrev %s hele linjer pluss %s byte til slutt (%s byte).",
+		"done.exactOne":   "Ferdig! Skrev 1 hel linje pluss %s byte til slutt (%s byte).",
+		"done.records":    "Ferdig! Skrev %s poster på %s fysiske linjer.",
+		"done.binrec":     "Ferdig! Skrev %s poster på %s byte (%s byte).",
+		"done.gzip":       "Ferdig! Skrev %s gzip-medlemmer på opptil %s linjer (%s byte komprimert).",
+		"done.align":      "Ferdig! La inn %s fyllinjer for å holde linjene innenfor %s-byte-blokker (%s byte).",
+		"done.members":    "Ferdig! Skrev %s medlemmer til %s",
+		"done.parts":      "Ferdig! Skrev %s .. %s",
+		"done.upload":     "Ferdig! Lastet opp %s linjer (%s byte).",
+		"done.verified":   "Ferdig! Alle %s linjene er verifisert.",
+		"done.regen":      "Ferdig! Sjekksummen stemmer med den registrerte kjøringen.",
+		"maxBytes.cut":    "Stoppet av --max-bytes: skrev %s av %s linjer, %s byte (tak %s byte).",
+		"maxBytes.exact":  "Skrev alle %s linjer og nådde akkurat --max-bytes: %s byte.",
+		"maxBytes.within": "Skrev alle %s linjer innenfor --max-bytes: %s byte (tak %s byte).",
+		"output.closed":   "Utdata lukket etter %s linjer.",
+
+		"pattern.summary":  "Linjemønster %s: %s linjer med innhold, %s tomme linjer.",
+		"trailing.summary": "La til blanktegn på slutten av %s av %s linjer (%s byte).",
+		"inject.summary":   "Satte inn %s usynlige kodepunkter: %s.",
+	},
+}
+
+// languageAliases maps language codes to the catalog that serves them.
+var languageAliases = map[string]string{
+	"no": "nb", // generic Norwegian
+}
+
+// messages is the catalog of the current locale (see configureLocale).
+var messages = catalogs["en"]
+
+// configureLocale selects the message catalog from the environment.
+func configureLocale() {
+	messages = catalogs[localeLanguage(os.Getenv)]
+}
+
+// localeLanguage returns the catalog language for the locale set in the
+// environment: LC_ALL, then LC_MESSAGES, then LANG, as POSIX orders them.
+// A locale without a catalog (or C/POSIX) gives English.
+func localeLanguage(getenv func(string) string) string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale := getenv(name)
+		if locale == "" {
+			continue
+		}
+		// language[_territory][.codeset][@modifier], e.g. nb_NO.UTF-8
+		lang := strings.ToLower(locale)
+		if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+			lang = lang[:i]
+		}
+		if alias, ok := languageAliases[lang]; ok {
+			lang = alias
+		}
+		if _, ok := catalogs[lang]; ok {
+			return lang
+		}
+		return "en"
+	}
+	return "en"
+}
+
+// text returns the message key of the current locale, formatted with args
+// if any are given. Integer args are counts: a %s in the catalog formats them
+// with humanize.Count, while a %d, used for settings echoed as given (such
+// as width=%d), prints them plainly.
+func text(key string, args ...any) string {
+	s, ok := messages[key]
+	if !ok {
+		s = catalogs["en"][key]
+	}
+	if len(args) == 0 {
+		return s
+	}
+	for i, a := range args {
+		switch n := a.(type) {
+		case int:
+			args[i] = count(n)
+		case int64:
+			args[i] = count(n)
+		case int32:
+			args[i] = count(n)
+		case uint32:
+			args[i] = count(n)
+		case uint64:
+			args[i] = count(n)
+		}
+	}
+	return fmt.Sprintf(s, args...)
+}
+
+// count is an integer argument of text.
+type count int64
+
+func (c count) Format(f fmt.State, verb rune) {
+	if verb == 's' || verb == 'v' {
+		io.WriteString(f, humanize.Count(int64(c)))
+		return
+	}
+	fmt.Fprintf(f, fmt.FormatString(f, verb), int64(c))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the tests in the C locale, so the English messages they
+// check do not depend on the developer's environment.
+func TestMain(m *testing.M) {
+	os.Setenv("LC_ALL", "C")
+	os.Exit(m.Run())
+}
+
+func TestLocaleLanguage(t *testing.T) {
+	for _, tt := range []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{}, "en"},
+		{map[string]string{"LANG": "nb_NO.UTF-8"}, "nb"},
+		{map[string]string{"LANG": "no_NO"}, "nb"},
+		{map[string]string{"LANG": "NB"}, "nb"},
+		{map[string]string{"LANG": "fr_FR.UTF-8"}, "en"},
+		{map[string]string{"LANG": "C"}, "en"},
+		{map[string]string{"LANG": "nb_NO.UTF-8", "LC_MESSAGES": "en_US.UTF-8"}, "en"},
+		{map[string]string{"LANG": "en_US.UTF-8", "LC_ALL": "nb_NO@euro"}, "nb"},
+	} {
+		if got := localeLanguage(func(k string) string { return tt.env[k] }); got != tt.want {
+			t.Errorf("%v: %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
+
+var formatVerb = regexp.MustCompile(`%[-+ #0-9.]*[a-zA-Z%]`)
+
+func TestCatalogs_MatchEnglish(t *testing.T) {
+	en := catalogs["en"]
+	for lang, catalog := range catalogs {
+		for key, msg := range catalog {
+			want, ok := en[key]
+			if !ok {
+				t.Errorf("%s: %s has no English message", lang, key)
+				continue
+			}
+			got, wantVerbs := formatVerb.FindAllString(msg, -1), formatVerb.FindAllString(want, -1)
+			if strings.Join(got, " ") != strings.Join(wantVerbs, " ") {
+				t.Errorf("%s: %s has verbs %v, English %v", lang, key, got, wantVerbs)
+			}
+			if strings.HasSuffix(want, "\n") != strings.HasSuffix(msg, "\n") {
+				t.Errorf("%s: %s does not end like the English message", lang, key)
+			}
+		}
+	}
+}
+
+func TestText_FormatsCountsWithHumanize(t *testing.T) {
+	if got, want := text("done.exact", 1_000_000, 5, int64(80_000_005)), "Done! Wrote 1_000_000 complete lines plus 5 trailing bytes (80_000_005 bytes)."; got != want {
+		t.Errorf("done.exact = %q, want %q", got, want)
+	}
+	// Settings echoed as given stay plain.
+	if got, want := text("generate.lines", 12345, 1200, "digits", "", "out.txt"), "Generating 12_345 lines (width=1200, mode=digits) -> out.txt\n"; got != want {
+		t.Errorf("generate.lines = %q, want %q", got, want)
+	}
+}
+
+func TestRun_NorwegianPrompts(t *testing.T) {
+	t.Setenv("LC_ALL", "nb_NO.UTF-8")
+	t.Cleanup(func() { messages = catalogs["en"] })
+	path := filepath.Join(t.TempDir(), "ut.txt")
+
+	withStdin(t, "3\n"+path+"\n", true)
+	output := captureStdout(t)
+	if code := run(nil); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	text := output()
+	for _, want := range []string{"Skriv inn antall linjer: ", "Skriv inn filnavn: ", "Genererer 3 linjer", "Ferdig!"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output lacks %q:\n%s", want, text)
+		}
+	}
+
+	withStdin(t, "maybe\ny\n", true)
+	output = captureStdout(t)
+	if code := run([]string{"2", path}); code != 0 {
+		t.Fatalf("overwrite run exited with %d", code)
+	}
+	text = output()
+	for _, want := range []string{"ut.txt finnes allerede (243 B, endret ", "); den nye utdataen er 162 B. Overskrive? [y/n]: ", "Svar y (ja) eller n (nei)."} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output lacks %q:\n%s", want, text)
+		}
+	}
+	if data, _ := os.ReadFile(path); strings.Count(string(data), "\n") != 2 {
+		t.Errorf("file not overwritten: %q", data)
+	}
+
+	// An unknown locale falls back to English without a word.
+	t.Setenv("LC_ALL", "xx_XX")
+	output = captureStdout(t)
+	errOutput := captureStderr(t)
+	if code := run([]string{"1", path, "y"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if text := errOutput(); !strings.Contains(text, "); the new output is 81 B. Overwriting...") {
+		t.Errorf("no English fallback:\n%s", text)
+	}
+	if text := output(); !strings.Contains(text, "Done!") {
+		t.Errorf("no English fallback:\n%s", text)
+	}
+}
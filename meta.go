@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// metaSuffix is appended to the output filename to name its sidecar.
+const metaSuffix = ".meta"
+
+// runMeta is the JSON sidecar recording everything needed to reproduce an output file.
+type runMeta struct {
+	Tool           string    `json:"tool"`
+	Version        string    `json:"version"`
+	Created        time.Time `json:"created"`
+	File           string    `json:"file"` // base name, relative to the sidecar
+	Lines          int       `json:"lines"`
+	Width          int       `json:"width"`
+	Mode           string    `json:"mode"`
+	ModeArg        string    `json:"modeArg,omitempty"`
+	CommentEvery   int       `json:"commentEvery,omitempty"`
+	CommentText    string    `json:"commentText,omitempty"`
+	LineChecksum   bool      `json:"lineChecksum,omitempty"`
+	ExactBytes     int64     `json:"exactBytes,omitempty"`
+	MaxBytes       int64     `json:"maxBytes,omitempty"`
+	Align          int64     `json:"align,omitempty"`
+	AlignFill      string    `json:"alignFill,omitempty"` // empty = space
+	EscapeNonASCII bool      `json:"escapeNonASCII,omitempty"`
+	Continuation   string    `json:"continuation,omitempty"`
+	FirstLine      string    `json:"firstLine,omitempty"` // as given, {config} unexpanded
+	LastLine       string    `json:"lastLine,omitempty"`
+	Rot            int       `json:"rot,omitempty"`
+	TrailingWS     string    `json:"trailingWS,omitempty"`    // --trailing-ws spec
+	LinePattern    string    `json:"linePattern,omitempty"`   // --line-pattern
+	InjectUnicode  string    `json:"injectUnicode,omitempty"` // --inject-unicode spec
+	StartOffset    int64     `json:"startOffset,omitempty"`
+	SafeStart      bool      `json:"safeStart,omitempty"`
+	SafeStartShift int64     `json:"safeStartShift,omitempty"` // as reported; regen shifts again
+	ByteRange      string    `json:"byteRange,omitempty"`      // --byte-range, in hex
+	Sort           string    `json:"sort,omitempty"`           // asc or desc
+	SortMaxMemory  int64     `json:"sortMaxMemory,omitempty"`  // --sort-max-memory, if given
+	Unsafe         bool      `json:"unsafe,omitempty"`
+	LineEnding     string    `json:"lineEnding,omitempty"` // --line-ending name; empty = lf
+	Ramp           string    `json:"ramp,omitempty"`       // --ramp spec
+	Seed           *uint64   `json:"seed,omitempty"`       // global --seed
+	Stamp          bool      `json:"stamp,omitempty"`      // --stamp: line 1 is stampLine
+	Bytes          int64     `json:"bytes"`
+	SHA256         string    `json:"sha256"`
+
+	// Sources records where each parameter of the run came from, as
+	// --explain shows it (e.g. "width": "positional arg 4"). regen ignores it.
+	Sources map[string]string `json:"sources,omitempty"`
+}
+
+// newSeed returns a fresh random seed for runs where the user did not pick one.
+func newSeed() uint64 {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return uint64(time.Now().UnixNano())
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// newRunMeta describes a finished run of opts into filename.
+func newRunMeta(filename string, opts genlines.Options, bytes int64, sum string) runMeta {
+	m := runMeta{
+		Tool:           "generatelines",
+		Version:        version,
+		Created:        time.Now().UTC().Truncate(time.Second),
+		File:           filepath.Base(filename),
+		Lines:          opts.Lines,
+		Width:          opts.Width,
+		Mode:           opts.Mode,
+		ModeArg:        opts.ModeArg,
+		CommentEvery:   opts.CommentEvery,
+		LineChecksum:   opts.LineChecksum,
+		ExactBytes:     opts.ExactBytes,
+		MaxBytes:       opts.MaxBytes,
+		Align:          opts.Align,
+		EscapeNonASCII: opts.EscapeNonASCII,
+		Continuation:   opts.Continuation,
+		FirstLine:      opts.FirstLine,
+		LastLine:       opts.LastLine,
+		Rot:            opts.Rot,
+		StartOffset:    opts.StartOffset,
+		SafeStart:      opts.SafeStart,
+		Sort:           string(opts.Sort),
+		SortMaxMemory:  opts.SortMaxMemory,
+		Unsafe:         opts.Unsafe,
+		Bytes:          bytes,
+		SHA256:         sum,
+	}
+	if opts.CommentEvery > 0 {
+		m.CommentText = opts.CommentText
+	}
+	if opts.Align > 0 && opts.AlignFill != 0 && opts.AlignFill != genlines.DefaultAlignFill {
+		m.AlignFill = string(opts.AlignFill)
+	}
+	if opts.Ramp.Enabled() {
+		m.Ramp = opts.Ramp.String()
+	}
+	if opts.TrailingWS.Enabled() {
+		m.TrailingWS = opts.TrailingWS.String()
+	}
+	if opts.LinePattern.Enabled() {
+		m.LinePattern = string(opts.LinePattern)
+	}
+	if opts.InjectUnicode.Enabled() {
+		m.InjectUnicode = opts.InjectUnicode.String()
+	}
+	if opts.ByteRange.Enabled() {
+		m.ByteRange = opts.ByteRange.String()
+	}
+	if opts.SafeStart {
+		m.SafeStartShift, _, _ = genlines.SafeStartShift(opts)
+	}
+	if opts.HasSeed {
+		seed := opts.Seed
+		m.Seed = &seed
+	}
+	if opts.EOL != nil && string(opts.EOL) != "\n" {
+		m.LineEnding = eolName(opts.EOL)
+	}
+	m.Stamp = opts.HeaderLine != ""
+	return m
+}
+
+// options returns the generation options recorded in m (readMeta or
+// parseStamp has already checked the ramp, trailing whitespace, line pattern,
+// injection and byte range specs).
+func (m runMeta) options() genlines.Options {
+	ramp, _ := genlines.ParseRamp(m.Ramp)
+	var ws genlines.TrailingWS
+	if m.TrailingWS != "" {
+		ws, _ = genlines.ParseTrailingWS(m.TrailingWS)
+	}
+	var inject genlines.InjectUnicode
+	if m.InjectUnicode != "" {
+		inject, _ = genlines.ParseInjectUnicode(m.InjectUnicode)
+	}
+	var byteRange genlines.ByteRange
+	if m.ByteRange != "" {
+		byteRange, _ = genlines.ParseByteRange(m.ByteRange)
+	}
+	opts := genlines.Options{
+		Lines:          m.Lines,
+		Width:          m.Width,
+		Mode:           m.Mode,
+		ModeArg:        m.ModeArg,
+		CommentEvery:   m.CommentEvery,
+		CommentText:    m.CommentText,
+		LineChecksum:   m.LineChecksum,
+		ExactBytes:     m.ExactBytes,
+		MaxBytes:       m.MaxBytes,
+		Align:          m.Align,
+		EscapeNonASCII: m.EscapeNonASCII,
+		Continuation:   m.Continuation,
+		FirstLine:      m.FirstLine,
+		LastLine:       m.LastLine,
+		Rot:            m.Rot,
+		StartOffset:    m.StartOffset,
+		SafeStart:      m.SafeStart,
+		ByteRange:      byteRange,
+		Sort:           genlines.SortOrder(m.Sort),
+		SortMaxMemory:  m.SortMaxMemory,
+		Unsafe:         m.Unsafe,
+		EOL:            lineEndings[m.LineEnding],
+		Ramp:           ramp,
+		TrailingWS:     ws,
+		LinePattern:    genlines.LinePattern(m.LinePattern),
+		InjectUnicode:  inject,
+	}
+	if m.AlignFill != "" {
+		opts.AlignFill = m.AlignFill[0]
+	}
+	if m.Seed != nil {
+		opts.Seed, opts.HasSeed = *m.Seed, true
+	}
+	if m.Stamp {
+		opts.HeaderLine = stampLine(m)
+	}
+	return opts
+}
+
+// writeMeta writes m as indented JSON to path.
+func writeMeta(path string, m runMeta) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// readMeta loads a sidecar written by writeMeta.
+func readMeta(path string) (runMeta, error) {
+	var m runMeta
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("%s is not a valid metadata file: %v", path, err)
+	}
+	if m.Tool != "generatelines" || m.Mode == "" {
+		return m, fmt.Errorf("%s is not a generatelines metadata file", path)
+	}
+	if err := m.check(); err != nil {
+		return m, fmt.Errorf("%s: %v", path, err)
+	}
+	return m, nil
+}
+
+// check reports a setting of m that options cannot turn into genlines
+// options: an unknown line ending or a spec that does not parse.
+func (m runMeta) check() error {
+	if _, ok := lineEndings[m.LineEnding]; m.LineEnding != "" && !ok {
+		return fmt.Errorf("unknown lineEnding %q", m.LineEnding)
+	}
+	if m.Ramp != "" {
+		if _, err := genlines.ParseRamp(m.Ramp); err != nil {
+			return err
+		}
+	}
+	if m.TrailingWS != "" {
+		if _, err := genlines.ParseTrailingWS(m.TrailingWS); err != nil {
+			return err
+		}
+	}
+	if m.LinePattern != "" {
+		if _, err := genlines.ParseLinePattern(m.LinePattern); err != nil {
+			return err
+		}
+	}
+	if m.InjectUnicode != "" {
+		if _, err := genlines.ParseInjectUnicode(m.InjectUnicode); err != nil {
+			return err
+		}
+	}
+	if m.ByteRange != "" {
+		if _, err := genlines.ParseByteRange(m.ByteRange); err != nil {
+			return err
+		}
+	}
+	if m.Sort != "" {
+		if _, err := genlines.ParseSortOrder(m.Sort); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// regenerate reproduces the run described by m into out and returns its size and SHA-256.
+func regenerate(m runMeta, out string) (int64, string, error) {
+	f, err := os.OpenFile(out, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	var st genlines.Stats
+	opts := m.options()
+	opts.Stats, opts.Checksums = &st, []string{"sha256"}
+	if _, _, err := genlines.GenerateTo(context.Background(), f, opts); err != nil {
+		return st.Bytes, "", err
+	}
+	return st.Bytes, st.Checksums["sha256"], f.Close()
+}
+
+// runRegenCmd handles "regen <file.meta> [output]" and returns the exit code.
+func runRegenCmd(args []string) int {
+	if len(args) == 0 {
+		cli.Error("regen requires a metadata file")
+		cli.Hint(helpHint())
+		return 1
+	}
+
+	m, err := readMeta(args[0])
+	if err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+
+	out := filepath.Join(filepath.Dir(args[0]), m.File)
+	if len(args) >= 2 {
+		out = args[1]
+	}
+	if fileExists(out) {
+		cli.Error("%s already exists; remove it or give another output path", out)
+		return 1
+	}
+
+	cli.Info("Regenerating %d lines (width=%d, mode=%s) -> %s", m.Lines, m.Width, m.Mode, out)
+	n, sum, err := regenerate(m, out)
+	if err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+	if m.SHA256 != "" && (sum != m.SHA256 || n != m.Bytes) {
+		cli.Error("regenerated file differs from the recorded one (sha256 %s, expected %s)", sum, m.SHA256)
+		return 1
+	}
+
+	cli.Summary("%s", text("done.regen"))
+	return 0
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+func fileSHA256(t *testing.T, path string) [32]byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return sha256.Sum256(data)
+}
+
+func TestRun_RandomWithoutSeedWritesMeta_RegenReproduces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.txt")
+	if code := run([]string{"50", path, "y", "40", "random"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	original := fileSHA256(t, path)
+
+	m, err := readMeta(path + metaSuffix)
+	if err != nil {
+		t.Fatalf("readMeta: %v", err)
+	}
+	if m.Mode != "random" || m.ModeArg == "" || m.Lines != 50 || m.Width != 40 || m.Version != version {
+		t.Fatalf("unexpected metadata: %+v", m)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if code := runRegenCmd([]string{path + metaSuffix}); code != 0 {
+		t.Fatalf("regen exited with %d", code)
+	}
+	if fileSHA256(t, path) != original {
+		t.Fatalf("regenerated file differs from the original")
+	}
+}
+
+func TestRun_MetaSuppressedAndOptIn(t *testing.T) {
+	dir := t.TempDir()
+
+	noMeta := filepath.Join(dir, "nometa.txt")
+	if code := run([]string{"5", noMeta, "y", "10", "random", "--no-meta"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if fileExists(noMeta + metaSuffix) {
+		t.Fatalf("expected --no-meta to suppress the sidecar")
+	}
+
+	plain := filepath.Join(dir, "plain.txt")
+	if code := run([]string{"5", plain, "y", "10", "digits"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if fileExists(plain + metaSuffix) {
+		t.Fatalf("deterministic runs should not write a sidecar by default")
+	}
+
+	optIn := filepath.Join(dir, "optin.txt")
+	if code := run([]string{"5", optIn, "y", "10", "digits", "--meta"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if !fileExists(optIn + metaSuffix) {
+		t.Fatalf("expected --meta to write a sidecar")
+	}
+}
+
+func TestRun_NoiseWritesNoMetaAndSkipsVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "noise.txt")
+	errOut := captureStderr(t)
+	output := captureStdout(t)
+	if code := run([]string{"50", path, "y", "64", "noise", "--meta", "--verify-after"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if fileExists(path + metaSuffix) {
+		t.Error("noise run wrote a sidecar")
+	}
+	if data, err := os.ReadFile(path); err != nil || len(data) != 50*65 {
+		t.Errorf("wrote %d bytes (%v), want %d", len(data), err, 50*65)
+	}
+	if msg := errOut(); !strings.Contains(msg, "--meta ignored") || !strings.Contains(msg, "different every run") {
+		t.Errorf("stderr lacks the warnings:\n%s", msg)
+	}
+	if out := output(); !strings.Contains(out, "--verify-after is skipped") || strings.Contains(out, "Verified") {
+		t.Errorf("verify not skipped:\n%s", out)
+	}
+}
+
+func TestRegen_RefusesExistingOutputAndBadMeta(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.txt")
+	if code := run([]string{"5", path, "y", "10", "random", "7"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if fileExists(path + metaSuffix) {
+		t.Fatalf("seeded random runs are reproducible and should not need a sidecar")
+	}
+
+	meta := newRunMeta(path, genlines.Options{Lines: 5, Width: 10, Mode: "random", ModeArg: "7"}, 0, "")
+	if err := writeMeta(path+metaSuffix, meta); err != nil {
+		t.Fatalf("writeMeta: %v", err)
+	}
+	if code := runRegenCmd([]string{path + metaSuffix}); code == 0 {
+		t.Fatalf("expected regen to refuse overwriting an existing file")
+	}
+
+	bad := filepath.Join(dir, "bad.meta")
+	os.WriteFile(bad, []byte("{not json"), 0644)
+	if code := runRegenCmd([]string{bad}); code == 0 {
+		t.Fatalf("expected regen to reject a corrupt metadata file")
+	}
+}
+
+func TestRun_HashfillMatchesLineFor(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	if code := run([]string{"300", a, "y", "64", "hashfill", "alpha"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if code := run([]string{"300", b, "y", "64", "hashfill", "beta"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+
+	dataA, _ := os.ReadFile(a)
+	dataB, _ := os.ReadFile(b)
+	linesA := strings.Split(strings.TrimSuffix(string(dataA), "\n"), "\n")
+	linesB := strings.Split(strings.TrimSuffix(string(dataB), "\n"), "\n")
+	if len(linesA) != 300 {
+		t.Fatalf("got %d lines, want 300", len(linesA))
+	}
+	for _, k := range []int{0, 1, 99, 150, 299} {
+		if want := genlines.LineFor("alpha", k, 64); linesA[k] != want {
+			t.Errorf("line %d = %q, want LineFor = %q", k, linesA[k], want)
+		}
+		if linesA[k] == linesB[k] {
+			t.Errorf("line %d is the same for different seeds", k)
+		}
+	}
+	if fileExists(a + metaSuffix) {
+		t.Error("a seeded hashfill run should not write a sidecar")
+	}
+}
+
+func TestRun_MetaRecordsLineEnding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crlf.txt")
+	if code := run([]string{"5", path, "y", "10", "digits", "--meta", "--line-ending", "crlf"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	original := fileSHA256(t, path)
+
+	m, err := readMeta(path + metaSuffix)
+	if err != nil {
+		t.Fatalf("readMeta: %v", err)
+	}
+	if m.LineEnding != "crlf" {
+		t.Fatalf("lineEnding = %q, want crlf", m.LineEnding)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if code := runRegenCmd([]string{path + metaSuffix}); code != 0 {
+		t.Fatalf("regen exited with %d", code)
+	}
+	if fileSHA256(t, path) != original {
+		t.Fatalf("regenerated file differs from the original")
+	}
+}
+
+func TestRun_GlobalSeedReproducesAndIsRecorded(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	for _, path := range []string{a, b} {
+		if code := run([]string{"40", path, "y", "30", "random", "--seed", "12345", "--meta"}); code != 0 {
+			t.Fatalf("run exited with %d", code)
+		}
+	}
+	if fileSHA256(t, a) != fileSHA256(t, b) {
+		t.Fatalf("the same --seed gave different files")
+	}
+
+	m, err := readMeta(a + metaSuffix)
+	if err != nil {
+		t.Fatalf("readMeta: %v", err)
+	}
+	if m.Seed == nil || *m.Seed != 12345 || m.ModeArg != "" {
+		t.Fatalf("seed not recorded as the global seed: %+v", m)
+	}
+	original := fileSHA256(t, a)
+	if err := os.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	if code := runRegenCmd([]string{a + metaSuffix}); code != 0 {
+		t.Fatalf("regen exited with %d", code)
+	}
+	if fileSHA256(t, a) != original {
+		t.Fatalf("regenerated file differs from the original")
+	}
+}
+
+func TestRun_FirstAndLastLineRecordedAndRegenerated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sentinel.txt")
+	if code := run([]string{"6", path, "y", "24", "random", "8", "--meta", "--first-line", "BEGIN-{config}", "--last-line", "END"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	m, err := readMeta(path + metaSuffix)
+	if err != nil {
+		t.Fatalf("readMeta: %v", err)
+	}
+	if want := "BEGIN-" + genlines.ConfigChecksum(m.options()) + "          "; lines[0] != want || lines[5] != "END"+strings.Repeat(" ", 21) {
+		t.Errorf("lines %q, %q; want %q", lines[0], lines[5], want)
+	}
+	if m.FirstLine != "BEGIN-{config}" || m.LastLine != "END" {
+		t.Errorf("recorded %q, %q", m.FirstLine, m.LastLine)
+	}
+
+	original := fileSHA256(t, path)
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if code := runRegenCmd([]string{path + metaSuffix}); code != 0 {
+		t.Fatalf("regen exited with %d", code)
+	}
+	if fileSHA256(t, path) != original {
+		t.Fatalf("regenerated file differs from the original")
+	}
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// outTarget is one file of a multi-target run.
+type outTarget struct {
+	path    string
+	f       *os.File
+	w       *bufio.Writer
+	wc      *writeCounter // counts the writes that reach f
+	bytes   int64
+	err     error // why the target failed, if it did
+	skipped bool  // existed and was not to be overwritten
+	created bool  // did not exist before the run
+}
+
+// targetError reports a failure of one target. It deliberately does not
+// unwrap: the run's write retry would resend the whole chunk to every target,
+// including those that already took it.
+type targetError struct {
+	path string
+	err  error
+}
+
+func (e *targetError) Error() string { return fmt.Sprintf("%s: %v", e.path, e.err) }
+
+// fanout writes the stream to every live target through its own buffer.
+// Unlike io.MultiWriter it tracks failures per target: a failed target is
+// dropped when keepGoing is set, and fails the whole write otherwise. With
+// flush set (--flush-every), every write is pushed on to the files, as the
+// run only writes when it flushes.
+type fanout struct {
+	targets   []*outTarget
+	keepGoing bool
+	flush     bool
+}
+
+func (o *fanout) Write(p []byte) (int, error) {
+	live := 0
+	for _, t := range o.targets {
+		if t.err != nil || t.skipped {
+			continue
+		}
+		n, err := t.w.Write(p)
+		t.bytes += int64(n)
+		if err == nil && o.flush {
+			err = t.w.Flush()
+		}
+		if err != nil {
+			t.err = err
+			if !o.keepGoing {
+				return 0, &targetError{t.path, err}
+			}
+			cli.Error("%s: %v (continuing with the other targets)", t.path, err)
+			continue
+		}
+		live++
+	}
+	if live == 0 {
+		return 0, errors.New("every target failed")
+	}
+	return len(p), nil
+}
+
+// runMulti writes one generated stream to every file in paths (the positional
+// filename first, then each --out) and returns the exit code. Overwriting is
+// decided per target; a failing target aborts the run unless --keep-going is
+// given, in which case the others are completed and the exit code is 1.
+func runMulti(prompt *overwritePrompt, hist *runHistory, paths []string, opts genlines.Options, flags cliFlags, writeMetaFile bool) int {
+	seen := map[string]bool{}
+	targets := make([]*outTarget, 0, len(paths))
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			abs = filepath.Clean(p)
+		}
+		if seen[abs] {
+			cli.Error("%s is given more than once", p)
+			return 1
+		}
+		seen[abs] = true
+		if !guardTargets(flags, p) {
+			return 1
+		}
+		targets = append(targets, &outTarget{path: p})
+	}
+
+	var conflicts []*outTarget
+	for _, t := range targets {
+		if fileExists(t.path) {
+			conflicts = append(conflicts, t)
+		} else {
+			t.created = true
+		}
+	}
+	_, size, planErr := genlines.PlanAlign(opts)
+	if planErr != nil {
+		size = -1
+	}
+	for i, t := range conflicts {
+		asked, what := prompt.asks(), describeExisting(t.path, size)
+		overwrite, err := prompt.allow(what, i < len(conflicts)-1)
+		if err == nil && asked && overwrite && !flags.noNameCheck {
+			overwrite, err = prompt.confirmName(t.path, size)
+		}
+		if err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+		if overwrite {
+			cli.Warn(text("overwrite.overwriting"), what)
+		} else {
+			cli.Warn(text("overwrite.skipped"), what)
+			t.skipped = true
+		}
+	}
+
+	// Every target is opened before any is truncated, so an abort leaves
+	// existing files as they were.
+	live := 0
+	for _, t := range targets {
+		if t.skipped {
+			continue
+		}
+		t.f, t.err = os.OpenFile(t.path, os.O_CREATE|os.O_WRONLY, 0644)
+		if t.err != nil {
+			t.f = nil
+			cli.Error("opening %s: %v", t.path, t.err)
+			if !flags.keepGoing {
+				abandonTargets(targets)
+				cli.Hint("Not generating (use --keep-going to write the other targets anyway).")
+				return 1
+			}
+			continue
+		}
+		defer t.f.Close()
+		live++
+	}
+	for _, t := range targets {
+		if t.f == nil {
+			continue
+		}
+		if flags.wipeOld {
+			if err := wipe(t.f); err != nil {
+				t.err = err
+				cli.Error("wiping %s: %v", t.path, err)
+				live--
+				continue
+			}
+		}
+		if err := t.f.Truncate(0); err != nil && !isDevice(t.f) {
+			t.err = err
+			cli.Error("truncating %s: %v", t.path, err)
+			live--
+			continue
+		}
+		t.wc = &writeCounter{w: t.f}
+		t.w = bufio.NewWriterSize(t.wc, 64*1024)
+	}
+	if live == 0 {
+		printTargetSummary(targets, "")
+		if anyTargetFailed(targets) {
+			return 1
+		}
+		cli.Info("Nothing to write. Exiting.")
+		hist.skip()
+		return 0
+	}
+
+	cli.Info("%s", text("generate.files", opts.Lines, opts.Width, opts.Mode, live))
+	var out io.Writer = &fanout{targets: targets, keepGoing: flags.keepGoing, flush: opts.FlushEvery > 0}
+	// The throttle paces the stream once, so every file gets it at --bps.
+	var throttle *throttledWriter
+	if flags.bps > 0 {
+		throttle = newThrottledWriter(context.Background(), out, flags.bps, throttleClock)
+		out = throttle
+	}
+	var stats *genlines.ContentStats
+	if flags.stats {
+		stats = &genlines.ContentStats{}
+		out = io.MultiWriter(out, stats)
+	}
+	var head *headRecorder
+	if sniffable(opts.Mode) {
+		head = &headRecorder{}
+		out = io.MultiWriter(out, head)
+	}
+
+	var st genlines.Stats
+	runOpts := opts
+	runOpts.Stats, runOpts.Checksums = &st, []string{"sha256"}
+	_, _, err := genlines.GenerateTo(context.Background(), out, runOpts)
+	generated := st.Lines
+	hist.written(st.Lines, st.Bytes)
+	for _, t := range targets {
+		if t.f == nil || t.err != nil {
+			continue
+		}
+		if ferr := t.w.Flush(); ferr != nil {
+			t.err = ferr
+		} else if cerr := t.f.Close(); cerr != nil {
+			t.err = cerr
+		}
+	}
+	if err != nil {
+		cli.Error("%v", err)
+		printTargetSummary(targets, "")
+		return 1
+	}
+	hexSum := st.Checksums["sha256"]
+
+	if flags.verifyAfter {
+		if beforeVerify != nil {
+			beforeVerify(okTargetPaths(targets))
+		}
+		for _, t := range targets {
+			if t.f == nil || t.err != nil {
+				continue
+			}
+			if err := verifyFile(t.path, 0, opts, hexSum); err != nil {
+				reportVerify(t.path, err)
+				t.err = fmt.Errorf("verification failed: %w", err)
+			}
+		}
+	}
+
+	printTargetSummary(targets, hexSum)
+	if opts.MaxBytes > 0 {
+		cli.Info("%s", maxBytesSummary(int(generated), opts.Lines, sizeOf(targets), opts.MaxBytes))
+	}
+
+	if flags.manifest != "" || writeMetaFile {
+		m := newManifest()
+		for _, t := range targets {
+			if t.f == nil || t.err != nil {
+				continue
+			}
+			m.add(t.path, generated, t.bytes, hexSum)
+			if writeMetaFile {
+				if err := writeMeta(t.path+metaSuffix, newRunMeta(t.path, opts, t.bytes, hexSum)); err != nil {
+					cli.Error("writing metadata: %v", err)
+					return 1
+				}
+			}
+		}
+		if flags.manifest != "" {
+			if err := m.write(flags.manifest); err != nil {
+				cli.Error("writing manifest: %v", err)
+				return 1
+			}
+			cli.Info("Wrote manifest %s", flags.manifest)
+		}
+	}
+
+	if stats != nil {
+		stats.Finish()
+		printStats(cli.infoOut(), stats)
+	}
+	printInjected(flags, st)
+	if head != nil {
+		warnSignature(flags, fmt.Sprintf("the output (%d files)", len(targets)), head.head)
+	}
+	cli.Verbose("Output:")
+	for _, t := range targets {
+		if t.wc != nil {
+			cli.Verbose("  %s: %s", t.path, t.wc.summary())
+		}
+	}
+	if throttle != nil {
+		cli.Info("%s", throttle.summary())
+	}
+	if flags.flushEvery > 0 {
+		cli.Verbose("Flushed %d times (every %d lines and at the end)", st.Flushes, flags.flushEvery)
+	}
+	if anyTargetFailed(targets) {
+		return 1
+	}
+	return 0
+}
+
+// printTargetSummary lists the outcome for every target. sum is the stream's
+// SHA-256, shown for completed targets when known.
+func printTargetSummary(targets []*outTarget, sum string) {
+	cli.Info("Targets:")
+	for _, t := range targets {
+		switch {
+		case t.skipped:
+			cli.Info("  %s: skipped (exists)", t.path)
+		case t.err != nil:
+			cli.Error("%s: FAILED: %v", t.path, t.err)
+		case t.f == nil:
+			cli.Info("  %s: not written", t.path)
+		case sum != "":
+			cli.Summary("  %s: %d bytes, sha256 %s", t.path, t.bytes, sum)
+		default:
+			cli.Info("  %s: %d bytes", t.path, t.bytes)
+		}
+	}
+}
+
+// abandonTargets closes every opened target and removes the ones this run
+// created.
+func abandonTargets(targets []*outTarget) {
+	for _, t := range targets {
+		if t.f == nil {
+			continue
+		}
+		t.f.Close()
+		if t.created {
+			os.Remove(t.path)
+		}
+	}
+}
+
+// isDevice reports whether f is a device rather than a regular file, which
+// cannot be truncated.
+func isDevice(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && !fi.Mode().IsRegular()
+}
+
+// okTargetPaths returns the paths of the targets written without error.
+func okTargetPaths(targets []*outTarget) []string {
+	var paths []string
+	for _, t := range targets {
+		if t.f != nil && t.err == nil {
+			paths = append(paths, t.path)
+		}
+	}
+	return paths
+}
+
+// anyTargetFailed reports whether a target failed.
+func anyTargetFailed(targets []*outTarget) bool {
+	for _, t := range targets {
+		if t.err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// sizeOf returns the bytes written to the first completed target; every
+// completed target holds the same stream.
+func sizeOf(targets []*outTarget) int64 {
+	for _, t := range targets {
+		if t.f != nil && t.err == nil {
+			return t.bytes
+		}
+	}
+	return 0
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_MultiTargetWritesIdenticalFiles(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, svc := range []string{"api", "worker", "web"} {
+		os.Mkdir(filepath.Join(dir, svc), 0755)
+		paths = append(paths, filepath.Join(dir, svc, "fixture.txt"))
+	}
+	output := captureStdout(t)
+	code := run([]string{"500", paths[0], "y", "60", "random", "7", "--out", paths[1], "--out=" + paths[2]})
+	if code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+
+	want, err := fileSHA256Hex(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range paths {
+		got, err := fileSHA256Hex(p)
+		if err != nil || got != want {
+			t.Errorf("%s: sha256 %s (%v), want %s", p, got, err, want)
+		}
+		if !strings.Contains(output(), p+": 30500 bytes, sha256 "+want) {
+			t.Errorf("summary lacks %s:\n%s", p, output())
+		}
+	}
+}
+
+func TestRun_MultiTargetUnwritable(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.txt")
+	os.WriteFile(existing, []byte("keep me\n"), 0644)
+	fresh := filepath.Join(dir, "fresh.txt")
+	unwritable := filepath.Join(dir, "missing-dir", "x.txt")
+	captureStdout(t)
+	errOut := captureStderr(t)
+
+	// By default one bad target stops the run before anything is touched.
+	if code := run([]string{"10", existing, "y", "10", "digits", "--out", fresh, "--out", unwritable}); code != 1 {
+		t.Fatalf("run exited with %d, want 1", code)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "keep me\n" {
+		t.Errorf("aborted run changed %s: %q", existing, data)
+	}
+	if fileExists(fresh) {
+		t.Errorf("aborted run left %s behind", fresh)
+	}
+
+	// --keep-going writes the others and still fails.
+	if code := run([]string{"10", existing, "y", "10", "digits", "--out", fresh, "--out", unwritable, "--keep-going"}); code != 1 {
+		t.Fatalf("--keep-going run exited with %d, want 1", code)
+	}
+	for _, p := range []string{existing, fresh} {
+		if data, _ := os.ReadFile(p); string(data) != strings.Repeat("0123456789\n", 10) {
+			t.Errorf("%s: %q", p, data)
+		}
+	}
+	if !strings.Contains(errOut(), unwritable+": FAILED") {
+		t.Errorf("summary does not report the failed target:\n%s", errOut())
+	}
+}
+
+func TestRun_MultiTargetWriteFailure(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("no /dev/full")
+	}
+	path := filepath.Join(t.TempDir(), "ok.txt")
+	captureStdout(t)
+	captureStderr(t)
+	if code := run([]string{"10000", path, "y", "80", "digits", "--out", "/dev/full", "--keep-going", "--retries", "0"}); code != 1 {
+		t.Fatalf("run exited with %d, want 1", code)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 810000 {
+		t.Errorf("the healthy target was not completed: %v", err)
+	}
+	if code := run([]string{"10000", path, "y", "80", "digits", "--out", "/dev/full", "--retries", "0"}); code != 1 {
+		t.Fatalf("run without --keep-going exited with %d, want 1", code)
+	}
+}
+
+func TestRun_MultiTargetRejectsDuplicatesAndSkipsDeclined(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	captureStdout(t)
+	captureStderr(t)
+	if code := run([]string{"3", a, "y", "--out", a}); code != 1 {
+		t.Errorf("duplicate target exited with %d, want 1", code)
+	}
+
+	os.WriteFile(a, []byte("old\n"), 0644)
+	if code := run([]string{"3", a, "n", "5", "upper", "--out", b}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "old\n" {
+		t.Errorf("declined target was overwritten: %q", data)
+	}
+	if data, _ := os.ReadFile(b); string(data) != "ABCDE\nFGHIJ\nKLMNO\n" {
+		t.Errorf("b.txt: %q", data)
+	}
+}
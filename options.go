@@ -0,0 +1,639 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// cliFlags holds the --options given on the command line.
+type cliFlags struct {
+	commentEvery int
+	commentText  string
+	force        bool
+	forceTarget  bool
+	meta         bool
+	maxLines     int
+	maxLinesSet  bool
+	noMeta       bool
+	manifest     string
+	golden       string // --golden: per-line index of the output
+	summaryJSON  string // --summary-json: JSON summary of the run
+	jsonSchema   bool   // --json-schema: print the summary's schema
+	noColor      bool
+	lineChecksum bool
+	escapeASCII  bool   // --escape-nonascii
+	rot          int    // --rot / --rot13; 0 = none
+	continuation string // --continuation / --continuation-marker; "" = none
+	firstLine    string // --first-line: content of line 1
+	lastLine     string // --last-line: content of the last line
+	stamp        bool   // --stamp: a first line describing the run
+	tempName     string // --temp-name: prefix of a new, uniquely named file
+	wipeOld      bool   // --wipe-old: zero files before they are replaced
+	unsafe       bool
+	allowControl bool
+	stats        bool
+	verbose      bool
+	quiet        bool // --quiet: warnings and errors only
+	jsonMsgs     bool // --json-messages: messages as JSON lines
+	exactBytes   int64
+	maxBytes     int64
+	align        int64
+	alignFill    byte
+	appendOut    bool
+	ramp         genlines.Ramp
+	trailingWS   genlines.TrailingWS
+	linePattern  genlines.LinePattern
+	inject       genlines.InjectUnicode
+	startOffset  int64
+	safeStart    bool
+	byteRange    genlines.ByteRange
+	sortOrder    genlines.SortOrder
+	sortMaxMem   int64 // --sort-max-memory; 0 = genlines.DefaultSortMaxMemory
+	noSniffWarn  bool  // --no-sniff-warning
+	noNameCheck  bool  // --no-name-confirm
+	verifyAfter  bool
+	forceANSI    bool
+	outs         []string // --out, repeatable: more targets for the same stream
+	keepGoing    bool
+	alsoLink     []string // --also-link: directories to hard-link (or copy) the output into
+	seed         uint64
+	seedSet      bool
+	flushEvery   int // --flush-every, if flushSet
+	flushSet     bool
+	bps          int64 // --bps: bytes per second; 0 = no limit
+	retries      int
+	retriesSet   bool
+	retryBackoff time.Duration
+	backoffSet   bool
+	eol          []byte // --line-ending; nil = default (or sniffed when appending)
+	explain      bool
+	given        []givenFlag // every option given, in order, for --explain
+
+	// split options
+	splitLines   int
+	gzMembers    int // --gz-member-lines
+	gzIndex      bool
+	strictArgs   bool
+	noExpand     bool
+	autoExt      bool // --auto-ext / --no-auto-ext, if autoExtSet
+	autoExtSet   bool
+	splitPattern string
+
+	// daemon options
+	rate       float64
+	rotateSize int64
+	keep       int
+	keepSet    bool
+
+	// stress-files options
+	concurrency int
+
+	// streams options
+	ratioOut int // --ratio A:B; 0 = default
+	ratioErr int
+	tags     bool
+	delay    time.Duration
+
+	// verify options
+	auto bool // --auto: configure from the file's stamp
+}
+
+// flagSpec describes one --option: its name, whether it takes a value, and how to apply it.
+type flagSpec struct {
+	name     string
+	hasValue bool
+	set      func(f *cliFlags, value string) error
+}
+
+var flagSpecs = []flagSpec{
+	{"comment-every", true, func(f *cliFlags, v string) error {
+		n, err := parsePositiveInt(v)
+		if err != nil {
+			return fmt.Errorf("invalid --comment-every: %q (expected a positive integer)", v)
+		}
+		f.commentEvery = n
+		return nil
+	}},
+	{"comment-text", true, func(f *cliFlags, v string) error {
+		if err := genlines.ValidateCommentText(v); err != nil {
+			return err
+		}
+		f.commentText = v
+		return nil
+	}},
+	{"force", false, func(f *cliFlags, v string) error {
+		f.force = true
+		return nil
+	}},
+	{"force-target", false, func(f *cliFlags, v string) error {
+		f.forceTarget = true
+		return nil
+	}},
+	{"meta", false, func(f *cliFlags, v string) error {
+		f.meta = true
+		return nil
+	}},
+	{"no-meta", false, func(f *cliFlags, v string) error {
+		f.noMeta = true
+		return nil
+	}},
+	{"exact-bytes", true, func(f *cliFlags, v string) error {
+		n, err := parseByteSize(v)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid --exact-bytes: %q (expected a size > 0, e.g. 1048576 or 1MiB)", v)
+		}
+		f.exactBytes = n
+		return nil
+	}},
+	{"max-bytes", true, func(f *cliFlags, v string) error {
+		n, err := parseByteSize(v)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid --max-bytes: %q (expected a size > 0, e.g. 4294967295 or 4GiB)", v)
+		}
+		f.maxBytes = n
+		return nil
+	}},
+	{"align", true, func(f *cliFlags, v string) error {
+		n, err := parseByteSize(v)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid --align: %q (expected a size > 0, e.g. 4096 or 4KiB)", v)
+		}
+		f.align = n
+		return nil
+	}},
+	{"align-fill", true, func(f *cliFlags, v string) error {
+		if len(v) != 1 || v[0] < ' ' || v[0] > '~' {
+			return fmt.Errorf("invalid --align-fill: %q (expected one printable ASCII character)", v)
+		}
+		f.alignFill = v[0]
+		return nil
+	}},
+	{"stats", false, func(f *cliFlags, v string) error {
+		f.stats = true
+		return nil
+	}},
+	{"verbose", false, func(f *cliFlags, v string) error {
+		f.verbose = true
+		return nil
+	}},
+	{"wipe-old", false, func(f *cliFlags, v string) error {
+		f.wipeOld = true
+		return nil
+	}},
+	{"quiet", false, func(f *cliFlags, v string) error {
+		f.quiet = true
+		return nil
+	}},
+	{"json-messages", false, func(f *cliFlags, v string) error {
+		f.jsonMsgs = true
+		return nil
+	}},
+	{"allow-control", false, func(f *cliFlags, v string) error {
+		f.allowControl = true
+		return nil
+	}},
+	{"ramp", true, func(f *cliFlags, v string) error {
+		r, err := genlines.ParseRamp(v)
+		if err != nil {
+			return fmt.Errorf("invalid --ramp: %v", err)
+		}
+		f.ramp = r
+		return nil
+	}},
+	{"verify-after", false, func(f *cliFlags, v string) error {
+		f.verifyAfter = true
+		return nil
+	}},
+	{"seed", true, func(f *cliFlags, v string) error {
+		seed, err := genlines.ParseSeed(v)
+		if err != nil {
+			return fmt.Errorf("invalid --seed: %v", err)
+		}
+		f.seed = seed
+		f.seedSet = true
+		return nil
+	}},
+	{"flush-every", true, func(f *cliFlags, v string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid --flush-every: %q (expected a non-negative integer)", v)
+		}
+		f.flushEvery = n
+		f.flushSet = true
+		return nil
+	}},
+	{"bps", true, func(f *cliFlags, v string) error {
+		n, err := parseByteSize(v)
+		if err != nil {
+			return fmt.Errorf("invalid --bps: %v", err)
+		}
+		f.bps = n
+		return nil
+	}},
+	{"retries", true, func(f *cliFlags, v string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid --retries: %q (expected a non-negative integer)", v)
+		}
+		f.retries = n
+		f.retriesSet = true
+		return nil
+	}},
+	{"retry-backoff", true, func(f *cliFlags, v string) error {
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid --retry-backoff: %q (expected a duration such as 200ms or 1s)", v)
+		}
+		f.retryBackoff = d
+		f.backoffSet = true
+		return nil
+	}},
+	{"line-checksum", false, func(f *cliFlags, v string) error {
+		f.lineChecksum = true
+		return nil
+	}},
+	{"continuation", false, func(f *cliFlags, v string) error {
+		if f.continuation == "" {
+			f.continuation = genlines.DefaultContinuation
+		}
+		return nil
+	}},
+	{"continuation-marker", true, func(f *cliFlags, v string) error {
+		if v == "" {
+			return fmt.Errorf("invalid --continuation-marker: %q (expected one or more characters)", v)
+		}
+		f.continuation = v
+		return nil
+	}},
+	{"first-line", true, func(f *cliFlags, v string) error {
+		if v == "" {
+			return fmt.Errorf("invalid --first-line: expected the text of the line")
+		}
+		f.firstLine = v
+		return nil
+	}},
+	{"last-line", true, func(f *cliFlags, v string) error {
+		if v == "" {
+			return fmt.Errorf("invalid --last-line: expected the text of the line")
+		}
+		f.lastLine = v
+		return nil
+	}},
+	{"stamp", false, func(f *cliFlags, v string) error {
+		f.stamp = true
+		return nil
+	}},
+	{"temp-name", true, func(f *cliFlags, v string) error {
+		if strings.TrimSpace(v) == "" {
+			return fmt.Errorf("invalid --temp-name: expected a file name prefix")
+		}
+		f.tempName = v
+		return nil
+	}},
+	{"auto", false, func(f *cliFlags, v string) error {
+		f.auto = true
+		return nil
+	}},
+	{"escape-nonascii", false, func(f *cliFlags, v string) error {
+		f.escapeASCII = true
+		return nil
+	}},
+	{"rot", true, func(f *cliFlags, v string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 1 || n > 25 {
+			return fmt.Errorf("invalid --rot: %q (expected 1 to 25)", v)
+		}
+		f.rot = n
+		return nil
+	}},
+	{"rot13", false, func(f *cliFlags, v string) error {
+		f.rot = genlines.DefaultRot
+		return nil
+	}},
+	{"trailing-ws", true, func(f *cliFlags, v string) error {
+		ws, err := genlines.ParseTrailingWS(v)
+		if err != nil {
+			return fmt.Errorf("invalid --trailing-ws: %v", err)
+		}
+		f.trailingWS = ws
+		return nil
+	}},
+	{"line-pattern", true, func(f *cliFlags, v string) error {
+		p, err := genlines.ParseLinePattern(v)
+		if err != nil {
+			return fmt.Errorf("invalid --line-pattern: %v", err)
+		}
+		f.linePattern = p
+		return nil
+	}},
+	{"start-offset", true, func(f *cliFlags, v string) error {
+		digits, err := stripDigitSeparators(strings.TrimSpace(v))
+		if err == nil {
+			f.startOffset, err = strconv.ParseInt(digits, 10, 64)
+		}
+		if err != nil || f.startOffset < 0 {
+			return fmt.Errorf("invalid --start-offset: %q (expected a non-negative number of characters)", v)
+		}
+		return nil
+	}},
+	{"inject-unicode", true, func(f *cliFlags, v string) error {
+		u, err := genlines.ParseInjectUnicode(v)
+		if err != nil {
+			return fmt.Errorf("invalid --inject-unicode: %v", err)
+		}
+		f.inject = u
+		return nil
+	}},
+	{"safe-start", false, func(f *cliFlags, v string) error {
+		f.safeStart = true
+		return nil
+	}},
+	{"byte-range", true, func(f *cliFlags, v string) error {
+		r, err := genlines.ParseByteRange(v)
+		if err != nil {
+			return fmt.Errorf("invalid --byte-range: %v", err)
+		}
+		f.byteRange = r
+		return nil
+	}},
+	{"sort", true, func(f *cliFlags, v string) error {
+		o, err := genlines.ParseSortOrder(v)
+		if err != nil {
+			return fmt.Errorf("invalid --sort: %v", err)
+		}
+		f.sortOrder = o
+		return nil
+	}},
+	{"sort-max-memory", true, func(f *cliFlags, v string) error {
+		n, err := parseByteSize(v)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid --sort-max-memory: %q (expected a size > 0, e.g. 1073741824 or 1GiB)", v)
+		}
+		f.sortMaxMem = n
+		return nil
+	}},
+	{"no-name-confirm", false, func(f *cliFlags, v string) error {
+		f.noNameCheck = true
+		return nil
+	}},
+	{"no-sniff-warning", false, func(f *cliFlags, v string) error {
+		f.noSniffWarn = true
+		return nil
+	}},
+	{"unsafe", false, func(f *cliFlags, v string) error {
+		f.unsafe = true
+		return nil
+	}},
+	{"out", true, func(f *cliFlags, v string) error {
+		if strings.TrimSpace(v) == "" {
+			return fmt.Errorf("invalid --out: expected a file path")
+		}
+		f.outs = append(f.outs, v)
+		return nil
+	}},
+	{"also-link", true, func(f *cliFlags, v string) error {
+		for _, dir := range strings.Split(v, ",") {
+			if strings.TrimSpace(dir) == "" {
+				return fmt.Errorf("invalid --also-link: %q (expected comma-separated directories)", v)
+			}
+			f.alsoLink = append(f.alsoLink, dir)
+		}
+		return nil
+	}},
+	{"keep-going", false, func(f *cliFlags, v string) error {
+		f.keepGoing = true
+		return nil
+	}},
+	{"append", false, func(f *cliFlags, v string) error {
+		f.appendOut = true
+		return nil
+	}},
+	{"line-ending", true, func(f *cliFlags, v string) error {
+		eol, err := parseLineEnding(v)
+		if err != nil {
+			return err
+		}
+		f.eol = eol
+		return nil
+	}},
+	{"force-ansi", false, func(f *cliFlags, v string) error {
+		f.forceANSI = true
+		return nil
+	}},
+	{"no-color", false, func(f *cliFlags, v string) error {
+		f.noColor = true
+		return nil
+	}},
+	{"golden", true, func(f *cliFlags, v string) error {
+		if strings.TrimSpace(v) == "" {
+			return fmt.Errorf("invalid --golden: expected a file path")
+		}
+		f.golden = v
+		return nil
+	}},
+	{"explain", false, func(f *cliFlags, v string) error {
+		f.explain = true
+		return nil
+	}},
+	{"max-lines", true, func(f *cliFlags, v string) error {
+		n, err := parseLineCount(v)
+		if err != nil {
+			return fmt.Errorf("invalid --max-lines: %q (%v)", v, err)
+		}
+		f.maxLines = n
+		f.maxLinesSet = true
+		return nil
+	}},
+	{"summary-json", true, func(f *cliFlags, v string) error {
+		if strings.TrimSpace(v) == "" {
+			return fmt.Errorf("invalid --summary-json: expected a file path")
+		}
+		f.summaryJSON = v
+		return nil
+	}},
+	{"json-schema", false, func(f *cliFlags, v string) error {
+		f.jsonSchema = true
+		return nil
+	}},
+	{"manifest", true, func(f *cliFlags, v string) error {
+		if strings.TrimSpace(v) == "" {
+			return fmt.Errorf("invalid --manifest: expected a file path")
+		}
+		f.manifest = v
+		return nil
+	}},
+	{"split-lines", true, func(f *cliFlags, v string) error {
+		n, err := parseLineCount(v)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid --split-lines: %q (expected a positive line count)", v)
+		}
+		f.splitLines = n
+		return nil
+	}},
+	{"no-expand", false, func(f *cliFlags, v string) error {
+		f.noExpand = true
+		return nil
+	}},
+	{"strict-args", false, func(f *cliFlags, v string) error {
+		f.strictArgs = true
+		return nil
+	}},
+	{"gz-member-lines", true, func(f *cliFlags, v string) error {
+		n, err := parseLineCount(v)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid --gz-member-lines: %q (expected a positive line count)", v)
+		}
+		f.gzMembers = n
+		return nil
+	}},
+	{"gz-index", false, func(f *cliFlags, v string) error {
+		f.gzIndex = true
+		return nil
+	}},
+	{"split-pattern", true, func(f *cliFlags, v string) error {
+		if err := validateSplitPattern(v); err != nil {
+			return err
+		}
+		f.splitPattern = v
+		return nil
+	}},
+	{"rate", true, func(f *cliFlags, v string) error {
+		r, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || r <= 0 || math.IsInf(r, 0) {
+			return fmt.Errorf("invalid --rate: %q (expected lines per second > 0)", v)
+		}
+		f.rate = r
+		return nil
+	}},
+	{"rotate-size", true, func(f *cliFlags, v string) error {
+		n, err := parseByteSize(v)
+		if err != nil {
+			return fmt.Errorf("invalid --rotate-size: %v", err)
+		}
+		f.rotateSize = n
+		return nil
+	}},
+	{"keep", true, func(f *cliFlags, v string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid --keep: %q (expected a non-negative integer)", v)
+		}
+		f.keep = n
+		f.keepSet = true
+		return nil
+	}},
+	{"auto-ext", false, func(f *cliFlags, v string) error {
+		f.autoExt, f.autoExtSet = true, true
+		return nil
+	}},
+	{"no-auto-ext", false, func(f *cliFlags, v string) error {
+		f.autoExt, f.autoExtSet = false, true
+		return nil
+	}},
+	{"ratio", true, func(f *cliFlags, v string) error {
+		a, b, ok := strings.Cut(v, ":")
+		out, err1 := parsePositiveInt(a)
+		errLines, err2 := parsePositiveInt(b)
+		if !ok || err1 != nil || err2 != nil {
+			return fmt.Errorf("invalid --ratio: %q (expected stdout:stderr lines per turn, e.g. 3:1)", v)
+		}
+		f.ratioOut, f.ratioErr = out, errLines
+		return nil
+	}},
+	{"tags", false, func(f *cliFlags, v string) error {
+		f.tags = true
+		return nil
+	}},
+	{"delay", true, func(f *cliFlags, v string) error {
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid --delay: %q (expected a duration such as 10ms)", v)
+		}
+		f.delay = d
+		return nil
+	}},
+	{"concurrency", true, func(f *cliFlags, v string) error {
+		n, err := parsePositiveInt(v)
+		if err != nil {
+			return fmt.Errorf("invalid --concurrency: %q (expected a positive integer)", v)
+		}
+		f.concurrency = n
+		return nil
+	}},
+}
+
+// lookupFlag returns the spec for the named option.
+func lookupFlag(name string) (flagSpec, bool) {
+	for _, s := range flagSpecs {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return flagSpec{}, false
+}
+
+// splitFlags separates --name value / --name=value options from positional arguments.
+// Everything after a bare "--" is treated as positional.
+func splitFlags(args []string) (positional []string, flags cliFlags, err error) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(a, "--") {
+			positional = append(positional, a)
+			continue
+		}
+
+		name, value, hasInline := strings.Cut(a[2:], "=")
+		spec, ok := lookupFlag(strings.ToLower(name))
+		if !ok {
+			return nil, flags, fmt.Errorf("unknown option: --%s", name)
+		}
+
+		if spec.hasValue && !hasInline {
+			if i+1 >= len(args) {
+				return nil, flags, fmt.Errorf("option --%s requires a value", spec.name)
+			}
+			i++
+			value = args[i]
+		} else if !spec.hasValue && hasInline {
+			return nil, flags, fmt.Errorf("option --%s does not take a value", spec.name)
+		}
+
+		if err := spec.set(&flags, value); err != nil {
+			return nil, flags, err
+		}
+		flags.given = append(flags.given, givenFlag{name: spec.name, value: value})
+	}
+	return positional, flags, nil
+}
+
+const (
+	// defaultRetries is how often a transient write error is retried.
+	defaultRetries = 3
+	// defaultRetryBackoff is the wait before the first retry.
+	defaultRetryBackoff = 200 * time.Millisecond
+)
+
+// writeRetry returns the retry policy for transient write errors, logging
+// every retry to stderr.
+func writeRetry(flags cliFlags) genlines.Retry {
+	r := genlines.Retry{Attempts: defaultRetries, Backoff: defaultRetryBackoff}
+	if flags.retriesSet {
+		r.Attempts = flags.retries
+	}
+	if flags.backoffSet {
+		r.Backoff = flags.retryBackoff
+	}
+	r.OnRetry = func(line int64, attempt int, err error) {
+		cli.Warn("transient write error at line %d (retry %d of %d): %v", line, attempt, r.Attempts, err)
+	}
+	return r
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/Bjornsrud/GenerateLines/genlines/humanize"
+)
+
+// shrinkRatio is how many times the size of the new output an existing file
+// must exceed for an interactive overwrite to ask for its name as well.
+const shrinkRatio = 100
+
+// overwritePrompt decides whether existing output files may be overwritten,
+// for every conflict of one run: from the overwrite argument when given,
+// otherwise by asking. Answering A (all) or N (none) settles the remaining
+// conflicts of the run too, so a split or multi-file run hitting many
+// existing files asks at most once per file until then.
+type overwritePrompt struct {
+	in     *bufio.Reader
+	answer string // overwrite argument, or "y"/"n" after A/N; "" = ask
+}
+
+func newOverwritePrompt(in *bufio.Reader, overwriteFlag string) *overwritePrompt {
+	return &overwritePrompt{in: in, answer: overwriteFlag}
+}
+
+// asks reports whether the next conflict will be put to the user.
+func (p *overwritePrompt) asks() bool { return p.answer == "" }
+
+// allow reports whether the conflict described by what may be overwritten.
+// more says whether other conflicts follow in this run; only then are the
+// A and N answers offered.
+func (p *overwritePrompt) allow(what string, more bool) (bool, error) {
+	if !p.asks() {
+		return parseYesNo(p.answer), nil
+	}
+	choices := "[y/n]"
+	if more {
+		choices = "[y/n/A(all)/N(none)]"
+	}
+	for {
+		s, err := promptLineR(p.in, cli.highlight(ansiYellow, what)+text("overwrite.question")+choices+": ")
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case s == "A" || strings.EqualFold(s, "all"):
+			p.answer = "y"
+			return true, nil
+		case s == "N" || strings.EqualFold(s, "none"):
+			p.answer = "n"
+			return false, nil
+		case strings.EqualFold(s, "y") || strings.EqualFold(s, "yes"):
+			return true, nil
+		case strings.EqualFold(s, "n") || strings.EqualFold(s, "no"):
+			return false, nil
+		}
+		if more {
+			cli.Prompt(text("overwrite.retryAll") + "\n")
+		} else {
+			cli.Prompt(text("overwrite.retry") + "\n")
+		}
+	}
+}
+
+// confirmName asks for the name of path before it is replaced by newSize
+// bytes of output (-1 if unknown), if it is over shrinkRatio times that
+// size, and reports whether the answer matched. Other files need no name.
+func (p *overwritePrompt) confirmName(path string, newSize int64) (bool, error) {
+	fi, err := os.Stat(path)
+	if err != nil || newSize < 0 || float64(fi.Size()) <= shrinkRatio*float64(newSize) {
+		return true, nil
+	}
+	s, err := promptLineR(p.in, cli.highlight(ansiYellow, text("overwrite.typeName", path, shrinkRatio)))
+	if err != nil {
+		return false, err
+	}
+	if s != path {
+		cli.Prompt(text("overwrite.nameWrong") + "\n")
+		return false, nil
+	}
+	return true, nil
+}
+
+// describeExisting returns the message about an existing output file that
+// the overwrite question and its log lines start with: its size and
+// modification time and, when known (newSize >= 0), the size of the output
+// replacing it.
+func describeExisting(path string, newSize int64) string {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return text("overwrite.exists", path)
+	}
+	size, mtime := humanize.Bytes(fi.Size()), fi.ModTime().Format("2006-01-02 15:04")
+	if newSize < 0 {
+		return text("overwrite.existsInfo", path, size, mtime)
+	}
+	return text("overwrite.existsNew", path, size, mtime, humanize.Bytes(newSize))
+}
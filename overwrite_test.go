@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOverwritePrompt_AllAndNone(t *testing.T) {
+	out := captureStdout(t)
+	// After A the input is exhausted: a third prompt would fail with EOF.
+	p := newOverwritePrompt(bufio.NewReader(strings.NewReader("y\nmaybe\nA\n")), "")
+	for i, want := range []bool{true, true, true} {
+		got, err := p.allow("f already exists.", true)
+		if err != nil || got != want {
+			t.Fatalf("conflict %d: allow = %v, %v", i+1, got, err)
+		}
+	}
+	text := out()
+	if n := strings.Count(text, "Overwrite? [y/n/A(all)/N(none)]: "); n != 3 {
+		t.Errorf("asked %d times, want 3:\n%s", n, text)
+	}
+	if !strings.Contains(text, "Please answer y, n, A (all remaining) or N (none of the remaining).") {
+		t.Errorf("no retry hint for an invalid answer:\n%s", text)
+	}
+
+	p = newOverwritePrompt(bufio.NewReader(strings.NewReader("N\n")), "")
+	for i := 0; i < 2; i++ {
+		if got, err := p.allow("f already exists.", true); err != nil || got {
+			t.Fatalf("after N, conflict %d: allow = %v, %v", i+1, got, err)
+		}
+	}
+
+	// A lowercase n only declines the one file.
+	p = newOverwritePrompt(bufio.NewReader(strings.NewReader("n\n")), "")
+	p.allow("f already exists.", true)
+	if !p.asks() {
+		t.Error("n should not settle the remaining conflicts")
+	}
+
+	// The overwrite argument answers without asking.
+	p = newOverwritePrompt(bufio.NewReader(strings.NewReader("")), "y")
+	if got, err := p.allow("f already exists.", true); err != nil || !got {
+		t.Errorf("with y given: allow = %v, %v", got, err)
+	}
+}
+
+func TestRun_OverwriteAllWithOut(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "c.txt")}
+	for _, p := range paths {
+		os.WriteFile(p, []byte("old\n"), 0644)
+	}
+
+	withStdin(t, "y\nA\n", true)
+	out := captureStdout(t)
+	if code := run([]string{"2", paths[0], "5", "digits", "--out", paths[1], "--out", paths[2]}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	text := out()
+	if n := strings.Count(text, "Overwrite?"); n != 2 {
+		t.Errorf("asked %d times, want 2:\n%s", n, text)
+	}
+	for _, p := range paths {
+		if data, _ := os.ReadFile(p); string(data) != "01234\n56789\n" {
+			t.Errorf("%s = %q, want it overwritten", p, data)
+		}
+	}
+}
+
+func TestRun_SplitOverwritePrompts(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+	writeParts := func() {
+		for _, name := range []string{"out-001.txt", "out-002.txt", "out-003.txt"} {
+			os.WriteFile(filepath.Join(dir, name), []byte("old\n"), 0644)
+		}
+	}
+
+	// N on the second part declines the rest: nothing is written.
+	writeParts()
+	withStdin(t, "y\nN\n", true)
+	if code := run([]string{"30", path, "10", "digits", "--split-lines", "10"}); code != 0 {
+		t.Fatalf("declined run exited with %d", code)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "out-001.txt")); string(data) != "old\n" {
+		t.Errorf("declined run wrote out-001.txt: %q", data)
+	}
+
+	// A on the second part overwrites the third without asking.
+	withStdin(t, "y\nA\n", true)
+	out := captureStdout(t)
+	errOut := captureStderr(t)
+	if code := run([]string{"30", path, "10", "digits", "--split-lines", "10"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	text := out()
+	if n := strings.Count(text, "Overwrite?"); n != 2 {
+		t.Errorf("asked %d times, want 2:\n%s", n, text)
+	}
+	if got := errOut(); !strings.Contains(got, "WARNING: "+filepath.Join(dir, "out-003.txt")+" already exists. Overwriting...") {
+		t.Errorf("the third part is not reported as overwritten:\n%s", got)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "out-003.txt")); string(data) == "old\n" {
+		t.Error("out-003.txt was not overwritten")
+	}
+}
+
+func TestRun_OverwriteShowsSizes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f.txt")
+	os.WriteFile(path, make([]byte, 2048), 0644)
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	os.Chtimes(path, mtime, mtime)
+
+	errOut := captureStderr(t)
+	if code := run([]string{"2", path, "y", "10"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if want := "WARNING: " + path + " already exists (2.0 KiB, modified 2024-01-02 03:04); the new output is 22 B. Overwriting..."; !strings.Contains(errOut(), want) {
+		t.Errorf("stderr lacks %q:\n%s", want, errOut())
+	}
+
+	// Without a planned size the new output is left out.
+	os.Chtimes(path, mtime, mtime)
+	if got, want := describeExisting(path, -1), path+" already exists (22 B, modified 2024-01-02 03:04)."; got != want {
+		t.Errorf("describeExisting = %q, want %q", got, want)
+	}
+}
+
+func TestRun_OverwriteMuchLargerAsksForName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "curated.txt")
+	big := strings.Repeat("x", 100*22+1) // over 100 times the 22 bytes of 2 lines of 10
+	os.WriteFile(path, []byte(big), 0644)
+
+	withStdin(t, "y\ncurated.txt\n", true)
+	out := captureStdout(t)
+	if code := run([]string{"2", path, "10"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	text := out()
+	for _, want := range []string{
+		path + " is over 100 times the size of the new output. Type its name to overwrite it: ",
+		"The name does not match.",
+		"Not overwriting. Exiting.",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output lacks %q:\n%s", want, text)
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != big {
+		t.Fatal("overwritten without the name")
+	}
+
+	withStdin(t, "y\n"+path+"\n", true)
+	if code := run([]string{"2", path, "10"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if data, _ := os.ReadFile(path); len(data) != 22 {
+		t.Fatalf("not overwritten after typing the name: %d bytes", len(data))
+	}
+
+	// --no-name-confirm and an overwrite argument go without the name.
+	for _, args := range [][]string{{"2", path, "10", "--no-name-confirm"}, {"2", path, "y", "10"}} {
+		os.WriteFile(path, []byte(big), 0644)
+		withStdin(t, "y\n", true) // a name prompt would hit EOF and fail
+		if code := run(args); code != 0 {
+			t.Fatalf("%v exited with %d", args, code)
+		}
+		if data, _ := os.ReadFile(path); len(data) != 22 {
+			t.Errorf("%v: not overwritten", args)
+		}
+	}
+
+	// Exactly 100 times the size needs no name.
+	os.WriteFile(path, []byte(big[:100*22]), 0644)
+	p := newOverwritePrompt(bufio.NewReader(strings.NewReader("")), "")
+	if ok, err := p.confirmName(path, 22); !ok || err != nil {
+		t.Errorf("confirmName at 100 times = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// configDirEnv overrides the directory holding presets.json (default:
+// os.UserConfigDir()/generatelines).
+const configDirEnv = "GENERATELINES_CONFIG_DIR"
+
+// preset is a saved command line. Fields hold the arguments as typed, so
+// e.g. "1M" or "term-2" are resolved again on every run.
+type preset struct {
+	Lines     string   `json:"lines"`
+	Filename  string   `json:"filename"`
+	Overwrite string   `json:"overwrite,omitempty"`
+	Width     string   `json:"width,omitempty"`
+	Mode      string   `json:"mode,omitempty"`
+	ModeArg   string   `json:"modeArg,omitempty"`
+	Options   []string `json:"options,omitempty"` // normalized to --name or --name=value
+}
+
+// presetFields are the names accepted as field=value overrides by "preset run".
+var presetFields = []string{"lines", "filename", "overwrite", "width", "mode", "modeArg"}
+
+// presetsPath returns the location of presets.json.
+func presetsPath() (string, error) {
+	if dir := os.Getenv(configDirEnv); dir != "" {
+		return filepath.Join(dir, "presets.json"), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("no config directory for presets (set %s): %w", configDirEnv, err)
+	}
+	return filepath.Join(dir, "generatelines", "presets.json"), nil
+}
+
+// loadPresets reads the presets file. A missing file is an empty set.
+func loadPresets(path string) (map[string]preset, error) {
+	presets := map[string]preset{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return presets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, fmt.Errorf("presets file %s is corrupt (%v); fix or delete it", path, err)
+	}
+	if presets == nil { // the file contained null
+		presets = map[string]preset{}
+	}
+	return presets, nil
+}
+
+// savePresets writes presets to path, creating its directory if needed.
+func savePresets(path string, presets map[string]preset) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(presets, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// separateOptions splits args into positional arguments and --options, the
+// latter normalized to "--name" or "--name=value".
+func separateOptions(args []string) (positional, options []string, err error) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(a, "--") {
+			positional = append(positional, a)
+			continue
+		}
+		name, value, hasInline := strings.Cut(a[2:], "=")
+		spec, ok := lookupFlag(strings.ToLower(name))
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown option: --%s", name)
+		}
+		switch {
+		case spec.hasValue && !hasInline:
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("option --%s requires a value", spec.name)
+			}
+			i++
+			value = args[i]
+			fallthrough
+		case spec.hasValue:
+			options = append(options, "--"+spec.name+"="+value)
+		default:
+			options = append(options, "--"+spec.name)
+		}
+	}
+	if _, _, err := splitFlags(options); err != nil {
+		return nil, nil, err
+	}
+	return positional, options, nil
+}
+
+// newPreset builds a preset from a generation command line
+// (<lines> <filename> [y|n] [width] [mode] [modeArg] [options]).
+func newPreset(args []string) (preset, error) {
+	positional, options, err := separateOptions(args)
+	if err != nil {
+		return preset{}, err
+	}
+	if len(positional) < 2 {
+		return preset{}, errors.New("a preset needs at least <lines> and <filename>")
+	}
+	if len(positional) > 6 {
+		return preset{}, fmt.Errorf("too many arguments: %q", positional[6:])
+	}
+
+	p := preset{Lines: positional[0], Filename: positional[1], Options: options}
+	rest := positional[2:]
+	if len(rest) > 0 && looksLikeYesNo(rest[0]) {
+		p.Overwrite, rest = rest[0], rest[1:]
+	}
+	if len(rest) > 0 {
+		if _, err := parsePositiveInt(rest[0]); err == nil || isTermWidth(rest[0]) {
+			p.Width, rest = rest[0], rest[1:]
+		}
+	}
+	if len(rest) > 0 {
+		p.Mode, rest = rest[0], rest[1:]
+	}
+	if len(rest) > 0 {
+		p.ModeArg = rest[0]
+	}
+	return p, p.validate()
+}
+
+// validate checks that p describes a valid generation command.
+func (p preset) validate() error {
+	if p.Mode == "" && p.ModeArg != "" {
+		return errors.New("a modeArg needs a mode")
+	}
+	if p.Width != "" && !isTermWidth(p.Width) {
+		if _, err := parsePositiveInt(p.Width); err != nil {
+			return fmt.Errorf("invalid width: %q", p.Width)
+		}
+	}
+	if p.Overwrite != "" && !looksLikeYesNo(p.Overwrite) {
+		return fmt.Errorf("invalid overwrite answer: %q (expected y or n)", p.Overwrite)
+	}
+	if _, _, ok := parseMatchArg(p.Lines); !ok {
+		if _, err := parseLineCount(p.Lines); err != nil {
+			return fmt.Errorf("invalid number of lines: %q (%v)", p.Lines, err)
+		}
+	}
+	if strings.TrimSpace(p.Filename) == "" {
+		return errors.New("filename cannot be empty")
+	}
+	if p.Mode != "" {
+		if _, _, err := normalizeMode(p.Mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// args returns the command line p stands for.
+func (p preset) args() []string {
+	args := []string{p.Lines, p.Filename}
+	for _, v := range []string{p.Overwrite, p.Width, p.Mode, p.ModeArg} {
+		if v != "" {
+			args = append(args, v)
+		}
+	}
+	return append(args, p.Options...)
+}
+
+// withOverrides returns p with field=value overrides applied and extra
+// --options appended (later options win).
+func (p preset) withOverrides(overrides []string) (preset, error) {
+	positional, options, err := separateOptions(overrides)
+	if err != nil {
+		return p, err
+	}
+	p.Options = append(append([]string{}, p.Options...), options...)
+
+	for _, o := range positional {
+		field, value, ok := strings.Cut(o, "=")
+		if !ok {
+			return p, fmt.Errorf("invalid override %q (expected field=value with field one of %s)", o, strings.Join(presetFields, ", "))
+		}
+		switch strings.ToLower(field) {
+		case "lines":
+			p.Lines = value
+		case "filename":
+			p.Filename = value
+		case "overwrite":
+			p.Overwrite = value
+		case "width":
+			p.Width = value
+		case "mode":
+			p.Mode = value
+		case "modearg":
+			p.ModeArg = value
+		default:
+			return p, fmt.Errorf("unknown preset field %q (expected one of %s)", field, strings.Join(presetFields, ", "))
+		}
+	}
+	return p, p.validate()
+}
+
+// validPresetName reports whether name can be used as a preset name.
+func validPresetName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\r\n=") && !strings.HasPrefix(name, "-")
+}
+
+// runPresetCmd handles "preset save|run|list|delete" and returns the exit code.
+func runPresetCmd(args []string) int {
+	fail := func(err error) int {
+		cli.Error("%v", err)
+		return 1
+	}
+	if len(args) == 0 {
+		cli.Error("preset requires one of: save, run, list, delete")
+		cli.Hint(helpHint())
+		return 1
+	}
+
+	path, err := presetsPath()
+	if err != nil {
+		return fail(err)
+	}
+	presets, err := loadPresets(path)
+	if err != nil {
+		return fail(err)
+	}
+
+	sub, args := strings.ToLower(args[0]), args[1:]
+	if sub == "list" {
+		names := make([]string, 0, len(presets))
+		for name := range presets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			cli.Info("No presets saved (%s)", path)
+		}
+		for _, name := range names {
+			fmt.Printf("%s: %s\n", name, strings.Join(presets[name].args(), " "))
+		}
+		return 0
+	}
+
+	if len(args) == 0 || !validPresetName(args[0]) {
+		return fail(fmt.Errorf("preset %s requires a name (no spaces, '=' or leading '-')", sub))
+	}
+	name, args := args[0], args[1:]
+
+	switch sub {
+	case "save":
+		p, err := newPreset(args)
+		if err != nil {
+			return fail(err)
+		}
+		_, replaced := presets[name]
+		presets[name] = p
+		if err := savePresets(path, presets); err != nil {
+			return fail(err)
+		}
+		verb := "Saved"
+		if replaced {
+			verb = "Replaced"
+		}
+		cli.Info("%s preset %s: %s", verb, name, strings.Join(p.args(), " "))
+		return 0
+
+	case "run":
+		p, ok := presets[name]
+		if !ok {
+			return fail(fmt.Errorf("no preset named %q (see: %s preset list)", name, cmdName))
+		}
+		p, err := p.withOverrides(args)
+		if err != nil {
+			return fail(err)
+		}
+		return run(p.args())
+
+	case "delete":
+		if _, ok := presets[name]; !ok {
+			return fail(fmt.Errorf("no preset named %q", name))
+		}
+		delete(presets, name)
+		if err := savePresets(path, presets); err != nil {
+			return fail(err)
+		}
+		cli.Info("Deleted preset %s", name)
+		return 0
+	}
+	return fail(fmt.Errorf("unknown preset command %q (expected save, run, list or delete)", sub))
+}
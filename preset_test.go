@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withConfigDir points the preset store at a fresh temp directory.
+func withConfigDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv(configDirEnv, dir)
+	return dir
+}
+
+func TestPreset_SaveRunListDelete(t *testing.T) {
+	cfg := withConfigDir(t)
+	out := t.TempDir()
+	path := filepath.Join(out, "a.txt")
+
+	if code := run([]string{"preset", "save", "small", "5", path, "y", "12", "digits", "--comment-every", "2"}); code != 0 {
+		t.Fatalf("preset save exited with %d", code)
+	}
+	presets, err := loadPresets(filepath.Join(cfg, "presets.json"))
+	if err != nil {
+		t.Fatalf("loadPresets: %v", err)
+	}
+	want := preset{Lines: "5", Filename: path, Overwrite: "y", Width: "12", Mode: "digits", Options: []string{"--comment-every=2"}}
+	if got := presets["small"]; strings.Join(got.args(), " ") != strings.Join(want.args(), " ") {
+		t.Errorf("saved preset = %+v, want %+v", got, want)
+	}
+
+	if code := run([]string{"preset", "run", "small"}); code != 0 {
+		t.Fatalf("preset run exited with %d", code)
+	}
+	data, _ := os.ReadFile(path)
+	if got := strings.Count(string(data), "\n"); got != 5+2 {
+		t.Errorf("preset run wrote %d lines, want 5 data + 2 comment lines", got)
+	}
+
+	// Overrides replace single fields; the rest comes from the preset.
+	other := filepath.Join(out, "b.txt")
+	if code := run([]string{"preset", "run", "small", "filename=" + other, "lines=3", "--comment-every", "10"}); code != 0 {
+		t.Fatalf("preset run with overrides exited with %d", code)
+	}
+	data, _ = os.ReadFile(other)
+	if string(data) != "012345678901\n234567890123\n456789012345\n" {
+		t.Errorf("overridden run wrote %q", data)
+	}
+
+	if code := run([]string{"preset", "list"}); code != 0 {
+		t.Fatalf("preset list exited with %d", code)
+	}
+	if code := run([]string{"preset", "delete", "small"}); code != 0 {
+		t.Fatalf("preset delete exited with %d", code)
+	}
+	presets, _ = loadPresets(filepath.Join(cfg, "presets.json"))
+	if len(presets) != 0 {
+		t.Errorf("presets after delete = %v", presets)
+	}
+	if code := run([]string{"preset", "run", "small"}); code == 0 {
+		t.Error("running a deleted preset should fail")
+	}
+}
+
+func TestPreset_RejectsInvalid(t *testing.T) {
+	withConfigDir(t)
+	for _, args := range [][]string{
+		{"preset", "save", "p", "5"},
+		{"preset", "save", "p", "five", "out.txt"},
+		{"preset", "save", "p", "5", "out.txt", "80", "digitz"},
+		{"preset", "save", "p", "5", "out.txt", "--bogus"},
+		{"preset", "save", "bad name", "5", "out.txt"},
+		{"preset", "frobnicate", "p"},
+	} {
+		if code := run(args); code == 0 {
+			t.Errorf("run(%q) succeeded, want failure", args)
+		}
+	}
+
+	if code := run([]string{"preset", "save", "p", "5", "out.txt"}); code != 0 {
+		t.Fatalf("preset save exited with %d", code)
+	}
+	if code := run([]string{"preset", "run", "p", "colour=blue"}); code == 0 {
+		t.Error("unknown override field should fail")
+	}
+}
+
+func TestPreset_CorruptFile(t *testing.T) {
+	cfg := withConfigDir(t)
+	file := filepath.Join(cfg, "presets.json")
+	os.WriteFile(file, []byte("{not json"), 0644)
+
+	_, err := loadPresets(file)
+	if err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Fatalf("loadPresets error = %v, want a corrupt-file error", err)
+	}
+	if code := run([]string{"preset", "list"}); code != 1 {
+		t.Errorf("preset list on a corrupt file exited with %d, want 1", code)
+	}
+}
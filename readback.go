@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// exitVerifyFailed is the exit code when --verify-after finds the output
+// differs from what was generated.
+const exitVerifyFailed = 4
+
+// beforeVerify, when set, runs after the output is closed and before it is
+// read back. Tests use it to corrupt files.
+var beforeVerify func(paths []string)
+
+// mismatchError reports the first byte offset at which read-back content
+// differs from the generated content.
+type mismatchError struct {
+	offset int64
+	short  bool // the read-back content ended early
+}
+
+func (e *mismatchError) Error() string {
+	if e.short {
+		return fmt.Sprintf("content ends early at byte offset %d", e.offset)
+	}
+	return fmt.Sprintf("content differs at byte offset %d", e.offset)
+}
+
+// compareWriter checks everything written to it against the bytes read from r.
+type compareWriter struct {
+	r   *bufio.Reader
+	buf []byte
+	off int64
+}
+
+func newCompareWriter(r io.Reader) *compareWriter {
+	return &compareWriter{r: bufio.NewReaderSize(r, 64*1024), buf: make([]byte, 64*1024)}
+}
+
+func (c *compareWriter) Write(p []byte) (int, error) {
+	done := 0
+	for done < len(p) {
+		chunk := p[done:min(len(p), done+len(c.buf))]
+		n, err := io.ReadFull(c.r, c.buf[:len(chunk)])
+		for i := 0; i < n; i++ {
+			if c.buf[i] != chunk[i] {
+				return done + i, &mismatchError{offset: c.off + int64(i)}
+			}
+		}
+		c.off += int64(n)
+		done += n
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return done, &mismatchError{offset: c.off, short: true}
+		}
+		if err != nil {
+			return done, err
+		}
+	}
+	return done, nil
+}
+
+// compareGenerated streams a fresh run of opts against r with constant
+// memory. It returns a *mismatchError if r differs, including when it holds
+// more bytes than the run produces.
+func compareGenerated(r io.Reader, opts genlines.Options) error {
+	opts.Progress = nil
+	opts.PiDigits = nil // consumed by the run being checked
+	cw := newCompareWriter(r)
+	if _, _, err := genlines.GenerateTo(context.Background(), cw, opts); err != nil {
+		var mm *mismatchError
+		if errors.As(err, &mm) {
+			return mm
+		}
+		return err
+	}
+	if _, err := cw.r.ReadByte(); err == nil {
+		return &mismatchError{offset: cw.off}
+	} else if err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// verifyFile reads back path from byte offset start and checks it against the
+// content opts generates. When wantSum (the SHA-256 recorded while writing) is
+// set, the file is hashed first and only regenerated to locate a mismatch.
+func verifyFile(path string, start int64, opts genlines.Options, wantSum string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	if wantSum != "" {
+		sum := sha256.New()
+		if _, err := io.Copy(sum, f); err != nil {
+			return err
+		}
+		if hex.EncodeToString(sum.Sum(nil)) == wantSum {
+			return nil
+		}
+		if _, err := f.Seek(start, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	return compareGenerated(f, opts)
+}
+
+// verifySplit reads back the part files of a split run in order and checks
+// them against a single run of opts. Every part's recorded SHA-256 is checked
+// first; the content is only regenerated to locate a mismatch, which is
+// reported relative to the part that holds it.
+func verifySplit(names []string, parts []genlines.Part, sums []string, opts genlines.Options) error {
+	intact := true
+	for i, name := range names {
+		got, err := fileSHA256Hex(name)
+		if err != nil {
+			return err
+		}
+		if got != sums[i] {
+			intact = false
+			break
+		}
+	}
+	if intact {
+		return nil
+	}
+
+	readers := make([]io.Reader, len(names))
+	for i, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		readers[i] = f
+	}
+	err := compareGenerated(io.MultiReader(readers...), opts)
+	var mm *mismatchError
+	if !errors.As(err, &mm) {
+		return err
+	}
+	off := mm.offset
+	for i, p := range parts {
+		if off < p.Bytes || i == len(parts)-1 {
+			return fmt.Errorf("%s: %w", names[i], &mismatchError{offset: off, short: mm.short})
+		}
+		off -= p.Bytes
+	}
+	return mm
+}
+
+// isMismatch reports whether err is a read-back mismatch.
+func isMismatch(err error) bool {
+	var mm *mismatchError
+	return errors.As(err, &mm)
+}
+
+// fileSHA256Hex returns the hex SHA-256 of the file at path.
+func fileSHA256Hex(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sum := sha256.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// reportVerify prints the outcome of a read-back check and returns the exit
+// code for the run.
+func reportVerify(what string, err error) int {
+	switch {
+	case err == nil:
+		cli.Summary("Verified %s: read-back content matches the generated content", what)
+		return 0
+	case isMismatch(err):
+		cli.Error("verification FAILED: %s: %v", what, err)
+		return exitVerifyFailed
+	default:
+		cli.Error("verifying output: %v", err)
+		return 1
+	}
+}
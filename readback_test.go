@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// withBeforeVerify installs hook as the read-back test hook for the duration of the test.
+func withBeforeVerify(t *testing.T, hook func(paths []string)) {
+	t.Helper()
+	old := beforeVerify
+	beforeVerify = hook
+	t.Cleanup(func() { beforeVerify = old })
+}
+
+// flipByte changes the byte at off in path.
+func flipByte(t *testing.T, path string, off int64) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[off] ^= 0x01
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompareGenerated(t *testing.T) {
+	opts := genlines.Options{Lines: 20, Width: 10, Mode: "digits"}
+	var want bytes.Buffer
+	if _, _, err := genlines.GenerateTo(t.Context(), &want, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := compareGenerated(bytes.NewReader(want.Bytes()), opts); err != nil {
+		t.Fatalf("identical content: %v", err)
+	}
+
+	changed := bytes.Clone(want.Bytes())
+	changed[123] = 'x'
+	var mm *mismatchError
+	if err := compareGenerated(bytes.NewReader(changed), opts); !errors.As(err, &mm) || mm.offset != 123 || mm.short {
+		t.Errorf("changed byte: got %v, want a mismatch at 123", err)
+	}
+	if err := compareGenerated(bytes.NewReader(want.Bytes()[:150]), opts); !errors.As(err, &mm) || mm.offset != 150 || !mm.short {
+		t.Errorf("truncated: got %v, want a short read at 150", err)
+	}
+	if err := compareGenerated(bytes.NewReader(append(want.Bytes(), 'z')), opts); !errors.As(err, &mm) || mm.offset != int64(want.Len()) {
+		t.Errorf("extra byte: got %v, want a mismatch at %d", err, want.Len())
+	}
+}
+
+func TestRun_VerifyAfter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+	if code := run([]string{"100", path, "y", "40", "random", "7", "--verify-after"}); code != 0 {
+		t.Fatalf("intact file: exit code %d", code)
+	}
+
+	for _, extra := range [][]string{nil, {"--manifest", filepath.Join(dir, "m.json")}} {
+		withBeforeVerify(t, func(paths []string) { flipByte(t, paths[0], 2000) })
+		args := append([]string{"100", path, "y", "40", "random", "7", "--verify-after"}, extra...)
+		if code := run(args); code != exitVerifyFailed {
+			t.Errorf("%q: corrupted file gave exit code %d, want %d", extra, code, exitVerifyFailed)
+		}
+	}
+	if fileExists(filepath.Join(dir, "m.json")) {
+		t.Errorf("manifest written for a run that failed verification")
+	}
+}
+
+func TestRun_VerifyAfterAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	if err := os.WriteFile(path, []byte("old line"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"10", path, "20", "digits", "--append", "--verify-after"}); code != 0 {
+		t.Fatalf("append: exit code %d", code)
+	}
+}
+
+func TestRun_VerifyAfterSplit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+	if code := run([]string{"30", path, "y", "10", "ascii", "--split-lines", "10", "--verify-after"}); code != 0 {
+		t.Fatalf("intact parts: exit code %d", code)
+	}
+
+	withBeforeVerify(t, func(paths []string) { flipByte(t, paths[1], 5) })
+	if code := run([]string{"30", path, "y", "10", "ascii", "--split-lines", "10", "--verify-after"}); code != exitVerifyFailed {
+		t.Fatalf("corrupted part: exit code %d, want %d", code, exitVerifyFailed)
+	}
+}
+
+func TestVerifySplit_ReportsPartAndOffset(t *testing.T) {
+	dir := t.TempDir()
+	opts := genlines.Options{Lines: 30, Width: 10}
+	var (
+		names []string
+		parts []genlines.Part
+		sums  []string
+	)
+	written, err := genlines.GenerateSplit(t.Context(), opts, 10, func(i int) (io.WriteCloser, error) {
+		name := filepath.Join(dir, fmt.Sprintf("part-%d.txt", i))
+		names = append(names, name)
+		return os.Create(name)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts = written
+	for _, name := range names {
+		sum, err := fileSHA256Hex(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sums = append(sums, sum)
+	}
+
+	if err := verifySplit(names, parts, sums, opts); err != nil {
+		t.Fatalf("intact parts: %v", err)
+	}
+	flipByte(t, names[1], 5)
+	err = verifySplit(names, parts, sums, opts)
+	if !isMismatch(err) || !strings.HasSuffix(err.Error(), "part-2.txt: content differs at byte offset 5") {
+		t.Errorf("got %v, want a mismatch in part-2.txt at offset 5", err)
+	}
+}
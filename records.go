@@ -0,0 +1,19 @@
+package main
+
+import "bytes"
+
+// isRecordMode reports whether mode writes records that may span several
+// physical lines.
+func isRecordMode(mode string) bool {
+	return mode == "csv" || mode == "jsonl"
+}
+
+// newlineCounter counts the physical lines (LF bytes) written through it.
+type newlineCounter struct {
+	n int64
+}
+
+func (c *newlineCounter) Write(p []byte) (int, error) {
+	c.n += int64(bytes.Count(p, []byte{'\n'}))
+	return len(p), nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// defaultSampleCount is how many lines "sample" prints without a count.
+const defaultSampleCount = 5
+
+// parseSampleArgs parses "sample <width> <mode> [modeArg] [count]" (without the
+// leading "sample") into the options of a run whose first lines are the
+// sample. A modeArg of "-" stands for none, so a count can follow it.
+func parseSampleArgs(args []string, flags cliFlags) (genlines.Options, error) {
+	if len(args) < 2 || len(args) > 4 {
+		return genlines.Options{}, errors.New("sample requires <width> <mode> [modeArg] [count]")
+	}
+
+	width, err := parsePositiveInt(args[0])
+	if err != nil {
+		if !isTermWidth(args[0]) {
+			return genlines.Options{}, fmt.Errorf("invalid width: %q", args[0])
+		}
+		if width, err = resolveTermWidth(args[0]); err != nil {
+			return genlines.Options{}, err
+		}
+	}
+	mode, implied, err := normalizeMode(args[1])
+	if err != nil {
+		return genlines.Options{}, err
+	}
+
+	modeArg := ""
+	if len(args) >= 3 && args[2] != "-" {
+		modeArg = args[2]
+	}
+	if modeArg, err = mergeModeArg(implied, modeArg); err != nil {
+		return genlines.Options{}, err
+	}
+	if mode == "char" {
+		if modeArg, err = decodeModeArg(modeArg, flags.allowControl); err != nil {
+			return genlines.Options{}, err
+		}
+	}
+
+	count := defaultSampleCount
+	if len(args) == 4 {
+		if count, err = parsePositiveInt(args[3]); err != nil {
+			return genlines.Options{}, fmt.Errorf("invalid sample count: %q (expected a positive integer)", args[3])
+		}
+	}
+
+	return genlines.Options{
+		Lines:          count,
+		Width:          width,
+		Mode:           mode,
+		ModeArg:        modeArg,
+		CommentEvery:   flags.commentEvery,
+		CommentText:    flags.commentText,
+		LineChecksum:   flags.lineChecksum,
+		EscapeNonASCII: flags.escapeASCII,
+		Rot:            flags.rot,
+		TrailingWS:     flags.trailingWS,
+		LinePattern:    flags.linePattern,
+		InjectUnicode:  flags.inject,
+		StartOffset:    flags.startOffset,
+		SafeStart:      flags.safeStart,
+		ByteRange:      flags.byteRange,
+		Unsafe:         flags.unsafe,
+		Ramp:           flags.ramp,
+		Seed:           flags.seed,
+		HasSeed:        flags.seedSet,
+	}, nil
+}
+
+// runSampleCmd handles "sample": it prints the first lines a run with the
+// given settings would write, from a generator of its own, and writes no file.
+// If stdout is closed before the count is reached, it stops quietly.
+func runSampleCmd(args []string, flags cliFlags) int {
+	// stdout carries the sample: every message goes to stderr.
+	cli.dataOut = true
+	opts, err := parseSampleArgs(args, flags)
+	if err != nil {
+		cli.Error("%v", err)
+		cli.Hint(helpHint())
+		return 1
+	}
+	if err := checkANSITarget(opts.Mode, isTerminal(os.Stdout), flags.forceANSI); err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+	if !opts.HasSeed && (opts.Mode == "random" || opts.Mode == "hashfill") && strings.TrimSpace(opts.ModeArg) == "" {
+		opts.ModeArg = strconv.FormatUint(newSeed(), 10)
+		cli.Info("mode=%s: no seed given, sampling with seed %s", opts.Mode, opts.ModeArg)
+	}
+	// A reader that stops early, like "head", closes the pipe: that ends the
+	// sample, not in failure. Ignoring SIGPIPE turns the signal Go would die
+	// of on stdout into an EPIPE write error.
+	signal.Ignore(syscall.SIGPIPE)
+	lines, _, err := genlines.GenerateTo(context.Background(), os.Stdout, opts)
+	if errors.Is(err, genlines.ErrOutputClosed) {
+		cli.Info("%s", text("output.closed", lines))
+		return 0
+	}
+	if err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+	return 0
+}
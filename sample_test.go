@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSample_MatchesHeadOfRealRun(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		sample []string
+		run    []string
+	}{
+		{[]string{"sample", "40", "pi"}, []string{"100", "pi.txt", "y", "40", "pi"}},
+		{[]string{"sample", "25", "ascii", "-", "8"}, []string{"100", "ascii.txt", "y", "25", "ascii"}},
+		{[]string{"sample", "30", "random", "42", "3"}, []string{"100", "random.txt", "y", "30", "random", "42"}},
+		{[]string{"sample", "20", "dates", "2006-01-02|24h"}, []string{"100", "dates.txt", "y", "20", "dates", "2006-01-02|24h"}},
+		{[]string{"sample", "30", "digits", "-", "4", "--line-checksum"}, []string{"100", "sum.txt", "y", "30", "digits", "--line-checksum"}},
+	} {
+		read := captureStdout(t)
+		if code := run(tt.sample); code != 0 {
+			t.Fatalf("%q: exit code %d", tt.sample, code)
+		}
+		sampled := read()
+
+		tt.run[1] = filepath.Join(dir, tt.run[1])
+		if code := run(tt.run); code != 0 {
+			t.Fatalf("%q: exit code %d", tt.run, code)
+		}
+		data, err := os.ReadFile(tt.run[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(data), sampled) || sampled == "" {
+			t.Errorf("%q: sample %q is not the head of the real run", tt.sample, sampled)
+		}
+		want := defaultSampleCount
+		if len(tt.sample) > 4 {
+			want, _ = parsePositiveInt(tt.sample[4])
+		}
+		if got := strings.Count(sampled, "\n"); got != want {
+			t.Errorf("%q: %d lines sampled, want %d", tt.sample, got, want)
+		}
+	}
+}
+
+func TestParseSampleArgs_Errors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"80"},
+		{"wide", "ascii"},
+		{"80", "digitz"},
+		{"80", "ascii", "-", "0"},
+		{"80", "ascii", "-", "5", "extra"},
+		{"80", "char", "\t"},
+	} {
+		if _, err := parseSampleArgs(args, cliFlags{}); err == nil {
+			t.Errorf("%q: expected an error", args)
+		}
+	}
+}
+
+// pipeStdoutToHead points os.Stdout at a pipe whose reader, like "head -n",
+// closes it after n lines, which it then sends.
+func pipeStdoutToHead(t *testing.T, n int) <-chan string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	t.Cleanup(func() {
+		os.Stdout = old
+		w.Close()
+	})
+	head := make(chan string, 1)
+	go func() {
+		sc := bufio.NewScanner(r)
+		var got []string
+		for len(got) < n && sc.Scan() {
+			got = append(got, sc.Text())
+		}
+		r.Close()
+		head <- strings.Join(got, "\n")
+	}()
+	return head
+}
+
+func TestRunSample_StdoutClosedEarly(t *testing.T) {
+	head := pipeStdoutToHead(t, 5)
+	errOut := captureStderr(t)
+
+	if code := run([]string{"sample", "80", "ascii", "-", "1000000"}); code != 0 {
+		t.Fatalf("exit code %d, want 0", code)
+	}
+	if lines := strings.Count(<-head, "\n") + 1; lines != 5 {
+		t.Errorf("head read %d lines", lines)
+	}
+	if msg := errOut(); !strings.Contains(msg, "Output closed after ") || strings.Contains(msg, "Error") {
+		t.Errorf("stderr %q", msg)
+	}
+}
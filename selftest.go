@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+const (
+	// selftestLines and selftestWidth size the sample generated for every mode.
+	selftestLines = 25
+	selftestWidth = 40
+
+	// blockPrefix starts every cell of mode=blocks.
+	blockPrefix = "\x1b[48;5;"
+)
+
+// selftestCase is what the self-test knows about a built-in mode beyond the
+// registry: a modeArg to run it with and the invariants of its lines.
+type selftestCase struct {
+	arg   string
+	file  string                     // written to a file in dir, whose path fills %s in arg
+	split func(out string) []string  // records of the output; default splitLines
+	width func(line string) int      // visible width; default len
+	check func(lines []string) error // nil: counts and widths only
+}
+
+// splitLines splits newline-terminated output into its lines, or returns nil
+// if the output does not end with a terminator.
+func splitLines(out string) []string {
+	if !strings.HasSuffix(out, "\n") {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+}
+
+// splitRecords returns a split of output into records of size bytes.
+func splitRecords(size int) func(string) []string {
+	return func(out string) []string {
+		var records []string
+		for ; len(out) >= size; out = out[size:] {
+			records = append(records, out[:size])
+		}
+		if out != "" {
+			return nil
+		}
+		return records
+	}
+}
+
+// everyLine returns a check that applies ok to every line.
+func everyLine(what string, ok func(line string) bool) func([]string) error {
+	return func(lines []string) error {
+		for i, line := range lines {
+			if !ok(line) {
+				return fmt.Errorf("line %d is not %s: %q", i+1, what, line)
+			}
+		}
+		return nil
+	}
+}
+
+// onlyRunes reports whether every rune of s satisfies ok.
+func onlyRunes(s string, ok func(r rune) bool) bool {
+	for _, r := range s {
+		if !ok(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPrintableASCII reports whether r is in the range 32-126.
+func isPrintableASCII(r rune) bool { return r >= ' ' && r <= '~' }
+
+var selftestCases = map[string]selftestCase{
+	"ascii": {check: everyLine("printable ASCII", func(l string) bool { return onlyRunes(l, isPrintableASCII) })},
+	"digits": {check: everyLine("digits only", func(l string) bool {
+		return onlyRunes(l, func(r rune) bool { return r >= '0' && r <= '9' })
+	})},
+	"upper": {check: everyLine("A-Z only", func(l string) bool {
+		return onlyRunes(l, func(r rune) bool { return r >= 'A' && r <= 'Z' })
+	})},
+	"alpha": {check: everyLine("letters only", func(l string) bool {
+		return onlyRunes(l, func(r rune) bool { return r < unicode.MaxASCII && unicode.IsLetter(r) })
+	})},
+	"char":   {arg: "#", check: everyLine("all #", func(l string) bool { return strings.Trim(l, "#") == "" })},
+	"random": {check: everyLine("printable ASCII", func(l string) bool { return onlyRunes(l, isPrintableASCII) })},
+	"noise":  {check: everyLine("printable ASCII", func(l string) bool { return onlyRunes(l, isPrintableASCII) })},
+	"hashfill": {arg: "selftest", check: func(lines []string) error {
+		for i, line := range lines {
+			if want := genlines.LineFor("selftest", i, selftestWidth); line != want {
+				return fmt.Errorf("line %d differs from LineFor: %q", i+1, line)
+			}
+		}
+		return nil
+	}},
+	"dates": {check: everyLine("an RFC 3339 timestamp", func(l string) bool {
+		_, err := time.Parse(time.RFC3339, strings.TrimRight(l, " "))
+		return err == nil
+	})},
+	"ip": {check: everyLine("an IP address", func(l string) bool {
+		return net.ParseIP(strings.TrimRight(l, " ")) != nil
+	})},
+	"words": {arg: "alpha,beta,gamma", check: everyLine("dictionary words", func(l string) bool {
+		return onlyRunes(l, func(r rune) bool { return strings.ContainsRune("abeglmpht ", r) }) && !strings.Contains(l, "  ")
+	})},
+	"lorem": {check: everyLine("lowercase words", func(l string) bool {
+		return onlyRunes(l, func(r rune) bool { return r == ' ' || (r >= 'a' && r <= 'z') })
+	})},
+	"csv": {check: func(lines []string) error {
+		r := csv.NewReader(strings.NewReader(strings.Join(lines, "\n")))
+		records, err := r.ReadAll()
+		if err != nil {
+			return err
+		}
+		for i, rec := range records {
+			if len(rec) != genlines.DefaultCSVColumns {
+				return fmt.Errorf("record %d has %d fields, want %d", i+1, len(rec), genlines.DefaultCSVColumns)
+			}
+			for _, field := range rec {
+				if strings.ContainsAny(field[:1], "=+-@") {
+					return fmt.Errorf("record %d has a formula field %q", i+1, field)
+				}
+			}
+		}
+		return nil
+	}},
+	"jsonl": {check: everyLine("a JSON object", func(l string) bool {
+		var v struct {
+			ID   int    `json:"id"`
+			Text string `json:"text"`
+		}
+		return json.Unmarshal([]byte(l), &v) == nil && v.ID > 0
+	})},
+	"template": {arg: "inline:{{Fill .Width}}", check: everyLine("printable ASCII", func(l string) bool {
+		return onlyRunes(l, isPrintableASCII)
+	})},
+	"blocks": {
+		arg:   "digits",
+		width: func(l string) int { return strings.Count(l, blockPrefix) },
+		check: everyLine("color cells", func(l string) bool {
+			return strings.HasPrefix(l, blockPrefix) && strings.HasSuffix(l, "\x1b[0m")
+		}),
+	},
+	"binrec": {
+		arg:   "u32be:counter,bytes:36:cycle",
+		split: splitRecords(selftestWidth),
+		check: func(records []string) error {
+			for i, rec := range records {
+				if n := binary.BigEndian.Uint32([]byte(rec)); n != uint32(i+1) {
+					return fmt.Errorf("record %d has counter %d", i+1, n)
+				}
+				if !onlyRunes(rec[4:], isPrintableASCII) {
+					return fmt.Errorf("record %d has non-ASCII cycle bytes: %q", i+1, rec[4:])
+				}
+			}
+			return nil
+		},
+	},
+	"palette-file": {file: "\n\x00\x7f\x80\xfe\xff", arg: "%s:1:5", check: func(lines []string) error {
+		all := strings.Join(lines, "")
+		if want := strings.Repeat("\x00\x7f\x80\xfe\xff", len(all)/5); all != want {
+			return errors.New("does not cycle bytes 1-5 of its palette file")
+		}
+		return nil
+	}},
+	"pi": {check: func(lines []string) error {
+		if !strings.HasPrefix(lines[0], "3141592653589793") {
+			return fmt.Errorf("does not start with the digits of pi: %q", lines[0])
+		}
+		return nil
+	}},
+}
+
+// errSkipped marks a mode the self-test cannot run.
+var errSkipped = errors.New("skipped")
+
+// selftestMode generates a sample of mode in memory and through a file in
+// dir, and checks it. It returns a short description of what was checked.
+func selftestMode(mode, dir string) (string, error) {
+	_, spec, err := genlines.LookupMode(mode)
+	if err != nil {
+		return "", err
+	}
+	tc, known := selftestCases[mode]
+	if !known && spec.RequiresArg {
+		return "needs a modeArg the self-test does not know", errSkipped
+	}
+	opts := genlines.Options{
+		Lines:   selftestLines,
+		Width:   selftestWidth,
+		Mode:    mode,
+		ModeArg: tc.arg,
+		Seed:    1,
+		HasSeed: true,
+	}
+	if tc.file != "" {
+		path := filepath.Join(dir, mode+".in")
+		if err := os.WriteFile(path, []byte(tc.file), 0644); err != nil {
+			return "", err
+		}
+		opts.ModeArg = fmt.Sprintf(tc.arg, path)
+	}
+
+	var buf bytes.Buffer
+	lines, size, err := genlines.GenerateTo(context.Background(), &buf, opts)
+	if err != nil {
+		return "", err
+	}
+	if lines != selftestLines {
+		return "", fmt.Errorf("wrote %d lines, want %d", lines, selftestLines)
+	}
+	if planned, err := genlines.PlanSize(opts); err == nil && planned != size {
+		return "", fmt.Errorf("wrote %d bytes, planned %d", size, planned)
+	}
+
+	split := tc.split
+	if split == nil {
+		split = splitLines
+	}
+	got := split(buf.String())
+	if len(got) != selftestLines {
+		return "", fmt.Errorf("output has %d lines, want %d", len(got), selftestLines)
+	}
+	width := tc.width
+	if width == nil {
+		width = func(l string) int { return len(l) }
+	}
+	for i, line := range got {
+		if w := width(line); w != selftestWidth {
+			return "", fmt.Errorf("line %d is %d columns, want %d", i+1, w, selftestWidth)
+		}
+	}
+	if tc.check != nil {
+		if err := tc.check(got); err != nil {
+			return "", err
+		}
+	}
+
+	// The same run through the filesystem must give the same bytes, or for
+	// noise, as many different ones.
+	path := filepath.Join(dir, mode+".txt")
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	_, _, err = genlines.GenerateTo(context.Background(), f, opts)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("writing %s: %w", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !genlines.Reproducible(opts) {
+		if len(data) != buf.Len() || bytes.Equal(data, buf.Bytes()) {
+			return "", errors.New("the file does not hold a different sample of the same size")
+		}
+	} else if !bytes.Equal(data, buf.Bytes()) {
+		return "", errors.New("the file differs from the in-memory sample")
+	}
+
+	detail := fmt.Sprintf("%d lines × %d columns, %d bytes", selftestLines, selftestWidth, size)
+	if tc.check == nil {
+		detail += " (no content checks)"
+	}
+	return detail, nil
+}
+
+// runSelftestCmd handles "selftest": it checks every registered mode and
+// prints a PASS/FAIL table, returning 1 if any mode fails.
+func runSelftestCmd(args []string) int {
+	if len(args) != 0 {
+		cli.Error("selftest takes no arguments")
+		return 1
+	}
+	dir, err := os.MkdirTemp("", "generatelines-selftest-")
+	if err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	cli.Info("Self-test of generatelines %s (%s/%s, %s)", version, runtime.GOOS, runtime.GOARCH, runtime.Version())
+	modes := genlines.ModeNames()
+	col := len("MODE")
+	for _, mode := range modes {
+		col = max(col, len(mode))
+	}
+	cli.Info("%-*s %-6s %s", col, "MODE", "RESULT", "DETAIL")
+	failed := 0
+	for _, mode := range modes {
+		detail, err := selftestMode(mode, dir)
+		switch {
+		case errors.Is(err, errSkipped):
+			cli.Info("%-*s %s %s", col, mode, cli.highlight(ansiYellow, fmt.Sprintf("%-6s", "SKIP")), detail)
+		case err != nil:
+			failed++
+			cli.Info("%-*s %s %v", col, mode, cli.highlight(ansiRed, fmt.Sprintf("%-6s", "FAIL")), err)
+		default:
+			cli.Info("%-*s %s %s", col, mode, cli.highlight(ansiGreen, fmt.Sprintf("%-6s", "PASS")), detail)
+		}
+	}
+
+	if failed > 0 {
+		cli.Error("self-test FAILED: %d of %d modes", failed, len(modes))
+		return 1
+	}
+	cli.Summary("Self-test passed: %d modes", len(modes))
+	return 0
+}
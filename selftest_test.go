@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+func TestRun_Selftest(t *testing.T) {
+	out := captureStdout(t)
+	if code := run([]string{"selftest"}); code != 0 {
+		t.Fatalf("selftest exited with %d:\n%s", code, out())
+	}
+	text := out()
+	// The RESULT column lines up under its heading, past the longest mode name.
+	col := strings.Index(text[strings.Index(text, "\nMODE "):], "RESULT") - 1
+	for _, mode := range genlines.ModeNames() {
+		if !strings.Contains(text, "\n"+mode+strings.Repeat(" ", col-len(mode))+"PASS") {
+			t.Errorf("table lacks an aligned PASS row for %s:\n%s", mode, text)
+		}
+		if _, known := selftestCases[mode]; !known {
+			t.Errorf("built-in mode %s has no self-test case", mode)
+		}
+	}
+}
+
+func TestRun_SelftestReportsFailures(t *testing.T) {
+	t.Cleanup(genlines.ResetModes)
+	genlines.RegisterMode("broken", genlines.ModeSpec{
+		Factory: func(string, int) (genlines.Generator, error) {
+			return nil, errors.New("mode=broken: always fails")
+		},
+	})
+	genlines.RegisterMode("needsarg", genlines.ModeSpec{
+		RequiresArg: true,
+		Factory: func(string, int) (genlines.Generator, error) {
+			return genlines.NewGenerator("digits", "", 0)
+		},
+	})
+
+	out := captureStdout(t)
+	captureStderr(t)
+	if code := run([]string{"selftest"}); code != 1 {
+		t.Errorf("selftest with a broken mode exited with %d, want 1", code)
+	}
+	text := out()
+	// The mode column is as wide as the longest name, palette-file.
+	for _, want := range []string{"broken       FAIL   mode=broken: always fails", "needsarg     SKIP"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("table lacks %q:\n%s", want, text)
+		}
+	}
+}
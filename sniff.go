@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// sniffLen is how many leading bytes of an output are checked against the
+// file type signatures (see genlines.Signatures).
+const sniffLen = 2
+
+// headRecorder keeps the first sniffLen bytes written through it.
+type headRecorder struct {
+	head []byte
+}
+
+func (h *headRecorder) Write(p []byte) (int, error) {
+	if n := sniffLen - len(h.head); n > 0 {
+		h.head = append(h.head, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// sniffable reports whether the start of the output of mode is checked
+// against the file type signatures. Records are meant to be read as records
+// (a jsonl file does start with "{"), and binrec output is not text.
+func sniffable(mode string) bool {
+	return mode != "binrec" && !isRecordMode(mode)
+}
+
+// warnSignature warns when head, the start of the output what, matches a
+// file type signature, unless --no-sniff-warning is given.
+func warnSignature(flags cliFlags, what string, head []byte) {
+	if flags.noSniffWarn {
+		return
+	}
+	if sig, ok := genlines.SniffSignature(head); ok {
+		cli.Warn("%s starts with %q, the signature of: %s; tools that sniff file types may misread it (--safe-start starts it with letters)",
+			what, sig.Prefix, sig.Name)
+	}
+}
+
+// reportSafeStart says how --safe-start changes the start of the output of
+// opts, so the start offset can be given again to reproduce it.
+func reportSafeStart(opts genlines.Options) error {
+	shift, shifted, err := genlines.SafeStartShift(opts)
+	switch {
+	case err != nil:
+		return err
+	case !shifted:
+		cli.Info("Safe start: mode=%s cannot be shifted to start with letters; the first characters of line 1 are replaced by letters if needed", opts.Mode)
+	case shift > 0:
+		cli.Info("Safe start: the content starts %d characters later (start offset %d)", shift, opts.StartOffset+shift)
+	}
+	return nil
+}
+
+// reportPalette prints the bytes the content of opts may hold once
+// --byte-range is applied, for --verbose.
+func reportPalette(opts genlines.Options) error {
+	palette, ok, err := genlines.EffectivePalette(opts)
+	switch {
+	case err != nil:
+		return err
+	case !ok:
+		cli.Verbose("Byte range %s: mode=%s fits in it", opts.ByteRange, opts.Mode)
+	default:
+		cli.Verbose("Byte range %s: palette %q (%d characters)", opts.ByteRange, palette, len(palette))
+	}
+	return nil
+}
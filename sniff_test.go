@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHeadRecorder(t *testing.T) {
+	var h headRecorder
+	for _, p := range []string{"", "P", "KZZ", "more"} {
+		if n, err := h.Write([]byte(p)); n != len(p) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", p, n, err)
+		}
+	}
+	if string(h.head) != "PK" {
+		t.Errorf("head %q, want \"PK\"", h.head)
+	}
+}
+
+func TestRun_SniffWarning(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		args []string
+		want string // "" = no warning
+	}{
+		{[]string{"3", filepath.Join(dir, "json.txt"), "y", "10", "char", "{"}, `starts with "{", the signature of: JSON object`},
+		{[]string{"3", filepath.Join(dir, "pdf.txt"), "y", "10", "words", "%PDF"}, `starts with "%P", the signature of: PDF document`},
+		{[]string{"4", filepath.Join(dir, "split.txt"), "y", "10", "char", "{", "--split-lines", "2"}, "split-001.txt starts with"},
+		{[]string{"3", filepath.Join(dir, "quiet.txt"), "y", "10", "char", "{", "--no-sniff-warning"}, ""},
+		{[]string{"3", filepath.Join(dir, "hash.txt"), "y", "10", "char", "#"}, ""},
+		{[]string{"3", filepath.Join(dir, "ascii.txt"), "y", "20", "ascii"}, ""},
+		{[]string{"3", filepath.Join(dir, "records.jsonl"), "y", "40", "jsonl"}, ""},
+		{[]string{"3", filepath.Join(dir, "records.csv"), "y", "40", "csv", "multiline=1"}, ""},
+	} {
+		output := captureStderr(t)
+		if code := run(tt.args); code != 0 {
+			t.Fatalf("%v exited with %d", tt.args, code)
+		}
+		got := output()
+		if tt.want == "" && strings.Contains(got, "WARNING") || !strings.Contains(got, tt.want) {
+			t.Errorf("%v: stderr %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRun_SafeStartRecordsShift(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "safe.txt")
+	errOutput := captureStderr(t)
+	output := captureStdout(t)
+	if code := run([]string{"5", path, "y", "20", "ascii", "--safe-start", "--meta"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if text := output(); !strings.Contains(text, "Safe start: the content starts 16 characters later (start offset 16)") {
+		t.Errorf("shift not reported:\n%s", text)
+	}
+	if text := errOutput(); strings.Contains(text, "WARNING") {
+		t.Errorf("unexpected warning: %s", text)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "0123456789") {
+		t.Errorf("output starts %q", data[:10])
+	}
+	m, err := readMeta(path + metaSuffix)
+	if err != nil || !m.SafeStart || m.SafeStartShift != 16 {
+		t.Fatalf("sidecar safeStart %v, shift %d, %v", m.SafeStart, m.SafeStartShift, err)
+	}
+	if code := run([]string{"regen", path + metaSuffix, filepath.Join(dir, "again.txt")}); code != 0 {
+		t.Errorf("regen exited with %d", code)
+	}
+	if code := run([]string{"5", filepath.Join(dir, "checked.txt"), "y", "10", "char", "<", "--safe-start", "--verify-after"}); code != 0 {
+		t.Errorf("--verify-after of a replaced start exited with %d", code)
+	}
+	if code := run([]string{"2", filepath.Join(dir, "blocks.txt"), "y", "4", "blocks", "--safe-start"}); code == 0 {
+		t.Error("--safe-start accepted mode=blocks")
+	}
+}
+
+func TestRun_ByteRangeVerbosePalette(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "digits.txt")
+	output := captureStdout(t)
+	if code := run([]string{"4", path, "y", "20", "ascii", "--byte-range", "0x30-0x39", "--verbose", "--meta"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if text := output(); !strings.Contains(text, `Byte range 0x30-0x39: palette "0123456789" (10 characters)`) {
+		t.Errorf("palette not printed:\n%s", text)
+	}
+	data, _ := os.ReadFile(path)
+	if want := strings.Repeat("01234567890123456789\n", 4); string(data) != want {
+		t.Errorf("output %q", data)
+	}
+	if m, err := readMeta(path + metaSuffix); err != nil || m.ByteRange != "0x30-0x39" {
+		t.Fatalf("sidecar byteRange %q, %v", m.ByteRange, err)
+	}
+	if code := run([]string{"regen", path + metaSuffix, filepath.Join(dir, "again.txt")}); code != 0 {
+		t.Errorf("regen exited with %d", code)
+	}
+	for _, args := range [][]string{
+		{"3", filepath.Join(dir, "csv.txt"), "y", "20", "csv", "--byte-range", "48-57"},
+		{"3", filepath.Join(dir, "empty.txt"), "y", "20", "digits", "--byte-range", "0x41-0x5a"},
+		{"3", filepath.Join(dir, "bad.txt"), "y", "20", "ascii", "--byte-range", "9-1"},
+	} {
+		if code := run(args); code == 0 {
+			t.Errorf("%v accepted", args)
+		}
+	}
+}
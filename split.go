@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// partVerb matches the single %d / %0Nd verb allowed in a --split-pattern.
+var partVerb = regexp.MustCompile(`%(0[1-9][0-9]?)?d`)
+
+// validateSplitPattern checks that pattern contains exactly one %d or %0Nd verb
+// and no other % directives.
+func validateSplitPattern(pattern string) error {
+	if n := len(partVerb.FindAllStringIndex(pattern, -1)); n != 1 {
+		return fmt.Errorf("split pattern %q must contain exactly one %%d or %%0Nd, found %d", pattern, n)
+	}
+	if strings.Contains(partVerb.ReplaceAllString(pattern, ""), "%") {
+		return fmt.Errorf("split pattern %q may only contain a single %%d or %%0Nd verb", pattern)
+	}
+	return nil
+}
+
+// defaultSplitPattern derives the part name pattern from filename:
+// out.txt becomes out-%03d.txt, zero-padded to at least three digits and
+// enough to keep the names sorted for the given number of parts.
+func defaultSplitPattern(filename string, parts int) string {
+	pad := max(3, len(strconv.Itoa(parts)))
+	ext := filepath.Ext(filename)
+	if ext == filepath.Base(filename) {
+		ext = "" // dotfile such as ".out"
+	}
+	stem := strings.TrimSuffix(filename, ext)
+	// A % in the name itself must not read as a verb.
+	esc := strings.NewReplacer("%", "%%")
+	return fmt.Sprintf("%s-%%0%dd%s", esc.Replace(stem), pad, esc.Replace(ext))
+}
+
+// partNames returns the file names of parts 1..parts.
+func partNames(pattern string, parts int) []string {
+	names := make([]string, parts)
+	for i := range names {
+		names[i] = fmt.Sprintf(pattern, i+1)
+	}
+	return names
+}
+
+// partRotation opens the part files of a split run one at a time. Opening a
+// part while the one before is still open is an error rather than another
+// handle, so a run of thousands of parts never holds more than one, and a
+// part that is not closed shows up at once instead of at the ulimit.
+type partRotation struct {
+	current string // name of the open part; "" = none
+	wipeOld bool   // wipe a part that already exists before truncating it
+}
+
+// open creates (or truncates) the part file name.
+func (r *partRotation) open(name string) (io.WriteCloser, error) {
+	if r.current != "" {
+		return nil, fmt.Errorf("cannot open %s: part %s is still open", name, r.current)
+	}
+	if r.wipeOld {
+		if err := wipeFile(name); err != nil {
+			return nil, fmt.Errorf("wiping %s: %w", name, err)
+		}
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	r.current = name
+	return &rotatedPart{File: f, rotation: r}, nil
+}
+
+// rotatedPart is an open part file of a partRotation.
+type rotatedPart struct {
+	*os.File
+	rotation *partRotation
+}
+
+// Close closes the part, freeing the rotation for the next one even if the
+// close fails; the error (an *os.PathError) names the part.
+func (p *rotatedPart) Close() error {
+	p.rotation.current = ""
+	return p.File.Close()
+}
+
+// splitParts returns how many parts lines split into with perPart lines each.
+func splitParts(lines, perPart int) int {
+	return (lines + perPart - 1) / perPart
+}
+
+// partSizes returns the exact byte size of each part, as the difference of
+// planned sizes at the part boundaries.
+func partSizes(opts genlines.Options, perPart, parts int) ([]int64, error) {
+	sizes := make([]int64, parts)
+	prev := int64(0)
+	for i := range sizes {
+		o := opts
+		o.Lines = min((i+1)*perPart, opts.Lines)
+		end, err := genlines.PlanSize(o)
+		if err != nil {
+			return nil, err
+		}
+		sizes[i], prev = end-prev, end
+	}
+	return sizes, nil
+}
+
+// runSplit generates opts into consecutive part files of flags.splitLines
+// lines each and returns the exit code. If filename is a .zip or .tar archive,
+// the parts become members of that archive instead.
+func runSplit(prompt *overwritePrompt, hist *runHistory, filename string, opts genlines.Options, flags cliFlags) int {
+	kind := archiveKind(filename)
+	pattern := flags.splitPattern
+	parts := splitParts(opts.Lines, flags.splitLines)
+	if pattern == "" {
+		if kind != "" {
+			stem := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+			pattern = defaultSplitPattern(stem+".txt", parts)
+		} else {
+			pattern = defaultSplitPattern(filename, parts)
+		}
+	}
+	names := partNames(pattern, parts)
+
+	// Files on disk: the parts themselves, or the archive as a whole.
+	targets := names
+	if kind != "" {
+		targets = []string{filename}
+		for _, name := range names {
+			if err := validateMemberName(name); err != nil {
+				cli.Error("%v", err)
+				return 1
+			}
+		}
+	}
+
+	if !guardTargets(flags, targets...) {
+		return 1
+	}
+
+	var existing []string
+	for _, name := range targets {
+		if fileExists(name) {
+			existing = append(existing, name)
+		}
+	}
+	// Ask part by part until an answer covers the rest; any refusal ends
+	// the run before a part is written.
+	for len(existing) > 0 && prompt.asks() {
+		overwrite, err := prompt.allow(text("overwrite.exists", existing[0]), len(existing) > 1)
+		if err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+		if !overwrite {
+			cli.Info("%s", text("overwrite.declined"))
+			hist.skip()
+			return 0
+		}
+		existing = existing[1:]
+	}
+	if len(existing) > 0 {
+		what := text("overwrite.exists", existing[0])
+		if len(existing) > 1 {
+			what = text("overwrite.partsExist", len(existing), existing[0])
+		}
+		if overwrite, _ := prompt.allow(what, false); !overwrite {
+			cli.Warn(text("overwrite.exiting"), what)
+			hist.skip()
+			return 0
+		}
+		cli.Warn(text("overwrite.overwriting"), what)
+	}
+
+	if parts == 0 && kind == "" {
+		cli.Info("%s", text("generate.noParts"))
+		return 0
+	}
+
+	var (
+		files   = make([]*hashingWriter, 0, parts)
+		create  func(index int) (io.WriteCloser, error)
+		archive archiveWriter
+		archF   *os.File
+	)
+	if kind != "" {
+		sizes, err := partSizes(opts, flags.splitLines, parts)
+		if err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+		if flags.wipeOld {
+			if err := wipeFile(filename); err != nil {
+				cli.Error("wiping %s: %v", filename, err)
+				return 1
+			}
+		}
+		if archF, err = os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644); err != nil {
+			cli.Error("opening file: %v", err)
+			return 1
+		}
+		defer archF.Close()
+		archive = newArchiveWriter(kind, archF)
+
+		cli.Info("%s", text("generate.members",
+			opts.Lines, parts, kind, flags.splitLines, filename, pattern))
+		create = func(index int) (io.WriteCloser, error) {
+			w, err := archive.create(names[index-1], sizes[index-1])
+			if err != nil {
+				return nil, err
+			}
+			h := newHashingWriter(w)
+			files = append(files, h)
+			return h, nil
+		}
+	} else {
+		cli.Info("%s", text("generate.parts",
+			opts.Lines, parts, flags.splitLines, pattern))
+		rotation := partRotation{wipeOld: flags.wipeOld}
+		create = func(index int) (io.WriteCloser, error) {
+			f, err := rotation.open(names[index-1])
+			if err != nil {
+				return nil, err
+			}
+			h := newHashingWriter(f)
+			files = append(files, h)
+			return h, nil
+		}
+	}
+
+	// Archive members are not sniffed on their own; the first part file is.
+	var head *headRecorder
+	if kind == "" && sniffable(opts.Mode) {
+		head = &headRecorder{}
+		inner := create
+		create = func(index int) (io.WriteCloser, error) {
+			w, err := inner(index)
+			if err != nil || index > 1 {
+				return w, err
+			}
+			return struct {
+				io.Writer
+				io.Closer
+			}{io.MultiWriter(w, head), w}, nil
+		}
+	}
+
+	var stats *genlines.ContentStats
+	if flags.stats {
+		stats = &genlines.ContentStats{}
+		inner := create
+		create = func(index int) (io.WriteCloser, error) {
+			w, err := inner(index)
+			if err != nil {
+				return nil, err
+			}
+			return struct {
+				io.Writer
+				io.Closer
+			}{io.MultiWriter(w, stats), w}, nil
+		}
+	}
+
+	var st genlines.Stats
+	runOpts := opts
+	runOpts.Stats = &st
+	written, err := genlines.GenerateSplit(context.Background(), runOpts, flags.splitLines, create)
+	hist.written(st.Lines, st.Bytes)
+	if err != nil {
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			cli.Error("opening file: %v", err)
+		} else {
+			cli.Error("%v", err)
+		}
+		return 1
+	}
+	if archive != nil {
+		if err := archive.Close(); err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+		if err := archF.Close(); err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+	}
+
+	if flags.verifyAfter {
+		if kind != "" {
+			cli.Info("Note: --verify-after is skipped for %s archives", kind)
+		} else {
+			paths := names[:len(written)]
+			sums := make([]string, len(files))
+			for i, h := range files {
+				sums[i] = h.hexSum()
+			}
+			if beforeVerify != nil {
+				beforeVerify(paths)
+			}
+			if code := reportVerify(fmt.Sprintf("%d part files", len(paths)), verifySplit(paths, written, sums, opts)); code != 0 {
+				return code
+			}
+		}
+	}
+
+	if flags.manifest != "" {
+		m := newManifest()
+		for i, p := range written {
+			if kind != "" {
+				m.addMember(filename, path.Clean(filepath.ToSlash(names[i])), p.Lines, p.Bytes, files[i].hexSum())
+			} else {
+				m.add(names[i], p.Lines, p.Bytes, files[i].hexSum())
+			}
+		}
+		if err := m.write(flags.manifest); err != nil {
+			cli.Error("writing manifest: %v", err)
+			return 1
+		}
+		cli.Info("Wrote manifest %s", flags.manifest)
+	}
+
+	if stats != nil {
+		stats.Finish()
+		printStats(cli.infoOut(), stats)
+	}
+	printInjected(flags, st)
+	if head != nil {
+		warnSignature(flags, names[0], head.head)
+	}
+
+	if kind != "" {
+		cli.Summary("%s", text("done.members", len(written), filename))
+		return 0
+	}
+	cli.Summary("%s", text("done.parts", names[0], names[len(names)-1]))
+	return 0
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultSplitPattern(t *testing.T) {
+	tests := []struct {
+		filename string
+		parts    int
+		want     string
+	}{
+		{"out.txt", 5, "out-%03d.txt"},
+		{"out.txt", 12345, "out-%05d.txt"},
+		{"dir/data", 2, "dir/data-%03d"},
+		{"archive.tar.gz", 2, "archive.tar-%03d.gz"},
+	}
+	for _, tt := range tests {
+		if got := defaultSplitPattern(tt.filename, tt.parts); got != tt.want {
+			t.Errorf("defaultSplitPattern(%q, %d) = %q, want %q", tt.filename, tt.parts, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSplitPattern(t *testing.T) {
+	for _, ok := range []string{"part-%d.txt", "part-%04d.txt"} {
+		if err := validateSplitPattern(ok); err != nil {
+			t.Errorf("validateSplitPattern(%q) = %v, want nil", ok, err)
+		}
+	}
+	for _, bad := range []string{"part.txt", "%d-%d.txt", "%s.txt", "%d%%.txt", "%5d.txt"} {
+		if err := validateSplitPattern(bad); err == nil {
+			t.Errorf("validateSplitPattern(%q) = nil, want error", bad)
+		}
+	}
+}
+
+func TestRun_SplitWritesManifestMatchingFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+	manifestFile := filepath.Join(dir, "manifest.json")
+
+	if code := run([]string{"25", path, "y", "10", "digits", "--split-lines", "10", "--manifest", manifestFile}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+
+	data, err := os.ReadFile(manifestFile)
+	if err != nil {
+		t.Fatalf("reading manifest: %v", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("parsing manifest: %v", err)
+	}
+
+	wantPaths := []string{"out-001.txt", "out-002.txt", "out-003.txt"}
+	wantLines := []int64{10, 10, 5}
+	if len(m.Files) != len(wantPaths) {
+		t.Fatalf("manifest lists %d files, want %d", len(m.Files), len(wantPaths))
+	}
+
+	var all strings.Builder
+	for i, e := range m.Files {
+		if e.Path != wantPaths[i] || e.Lines != wantLines[i] {
+			t.Errorf("entry %d = %s (%d lines), want %s (%d lines)", i, e.Path, e.Lines, wantPaths[i], wantLines[i])
+		}
+		content, err := os.ReadFile(filepath.Join(dir, e.Path))
+		if err != nil {
+			t.Fatalf("reading %s: %v", e.Path, err)
+		}
+		sum := sha256.Sum256(content)
+		if int64(len(content)) != e.Bytes || hex.EncodeToString(sum[:]) != e.SHA256 {
+			t.Errorf("%s does not match its manifest entry", e.Path)
+		}
+		if got := int64(strings.Count(string(content), "\n")); got != e.Lines {
+			t.Errorf("%s has %d lines, manifest says %d", e.Path, got, e.Lines)
+		}
+		all.Write(content)
+	}
+
+	// The parts continue each other: together they equal a single run.
+	single := filepath.Join(dir, "single.txt")
+	if code := run([]string{"25", single, "y", "10", "digits"}); code != 0 {
+		t.Fatalf("single run exited with %d", code)
+	}
+	want, _ := os.ReadFile(single)
+	if all.String() != string(want) {
+		t.Error("concatenated parts differ from a single run")
+	}
+	if fileExists(path) {
+		t.Errorf("split run should not create %s itself", path)
+	}
+}
+
+func TestRun_SingleFileManifest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+	manifestFile := filepath.Join(dir, "sub", "m.json")
+	os.Mkdir(filepath.Dir(manifestFile), 0755)
+
+	if code := run([]string{"3", path, "y", "5", "--manifest", manifestFile}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	data, err := os.ReadFile(manifestFile)
+	if err != nil {
+		t.Fatalf("reading manifest: %v", err)
+	}
+	var m manifest
+	json.Unmarshal(data, &m)
+	if len(m.Files) != 1 || m.Files[0].Path != "../out.txt" || m.Files[0].Lines != 3 || m.Files[0].Bytes != 18 {
+		t.Errorf("manifest files = %+v", m.Files)
+	}
+}
+
+func TestRun_SplitRefusesExistingPartsWithoutManifest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+	manifestFile := filepath.Join(dir, "manifest.json")
+	existing := filepath.Join(dir, "out-002.txt")
+	os.WriteFile(existing, []byte("keep\n"), 0644)
+
+	if code := run([]string{"20", path, "n", "--split-lines", "10", "--manifest", manifestFile}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if got, _ := os.ReadFile(existing); string(got) != "keep\n" {
+		t.Errorf("existing part was modified: %q", got)
+	}
+	if fileExists(manifestFile) || fileExists(filepath.Join(dir, "out-001.txt")) {
+		t.Error("declined run should not write parts or a manifest")
+	}
+}
+
+// openFDs returns the number of open file descriptors of the process, or -1
+// where /proc/self/fd is not available.
+func openFDs() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
+
+func TestPartRotation_OneOpenAtATime(t *testing.T) {
+	dir := t.TempDir()
+	var r partRotation
+	first, err := r.open(filepath.Join(dir, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.open(filepath.Join(dir, "b.txt")); err == nil || !strings.Contains(err.Error(), "a.txt is still open") {
+		t.Fatalf("second open: err = %v", err)
+	}
+	first.Close()
+	second, err := r.open(filepath.Join(dir, "b.txt"))
+	if err != nil {
+		t.Fatalf("open after close: %v", err)
+	}
+	second.Close()
+	if err := second.Close(); err == nil || !strings.Contains(err.Error(), "b.txt") {
+		t.Errorf("a failed close should name the part: %v", err)
+	}
+}
+
+func TestRun_SplitThousandsOfParts(t *testing.T) {
+	dir := t.TempDir()
+	before := openFDs()
+	if code := run([]string{"2000", filepath.Join(dir, "tiny.txt"), "y", "4", "digits", "--split-lines", "1"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if after := openFDs(); before >= 0 && after != before {
+		t.Errorf("%d file descriptors open after the run, %d before", after, before)
+	}
+
+	const digits = "0123456789"
+	for i := 0; i < 2000; i++ {
+		data, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("tiny-%04d.txt", i+1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want strings.Builder
+		for j := 0; j < 4; j++ {
+			want.WriteByte(digits[(4*i+j)%10])
+		}
+		if string(data) != want.String()+"\n" {
+			t.Fatalf("part %d = %q, want %q", i+1, data, want.String()+"\n")
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// stampPrefix starts the first line --stamp writes. The # makes most parsers
+// of comment-aware formats skip it.
+const stampPrefix = "# generatelines "
+
+// stampSkipped lists the runMeta fields a stamp leaves out: they describe the
+// file rather than the settings that generate it.
+var stampSkipped = map[string]bool{
+	"tool": true, "version": true, "created": true, "file": true,
+	"bytes": true, "sha256": true, "sources": true, "stamp": true,
+}
+
+// stampLine returns the stamp of the run described by m, e.g.
+//
+//	# generatelines v1.0.1 lines=1000 width=80 mode=digits seed=42 config=1c0ffee5
+//
+// The settings are the fields of m that are set, under their sidecar names,
+// and config is their ConfigChecksum, so a changed stamp is told apart from
+// another run's.
+func stampLine(m runMeta) string {
+	var b strings.Builder
+	b.WriteString(stampPrefix + "v" + m.Version)
+	for _, s := range m.settings() {
+		value := fmt.Sprint(s.value)
+		if _, ok := s.value.(string); ok && !stampRaw(value) {
+			value = strconv.QuoteToASCII(value)
+		}
+		fmt.Fprintf(&b, " %s=%s", s.name, value)
+	}
+	m.Stamp = false // options would call back in here for the stamp itself
+	fmt.Fprintf(&b, " config=%s", genlines.ConfigChecksum(m.options()))
+	return b.String()
+}
+
+// setting is one generation setting of a runMeta, under its sidecar name.
+type setting struct {
+	name  string
+	value any
+}
+
+// settings returns the fields of m that are set and decide the content, in
+// sidecar order, leaving out those in stampSkipped.
+func (m runMeta) settings() []setting {
+	var list []setting
+	v := reflect.ValueOf(m)
+	for i := range v.NumField() {
+		name := stampName(v.Type().Field(i))
+		f := v.Field(i)
+		if stampSkipped[name] || f.IsZero() {
+			continue
+		}
+		if f.Kind() == reflect.Pointer {
+			f = f.Elem()
+		}
+		list = append(list, setting{name, f.Interface()})
+	}
+	return list
+}
+
+// stampName returns the sidecar name of a runMeta field.
+func stampName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+// stampRaw reports whether s can stand in a stamp unquoted: printable ASCII
+// without spaces, not starting with a quote.
+func stampRaw(s string) bool {
+	if strings.HasPrefix(s, `"`) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseStamp reads back a line written by stampLine. It returns the settings
+// and the config checksum the stamp records, which a tampered stamp no longer
+// matches.
+func parseStamp(line string) (runMeta, string, error) {
+	m := runMeta{Tool: "generatelines", Stamp: true}
+	rest, ok := strings.CutPrefix(line, stampPrefix)
+	if !ok {
+		return m, "", errors.New("no generatelines stamp on the first line")
+	}
+	ver, rest, _ := strings.Cut(rest, " ")
+	if m.Version, ok = strings.CutPrefix(ver, "v"); !ok || m.Version == "" {
+		return m, "", fmt.Errorf("stamp: invalid version %q", ver)
+	}
+
+	fields := map[string]reflect.Value{}
+	v := reflect.ValueOf(&m).Elem()
+	for i := range v.NumField() {
+		if name := stampName(v.Type().Field(i)); !stampSkipped[name] {
+			fields[name] = v.Field(i)
+		}
+	}
+	config := ""
+	for rest != "" {
+		name, after, ok := strings.Cut(rest, "=")
+		if !ok || name == "" || strings.Contains(name, " ") {
+			return m, "", fmt.Errorf("stamp: expected name=value at %q", rest)
+		}
+		value := after
+		if strings.HasPrefix(after, `"`) {
+			if value, ok = stampQuoted(after); !ok {
+				return m, "", fmt.Errorf("stamp: unterminated %s value", name)
+			}
+		} else {
+			value, _, _ = strings.Cut(after, " ")
+		}
+		rest = strings.TrimPrefix(after[len(value):], " ")
+
+		if name == "config" {
+			config = value
+			continue
+		}
+		f, ok := fields[name]
+		if !ok {
+			return m, "", fmt.Errorf("stamp: unknown setting %q", name)
+		}
+		if err := setStampField(f, value); err != nil {
+			return m, "", fmt.Errorf("stamp: invalid %s %q", name, value)
+		}
+	}
+	if config == "" {
+		return m, "", errors.New("stamp: no config checksum")
+	}
+	if m.Mode == "" {
+		return m, "", errors.New("stamp: no mode")
+	}
+	if err := m.check(); err != nil {
+		return m, "", fmt.Errorf("stamp: %v", err)
+	}
+	return m, config, nil
+}
+
+// stampQuoted returns the quoted value s starts with, quotes included.
+func stampQuoted(s string) (string, bool) {
+	q, err := strconv.QuotedPrefix(s)
+	return q, err == nil
+}
+
+// setStampField sets f from its stamp value.
+func setStampField(f reflect.Value, value string) error {
+	if f.Kind() == reflect.Pointer {
+		f.Set(reflect.New(f.Type().Elem()))
+		f = f.Elem()
+	}
+	switch f.Kind() {
+	case reflect.String:
+		if strings.HasPrefix(value, `"`) {
+			s, err := strconv.Unquote(value)
+			f.SetString(s)
+			return err
+		}
+		f.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		f.SetBool(b)
+		return err
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		f.SetInt(n)
+		return err
+	case reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, 64)
+		f.SetUint(n)
+		return err
+	default:
+		return fmt.Errorf("unsupported kind %s", f.Kind())
+	}
+	return nil
+}
+
+// readStamp returns the first line of path, without its terminator (LF,
+// CRLF or NUL).
+func readStamp(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, sniffSize)
+	head, _ := r.Peek(sniffSize)
+	sep := recordSeparator(head)
+	line, err := r.ReadString(sep)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, string(sep)), "\r"), nil
+}
+
+// runVerifyCmd handles "verify <file>", which checks file against the run
+// recorded in its .meta sidecar, and "verify --auto <file>", which takes the
+// run from the stamp on its first line instead. It returns the exit code.
+func runVerifyCmd(args []string, flags cliFlags) int {
+	if len(args) != 1 {
+		cli.Error("verify requires exactly one file")
+		cli.Hint(helpHint())
+		return 1
+	}
+	path := args[0]
+
+	if !flags.auto {
+		m, err := readMeta(path + metaSuffix)
+		if err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+		return reportVerify(path, verifyFile(path, 0, m.options(), m.SHA256))
+	}
+
+	line, err := readStamp(path)
+	if err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+	m, config, err := parseStamp(line)
+	if err != nil {
+		cli.Error("%s: %v", path, err)
+		return 1
+	}
+	opts := m.options()
+	if sum := genlines.ConfigChecksum(opts); sum != config {
+		cli.Error("verification FAILED: %s: the stamp records config %s, but its settings give %s", path, config, sum)
+		return exitVerifyFailed
+	}
+	cli.Info("Stamp: %d lines (width=%d, mode=%s)", m.Lines, m.Width, m.Mode)
+	return reportVerify(path, verifyFile(path, 0, opts, ""))
+}
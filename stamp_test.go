@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStamp_RoundTrip(t *testing.T) {
+	seed := uint64(42)
+	m := runMeta{Version: "1.0.1", Lines: 1000, Width: 80, Mode: "random", ModeArg: "7", CommentEvery: 10,
+		CommentText: `# line "%d"\ here`, LineChecksum: true, LineEnding: "crlf", FirstLine: "BEGIN-{config}", Seed: &seed}
+	line := stampLine(m)
+	if !strings.HasPrefix(line, "# generatelines v1.0.1 lines=1000 width=80 mode=random modeArg=7 ") ||
+		!strings.Contains(line, " seed=42 config=") {
+		t.Errorf("stamp %q", line)
+	}
+	got, config, err := parseStamp(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config != strings.Fields(line)[len(strings.Fields(line))-1][len("config="):] {
+		t.Errorf("config %q", config)
+	}
+	if got.CommentText != m.CommentText || *got.Seed != 42 || got.LineEnding != "crlf" || !got.Stamp {
+		t.Errorf("parsed %+v", got)
+	}
+	if again := stampLine(got); again != line {
+		t.Errorf("restamped:\n%s\n%s", again, line)
+	}
+
+	for _, bad := range []string{
+		"lines=5 mode=ascii config=0",
+		"# generatelines 1.0 mode=ascii config=0",
+		"# generatelines v1 mode=ascii",
+		"# generatelines v1 mode=ascii colour=red config=0",
+		"# generatelines v1 mode=ascii lines=five config=0",
+		`# generatelines v1 mode=ascii commentText="open config=0`,
+		"# generatelines v1 mode=ascii lineEnding=lfcr config=0",
+	} {
+		if _, _, err := parseStamp(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestRun_StampVerifyAuto(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stamped.txt")
+	output := captureStdout(t)
+	code := run([]string{"50", path, "y", "30", "random", "--seed", "9", "--line-checksum", "--stamp", "--meta", "--verify-after"})
+	if text := output(); code != 0 {
+		t.Fatalf("run exited with %d:\n%s", code, text)
+	}
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 51 || !strings.HasPrefix(lines[0], "# generatelines v"+version+" lines=50 width=30 mode=random lineChecksum=true seed=9 config=") {
+		t.Fatalf("%d lines, first %q", len(lines), lines[0])
+	}
+
+	// The stamp is all verify --auto needs; verify-lines steps over it.
+	if err := os.Remove(path + metaSuffix); err != nil {
+		t.Fatal(err)
+	}
+	output = captureStdout(t)
+	if code := run([]string{"verify", "--auto", path}); code != 0 {
+		t.Fatalf("verify --auto exited with %d:\n%s", code, output())
+	}
+	if code := run([]string{"verify-lines", path}); code != 0 {
+		t.Fatalf("verify-lines exited with %d", code)
+	}
+
+	for _, tamper := range []struct {
+		name string
+		edit func(string) string
+		want string
+	}{
+		{"content", func(s string) string { return strings.Replace(s, lines[20], strings.ToUpper(lines[20]), 1) },
+			"content differs at byte offset"},
+		{"stamp", func(s string) string { return strings.Replace(s, "lines=50", "lines=40", 1) },
+			"the stamp records config"},
+		{"truncated", func(s string) string { return s[:len(s)-31] }, "content ends early"},
+	} {
+		if err := os.WriteFile(path, []byte(tamper.edit(string(data))), 0644); err != nil {
+			t.Fatal(err)
+		}
+		errOutput := captureStderr(t)
+		if code := run([]string{"verify", "--auto", path}); code != exitVerifyFailed {
+			t.Errorf("%s: verify --auto exited with %d", tamper.name, code)
+		}
+		if got := errOutput(); !strings.Contains(got, tamper.want) {
+			t.Errorf("%s: stderr %q, want %q", tamper.name, got, tamper.want)
+		}
+	}
+}
+
+func TestRun_StampRegenAndVerifyMeta(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stamped.txt")
+	if code := run([]string{"20", path, "y", "16", "digits", "--stamp", "--meta", "--comment-every", "5"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	data, _ := os.ReadFile(path)
+	if code := run([]string{"verify", path}); code != 0 {
+		t.Fatalf("verify exited with %d", code)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if code := runRegenCmd([]string{path + metaSuffix}); code != 0 {
+		t.Fatalf("regen exited with %d", code)
+	}
+	if again, _ := os.ReadFile(path); string(again) != string(data) {
+		t.Error("regenerated file differs from the original")
+	}
+
+	// A file without a stamp cannot configure verify --auto.
+	plain := filepath.Join(t.TempDir(), "plain.txt")
+	if code := run([]string{"5", plain, "y"}); code != 0 {
+		t.Fatal(code)
+	}
+	errOutput := captureStderr(t)
+	if code := run([]string{"verify", "--auto", plain}); code != 1 {
+		t.Errorf("verify --auto on a plain file exited with %d", code)
+	}
+	if got := errOutput(); !strings.Contains(got, "no generatelines stamp") {
+		t.Errorf("stderr %q", got)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+	"github.com/Bjornsrud/GenerateLines/genlines/humanize"
+)
+
+// statsTopBytes is how many of the most frequent bytes --stats lists.
+const statsTopBytes = 16
+
+// printStats writes the --stats profile of a finished run to w.
+func printStats(w io.Writer, s *genlines.ContentStats) {
+	fmt.Fprintln(w, "Stats (content bytes, excluding line terminators):")
+	fmt.Fprintf(w, "  bytes:      %d\n", s.Bytes)
+	fmt.Fprintf(w, "  lines:      %d\n", s.Lines)
+	fmt.Fprintf(w, "  line width: min %d, max %d\n", s.MinLineWidth, s.MaxLineWidth)
+	fmt.Fprintf(w, "  distinct:   %d bytes\n", s.Distinct())
+	fmt.Fprintf(w, "  entropy:    %s bits/byte\n", humanize.Fixed(s.Entropy(), 3))
+
+	var used []int
+	for b, c := range s.Histogram {
+		if c > 0 {
+			used = append(used, b)
+		}
+	}
+	if len(used) == 0 {
+		return
+	}
+	sort.SliceStable(used, func(i, j int) bool { return s.Histogram[used[i]] > s.Histogram[used[j]] })
+
+	fmt.Fprintln(w, "  histogram:")
+	for _, b := range used[:min(len(used), statsTopBytes)] {
+		fmt.Fprintf(w, "    %-6s %d\n", byteLabel(byte(b)), s.Histogram[b])
+	}
+	if rest := len(used) - statsTopBytes; rest > 0 {
+		fmt.Fprintf(w, "    ... %d more\n", rest)
+	}
+}
+
+// byteLabel shows b as a quoted character, or as an escape if it is not printable.
+func byteLabel(b byte) string {
+	if b >= 32 && b < 127 {
+		return strconv.QuoteRune(rune(b))
+	}
+	return fmt.Sprintf("0x%02x", b)
+}
+
+// printInjected reports what --line-pattern, --trailing-ws and
+// --inject-unicode added to the run summarized by st, if they were given.
+func printInjected(flags cliFlags, st genlines.Stats) {
+	if flags.linePattern.Enabled() {
+		cli.Info("%s", text("pattern.summary", flags.linePattern, st.Lines-st.BlankLines, st.BlankLines))
+	}
+	if flags.trailingWS.Enabled() {
+		cli.Info("%s", text("trailing.summary", st.TrailingLines, st.Lines, st.TrailingBytes))
+	}
+	if flags.inject.Enabled() {
+		var total int64
+		var counts []string
+		for _, name := range genlines.InvisibleRuneNames() {
+			if n := st.Injected[name]; n > 0 {
+				r, _ := genlines.InvisibleRune(name)
+				counts = append(counts, fmt.Sprintf("%s (U+%04X) %d", name, r, n))
+				total += n
+			}
+		}
+		if len(counts) == 0 {
+			counts = append(counts, "none")
+		}
+		cli.Info("%s", text("inject.summary", total, strings.Join(counts, ", ")))
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_StatsPrintsProfile(t *testing.T) {
+	output := captureStdout(t)
+	path := filepath.Join(t.TempDir(), "digits.txt")
+	if code := run([]string{"10", path, "y", "25", "digits", "--stats"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	got := output()
+	for _, want := range []string{"distinct:   10 bytes", "line width: min 25, max 25", "bytes:      250", "'7'"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output lacks %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// streamsTagDigits is the least number of digits in a streams tag.
+const streamsTagDigits = 5
+
+// streamsConfig configures a streams run.
+type streamsConfig struct {
+	outLines int // lines written to stdout
+	errLines int // lines written to stderr
+	width    int
+	mode     string
+	modeArg  string
+	eol      []byte // nil = LF
+	ratioOut int    // stdout lines per turn
+	ratioErr int    // stderr lines per turn
+	tags     bool   // start lines with OUT/ERR and their number in the stream
+	delay    time.Duration
+}
+
+// streamsStats summarizes a streams run.
+type streamsStats struct {
+	outLines, errLines int
+	outBytes, errBytes int64
+}
+
+// parseStreamsArgs parses "streams <stdout-lines> <stderr-lines> [width]
+// [mode] [modeArg]" (without the leading "streams") together with its
+// options.
+func parseStreamsArgs(args []string, flags cliFlags) (streamsConfig, error) {
+	cfg := streamsConfig{
+		width:    defaultWidth,
+		mode:     "ascii",
+		eol:      flags.eol,
+		ratioOut: flags.ratioOut,
+		ratioErr: flags.ratioErr,
+		tags:     flags.tags,
+		delay:    flags.delay,
+	}
+	if cfg.ratioOut == 0 {
+		cfg.ratioOut, cfg.ratioErr = 1, 1
+	}
+	if len(args) < 2 {
+		return cfg, errors.New("streams requires a stdout line count and a stderr line count")
+	}
+	if len(args) > 5 {
+		return cfg, fmt.Errorf("streams takes at most 5 arguments, got %d", len(args))
+	}
+	var err error
+	if cfg.outLines, err = parseLineCount(args[0]); err != nil {
+		return cfg, fmt.Errorf("invalid stdout line count %q (expected a non-negative integer)", args[0])
+	}
+	if cfg.errLines, err = parseLineCount(args[1]); err != nil {
+		return cfg, fmt.Errorf("invalid stderr line count %q (expected a non-negative integer)", args[1])
+	}
+	if len(args) >= 3 {
+		if cfg.width, err = parsePositiveInt(args[2]); err != nil {
+			return cfg, fmt.Errorf("invalid width %q (expected a positive integer)", args[2])
+		}
+	}
+	if len(args) >= 4 {
+		if cfg.mode, cfg.modeArg, err = normalizeMode(args[3]); err != nil {
+			return cfg, err
+		}
+	}
+	if len(args) >= 5 {
+		if cfg.modeArg, err = mergeModeArg(cfg.modeArg, args[4]); err != nil {
+			return cfg, err
+		}
+	}
+	if flags.seedSet {
+		if cfg.modeArg, err = genlines.SeedModeArg(cfg.mode, cfg.modeArg, flags.seed, genlines.SeedLabelContent); err != nil {
+			return cfg, err
+		}
+	}
+	if cfg.mode == "binrec" {
+		return cfg, fmt.Errorf("mode=%s is not supported by streams", cfg.mode)
+	}
+	if cfg.tags && cfg.width <= len(cfg.tag("OUT", 1)) {
+		return cfg, fmt.Errorf("width %d leaves no room for content after the %d-character tags", cfg.width, len(cfg.tag("OUT", 1)))
+	}
+	return cfg, checkANSITarget(cfg.mode, false, flags.forceANSI)
+}
+
+// tag returns the tag of line n (from 1) of the stream named name: the name
+// and n zero-padded to the digits of the longer stream, at least
+// streamsTagDigits, followed by a space.
+func (c streamsConfig) tag(name string, n int) string {
+	digits := max(streamsTagDigits, len(strconv.Itoa(max(c.outLines, c.errLines))))
+	return fmt.Sprintf("%s %0*d ", name, digits, n)
+}
+
+// runStreamsCmd runs the streams subcommand and returns the exit code. The
+// two streams carry only the generated lines; nothing else is printed unless
+// it fails.
+func runStreamsCmd(args []string, flags cliFlags) int {
+	cfg, err := parseStreamsArgs(args, flags)
+	if err != nil {
+		cli.Error("%v", err)
+		cli.Hint(helpHint())
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if _, err := runStreams(ctx, cfg, os.Stdout, os.Stderr, realClock{}); err != nil {
+		if !errors.Is(err, context.Canceled) {
+			cli.Error("%v", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// runStreams writes cfg.outLines lines to out and cfg.errLines lines to
+// errOut, taking turns: cfg.ratioOut lines to out, then cfg.ratioErr to
+// errOut, until one stream is complete and the other gets the rest. Every
+// line is written with one call, so unbuffered streams see each line as
+// soon as it is generated, and cfg.delay passes after each. Each stream has
+// a generator of its own, so its content does not depend on the ratio.
+func runStreams(ctx context.Context, cfg streamsConfig, out, errOut io.Writer, clk clock) (streamsStats, error) {
+	var st streamsStats
+	eol := cfg.eol
+	if eol == nil {
+		eol = []byte("\n")
+	}
+	type stream struct {
+		name  string // tag name
+		dest  string // for errors
+		w     io.Writer
+		gen   genlines.Generator
+		total int
+		turn  int
+		lines *int
+		bytes *int64
+	}
+	streams := []*stream{
+		{name: "OUT", dest: "stdout", w: out, total: cfg.outLines, turn: cfg.ratioOut, lines: &st.outLines, bytes: &st.outBytes},
+		{name: "ERR", dest: "stderr", w: errOut, total: cfg.errLines, turn: cfg.ratioErr, lines: &st.errLines, bytes: &st.errBytes},
+	}
+	for _, s := range streams {
+		gen, err := genlines.NewModeGenerator(cfg.mode, cfg.modeArg)
+		if err != nil {
+			return st, err
+		}
+		s.gen = gen
+	}
+
+	// Each line is assembled in buf, reused, to keep to one write without
+	// allocating a joined string per line.
+	var buf []byte
+	for st.outLines < cfg.outLines || st.errLines < cfg.errLines {
+		for _, s := range streams {
+			for range s.turn {
+				if *s.lines == s.total {
+					break
+				}
+				if ctx.Err() != nil {
+					return st, ctx.Err()
+				}
+				buf = buf[:0]
+				width := cfg.width
+				if cfg.tags {
+					buf = append(buf, cfg.tag(s.name, *s.lines+1)...)
+					width -= len(buf)
+				}
+				buf = append(append(buf, s.gen.NextLine(width)...), eol...)
+				n, err := s.w.Write(buf)
+				*s.bytes += int64(n)
+				if err != nil {
+					return st, fmt.Errorf("writing to %s: %w", s.dest, err)
+				}
+				*s.lines++
+				if cfg.delay > 0 {
+					clk.Sleep(ctx, cfg.delay)
+				}
+			}
+		}
+	}
+	return st, nil
+}
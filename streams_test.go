@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// streamLog records the lines written to two streams in the order they
+// arrive, each prefixed with its stream.
+type streamLog struct {
+	lines []string
+}
+
+func (l *streamLog) writer(name string) *logWriter {
+	return &logWriter{log: l, name: name}
+}
+
+type logWriter struct {
+	log  *streamLog
+	name string
+	buf  bytes.Buffer
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.log.lines = append(w.log.lines, w.name+": "+strings.TrimSuffix(string(p), "\n"))
+	w.buf.Write(p)
+	return len(p), nil
+}
+
+func TestRunStreams_RatioAndTags(t *testing.T) {
+	cfg, err := parseStreamsArgs([]string{"7", "4", "20", "digits"}, cliFlags{ratioOut: 3, ratioErr: 1, tags: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log streamLog
+	out, errOut := log.writer("stdout"), log.writer("stderr")
+	st, err := runStreams(context.Background(), cfg, out, errOut, &fakeClock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.outLines != 7 || st.errLines != 4 || st.outBytes != int64(out.buf.Len()) || st.outBytes != 7*21 || st.errBytes != 4*21 {
+		t.Errorf("stats %+v", st)
+	}
+
+	var order []string
+	for _, line := range log.lines {
+		stream, rest, _ := strings.Cut(line, ": ")
+		if len(rest) != 20 {
+			t.Errorf("%q is not 20 wide", rest)
+		}
+		tag := rest[:9]
+		if (stream == "stdout") != strings.HasPrefix(tag, "OUT ") {
+			t.Errorf("%s got %q", stream, rest)
+		}
+		order = append(order, tag)
+	}
+	want := "OUT 00001 OUT 00002 OUT 00003 ERR 00001 OUT 00004 OUT 00005 OUT 00006 ERR 00002 OUT 00007 ERR 00003 ERR 00004"
+	if got := strings.Join(order, " "); got != want {
+		t.Errorf("order\n%s\nwant\n%s", got, want)
+	}
+	if first := log.lines[0]; first != "stdout: OUT 00001 0123456789" {
+		t.Errorf("first line %q", first)
+	}
+}
+
+func TestRunStreams_ContentIndependentOfRatio(t *testing.T) {
+	var outs []string
+	for _, ratio := range [][2]int{{1, 1}, {5, 2}, {1, 9}} {
+		cfg, err := parseStreamsArgs([]string{"12", "6", "30", "random", "7"}, cliFlags{ratioOut: ratio[0], ratioErr: ratio[1]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out, errOut bytes.Buffer
+		if _, err := runStreams(context.Background(), cfg, &out, &errOut, &fakeClock{}); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Count(out.String(), "\n") != 12 || strings.Count(errOut.String(), "\n") != 6 {
+			t.Fatalf("ratio %v: %d and %d lines", ratio, strings.Count(out.String(), "\n"), strings.Count(errOut.String(), "\n"))
+		}
+		outs = append(outs, out.String()+"|"+errOut.String())
+	}
+	if outs[0] != outs[1] || outs[0] != outs[2] {
+		t.Error("stream content depends on the ratio")
+	}
+}
+
+func TestRunStreams_DelayAndCancel(t *testing.T) {
+	cfg, err := parseStreamsArgs([]string{"3", "2"}, cliFlags{delay: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := &fakeClock{stopAt: time.Unix(0, 0).Add(time.Hour)}
+	clk.now = time.Unix(0, 0)
+	if _, err := runStreams(context.Background(), cfg, &bytes.Buffer{}, &bytes.Buffer{}, clk); err != nil {
+		t.Fatal(err)
+	}
+	if clk.slept != 5*50*time.Millisecond {
+		t.Errorf("slept %v, want 250ms", clk.slept)
+	}
+
+	// Cancelled during the delay after the third line.
+	ctx, cancel := context.WithCancel(context.Background())
+	clk = &fakeClock{now: time.Unix(0, 0), stopAt: time.Unix(0, 0).Add(150 * time.Millisecond), cancel: cancel}
+	st, err := runStreams(ctx, cfg, &bytes.Buffer{}, &bytes.Buffer{}, clk)
+	if !errors.Is(err, context.Canceled) || st.outLines+st.errLines != 3 {
+		t.Errorf("stopped after %+v: %v", st, err)
+	}
+}
+
+func TestParseStreamsArgs_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		args  []string
+		flags cliFlags
+	}{
+		{[]string{"5"}, cliFlags{}},
+		{[]string{"x", "5"}, cliFlags{}},
+		{[]string{"5", "5", "0"}, cliFlags{}},
+		{[]string{"5", "5", "80", "binrec"}, cliFlags{}},
+		{[]string{"5", "5", "10"}, cliFlags{tags: true}},
+		{[]string{"5", "5", "80", "ascii", "", "extra"}, cliFlags{}},
+	} {
+		if _, err := parseStreamsArgs(tt.args, tt.flags); err == nil {
+			t.Errorf("%v %+v accepted", tt.args, tt.flags)
+		}
+	}
+	for _, ratio := range []string{"3", "0:1", "1:x", "-1:2"} {
+		if code := run([]string{"streams", "1", "1", "--ratio", ratio}); code == 0 {
+			t.Errorf("--ratio %s accepted", ratio)
+		}
+	}
+}
+
+func TestRunStreams_MatchesJoinedLines(t *testing.T) {
+	cfg, err := parseStreamsArgs([]string{"3000", "1500", "80", "random", "4"}, cliFlags{ratioOut: 2, ratioErr: 1, tags: true, eol: []byte("\r\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out, errOut bytes.Buffer
+	if _, err := runStreams(context.Background(), cfg, &out, &errOut, &fakeClock{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The old path joined tag, line and terminator into one string.
+	for _, s := range []struct {
+		name  string
+		lines int
+		got   string
+	}{{"OUT", 3000, out.String()}, {"ERR", 1500, errOut.String()}} {
+		gen, err := genlines.NewModeGenerator(cfg.mode, cfg.modeArg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want strings.Builder
+		for n := range s.lines {
+			tag := cfg.tag(s.name, n+1)
+			want.WriteString(tag + gen.NextLine(cfg.width-len(tag)) + string(cfg.eol))
+		}
+		if s.got != want.String() {
+			t.Errorf("%s: %d bytes differ from joined lines (%d bytes)", s.name, len(s.got), want.Len())
+		}
+	}
+}
+
+func BenchmarkRunStreams(b *testing.B) {
+	cfg, err := parseStreamsArgs([]string{"10000", "0", "80", "ascii"}, cliFlags{ratioOut: 1, ratioErr: 1})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := runStreams(context.Background(), cfg, io.Discard, io.Discard, &fakeClock{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
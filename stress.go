@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+	"github.com/Bjornsrud/GenerateLines/genlines/humanize"
+)
+
+// defaultStressSize is the size of every file stress-files creates unless
+// one is given.
+const defaultStressSize = 1 << 10
+
+// stressConfig configures a stress-files run.
+type stressConfig struct {
+	count       int
+	dir         string
+	size        int64 // bytes per file
+	mode        string
+	modeArg     string
+	eol         []byte // nil = LF
+	seed        uint64
+	hasSeed     bool
+	concurrency int  // files created at a time
+	overwrite   bool // replace files that already exist (--force)
+}
+
+// stressStats summarizes a stress-files run: the files completed and their
+// bytes. A file cut short by an interruption or an error is removed and not
+// counted.
+type stressStats struct {
+	files   int
+	bytes   int64
+	elapsed time.Duration
+}
+
+// parseStressArgs parses "stress-files <count> <dir> [size-per-file] [mode]
+// [modeArg]" (without the leading "stress-files") together with its options.
+func parseStressArgs(args []string, flags cliFlags) (stressConfig, error) {
+	cfg := stressConfig{
+		size:        defaultStressSize,
+		mode:        "ascii",
+		eol:         flags.eol,
+		seed:        flags.seed,
+		hasSeed:     flags.seedSet,
+		concurrency: flags.concurrency,
+		overwrite:   flags.force,
+	}
+	if cfg.concurrency == 0 {
+		cfg.concurrency = 1
+	}
+	if len(args) < 2 {
+		return cfg, errors.New("stress-files requires a file count and a directory")
+	}
+	if len(args) > 5 {
+		return cfg, fmt.Errorf("stress-files takes at most 5 arguments, got %d", len(args))
+	}
+	n, err := parseLineCount(args[0])
+	if err != nil || n == 0 {
+		return cfg, fmt.Errorf("invalid file count %q (expected a positive integer)", args[0])
+	}
+	cfg.count, cfg.dir = n, args[1]
+	if len(args) >= 3 {
+		if cfg.size, err = parseByteSize(args[2]); err != nil {
+			return cfg, err
+		}
+	}
+	if len(args) >= 4 {
+		if cfg.mode, cfg.modeArg, err = normalizeMode(args[3]); err != nil {
+			return cfg, err
+		}
+	}
+	if len(args) >= 5 {
+		if cfg.modeArg, err = mergeModeArg(cfg.modeArg, args[4]); err != nil {
+			return cfg, err
+		}
+	}
+	if cfg.mode == "pi" || cfg.mode == "binrec" {
+		return cfg, fmt.Errorf("mode=%s is not supported by stress-files", cfg.mode)
+	}
+	return cfg, checkANSITarget(cfg.mode, false, flags.forceANSI)
+}
+
+// name returns the path of file i (from 1): its index zero-padded to the
+// digits of the file count, so the names sort in creation order.
+func (c stressConfig) name(i int) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%0*d.txt", len(strconv.Itoa(c.count)), i))
+}
+
+// options returns the generation options of every file.
+func (c stressConfig) options() genlines.Options {
+	return genlines.Options{
+		Width:      defaultWidth,
+		Mode:       c.mode,
+		ModeArg:    c.modeArg,
+		EOL:        c.eol,
+		ExactBytes: c.size,
+		Seed:       c.seed,
+		HasSeed:    c.hasSeed,
+	}
+}
+
+// existing returns how many of the files of c already exist, and the first.
+func (c stressConfig) existing() (int, string) {
+	n, first := 0, ""
+	for i := 1; i <= c.count; i++ {
+		if _, err := os.Lstat(c.name(i)); err == nil {
+			if n == 0 {
+				first = c.name(i)
+			}
+			n++
+		}
+	}
+	return n, first
+}
+
+// runStressFilesCmd runs the stress-files subcommand and returns the exit code.
+func runStressFilesCmd(args []string, flags cliFlags) int {
+	cfg, err := parseStressArgs(args, flags)
+	if err == nil {
+		// Settings that cannot be generated fail before any file exists.
+		_, err = genlines.PlanSize(cfg.options())
+	}
+	if err != nil {
+		cli.Error("%v", err)
+		cli.Hint(helpHint())
+		return 1
+	}
+	if !cfg.overwrite {
+		if n, first := cfg.existing(); n > 0 {
+			cli.Error("%d of the files already exist (first: %s); use --force to overwrite them", n, first)
+			return 1
+		}
+	}
+	if err := os.MkdirAll(cfg.dir, 0755); err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cli.Info("Creating %d files of %d bytes (mode=%s, %d at a time) -> %s. Press Ctrl-C to stop.",
+		cfg.count, cfg.size, cfg.mode, cfg.concurrency, cfg.dir)
+	st, err := runStressFiles(ctx, cfg)
+	cli.Info("%s files, %s in %s: %s files/s, %s/s", humanize.Count(int64(st.files)), humanize.Bytes(st.bytes),
+		humanize.Duration(st.elapsed), humanize.Count(humanize.PerSecond(int64(st.files), st.elapsed)),
+		humanize.Bytes(humanize.PerSecond(st.bytes, st.elapsed)))
+	switch {
+	case errors.Is(err, context.Canceled):
+		cli.Error("interrupted: %d of %d files completed", st.files, cfg.count)
+		return 1
+	case err != nil:
+		cli.Error("%v", err)
+		return 1
+	}
+	cli.Summary("%s", text("done"))
+	return 0
+}
+
+// runStressFiles creates the files of cfg, cfg.concurrency at a time, each
+// through GenerateTo, until all are done, one fails or ctx is cancelled. It
+// returns the files completed.
+func runStressFiles(ctx context.Context, cfg stressConfig) (stressStats, error) {
+	start := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	opts := cfg.options()
+
+	var (
+		mu       sync.Mutex
+		st       stressStats
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	next := make(chan int)
+	for range cfg.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				n, err := writeStressFile(ctx, cfg.name(i), opts)
+				mu.Lock()
+				if err != nil {
+					// After a cancellation, the files cut short are not errors.
+					if firstErr == nil && ctx.Err() == nil {
+						firstErr = err
+					}
+					cancel()
+				} else {
+					st.files++
+					st.bytes += n
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for i := 1; i <= cfg.count; i++ {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	st.elapsed = time.Since(start)
+	if firstErr != nil {
+		return st, firstErr
+	}
+	return st, ctx.Err()
+}
+
+// writeStressFile creates path with the content of opts and returns its size.
+// A file that could not be completed is removed.
+func writeStressFile(ctx context.Context, path string, opts genlines.Options) (int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	_, n, err := genlines.GenerateTo(ctx, f, opts)
+	if cerr := f.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("closing %s: %w", path, cerr)
+	}
+	if err != nil {
+		if rerr := os.Remove(path); rerr != nil && !errors.Is(rerr, fs.ErrNotExist) {
+			err = errors.Join(err, rerr)
+		}
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestRunStressFiles_CountSizesAndNames(t *testing.T) {
+	for _, concurrency := range []int{1, 8} {
+		dir := filepath.Join(t.TempDir(), "deep", "stress")
+		cfg, err := parseStressArgs([]string{"300", dir, "100", "digits"}, cliFlags{concurrency: concurrency})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		st, err := runStressFiles(context.Background(), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.files != 300 || st.bytes != 300*100 {
+			t.Errorf("concurrency %d: stats %+v", concurrency, st)
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range entries {
+			info, _ := e.Info()
+			if info.Size() != 100 {
+				t.Errorf("%s: %d bytes, want 100", e.Name(), info.Size())
+			}
+			names = append(names, e.Name())
+		}
+		sort.Strings(names)
+		if len(names) != 300 || names[0] != "001.txt" || names[9] != "010.txt" || names[299] != "300.txt" {
+			t.Errorf("concurrency %d: %d files named %v .. %v", concurrency, len(names), names[:min(3, len(names))], names[max(0, len(names)-1):])
+		}
+	}
+}
+
+func TestRunStressFiles_CancelledLeavesOnlyCompleteFiles(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cfg := stressConfig{count: 50, dir: dir, size: 1 << 20, mode: "ascii", concurrency: 4}
+	st, err := runStressFiles(ctx, cfg)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != st.files {
+		t.Errorf("%d files on disk, %d reported completed", len(entries), st.files)
+	}
+	for _, e := range entries {
+		if info, _ := e.Info(); info.Size() != cfg.size {
+			t.Errorf("%s left at %d bytes", e.Name(), info.Size())
+		}
+	}
+}
+
+func TestRunStressFilesCmd_ExistingAndBadArgs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "2.txt"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"stress-files", "3", dir, "10"}); code == 0 {
+		t.Error("stress-files overwrote an existing file without --force")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "2.txt")); string(data) != "keep" {
+		t.Errorf("existing file changed to %q", data)
+	}
+	if code := run([]string{"stress-files", "3", dir, "10", "--force", "--concurrency", "2"}); code != 0 {
+		t.Fatalf("stress-files --force exited with %d", code)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "2.txt")); err != nil || info.Size() != 10 {
+		t.Errorf("2.txt not replaced: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"stress-files"},
+		{"stress-files", "0", dir},
+		{"stress-files", "3", dir, "0"},
+		{"stress-files", "3", dir, "10", "nosuchmode"},
+		{"stress-files", "3", dir, "10", "pi"},
+		{"stress-files", "3", dir, "--concurrency", "0"},
+	} {
+		if code := run(args); code == 0 {
+			t.Errorf("%v accepted", args)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// summarySchemaVersion is the version of the --summary-json format. Raise it
+// when a field is removed or renamed, changes type or becomes required;
+// adding an optional field keeps it.
+const summarySchemaVersion = 1
+
+// runSummary is the JSON summary --summary-json writes after a successful
+// run. --json-schema describes it, from the json and desc tags below.
+type runSummary struct {
+	SchemaVersion int               `json:"schemaVersion" desc:"Version of the summary format, raised on every incompatible change"`
+	Tool          string            `json:"tool" desc:"Always generatelines"`
+	Version       string            `json:"version" desc:"Version of the tool that wrote the summary"`
+	Created       time.Time         `json:"created" desc:"When the run finished, in UTC"`
+	File          string            `json:"file" desc:"The output file as given (fd:N for a file descriptor)"`
+	Lines         int64             `json:"lines" desc:"Data lines written (records for binrec)"`
+	BlankLines    int64             `json:"blankLines,omitempty" desc:"How many of the data lines are blank lines of --line-pattern"`
+	Bytes         int64             `json:"bytes" desc:"Bytes written, terminators, comment lines and padding included"`
+	DurationMs    float64           `json:"durationMs" desc:"Time spent generating and writing, in milliseconds"`
+	Mode          string            `json:"mode" desc:"Canonical mode name, or the interleave spec"`
+	ModeArg       string            `json:"modeArg,omitempty" desc:"The modeArg, with a picked seed filled in"`
+	Width         int               `json:"width" desc:"Line width in columns"`
+	Seed          *uint64           `json:"seed,omitempty" desc:"The global --seed, if given"`
+	Checksums     map[string]string `json:"checksums" desc:"Hex digest of the bytes written (compressed ones with --gz-member-lines), by algorithm"`
+	Sources       map[string]string `json:"sources" desc:"Where each parameter came from, as --explain shows it"`
+	Meta          string            `json:"meta,omitempty" desc:"The .meta sidecar written for the run"`
+	Manifest      string            `json:"manifest,omitempty" desc:"The --manifest written for the run"`
+	Golden        string            `json:"golden,omitempty" desc:"The --golden file written for the run"`
+}
+
+// newRunSummary describes a finished run into filename from its stats.
+func newRunSummary(filename string, opts genlines.Options, st genlines.Stats, sources map[string]string) runSummary {
+	s := runSummary{
+		SchemaVersion: summarySchemaVersion,
+		Tool:          "generatelines",
+		Version:       version,
+		Created:       time.Now().UTC().Truncate(time.Second),
+		File:          filename,
+		Lines:         st.Lines,
+		BlankLines:    st.BlankLines,
+		Bytes:         st.Bytes,
+		DurationMs:    float64(st.Duration.Microseconds()) / 1000,
+		Mode:          st.Mode,
+		ModeArg:       opts.ModeArg,
+		Width:         st.Width,
+		Checksums:     st.Checksums,
+		Sources:       sources,
+	}
+	if st.HasSeed {
+		seed := st.Seed
+		s.Seed = &seed
+	}
+	return s
+}
+
+// write stores s as indented JSON at path, atomically like the manifest.
+func (s runSummary) write(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// summarySchema returns the JSON Schema of runSummary.
+func summarySchema() map[string]any {
+	schema := jsonSchema(reflect.TypeFor[runSummary]())
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "generatelines run summary"
+	schema["properties"].(map[string]any)["schemaVersion"].(map[string]any)["const"] = summarySchemaVersion
+	return schema
+}
+
+// jsonSchema describes the JSON encoding of values of type t. Struct fields
+// are named by their json tag and described by their desc tag, and are
+// required unless they are omitempty.
+func jsonSchema(t reflect.Type) map[string]any {
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchema(t.Elem())
+	case reflect.Struct:
+		props, required := map[string]any{}, []string{}
+		for _, f := range reflect.VisibleFields(t) {
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || f.Anonymous || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			prop := jsonSchema(f.Type)
+			if desc := f.Tag.Get("desc"); desc != "" {
+				prop["description"] = desc
+			}
+			props[name] = prop
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]any{"type": "object", "properties": props, "required": required}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	panic(fmt.Sprintf("jsonSchema: unsupported type %s", t))
+}
+
+// printSummarySchema writes the schema of the --summary-json file to stdout.
+func printSummarySchema() error {
+	data, err := json.MarshalIndent(summarySchema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
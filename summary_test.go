@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// validateJSON checks v, decoded from JSON, against the parts of a JSON
+// Schema that jsonSchema writes: type, required, properties,
+// additionalProperties, items and const.
+func validateJSON(schema map[string]any, v any, path string) error {
+	if want, ok := schema["const"]; ok && fmt.Sprint(want) != fmt.Sprint(v) {
+		return fmt.Errorf("%s: %v, want %v", path, v, want)
+	}
+	switch schema["type"] {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: %T is not an object", path, v)
+		}
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				return fmt.Errorf("%s: %s is missing", path, name)
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for name, field := range obj {
+			sub, ok := props[name].(map[string]any)
+			if !ok {
+				sub, ok = schema["additionalProperties"].(map[string]any)
+			}
+			if !ok {
+				continue
+			}
+			if err := validateJSON(sub, field, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: %T is not an array", path, v)
+		}
+		for i, item := range arr {
+			if err := validateJSON(schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s: %T is not a string", path, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: %T is not a boolean", path, v)
+		}
+	case "number", "integer":
+		n, ok := v.(float64)
+		if !ok || schema["type"] == "integer" && n != math.Trunc(n) {
+			return fmt.Errorf("%s: %v is not an %s", path, v, schema["type"])
+		}
+	default:
+		return fmt.Errorf("%s: unknown schema type %v", path, schema["type"])
+	}
+	return nil
+}
+
+// decodeJSON decodes data into a generic value.
+func decodeJSON(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	var v map[string]any
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("%v:\n%s", err, data)
+	}
+	return v
+}
+
+func TestRun_SummaryValidatesAgainstSchema(t *testing.T) {
+	out := captureStdout(t)
+	if code := run([]string{"--json-schema"}); code != 0 {
+		t.Fatalf("--json-schema exited with %d", code)
+	}
+	schema := decodeJSON(t, []byte(out()))
+
+	dir := t.TempDir()
+	for i, args := range [][]string{
+		{"20", "out.txt", "y", "30", "digits"},
+		{"20", "out.txt", "y", "30", "random", "--seed", "7", "--meta", "--manifest", "m.json", "--golden", "g.tsv"},
+		{"10", "out.gz", "y", "30", "--gz-member-lines", "4"},
+	} {
+		for j, a := range args {
+			if strings.Contains(a, ".") {
+				args[j] = filepath.Join(dir, a)
+			}
+		}
+		summary := filepath.Join(dir, fmt.Sprintf("summary-%d.json", i))
+		if code := run(append(args, "--summary-json", summary)); code != 0 {
+			t.Fatalf("%v exited with %d", args, code)
+		}
+		data, err := os.ReadFile(summary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := decodeJSON(t, data)
+		if err := validateJSON(schema, v, "summary"); err != nil {
+			t.Errorf("%v: %v", args, err)
+		}
+		if v["file"] != args[1] || fmt.Sprint(v["lines"]) != args[0] || v["schemaVersion"] != float64(summarySchemaVersion) {
+			t.Errorf("%v: summary %s", args, data)
+		}
+		if i == 1 && (v["seed"] != float64(7) || v["meta"] != args[1]+metaSuffix || v["sources"].(map[string]any)["width"] != "positional arg 4") {
+			t.Errorf("%v: summary %s", args, data)
+		}
+	}
+
+	// The validator itself catches what it should.
+	for _, bad := range []string{`{"schemaVersion": 2}`, `{"lines": 1.5}`, `{"checksums": {"sha256": 1}}`, `[]`} {
+		v := map[string]any{}
+		json.Unmarshal([]byte(`{"schemaVersion":1,"tool":"generatelines","version":"x","created":"","file":"f","lines":1,"bytes":1,"durationMs":0,"mode":"ascii","width":1,"checksums":{},"sources":{}}`), &v)
+		var patch any
+		json.Unmarshal([]byte(bad), &patch)
+		if p, ok := patch.(map[string]any); ok {
+			for k, x := range p {
+				v[k] = x
+			}
+			if validateJSON(schema, v, "summary") == nil {
+				t.Errorf("%s validated", bad)
+			}
+		} else if validateJSON(schema, patch, "summary") == nil {
+			t.Errorf("%s validated", bad)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"5", filepath.Join(dir, "a.txt"), "y", "--summary-json", filepath.Join(dir, "a.txt")},
+		{"5", filepath.Join(dir, "a.txt"), "y", "--summary-json", filepath.Join(dir, "s.json"), "--split-lines", "2"},
+		{"--json-schema", "5"},
+	} {
+		if code := run(args); code == 0 {
+			t.Errorf("%v accepted", args)
+		}
+	}
+}
+
+// TestSummarySchema_Compatible checks the schema against the one checked in
+// for its version: a field removed, retyped or newly required is a breaking
+// change that needs summarySchemaVersion raised and a new file in testdata.
+func TestSummarySchema_Compatible(t *testing.T) {
+	path := filepath.Join("testdata", fmt.Sprintf("summary-schema-v%d.json", summarySchemaVersion))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("no schema checked in for version %d: %v (write it with generatelines --json-schema)", summarySchemaVersion, err)
+	}
+	old := decodeJSON(t, data)
+	cur := decodeJSON(t, must(json.Marshal(summarySchema())))
+
+	oldProps, curProps := old["properties"].(map[string]any), cur["properties"].(map[string]any)
+	for name, p := range oldProps {
+		c, ok := curProps[name]
+		if !ok {
+			t.Errorf("field %s was removed; raise summarySchemaVersion", name)
+			continue
+		}
+		if was, is := p.(map[string]any)["type"], c.(map[string]any)["type"]; was != is {
+			t.Errorf("field %s changed type from %v to %v; raise summarySchemaVersion", name, was, is)
+		}
+	}
+	oldRequired := old["required"].([]any)
+	for _, name := range cur["required"].([]any) {
+		if !slices.Contains(oldRequired, name) {
+			t.Errorf("field %s became required; raise summarySchemaVersion", name)
+		}
+	}
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
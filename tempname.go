@@ -0,0 +1,52 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// tempNameHole stands for the random part of a --temp-name file until the
+// file is created; it also gives the name its extension.
+const tempNameHole = "-XXXXXXXX.txt"
+
+// tempNameAttempts caps how many names createTempName tries.
+const tempNameAttempts = 100
+
+// tempNameRand supplies the random part of --temp-name files. Tests replace
+// it to force collisions.
+var tempNameRand io.Reader = rand.Reader
+
+// createTempName creates a new, empty file named prefix-XXXXXXXX.txt, where
+// XXXXXXXX are random hex digits, and returns it open for writing. The file
+// is created with O_EXCL, so a name another process (or run) already took is
+// never reused: a collision draws a new name.
+func createTempName(prefix string) (*os.File, string, error) {
+	var b [4]byte
+	for range tempNameAttempts {
+		if _, err := io.ReadFull(tempNameRand, b[:]); err != nil {
+			return nil, "", fmt.Errorf("picking a file name: %w", err)
+		}
+		name := prefix + "-" + hex.EncodeToString(b[:]) + ".txt"
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return f, name, nil
+	}
+	return nil, "", fmt.Errorf("no free name for %s after %d attempts", prefix+tempNameHole, tempNameAttempts)
+}
+
+// tempNamePrefix returns the prefix of a name that stands for a --temp-name
+// file, after the filename expansions have been applied to it.
+func tempNamePrefix(name string) string {
+	return strings.TrimSuffix(name, tempNameHole)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCreateTempName_Concurrent(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "ci")
+	const n = 32
+	names := make([]string, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			f, name, err := createTempName(prefix)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer f.Close()
+			if _, err := f.WriteString(name); err != nil {
+				t.Error(err)
+			}
+			names[i] = name
+		})
+	}
+	wg.Wait()
+
+	pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(prefix) + `-[0-9a-f]{8}\.txt$`)
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !pattern.MatchString(name) {
+			t.Errorf("name %q", name)
+		}
+		if seen[name] {
+			t.Errorf("%s picked twice", name)
+		}
+		seen[name] = true
+		// Each file holds what its own creator wrote.
+		if got, _ := os.ReadFile(name); string(got) != name {
+			t.Errorf("%s holds %q", name, got)
+		}
+	}
+}
+
+func TestCreateTempName_Collisions(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "out")
+	taken := prefix + "-00000000.txt"
+	if err := os.WriteFile(taken, []byte("keep\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := tempNameRand
+	defer func() { tempNameRand = old }()
+
+	// A taken name is never opened: the next draw is used instead.
+	tempNameRand = bytes.NewReader([]byte{0, 0, 0, 0, 1, 2, 3, 4})
+	f, name, err := createTempName(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if name != prefix+"-01020304.txt" {
+		t.Errorf("name %q", name)
+	}
+	if got, _ := os.ReadFile(taken); string(got) != "keep\n" {
+		t.Errorf("taken file changed: %q", got)
+	}
+
+	tempNameRand = bytes.NewReader(make([]byte, 4*tempNameAttempts))
+	if _, _, err := createTempName(prefix); err == nil || !strings.Contains(err.Error(), "no free name") {
+		t.Errorf("every name taken: %v", err)
+	}
+}
+
+func TestRun_TempName(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "fixture")
+	var paths []string
+	for range 2 {
+		output := captureStdout(t)
+		code := run([]string{"3", "--temp-name", prefix, "10", "digits"})
+		text := output()
+		if code != 0 {
+			t.Fatalf("run exited with %d:\n%s", code, text)
+		}
+		// The path is printed on a line of its own.
+		var path string
+		for line := range strings.Lines(text) {
+			if line = strings.TrimSuffix(line, "\n"); strings.HasPrefix(line, prefix+"-") {
+				path = line
+			}
+		}
+		if got, _ := os.ReadFile(path); string(got) != strings.Repeat("0123456789\n", 3) {
+			t.Errorf("%q holds %q:\n%s", path, got, text)
+		}
+		paths = append(paths, path)
+	}
+	if paths[0] == paths[1] {
+		t.Errorf("both runs wrote %s", paths[0])
+	}
+
+	if code := run([]string{"--temp-name", prefix}); code != 1 {
+		t.Errorf("--temp-name without lines exited with %d", code)
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+// terminalProbe reports the size of the terminal behind stdout. It sits
+// behind an interface so width resolution can be tested without a TTY.
+type terminalProbe interface {
+	// Columns returns the terminal width, or ok=false if stdout is not a terminal.
+	Columns() (cols int, ok bool)
+}
+
+// stdoutTerminal is the platform terminalProbe for os.Stdout.
+type stdoutTerminal struct{}
+
+// termProbe is the probe used to resolve "term" widths. Tests replace it.
+var termProbe terminalProbe = stdoutTerminal{}
@@ -0,0 +1,17 @@
+//go:build !(linux || darwin || freebsd || netbsd || dragonfly || windows)
+
+package main
+
+import "os"
+
+// Columns reports no terminal on platforms without a supported size query.
+func (stdoutTerminal) Columns() (int, bool) {
+	return 0, false
+}
+
+// isTTY reports whether f is a character device, the closest this platform
+// gets to asking whether it is a terminal.
+func isTTY(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,36 @@
+//go:build linux || darwin || freebsd || netbsd || dragonfly
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// winsize mirrors struct winsize from <sys/ioctl.h>.
+type winsize struct {
+	rows, cols, xpixel, ypixel uint16
+}
+
+// windowSize asks the terminal behind f for its size; ok is false when f is
+// not a terminal, e.g. /dev/null, a file or a pipe.
+func windowSize(f *os.File) (ws winsize, ok bool) {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(),
+		uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	return ws, errno == 0
+}
+
+func (stdoutTerminal) Columns() (int, bool) {
+	ws, ok := windowSize(os.Stdout)
+	if !ok || ws.cols == 0 {
+		return 0, false
+	}
+	return int(ws.cols), true
+}
+
+// isTTY reports whether f is a terminal.
+func isTTY(f *os.File) bool {
+	_, ok := windowSize(f)
+	return ok
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Bjornsrud/GenerateLines/genlines/humanize"
+)
+
+// throttleClock is the clock --bps paces writes by. Tests replace it to
+// check the pacing without sleeping.
+var throttleClock clock = realClock{}
+
+// tokenBucket paces a byte stream to rate bytes per second. It fills with one
+// token per byte at rate, up to burst (a tenth of a second's worth), and
+// starts empty, so the average never exceeds rate, not even at the start.
+type tokenBucket struct {
+	rate   float64 // tokens added per second
+	burst  float64 // most tokens held
+	tokens float64 // may go below zero when a sleep was cut short
+	last   time.Time
+	clk    clock
+}
+
+func newTokenBucket(rate int64, clk clock) *tokenBucket {
+	return &tokenBucket{rate: float64(rate), burst: max(float64(rate)/10, 1), last: clk.Now(), clk: clk}
+}
+
+// refill adds the tokens earned since the last refill.
+func (b *tokenBucket) refill() {
+	now := b.clk.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// take waits until n tokens (at most burst) are there, or ctx is done, and
+// takes them.
+func (b *tokenBucket) take(ctx context.Context, n int) {
+	b.refill()
+	if short := float64(n) - b.tokens; short > 0 && ctx.Err() == nil {
+		b.clk.Sleep(ctx, time.Duration(short/b.rate*float64(time.Second)))
+		b.refill()
+	}
+	b.tokens -= float64(n)
+}
+
+// throttledWriter passes writes to w no faster than its bucket allows, in
+// pieces of at most the bucket's burst, so even a 64 KiB buffer flush or a
+// wide line goes out at an even pace. Once ctx is done it stops waiting, so
+// what is left is still written.
+type throttledWriter struct {
+	ctx    context.Context
+	w      io.Writer
+	bucket *tokenBucket
+	start  time.Time
+	bytes  int64 // written through so far
+}
+
+func newThrottledWriter(ctx context.Context, w io.Writer, bps int64, clk clock) *throttledWriter {
+	return &throttledWriter{ctx: ctx, w: w, bucket: newTokenBucket(bps, clk), start: clk.Now()}
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		piece := p[:min(len(p), int(t.bucket.burst))]
+		t.bucket.take(t.ctx, len(piece))
+		n, err := t.w.Write(piece)
+		written += n
+		t.bytes += int64(n)
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// average returns the bytes per second written since t was made.
+func (t *throttledWriter) average() int64 {
+	return humanize.PerSecond(t.bytes, t.bucket.clk.Now().Sub(t.start))
+}
+
+// summary reports the average against the limit, e.g.
+// "Throughput: 499.8 KiB/s on average (--bps 500.0 KiB/s)".
+func (t *throttledWriter) summary() string {
+	return fmt.Sprintf("Throughput: %s/s on average (--bps %s/s)",
+		humanize.Bytes(t.average()), humanize.Bytes(int64(t.bucket.rate)))
+}
+
+// stricterLimit describes which of --rate (lines per second, of lineBytes
+// bytes each) and --bps sets the pace when both are given.
+func stricterLimit(rate float64, lineBytes int, bps int64) string {
+	lineRate := fmt.Sprintf("--rate %s lines/s (%s/s at %d bytes a line)",
+		humanize.Fixed(rate, -1), humanize.Bytes(int64(rate*float64(lineBytes))), lineBytes)
+	byteRate := fmt.Sprintf("--bps %s/s", humanize.Bytes(bps))
+	if rate*float64(lineBytes) <= float64(bps) {
+		return lineRate + " is stricter than " + byteRate + " and sets the pace"
+	}
+	return byteRate + " is stricter than " + lineRate + " and sets the pace"
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// pacedWrite is a write that reached the writer under a throttle, and when.
+type pacedWrite struct {
+	at time.Time
+	n  int
+}
+
+// pacedRecorder records the writes that reach it on clk.
+type pacedRecorder struct {
+	clk    clock
+	writes []pacedWrite
+	buf    bytes.Buffer
+}
+
+func (r *pacedRecorder) Write(p []byte) (int, error) {
+	r.writes = append(r.writes, pacedWrite{r.clk.Now(), len(p)})
+	return r.buf.Write(p)
+}
+
+// newThrottleClock returns a fake clock that never stops the run.
+func newThrottleClock() *fakeClock {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &fakeClock{now: start, stopAt: start.Add(24 * time.Hour), cancel: func() {}}
+}
+
+// near reports whether d is within a millisecond of want.
+func near(d, want time.Duration) bool {
+	return d > want-time.Millisecond && d < want+time.Millisecond
+}
+
+func TestThrottledWriter_Pacing(t *testing.T) {
+	clk := newThrottleClock()
+	start := clk.now
+	rec := &pacedRecorder{clk: clk}
+	thr := newThrottledWriter(context.Background(), rec, 1000, clk)
+
+	// One large write leaves in pieces of a tenth of a second's worth, and
+	// by no point in time is more than rate × elapsed out.
+	if n, err := thr.Write(bytes.Repeat([]byte("x"), 5000)); n != 5000 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if !near(clk.slept, 5*time.Second) {
+		t.Errorf("slept %s, want 5s", clk.slept)
+	}
+	var out int
+	for _, w := range rec.writes {
+		if w.n > 100 {
+			t.Errorf("piece of %d bytes, want at most 100", w.n)
+		}
+		out += w.n
+		if allowed := w.at.Sub(start).Seconds()*1000 + 1; float64(out) > allowed {
+			t.Fatalf("%d bytes out after %s", out, w.at.Sub(start))
+		}
+	}
+	if got := thr.average(); got != 1000 {
+		t.Errorf("average %d B/s, want 1000", got)
+	}
+
+	// Idle time fills the bucket to its burst and no further.
+	clk.Sleep(context.Background(), 10*time.Second)
+	clk.slept = 0
+	thr.Write(bytes.Repeat([]byte("x"), 300))
+	if !near(clk.slept, 200*time.Millisecond) {
+		t.Errorf("after idling slept %s, want 200ms", clk.slept)
+	}
+}
+
+func TestThrottledWriter_WideLines(t *testing.T) {
+	clk := newThrottleClock()
+	rec := &pacedRecorder{clk: clk}
+	thr := newThrottledWriter(context.Background(), rec, 1<<20, clk)
+
+	// A 3 MiB line is written in chunks; the throttle paces them too.
+	opts := genlines.Options{Lines: 2, Width: 3 << 20, Mode: "digits"}
+	if _, _, err := genlines.GenerateTo(context.Background(), thr, opts); err != nil {
+		t.Fatal(err)
+	}
+	if rec.buf.Len() != 2*(3<<20+1) {
+		t.Fatalf("wrote %d bytes", rec.buf.Len())
+	}
+	if want := time.Duration(float64(rec.buf.Len()) / (1 << 20) * float64(time.Second)); !near(clk.slept, want) {
+		t.Errorf("slept %s, want %s", clk.slept, want)
+	}
+}
+
+func TestStricterLimit(t *testing.T) {
+	if got := stricterLimit(10, 81, 4096); got != "--rate 10 lines/s (810 B/s at 81 bytes a line) is stricter than --bps 4.0 KiB/s and sets the pace" {
+		t.Errorf("rate: %q", got)
+	}
+	if got := stricterLimit(100, 81, 1024); !strings.HasPrefix(got, "--bps 1.0 KiB/s is stricter than --rate 100 lines/s (7.9 KiB/s") {
+		t.Errorf("bps: %q", got)
+	}
+}
+
+func TestRunDaemon_BpsStricterThanRate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Unix(0, 0)
+	clk := &fakeClock{now: start, stopAt: start.Add(5 * time.Second), cancel: cancel}
+
+	// 100 lines/s of 10 bytes would be 1000 B/s; --bps holds it to 200.
+	cfg := daemonConfig{path: path, width: 9, mode: "digits", rate: 100, bps: 200, flushEvery: 1}
+	st, err := runDaemon(ctx, cfg, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.bytes < 970 || st.bytes > 1010 {
+		t.Errorf("%d bytes in %s, want about 1000", st.bytes, st.elapsed)
+	}
+	if fi, _ := os.Stat(path); fi.Size() != st.bytes {
+		t.Errorf("file has %d bytes, stats say %d", fi.Size(), st.bytes)
+	}
+}
+
+func TestParseDaemonArgs_BpsAlone(t *testing.T) {
+	cfg, err := parseDaemonArgs([]string{"app.log"}, cliFlags{bps: 512000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.rate != 0 || cfg.bps != 512000 {
+		t.Errorf("rate %v, bps %d: want no line rate besides --bps", cfg.rate, cfg.bps)
+	}
+	if cfg, _ := parseDaemonArgs([]string{"app.log"}, cliFlags{}); cfg.rate != defaultDaemonRate {
+		t.Errorf("default rate %v", cfg.rate)
+	}
+}
+
+func TestRun_BpsReportsThroughput(t *testing.T) {
+	old := throttleClock
+	throttleClock = newThrottleClock()
+	t.Cleanup(func() { throttleClock = old })
+
+	path := filepath.Join(t.TempDir(), "out.txt")
+	out := captureStdout(t)
+	if code := run([]string{"100", path, "y", "9", "digits", "--bps", "1K"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if got := out(); !strings.Contains(got, "Throughput: 1.0 KiB/s on average (--bps 1.0 KiB/s)\n") {
+		t.Errorf("stdout %q", got)
+	}
+	if fi, _ := os.Stat(path); fi.Size() != 1000 {
+		t.Errorf("file has %d bytes", fi.Size())
+	}
+}
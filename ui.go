@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// uiLevel is how much a ui prints.
+type uiLevel int
+
+const (
+	levelQuiet   uiLevel = iota // --quiet: warnings and errors only
+	levelNormal                 // progress, results, warnings and errors
+	levelVerbose                // --verbose: also the details Verbose prints
+)
+
+// Prefixes of the message classes that go to stderr.
+const (
+	warnPrefix  = "WARNING: "
+	errorPrefix = "Error: "
+)
+
+// ui prints the human-facing messages of a run. Progress and results (Info,
+// Verbose, Summary) go to out; warnings and errors always go to err, with
+// the prefixes above. When stdout carries data (dataOut), everything goes to
+// err so nothing but the data reaches out.
+type ui struct {
+	out, err io.Writer // nil means os.Stdout and os.Stderr as they are when printing
+	level    uiLevel
+	color    bool // color messages written to a terminal
+	json     bool // print each message as a JSON object on a line of its own
+	dataOut  bool // stdout carries data (sample, --temp-name)
+}
+
+// cli is the ui of the running command, set up by run from its flags.
+var cli = &ui{level: levelNormal}
+
+// newUI returns the ui flags ask for. Color is off under --no-color or a
+// non-empty NO_COLOR, and for streams that are not terminals.
+func newUI(flags cliFlags) *ui {
+	u := &ui{
+		level: levelNormal,
+		color: !flags.noColor && os.Getenv("NO_COLOR") == "",
+		json:  flags.jsonMsgs,
+	}
+	switch {
+	case flags.quiet:
+		u.level = levelQuiet
+	case flags.verbose:
+		u.level = levelVerbose
+	}
+	return u
+}
+
+// useUI makes u the ui of the running command until restore is called.
+// Batch jobs run nested, each with a ui of its own.
+func useUI(u *ui) (restore func()) {
+	prev := cli
+	cli = u
+	return func() { cli = prev }
+}
+
+func (u *ui) stdout() io.Writer {
+	if u.out == nil {
+		return os.Stdout
+	}
+	return u.out
+}
+
+func (u *ui) stderr() io.Writer {
+	if u.err == nil {
+		return os.Stderr
+	}
+	return u.err
+}
+
+// infoOut returns where Info and Summary print; reports such as --stats and
+// --explain print there too.
+func (u *ui) infoOut() io.Writer {
+	if u.dataOut {
+		return u.stderr()
+	}
+	return u.stdout()
+}
+
+// uiMessage is a message under --json-messages.
+type uiMessage struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// print writes one message of class level to w: as uiMessage under json,
+// otherwise as prefix+msg on a line of its own, colored with the SGR code
+// (if any) on terminals.
+func (u *ui) print(w io.Writer, level, code, prefix, msg string) {
+	msg = strings.TrimSuffix(msg, "\n")
+	if u.json {
+		data, _ := json.Marshal(uiMessage{level, msg})
+		fmt.Fprintf(w, "%s\n", data)
+		return
+	}
+	fmt.Fprintln(w, u.paint(w, code, prefix+msg))
+}
+
+// uiTerminal reports whether w gets colors when they are on. Tests replace
+// it to see the colors on buffers.
+var uiTerminal = isTerminal
+
+// paint wraps text in the SGR code when w gets colors.
+func (u *ui) paint(w io.Writer, code, text string) string {
+	if code == "" || !u.color || u.json || !uiTerminal(w) {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
+
+// highlight colors text for printing on infoOut, e.g. a cell of a table.
+func (u *ui) highlight(code, text string) string {
+	return u.paint(u.infoOut(), code, text)
+}
+
+// Info prints progress: what is being done, and notes about it.
+func (u *ui) Info(format string, a ...any) {
+	if u.level >= levelNormal {
+		u.print(u.infoOut(), "info", "", "", fmt.Sprintf(format, a...))
+	}
+}
+
+// Verbose prints details asked for with --verbose.
+func (u *ui) Verbose(format string, a ...any) {
+	if u.level >= levelVerbose {
+		u.print(u.infoOut(), "verbose", "", "", fmt.Sprintf(format, a...))
+	}
+}
+
+// Summary prints the result of a command, in green.
+func (u *ui) Summary(format string, a ...any) {
+	if u.level >= levelNormal {
+		u.print(u.infoOut(), "summary", ansiGreen, "", fmt.Sprintf(format, a...))
+	}
+}
+
+// Warn prints a warning to err, in yellow, at every level.
+func (u *ui) Warn(format string, a ...any) {
+	u.print(u.stderr(), "warning", ansiYellow, warnPrefix, fmt.Sprintf(format, a...))
+}
+
+// Error prints an error to err, in red, at every level.
+func (u *ui) Error(format string, a ...any) {
+	u.print(u.stderr(), "error", ansiRed, errorPrefix, fmt.Sprintf(format, a...))
+}
+
+// Hint prints advice following an error or warning, such as helpHint, to
+// err at every level.
+func (u *ui) Hint(text string) {
+	u.print(u.stderr(), "hint", "", "", text)
+}
+
+// Prompt prints a question for the user, as is: no newline, prefix or JSON.
+// Prompts go where Info goes, at every level.
+func (u *ui) Prompt(text string) {
+	fmt.Fprint(u.infoOut(), text)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// printAll prints one message of every class to u.
+func printAll(u *ui) {
+	u.Info("info %d", 1)
+	u.Verbose("verbose %d", 2)
+	u.Summary("summary %d", 3)
+	u.Warn("warn %d", 4)
+	u.Error("error %d", 5)
+	u.Hint("hint 6")
+}
+
+func TestUI_Routing(t *testing.T) {
+	tests := []struct {
+		name     string
+		u        ui
+		out, err string
+	}{
+		{"normal", ui{level: levelNormal},
+			"info 1\nsummary 3\n", "WARNING: warn 4\nError: error 5\nhint 6\n"},
+		{"quiet", ui{level: levelQuiet},
+			"", "WARNING: warn 4\nError: error 5\nhint 6\n"},
+		{"verbose", ui{level: levelVerbose},
+			"info 1\nverbose 2\nsummary 3\n", "WARNING: warn 4\nError: error 5\nhint 6\n"},
+		{"data on stdout", ui{level: levelVerbose, dataOut: true},
+			"", "info 1\nverbose 2\nsummary 3\nWARNING: warn 4\nError: error 5\nhint 6\n"},
+		{"quiet with data on stdout", ui{level: levelQuiet, dataOut: true},
+			"", "WARNING: warn 4\nError: error 5\nhint 6\n"},
+		{"colors off the terminal", ui{level: levelNormal, color: true},
+			"info 1\nsummary 3\n", "WARNING: warn 4\nError: error 5\nhint 6\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, err bytes.Buffer
+			u := tt.u
+			u.out, u.err = &out, &err
+			printAll(&u)
+			if out.String() != tt.out {
+				t.Errorf("out = %q, want %q", out.String(), tt.out)
+			}
+			if err.String() != tt.err {
+				t.Errorf("err = %q, want %q", err.String(), tt.err)
+			}
+		})
+	}
+}
+
+func TestUI_JSON(t *testing.T) {
+	var out, errOut bytes.Buffer
+	u := &ui{out: &out, err: &errOut, level: levelNormal, color: true, json: true}
+	old := uiTerminal
+	uiTerminal = func(io.Writer) bool { return true }
+	defer func() { uiTerminal = old }()
+	printAll(u)
+
+	decode := func(s string) []uiMessage {
+		var msgs []uiMessage
+		for line := range strings.Lines(s) {
+			var m uiMessage
+			if err := json.Unmarshal([]byte(line), &m); err != nil {
+				t.Fatalf("line %q: %v", line, err)
+			}
+			msgs = append(msgs, m)
+		}
+		return msgs
+	}
+	// Under JSON the class is the level: no prefixes, no colors.
+	if got := decode(out.String()); len(got) != 2 || got[0] != (uiMessage{"info", "info 1"}) || got[1] != (uiMessage{"summary", "summary 3"}) {
+		t.Errorf("out %q", out.String())
+	}
+	got := decode(errOut.String())
+	if len(got) != 3 || got[0] != (uiMessage{"warning", "warn 4"}) || got[1] != (uiMessage{"error", "error 5"}) || got[2] != (uiMessage{"hint", "hint 6"}) {
+		t.Errorf("err %q", errOut.String())
+	}
+}
+
+func TestUI_ColorsOnTerminals(t *testing.T) {
+	old := uiTerminal
+	uiTerminal = func(io.Writer) bool { return true }
+	defer func() { uiTerminal = old }()
+
+	var out, errOut bytes.Buffer
+	u := &ui{out: &out, err: &errOut, level: levelNormal, color: true}
+	printAll(u)
+	if want := "info 1\n\x1b[32msummary 3\x1b[0m\n"; out.String() != want {
+		t.Errorf("out = %q, want %q", out.String(), want)
+	}
+	if want := "\x1b[33mWARNING: warn 4\x1b[0m\n\x1b[31mError: error 5\x1b[0m\nhint 6\n"; errOut.String() != want {
+		t.Errorf("err = %q, want %q", errOut.String(), want)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if newUI(cliFlags{}).color || newUI(cliFlags{noColor: true}).color {
+		t.Error("colors on despite NO_COLOR or --no-color")
+	}
+	t.Setenv("NO_COLOR", "")
+	if !newUI(cliFlags{}).color {
+		t.Error("colors off by default")
+	}
+}
+
+func TestRun_MessageStreams(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+	os.WriteFile(path, []byte("old\n"), 0644)
+
+	// Progress and results on stdout, the overwrite warning on stderr.
+	output, errOutput := captureStdout(t), captureStderr(t)
+	if code := run([]string{"3", path, "y", "10", "digits"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if got := output(); !strings.HasPrefix(got, "Generating 3 lines") || !strings.HasSuffix(got, "Done!\n") || strings.Contains(got, "already exists") {
+		t.Errorf("stdout %q", got)
+	}
+	if got := errOutput(); !strings.HasPrefix(got, "WARNING: "+path+" already exists (4 B, modified ") || !strings.HasSuffix(got, "Overwriting...\n") {
+		t.Errorf("stderr %q", got)
+	}
+
+	// --quiet keeps stdout empty and the warning.
+	output, errOutput = captureStdout(t), captureStderr(t)
+	if code := run([]string{"3", path, "y", "10", "digits", "--quiet"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if got := output(); got != "" {
+		t.Errorf("quiet stdout %q", got)
+	}
+	if got := errOutput(); !strings.HasPrefix(got, "WARNING: ") {
+		t.Errorf("quiet stderr %q", got)
+	}
+
+	// --json-messages: one object per message, on the same streams.
+	output, errOutput = captureStdout(t), captureStderr(t)
+	if code := run([]string{"3", path, "y", "10", "digits", "--json-messages"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if got := output(); !strings.Contains(got, `{"level":"info","message":"Generating 3 lines`) || !strings.HasSuffix(got, `{"level":"summary","message":"Done!"}`+"\n") {
+		t.Errorf("json stdout %q", got)
+	}
+	if got := errOutput(); !strings.HasPrefix(got, `{"level":"warning","message":"`) {
+		t.Errorf("json stderr %q", got)
+	}
+
+	// --temp-name: stdout is the path alone, the messages go to stderr.
+	output, errOutput = captureStdout(t), captureStderr(t)
+	if code := run([]string{"3", "--temp-name", filepath.Join(dir, "tmp"), "10", "digits"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	name := strings.TrimSuffix(output(), "\n")
+	if _, err := os.Stat(name); err != nil || strings.Contains(name, "\n") {
+		t.Errorf("stdout %q is not the path alone: %v", name, err)
+	}
+	if got := errOutput(); !strings.Contains(got, "Generating 3 lines") || !strings.HasSuffix(got, "Done!\n") {
+		t.Errorf("temp-name stderr %q", got)
+	}
+
+	// sample: stdout is the sample alone.
+	output, errOutput = captureStdout(t), captureStderr(t)
+	if code := run([]string{"sample", "8", "random", "-", "2"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if got := output(); strings.Count(got, "\n") != 2 || strings.Contains(got, "seed") {
+		t.Errorf("sample stdout %q", got)
+	}
+	if got := errOutput(); !strings.HasPrefix(got, "mode=random: no seed given, sampling with seed ") {
+		t.Errorf("sample stderr %q", got)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// uploadHeaderPrefix marks environment variables that become request headers:
+// GENERATELINES_HEADER_X_API_KEY=secret sends "X-Api-Key: secret".
+const uploadHeaderPrefix = "GENERATELINES_HEADER_"
+
+// uploadResult describes a finished upload.
+type uploadResult struct {
+	lines  int64
+	bytes  int64
+	sha256 string
+	status string
+}
+
+// isUploadURL reports whether filename is an http(s) URL to upload to.
+func isUploadURL(filename string) bool {
+	lower := strings.ToLower(filename)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return false
+	}
+	u, err := url.Parse(filename)
+	return err == nil && u.Host != ""
+}
+
+// uploadHeaders builds the extra request headers from environment entries
+// (KEY=value) carrying uploadHeaderPrefix.
+func uploadHeaders(environ []string) http.Header {
+	h := http.Header{}
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		name, ok := strings.CutPrefix(key, uploadHeaderPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		h.Set(textproto.CanonicalMIMEHeaderKey(strings.ReplaceAll(name, "_", "-")), value)
+	}
+	return h
+}
+
+// upload streams the generated content as the body of a PUT request to target,
+// using chunked transfer encoding so memory use does not depend on the size.
+// Non-2xx responses are errors.
+func upload(ctx context.Context, client *http.Client, target string, opts genlines.Options, headers http.Header) (uploadResult, error) {
+	var res uploadResult
+
+	pr, pw := io.Pipe()
+	var st genlines.Stats
+	opts.Stats, opts.Checksums = &st, []string{"sha256"}
+	var genErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		res.lines, res.bytes, genErr = genlines.GenerateTo(ctx, pw, opts)
+		pw.CloseWithError(genErr)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, pr)
+	if err != nil {
+		pr.CloseWithError(err)
+		<-done
+		return res, err
+	}
+	req.ContentLength = -1
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		pr.CloseWithError(err)
+		<-done
+		return res, fmt.Errorf("uploading to %s: %w", target, err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	pr.Close()
+	<-done
+
+	res.status = resp.Status
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return res, fmt.Errorf("server responded %s", resp.Status)
+	}
+	if genErr != nil {
+		return res, genErr
+	}
+	res.sha256 = st.Checksums["sha256"]
+	return res, nil
+}
+
+// runUpload generates opts into a PUT request to target and returns the exit code.
+func runUpload(hist *runHistory, target string, opts genlines.Options, flags cliFlags) int {
+	cli.Info("Uploading %d lines (width=%d, mode=%s) -> %s", opts.Lines, opts.Width, opts.Mode, target)
+
+	res, err := upload(context.Background(), http.DefaultClient, target, opts, uploadHeaders(os.Environ()))
+	hist.written(res.lines, res.bytes)
+	if res.status != "" {
+		cli.Info("Server responded %s", res.status)
+	}
+	if err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+
+	if flags.manifest != "" {
+		m := newManifest()
+		m.add(target, res.lines, res.bytes, res.sha256)
+		if err := m.write(flags.manifest); err != nil {
+			cli.Error("writing manifest: %v", err)
+			return 1
+		}
+		cli.Info("Wrote manifest %s", flags.manifest)
+	}
+
+	cli.Summary("%s", text("done.upload", res.lines, res.bytes))
+	return 0
+}
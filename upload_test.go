@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+func TestIsUploadURL(t *testing.T) {
+	for _, s := range []string{"http://example.com/a.txt", "HTTPS://bucket.example.com/key"} {
+		if !isUploadURL(s) {
+			t.Errorf("isUploadURL(%q) = false", s)
+		}
+	}
+	for _, s := range []string{"out.txt", "http.txt", "https://", "ftp://example.com/a"} {
+		if isUploadURL(s) {
+			t.Errorf("isUploadURL(%q) = true", s)
+		}
+	}
+}
+
+func TestUploadHeaders(t *testing.T) {
+	h := uploadHeaders([]string{
+		"PATH=/bin",
+		"GENERATELINES_HEADER_X_API_KEY=secret",
+		"GENERATELINES_HEADER_AUTHORIZATION=Bearer abc=",
+		"GENERATELINES_HEADER_=ignored",
+	})
+	if len(h) != 2 || h.Get("X-Api-Key") != "secret" || h.Get("Authorization") != "Bearer abc=" {
+		t.Errorf("headers = %v", h)
+	}
+}
+
+func TestUpload_StreamsChunkedBody(t *testing.T) {
+	var (
+		body     []byte
+		header   http.Header
+		method   string
+		chunked  bool
+		received = make(chan struct{})
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(received)
+		method, header = r.Method, r.Header
+		chunked = r.ContentLength == -1 && len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked"
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	opts := genlines.Options{Lines: 500, Width: 30, Mode: "digits"}
+	extra := http.Header{"X-Api-Key": {"secret"}}
+	res, err := upload(context.Background(), srv.Client(), srv.URL+"/data.txt", opts, extra)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	<-received
+
+	var want bytes.Buffer
+	genlines.GenerateTo(context.Background(), &want, opts)
+	if !bytes.Equal(body, want.Bytes()) {
+		t.Errorf("server received %d bytes, want %d identical bytes", len(body), want.Len())
+	}
+	if method != http.MethodPut || !chunked {
+		t.Errorf("method=%s chunked=%v, want PUT with chunked transfer", method, chunked)
+	}
+	if header.Get("X-Api-Key") != "secret" || !strings.HasPrefix(header.Get("Content-Type"), "text/plain") {
+		t.Errorf("headers = %v", header)
+	}
+	if res.lines != 500 || res.bytes != int64(want.Len()) || !strings.HasPrefix(res.status, "201") {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestUpload_FailsOnNon2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "denied", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	res, err := upload(context.Background(), srv.Client(), srv.URL, genlines.Options{Lines: 100000, Width: 80}, nil)
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("err = %v, want a 403 error", err)
+	}
+	if !strings.HasPrefix(res.status, "403") {
+		t.Errorf("status = %q", res.status)
+	}
+}
+
+func TestRun_UploadSkipsOverwritePrompt(t *testing.T) {
+	var got int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = len(b)
+	}))
+	defer srv.Close()
+
+	withStdin(t, "", true) // a prompt would hit EOF and fail
+	if code := run([]string{"4", srv.URL + "/x.txt", "10"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if got != 4*11 {
+		t.Errorf("server received %d bytes, want %d", got, 4*11)
+	}
+}
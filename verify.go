@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// maxReportedMismatches caps how many bad lines verify-lines prints individually.
+const maxReportedMismatches = 50
+
+// recordSeparator returns the first terminator in head: 0 for NUL-delimited
+// records, else '\n'.
+func recordSeparator(head []byte) byte {
+	if i := bytes.IndexAny(head, "\n\x00"); i >= 0 && head[i] == 0 {
+		return 0
+	}
+	return '\n'
+}
+
+// runVerifyLinesCmd handles "verify-lines <file>": it checks the per-line
+// checksums written with --line-checksum and returns 0 if all match.
+func runVerifyLinesCmd(args []string) int {
+	if len(args) != 1 {
+		cli.Error("verify-lines requires exactly one file")
+		cli.Hint(helpHint())
+		return 1
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+	defer f.Close()
+
+	// NUL-delimited records (--line-ending nul) are told by their first
+	// terminator.
+	r, sep := bufio.NewReaderSize(f, sniffSize), byte('\n')
+	if head, _ := r.Peek(sniffSize); recordSeparator(head) == 0 {
+		sep = 0
+		cli.Info("Records are NUL-delimited")
+	}
+
+	// A --stamp line has no checksum: skip it, numbering the rest as in the file.
+	skipped := int64(0)
+	if head, _ := r.Peek(len(stampPrefix)); string(head) == stampPrefix {
+		if _, err := r.ReadString(sep); err != nil && err != io.EOF {
+			cli.Error("%v", err)
+			return 1
+		}
+		skipped = 1
+	}
+
+	bad := 0
+	total, err := genlines.VerifyRecords(r, sep, func(n int64, line string) {
+		bad++
+		if bad <= maxReportedMismatches {
+			cli.Info("line %d: checksum mismatch", n+skipped)
+		}
+	})
+	if err != nil {
+		cli.Error("%v", err)
+		return 1
+	}
+
+	if bad > maxReportedMismatches {
+		cli.Info("... %d more", bad-maxReportedMismatches)
+	}
+	if bad > 0 {
+		cli.Error("%d of %d lines failed verification", bad, total)
+		return 1
+	}
+	cli.Summary("%s", text("done.verified", total))
+	return 0
+}
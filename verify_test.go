@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// captureStdout redirects os.Stdout to a temp file for the rest of the test
+// and returns a function that reads what was written so far.
+func captureStdout(t testing.TB) func() string {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = f
+	t.Cleanup(func() {
+		os.Stdout = old
+		f.Close()
+	})
+	return func() string {
+		data, _ := os.ReadFile(f.Name())
+		return string(data)
+	}
+}
+
+func TestRun_VerifyLinesPinpointsCorruption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sum.txt")
+	if code := run([]string{"100", path, "y", "40", "alpha", "--line-checksum"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if code := run([]string{"verify-lines", path}); code != 0 {
+		t.Fatalf("verify-lines on an intact file exited with %d", code)
+	}
+
+	data, _ := os.ReadFile(path)
+	data[41*41+10] = '!' // line 42
+	os.WriteFile(path, data, 0644)
+
+	output := captureStdout(t)
+	if code := run([]string{"verify-lines", path}); code != 1 {
+		t.Fatalf("verify-lines on a corrupted file exited with %d, want 1", code)
+	}
+	if got := output(); got != "line 42: checksum mismatch\n" {
+		t.Errorf("verify-lines printed %q", got)
+	}
+}
+
+func TestRun_LineChecksumRejectsNarrowWidth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "narrow.txt")
+	if code := run([]string{"5", path, "y", "9", "--line-checksum"}); code == 0 {
+		t.Fatal("width 9 with --line-checksum should fail")
+	}
+	if data, _ := os.ReadFile(path); strings.TrimSpace(string(data)) != "" {
+		t.Errorf("failed run wrote %q", data)
+	}
+}
+
+func TestRun_ContinuationAcrossSplitParts(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cont.txt")
+	if code := run([]string{"7", path, "y", "20", "alpha", "--line-checksum", "--continuation", "--split-lines", "3"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	var all []string
+	for _, part := range []string{"cont-001.txt", "cont-002.txt", "cont-003.txt"} {
+		data, err := os.ReadFile(filepath.Join(dir, part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")...)
+		if code := run([]string{"verify-lines", filepath.Join(dir, part)}); code != 0 {
+			t.Errorf("verify-lines %s exited with %d", part, code)
+		}
+	}
+	for i, line := range all {
+		if len(line) != 20 || strings.HasSuffix(line, `\`) != (i < 6) {
+			t.Errorf("line %d: %q", i+1, line)
+		}
+	}
+
+	if code := run([]string{"3", path, "y", "20", "--continuation-marker", " &", "--meta"}); code != 0 {
+		t.Fatalf("meta run exited with %d", code)
+	}
+	if m, err := readMeta(path + metaSuffix); err != nil || m.Continuation != " &" {
+		t.Errorf("meta continuation = %q (%v)", m.Continuation, err)
+	}
+	if code := run([]string{"3", path, "y", "20", "--continuation", "--append"}); code != 1 {
+		t.Errorf("--append: exit %d, want 1", code)
+	}
+}
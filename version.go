@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/Bjornsrud/GenerateLines/genlines"
+)
+
+// buildInfo is what "version --full" and "version --json" report.
+type buildInfo struct {
+	Version      string     `json:"version"`
+	GoVersion    string     `json:"goVersion"`
+	OS           string     `json:"os"`
+	Arch         string     `json:"arch"`
+	Revision     string     `json:"revision,omitempty"` // VCS revision, when built from a checkout
+	RevisionTime string     `json:"revisionTime,omitempty"`
+	Modified     bool       `json:"modified,omitempty"` // built from a checkout with local changes
+	Modes        []modeInfo `json:"modes"`
+}
+
+// modeInfo describes one compiled-in mode.
+type modeInfo struct {
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Description string   `json:"description"`
+}
+
+// collectBuildInfo gathers the running binary's build information and modes.
+func collectBuildInfo() buildInfo {
+	info := buildInfo{
+		Version:   version,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Revision = s.Value
+			case "vcs.time":
+				info.RevisionTime = s.Value
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	for _, name := range genlines.ModeNames() {
+		_, spec, _ := genlines.LookupMode(name)
+		info.Modes = append(info.Modes, modeInfo{Name: name, Aliases: spec.Aliases, Description: spec.Description})
+	}
+	return info
+}
+
+// runVersionCmd handles "version [--full|--json]" and returns the exit code.
+// Without an option it prints the single line scripts rely on.
+func runVersionCmd(args []string) int {
+	form := ""
+	if len(args) > 0 {
+		form = strings.ToLower(strings.TrimSpace(args[0]))
+	}
+	if len(args) > 1 || (form != "" && form != "--full" && form != "--json") {
+		cli.Error("version accepts only --full or --json")
+		return 1
+	}
+
+	switch form {
+	case "":
+		fmt.Printf("GenerateLines %s\n", version)
+	case "--json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(collectBuildInfo()); err != nil {
+			cli.Error("%v", err)
+			return 1
+		}
+	case "--full":
+		info := collectBuildInfo()
+		fmt.Printf("GenerateLines %s\n", info.Version)
+		fmt.Printf("Go:        %s %s/%s\n", info.GoVersion, info.OS, info.Arch)
+		if info.Revision != "" {
+			rev := info.Revision
+			if info.Modified {
+				rev += " (modified)"
+			}
+			fmt.Printf("Revision:  %s\n", rev)
+			if info.RevisionTime != "" {
+				fmt.Printf("Committed: %s\n", info.RevisionTime)
+			}
+		} else {
+			fmt.Println("Revision:  unknown (not built from a VCS checkout)")
+		}
+		fmt.Println("Modes:")
+		for _, m := range info.Modes {
+			line := "  " + m.Name
+			if len(m.Aliases) > 0 {
+				line += " (" + strings.Join(m.Aliases, ", ") + ")"
+			}
+			fmt.Printf("%-32s %s\n", line, m.Description)
+		}
+	}
+	return 0
+}
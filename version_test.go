@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRun_VersionShortFormUnchanged(t *testing.T) {
+	for _, arg := range []string{"version", "--version", "-v", "/v"} {
+		read := captureStdout(t)
+		if code := run([]string{arg}); code != 0 {
+			t.Fatalf("%s: exit code %d", arg, code)
+		}
+		if got, want := read(), "GenerateLines "+version+"\n"; got != want {
+			t.Errorf("%s: got %q, want %q", arg, got, want)
+		}
+	}
+}
+
+func TestRun_VersionJSON(t *testing.T) {
+	read := captureStdout(t)
+	if code := run([]string{"version", "--json"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(read()), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	for _, field := range []string{"version", "goVersion", "os", "arch", "modes"} {
+		if _, ok := got[field]; !ok {
+			t.Errorf("missing field %q", field)
+		}
+	}
+	if got["version"] != version {
+		t.Errorf("version = %v, want %s", got["version"], version)
+	}
+	modes, _ := got["modes"].([]any)
+	if len(modes) == 0 {
+		t.Fatalf("no modes listed")
+	}
+	if first, _ := modes[0].(map[string]any); first["name"] != "ascii" || first["description"] == "" {
+		t.Errorf("unexpected first mode: %v", modes[0])
+	}
+}
+
+func TestRun_VersionFullListsModes(t *testing.T) {
+	read := captureStdout(t)
+	if code := run([]string{"version", "--full"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	out := read()
+	for _, want := range []string{"GenerateLines " + version, "Go:", "Modes:", "hashfill (hash)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if code := run([]string{"version", "--short"}); code != 1 {
+		t.Errorf("unknown version option: exit code %d, want 1", code)
+	}
+}
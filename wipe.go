@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// wipeChunk is how many zero bytes wipe writes per call.
+const wipeChunk = 1 << 20
+
+// wipeHook, if set, is called with the name of every file wipe has zeroed and
+// synced, before the file is truncated, replaced or removed. Tests use it to
+// look at the wiped file.
+var wipeHook func(name string)
+
+// wipe overwrites the current extent of f with zeros, in wipeChunk writes
+// from offset 0, and syncs it, so the old content does not survive in free
+// blocks once the file is truncated or removed. Files other than regular
+// files are left alone. On SSDs and copy-on-write or journaling filesystems
+// the zeros may land in other blocks than the old content: it is a best
+// effort, not a guarantee.
+func wipe(f *os.File) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil
+	}
+	zeros := make([]byte, min(wipeChunk, fi.Size()))
+	for off := int64(0); off < fi.Size(); {
+		n, err := f.WriteAt(zeros[:min(int64(len(zeros)), fi.Size()-off)], off)
+		if err != nil {
+			return err
+		}
+		off += int64(n)
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if wipeHook != nil {
+		wipeHook(f.Name())
+	}
+	return nil
+}
+
+// wipeFile wipes the file at name, if it is a regular file. A FIFO is not
+// even opened: that would wait for a reader.
+func wipeFile(name string) error {
+	fi, err := os.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil || !fi.Mode().IsRegular() {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if err := wipe(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// removeWiped removes the file at name, wiping it first if wipeOld is set.
+func removeWiped(name string, wipeOld bool) error {
+	if wipeOld {
+		if err := wipeFile(name); err != nil {
+			return err
+		}
+	}
+	return os.Remove(name)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// watchWipes sets wipeHook to record, for every wiped file, whether it held
+// only zeros and its size at that moment, while the old content is still
+// under its name.
+func watchWipes(t *testing.T) map[string]int64 {
+	t.Helper()
+	seen := map[string]int64{}
+	wipeHook = func(name string) {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Errorf("reading wiped %s: %v", name, err)
+			return
+		}
+		if i := bytes.IndexFunc(data, func(r rune) bool { return r != 0 }); i >= 0 {
+			t.Errorf("%s: byte %d not wiped", name, i)
+		}
+		seen[name] = int64(len(data))
+	}
+	t.Cleanup(func() { wipeHook = nil })
+	return seen
+}
+
+func TestWipeFile_ZerosInChunks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.txt")
+	size := int64(2*wipeChunk + 123)
+	os.WriteFile(path, bytes.Repeat([]byte("secret\n"), int(size/7)+1)[:size], 0644)
+	seen := watchWipes(t)
+
+	if err := wipeFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if seen[path] != size {
+		t.Errorf("wiped %d bytes, want %d", seen[path], size)
+	}
+}
+
+func TestWipeFile_SkipsMissingAndDirectories(t *testing.T) {
+	dir := t.TempDir()
+	seen := watchWipes(t)
+	if err := wipeFile(filepath.Join(dir, "missing.txt")); err != nil {
+		t.Errorf("missing file: %v", err)
+	}
+	if err := wipeFile(dir); err != nil {
+		t.Errorf("directory: %v", err)
+	}
+	if len(seen) != 0 {
+		t.Errorf("wiped %v", seen)
+	}
+}
+
+func TestRun_WipeOldBeforeOverwrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	os.WriteFile(path, []byte(strings.Repeat("old secret\n", 100)), 0644)
+	seen := watchWipes(t)
+
+	captureStdout(t)
+	if code := run([]string{"3", path, "y", "10", "digits", "--wipe-old"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if seen[path] != 1100 {
+		t.Errorf("wiped %v, want %s at its old size", seen, path)
+	}
+	if data, _ := os.ReadFile(path); len(data) != 33 {
+		t.Errorf("new content %q", data)
+	}
+}
+
+func TestAlsoLink_WipesReplacedFile(t *testing.T) {
+	src, dir1, _ := linkDirs(t)
+	os.WriteFile(src, []byte("content\n"), 0644)
+	dst := filepath.Join(dir1, "out.txt")
+	os.WriteFile(dst, []byte("old destination\n"), 0644)
+
+	// Between the wipe and the rename, dst is still the old file.
+	seen := map[string]bool{}
+	watched := watchWipes(t)
+	check := wipeHook
+	wipeHook = func(name string) {
+		check(name)
+		seen[name] = !isSameFile(t, src, name)
+	}
+
+	captureStdout(t)
+	if !alsoLink(newOverwritePrompt(nil, "y"), src, []string{dir1}, false, true) {
+		t.Fatal("alsoLink failed")
+	}
+	if !seen[dst] || watched[dst] != 16 {
+		t.Errorf("old %s not wiped before the rename: %v", dst, watched)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "content\n" {
+		t.Errorf("destination holds %q", data)
+	}
+}
+
+func TestRotateFiles_WipesDroppedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("new\n"), 0644)
+	os.WriteFile(path+".1", []byte("oldest\n"), 0644)
+	seen := watchWipes(t)
+
+	if err := rotateFiles(path, 1, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 1 || seen[path+".1"] != 7 {
+		t.Errorf("wiped %v, want only %s.1", seen, path)
+	}
+	if data, _ := os.ReadFile(path + ".1"); string(data) != "new\n" {
+		t.Errorf("%s.1 holds %q", path, data)
+	}
+}
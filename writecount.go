@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Bjornsrud/GenerateLines/genlines/humanize"
+)
+
+// writeCounter counts the Write calls that reach an output file and the bytes
+// they carry, to show how well the run's buffering batches its writes.
+type writeCounter struct {
+	w     io.Writer
+	calls int64
+	bytes int64
+}
+
+func (c *writeCounter) Write(p []byte) (int, error) {
+	c.calls++
+	n, err := c.w.Write(p)
+	c.bytes += int64(n)
+	return n, err
+}
+
+// summary describes the writes, e.g. "2 writes, avg 48.8 KiB/write (100_000 bytes)".
+func (c *writeCounter) summary() string {
+	if c.calls == 0 {
+		return "0 writes"
+	}
+	noun := "writes"
+	if c.calls == 1 {
+		noun = "write"
+	}
+	avg := float64(c.bytes) / float64(c.calls) / 1024
+	return fmt.Sprintf("%d %s, avg %s KiB/write (%s bytes)", c.calls, noun, humanize.Fixed(avg, 1), humanize.Count(c.bytes))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteCounter(t *testing.T) {
+	c := &writeCounter{w: io.Discard}
+	if got := c.summary(); got != "0 writes" {
+		t.Errorf("empty summary = %q", got)
+	}
+	c.Write(make([]byte, 1024))
+	if got := c.summary(); got != "1 write, avg 1.0 KiB/write (1_024 bytes)" {
+		t.Errorf("summary = %q", got)
+	}
+	c.Write(make([]byte, 2048))
+	if c.calls != 2 || c.bytes != 3072 {
+		t.Errorf("calls=%d bytes=%d", c.calls, c.bytes)
+	}
+}
+
+func TestRun_VerboseCountsWrites(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+
+	// 100000 bytes through the 64 KiB write buffer: one full buffer, then the rest.
+	out := captureStdout(t)
+	if code := run([]string{"1000", path, "y", "99", "digits", "--verbose"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	if text := out(); !strings.Contains(text, "Output: 2 writes, avg 48.8 KiB/write (100_000 bytes)") {
+		t.Errorf("stdout lacks the write summary:\n%s", text)
+	}
+
+	// The terminator added before appending is a write of its own.
+	os.WriteFile(path, []byte("old"), 0644)
+	if code := run([]string{"10", path, "9", "digits", "--append", "--verbose"}); code != 0 {
+		t.Fatalf("append run exited with %d", code)
+	}
+	if text := out(); !strings.Contains(text, "Output: 2 writes, avg 0.0 KiB/write (101 bytes)") {
+		t.Errorf("stdout lacks the append write summary:\n%s", text)
+	}
+
+	// Comment lines and padding are counted with the data.
+	if code := run([]string{"50", path, "y", "10", "--comment-every", "7", "--align", "64", "--verbose"}); code != 0 {
+		t.Fatalf("aligned run exited with %d", code)
+	}
+	fi, _ := os.Stat(path)
+	if text := out(); !strings.Contains(text, fmt.Sprintf("Output: 1 write, avg 0.7 KiB/write (%d bytes)", fi.Size())) {
+		t.Errorf("size %d, stdout:\n%s", fi.Size(), text)
+	}
+}
+
+func TestRun_VerboseCountsFlushes(t *testing.T) {
+	dir := t.TempDir()
+	path, other := filepath.Join(dir, "out.txt"), filepath.Join(dir, "other.txt")
+
+	out := captureStdout(t)
+	if code := run([]string{"10", path, "y", "9", "digits", "--flush-every", "4", "--verbose"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	text := out()
+	if !strings.Contains(text, "Output: 3 writes, avg 0.0 KiB/write (100 bytes)") || !strings.Contains(text, "Flushed 3 times (every 4 lines and at the end)") {
+		t.Errorf("stdout lacks the flush summary:\n%s", text)
+	}
+
+	// Every --out target gets the flushed lines at once too.
+	if code := run([]string{"10", path, "y", "9", "digits", "--out", other, "--flush-every", "1", "--verbose"}); code != 0 {
+		t.Fatalf("run exited with %d", code)
+	}
+	text = out()
+	for _, p := range []string{path, other} {
+		if !strings.Contains(text, p+": 10 writes, avg 0.0 KiB/write (100 bytes)") {
+			t.Errorf("stdout lacks the writes to %s:\n%s", p, text)
+		}
+	}
+	if !strings.Contains(text, "Flushed 10 times") {
+		t.Errorf("stdout lacks the flush count:\n%s", text)
+	}
+}